@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// APIToken is a personal token for automation, Token is set in the
+// response to the creation only. Empty SecretTypes and SecretIDs do not
+// limit the token, zero ExpiresAt means the token never expires.
+type APIToken struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Token       string    `json:"token,omitempty"`
+	ReadOnly    bool      `json:"read_only"`
+	SecretTypes []string  `json:"secret_types"`
+	SecretIDs   []int64   `json:"secret_ids"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateAPITokenParams ExpiresAt is nil for a token which never expires.
+type CreateAPITokenParams struct {
+	Name        string     `json:"name"`
+	ReadOnly    bool       `json:"read_only"`
+	SecretTypes []string   `json:"secret_types,omitempty"`
+	SecretIDs   []int64    `json:"secret_ids,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+type apiTokensIndexResponse struct {
+	Tokens []APIToken `json:"tokens"`
+}
+
+func (client *GophkeeperClient) CreateAPIToken(ctx context.Context, params CreateAPITokenParams) (APIToken, error) {
+	var token APIToken
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		client.baseURL+"/api/user/tokens",
+		params,
+		http.StatusCreated,
+		&token,
+	)
+	if err != nil {
+		return token, fmt.Errorf("failed to create api token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (client *GophkeeperClient) ListAPITokens(ctx context.Context) ([]APIToken, error) {
+	var response apiTokensIndexResponse
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodGet,
+		client.baseURL+"/api/user/tokens",
+		nil,
+		http.StatusOK,
+		&response,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api tokens: %w", err)
+	}
+
+	return response.Tokens, nil
+}
+
+func (client *GophkeeperClient) RevokeAPIToken(ctx context.Context, id int64) error {
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodDelete,
+		fmt.Sprintf("%s/api/user/tokens/%d", client.baseURL, id),
+		nil,
+		http.StatusOK,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api token: %w", err)
+	}
+
+	return nil
+}
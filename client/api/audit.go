@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// AuditEvent is an action of the user with the secret, Action is "read",
+// "create", "update", "delete", "restore" or "purge".
+type AuditEvent struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Action    string    `json:"action"`
+	SecretID  int64     `json:"secret_id"`
+	OwnerID   int64     `json:"owner_id"`
+	VaultID   int64     `json:"vault_id"`
+	ClientIP  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	Hash      string    `json:"hash"`
+}
+
+// AuditParams selects audit events created in [From, To), zero fields
+// are not sent.
+type AuditParams struct {
+	From     time.Time
+	To       time.Time
+	SecretID int64
+	Limit    int
+}
+
+// ListAuditEvents returns audit events visible to the user, the latest first.
+func (client *GophkeeperClient) ListAuditEvents(ctx context.Context, params AuditParams) ([]AuditEvent, error) {
+	var response struct {
+		Events []AuditEvent `json:"events"`
+	}
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodGet,
+		client.baseURL+"/api/audit?"+params.query().Encode(),
+		nil,
+		http.StatusOK,
+		&response,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	return response.Events, nil
+}
+
+func (params AuditParams) query() url.Values {
+	query := url.Values{}
+	if !params.From.IsZero() {
+		query.Set("from", params.From.Format(time.RFC3339))
+	}
+	if !params.To.IsZero() {
+		query.Set("to", params.To.Format(time.RFC3339))
+	}
+	if params.SecretID != 0 {
+		query.Set("secret_id", strconv.FormatInt(params.SecretID, 10))
+	}
+	if params.Limit != 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+
+	return query
+}
@@ -3,18 +3,24 @@ package api
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 )
 
 type GophkeeperClient struct {
 	baseURL    string
 	jwt        string
+	apiToken   string
 	httpClient http.Client
+	uploads    UploadStore
 }
 
 func NewGophkeeperClient(baseURL string) *GophkeeperClient {
@@ -24,7 +30,8 @@ func NewGophkeeperClient(baseURL string) *GophkeeperClient {
 	}
 }
 
-func (client *GophkeeperClient) RegisterUser(ctx context.Context, login, password string) error {
+// RegisterUser creates the user and returns tokens of the new session.
+func (client *GophkeeperClient) RegisterUser(ctx context.Context, login, password string) (SessionTokens, error) {
 	reqBody, err := json.Marshal(
 		UserCredentials{
 			Login:    login,
@@ -32,7 +39,7 @@ func (client *GophkeeperClient) RegisterUser(ctx context.Context, login, passwor
 		},
 	)
 	if err != nil {
-		return fmt.Errorf("failed encode request body: %w", err)
+		return SessionTokens{}, fmt.Errorf("failed encode request body: %w", err)
 	}
 	req, err := http.NewRequest(
 		http.MethodPost,
@@ -40,25 +47,27 @@ func (client *GophkeeperClient) RegisterUser(ctx context.Context, login, passwor
 		bytes.NewReader(reqBody),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return SessionTokens{}, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 	req.Header.Set("Accept", "application/json; charset=utf-8")
 
 	resp, err := client.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to do request: %w", err)
+		return SessionTokens{}, fmt.Errorf("failed to do request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return errors.New("failed to register user")
+		return SessionTokens{}, errors.New("failed to register user")
 	}
 
-	return nil
+	return sessionFromCookies(resp)
 }
 
-func (client *GophkeeperClient) AuthenticateUser(ctx context.Context, login, password string) (string, error) {
+// AuthenticateUser returns tokens of the new session. ErrTOTPRequired is returned if
+// the user has enabled TOTP.
+func (client *GophkeeperClient) AuthenticateUser(ctx context.Context, login, password string) (SessionTokens, error) {
 	reqBody, err := json.Marshal(
 		UserCredentials{
 			Login:    login,
@@ -66,7 +75,7 @@ func (client *GophkeeperClient) AuthenticateUser(ctx context.Context, login, pas
 		},
 	)
 	if err != nil {
-		return "", fmt.Errorf("failed encode request body: %w", err)
+		return SessionTokens{}, fmt.Errorf("failed encode request body: %w", err)
 	}
 	req, err := http.NewRequest(
 		http.MethodPost,
@@ -74,371 +83,550 @@ func (client *GophkeeperClient) AuthenticateUser(ctx context.Context, login, pas
 		bytes.NewReader(reqBody),
 	)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return SessionTokens{}, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 	req.Header.Set("Accept", "application/json; charset=utf-8")
 
 	resp, err := client.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to do request: %w", err)
+		return SessionTokens{}, fmt.Errorf("failed to do request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return SessionTokens{}, fmt.Errorf(
+			"failed to authenticate user: too many failed attempts, retry after %s seconds",
+			resp.Header.Get("Retry-After"),
+		)
+	}
 	if resp.StatusCode != http.StatusOK {
-		return "", errors.New("failed to authenticate user")
+		return SessionTokens{}, errors.New("failed to authenticate user")
 	}
 
-	for _, cookie := range resp.Cookies() {
-		if cookie.Name == "jwt" {
-			return cookie.Value, nil
-		}
+	tokens, err := sessionFromCookies(resp)
+	if err == nil {
+		return tokens, nil
+	}
+	// users with TOTP enabled get an MFA token instead of the cookie
+	var response struct {
+		TOTPRequired bool   `json:"totp_required"`
+		MFAToken     string `json:"mfa_token"`
+	}
+	if decodeErr := json.NewDecoder(resp.Body).Decode(&response); decodeErr == nil && response.TOTPRequired {
+		return SessionTokens{}, ErrTOTPRequired{MFAToken: response.MFAToken}
 	}
 
-	return "", errors.New("failed to get JWT from response")
+	return SessionTokens{}, err
 }
 
-func (client *GophkeeperClient) GetSecrets(ctx context.Context) ([]byte, error) {
-	req, err := http.NewRequest(
+// GetSecrets downloads the archive with user secrets into w. Secrets are
+// placed into directories named after their folders.
+func (client *GophkeeperClient) GetSecrets(ctx context.Context, params GetSecretsParams, w io.Writer) error {
+	query := url.Values{}
+	if params.FolderID != 0 {
+		query.Set("folder_id", strconv.FormatInt(params.FolderID, 10))
+	}
+	if params.TagID != 0 {
+		query.Set("tag_id", strconv.FormatInt(params.TagID, 10))
+	}
+	if params.VaultID != 0 {
+		query.Set("vault_id", strconv.FormatInt(params.VaultID, 10))
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodGet,
-		client.baseURL+"/api/secrets",
+		client.baseURL+"/api/secrets?"+query.Encode(),
 		nil,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/zip")
-	req.AddCookie(&http.Cookie{
-		Name:  "jwt",
-		Value: client.jwt,
-	})
+	req.Header.Set("Accept", "application/zip")
+	client.authorize(req)
 
 	resp, err := client.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to do request")
+		return fmt.Errorf("failed to do request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	archiveContent, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read archive content: %w", err)
-	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get secrets status=%d", resp.StatusCode)
+		return fmt.Errorf("failed to get secrets status=%d", resp.StatusCode)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to read archive content: %w", err)
 	}
 
-	return archiveContent, nil
+	return nil
 }
 
-func (client *GophkeeperClient) CreateCredentials(ctx context.Context, login, password string) error {
-	reqBody := &bytes.Buffer{}
-	writer := multipart.NewWriter(reqBody)
-	if err := createFormField(writer, "secret_type", []byte("credentials")); err != nil {
-		return fmt.Errorf("failed to add secret_type field: %w", err)
+func (client *GophkeeperClient) ListSecrets(ctx context.Context, params ListSecretsParams) (SecretsPage, error) {
+	query := url.Values{}
+	if params.Cursor != 0 {
+		query.Set("cursor", strconv.FormatInt(params.Cursor, 10))
 	}
-	if err := createFormField(writer, "login", []byte(login)); err != nil {
-		return fmt.Errorf("failed to add login field: %w", err)
+	if params.Limit != 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
 	}
-	if err := createFormField(writer, "password", []byte(password)); err != nil {
-		return fmt.Errorf("failed to add password filed: %w", err)
+	if params.SecretType != "" {
+		query.Set("type", params.SecretType)
 	}
-	if err := writer.Close(); err != nil {
-		return fmt.Errorf("failed to create request form: %w", err)
+	if params.Description != "" {
+		query.Set("description", params.Description)
 	}
-
-	req, err := http.NewRequest(
-		http.MethodPost,
-		client.baseURL+"/api/secrets",
-		reqBody,
+	if params.FolderID != 0 {
+		query.Set("folder_id", strconv.FormatInt(params.FolderID, 10))
+	}
+	if params.TagID != 0 {
+		query.Set("tag_id", strconv.FormatInt(params.TagID, 10))
+	}
+	if params.VaultID != 0 {
+		query.Set("vault_id", strconv.FormatInt(params.VaultID, 10))
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		client.baseURL+"/api/secrets/index?"+query.Encode(),
+		nil,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return SecretsPage{}, fmt.Errorf("failed to create request: %w", err)
 	}
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	req.AddCookie(&http.Cookie{
-		Name:  "jwt",
-		Value: client.jwt,
-	})
+	req.Header.Set("Accept", "application/json")
+	client.authorize(req)
+
 	resp, err := client.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to do request: %w", err)
+		return SecretsPage{}, fmt.Errorf("failed to do request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return errors.New("failed to create creadentials")
+		return SecretsPage{}, fmt.Errorf("failed to list secrets status=%d", resp.StatusCode)
+	}
+	var page SecretsPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return page, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return nil
+	return page, nil
 }
 
-func (client *GophkeeperClient) CreateCreditCard(ctx context.Context, number, name, expiryDateStr, cvv2 string) error {
-	reqBody := &bytes.Buffer{}
-	writer := multipart.NewWriter(reqBody)
-	if err := createFormField(writer, "secret_type", []byte("credit_card_info")); err != nil {
-		return fmt.Errorf("failed to add secret_type field: %w", err)
-	}
-	if err := createFormField(writer, "credit_card_number", []byte(number)); err != nil {
-		return fmt.Errorf("failed to add credit_card_number field: %w", err)
+// ErrRangeNotSatisfiable is returned if GetSecretParams.Offset is beyond
+// the end of binary data.
+var ErrRangeNotSatisfiable = errors.New("requested range not satisfiable")
+
+func (client *GophkeeperClient) GetSecret(ctx context.Context, id int64, params GetSecretParams) (Secret, error) {
+	return client.getSecret(ctx, fmt.Sprintf("%s/api/secrets/%d", client.baseURL, id), params)
+}
+
+func (client *GophkeeperClient) getSecret(ctx context.Context, secretURL string, params GetSecretParams) (Secret, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, secretURL, nil)
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to create request: %w", err)
 	}
-	if err := createFormField(writer, "credit_card_name", []byte(name)); err != nil {
-		return fmt.Errorf("failed to add credit_card_name field: %w", err)
+	client.authorize(req)
+	if params.IfNoneMatch != "" {
+		req.Header.Set("If-None-Match", params.IfNoneMatch)
 	}
-	if err := createFormField(writer, "credit_card_expiry_date", []byte(expiryDateStr)); err != nil {
-		return fmt.Errorf("failed to add credit_card_expirty_date field: %w", err)
+	if params.Offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", params.Offset))
+		if params.IfRange != "" {
+			req.Header.Set("If-Range", params.IfRange)
+		}
 	}
-	if err := createFormField(writer, "credit_card_cvv2", []byte(cvv2)); err != nil {
-		return fmt.Errorf("failed to add credit_card_cvv2 field: %w", err)
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to do request: %w", err)
+	}
+	secret := Secret{
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        resp.Header.Get("ETag"),
+		Content:     resp.Body,
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusPartialContent:
+		secret.Partial = true
+	case http.StatusNotModified:
+		secret.NotModified = true
+	case http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		return Secret{}, ErrRangeNotSatisfiable
+	default:
+		resp.Body.Close()
+		return Secret{}, fmt.Errorf("failed to get secret status=%d", resp.StatusCode)
+	}
+
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		secret.Filename = params["filename"]
+	}
+	secret.SHA256 = parseSHA256Digest(resp.Header.Get("Repr-Digest"))
+	if metadataHeader := resp.Header.Get("Secret-Metadata"); metadataHeader != "" {
+		values, err := url.ParseQuery(metadataHeader)
+		if err == nil {
+			secret.Metadata = make(map[string]string, len(values))
+			for key := range values {
+				secret.Metadata[key] = values.Get(key)
+			}
+		}
 	}
-	if err := writer.Close(); err != nil {
-		return fmt.Errorf("failed to create request form: %w", err)
+
+	return secret, nil
+}
+
+// createSecretURL returns the URL secrets are created with, a secret of
+// the vault is created if vaultID is not zero.
+func (client *GophkeeperClient) createSecretURL(vaultID int64) string {
+	if vaultID == 0 {
+		return client.baseURL + "/api/secrets"
 	}
 
-	req, err := http.NewRequest(
+	return fmt.Sprintf("%s/api/secrets?vault_id=%d", client.baseURL, vaultID)
+}
+
+func (client *GophkeeperClient) CreateCredentials(ctx context.Context, vaultID int64, login, password string, metadata map[string]string) (SecretInfo, error) {
+	info, err := client.sendSecretJSON(
+		ctx,
 		http.MethodPost,
-		client.baseURL+"/api/secrets",
-		reqBody,
+		client.createSecretURL(vaultID),
+		secretPayload{
+			SecretType: "credentials",
+			Data: credentialsPayload{
+				Login:    login,
+				Password: password,
+			},
+			Metadata: metadata,
+		},
+		http.StatusCreated,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return info, fmt.Errorf("failed to create credentials: %w", err)
 	}
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	req.AddCookie(&http.Cookie{
-		Name:  "jwt",
-		Value: client.jwt,
-	})
 
-	resp, err := client.httpClient.Do(req)
+	return info, nil
+}
+
+func (client *GophkeeperClient) CreateCreditCard(ctx context.Context, vaultID int64, number, name, expiryDateStr, cvv2 string, metadata map[string]string) (SecretInfo, error) {
+	info, err := client.sendSecretJSON(
+		ctx,
+		http.MethodPost,
+		client.createSecretURL(vaultID),
+		secretPayload{
+			SecretType: "credit_card_info",
+			Data: creditCardPayload{
+				Number:     number,
+				Name:       name,
+				ExpiryDate: expiryDateStr,
+				CVV2:       cvv2,
+			},
+			Metadata: metadata,
+		},
+		http.StatusCreated,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to do request: %w", err)
+		return info, fmt.Errorf("failed to create credit card: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New("failed to create credit card")
+	return info, nil
+}
+
+func (client *GophkeeperClient) CreateNote(ctx context.Context, vaultID int64, title, body string, metadata map[string]string) (SecretInfo, error) {
+	info, err := client.sendSecretJSON(
+		ctx,
+		http.MethodPost,
+		client.createSecretURL(vaultID),
+		secretPayload{
+			SecretType: "text",
+			Data: textPayload{
+				Title: title,
+				Body:  body,
+			},
+			Metadata: metadata,
+		},
+		http.StatusCreated,
+	)
+	if err != nil {
+		return info, fmt.Errorf("failed to create note: %w", err)
 	}
 
-	return nil
+	return info, nil
 }
 
-func (client *GophkeeperClient) CreateBinData(ctx context.Context, filename string, fileContent []byte) error {
-	reqBody := &bytes.Buffer{}
-	writer := multipart.NewWriter(reqBody)
-	if err := createFormField(writer, "secret_type", []byte("bin_data")); err != nil {
-		return fmt.Errorf("failed to add secret_type filed: %w", err)
-	}
-	fw, err := writer.CreateFormFile("file", filename)
+// CreateBinData uploads content with the resumable upload protocol.
+// Interrupted uploads are resumed automatically. The secret is created
+// in the vault if vaultID is not zero.
+func (client *GophkeeperClient) CreateBinData(ctx context.Context, vaultID int64, filename string, fileContent io.ReadSeeker, metadata map[string]string) (SecretInfo, error) {
+	info, err := client.uploadBinData(ctx, vaultID, 0, 0, filename, fileContent, metadata)
 	if err != nil {
-		return fmt.Errorf("failed to add file field to form: %w", err)
+		return info, fmt.Errorf("failed to create bin data: %w", err)
 	}
-	_, err = fw.Write(fileContent)
+
+	return info, nil
+}
+
+// UpdateCredentials updates the credentials. If version is not zero, the
+// update fails with ErrSecretChanged if the secret has been changed since it.
+func (client *GophkeeperClient) UpdateCredentials(ctx context.Context, id, version int64, login, password string, metadata map[string]string) (SecretInfo, error) {
+	info, err := client.updateSecret(
+		ctx,
+		id,
+		version,
+		secretPayload{
+			SecretType: "credentials",
+			Data: credentialsPayload{
+				Login:    login,
+				Password: password,
+			},
+			Metadata: metadata,
+		},
+	)
 	if err != nil {
-		return fmt.Errorf("failed to write file content: %w", err)
-	}
-	if err := writer.Close(); err != nil {
-		return fmt.Errorf("failed to create request form: %w", err)
+		return info, fmt.Errorf("failed to update credentials: %w", err)
 	}
 
-	req, err := http.NewRequest(
-		http.MethodPost,
-		client.baseURL+"/api/secrets",
-		reqBody,
+	return info, nil
+}
+
+// UpdateCreditCard updates the credit card, version is checked as in
+// UpdateCredentials.
+func (client *GophkeeperClient) UpdateCreditCard(ctx context.Context, id, version int64, number, name, expiryDate, cvv2 string, metadata map[string]string) (SecretInfo, error) {
+	info, err := client.updateSecret(
+		ctx,
+		id,
+		version,
+		secretPayload{
+			SecretType: "credit_card_info",
+			Data: creditCardPayload{
+				Number:     number,
+				Name:       name,
+				ExpiryDate: expiryDate,
+				CVV2:       cvv2,
+			},
+			Metadata: metadata,
+		},
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return info, fmt.Errorf("failed to update credit card: %w", err)
 	}
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	req.AddCookie(&http.Cookie{
-		Name:  "jwt",
-		Value: client.jwt,
-	})
 
-	resp, err := client.httpClient.Do(req)
+	return info, nil
+}
+
+// UpdateNote updates the note, version is checked as in UpdateCredentials.
+func (client *GophkeeperClient) UpdateNote(ctx context.Context, id, version int64, title, body string, metadata map[string]string) (SecretInfo, error) {
+	info, err := client.updateSecret(
+		ctx,
+		id,
+		version,
+		secretPayload{
+			SecretType: "text",
+			Data: textPayload{
+				Title: title,
+				Body:  body,
+			},
+			Metadata: metadata,
+		},
+	)
 	if err != nil {
-		return fmt.Errorf("failed to do request: %w", err)
+		return info, fmt.Errorf("failed to update note: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New("failed to create bin data")
+	return info, nil
+}
+
+// UpdateBinData uploads new content of the secret with the resumable
+// upload protocol. Interrupted uploads are resumed automatically. Version
+// is checked as in UpdateCredentials.
+func (client *GophkeeperClient) UpdateBinData(ctx context.Context, id, version int64, filename string, fileContent io.ReadSeeker, metadata map[string]string) (SecretInfo, error) {
+	info, err := client.uploadBinData(ctx, 0, id, version, filename, fileContent, metadata)
+	if err != nil {
+		return info, fmt.Errorf("failed to update bin data: %w", err)
 	}
 
-	return nil
+	return info, nil
 }
 
-func (client *GophkeeperClient) UpdateCredentials(ctx context.Context, id int64, login, password string) error {
-	reqBody := &bytes.Buffer{}
-	writer := multipart.NewWriter(reqBody)
-	if err := createFormField(writer, "secret_type", []byte("credentials")); err != nil {
-		return fmt.Errorf("failed to add secret_type field: %w", err)
+// UpdateSecretMetadata replaces secret metadata without changing its data.
+// Empty metadata removes it. Version is checked as in UpdateCredentials.
+func (client *GophkeeperClient) UpdateSecretMetadata(ctx context.Context, id, version int64, metadata map[string]string) (SecretInfo, error) {
+	if metadata == nil {
+		metadata = map[string]string{}
 	}
-	if err := createFormField(writer, "login", []byte(login)); err != nil {
-		return fmt.Errorf("failed to add login field: %w", err)
+	reqBody, err := json.Marshal(secretMetadataPayload{Metadata: metadata})
+	if err != nil {
+		return SecretInfo{}, fmt.Errorf("failed encode request body: %w", err)
 	}
-	if err := createFormField(writer, "password", []byte(password)); err != nil {
-		return fmt.Errorf("failed to add password filed: %w", err)
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPut,
+		fmt.Sprintf("%s/api/secrets/%d/metadata", client.baseURL, id),
+		bytes.NewReader(reqBody),
+	)
+	if err != nil {
+		return SecretInfo{}, fmt.Errorf("failed to create request: %w", err)
 	}
-	if err := writer.Close(); err != nil {
-		return fmt.Errorf("failed to create request form: %w", err)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	setIfMatch(req, version)
+
+	info, err := client.doSecretRequest(req, http.StatusOK)
+	if errors.Is(err, errPreconditionFailed) {
+		err = ErrSecretChanged{ID: id, Version: version}
+	}
+	if err != nil {
+		return info, fmt.Errorf("failed to update secret metadata: %w", err)
 	}
 
-	req, err := http.NewRequest(
-		http.MethodPatch,
-		fmt.Sprintf("%s/api/secrets/%d", client.baseURL, id),
-		reqBody,
-	)
+	return info, nil
+}
+
+// DeleteSecret moves the secret to the trash or deletes it permanently. If
+// version is not zero, the secret is not deleted and ErrSecretChanged is
+// returned if the secret has been changed since it.
+func (client *GophkeeperClient) DeleteSecret(ctx context.Context, id int64, permanent bool, version int64) error {
+	deleteURL := fmt.Sprintf("%s/api/secrets/%d", client.baseURL, id)
+	if permanent {
+		deleteURL += "?permanent=true"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, deleteURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	req.AddCookie(&http.Cookie{
-		Name:  "jwt",
-		Value: client.jwt,
-	})
+	client.authorize(req)
+	setIfMatch(req, version)
+
 	resp, err := client.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to do request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return ErrSecretChanged{ID: id, Version: version}
+	}
 	if resp.StatusCode != http.StatusOK {
-		return errors.New("failed to update creadentials")
+		return errors.New("failed to delete secret")
 	}
 
 	return nil
 }
 
-func (client *GophkeeperClient) UpdateCreditCard(ctx context.Context, id int64, number, name, expiryDate, cvv2 string) error {
-	reqBody := &bytes.Buffer{}
-	writer := multipart.NewWriter(reqBody)
-	if err := createFormField(writer, "secret_type", []byte("credit_card_info")); err != nil {
-		return fmt.Errorf("failed to add secret_type field: %w", err)
-	}
-	if err := createFormField(writer, "credit_card_number", []byte(number)); err != nil {
-		return fmt.Errorf("failed to add credit_card_number field: %w", err)
-	}
-	if err := createFormField(writer, "credit_card_name", []byte(name)); err != nil {
-		return fmt.Errorf("failed to add credit_card_name field: %w", err)
-	}
-	if err := createFormField(writer, "credit_card_expiry_date", []byte(expiryDate)); err != nil {
-		return fmt.Errorf("failed to add credit_card_expirty_date field: %w", err)
-	}
-	if err := createFormField(writer, "credit_card_cvv2", []byte(cvv2)); err != nil {
-		return fmt.Errorf("failed to add credit_card_cvv2 field: %w", err)
-	}
-	if err := writer.Close(); err != nil {
-		return fmt.Errorf("failed to create request form: %w", err)
+// parseSHA256Digest returns the SHA-256 hash from the Repr-Digest header
+// or nil if there is none.
+func parseSHA256Digest(header string) []byte {
+	for _, digest := range strings.Split(header, ",") {
+		algorithm, value, ok := strings.Cut(strings.TrimSpace(digest), "=")
+		if !ok || algorithm != "sha-256" {
+			continue
+		}
+		hash, err := base64.StdEncoding.DecodeString(strings.Trim(value, ":"))
+		if err == nil {
+			return hash
+		}
 	}
 
-	req, err := http.NewRequest(
-		http.MethodPatch,
-		fmt.Sprintf("%s/api/secrets/%d", client.baseURL, id),
-		reqBody,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	return nil
+}
+
+func (client *GophkeeperClient) SetJWT(jwt string) {
+	client.jwt = jwt
+}
+
+// SetAPIToken sets the API token or the service account access token
+// requests are authenticated with if no JWT is set.
+func (client *GophkeeperClient) SetAPIToken(token string) {
+	client.apiToken = token
+}
+
+// authorize authenticates the request with the JWT or, if it is not set,
+// with the API token.
+func (client *GophkeeperClient) authorize(req *http.Request) {
+	if client.jwt == "" && client.apiToken != "" {
+		req.Header.Set("Authorization", "Bearer "+client.apiToken)
+		return
 	}
-	req.Header.Add("Content-Type", writer.FormDataContentType())
 	req.AddCookie(&http.Cookie{
 		Name:  "jwt",
 		Value: client.jwt,
 	})
+}
 
-	resp, err := client.httpClient.Do(req)
+// SetUploadStore sets the store of unfinished uploads, which allows to
+// resume uploads interrupted in previous runs.
+func (client *GophkeeperClient) SetUploadStore(store UploadStore) {
+	client.uploads = store
+}
+
+func (client *GophkeeperClient) sendSecretJSON(
+	ctx context.Context,
+	method string,
+	url string,
+	payload interface{},
+	expectedStatus int) (SecretInfo, error) {
+
+	reqBody, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to do request: %w", err)
+		return SecretInfo{}, fmt.Errorf("failed encode request body: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return errors.New("failed to update credit card")
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return SecretInfo{}, fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 
-	return nil
+	return client.doSecretRequest(req, expectedStatus)
 }
 
-func (client *GophkeeperClient) UpdateBinData(ctx context.Context, id int64, filename string, fileContent []byte) error {
-	reqBody := &bytes.Buffer{}
-	writer := multipart.NewWriter(reqBody)
-	if err := createFormField(writer, "secret_type", []byte("bin_data")); err != nil {
-		return fmt.Errorf("failed to add secret_type filed: %w", err)
-	}
-	fw, err := writer.CreateFormFile("file", filename)
-	if err != nil {
-		return fmt.Errorf("failed to add file field to form: %w", err)
-	}
-	_, err = fw.Write(fileContent)
+// updateSecret replaces the secret data, the request is conditional on
+// version if it is not zero.
+func (client *GophkeeperClient) updateSecret(ctx context.Context, id, version int64, payload secretPayload) (SecretInfo, error) {
+	reqBody, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to write file content: %w", err)
+		return SecretInfo{}, fmt.Errorf("failed encode request body: %w", err)
 	}
-	if err := writer.Close(); err != nil {
-		return fmt.Errorf("failed to create request form: %w", err)
-	}
-
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodPatch,
 		fmt.Sprintf("%s/api/secrets/%d", client.baseURL, id),
-		reqBody,
+		bytes.NewReader(reqBody),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return SecretInfo{}, fmt.Errorf("failed to create request: %w", err)
 	}
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	req.AddCookie(&http.Cookie{
-		Name:  "jwt",
-		Value: client.jwt,
-	})
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	setIfMatch(req, version)
 
-	resp, err := client.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to do request: %w", err)
+	info, err := client.doSecretRequest(req, http.StatusOK)
+	if errors.Is(err, errPreconditionFailed) {
+		return info, ErrSecretChanged{ID: id, Version: version}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New("failed to update bin data")
-	}
-
-	return nil
+	return info, err
 }
 
-func (client *GophkeeperClient) DeleteSecret(ctx context.Context, id int64) error {
-	req, err := http.NewRequest(
-		http.MethodDelete,
-		fmt.Sprintf("%s/api/secrets/%d", client.baseURL, id),
-		nil,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	req.AddCookie(&http.Cookie{
-		Name:  "jwt",
-		Value: client.jwt,
-	})
+func (client *GophkeeperClient) doSecretRequest(req *http.Request, expectedStatus int) (SecretInfo, error) {
+	req.Header.Set("Accept", "application/json")
+	client.authorize(req)
 
 	resp, err := client.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to do request: %w", err)
+		return SecretInfo{}, fmt.Errorf("failed to do request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New("failed to delete secret")
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return SecretInfo{}, errPreconditionFailed
+	}
+	if resp.StatusCode != expectedStatus {
+		return SecretInfo{}, fmt.Errorf("unexpected response status=%d", resp.StatusCode)
 	}
 
-	return nil
-}
-
-func (client *GophkeeperClient) SetJWT(jwt string) {
-	client.jwt = jwt
-}
-
-func createFormField(writer *multipart.Writer, name string, value []byte) error {
-	fw, err := writer.CreateFormField(name)
-	if err != nil {
-		return err
+	var info SecretInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return info, fmt.Errorf("failed to decode response: %w", err)
 	}
-	_, err = fw.Write(value)
 
-	return err
+	return info, nil
 }
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrEventStreamClosed is returned by WatchSecrets if the server has closed
+// the event stream. Events may have been lost, secrets should be synced
+// from the last received revision before watching again.
+var ErrEventStreamClosed = errors.New("secret event stream has been closed")
+
+// SecretEvent notifies that the secret has been created, updated or
+// deleted at the revision. Revision is zero for secrets of other users and
+// vaults, which are not synced.
+type SecretEvent struct {
+	Kind     string `json:"-"`
+	ID       int64  `json:"id"`
+	Revision int64  `json:"revision"`
+}
+
+// WatchSecrets calls handle for every change of secrets the user can read
+// until ctx is done, the stream is closed or handle returns an error.
+func (client *GophkeeperClient) WatchSecrets(ctx context.Context, handle func(SecretEvent) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, client.baseURL+"/api/events", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	client.authorize(req)
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to watch secrets status=%d", resp.StatusCode)
+	}
+
+	var kind, data string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// an empty line dispatches the event
+			if data != "" {
+				event := SecretEvent{Kind: kind}
+				if err := json.Unmarshal([]byte(data), &event); err != nil {
+					return fmt.Errorf("failed to decode secret event: %w", err)
+				}
+				if err := handle(event); err != nil {
+					return err
+				}
+			}
+			kind, data = "", ""
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			kind = value
+		case "data":
+			data += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to read secret events: %w", err)
+	}
+
+	return ErrEventStreamClosed
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type Folder struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	ParentID int64  `json:"parent_id"`
+}
+
+type Tag struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// UpdateFolderParams fields are optional, a nil field is not changed.
+type UpdateFolderParams struct {
+	Name *string `json:"name,omitempty"`
+	// ParentID is zero to move the folder to the top level
+	ParentID *int64 `json:"parent_id,omitempty"`
+}
+
+type folderPayload struct {
+	Name     string `json:"name"`
+	ParentID int64  `json:"parent_id,omitempty"`
+}
+
+type tagPayload struct {
+	Name string `json:"name"`
+}
+
+type secretFolderPayload struct {
+	FolderID int64 `json:"folder_id"`
+}
+
+type secretTagsPayload struct {
+	TagIDs []int64 `json:"tag_ids"`
+}
+
+// CreateFolder creates a folder inside the parent folder, zero parentID
+// creates a top level folder.
+func (client *GophkeeperClient) CreateFolder(ctx context.Context, name string, parentID int64) (Folder, error) {
+	var folder Folder
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		client.baseURL+"/api/folders",
+		folderPayload{Name: name, ParentID: parentID},
+		http.StatusCreated,
+		&folder,
+	)
+	if err != nil {
+		return folder, fmt.Errorf("failed to create folder: %w", err)
+	}
+
+	return folder, nil
+}
+
+func (client *GophkeeperClient) ListFolders(ctx context.Context) ([]Folder, error) {
+	var response struct {
+		Folders []Folder `json:"folders"`
+	}
+	err := client.doJSONRequest(ctx, http.MethodGet, client.baseURL+"/api/folders", nil, http.StatusOK, &response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folders: %w", err)
+	}
+
+	return response.Folders, nil
+}
+
+// UpdateFolder renames the folder and moves it into another folder.
+func (client *GophkeeperClient) UpdateFolder(ctx context.Context, id int64, params UpdateFolderParams) (Folder, error) {
+	var folder Folder
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPatch,
+		fmt.Sprintf("%s/api/folders/%d", client.baseURL, id),
+		params,
+		http.StatusOK,
+		&folder,
+	)
+	if err != nil {
+		return folder, fmt.Errorf("failed to update folder: %w", err)
+	}
+
+	return folder, nil
+}
+
+// MoveSecret moves the secret into the folder, zero folderID moves it out
+// of any folder.
+func (client *GophkeeperClient) MoveSecret(ctx context.Context, id int64, folderID int64) (SecretInfo, error) {
+	info, err := client.sendSecretJSON(
+		ctx,
+		http.MethodPut,
+		fmt.Sprintf("%s/api/secrets/%d/folder", client.baseURL, id),
+		secretFolderPayload{FolderID: folderID},
+		http.StatusOK,
+	)
+	if err != nil {
+		return info, fmt.Errorf("failed to move secret: %w", err)
+	}
+
+	return info, nil
+}
+
+func (client *GophkeeperClient) CreateTag(ctx context.Context, name string) (Tag, error) {
+	var tag Tag
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		client.baseURL+"/api/tags",
+		tagPayload{Name: name},
+		http.StatusCreated,
+		&tag,
+	)
+	if err != nil {
+		return tag, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+func (client *GophkeeperClient) ListTags(ctx context.Context) ([]Tag, error) {
+	var response struct {
+		Tags []Tag `json:"tags"`
+	}
+	err := client.doJSONRequest(ctx, http.MethodGet, client.baseURL+"/api/tags", nil, http.StatusOK, &response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	return response.Tags, nil
+}
+
+func (client *GophkeeperClient) RenameTag(ctx context.Context, id int64, name string) (Tag, error) {
+	var tag Tag
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPatch,
+		fmt.Sprintf("%s/api/tags/%d", client.baseURL, id),
+		tagPayload{Name: name},
+		http.StatusOK,
+		&tag,
+	)
+	if err != nil {
+		return tag, fmt.Errorf("failed to rename tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+// SetSecretTags replaces the secret tags, empty tagIDs removes all tags.
+func (client *GophkeeperClient) SetSecretTags(ctx context.Context, id int64, tagIDs []int64) (SecretInfo, error) {
+	if tagIDs == nil {
+		tagIDs = []int64{}
+	}
+	info, err := client.sendSecretJSON(
+		ctx,
+		http.MethodPut,
+		fmt.Sprintf("%s/api/secrets/%d/tags", client.baseURL, id),
+		secretTagsPayload{TagIDs: tagIDs},
+		http.StatusOK,
+	)
+	if err != nil {
+		return info, fmt.Errorf("failed to set secret tags: %w", err)
+	}
+
+	return info, nil
+}
+
+// doJSONRequest sends payload as JSON unless it is nil and decodes the
+// response into result unless it is nil.
+func (client *GophkeeperClient) doJSONRequest(
+	ctx context.Context,
+	method string,
+	url string,
+	payload interface{},
+	expectedStatus int,
+	result interface{}) error {
+
+	var body io.Reader
+	if payload != nil {
+		reqBody, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed encode request body: %w", err)
+		}
+		body = bytes.NewReader(reqBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	req.Header.Set("Accept", "application/json")
+	client.authorize(req)
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		return fmt.Errorf("unexpected response status=%d", resp.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
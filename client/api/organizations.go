@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Organization is an organization the user is a member of, Invited is
+// set if the user has not accepted the invitation yet.
+type Organization struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   int64     `json:"owner_id"`
+	Invited   bool      `json:"invited"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrganizationMember is a member of the organization, Role is "viewer",
+// "editor" or "admin".
+type OrganizationMember struct {
+	UserID    int64     `json:"user_id"`
+	Login     string    `json:"login"`
+	Role      string    `json:"role"`
+	Accepted  bool      `json:"accepted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Vault is a set of secrets shared by members of the organization
+// according to their roles.
+type Vault struct {
+	ID             int64     `json:"id"`
+	OrganizationID int64     `json:"organization_id"`
+	Name           string    `json:"name"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CreateOrganization creates an organization owned by the user.
+func (client *GophkeeperClient) CreateOrganization(ctx context.Context, name string) (Organization, error) {
+	var org Organization
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		client.baseURL+"/api/organizations",
+		struct {
+			Name string `json:"name"`
+		}{Name: name},
+		http.StatusCreated,
+		&org,
+	)
+	if err != nil {
+		return org, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	return org, nil
+}
+
+// ListOrganizations returns organizations the user is a member of or is
+// invited to.
+func (client *GophkeeperClient) ListOrganizations(ctx context.Context) ([]Organization, error) {
+	var response struct {
+		Organizations []Organization `json:"organizations"`
+	}
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodGet,
+		client.baseURL+"/api/organizations",
+		nil,
+		http.StatusOK,
+		&response,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+
+	return response.Organizations, nil
+}
+
+// InviteMember invites the user with the login to the organization with
+// the role, the server gives the editor role if the role is empty.
+func (client *GophkeeperClient) InviteMember(
+	ctx context.Context,
+	orgID int64,
+	login string,
+	role string) (OrganizationMember, error) {
+
+	var member OrganizationMember
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s/api/organizations/%d/members", client.baseURL, orgID),
+		struct {
+			Login string `json:"login"`
+			Role  string `json:"role,omitempty"`
+		}{Login: login, Role: role},
+		http.StatusCreated,
+		&member,
+	)
+	if err != nil {
+		return member, fmt.Errorf("failed to invite organization member: %w", err)
+	}
+
+	return member, nil
+}
+
+// AcceptInvitation makes the user a member of the organization.
+func (client *GophkeeperClient) AcceptInvitation(ctx context.Context, orgID int64) error {
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s/api/organizations/%d/accept", client.baseURL, orgID),
+		nil,
+		http.StatusOK,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to accept organization invitation: %w", err)
+	}
+
+	return nil
+}
+
+// ListMembers returns members and invited users of the organization.
+func (client *GophkeeperClient) ListMembers(ctx context.Context, orgID int64) ([]OrganizationMember, error) {
+	var response struct {
+		Members []OrganizationMember `json:"members"`
+	}
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s/api/organizations/%d/members", client.baseURL, orgID),
+		nil,
+		http.StatusOK,
+		&response,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+
+	return response.Members, nil
+}
+
+// SetMemberRole changes the role of the user in the organization.
+func (client *GophkeeperClient) SetMemberRole(ctx context.Context, orgID int64, userID int64, role string) error {
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPut,
+		fmt.Sprintf("%s/api/organizations/%d/members/%d", client.baseURL, orgID, userID),
+		struct {
+			Role string `json:"role"`
+		}{Role: role},
+		http.StatusOK,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to change organization member role: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveMember removes the user from the organization, which takes away
+// access to all of its vaults.
+func (client *GophkeeperClient) RemoveMember(ctx context.Context, orgID int64, userID int64) error {
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodDelete,
+		fmt.Sprintf("%s/api/organizations/%d/members/%d", client.baseURL, orgID, userID),
+		nil,
+		http.StatusOK,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to remove organization member: %w", err)
+	}
+
+	return nil
+}
+
+func (client *GophkeeperClient) CreateVault(ctx context.Context, orgID int64, name string) (Vault, error) {
+	var vault Vault
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s/api/organizations/%d/vaults", client.baseURL, orgID),
+		struct {
+			Name string `json:"name"`
+		}{Name: name},
+		http.StatusCreated,
+		&vault,
+	)
+	if err != nil {
+		return vault, fmt.Errorf("failed to create vault: %w", err)
+	}
+
+	return vault, nil
+}
+
+// ListVaults returns vaults of all organizations the user is a member of.
+func (client *GophkeeperClient) ListVaults(ctx context.Context) ([]Vault, error) {
+	var response struct {
+		Vaults []Vault `json:"vaults"`
+	}
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodGet,
+		client.baseURL+"/api/vaults",
+		nil,
+		http.StatusOK,
+		&response,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vaults: %w", err)
+	}
+
+	return response.Vaults, nil
+}
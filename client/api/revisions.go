@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SecretRevision is a previous version of a secret.
+type SecretRevision struct {
+	Version     int       `json:"version"`
+	Description string    `json:"description"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type userSettingsPayload struct {
+	RevisionsRetention int `json:"revisions_retention"`
+}
+
+// ListRevisions returns secret revisions, the latest first.
+func (client *GophkeeperClient) ListRevisions(ctx context.Context, id int64) ([]SecretRevision, error) {
+	var response struct {
+		Revisions []SecretRevision `json:"revisions"`
+	}
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s/api/secrets/%d/revisions", client.baseURL, id),
+		nil,
+		http.StatusOK,
+		&response,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secret revisions: %w", err)
+	}
+
+	return response.Revisions, nil
+}
+
+// GetSecretRevision returns the secret as it was at the revision in the
+// same form as GetSecret.
+func (client *GophkeeperClient) GetSecretRevision(ctx context.Context, id int64, version int) (Secret, error) {
+	return client.getSecret(
+		ctx,
+		fmt.Sprintf("%s/api/secrets/%d/revisions/%d", client.baseURL, id, version),
+		GetSecretParams{},
+	)
+}
+
+// RestoreRevision makes the revision the current secret version, the
+// replaced version is kept as the latest revision.
+func (client *GophkeeperClient) RestoreRevision(ctx context.Context, id int64, version int) error {
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s/api/secrets/%d/revisions/%d/restore", client.baseURL, id, version),
+		nil,
+		http.StatusOK,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to restore secret revision: %w", err)
+	}
+
+	return nil
+}
+
+// SetRevisionsRetention sets the number of revisions kept for every
+// secret, zero disables secret history.
+func (client *GophkeeperClient) SetRevisionsRetention(ctx context.Context, count int) error {
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPut,
+		client.baseURL+"/api/user/settings",
+		userSettingsPayload{RevisionsRetention: count},
+		http.StatusOK,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update settings: %w", err)
+	}
+
+	return nil
+}
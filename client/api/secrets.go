@@ -0,0 +1,143 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type SecretInfo struct {
+	ID          int64  `json:"id"`
+	SecretType  string `json:"secret_type"`
+	Description string `json:"description"`
+}
+
+type SecretMetadata struct {
+	ID          int64     `json:"id"`
+	SecretType  string    `json:"secret_type"`
+	Description string    `json:"description"`
+	Size        int64     `json:"size"`
+	FolderID    int64     `json:"folder_id"`
+	Tags        []string  `json:"tags"`
+	Version     int64     `json:"version"`
+	Shared      string    `json:"shared"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SecretsPage struct {
+	Secrets    []SecretMetadata `json:"secrets"`
+	NextCursor int64            `json:"next_cursor"`
+}
+
+type ListSecretsParams struct {
+	Cursor      int64
+	Limit       int
+	SecretType  string
+	Description string
+	// FolderID selects secrets of the folder and all of its subfolders
+	FolderID int64
+	TagID    int64
+	// VaultID selects secrets of the vault instead of the user secrets
+	VaultID int64
+}
+
+// GetSecretsParams selects secrets of the archive, zero values select
+// all secrets of the user. If VaultID is set, secrets of the vault are
+// selected instead.
+type GetSecretsParams struct {
+	FolderID int64
+	TagID    int64
+	VaultID  int64
+}
+
+// Secret is a single decrypted secret. Filename, SHA256 and Metadata are
+// set only for binary data, which the server returns as an attachment,
+// metadata of other secrets is included in Content. Content is streamed
+// from the response body and must be closed by the caller.
+type Secret struct {
+	ContentType string
+	Filename    string
+	ETag        string
+	SHA256      []byte
+	Metadata    map[string]string
+	// NotModified is set if the secret matches GetSecretParams.IfNoneMatch,
+	// Content is empty then.
+	NotModified bool
+	// Partial is set if Content starts from GetSecretParams.Offset.
+	Partial bool
+	Content io.ReadCloser
+}
+
+// GetSecretParams makes a secret request conditional or partial.
+type GetSecretParams struct {
+	// IfNoneMatch is the ETag of the secret the caller already has.
+	IfNoneMatch string
+	// Offset and IfRange request binary data from Offset if its ETag
+	// is still IfRange, otherwise the whole content is returned.
+	Offset  int64
+	IfRange string
+}
+
+type secretPayload struct {
+	SecretType  string      `json:"secret_type"`
+	Description string      `json:"description"`
+	Data        interface{} `json:"data"`
+	// Metadata is omitted if nil, so the secret metadata is kept on update
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type credentialsPayload struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+type secretMetadataPayload struct {
+	Metadata map[string]string `json:"metadata"`
+}
+
+type textPayload struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type creditCardPayload struct {
+	Number     string `json:"number"`
+	Name       string `json:"name"`
+	ExpiryDate string `json:"expiry_date"`
+	CVV2       string `json:"cvv2"`
+}
+
+// ErrSecretChanged is returned if the secret has been changed by another
+// client since the version an update or deletion is based on.
+type ErrSecretChanged struct {
+	ID int64
+	// Version is zero if the version was not given explicitly
+	Version int64
+}
+
+func (err ErrSecretChanged) Error() string {
+	if err.Version == 0 {
+		return fmt.Sprintf("secret with id=%d has been changed by another client, get it and try again", err.ID)
+	}
+
+	return fmt.Sprintf(
+		"secret with id=%d has been changed since version %d by another client, get it and try again",
+		err.ID, err.Version,
+	)
+}
+
+// errPreconditionFailed is returned if the server rejects a conditional
+// request with the 412 status.
+var errPreconditionFailed = errors.New("precondition failed")
+
+// setIfMatch makes the request conditional on the secret version, zero
+// version is not checked.
+func setIfMatch(req *http.Request, version int64) {
+	if version != 0 {
+		req.Header.Set("If-Match", `"`+strconv.FormatInt(version, 10)+`"`)
+	}
+}
@@ -0,0 +1,327 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Grant types and the assertion audience of the service account token
+// endpoint.
+const (
+	clientCredentialsGrant = "client_credentials"
+	jwtBearerGrant         = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	assertionAudience      = "gophkeeper-token"
+	assertionLifetime      = time.Minute
+)
+
+// ServiceAccount is a non-human principal of an organization, ClientID
+// is its login.
+type ServiceAccount struct {
+	ID             int64     `json:"id"`
+	UserID         int64     `json:"user_id"`
+	OrganizationID int64     `json:"organization_id"`
+	Name           string    `json:"name"`
+	ClientID       string    `json:"client_id"`
+	CreatedBy      int64     `json:"created_by"`
+	Disabled       bool      `json:"disabled"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ServiceAccountCredential is a client secret or a public key of
+// the service account, ClientSecret is set in the response to the
+// creation only. The id of a public key is the "kid" header of assertions.
+type ServiceAccountCredential struct {
+	ID           int64     `json:"id"`
+	Type         string    `json:"type"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	PublicKey    string    `json:"public_key,omitempty"`
+	LastUsedAt   time.Time `json:"last_used_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type serviceAccountPayload struct {
+	Name string `json:"name"`
+	Role string `json:"role,omitempty"`
+}
+
+type credentialPayload struct {
+	Type      string `json:"type"`
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+type tokenRequestPayload struct {
+	GrantType    string `json:"grant_type"`
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	Assertion    string `json:"assertion,omitempty"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// CreateServiceAccount creates the service account of the organization
+// with the role, the viewer role is given if role is empty.
+func (client *GophkeeperClient) CreateServiceAccount(
+	ctx context.Context,
+	orgID int64,
+	name string,
+	role string) (ServiceAccount, error) {
+
+	var account ServiceAccount
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		client.serviceAccountsURL(orgID),
+		serviceAccountPayload{Name: name, Role: role},
+		http.StatusCreated,
+		&account,
+	)
+	if err != nil {
+		return account, fmt.Errorf("failed to create service account: %w", err)
+	}
+
+	return account, nil
+}
+
+func (client *GophkeeperClient) ListServiceAccounts(ctx context.Context, orgID int64) ([]ServiceAccount, error) {
+	var response struct {
+		ServiceAccounts []ServiceAccount `json:"service_accounts"`
+	}
+	err := client.doJSONRequest(ctx, http.MethodGet, client.serviceAccountsURL(orgID), nil, http.StatusOK, &response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service accounts: %w", err)
+	}
+
+	return response.ServiceAccounts, nil
+}
+
+func (client *GophkeeperClient) DisableServiceAccount(ctx context.Context, orgID int64, id int64) error {
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s/%d/disable", client.serviceAccountsURL(orgID), id),
+		nil,
+		http.StatusOK,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to disable service account: %w", err)
+	}
+
+	return nil
+}
+
+// CreateServiceAccountSecret creates a client secret of the service
+// account, its value is returned in this response only.
+func (client *GophkeeperClient) CreateServiceAccountSecret(
+	ctx context.Context,
+	orgID int64,
+	id int64) (ServiceAccountCredential, error) {
+
+	return client.createServiceAccountCredential(ctx, orgID, id, credentialPayload{Type: "secret"})
+}
+
+// AddServiceAccountPublicKey adds the PEM encoded Ed25519 or P-256 public
+// key of the service account.
+func (client *GophkeeperClient) AddServiceAccountPublicKey(
+	ctx context.Context,
+	orgID int64,
+	id int64,
+	publicKey string) (ServiceAccountCredential, error) {
+
+	return client.createServiceAccountCredential(
+		ctx,
+		orgID,
+		id,
+		credentialPayload{Type: "public_key", PublicKey: publicKey},
+	)
+}
+
+func (client *GophkeeperClient) ListServiceAccountCredentials(
+	ctx context.Context,
+	orgID int64,
+	id int64) ([]ServiceAccountCredential, error) {
+
+	var response struct {
+		Credentials []ServiceAccountCredential `json:"credentials"`
+	}
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s/%d/credentials", client.serviceAccountsURL(orgID), id),
+		nil,
+		http.StatusOK,
+		&response,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service account credentials: %w", err)
+	}
+
+	return response.Credentials, nil
+}
+
+func (client *GophkeeperClient) DeleteServiceAccountCredential(
+	ctx context.Context,
+	orgID int64,
+	id int64,
+	credentialID int64) error {
+
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodDelete,
+		fmt.Sprintf("%s/%d/credentials/%d", client.serviceAccountsURL(orgID), id, credentialID),
+		nil,
+		http.StatusOK,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete service account credential: %w", err)
+	}
+
+	return nil
+}
+
+// ListServiceAccountAuditEvents returns audit events of the service
+// account, the latest first.
+func (client *GophkeeperClient) ListServiceAccountAuditEvents(
+	ctx context.Context,
+	orgID int64,
+	id int64,
+	params AuditParams) ([]AuditEvent, error) {
+
+	var response struct {
+		Events []AuditEvent `json:"events"`
+	}
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s/%d/audit?%s", client.serviceAccountsURL(orgID), id, params.query().Encode()),
+		nil,
+		http.StatusOK,
+		&response,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service account audit events: %w", err)
+	}
+
+	return response.Events, nil
+}
+
+// AuthenticateServiceAccount exchanges the client secret of the service
+// account for an access token.
+func (client *GophkeeperClient) AuthenticateServiceAccount(
+	ctx context.Context,
+	clientID string,
+	clientSecret string) (string, error) {
+
+	return client.requestServiceAccountToken(ctx, tokenRequestPayload{
+		GrantType:    clientCredentialsGrant,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	})
+}
+
+// AuthenticateServiceAccountWithKey signs an assertion with the PEM encoded
+// Ed25519 or P-256 private key of the service account and exchanges it for
+// an access token, keyID is the id of the public key credential.
+func (client *GophkeeperClient) AuthenticateServiceAccountWithKey(
+	ctx context.Context,
+	clientID string,
+	keyID int64,
+	privateKeyPEM []byte) (string, error) {
+
+	assertion, err := signAssertion(clientID, keyID, privateKeyPEM)
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate service account: %w", err)
+	}
+
+	return client.requestServiceAccountToken(ctx, tokenRequestPayload{
+		GrantType: jwtBearerGrant,
+		Assertion: assertion,
+	})
+}
+
+func (client *GophkeeperClient) requestServiceAccountToken(
+	ctx context.Context,
+	payload tokenRequestPayload) (string, error) {
+
+	var response tokenResponse
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		client.baseURL+"/api/service-accounts/token",
+		payload,
+		http.StatusOK,
+		&response,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate service account: %w", err)
+	}
+
+	return response.AccessToken, nil
+}
+
+func (client *GophkeeperClient) createServiceAccountCredential(
+	ctx context.Context,
+	orgID int64,
+	id int64,
+	payload credentialPayload) (ServiceAccountCredential, error) {
+
+	var credential ServiceAccountCredential
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s/%d/credentials", client.serviceAccountsURL(orgID), id),
+		payload,
+		http.StatusCreated,
+		&credential,
+	)
+	if err != nil {
+		return credential, fmt.Errorf("failed to create service account credential: %w", err)
+	}
+
+	return credential, nil
+}
+
+func (client *GophkeeperClient) serviceAccountsURL(orgID int64) string {
+	return fmt.Sprintf("%s/api/organizations/%d/service-accounts", client.baseURL, orgID)
+}
+
+// signAssertion returns a short-lived assertion of the client with a random
+// id, so it is accepted once.
+func signAssertion(clientID string, keyID int64, privateKeyPEM []byte) (string, error) {
+	var method jwt.SigningMethod
+	var privateKey interface{}
+	if key, err := jwt.ParseEdPrivateKeyFromPEM(privateKeyPEM); err == nil {
+		method, privateKey = jwt.SigningMethodEdDSA, key
+	} else if key, err := jwt.ParseECPrivateKeyFromPEM(privateKeyPEM); err == nil {
+		method, privateKey = jwt.SigningMethodES256, key
+	} else {
+		return "", fmt.Errorf("private key must be a PEM encoded Ed25519 or P-256 key")
+	}
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("failed to generate assertion id: %w", err)
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(method, jwt.RegisteredClaims{
+		Issuer:    clientID,
+		Subject:   clientID,
+		Audience:  jwt.ClaimStrings{assertionAudience},
+		ID:        hex.EncodeToString(jti),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(assertionLifetime)),
+	})
+	token.Header["kid"] = strconv.FormatInt(keyID, 10)
+
+	return token.SignedString(privateKey)
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SessionTokens are the access token (JWT), which expires in minutes, and
+// the refresh token to get new tokens with. A refresh token can be used
+// once.
+type SessionTokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// Session is an active session of the user, Current is set for the
+// session of the JWT.
+type Session struct {
+	ID        int64     `json:"id"`
+	Current   bool      `json:"current"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RefreshSession exchanges the refresh token for new session tokens.
+func (client *GophkeeperClient) RefreshSession(ctx context.Context, refreshToken string) (SessionTokens, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.baseURL+"/api/user/refresh", nil)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.AddCookie(&http.Cookie{
+		Name:  "refresh_token",
+		Value: refreshToken,
+	})
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return SessionTokens{}, errors.New("failed to refresh session: refresh token is invalid or expired")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return SessionTokens{}, fmt.Errorf("unexpected response status=%d", resp.StatusCode)
+	}
+
+	return sessionFromCookies(resp)
+}
+
+// Logout revokes the session of the JWT, its access and refresh tokens
+// can not be used anymore.
+func (client *GophkeeperClient) Logout(ctx context.Context) error {
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		client.baseURL+"/api/user/logout",
+		nil,
+		http.StatusOK,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to logout: %w", err)
+	}
+
+	return nil
+}
+
+func (client *GophkeeperClient) ListSessions(ctx context.Context) ([]Session, error) {
+	var response struct {
+		Sessions []Session `json:"sessions"`
+	}
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodGet,
+		client.baseURL+"/api/user/sessions",
+		nil,
+		http.StatusOK,
+		&response,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	return response.Sessions, nil
+}
+
+func (client *GophkeeperClient) RevokeSession(ctx context.Context, id int64) error {
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodDelete,
+		fmt.Sprintf("%s/api/user/sessions/%d", client.baseURL, id),
+		nil,
+		http.StatusOK,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions revokes all sessions of the user except the session
+// of the JWT and returns their number.
+func (client *GophkeeperClient) RevokeOtherSessions(ctx context.Context) (int64, error) {
+	var response struct {
+		Revoked int64 `json:"revoked"`
+	}
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		client.baseURL+"/api/user/sessions/revoke-others",
+		nil,
+		http.StatusOK,
+		&response,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return response.Revoked, nil
+}
+
+func sessionFromCookies(resp *http.Response) (SessionTokens, error) {
+	var tokens SessionTokens
+	for _, cookie := range resp.Cookies() {
+		switch cookie.Name {
+		case "jwt":
+			tokens.AccessToken = cookie.Value
+		case "refresh_token":
+			tokens.RefreshToken = cookie.Value
+		}
+	}
+	if tokens.AccessToken == "" {
+		return tokens, errors.New("failed to get JWT from response")
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SecretShare is access to a secret given to another user, Access is
+// either "read" or "write".
+type SecretShare struct {
+	UserID    int64     `json:"user_id"`
+	Login     string    `json:"login"`
+	Access    string    `json:"access"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ShareSecret gives the user with the login access to the secret, the
+// access of the existing share is replaced.
+func (client *GophkeeperClient) ShareSecret(ctx context.Context, id int64, login, access string) (SecretShare, error) {
+	var share SecretShare
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s/api/secrets/%d/shares", client.baseURL, id),
+		struct {
+			Login  string `json:"login"`
+			Access string `json:"access"`
+		}{Login: login, Access: access},
+		http.StatusOK,
+		&share,
+	)
+	if err != nil {
+		return share, fmt.Errorf("failed to share secret: %w", err)
+	}
+
+	return share, nil
+}
+
+// ListSecretShares returns users the secret is shared with.
+func (client *GophkeeperClient) ListSecretShares(ctx context.Context, id int64) ([]SecretShare, error) {
+	var response struct {
+		Shares []SecretShare `json:"shares"`
+	}
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s/api/secrets/%d/shares", client.baseURL, id),
+		nil,
+		http.StatusOK,
+		&response,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secret shares: %w", err)
+	}
+
+	return response.Shares, nil
+}
+
+// RevokeShare takes access to the secret away from the user.
+func (client *GophkeeperClient) RevokeShare(ctx context.Context, id int64, userID int64) error {
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodDelete,
+		fmt.Sprintf("%s/api/secrets/%d/shares/%d", client.baseURL, id, userID),
+		nil,
+		http.StatusOK,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke secret share: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// SyncedSecret is the secret state after a change. Data is the secret in
+// the same form as GetSecret returns it, bin data content is not included.
+type SyncedSecret struct {
+	ID          int64           `json:"id"`
+	SecretType  string          `json:"secret_type"`
+	Description string          `json:"description"`
+	FolderID    int64           `json:"folder_id,omitempty"`
+	Revision    int64           `json:"revision"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// SyncChanges are secret changes up to Revision, which should be passed
+// as since on the next Sync. HasMore is set if there are newer changes.
+type SyncChanges struct {
+	Revision int64          `json:"revision"`
+	HasMore  bool           `json:"has_more"`
+	Created  []SyncedSecret `json:"created"`
+	Updated  []SyncedSecret `json:"updated"`
+	Deleted  []int64        `json:"deleted"`
+}
+
+// Sync returns secrets created, updated and deleted after the since revision.
+// Only personal secrets are returned, shared and vault secrets are not.
+func (client *GophkeeperClient) Sync(ctx context.Context, since int64, limit int) (SyncChanges, error) {
+	query := url.Values{}
+	query.Set("since", strconv.FormatInt(since, 10))
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var changes SyncChanges
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodGet,
+		client.baseURL+"/api/sync?"+query.Encode(),
+		nil,
+		http.StatusOK,
+		&changes,
+	)
+	if err != nil {
+		return changes, fmt.Errorf("failed to sync secrets: %w", err)
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrTOTPRequired is returned by AuthenticateUser if the user has enabled
+// TOTP, the login is finished by AuthenticateTOTP with MFAToken.
+type ErrTOTPRequired struct {
+	MFAToken string
+}
+
+func (err ErrTOTPRequired) Error() string {
+	return "TOTP code is required"
+}
+
+// TOTPEnrollment is the TOTP secret to add to an authenticator app, either
+// by the otpauth URI or by the base32 secret.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// AuthenticateTOTP finishes the login of the user with TOTP enabled and
+// returns session tokens. The code is a TOTP code or a recovery code.
+func (client *GophkeeperClient) AuthenticateTOTP(ctx context.Context, mfaToken, code string) (SessionTokens, error) {
+	reqBody, err := json.Marshal(
+		struct {
+			MFAToken string `json:"mfa_token"`
+			Code     string `json:"code"`
+		}{MFAToken: mfaToken, Code: code},
+	)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed encode request body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		client.baseURL+"/api/user/login/totp",
+		bytes.NewReader(reqBody),
+	)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Accept", "application/json; charset=utf-8")
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return SessionTokens{}, fmt.Errorf(
+			"failed to authenticate user: too many failed attempts, retry after %s seconds",
+			resp.Header.Get("Retry-After"),
+		)
+	case http.StatusUnauthorized:
+		return SessionTokens{}, errors.New("failed to authenticate user: invalid code")
+	default:
+		return SessionTokens{}, errors.New("failed to authenticate user")
+	}
+
+	return sessionFromCookies(resp)
+}
+
+// EnrollTOTP returns a new TOTP secret of the user, it is required at
+// login after ConfirmTOTP.
+func (client *GophkeeperClient) EnrollTOTP(ctx context.Context) (TOTPEnrollment, error) {
+	var enrollment TOTPEnrollment
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		client.baseURL+"/api/user/totp",
+		nil,
+		http.StatusOK,
+		&enrollment,
+	)
+	if err != nil {
+		return enrollment, fmt.Errorf("failed to enroll TOTP: %w", err)
+	}
+
+	return enrollment, nil
+}
+
+// ConfirmTOTP enables TOTP with the first code of the enrolled secret and
+// returns one-time recovery codes.
+func (client *GophkeeperClient) ConfirmTOTP(ctx context.Context, code string) ([]string, error) {
+	var response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		client.baseURL+"/api/user/totp/confirm",
+		struct {
+			Code string `json:"code"`
+		}{Code: code},
+		http.StatusOK,
+		&response,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm TOTP: %w", err)
+	}
+
+	return response.RecoveryCodes, nil
+}
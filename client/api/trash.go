@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// TrashedSecret is a deleted secret, which can be restored until the
+// trash is purged.
+type TrashedSecret struct {
+	SecretMetadata
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// ListTrash returns trashed secrets, the latest deleted first.
+func (client *GophkeeperClient) ListTrash(ctx context.Context) ([]TrashedSecret, error) {
+	var response struct {
+		Secrets []TrashedSecret `json:"secrets"`
+	}
+	err := client.doJSONRequest(ctx, http.MethodGet, client.baseURL+"/api/trash", nil, http.StatusOK, &response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	return response.Secrets, nil
+}
+
+// RestoreSecret moves the secret out of the trash.
+func (client *GophkeeperClient) RestoreSecret(ctx context.Context, id int64) (SecretInfo, error) {
+	var info SecretInfo
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s/api/trash/%d/restore", client.baseURL, id),
+		nil,
+		http.StatusOK,
+		&info,
+	)
+	if err != nil {
+		return info, fmt.Errorf("failed to restore secret: %w", err)
+	}
+
+	return info, nil
+}
+
+// PurgeSecret permanently deletes the trashed secret.
+func (client *GophkeeperClient) PurgeSecret(ctx context.Context, id int64) error {
+	err := client.doJSONRequest(
+		ctx,
+		http.MethodDelete,
+		fmt.Sprintf("%s/api/trash/%d", client.baseURL, id),
+		nil,
+		http.StatusOK,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to purge secret: %w", err)
+	}
+
+	return nil
+}
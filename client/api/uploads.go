@@ -0,0 +1,424 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	tusVersion = "1.0.0"
+	// maxUploadRetries is the number of consecutive failed attempts to
+	// continue an upload after which the upload is given up.
+	maxUploadRetries = 5
+	// fingerprintPrefixSize is the size of the content prefix included
+	// into upload fingerprint.
+	fingerprintPrefixSize = 64 << 10
+	uploadRetryDelay      = time.Second
+)
+
+// UploadStore keeps URLs of unfinished uploads by content fingerprint.
+type UploadStore interface {
+	Get(fingerprint string) (string, bool)
+	Set(fingerprint, uploadURL string) error
+	Delete(fingerprint string) error
+}
+
+// FileStateStore keeps string values by key in a JSON file. It is used
+// to keep state of uploads and downloads between runs.
+type FileStateStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{path: path}
+}
+
+func (store *FileStateStore) Get(key string) (string, bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	values, err := store.load()
+	if err != nil {
+		return "", false
+	}
+	value, ok := values[key]
+
+	return value, ok
+}
+
+func (store *FileStateStore) Set(key, value string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	values, err := store.load()
+	if err != nil {
+		return err
+	}
+	values[key] = value
+
+	return store.save(values)
+}
+
+func (store *FileStateStore) Delete(key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	values, err := store.load()
+	if err != nil {
+		return err
+	}
+	delete(values, key)
+
+	return store.save(values)
+}
+
+func (store *FileStateStore) load() (map[string]string, error) {
+	values := make(map[string]string)
+	content, err := os.ReadFile(store.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", store.path, err)
+	}
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", store.path, err)
+	}
+
+	return values, nil
+}
+
+func (store *FileStateStore) save(values map[string]string) error {
+	content, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", store.path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(store.path), 0700); err != nil {
+		return fmt.Errorf("failed to save %s: %w", store.path, err)
+	}
+	if err := os.WriteFile(store.path, content, 0600); err != nil {
+		return fmt.Errorf("failed to save %s: %w", store.path, err)
+	}
+
+	return nil
+}
+
+// uploadStatusError is returned when upload request has unexpected status.
+type uploadStatusError struct {
+	status int
+}
+
+func (err uploadStatusError) Error() string {
+	return fmt.Sprintf("unexpected response status=%d", err.status)
+}
+
+// secretChangedError replaces the precondition failed upload error with
+// ErrSecretChanged.
+func secretChangedError(err error, secretID, version int64) error {
+	var statusErr uploadStatusError
+	if errors.As(err, &statusErr) && statusErr.status == http.StatusPreconditionFailed {
+		return ErrSecretChanged{ID: secretID, Version: version}
+	}
+
+	return err
+}
+
+// uploadState is the state of an upload on the server. Location is the
+// secret location, which is set once the upload is completed.
+type uploadState struct {
+	offset   int64
+	location string
+}
+
+// uploadBinData uploads content of a new secret, which is created in the
+// vault if vaultID is not zero, or, if secretID is not zero, of the
+// existing one. If version is not zero, the upload fails with
+// ErrSecretChanged if the existing secret has been changed since it. Failed requests are retried from the offset
+// reported by the server. If the upload store is set, an upload
+// interrupted in the previous run is continued. Metadata of the existing
+// secret is kept if metadata is nil.
+func (client *GophkeeperClient) uploadBinData(
+	ctx context.Context,
+	vaultID int64,
+	secretID int64,
+	version int64,
+	filename string,
+	content io.ReadSeeker,
+	metadata map[string]string) (SecretInfo, error) {
+
+	length, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return SecretInfo{}, fmt.Errorf("failed to get content length: %w", err)
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return SecretInfo{}, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	fingerprint, err := uploadFingerprint(vaultID, secretID, filename, metadataJSON, length, content)
+	if err != nil {
+		return SecretInfo{}, err
+	}
+
+	uploadURL, state, err := client.resumeUpload(ctx, fingerprint)
+	if err != nil {
+		return SecretInfo{}, err
+	}
+	if uploadURL == "" {
+		uploadURL, err = client.createUpload(ctx, vaultID, secretID, version, filename, metadata, length)
+		if err != nil {
+			return SecretInfo{}, secretChangedError(err, secretID, version)
+		}
+		if client.uploads != nil {
+			if err := client.uploads.Set(fingerprint, uploadURL); err != nil {
+				return SecretInfo{}, err
+			}
+		}
+		if length == 0 {
+			state, err = client.headUpload(ctx, uploadURL)
+			if err != nil {
+				return SecretInfo{}, err
+			}
+		}
+	}
+
+	retries := 0
+	for state.location == "" {
+		state, err = client.patchUpload(ctx, uploadURL, content, state.offset, length)
+		if err == nil {
+			retries = 0
+			continue
+		}
+		for {
+			var statusErr uploadStatusError
+			if errors.As(err, &statusErr) && statusErr.status == http.StatusPreconditionFailed {
+				// the upload can not be completed, so it is not resumed in the next run
+				if client.uploads != nil {
+					if err := client.uploads.Delete(fingerprint); err != nil {
+						return SecretInfo{}, err
+					}
+				}
+				return SecretInfo{}, secretChangedError(err, secretID, version)
+			}
+			if errors.As(err, &statusErr) && statusErr.status < 500 && statusErr.status != http.StatusConflict {
+				return SecretInfo{}, err
+			}
+			if retries++; retries > maxUploadRetries {
+				return SecretInfo{}, err
+			}
+			select {
+			case <-ctx.Done():
+				return SecretInfo{}, ctx.Err()
+			case <-time.After(uploadRetryDelay * time.Duration(retries)):
+			}
+			state, err = client.headUpload(ctx, uploadURL)
+			if err == nil {
+				break
+			}
+		}
+	}
+	if client.uploads != nil {
+		if err := client.uploads.Delete(fingerprint); err != nil {
+			return SecretInfo{}, err
+		}
+	}
+
+	id, err := strconv.ParseInt(path.Base(state.location), 10, 64)
+	if err != nil {
+		return SecretInfo{}, fmt.Errorf("invalid secret location %q", state.location)
+	}
+
+	return SecretInfo{ID: id, SecretType: "bin_data"}, nil
+}
+
+// resumeUpload returns URL and state of the stored upload. Empty URL is
+// returned if there is no upload to resume.
+func (client *GophkeeperClient) resumeUpload(ctx context.Context, fingerprint string) (string, uploadState, error) {
+	if client.uploads == nil {
+		return "", uploadState{}, nil
+	}
+	uploadURL, ok := client.uploads.Get(fingerprint)
+	if !ok {
+		return "", uploadState{}, nil
+	}
+	state, err := client.headUpload(ctx, uploadURL)
+	if err != nil {
+		var statusErr uploadStatusError
+		if errors.As(err, &statusErr) && statusErr.status < 500 {
+			// the upload has expired or belongs to another user
+			return "", uploadState{}, client.uploads.Delete(fingerprint)
+		}
+		return "", uploadState{}, err
+	}
+
+	return uploadURL, state, nil
+}
+
+func (client *GophkeeperClient) createUpload(
+	ctx context.Context,
+	vaultID int64,
+	secretID int64,
+	version int64,
+	filename string,
+	secretMetadata map[string]string,
+	length int64) (string, error) {
+
+	metadata := []string{"filename " + base64.StdEncoding.EncodeToString([]byte(filename))}
+	if secretID != 0 {
+		metadata = append(
+			metadata,
+			"secret_id "+base64.StdEncoding.EncodeToString([]byte(strconv.FormatInt(secretID, 10))),
+		)
+	}
+	if vaultID != 0 {
+		metadata = append(
+			metadata,
+			"vault_id "+base64.StdEncoding.EncodeToString([]byte(strconv.FormatInt(vaultID, 10))),
+		)
+	}
+	if secretMetadata != nil {
+		metadataJSON, err := json.Marshal(secretMetadata)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode metadata: %w", err)
+		}
+		metadata = append(metadata, "metadata "+base64.StdEncoding.EncodeToString(metadataJSON))
+	}
+	req, err := client.newUploadRequest(ctx, http.MethodPost, client.baseURL+"/api/uploads", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Upload-Length", strconv.FormatInt(length, 10))
+	req.Header.Set("Upload-Metadata", strings.Join(metadata, ","))
+	setIfMatch(req, version)
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", uploadStatusError{status: resp.StatusCode}
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", errors.New("upload location is missing")
+	}
+
+	return client.baseURL + location, nil
+}
+
+func (client *GophkeeperClient) headUpload(ctx context.Context, uploadURL string) (uploadState, error) {
+	req, err := client.newUploadRequest(ctx, http.MethodHead, uploadURL, nil)
+	if err != nil {
+		return uploadState{}, err
+	}
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return uploadState{}, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return uploadState{}, uploadStatusError{status: resp.StatusCode}
+	}
+
+	return parseUploadState(resp)
+}
+
+// patchUpload sends content from offset to the end.
+func (client *GophkeeperClient) patchUpload(
+	ctx context.Context,
+	uploadURL string,
+	content io.ReadSeeker,
+	offset int64,
+	length int64) (uploadState, error) {
+
+	if _, err := content.Seek(offset, io.SeekStart); err != nil {
+		return uploadState{}, fmt.Errorf("failed to seek content: %w", err)
+	}
+	body := io.NopCloser(io.LimitReader(content, length-offset))
+	req, err := client.newUploadRequest(ctx, http.MethodPatch, uploadURL, body)
+	if err != nil {
+		return uploadState{}, err
+	}
+	req.ContentLength = length - offset
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return uploadState{}, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return uploadState{}, uploadStatusError{status: resp.StatusCode}
+	}
+
+	return parseUploadState(resp)
+}
+
+func (client *GophkeeperClient) newUploadRequest(
+	ctx context.Context,
+	method string,
+	url string,
+	body io.Reader) (*http.Request, error) {
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Tus-Resumable", tusVersion)
+	client.authorize(req)
+
+	return req, nil
+}
+
+func parseUploadState(resp *http.Response) (uploadState, error) {
+	offset, err := strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return uploadState{}, fmt.Errorf("invalid upload offset: %w", err)
+	}
+
+	return uploadState{
+		offset:   offset,
+		location: resp.Header.Get("Location"),
+	}, nil
+}
+
+// uploadFingerprint identifies upload content by its target, name,
+// metadata, length and prefix.
+func uploadFingerprint(
+	vaultID int64,
+	secretID int64,
+	filename string,
+	metadataJSON []byte,
+	length int64,
+	content io.ReadSeeker) (string, error) {
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to seek content: %w", err)
+	}
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d\n%d\n%s\n%s\n%d\n", vaultID, secretID, filename, metadataJSON, length)
+	if _, err := io.CopyN(hash, content, fingerprintPrefixSize); err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read content: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type APITokenManager interface {
+	CreateAPIToken(ctx context.Context, params api.CreateAPITokenParams) (api.APIToken, error)
+	ListAPITokens(ctx context.Context) ([]api.APIToken, error)
+	RevokeAPIToken(ctx context.Context, id int64) error
+	SetJWT(jwt string)
+}
+
+type APITokenCmd struct {
+	manager APITokenManager
+	stdout  io.Writer
+}
+
+func NewAPITokenCmd(manager APITokenManager, stdout io.Writer) APITokenCmd {
+	return APITokenCmd{
+		manager: manager,
+		stdout:  stdout,
+	}
+}
+
+// Execute creates an API token if params.Name is set and prints its value,
+// revokes the token with revokeID if it is set, otherwise it lists API
+// tokens of the user.
+func (tokenCmd APITokenCmd) Execute(params api.CreateAPITokenParams, revokeID int64, jwt string) error {
+	tokenCmd.manager.SetJWT(jwt)
+	if params.Name != "" {
+		token, err := tokenCmd.manager.CreateAPIToken(context.TODO(), params)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tokenCmd.stdout, "id=%d\ntoken=%s\n", token.ID, token.Token)
+		return nil
+	}
+	if revokeID != 0 {
+		return tokenCmd.manager.RevokeAPIToken(context.TODO(), revokeID)
+	}
+
+	tokens, err := tokenCmd.manager.ListAPITokens(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(tokenCmd.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tNAME\tSCOPE\tEXPIRES AT\tLAST USED AT\tCREATED AT")
+	for _, token := range tokens {
+		fmt.Fprintf(
+			writer,
+			"%d\t%s\t%s\t%s\t%s\t%s\n",
+			token.ID,
+			token.Name,
+			formatTokenScope(token),
+			formatOptionalTime(token.ExpiresAt, "never"),
+			formatOptionalTime(token.LastUsedAt, "never"),
+			token.CreatedAt.Local().Format(time.DateTime),
+		)
+	}
+
+	return writer.Flush()
+}
+
+func formatTokenScope(token api.APIToken) string {
+	scope := []string{"read-write"}
+	if token.ReadOnly {
+		scope[0] = "read-only"
+	}
+	if len(token.SecretTypes) > 0 {
+		scope = append(scope, "types="+strings.Join(token.SecretTypes, ","))
+	}
+	if len(token.SecretIDs) > 0 {
+		ids := make([]string, len(token.SecretIDs))
+		for i, id := range token.SecretIDs {
+			ids[i] = strconv.FormatInt(id, 10)
+		}
+		scope = append(scope, "secrets="+strings.Join(ids, ","))
+	}
+
+	return strings.Join(scope, " ")
+}
+
+func formatOptionalTime(t time.Time, zero string) string {
+	if t.IsZero() {
+		return zero
+	}
+
+	return t.Local().Format(time.DateTime)
+}
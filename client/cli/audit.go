@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type AuditLog interface {
+	ListAuditEvents(ctx context.Context, params api.AuditParams) ([]api.AuditEvent, error)
+	SetJWT(jwt string)
+}
+
+type AuditCmd struct {
+	log    AuditLog
+	stdout io.Writer
+}
+
+func NewAuditCmd(log AuditLog, stdout io.Writer) AuditCmd {
+	return AuditCmd{
+		log:    log,
+		stdout: stdout,
+	}
+}
+
+// Execute prints audit events matching params.
+func (auditCmd AuditCmd) Execute(params api.AuditParams, jwt string) error {
+	auditCmd.log.SetJWT(jwt)
+	events, err := auditCmd.log.ListAuditEvents(context.TODO(), params)
+	if err != nil {
+		return err
+	}
+
+	return printAuditEvents(auditCmd.stdout, events)
+}
+
+func printAuditEvents(stdout io.Writer, events []api.AuditEvent) error {
+	writer := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tTIME\tUSER ID\tACTION\tSECRET ID\tCLIENT IP\tUSER AGENT")
+	for _, event := range events {
+		fmt.Fprintf(
+			writer,
+			"%d\t%s\t%d\t%s\t%d\t%s\t%s\n",
+			event.ID,
+			event.CreatedAt.Local().Format(time.DateTime),
+			event.UserID,
+			event.Action,
+			event.SecretID,
+			event.ClientIP,
+			event.UserAgent,
+		)
+	}
+
+	return writer.Flush()
+}
@@ -1,25 +1,51 @@
 package cli
 
-import "context"
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
 
 type UserAuthenticator interface {
-	AuthenticateUser(ctx context.Context, login, password string) (string, error)
+	AuthenticateUser(ctx context.Context, login, password string) (api.SessionTokens, error)
+	AuthenticateTOTP(ctx context.Context, mfaToken, code string) (api.SessionTokens, error)
 }
 
 type AuthenticateCmd struct {
 	usrAuth UserAuthenticator
+	stdin   io.Reader
+	stdout  io.Writer
 }
 
-func NewAuthenticateCmd(usrAuth UserAuthenticator) AuthenticateCmd {
+func NewAuthenticateCmd(usrAuth UserAuthenticator, stdin io.Reader, stdout io.Writer) AuthenticateCmd {
 	return AuthenticateCmd{
 		usrAuth: usrAuth,
+		stdin:   stdin,
+		stdout:  stdout,
 	}
 }
 
-func (authCmd AuthenticateCmd) Execute(login, password string) (string, error) {
-	return authCmd.usrAuth.AuthenticateUser(
+// Execute returns session tokens of the user. If the user has enabled
+// TOTP, the code is asked for unless it is given.
+func (authCmd AuthenticateCmd) Execute(login, password, code string) (api.SessionTokens, error) {
+	tokens, err := authCmd.usrAuth.AuthenticateUser(
 		context.TODO(),
 		login,
 		password,
 	)
+	var totpErr api.ErrTOTPRequired
+	if !errors.As(err, &totpErr) {
+		return tokens, err
+	}
+
+	if code == "" {
+		code, err = promptLine(authCmd.stdin, authCmd.stdout, "TOTP code or recovery code: ")
+		if err != nil {
+			return api.SessionTokens{}, err
+		}
+	}
+
+	return authCmd.usrAuth.AuthenticateTOTP(context.TODO(), totpErr.MFAToken, code)
 }
@@ -3,12 +3,15 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
 )
 
 type BinDataCreator interface {
-	CreateBinData(ctx context.Context, filename string, filecontent []byte) error
+	CreateBinData(ctx context.Context, vaultID int64, filename string, filecontent io.ReadSeeker, metadata map[string]string) (api.SecretInfo, error)
 	SetJWT(jwt string)
 }
 
@@ -22,15 +25,18 @@ func NewCreateBinDataCmd(creator BinDataCreator) CreateBinDataCmd {
 	}
 }
 
-func (createCmd CreateBinDataCmd) Execute(filePath, jwt string) error {
-	fileContent, err := os.ReadFile(filePath)
+func (createCmd CreateBinDataCmd) Execute(vaultID int64, filePath string, metadata map[string]string, jwt string) (api.SecretInfo, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read %s: %w", filePath, err)
+		return api.SecretInfo{}, fmt.Errorf("failed to open %s: %w", filePath, err)
 	}
+	defer file.Close()
 	createCmd.creator.SetJWT(jwt)
 	return createCmd.creator.CreateBinData(
 		context.TODO(),
+		vaultID,
 		filepath.Base(filePath),
-		fileContent,
+		file,
+		metadata,
 	)
 }
@@ -1,9 +1,13 @@
 package cli
 
-import "context"
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
 
 type CreditCardCreator interface {
-	CreateCreditCard(ctx context.Context, number, name, expiryDateStr, cvv2 string) error
+	CreateCreditCard(ctx context.Context, vaultID int64, number, name, expiryDateStr, cvv2 string, metadata map[string]string) (api.SecretInfo, error)
 	SetJWT(jwtStr string)
 }
 
@@ -17,13 +21,15 @@ func NewCreateCreditCardCmd(creator CreditCardCreator) CreateCreditCardCmd {
 	}
 }
 
-func (createCmd CreateCreditCardCmd) Execute(number, name, expiryDateStr, cvv2, jwtStr string) error {
+func (createCmd CreateCreditCardCmd) Execute(vaultID int64, number, name, expiryDateStr, cvv2 string, metadata map[string]string, jwtStr string) (api.SecretInfo, error) {
 	createCmd.creator.SetJWT(jwtStr)
 	return createCmd.creator.CreateCreditCard(
 		context.TODO(),
+		vaultID,
 		number,
 		name,
 		expiryDateStr,
 		cvv2,
+		metadata,
 	)
 }
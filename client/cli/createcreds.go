@@ -1,9 +1,13 @@
 package cli
 
-import "context"
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
 
 type CredentialsCreator interface {
-	CreateCredentials(ctx context.Context, login, password string) error
+	CreateCredentials(ctx context.Context, vaultID int64, login, password string, metadata map[string]string) (api.SecretInfo, error)
 	SetJWT(jwt string)
 }
 
@@ -17,11 +21,13 @@ func NewCreateCredentialsCmd(creator CredentialsCreator) CreateCredentialsCmd {
 	}
 }
 
-func (createCmd CreateCredentialsCmd) Execute(login, password, jwtStr string) error {
+func (createCmd CreateCredentialsCmd) Execute(vaultID int64, login, password string, metadata map[string]string, jwtStr string) (api.SecretInfo, error) {
 	createCmd.creator.SetJWT(jwtStr)
 	return createCmd.creator.CreateCredentials(
 		context.TODO(),
+		vaultID,
 		login,
 		password,
+		metadata,
 	)
 }
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type FolderCreator interface {
+	CreateFolder(ctx context.Context, name string, parentID int64) (api.Folder, error)
+	SetJWT(jwt string)
+}
+
+type CreateFolderCmd struct {
+	creator FolderCreator
+}
+
+func NewCreateFolderCmd(creator FolderCreator) CreateFolderCmd {
+	return CreateFolderCmd{
+		creator: creator,
+	}
+}
+
+func (createCmd CreateFolderCmd) Execute(name string, parentID int64, jwt string) (api.Folder, error) {
+	createCmd.creator.SetJWT(jwt)
+	return createCmd.creator.CreateFolder(context.TODO(), name, parentID)
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type NoteCreator interface {
+	CreateNote(ctx context.Context, vaultID int64, title, body string, metadata map[string]string) (api.SecretInfo, error)
+	SetJWT(jwt string)
+}
+
+type CreateNoteCmd struct {
+	creator NoteCreator
+	stdin   io.Reader
+}
+
+func NewCreateNoteCmd(creator NoteCreator, stdin io.Reader) CreateNoteCmd {
+	return CreateNoteCmd{
+		creator: creator,
+		stdin:   stdin,
+	}
+}
+
+// Execute reads the note body from bodyPath or from stdin if bodyPath is empty or "-".
+func (createCmd CreateNoteCmd) Execute(vaultID int64, title, bodyPath string, metadata map[string]string, jwt string) (api.SecretInfo, error) {
+	body, err := readNoteBody(bodyPath, createCmd.stdin)
+	if err != nil {
+		return api.SecretInfo{}, err
+	}
+	createCmd.creator.SetJWT(jwt)
+	return createCmd.creator.CreateNote(
+		context.TODO(),
+		vaultID,
+		title,
+		body,
+		metadata,
+	)
+}
+
+func readNoteBody(bodyPath string, stdin io.Reader) (string, error) {
+	if bodyPath == "" || bodyPath == "-" {
+		body, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read note body: %w", err)
+		}
+		return string(body), nil
+	}
+
+	body, err := os.ReadFile(bodyPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", bodyPath, err)
+	}
+
+	return string(body), nil
+}
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type TagCreator interface {
+	CreateTag(ctx context.Context, name string) (api.Tag, error)
+	SetJWT(jwt string)
+}
+
+type CreateTagCmd struct {
+	creator TagCreator
+}
+
+func NewCreateTagCmd(creator TagCreator) CreateTagCmd {
+	return CreateTagCmd{
+		creator: creator,
+	}
+}
+
+func (createCmd CreateTagCmd) Execute(name string, jwt string) (api.Tag, error) {
+	createCmd.creator.SetJWT(jwt)
+	return createCmd.creator.CreateTag(context.TODO(), name)
+}
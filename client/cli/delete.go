@@ -3,7 +3,7 @@ package cli
 import "context"
 
 type SecretDeleter interface {
-	DeleteSecret(ctx context.Context, id int64) error
+	DeleteSecret(ctx context.Context, id int64, permanent bool, version int64) error
 	SetJWT(jwt string)
 }
 
@@ -17,10 +17,12 @@ func NewDeleteSecretCmd(deleter SecretDeleter) DeleteSecretCmd {
 	}
 }
 
-func (delCmd DeleteSecretCmd) Execute(id int64, jwt string) error {
+func (delCmd DeleteSecretCmd) Execute(id int64, permanent bool, version int64, jwt string) error {
 	delCmd.deleter.SetJWT(jwt)
 	return delCmd.deleter.DeleteSecret(
 		context.TODO(),
 		id,
+		permanent,
+		version,
 	)
 }
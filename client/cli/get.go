@@ -3,11 +3,14 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
 )
 
 type SecretFetcher interface {
-	GetSecrets(ctx context.Context) ([]byte, error)
+	GetSecrets(ctx context.Context, params api.GetSecretsParams, w io.Writer) error
 	SetJWT(jwt string)
 }
 
@@ -15,21 +18,25 @@ type GetSecretsCmd struct {
 	fetcher SecretFetcher
 }
 
-func NewGetSecretCmd(fetcher SecretFetcher) GetSecretsCmd {
+func NewGetSecretsCmd(fetcher SecretFetcher) GetSecretsCmd {
 	return GetSecretsCmd{
 		fetcher: fetcher,
 	}
 }
 
-func (getCmd GetSecretsCmd) Execute(archiveFilename, jwt string) error {
+func (getCmd GetSecretsCmd) Execute(archiveFilename string, params api.GetSecretsParams, jwt string) error {
 	getCmd.fetcher.SetJWT(jwt)
-	archiveContent, err := getCmd.fetcher.GetSecrets(context.TODO())
+	archive, err := os.OpenFile(archiveFilename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	err = getCmd.fetcher.GetSecrets(context.TODO(), params, archive)
+	if closeErr := archive.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to save archive: %w", closeErr)
 	}
-	err = os.WriteFile(archiveFilename, archiveContent, 0666)
 	if err != nil {
-		return fmt.Errorf("failed to save archive: %w", err)
+		os.Remove(archiveFilename)
+		return err
 	}
 
 	return nil
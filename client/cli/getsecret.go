@@ -0,0 +1,237 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+// partSuffix is appended to the name of a file being downloaded.
+const partSuffix = ".part"
+
+type SecretGetter interface {
+	GetSecret(ctx context.Context, id int64, params api.GetSecretParams) (api.Secret, error)
+	SetJWT(jwt string)
+}
+
+// DownloadStore keeps state of bin data downloads by secret ID.
+type DownloadStore interface {
+	Get(key string) (string, bool)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+type GetSecretCmd struct {
+	getter    SecretGetter
+	downloads DownloadStore
+	stdout    io.Writer
+}
+
+func NewGetSecretCmd(getter SecretGetter, downloads DownloadStore, stdout io.Writer) GetSecretCmd {
+	return GetSecretCmd{
+		getter:    getter,
+		downloads: downloads,
+		stdout:    stdout,
+	}
+}
+
+// downloadState is the state of the last bin data download. Until the
+// download is completed the content is saved to Path with partSuffix.
+type downloadState struct {
+	ETag     string `json:"etag"`
+	Path     string `json:"path"`
+	Complete bool   `json:"complete"`
+}
+
+// Execute prints credentials and credit cards to stdout unless output is set.
+// Binary data is always saved to a file, named after the original file by default.
+// An interrupted bin data download is resumed and a file which has not
+// changed since the last download is not downloaded again.
+func (getCmd GetSecretCmd) Execute(id int64, output, jwt string) error {
+	getCmd.getter.SetJWT(jwt)
+	key := strconv.FormatInt(id, 10)
+	state, hasState := getCmd.loadState(key)
+	if hasState && output != "" {
+		outputPath, err := filepath.Abs(output)
+		hasState = err == nil && outputPath == state.Path
+	}
+
+	var params api.GetSecretParams
+	if hasState && state.Complete {
+		if _, err := os.Stat(state.Path); err == nil {
+			params.IfNoneMatch = state.ETag
+		}
+	} else if hasState {
+		if info, err := os.Stat(state.Path + partSuffix); err == nil {
+			params.Offset = info.Size()
+			params.IfRange = state.ETag
+		}
+	}
+	secret, err := getCmd.getter.GetSecret(context.TODO(), id, params)
+	if errors.Is(err, api.ErrRangeNotSatisfiable) {
+		secret, err = getCmd.getter.GetSecret(context.TODO(), id, api.GetSecretParams{})
+	}
+	if err != nil {
+		return err
+	}
+	defer secret.Content.Close()
+
+	if secret.NotModified {
+		if _, err := fmt.Fprintf(getCmd.stdout, "%s is up to date\n", state.Path); err != nil {
+			return err
+		}
+		return getCmd.printMetadata(secret.Metadata)
+	}
+	if secret.Filename == "" {
+		return getCmd.saveSecret(output, secret)
+	}
+
+	path := output
+	if secret.Partial {
+		path = state.Path
+	} else if path == "" {
+		path = filepath.Base(secret.Filename)
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to save secret: %w", err)
+	}
+
+	if err := getCmd.saveBinData(key, path, secret); err != nil {
+		return err
+	}
+
+	return getCmd.printMetadata(secret.Metadata)
+}
+
+// printMetadata prints bin data metadata as key=value lines, metadata
+// of other secrets is printed along with them.
+func (getCmd GetSecretCmd) printMetadata(metadata map[string]string) error {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, err := fmt.Fprintf(getCmd.stdout, "%s=%s\n", key, metadata[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (getCmd GetSecretCmd) saveSecret(output string, secret api.Secret) error {
+	if output == "" {
+		_, err := io.Copy(getCmd.stdout, secret.Content)
+		return err
+	}
+
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save secret: %w", err)
+	}
+	_, err = io.Copy(f, secret.Content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+		return fmt.Errorf("failed to save secret: %w", err)
+	}
+
+	return nil
+}
+
+// saveBinData writes content to the part file, which is renamed to path
+// once the content is downloaded and its hash is verified.
+func (getCmd GetSecretCmd) saveBinData(key, path string, secret api.Secret) error {
+	partPath := path + partSuffix
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if secret.Partial {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(partPath, flags, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save secret: %w", err)
+	}
+	state := downloadState{ETag: secret.ETag, Path: path}
+	if err := getCmd.saveState(key, state); err != nil {
+		f.Close()
+		return err
+	}
+	_, err = io.Copy(f, secret.Content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("download interrupted, run the command again to resume: %w", err)
+	}
+
+	if len(secret.SHA256) > 0 {
+		if err := verifyFileHash(partPath, secret.SHA256); err != nil {
+			os.Remove(partPath)
+			getCmd.downloads.Delete(key)
+			return err
+		}
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return fmt.Errorf("failed to save secret: %w", err)
+	}
+	state.Complete = true
+
+	return getCmd.saveState(key, state)
+}
+
+func (getCmd GetSecretCmd) loadState(key string) (downloadState, bool) {
+	var state downloadState
+	value, ok := getCmd.downloads.Get(key)
+	if !ok {
+		return state, false
+	}
+	if err := json.Unmarshal([]byte(value), &state); err != nil {
+		return state, false
+	}
+
+	return state, state.ETag != ""
+}
+
+func (getCmd GetSecretCmd) saveState(key string, state downloadState) error {
+	if state.ETag == "" {
+		return getCmd.downloads.Delete(key)
+	}
+	value, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to save download state: %w", err)
+	}
+
+	return getCmd.downloads.Set(key, string(value))
+}
+
+func verifyFileHash(path string, wantHash []byte) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to verify secret: %w", err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return fmt.Errorf("failed to verify secret: %w", err)
+	}
+	if !bytes.Equal(hash.Sum(nil), wantHash) {
+		return errors.New("downloaded content hash mismatch, run the command again to download it from scratch")
+	}
+
+	return nil
+}
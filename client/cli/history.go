@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type RevisionGetter interface {
+	ListRevisions(ctx context.Context, id int64) ([]api.SecretRevision, error)
+	GetSecretRevision(ctx context.Context, id int64, version int) (api.Secret, error)
+	SetJWT(jwt string)
+}
+
+type HistoryCmd struct {
+	getter RevisionGetter
+	stdout io.Writer
+}
+
+func NewHistoryCmd(getter RevisionGetter, stdout io.Writer) HistoryCmd {
+	return HistoryCmd{
+		getter: getter,
+		stdout: stdout,
+	}
+}
+
+// Execute lists secret revisions if version is zero, otherwise it prints
+// the revision like the get command does. Bin data of the revision is
+// saved to output or to a file named after the original file.
+func (historyCmd HistoryCmd) Execute(id int64, version int, output, jwt string) error {
+	historyCmd.getter.SetJWT(jwt)
+	if version == 0 {
+		return historyCmd.list(id)
+	}
+
+	secret, err := historyCmd.getter.GetSecretRevision(context.TODO(), id, version)
+	if err != nil {
+		return err
+	}
+	defer secret.Content.Close()
+
+	if output == "" && secret.Filename != "" {
+		output = filepath.Base(secret.Filename)
+	}
+	if output == "" {
+		_, err := io.Copy(historyCmd.stdout, secret.Content)
+		return err
+	}
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save secret: %w", err)
+	}
+	_, err = io.Copy(f, secret.Content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+		return fmt.Errorf("failed to save secret: %w", err)
+	}
+
+	return nil
+}
+
+func (historyCmd HistoryCmd) list(id int64) error {
+	revisions, err := historyCmd.getter.ListRevisions(context.TODO(), id)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(historyCmd.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tDESCRIPTION\tSIZE\tCREATED AT")
+	for _, revision := range revisions {
+		fmt.Fprintf(
+			writer,
+			"%d\t%s\t%d\t%s\n",
+			revision.Version,
+			revision.Description,
+			revision.Size,
+			revision.CreatedAt.Local().Format(time.DateTime),
+		)
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type SecretsLister interface {
+	ListSecrets(ctx context.Context, params api.ListSecretsParams) (api.SecretsPage, error)
+	SetJWT(jwt string)
+}
+
+type ListSecretsCmd struct {
+	lister SecretsLister
+	stdout io.Writer
+}
+
+func NewListSecretsCmd(lister SecretsLister, stdout io.Writer) ListSecretsCmd {
+	return ListSecretsCmd{
+		lister: lister,
+		stdout: stdout,
+	}
+}
+
+func (listCmd ListSecretsCmd) Execute(params api.ListSecretsParams, jwt string) error {
+	listCmd.lister.SetJWT(jwt)
+	page, err := listCmd.lister.ListSecrets(context.TODO(), params)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(listCmd.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tVERSION\tTYPE\tDESCRIPTION\tSIZE\tFOLDER\tTAGS\tSHARED\tCREATED AT\tUPDATED AT")
+	for _, secret := range page.Secrets {
+		folder := "-"
+		if secret.FolderID != 0 {
+			folder = strconv.FormatInt(secret.FolderID, 10)
+		}
+		shared := "-"
+		if secret.Shared != "" {
+			shared = secret.Shared
+		}
+		fmt.Fprintf(
+			writer,
+			"%d\t%d\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			secret.ID,
+			secret.Version,
+			secret.SecretType,
+			secret.Description,
+			secret.Size,
+			folder,
+			strings.Join(secret.Tags, ","),
+			shared,
+			secret.CreatedAt.Local().Format(time.DateTime),
+			secret.UpdatedAt.Local().Format(time.DateTime),
+		)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if page.NextCursor != 0 {
+		fmt.Fprintf(listCmd.stdout, "\nmore secrets available, use -cursor=%d\n", page.NextCursor)
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type FoldersLister interface {
+	ListFolders(ctx context.Context) ([]api.Folder, error)
+	SetJWT(jwt string)
+}
+
+type ListFoldersCmd struct {
+	lister FoldersLister
+	stdout io.Writer
+}
+
+func NewListFoldersCmd(lister FoldersLister, stdout io.Writer) ListFoldersCmd {
+	return ListFoldersCmd{
+		lister: lister,
+		stdout: stdout,
+	}
+}
+
+// Execute prints folders with their full paths sorted by path.
+func (listCmd ListFoldersCmd) Execute(jwt string) error {
+	listCmd.lister.SetJWT(jwt)
+	folders, err := listCmd.lister.ListFolders(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int64]api.Folder, len(folders))
+	for _, folder := range folders {
+		byID[folder.ID] = folder
+	}
+	paths := make(map[int64]string, len(folders))
+	for _, folder := range folders {
+		path := folder.Name
+		for parentID, depth := folder.ParentID, 0; parentID != 0 && depth < len(folders); depth++ {
+			parent := byID[parentID]
+			path = parent.Name + "/" + path
+			parentID = parent.ParentID
+		}
+		paths[folder.ID] = path
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		return paths[folders[i].ID] < paths[folders[j].ID]
+	})
+
+	writer := tabwriter.NewWriter(listCmd.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tPATH")
+	for _, folder := range folders {
+		fmt.Fprintf(writer, "%d\t%s\n", folder.ID, paths[folder.ID])
+	}
+
+	return writer.Flush()
+}
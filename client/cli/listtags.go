@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type TagsLister interface {
+	ListTags(ctx context.Context) ([]api.Tag, error)
+	SetJWT(jwt string)
+}
+
+type ListTagsCmd struct {
+	lister TagsLister
+	stdout io.Writer
+}
+
+func NewListTagsCmd(lister TagsLister, stdout io.Writer) ListTagsCmd {
+	return ListTagsCmd{
+		lister: lister,
+		stdout: stdout,
+	}
+}
+
+func (listCmd ListTagsCmd) Execute(jwt string) error {
+	listCmd.lister.SetJWT(jwt)
+	tags, err := listCmd.lister.ListTags(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(listCmd.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tNAME")
+	for _, tag := range tags {
+		fmt.Fprintf(writer, "%d\t%s\n", tag.ID, tag.Name)
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type MembersManager interface {
+	InviteMember(ctx context.Context, orgID int64, login string, role string) (api.OrganizationMember, error)
+	AcceptInvitation(ctx context.Context, orgID int64) error
+	SetMemberRole(ctx context.Context, orgID int64, userID int64, role string) error
+	ListMembers(ctx context.Context, orgID int64) ([]api.OrganizationMember, error)
+	RemoveMember(ctx context.Context, orgID int64, userID int64) error
+	SetJWT(jwt string)
+}
+
+type MembersCmd struct {
+	manager MembersManager
+	stdout  io.Writer
+}
+
+func NewMembersCmd(manager MembersManager, stdout io.Writer) MembersCmd {
+	return MembersCmd{
+		manager: manager,
+		stdout:  stdout,
+	}
+}
+
+// Execute invites the user with the login to the organization with the
+// role if the login is set, accepts the invitation of the current user if
+// accept is set, gives the role to the user with roleUserID if it is not
+// zero, removes the user with removeUserID if it is not zero, otherwise
+// it lists members of the organization.
+func (membersCmd MembersCmd) Execute(
+	orgID int64,
+	login string,
+	role string,
+	accept bool,
+	roleUserID int64,
+	removeUserID int64,
+	jwt string) error {
+
+	membersCmd.manager.SetJWT(jwt)
+	if login != "" {
+		_, err := membersCmd.manager.InviteMember(context.TODO(), orgID, login, role)
+		return err
+	}
+	if accept {
+		return membersCmd.manager.AcceptInvitation(context.TODO(), orgID)
+	}
+	if roleUserID != 0 {
+		return membersCmd.manager.SetMemberRole(context.TODO(), orgID, roleUserID, role)
+	}
+	if removeUserID != 0 {
+		return membersCmd.manager.RemoveMember(context.TODO(), orgID, removeUserID)
+	}
+
+	members, err := membersCmd.manager.ListMembers(context.TODO(), orgID)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(membersCmd.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "USER ID\tLOGIN\tROLE\tSTATUS\tINVITED AT")
+	for _, member := range members {
+		status := "invited"
+		if member.Accepted {
+			status = "member"
+		}
+		fmt.Fprintf(
+			writer,
+			"%d\t%s\t%s\t%s\t%s\n",
+			member.UserID,
+			member.Login,
+			member.Role,
+			status,
+			member.CreatedAt.Local().Format(time.DateTime),
+		)
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type SecretMover interface {
+	MoveSecret(ctx context.Context, id int64, folderID int64) (api.SecretInfo, error)
+	SetJWT(jwt string)
+}
+
+type MoveSecretCmd struct {
+	mover SecretMover
+}
+
+func NewMoveSecretCmd(mover SecretMover) MoveSecretCmd {
+	return MoveSecretCmd{
+		mover: mover,
+	}
+}
+
+// Execute moves the secret into the folder, zero folderID moves it out
+// of any folder.
+func (moveCmd MoveSecretCmd) Execute(id int64, folderID int64, jwt string) (api.SecretInfo, error) {
+	moveCmd.mover.SetJWT(jwt)
+	return moveCmd.mover.MoveSecret(context.TODO(), id, folderID)
+}
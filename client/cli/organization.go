@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type OrganizationManager interface {
+	CreateOrganization(ctx context.Context, name string) (api.Organization, error)
+	ListOrganizations(ctx context.Context) ([]api.Organization, error)
+	SetJWT(jwt string)
+}
+
+type OrganizationCmd struct {
+	manager OrganizationManager
+	stdout  io.Writer
+}
+
+func NewOrganizationCmd(manager OrganizationManager, stdout io.Writer) OrganizationCmd {
+	return OrganizationCmd{
+		manager: manager,
+		stdout:  stdout,
+	}
+}
+
+// Execute creates an organization with the name if it is set, otherwise
+// it lists organizations of the user.
+func (orgCmd OrganizationCmd) Execute(name string, jwt string) error {
+	orgCmd.manager.SetJWT(jwt)
+	if name != "" {
+		org, err := orgCmd.manager.CreateOrganization(context.TODO(), name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(orgCmd.stdout, "id=%d\n", org.ID)
+		return nil
+	}
+
+	orgs, err := orgCmd.manager.ListOrganizations(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(orgCmd.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tNAME\tOWNER ID\tSTATUS\tCREATED AT")
+	for _, org := range orgs {
+		status := "member"
+		if org.Invited {
+			status = "invited"
+		}
+		fmt.Fprintf(
+			writer,
+			"%d\t%s\t%d\t%s\t%s\n",
+			org.ID,
+			org.Name,
+			org.OwnerID,
+			status,
+			org.CreatedAt.Local().Format(time.DateTime),
+		)
+	}
+
+	return writer.Flush()
+}
@@ -3,29 +3,49 @@ package cli
 import (
 	"context"
 	"errors"
+	"io"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
 )
 
 type UserRegistrator interface {
-	RegisterUser(ctx context.Context, login, password string) error
+	RegisterUser(ctx context.Context, login, password string) (api.SessionTokens, error)
+	EnrollTOTP(ctx context.Context) (api.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, code string) ([]string, error)
+	SetJWT(jwt string)
 }
 
 type RegisterCmd struct {
 	usrReg UserRegistrator
+	stdin  io.Reader
+	stdout io.Writer
 }
 
-func NewRegisterCmd(usrReg UserRegistrator) RegisterCmd {
+func NewRegisterCmd(usrReg UserRegistrator, stdin io.Reader, stdout io.Writer) RegisterCmd {
 	return RegisterCmd{
 		usrReg: usrReg,
+		stdin:  stdin,
+		stdout: stdout,
 	}
 }
 
-func (regCmd RegisterCmd) Execute(login, password string) error {
+// Execute registers the user and, if totp is set, enables TOTP of the
+// new user. Tokens of the new session are returned.
+func (regCmd RegisterCmd) Execute(login, password string, totp bool) (api.SessionTokens, error) {
 	if login == "" {
-		return errors.New("login must be non empty")
+		return api.SessionTokens{}, errors.New("login must be non empty")
 	}
 	if password == "" {
-		return errors.New("password must be non empty")
+		return api.SessionTokens{}, errors.New("password must be non empty")
+	}
+
+	tokens, err := regCmd.usrReg.RegisterUser(context.TODO(), login, password)
+	if err != nil {
+		return tokens, err
+	}
+	if totp {
+		err = enableTOTP(regCmd.usrReg, tokens.AccessToken, regCmd.stdin, regCmd.stdout)
 	}
 
-	return regCmd.usrReg.RegisterUser(context.TODO(), login, password)
+	return tokens, err
 }
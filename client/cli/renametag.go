@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type TagRenamer interface {
+	RenameTag(ctx context.Context, id int64, name string) (api.Tag, error)
+	SetJWT(jwt string)
+}
+
+type RenameTagCmd struct {
+	renamer TagRenamer
+}
+
+func NewRenameTagCmd(renamer TagRenamer) RenameTagCmd {
+	return RenameTagCmd{
+		renamer: renamer,
+	}
+}
+
+func (renameCmd RenameTagCmd) Execute(id int64, name string, jwt string) (api.Tag, error) {
+	renameCmd.renamer.SetJWT(jwt)
+	return renameCmd.renamer.RenameTag(context.TODO(), id, name)
+}
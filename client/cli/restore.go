@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type SecretRestorer interface {
+	RestoreSecret(ctx context.Context, id int64) (api.SecretInfo, error)
+	RestoreRevision(ctx context.Context, id int64, version int) error
+	SetJWT(jwt string)
+}
+
+type RestoreCmd struct {
+	restorer SecretRestorer
+}
+
+func NewRestoreCmd(restorer SecretRestorer) RestoreCmd {
+	return RestoreCmd{
+		restorer: restorer,
+	}
+}
+
+// Execute restores the secret revision, the secret is restored from the
+// trash if version is zero.
+func (restoreCmd RestoreCmd) Execute(id int64, version int, jwt string) error {
+	restoreCmd.restorer.SetJWT(jwt)
+	if version == 0 {
+		_, err := restoreCmd.restorer.RestoreSecret(context.TODO(), id)
+		return err
+	}
+
+	return restoreCmd.restorer.RestoreRevision(context.TODO(), id, version)
+}
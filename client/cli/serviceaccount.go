@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type ServiceAccountManager interface {
+	CreateServiceAccount(ctx context.Context, orgID int64, name string, role string) (api.ServiceAccount, error)
+	ListServiceAccounts(ctx context.Context, orgID int64) ([]api.ServiceAccount, error)
+	DisableServiceAccount(ctx context.Context, orgID int64, id int64) error
+	CreateServiceAccountSecret(ctx context.Context, orgID int64, id int64) (api.ServiceAccountCredential, error)
+	AddServiceAccountPublicKey(
+		ctx context.Context,
+		orgID int64,
+		id int64,
+		publicKey string) (api.ServiceAccountCredential, error)
+	ListServiceAccountCredentials(ctx context.Context, orgID int64, id int64) ([]api.ServiceAccountCredential, error)
+	DeleteServiceAccountCredential(ctx context.Context, orgID int64, id int64, credentialID int64) error
+	ListServiceAccountAuditEvents(
+		ctx context.Context,
+		orgID int64,
+		id int64,
+		params api.AuditParams) ([]api.AuditEvent, error)
+	SetJWT(jwt string)
+}
+
+// ServiceAccountParams selects the action of ServiceAccountCmd. Actions
+// other than Create and listing of service accounts are applied to the
+// service account with AccountID.
+type ServiceAccountParams struct {
+	OrgID              int64
+	Create             string
+	Role               string
+	AccountID          int64
+	Disable            bool
+	AddSecret          bool
+	PublicKey          string
+	RemoveCredentialID int64
+	Audit              bool
+	AuditParams        api.AuditParams
+}
+
+type ServiceAccountCmd struct {
+	manager ServiceAccountManager
+	stdout  io.Writer
+}
+
+func NewServiceAccountCmd(manager ServiceAccountManager, stdout io.Writer) ServiceAccountCmd {
+	return ServiceAccountCmd{
+		manager: manager,
+		stdout:  stdout,
+	}
+}
+
+// Execute creates the service account with the name params.Create if it is
+// set, lists service accounts of the organization if params.AccountID is
+// not set, otherwise it disables the service account, creates its client
+// secret, adds its public key, removes its credential, prints its audit
+// events or lists its credentials.
+func (accountCmd ServiceAccountCmd) Execute(params ServiceAccountParams, jwt string) error {
+	accountCmd.manager.SetJWT(jwt)
+	ctx := context.TODO()
+	if params.Create != "" {
+		account, err := accountCmd.manager.CreateServiceAccount(ctx, params.OrgID, params.Create, params.Role)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(accountCmd.stdout, "id=%d\nclient_id=%s\n", account.ID, account.ClientID)
+		return nil
+	}
+	if params.AccountID == 0 {
+		return accountCmd.listAccounts(ctx, params.OrgID)
+	}
+
+	switch {
+	case params.Disable:
+		return accountCmd.manager.DisableServiceAccount(ctx, params.OrgID, params.AccountID)
+	case params.AddSecret:
+		credential, err := accountCmd.manager.CreateServiceAccountSecret(ctx, params.OrgID, params.AccountID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(accountCmd.stdout, "id=%d\nclient_secret=%s\n", credential.ID, credential.ClientSecret)
+		return nil
+	case params.PublicKey != "":
+		credential, err := accountCmd.manager.AddServiceAccountPublicKey(
+			ctx,
+			params.OrgID,
+			params.AccountID,
+			params.PublicKey,
+		)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(accountCmd.stdout, "key_id=%d\n", credential.ID)
+		return nil
+	case params.RemoveCredentialID != 0:
+		return accountCmd.manager.DeleteServiceAccountCredential(
+			ctx,
+			params.OrgID,
+			params.AccountID,
+			params.RemoveCredentialID,
+		)
+	case params.Audit:
+		events, err := accountCmd.manager.ListServiceAccountAuditEvents(
+			ctx,
+			params.OrgID,
+			params.AccountID,
+			params.AuditParams,
+		)
+		if err != nil {
+			return err
+		}
+		return printAuditEvents(accountCmd.stdout, events)
+	}
+
+	return accountCmd.listCredentials(ctx, params.OrgID, params.AccountID)
+}
+
+func (accountCmd ServiceAccountCmd) listAccounts(ctx context.Context, orgID int64) error {
+	accounts, err := accountCmd.manager.ListServiceAccounts(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(accountCmd.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tNAME\tCLIENT ID\tSTATUS\tCREATED AT")
+	for _, account := range accounts {
+		status := "enabled"
+		if account.Disabled {
+			status = "disabled"
+		}
+		fmt.Fprintf(
+			writer,
+			"%d\t%s\t%s\t%s\t%s\n",
+			account.ID,
+			account.Name,
+			account.ClientID,
+			status,
+			account.CreatedAt.Local().Format(time.DateTime),
+		)
+	}
+
+	return writer.Flush()
+}
+
+func (accountCmd ServiceAccountCmd) listCredentials(ctx context.Context, orgID int64, id int64) error {
+	credentials, err := accountCmd.manager.ListServiceAccountCredentials(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(accountCmd.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tTYPE\tLAST USED AT\tCREATED AT")
+	for _, credential := range credentials {
+		fmt.Fprintf(
+			writer,
+			"%d\t%s\t%s\t%s\n",
+			credential.ID,
+			credential.Type,
+			formatOptionalTime(credential.LastUsedAt, "never"),
+			credential.CreatedAt.Local().Format(time.DateTime),
+		)
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type SessionRefresher interface {
+	RefreshSession(ctx context.Context, refreshToken string) (api.SessionTokens, error)
+}
+
+type RefreshCmd struct {
+	refresher SessionRefresher
+}
+
+func NewRefreshCmd(refresher SessionRefresher) RefreshCmd {
+	return RefreshCmd{
+		refresher: refresher,
+	}
+}
+
+// Execute returns new session tokens, the refresh token can not be used
+// again.
+func (refreshCmd RefreshCmd) Execute(refreshToken string) (api.SessionTokens, error) {
+	if refreshToken == "" {
+		return api.SessionTokens{}, errors.New("refresh token must be non empty")
+	}
+
+	return refreshCmd.refresher.RefreshSession(context.TODO(), refreshToken)
+}
+
+type SessionRevoker interface {
+	Logout(ctx context.Context) error
+	SetJWT(jwt string)
+}
+
+type LogoutCmd struct {
+	revoker SessionRevoker
+}
+
+func NewLogoutCmd(revoker SessionRevoker) LogoutCmd {
+	return LogoutCmd{
+		revoker: revoker,
+	}
+}
+
+// Execute revokes the session of the JWT.
+func (logoutCmd LogoutCmd) Execute(jwt string) error {
+	logoutCmd.revoker.SetJWT(jwt)
+	return logoutCmd.revoker.Logout(context.TODO())
+}
+
+type SessionManager interface {
+	ListSessions(ctx context.Context) ([]api.Session, error)
+	RevokeSession(ctx context.Context, id int64) error
+	RevokeOtherSessions(ctx context.Context) (int64, error)
+	SetJWT(jwt string)
+}
+
+type SessionsCmd struct {
+	manager SessionManager
+	stdout  io.Writer
+}
+
+func NewSessionsCmd(manager SessionManager, stdout io.Writer) SessionsCmd {
+	return SessionsCmd{
+		manager: manager,
+		stdout:  stdout,
+	}
+}
+
+// Execute revokes the session with revokeID if it is set, all sessions
+// except the current one if revokeOthers is set, otherwise it lists
+// active sessions of the user.
+func (sessionsCmd SessionsCmd) Execute(revokeID int64, revokeOthers bool, jwt string) error {
+	sessionsCmd.manager.SetJWT(jwt)
+	if revokeID != 0 {
+		return sessionsCmd.manager.RevokeSession(context.TODO(), revokeID)
+	}
+	if revokeOthers {
+		revoked, err := sessionsCmd.manager.RevokeOtherSessions(context.TODO())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(sessionsCmd.stdout, "revoked=%d\n", revoked)
+		return nil
+	}
+
+	sessions, err := sessionsCmd.manager.ListSessions(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(sessionsCmd.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tCURRENT\tCREATED AT\tEXPIRES AT")
+	for _, session := range sessions {
+		current := ""
+		if session.Current {
+			current = "*"
+		}
+		fmt.Fprintf(
+			writer,
+			"%d\t%s\t%s\t%s\n",
+			session.ID,
+			current,
+			session.CreatedAt.Local().Format(time.DateTime),
+			session.ExpiresAt.Local().Format(time.DateTime),
+		)
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"context"
+)
+
+type RetentionSetter interface {
+	SetRevisionsRetention(ctx context.Context, count int) error
+	SetJWT(jwt string)
+}
+
+type SetRetentionCmd struct {
+	setter RetentionSetter
+}
+
+func NewSetRetentionCmd(setter RetentionSetter) SetRetentionCmd {
+	return SetRetentionCmd{
+		setter: setter,
+	}
+}
+
+func (setCmd SetRetentionCmd) Execute(count int, jwt string) error {
+	setCmd.setter.SetJWT(jwt)
+	return setCmd.setter.SetRevisionsRetention(context.TODO(), count)
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type SecretTagsSetter interface {
+	SetSecretTags(ctx context.Context, id int64, tagIDs []int64) (api.SecretInfo, error)
+	SetJWT(jwt string)
+}
+
+type SetTagsCmd struct {
+	setter SecretTagsSetter
+}
+
+func NewSetTagsCmd(setter SecretTagsSetter) SetTagsCmd {
+	return SetTagsCmd{
+		setter: setter,
+	}
+}
+
+// Execute replaces the secret tags, empty tagIDs removes all tags.
+func (setCmd SetTagsCmd) Execute(id int64, tagIDs []int64, jwt string) (api.SecretInfo, error) {
+	setCmd.setter.SetJWT(jwt)
+	return setCmd.setter.SetSecretTags(context.TODO(), id, tagIDs)
+}
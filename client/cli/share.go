@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type SecretSharer interface {
+	ShareSecret(ctx context.Context, id int64, login, access string) (api.SecretShare, error)
+	ListSecretShares(ctx context.Context, id int64) ([]api.SecretShare, error)
+	RevokeShare(ctx context.Context, id int64, userID int64) error
+	SetJWT(jwt string)
+}
+
+type ShareCmd struct {
+	sharer SecretSharer
+	stdout io.Writer
+}
+
+func NewShareCmd(sharer SecretSharer, stdout io.Writer) ShareCmd {
+	return ShareCmd{
+		sharer: sharer,
+		stdout: stdout,
+	}
+}
+
+// Execute shares the secret with the user with the login if it is set,
+// revokes the share of the user with revokeUserID if it is not zero,
+// otherwise it lists users the secret is shared with.
+func (shareCmd ShareCmd) Execute(id int64, login, access string, revokeUserID int64, jwt string) error {
+	shareCmd.sharer.SetJWT(jwt)
+	if login != "" {
+		_, err := shareCmd.sharer.ShareSecret(context.TODO(), id, login, access)
+		return err
+	}
+	if revokeUserID != 0 {
+		return shareCmd.sharer.RevokeShare(context.TODO(), id, revokeUserID)
+	}
+
+	shares, err := shareCmd.sharer.ListSecretShares(context.TODO(), id)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(shareCmd.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "USER ID\tLOGIN\tACCESS\tSHARED AT")
+	for _, share := range shares {
+		fmt.Fprintf(
+			writer,
+			"%d\t%s\t%s\t%s\n",
+			share.UserID,
+			share.Login,
+			share.Access,
+			share.CreatedAt.Local().Format(time.DateTime),
+		)
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+// revisionFilename is the file in the mirror directory which keeps the
+// revision the mirror is synced to.
+const revisionFilename = ".revision"
+
+type SecretSyncer interface {
+	Sync(ctx context.Context, since int64, limit int) (api.SyncChanges, error)
+	GetSecret(ctx context.Context, id int64, params api.GetSecretParams) (api.Secret, error)
+	SetJWT(jwt string)
+}
+
+type SyncCmd struct {
+	syncer SecretSyncer
+	stdout io.Writer
+}
+
+func NewSyncCmd(syncer SecretSyncer, stdout io.Writer) SyncCmd {
+	return SyncCmd{
+		syncer: syncer,
+		stdout: stdout,
+	}
+}
+
+// Execute brings the mirror directory up to date. Every secret is kept in
+// <id>.json, bin data content is downloaded to <id>.bin. Only changes made
+// since the previous sync are fetched. Only personal secrets are mirrored,
+// secrets shared with the user and vault secrets are not.
+func (syncCmd SyncCmd) Execute(dir string, jwt string) error {
+	syncCmd.syncer.SetJWT(jwt)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+	revision, err := readRevision(dir)
+	if err != nil {
+		return err
+	}
+
+	var created, updated, deleted int
+	for {
+		changes, err := syncCmd.syncer.Sync(context.TODO(), revision, 0)
+		if err != nil {
+			return err
+		}
+		for _, secret := range append(changes.Created, changes.Updated...) {
+			if err := syncCmd.saveSecret(dir, secret); err != nil {
+				return err
+			}
+		}
+		for _, id := range changes.Deleted {
+			if err := removeSecret(dir, id); err != nil {
+				return err
+			}
+		}
+		created += len(changes.Created)
+		updated += len(changes.Updated)
+		deleted += len(changes.Deleted)
+
+		// The revision is saved after every page, so an interrupted
+		// sync continues from the last saved page.
+		revision = changes.Revision
+		if err := writeFileAtomic(filepath.Join(dir, revisionFilename), []byte(strconv.FormatInt(revision, 10))); err != nil {
+			return err
+		}
+		if !changes.HasMore {
+			break
+		}
+	}
+
+	_, err = fmt.Fprintf(
+		syncCmd.stdout,
+		"created %d, updated %d, deleted %d, revision %d\n",
+		created, updated, deleted, revision,
+	)
+	return err
+}
+
+func (syncCmd SyncCmd) saveSecret(dir string, secret api.SyncedSecret) error {
+	data, err := json.MarshalIndent(secret, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode secret: %w", err)
+	}
+	if err := writeFileAtomic(secretPath(dir, secret.ID, ".json"), data); err != nil {
+		return err
+	}
+	if secret.SecretType != "bin_data" {
+		return nil
+	}
+
+	content, err := syncCmd.syncer.GetSecret(context.TODO(), secret.ID, api.GetSecretParams{})
+	if err != nil {
+		return err
+	}
+	defer content.Content.Close()
+
+	path := secretPath(dir, secret.ID, ".bin")
+	f, err := os.OpenFile(path+partSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save bin data: %w", err)
+	}
+	_, err = io.Copy(f, content.Content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && len(content.SHA256) > 0 {
+		err = verifyFileHash(path+partSuffix, content.SHA256)
+	}
+	if err != nil {
+		os.Remove(path + partSuffix)
+		return fmt.Errorf("failed to save bin data: %w", err)
+	}
+
+	return os.Rename(path+partSuffix, path)
+}
+
+func removeSecret(dir string, id int64) error {
+	for _, ext := range []string{".json", ".bin"} {
+		err := os.Remove(secretPath(dir, id, ext))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove secret: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func secretPath(dir string, id int64, ext string) string {
+	return filepath.Join(dir, strconv.FormatInt(id, 10)+ext)
+}
+
+// readRevision returns zero if the mirror has never been synced.
+func readRevision(dir string) (int64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, revisionFilename))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read mirror revision: %w", err)
+	}
+	revision, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid mirror revision: %w", err)
+	}
+
+	return revision, nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.WriteFile(path+partSuffix, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(path+partSuffix, path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+
+	return nil
+}
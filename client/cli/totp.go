@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type TOTPEnroller interface {
+	EnrollTOTP(ctx context.Context) (api.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, code string) ([]string, error)
+	SetJWT(jwt string)
+}
+
+type EnableTOTPCmd struct {
+	enroller TOTPEnroller
+	stdin    io.Reader
+	stdout   io.Writer
+}
+
+func NewEnableTOTPCmd(enroller TOTPEnroller, stdin io.Reader, stdout io.Writer) EnableTOTPCmd {
+	return EnableTOTPCmd{
+		enroller: enroller,
+		stdin:    stdin,
+		stdout:   stdout,
+	}
+}
+
+// Execute shows a new TOTP secret, asks for the first code of it and
+// prints recovery codes once TOTP is enabled.
+func (totpCmd EnableTOTPCmd) Execute(jwt string) error {
+	return enableTOTP(totpCmd.enroller, jwt, totpCmd.stdin, totpCmd.stdout)
+}
+
+func enableTOTP(enroller TOTPEnroller, jwt string, stdin io.Reader, stdout io.Writer) error {
+	enroller.SetJWT(jwt)
+	enrollment, err := enroller.EnrollTOTP(context.TODO())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "Add the secret to your authenticator app:")
+	fmt.Fprintln(stdout, "  "+enrollment.URI)
+	fmt.Fprintln(stdout, "  secret: "+enrollment.Secret)
+	code, err := promptLine(stdin, stdout, "Enter the code from the app: ")
+	if err != nil {
+		return err
+	}
+	recoveryCodes, err := enroller.ConfirmTOTP(context.TODO(), code)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "TOTP is enabled. Keep the recovery codes, each of them can be used once instead of a code:")
+	for _, recoveryCode := range recoveryCodes {
+		fmt.Fprintln(stdout, "  "+recoveryCode)
+	}
+
+	return nil
+}
+
+func promptLine(stdin io.Reader, stdout io.Writer, prompt string) (string, error) {
+	fmt.Fprint(stdout, prompt)
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", errors.New("input must be non empty")
+	}
+
+	return line, nil
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type TrashManager interface {
+	ListTrash(ctx context.Context) ([]api.TrashedSecret, error)
+	PurgeSecret(ctx context.Context, id int64) error
+	SetJWT(jwt string)
+}
+
+type TrashCmd struct {
+	manager TrashManager
+	stdout  io.Writer
+}
+
+func NewTrashCmd(manager TrashManager, stdout io.Writer) TrashCmd {
+	return TrashCmd{
+		manager: manager,
+		stdout:  stdout,
+	}
+}
+
+// Execute lists trashed secrets if purgeID is zero, otherwise it
+// permanently deletes the trashed secret with purgeID.
+func (trashCmd TrashCmd) Execute(purgeID int64, jwt string) error {
+	trashCmd.manager.SetJWT(jwt)
+	if purgeID != 0 {
+		return trashCmd.manager.PurgeSecret(context.TODO(), purgeID)
+	}
+
+	secrets, err := trashCmd.manager.ListTrash(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(trashCmd.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tTYPE\tDESCRIPTION\tSIZE\tDELETED AT")
+	for _, secret := range secrets {
+		fmt.Fprintf(
+			writer,
+			"%d\t%s\t%s\t%d\t%s\n",
+			secret.ID,
+			secret.SecretType,
+			secret.Description,
+			secret.Size,
+			secret.DeletedAt.Local().Format(time.DateTime),
+		)
+	}
+
+	return writer.Flush()
+}
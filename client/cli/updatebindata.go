@@ -3,12 +3,15 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
 )
 
 type BinDataUpdater interface {
-	UpdateBinData(ctx context.Context, id int64, filename string, fileContent []byte) error
+	UpdateBinData(ctx context.Context, id, version int64, filename string, fileContent io.ReadSeeker, metadata map[string]string) (api.SecretInfo, error)
 	SetJWT(jwt string)
 }
 
@@ -22,16 +25,19 @@ func NewUpdateBinDataCmd(updater BinDataUpdater) UpdateBinDataCmd {
 	}
 }
 
-func (updCmd UpdateBinDataCmd) Execute(id int64, filePath, jwt string) error {
-	fileContent, err := os.ReadFile(filePath)
+func (updCmd UpdateBinDataCmd) Execute(id, version int64, filePath string, metadata map[string]string, jwt string) (api.SecretInfo, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read %s: %w", filePath, err)
+		return api.SecretInfo{}, fmt.Errorf("failed to open %s: %w", filePath, err)
 	}
+	defer file.Close()
 	updCmd.updater.SetJWT(jwt)
 	return updCmd.updater.UpdateBinData(
 		context.TODO(),
 		id,
+		version,
 		filepath.Base(filePath),
-		fileContent,
+		file,
+		metadata,
 	)
 }
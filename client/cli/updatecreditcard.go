@@ -1,9 +1,13 @@
 package cli
 
-import "context"
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
 
 type CreditCardUpdater interface {
-	UpdateCreditCard(ctx context.Context, id int64, number, name, expiryDate, cvv2 string) error
+	UpdateCreditCard(ctx context.Context, id, version int64, number, name, expiryDate, cvv2 string, metadata map[string]string) (api.SecretInfo, error)
 	SetJWT(jwt string)
 }
 
@@ -19,19 +23,23 @@ func NewUpdateCreditCardCmd(updater CreditCardUpdater) UpdateCreditCardCmd {
 
 func (updCmd UpdateCreditCardCmd) Execute(
 	id int64,
+	version int64,
 	number,
 	name,
 	expiryDate,
-	cvv2,
-	jwt string) error {
+	cvv2 string,
+	metadata map[string]string,
+	jwt string) (api.SecretInfo, error) {
 
 	updCmd.updater.SetJWT(jwt)
 	return updCmd.updater.UpdateCreditCard(
 		context.TODO(),
 		id,
+		version,
 		number,
 		name,
 		expiryDate,
 		cvv2,
+		metadata,
 	)
 }
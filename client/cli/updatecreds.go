@@ -1,9 +1,13 @@
 package cli
 
-import "context"
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
 
 type CredentialsUpdater interface {
-	UpdateCredentials(ctx context.Context, id int64, login, password string) error
+	UpdateCredentials(ctx context.Context, id, version int64, login, password string, metadata map[string]string) (api.SecretInfo, error)
 	SetJWT(jwt string)
 }
 
@@ -17,12 +21,14 @@ func NewUpdateCredentialsCmd(updater CredentialsUpdater) UpdateCredentialsCmd {
 	}
 }
 
-func (updateCmd UpdateCredentialsCmd) Execute(id int64, login, password, jwtStr string) error {
+func (updateCmd UpdateCredentialsCmd) Execute(id, version int64, login, password string, metadata map[string]string, jwtStr string) (api.SecretInfo, error) {
 	updateCmd.updater.SetJWT(jwtStr)
 	return updateCmd.updater.UpdateCredentials(
 		context.TODO(),
 		id,
+		version,
 		login,
 		password,
+		metadata,
 	)
 }
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type FolderUpdater interface {
+	UpdateFolder(ctx context.Context, id int64, params api.UpdateFolderParams) (api.Folder, error)
+	SetJWT(jwt string)
+}
+
+type UpdateFolderCmd struct {
+	updater FolderUpdater
+}
+
+func NewUpdateFolderCmd(updater FolderUpdater) UpdateFolderCmd {
+	return UpdateFolderCmd{
+		updater: updater,
+	}
+}
+
+// Execute renames the folder and moves it into another folder.
+func (updateCmd UpdateFolderCmd) Execute(id int64, params api.UpdateFolderParams, jwt string) (api.Folder, error) {
+	updateCmd.updater.SetJWT(jwt)
+	return updateCmd.updater.UpdateFolder(context.TODO(), id, params)
+}
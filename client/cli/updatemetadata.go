@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type SecretMetadataUpdater interface {
+	UpdateSecretMetadata(ctx context.Context, id, version int64, metadata map[string]string) (api.SecretInfo, error)
+	SetJWT(jwt string)
+}
+
+type UpdateMetadataCmd struct {
+	updater SecretMetadataUpdater
+}
+
+func NewUpdateMetadataCmd(updater SecretMetadataUpdater) UpdateMetadataCmd {
+	return UpdateMetadataCmd{
+		updater: updater,
+	}
+}
+
+// Execute replaces secret metadata, the secret data is not changed.
+func (updateCmd UpdateMetadataCmd) Execute(id, version int64, metadata map[string]string, jwt string) (api.SecretInfo, error) {
+	updateCmd.updater.SetJWT(jwt)
+	return updateCmd.updater.UpdateSecretMetadata(context.TODO(), id, version, metadata)
+}
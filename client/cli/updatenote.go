@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"context"
+	"io"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type NoteUpdater interface {
+	UpdateNote(ctx context.Context, id, version int64, title, body string, metadata map[string]string) (api.SecretInfo, error)
+	SetJWT(jwt string)
+}
+
+type UpdateNoteCmd struct {
+	updater NoteUpdater
+	stdin   io.Reader
+}
+
+func NewUpdateNoteCmd(updater NoteUpdater, stdin io.Reader) UpdateNoteCmd {
+	return UpdateNoteCmd{
+		updater: updater,
+		stdin:   stdin,
+	}
+}
+
+// Execute reads the note body from bodyPath or from stdin if bodyPath is empty or "-".
+func (updateCmd UpdateNoteCmd) Execute(id, version int64, title, bodyPath string, metadata map[string]string, jwt string) (api.SecretInfo, error) {
+	body, err := readNoteBody(bodyPath, updateCmd.stdin)
+	if err != nil {
+		return api.SecretInfo{}, err
+	}
+	updateCmd.updater.SetJWT(jwt)
+	return updateCmd.updater.UpdateNote(
+		context.TODO(),
+		id,
+		version,
+		title,
+		body,
+		metadata,
+	)
+}
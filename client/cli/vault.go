@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type VaultManager interface {
+	CreateVault(ctx context.Context, orgID int64, name string) (api.Vault, error)
+	ListVaults(ctx context.Context) ([]api.Vault, error)
+	SetJWT(jwt string)
+}
+
+type VaultCmd struct {
+	manager VaultManager
+	stdout  io.Writer
+}
+
+func NewVaultCmd(manager VaultManager, stdout io.Writer) VaultCmd {
+	return VaultCmd{
+		manager: manager,
+		stdout:  stdout,
+	}
+}
+
+// Execute creates a vault of the organization with the name if it is
+// set, otherwise it lists vaults available to the user.
+func (vaultCmd VaultCmd) Execute(orgID int64, name string, jwt string) error {
+	vaultCmd.manager.SetJWT(jwt)
+	if name != "" {
+		vault, err := vaultCmd.manager.CreateVault(context.TODO(), orgID, name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(vaultCmd.stdout, "id=%d\n", vault.ID)
+		return nil
+	}
+
+	vaults, err := vaultCmd.manager.ListVaults(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(vaultCmd.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tORGANIZATION ID\tNAME\tCREATED AT")
+	for _, vault := range vaults {
+		fmt.Fprintf(
+			writer,
+			"%d\t%d\t%s\t%s\n",
+			vault.ID,
+			vault.OrganizationID,
+			vault.Name,
+			vault.CreatedAt.Local().Format(time.DateTime),
+		)
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ilya-burinskiy/gophkeeper/client/api"
+)
+
+type SecretWatcher interface {
+	WatchSecrets(ctx context.Context, handle func(api.SecretEvent) error) error
+	SetJWT(jwt string)
+}
+
+type WatchCmd struct {
+	watcher SecretWatcher
+	stdout  io.Writer
+}
+
+func NewWatchCmd(watcher SecretWatcher, stdout io.Writer) WatchCmd {
+	return WatchCmd{
+		watcher: watcher,
+		stdout:  stdout,
+	}
+}
+
+// Execute prints changes of secrets the user can read as they happen, one
+// per line.
+func (watchCmd WatchCmd) Execute(jwt string) error {
+	watchCmd.watcher.SetJWT(jwt)
+	var lastRevision int64
+	err := watchCmd.watcher.WatchSecrets(context.TODO(), func(event api.SecretEvent) error {
+		if event.Revision == 0 {
+			_, err := fmt.Fprintf(watchCmd.stdout, "%s id=%d\n", event.Kind, event.ID)
+			return err
+		}
+		lastRevision = event.Revision
+		_, err := fmt.Fprintf(watchCmd.stdout, "%s id=%d revision=%d\n", event.Kind, event.ID, event.Revision)
+		return err
+	})
+	if errors.Is(err, api.ErrEventStreamClosed) {
+		return fmt.Errorf("%w, changes after revision %d may have been missed, run sync", err, lastRevision)
+	}
+
+	return err
+}
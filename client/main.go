@@ -1,9 +1,16 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ilya-burinskiy/gophkeeper/client/api"
 	"github.com/ilya-burinskiy/gophkeeper/client/cli"
@@ -18,91 +25,343 @@ func main() {
 
 	cmd, args := args[0], args[1:]
 	client := api.NewGophkeeperClient(os.Getenv("BASE_URL"))
+	client.SetAPIToken(os.Getenv("API_TOKEN"))
+	if clientID := os.Getenv("SERVICE_ACCOUNT_CLIENT_ID"); clientID != "" {
+		authenticateServiceAccount(client, clientID)
+	}
+	stateDir := stateDirPath()
+	client.SetUploadStore(api.NewFileStateStore(filepath.Join(stateDir, "uploads.json")))
 	switch cmd {
 	case "register":
 		execRegisterCmd(args, client)
 	case "authenticate":
 		execAuthenticateCmd(args, client)
+	case "enable-totp":
+		execEnableTOTPCmd(args, client)
+	case "refresh":
+		execRefreshCmd(args, client)
+	case "logout":
+		execLogoutCmd(args, client)
+	case "sessions":
+		execSessionsCmd(args, client)
 	case "get-secrets":
 		execGetSecretsCmd(args, client)
+	case "get":
+		execGetSecretCmd(args, client, stateDir)
+	case "list":
+		execListSecretsCmd(args, client)
 	case "create-creds":
 		execCreateCredsCmd(args, client)
 	case "create-credit-card":
 		execCreateCreditCardCmd(args, client)
+	case "create-note":
+		execCreateNoteCmd(args, client)
 	case "create-bin-data":
 		execCreateBinDataCmd(args, client)
 	case "update-creds":
 		execUpdateCredsCmd(args, client)
 	case "update-credit-card":
 		execUpdateCreditCardCmd(args, client)
+	case "update-note":
+		execUpdateNoteCmd(args, client)
 	case "update-bin-data":
 		execUpdateBinDataCmd(args, client)
+	case "update-meta":
+		execUpdateMetadataCmd(args, client)
 	case "delete":
 		execDeleteCmd(args, client)
+	case "create-folder":
+		execCreateFolderCmd(args, client)
+	case "list-folders":
+		execListFoldersCmd(args, client)
+	case "update-folder":
+		execUpdateFolderCmd(args, client)
+	case "move":
+		execMoveSecretCmd(args, client)
+	case "create-tag":
+		execCreateTagCmd(args, client)
+	case "list-tags":
+		execListTagsCmd(args, client)
+	case "rename-tag":
+		execRenameTagCmd(args, client)
+	case "tag":
+		execSetTagsCmd(args, client)
+	case "history":
+		execHistoryCmd(args, client)
+	case "restore":
+		execRestoreCmd(args, client)
+	case "set-retention":
+		execSetRetentionCmd(args, client)
+	case "trash":
+		execTrashCmd(args, client)
+	case "sync":
+		execSyncCmd(args, client, stateDir)
+	case "watch":
+		execWatchCmd(args, client)
+	case "share":
+		execShareCmd(args, client)
+	case "org":
+		execOrganizationCmd(args, client)
+	case "members":
+		execMembersCmd(args, client)
+	case "vault":
+		execVaultCmd(args, client)
+	case "audit":
+		execAuditCmd(args, client)
+	case "token":
+		execAPITokenCmd(args, client)
+	case "service-account":
+		execServiceAccountCmd(args, client)
 	default:
 		log.Fatal("invalid command")
 	}
 }
 
+// authenticateServiceAccount authenticates requests with the access token
+// of the service account, the token is obtained with the private key from
+// SERVICE_ACCOUNT_KEY_FILE if it is set, otherwise with the client secret
+// from SERVICE_ACCOUNT_SECRET.
+func authenticateServiceAccount(client *api.GophkeeperClient, clientID string) {
+	var token string
+	var err error
+	if keyFile := os.Getenv("SERVICE_ACCOUNT_KEY_FILE"); keyFile != "" {
+		keyID, parseErr := strconv.ParseInt(os.Getenv("SERVICE_ACCOUNT_KEY_ID"), 10, 64)
+		if parseErr != nil {
+			log.Fatal("invalid SERVICE_ACCOUNT_KEY_ID: ", parseErr)
+		}
+		privateKey, readErr := os.ReadFile(keyFile)
+		if readErr != nil {
+			log.Fatal("failed to read SERVICE_ACCOUNT_KEY_FILE: ", readErr)
+		}
+		token, err = client.AuthenticateServiceAccountWithKey(context.TODO(), clientID, keyID, privateKey)
+	} else {
+		token, err = client.AuthenticateServiceAccount(context.TODO(), clientID, os.Getenv("SERVICE_ACCOUNT_SECRET"))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	client.SetAPIToken(token)
+}
+
+// stateDirPath returns the directory of uploads and downloads state.
+func stateDirPath() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+
+	return filepath.Join(cacheDir, "gophkeeper")
+}
+
+// metadataFlag collects repeated -meta key=value flags.
+type metadataFlag map[string]string
+
+func (metadata metadataFlag) String() string {
+	pairs := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+func (metadata metadataFlag) Set(pair string) error {
+	key, value, ok := strings.Cut(pair, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("invalid metadata %q, key=value expected", pair)
+	}
+	metadata[key] = value
+
+	return nil
+}
+
+// value returns nil if no flag has been set, so metadata of the updated
+// secret is kept.
+func (metadata metadataFlag) value() map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	return metadata
+}
+
 func execRegisterCmd(args []string, client *api.GophkeeperClient) {
 	flagSet := flag.NewFlagSet("register", flag.ExitOnError)
 	var login, password string
+	var totp bool
 	flagSet.StringVar(&login, "login", "", "your login")
 	flagSet.StringVar(&password, "password", "", "your password")
+	flagSet.BoolVar(&totp, "totp", false, "enable TOTP two-factor authentication")
 	err := flagSet.Parse(args)
 	if err != nil {
 		log.Fatal("failed to parse register flags", err)
 	}
 
-	regCmd := cli.NewRegisterCmd(client)
-	err = regCmd.Execute(login, password)
+	regCmd := cli.NewRegisterCmd(client, os.Stdin, os.Stdout)
+	tokens, err := regCmd.Execute(login, password, totp)
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Println("Success")
+	log.Println("jwt=", tokens.AccessToken)
+	log.Println("refresh_token=", tokens.RefreshToken)
 }
 
 func execAuthenticateCmd(args []string, client *api.GophkeeperClient) {
 	flagSet := flag.NewFlagSet("authenticate", flag.ExitOnError)
-	var login, password string
+	var login, password, code string
 	flagSet.StringVar(&login, "login", "", "your login")
 	flagSet.StringVar(&password, "password", "", "your password")
+	flagSet.StringVar(&code, "code", "", "TOTP code or recovery code (asked for if TOTP is enabled)")
 	err := flagSet.Parse(args)
 	if err != nil {
 		log.Fatal("failed to parse authenticate flags", err)
 	}
 
-	authCmd := cli.NewAuthenticateCmd(client)
-	jwtStr, err := authCmd.Execute(login, password)
+	authCmd := cli.NewAuthenticateCmd(client, os.Stdin, os.Stdout)
+	tokens, err := authCmd.Execute(login, password, code)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("jwt=", tokens.AccessToken)
+	log.Println("refresh_token=", tokens.RefreshToken)
+}
+
+func execEnableTOTPCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("enable-totp", flag.ExitOnError)
+	var jwt string
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse enable-totp flags", err)
+	}
+
+	totpCmd := cli.NewEnableTOTPCmd(client, os.Stdin, os.Stdout)
+	if err := totpCmd.Execute(jwt); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func execRefreshCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("refresh", flag.ExitOnError)
+	var refreshToken string
+	flagSet.StringVar(&refreshToken, "refresh-token", "", "refresh token")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse refresh flags", err)
+	}
+
+	refreshCmd := cli.NewRefreshCmd(client)
+	tokens, err := refreshCmd.Execute(refreshToken)
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Println("jwt=", jwtStr)
+	log.Println("jwt=", tokens.AccessToken)
+	log.Println("refresh_token=", tokens.RefreshToken)
+}
+
+func execLogoutCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("logout", flag.ExitOnError)
+	var jwt string
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse logout flags", err)
+	}
+
+	logoutCmd := cli.NewLogoutCmd(client)
+	if err := logoutCmd.Execute(jwt); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Success")
+}
+
+func execSessionsCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("sessions", flag.ExitOnError)
+	var revokeID int64
+	var revokeOthers bool
+	var jwt string
+	flagSet.Int64Var(&revokeID, "revoke", 0, "ID of the session to revoke (sessions are listed if neither -revoke nor -revoke-others is set)")
+	flagSet.BoolVar(&revokeOthers, "revoke-others", false, "revoke all sessions except the session of the JWT")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse sessions flags", err)
+	}
+
+	sessionsCmd := cli.NewSessionsCmd(client, os.Stdout)
+	if err := sessionsCmd.Execute(revokeID, revokeOthers, jwt); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func execGetSecretsCmd(args []string, client *api.GophkeeperClient) {
 	flagSet := flag.NewFlagSet("get-secrets", flag.ExitOnError)
+	var params api.GetSecretsParams
 	var outputFname, jwt string
 	flagSet.StringVar(&outputFname, "output", "archive.zip", "output filename")
+	flagSet.Int64Var(&params.FolderID, "folder", 0, "folder ID, secrets of the folder and its subfolders are saved")
+	flagSet.Int64Var(&params.TagID, "tag", 0, "tag ID")
+	flagSet.Int64Var(&params.VaultID, "vault", 0, "vault ID, secrets of the vault are selected instead of your secrets")
 	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
 	err := flagSet.Parse(args)
 	if err != nil {
 		log.Fatal("failed to parse get-secrets flags", err)
 	}
 
-	getCmd := cli.NewGetSecretCmd(client)
-	err = getCmd.Execute(outputFname, jwt)
+	getCmd := cli.NewGetSecretsCmd(client)
+	err = getCmd.Execute(outputFname, params, jwt)
 	if err != nil {
 		log.Fatal(err)
 	}
 	log.Println("Success")
 }
 
+func execGetSecretCmd(args []string, client *api.GophkeeperClient, stateDir string) {
+	flagSet := flag.NewFlagSet("get", flag.ExitOnError)
+	var id int64
+	var outputFname, jwt string
+	flagSet.Int64Var(&id, "id", 0, "secret ID")
+	flagSet.StringVar(&outputFname, "output", "", "output filename (bin data is saved under its original name by default)")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse get flags", err)
+	}
+
+	downloads := api.NewFileStateStore(filepath.Join(stateDir, "downloads.json"))
+	getCmd := cli.NewGetSecretCmd(client, downloads, os.Stdout)
+	if err := getCmd.Execute(id, outputFname, jwt); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func execListSecretsCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("list", flag.ExitOnError)
+	var params api.ListSecretsParams
+	var jwt string
+	flagSet.Int64Var(&params.Cursor, "cursor", 0, "list secrets with ID greater than cursor")
+	flagSet.IntVar(&params.Limit, "limit", 0, "page size")
+	flagSet.StringVar(&params.SecretType, "type", "", "secret type (credentials, credit_card_info, text or bin_data)")
+	flagSet.StringVar(&params.Description, "description", "", "description substring")
+	flagSet.Int64Var(&params.FolderID, "folder", 0, "folder ID, secrets of the folder and its subfolders are listed")
+	flagSet.Int64Var(&params.TagID, "tag", 0, "tag ID")
+	flagSet.Int64Var(&params.VaultID, "vault", 0, "vault ID, secrets of the vault are selected instead of your secrets")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse list flags", err)
+	}
+
+	listCmd := cli.NewListSecretsCmd(client, os.Stdout)
+	if err := listCmd.Execute(params, jwt); err != nil {
+		log.Fatal(err)
+	}
+}
+
 func execCreateCredsCmd(args []string, client *api.GophkeeperClient) {
 	flagSet := flag.NewFlagSet("create-creds", flag.ExitOnError)
+	var vaultID int64
 	var login, password, jwt string
 	flagSet.StringVar(&login, "login", "", "login")
 	flagSet.StringVar(&password, "password", "", "password")
+	flagSet.Int64Var(&vaultID, "vault", 0, "vault ID (the secret is created in the vault if set)")
+	metadata := metadataFlag{}
+	flagSet.Var(metadata, "meta", "secret metadata in key=value format, may be repeated")
 	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
 	err := flagSet.Parse(args)
 	if err != nil {
@@ -110,20 +369,24 @@ func execCreateCredsCmd(args []string, client *api.GophkeeperClient) {
 	}
 
 	createCmd := cli.NewCreateCredentialsCmd(client)
-	err = createCmd.Execute(login, password, jwt)
+	info, err := createCmd.Execute(vaultID, login, password, metadata.value(), jwt)
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Println("Success")
+	log.Printf("Success id=%d\n", info.ID)
 }
 
 func execCreateCreditCardCmd(args []string, client *api.GophkeeperClient) {
 	flagSet := flag.NewFlagSet("create-credit-card", flag.ExitOnError)
+	var vaultID int64
 	var number, name, expiryDate, cvv2, jwt string
 	flagSet.StringVar(&number, "number", "", "credit card number")
 	flagSet.StringVar(&name, "name", "", "credit card owner name")
 	flagSet.StringVar(&expiryDate, "date", "", "credit card expriry date in RFC3339 format")
 	flagSet.StringVar(&cvv2, "cvv2", "", "credit card CVV2")
+	flagSet.Int64Var(&vaultID, "vault", 0, "vault ID (the secret is created in the vault if set)")
+	metadata := metadataFlag{}
+	flagSet.Var(metadata, "meta", "secret metadata in key=value format, may be repeated")
 	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
 	err := flagSet.Parse(args)
 	if err != nil {
@@ -131,36 +394,66 @@ func execCreateCreditCardCmd(args []string, client *api.GophkeeperClient) {
 	}
 
 	createCmd := cli.NewCreateCreditCardCmd(client)
-	err = createCmd.Execute(number, name, expiryDate, cvv2, jwt)
+	info, err := createCmd.Execute(vaultID, number, name, expiryDate, cvv2, metadata.value(), jwt)
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Println("Success")
+	log.Printf("Success id=%d\n", info.ID)
+}
+
+func execCreateNoteCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("create-note", flag.ExitOnError)
+	var vaultID int64
+	var title, bodyPath, jwt string
+	flagSet.StringVar(&title, "title", "", "note title")
+	flagSet.StringVar(&bodyPath, "file", "", "file with note body (stdin by default)")
+	flagSet.Int64Var(&vaultID, "vault", 0, "vault ID (the secret is created in the vault if set)")
+	metadata := metadataFlag{}
+	flagSet.Var(metadata, "meta", "secret metadata in key=value format, may be repeated")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse create-note flags", err)
+	}
+
+	createCmd := cli.NewCreateNoteCmd(client, os.Stdin)
+	info, err := createCmd.Execute(vaultID, title, bodyPath, metadata.value(), jwt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Success id=%d\n", info.ID)
 }
 
 func execCreateBinDataCmd(args []string, client *api.GophkeeperClient) {
 	flagSet := flag.NewFlagSet("create-bin-data", flag.ExitOnError)
+	var vaultID int64
 	var filepath, jwt string
 	flagSet.StringVar(&filepath, "path", "", "file path")
+	flagSet.Int64Var(&vaultID, "vault", 0, "vault ID (the secret is created in the vault if set)")
+	metadata := metadataFlag{}
+	flagSet.Var(metadata, "meta", "secret metadata in key=value format, may be repeated")
 	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
 	if err := flagSet.Parse(args); err != nil {
 		log.Fatal("failed to parse create-bin-data flags", err)
 	}
 
 	createCmd := cli.NewCreateBinDataCmd(client)
-	if err := createCmd.Execute(filepath, jwt); err != nil {
+	info, err := createCmd.Execute(vaultID, filepath, metadata.value(), jwt)
+	if err != nil {
 		log.Fatal(err)
 	}
-	log.Println("Success")
+	log.Printf("Success id=%d\n", info.ID)
 }
 
 func execUpdateCredsCmd(args []string, client *api.GophkeeperClient) {
 	flagSet := flag.NewFlagSet("update-creds", flag.ExitOnError)
-	var id int64
+	var id, version int64
 	var login, password, jwt string
 	flagSet.Int64Var(&id, "id", 0, "credentials ID")
+	flagSet.Int64Var(&version, "if-version", 0, "fail if the secret has been changed since this version")
 	flagSet.StringVar(&login, "login", "", "login")
 	flagSet.StringVar(&password, "password", "", "password")
+	metadata := metadataFlag{}
+	flagSet.Var(metadata, "meta", "secret metadata in key=value format, may be repeated")
 	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
 	err := flagSet.Parse(args)
 	if err != nil {
@@ -168,22 +461,25 @@ func execUpdateCredsCmd(args []string, client *api.GophkeeperClient) {
 	}
 
 	updateCmd := cli.NewUpdateCredentialsCmd(client)
-	err = updateCmd.Execute(id, login, password, jwt)
+	info, err := updateCmd.Execute(id, version, login, password, metadata.value(), jwt)
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Println("Success")
+	log.Printf("Success id=%d\n", info.ID)
 }
 
 func execUpdateCreditCardCmd(args []string, client *api.GophkeeperClient) {
 	flagSet := flag.NewFlagSet("update-credit-card", flag.ExitOnError)
-	var id int64
+	var id, version int64
 	var number, name, expiryDate, cvv2, jwt string
 	flagSet.Int64Var(&id, "id", 0, "credit card ID")
+	flagSet.Int64Var(&version, "if-version", 0, "fail if the secret has been changed since this version")
 	flagSet.StringVar(&number, "number", "", "credit card number")
 	flagSet.StringVar(&name, "name", "", "credit card owner name")
 	flagSet.StringVar(&expiryDate, "date", "", "credit card expriry date in RFC3339 format")
 	flagSet.StringVar(&cvv2, "cvv2", "", "credit card CVV2")
+	metadata := metadataFlag{}
+	flagSet.Var(metadata, "meta", "secret metadata in key=value format, may be repeated")
 	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
 	err := flagSet.Parse(args)
 	if err != nil {
@@ -191,44 +487,565 @@ func execUpdateCreditCardCmd(args []string, client *api.GophkeeperClient) {
 	}
 
 	updateCmd := cli.NewUpdateCreditCardCmd(client)
-	err = updateCmd.Execute(id, number, name, expiryDate, cvv2, jwt)
+	info, err := updateCmd.Execute(id, version, number, name, expiryDate, cvv2, metadata.value(), jwt)
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Println("Success")
+	log.Printf("Success id=%d\n", info.ID)
+}
+
+func execUpdateNoteCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("update-note", flag.ExitOnError)
+	var id, version int64
+	var title, bodyPath, jwt string
+	flagSet.Int64Var(&id, "id", 0, "note ID")
+	flagSet.Int64Var(&version, "if-version", 0, "fail if the secret has been changed since this version")
+	flagSet.StringVar(&title, "title", "", "note title")
+	flagSet.StringVar(&bodyPath, "file", "", "file with note body (stdin by default)")
+	metadata := metadataFlag{}
+	flagSet.Var(metadata, "meta", "secret metadata in key=value format, may be repeated")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse update-note flags", err)
+	}
+
+	updateCmd := cli.NewUpdateNoteCmd(client, os.Stdin)
+	info, err := updateCmd.Execute(id, version, title, bodyPath, metadata.value(), jwt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Success id=%d\n", info.ID)
 }
 
 func execUpdateBinDataCmd(args []string, client *api.GophkeeperClient) {
 	flagSet := flag.NewFlagSet("update-bin-data", flag.ExitOnError)
-	var id int64
+	var id, version int64
 	var filepath, jwt string
 	flagSet.Int64Var(&id, "id", 0, "bin data ID")
+	flagSet.Int64Var(&version, "if-version", 0, "fail if the secret has been changed since this version")
 	flagSet.StringVar(&filepath, "path", "", "file path")
+	metadata := metadataFlag{}
+	flagSet.Var(metadata, "meta", "secret metadata in key=value format, may be repeated")
 	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
 	if err := flagSet.Parse(args); err != nil {
 		log.Fatal("failed to parse update-bin-data flags", err)
 	}
 
 	updateCmd := cli.NewUpdateBinDataCmd(client)
-	if err := updateCmd.Execute(id, filepath, jwt); err != nil {
+	info, err := updateCmd.Execute(id, version, filepath, metadata.value(), jwt)
+	if err != nil {
 		log.Fatal(err)
 	}
-	log.Println("Success")
+	log.Printf("Success id=%d\n", info.ID)
+}
+
+func execUpdateMetadataCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("update-meta", flag.ExitOnError)
+	var id, version int64
+	var jwt string
+	metadata := metadataFlag{}
+	flagSet.Int64Var(&id, "id", 0, "secret ID")
+	flagSet.Int64Var(&version, "if-version", 0, "fail if the secret has been changed since this version")
+	flagSet.Var(metadata, "meta", "secret metadata in key=value format, may be repeated (all metadata is removed if not set)")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse update-meta flags", err)
+	}
+
+	updateCmd := cli.NewUpdateMetadataCmd(client)
+	info, err := updateCmd.Execute(id, version, metadata, jwt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Success id=%d\n", info.ID)
 }
 
 func execDeleteCmd(args []string, client *api.GophkeeperClient) {
 	flagSet := flag.NewFlagSet("delete", flag.ExitOnError)
-	var id int64
+	var id, version int64
+	var permanent bool
 	var jwt string
 	flagSet.Int64Var(&id, "id", 0, "secret ID")
+	flagSet.BoolVar(&permanent, "permanent", false, "delete the secret permanently instead of moving it to the trash")
+	flagSet.Int64Var(&version, "if-version", 0, "fail if the secret has been changed since this version")
 	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
 	if err := flagSet.Parse(args); err != nil {
 		log.Fatal("failed to parse delete flags")
 	}
 
 	delCmd := cli.NewDeleteSecretCmd(client)
-	if err := delCmd.Execute(id, jwt); err != nil {
+	if err := delCmd.Execute(id, permanent, version, jwt); err != nil {
 		log.Fatal(err)
 	}
 	log.Println("Success")
 }
+
+func execCreateFolderCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("create-folder", flag.ExitOnError)
+	var name, jwt string
+	var parentID int64
+	flagSet.StringVar(&name, "name", "", "folder name")
+	flagSet.Int64Var(&parentID, "parent", 0, "parent folder ID (top level folder is created if not set)")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse create-folder flags", err)
+	}
+
+	createCmd := cli.NewCreateFolderCmd(client)
+	folder, err := createCmd.Execute(name, parentID, jwt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Success id=%d\n", folder.ID)
+}
+
+func execListFoldersCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("list-folders", flag.ExitOnError)
+	var jwt string
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse list-folders flags", err)
+	}
+
+	listCmd := cli.NewListFoldersCmd(client, os.Stdout)
+	if err := listCmd.Execute(jwt); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func execUpdateFolderCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("update-folder", flag.ExitOnError)
+	var id, parentID int64
+	var name, jwt string
+	flagSet.Int64Var(&id, "id", 0, "folder ID")
+	flagSet.StringVar(&name, "name", "", "new folder name")
+	flagSet.Int64Var(&parentID, "parent", 0, "new parent folder ID, 0 moves the folder to the top level")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse update-folder flags", err)
+	}
+
+	// only explicitly set flags are changed
+	var params api.UpdateFolderParams
+	flagSet.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			params.Name = &name
+		case "parent":
+			params.ParentID = &parentID
+		}
+	})
+	updateCmd := cli.NewUpdateFolderCmd(client)
+	folder, err := updateCmd.Execute(id, params, jwt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Success id=%d\n", folder.ID)
+}
+
+func execMoveSecretCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("move", flag.ExitOnError)
+	var id, folderID int64
+	var jwt string
+	flagSet.Int64Var(&id, "id", 0, "secret ID")
+	flagSet.Int64Var(&folderID, "folder", 0, "folder ID, 0 moves the secret out of any folder")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse move flags", err)
+	}
+
+	moveCmd := cli.NewMoveSecretCmd(client)
+	info, err := moveCmd.Execute(id, folderID, jwt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Success id=%d\n", info.ID)
+}
+
+func execCreateTagCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("create-tag", flag.ExitOnError)
+	var name, jwt string
+	flagSet.StringVar(&name, "name", "", "tag name")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse create-tag flags", err)
+	}
+
+	createCmd := cli.NewCreateTagCmd(client)
+	tag, err := createCmd.Execute(name, jwt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Success id=%d\n", tag.ID)
+}
+
+func execListTagsCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("list-tags", flag.ExitOnError)
+	var jwt string
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse list-tags flags", err)
+	}
+
+	listCmd := cli.NewListTagsCmd(client, os.Stdout)
+	if err := listCmd.Execute(jwt); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func execRenameTagCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("rename-tag", flag.ExitOnError)
+	var id int64
+	var name, jwt string
+	flagSet.Int64Var(&id, "id", 0, "tag ID")
+	flagSet.StringVar(&name, "name", "", "new tag name")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse rename-tag flags", err)
+	}
+
+	renameCmd := cli.NewRenameTagCmd(client)
+	tag, err := renameCmd.Execute(id, name, jwt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Success id=%d\n", tag.ID)
+}
+
+func execSetTagsCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("tag", flag.ExitOnError)
+	var id int64
+	var tagsStr, jwt string
+	flagSet.Int64Var(&id, "id", 0, "secret ID")
+	flagSet.StringVar(&tagsStr, "tags", "", "comma separated tag IDs (all tags are removed if empty)")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse tag flags", err)
+	}
+
+	var tagIDs []int64
+	for _, tagIDStr := range strings.Split(tagsStr, ",") {
+		tagIDStr = strings.TrimSpace(tagIDStr)
+		if tagIDStr == "" {
+			continue
+		}
+		tagID, err := strconv.ParseInt(tagIDStr, 10, 64)
+		if err != nil {
+			log.Fatalf("invalid tag ID %q", tagIDStr)
+		}
+		tagIDs = append(tagIDs, tagID)
+	}
+	setCmd := cli.NewSetTagsCmd(client)
+	info, err := setCmd.Execute(id, tagIDs, jwt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Success id=%d\n", info.ID)
+}
+
+func execHistoryCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("history", flag.ExitOnError)
+	var id int64
+	var version int
+	var outputFname, jwt string
+	flagSet.Int64Var(&id, "id", 0, "secret ID")
+	flagSet.IntVar(&version, "version", 0, "revision version to show (revisions are listed if not set)")
+	flagSet.StringVar(&outputFname, "output", "", "output filename (bin data is saved under its original name by default)")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse history flags", err)
+	}
+
+	historyCmd := cli.NewHistoryCmd(client, os.Stdout)
+	if err := historyCmd.Execute(id, version, outputFname, jwt); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func execRestoreCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("restore", flag.ExitOnError)
+	var id int64
+	var version int
+	var jwt string
+	flagSet.Int64Var(&id, "id", 0, "secret ID")
+	flagSet.IntVar(&version, "version", 0, "revision version (the secret is restored from the trash if not set)")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse restore flags", err)
+	}
+
+	restoreCmd := cli.NewRestoreCmd(client)
+	if err := restoreCmd.Execute(id, version, jwt); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Success id=%d\n", id)
+}
+
+func execSetRetentionCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("set-retention", flag.ExitOnError)
+	var count int
+	var jwt string
+	flagSet.IntVar(&count, "count", 10, "number of revisions kept for every secret (0 disables history)")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse set-retention flags", err)
+	}
+
+	setCmd := cli.NewSetRetentionCmd(client)
+	if err := setCmd.Execute(count, jwt); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Success")
+}
+
+func execTrashCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("trash", flag.ExitOnError)
+	var purgeID int64
+	var jwt string
+	flagSet.Int64Var(&purgeID, "purge", 0, "ID of the trashed secret to delete permanently (trash is listed if not set)")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse trash flags", err)
+	}
+
+	trashCmd := cli.NewTrashCmd(client, os.Stdout)
+	if err := trashCmd.Execute(purgeID, jwt); err != nil {
+		log.Fatal(err)
+	}
+	if purgeID != 0 {
+		log.Printf("Success id=%d\n", purgeID)
+	}
+}
+
+func execSyncCmd(args []string, client *api.GophkeeperClient, stateDir string) {
+	flagSet := flag.NewFlagSet("sync", flag.ExitOnError)
+	var dir, jwt string
+	flagSet.StringVar(&dir, "dir", filepath.Join(stateDir, "mirror"), "local mirror directory of personal secrets")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse sync flags", err)
+	}
+
+	syncCmd := cli.NewSyncCmd(client, os.Stdout)
+	if err := syncCmd.Execute(dir, jwt); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func execWatchCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("watch", flag.ExitOnError)
+	var jwt string
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse watch flags", err)
+	}
+
+	watchCmd := cli.NewWatchCmd(client, os.Stdout)
+	if err := watchCmd.Execute(jwt); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func execShareCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("share", flag.ExitOnError)
+	var id, revokeUserID int64
+	var login, access, jwt string
+	flagSet.Int64Var(&id, "id", 0, "secret ID")
+	flagSet.StringVar(&login, "login", "", "login of the user to share the secret with")
+	flagSet.StringVar(&access, "access", "read", "access given to the user (read or write)")
+	flagSet.Int64Var(&revokeUserID, "revoke", 0, "ID of the user to revoke access from (shares are listed if neither -login nor -revoke is set)")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse share flags", err)
+	}
+
+	shareCmd := cli.NewShareCmd(client, os.Stdout)
+	if err := shareCmd.Execute(id, login, access, revokeUserID, jwt); err != nil {
+		log.Fatal(err)
+	}
+	if login != "" || revokeUserID != 0 {
+		log.Println("Success")
+	}
+}
+
+func execOrganizationCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("org", flag.ExitOnError)
+	var name, jwt string
+	flagSet.StringVar(&name, "create", "", "name of the organization to create (organizations are listed if not set)")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse org flags", err)
+	}
+
+	orgCmd := cli.NewOrganizationCmd(client, os.Stdout)
+	if err := orgCmd.Execute(name, jwt); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func execMembersCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("members", flag.ExitOnError)
+	var orgID, roleUserID, removeUserID int64
+	var accept bool
+	var login, role, jwt string
+	flagSet.Int64Var(&orgID, "org", 0, "organization ID")
+	flagSet.StringVar(&login, "invite", "", "login of the user to invite")
+	flagSet.StringVar(&role, "role", "", "role of the invited user or the new role of the user from -set-role (viewer, editor or admin, editor is given to invited users by default)")
+	flagSet.BoolVar(&accept, "accept", false, "accept the invitation to the organization")
+	flagSet.Int64Var(&roleUserID, "set-role", 0, "ID of the user to give the role from -role")
+	flagSet.Int64Var(&removeUserID, "remove", 0, "ID of the user to remove (members are listed if neither -invite, -accept, -set-role nor -remove is set)")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse members flags", err)
+	}
+
+	membersCmd := cli.NewMembersCmd(client, os.Stdout)
+	if err := membersCmd.Execute(orgID, login, role, accept, roleUserID, removeUserID, jwt); err != nil {
+		log.Fatal(err)
+	}
+	if login != "" || accept || roleUserID != 0 || removeUserID != 0 {
+		log.Println("Success")
+	}
+}
+
+func execVaultCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("vault", flag.ExitOnError)
+	var orgID int64
+	var name, jwt string
+	flagSet.Int64Var(&orgID, "org", 0, "organization ID of the created vault")
+	flagSet.StringVar(&name, "create", "", "name of the vault to create (vaults are listed if not set)")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse vault flags", err)
+	}
+
+	vaultCmd := cli.NewVaultCmd(client, os.Stdout)
+	if err := vaultCmd.Execute(orgID, name, jwt); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func execAuditCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("audit", flag.ExitOnError)
+	var params api.AuditParams
+	var from, to, jwt string
+	flagSet.StringVar(&from, "from", "", "select events since the time (RFC 3339 or YYYY-MM-DD)")
+	flagSet.StringVar(&to, "to", "", "select events before the time (RFC 3339 or YYYY-MM-DD)")
+	flagSet.Int64Var(&params.SecretID, "secret", 0, "select events of the secret")
+	flagSet.IntVar(&params.Limit, "limit", 0, "maximum number of events")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse audit flags", err)
+	}
+	var err error
+	if params.From, err = parseTimeFlag(from); err != nil {
+		log.Fatal("invalid -from: ", err)
+	}
+	if params.To, err = parseTimeFlag(to); err != nil {
+		log.Fatal("invalid -to: ", err)
+	}
+
+	auditCmd := cli.NewAuditCmd(client, os.Stdout)
+	if err := auditCmd.Execute(params, jwt); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseTimeFlag parses RFC 3339 time or a date in the local time zone,
+// empty value is zero time.
+func parseTimeFlag(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+func execAPITokenCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("token", flag.ExitOnError)
+	var params api.CreateAPITokenParams
+	var types, secretIDs, jwt string
+	var expires time.Duration
+	var revokeID int64
+	flagSet.StringVar(&params.Name, "create", "", "name of the token to create (tokens are listed if neither -create nor -revoke is set)")
+	flagSet.BoolVar(&params.ReadOnly, "read-only", false, "allow the created token to read secrets only")
+	flagSet.StringVar(&types, "types", "", "comma separated secret types the created token is limited to (credentials, credit_card_info, text, bin_data)")
+	flagSet.StringVar(&secretIDs, "secrets", "", "comma separated secret IDs the created token is limited to")
+	flagSet.DurationVar(&expires, "expires", 0, "lifetime of the created token, for example 720h (the token never expires if not set)")
+	flagSet.Int64Var(&revokeID, "revoke", 0, "ID of the token to revoke")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse token flags", err)
+	}
+	if types != "" {
+		params.SecretTypes = strings.Split(types, ",")
+	}
+	if secretIDs != "" {
+		for _, idStr := range strings.Split(secretIDs, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64)
+			if err != nil {
+				log.Fatalf("invalid secret id %q", idStr)
+			}
+			params.SecretIDs = append(params.SecretIDs, id)
+		}
+	}
+	if expires > 0 {
+		expiresAt := time.Now().Add(expires)
+		params.ExpiresAt = &expiresAt
+	}
+
+	tokenCmd := cli.NewAPITokenCmd(client, os.Stdout)
+	if err := tokenCmd.Execute(params, revokeID, jwt); err != nil {
+		log.Fatal(err)
+	}
+	if revokeID != 0 && params.Name == "" {
+		log.Println("Success")
+	}
+}
+
+func execServiceAccountCmd(args []string, client *api.GophkeeperClient) {
+	flagSet := flag.NewFlagSet("service-account", flag.ExitOnError)
+	var params cli.ServiceAccountParams
+	var keyFile, from, to, jwt string
+	flagSet.Int64Var(&params.OrgID, "org", 0, "organization ID")
+	flagSet.StringVar(&params.Create, "create", "", "name of the service account to create (service accounts are listed if neither -create nor -account is set)")
+	flagSet.StringVar(&params.Role, "role", "", "role of the created service account (viewer, editor or admin, viewer is given by default)")
+	flagSet.Int64Var(&params.AccountID, "account", 0, "ID of the service account to manage (its credentials are listed if no action is set)")
+	flagSet.BoolVar(&params.Disable, "disable", false, "disable the service account")
+	flagSet.BoolVar(&params.AddSecret, "add-secret", false, "create a client secret of the service account")
+	flagSet.StringVar(&keyFile, "add-key", "", "path to the PEM encoded Ed25519 or P-256 public key to add to the service account")
+	flagSet.Int64Var(&params.RemoveCredentialID, "remove-credential", 0, "ID of the client secret or public key to remove")
+	flagSet.BoolVar(&params.Audit, "audit", false, "list audit events of the service account")
+	flagSet.StringVar(&from, "from", "", "select audit events since the time (RFC 3339 or YYYY-MM-DD)")
+	flagSet.StringVar(&to, "to", "", "select audit events before the time (RFC 3339 or YYYY-MM-DD)")
+	flagSet.IntVar(&params.AuditParams.Limit, "limit", 0, "maximum number of audit events")
+	flagSet.StringVar(&jwt, "jwt", "", "authentication JWT")
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatal("failed to parse service-account flags", err)
+	}
+	if keyFile != "" {
+		publicKey, err := os.ReadFile(keyFile)
+		if err != nil {
+			log.Fatal("failed to read public key: ", err)
+		}
+		params.PublicKey = string(publicKey)
+	}
+	var err error
+	if params.AuditParams.From, err = parseTimeFlag(from); err != nil {
+		log.Fatal("invalid -from: ", err)
+	}
+	if params.AuditParams.To, err = parseTimeFlag(to); err != nil {
+		log.Fatal("invalid -to: ", err)
+	}
+
+	accountCmd := cli.NewServiceAccountCmd(client, os.Stdout)
+	if err := accountCmd.Execute(params, jwt); err != nil {
+		log.Fatal(err)
+	}
+	if params.Create == "" && params.AccountID != 0 && (params.Disable || params.RemoveCredentialID != 0) {
+		log.Println("Success")
+	}
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/hex"
+	"fmt"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
 	"github.com/ilya-burinskiy/gophkeeper/internal/configs"
 	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
 	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
@@ -16,20 +21,37 @@ import (
 )
 
 func main() {
-	config := configs.Parse()
+	config, err := configs.Parse()
+	if err != nil {
+		panic(err)
+	}
 	store, err := storage.NewDBStorage(config.DSN)
 	if err != nil {
 		panic(err)
 	}
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit" {
+		os.Exit(verifyAudit(services.NewAuditService(store)))
+	}
 	logger := configureLogger("info")
+	if config.JWTKeysPath != "" {
+		keySet, err := auth.LoadKeySet(config.JWTKeysPath)
+		if err != nil {
+			panic(err)
+		}
+		auth.SetKeySet(keySet)
+	} else {
+		logger.Warn("JWT_KEYS_PATH is not set, tokens are signed with a random key and are invalidated on restart")
+	}
 	router := chi.NewRouter()
 	router.Use(
 		middlewares.LogResponse(logger),
 		middlewares.LogRequest(logger),
+		middlewares.ClientInfo,
 	)
 
-	registerSrv := services.NewRegisterService(store)
-	authSrv := services.NewAuthenticateService(store)
+	sessionSrv := services.NewSessionService(store, services.CryptoRandGen{})
+	registerSrv := services.NewRegisterService(store, sessionSrv)
+	authSrv := services.NewAuthenticateService(store, store, sessionSrv)
 	masterKey, err := hex.DecodeString(config.MasterKey)
 	if err != nil {
 		panic(err)
@@ -41,22 +63,69 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	createSecretSrv := services.NewCreateSecretService(store, encryptor)
+	acl := services.NewSecretACL(store)
+	auditSrv := services.NewAuditService(store)
+	createSecretSrv := services.NewCreateSecretService(store, encryptor, acl)
 	findSrv := services.NewFindSecretService(store)
-	updateSrv := services.NewUpdateSecretService(store, encryptor)
-	fetchSrv := services.NewFetchUserSecretsService(store, encryptor)
-	deleteSrv := services.NewDeleteSecretService(store)
+	showSrv := services.NewShowSecretService(encryptor, acl, auditSrv)
+	listSrv := services.NewListSecretsService(store, acl)
+	updateSrv := services.NewUpdateSecretService(store, encryptor, acl)
+	metadataSrv := services.NewSecretMetadataService(store, encryptor, acl)
+	binDataSrv := services.NewBinDataService(store, encryptor, acl, config.MaxBinDataSize)
+	uploadSrv := services.NewUploadService(store, encryptor, services.CryptoRandGen{}, acl, config.MaxBinDataSize)
+	fetchSrv := services.NewFetchUserSecretsService(store, store, encryptor, binDataSrv, acl, auditSrv)
+	deleteSrv := services.NewDeleteSecretService(store, acl)
+	folderSrv := services.NewFolderService(store, acl)
+	tagSrv := services.NewTagService(store, acl)
+	revisionSrv := services.NewRevisionService(store, encryptor, acl, auditSrv)
+	shareSrv := services.NewShareService(store, acl)
+	settingsSrv := services.NewUserSettingsService(store)
+	totpSrv := services.NewTOTPService(store, encryptor, services.CryptoRandGen{}, store, sessionSrv)
+	trashSrv := services.NewTrashService(store, acl)
+	syncSrv := services.NewSyncService(store, encryptor, auditSrv)
+	eventsSrv := services.NewSecretEventsService(store, acl)
+	orgSrv := services.NewOrganizationService(store, acl)
+
+	apiTokenSrv := services.NewAPITokenService(store, services.CryptoRandGen{})
+	serviceAccountSrv := services.NewServiceAccountService(store, acl, auditSrv, services.CryptoRandGen{})
 
-	configureUserRouter(logger, registerSrv, authSrv, router)
+	authenticate := middlewares.Authenticate(store, apiTokenSrv, store)
+	configureUserRouter(logger, registerSrv, authSrv, totpSrv, sessionSrv, settingsSrv, apiTokenSrv, authenticate, router)
 	configureSecretRouter(
 		logger,
 		createSecretSrv,
 		findSrv,
+		showSrv,
+		listSrv,
 		updateSrv,
+		metadataSrv,
+		folderSrv,
+		tagSrv,
+		revisionSrv,
+		binDataSrv,
 		fetchSrv,
 		deleteSrv,
+		trashSrv,
+		syncSrv,
+		eventsSrv,
+		shareSrv,
+		authenticate,
 		router,
 	)
+	configureFolderRouter(logger, folderSrv, authenticate, router)
+	configureTagRouter(logger, tagSrv, authenticate, router)
+	configureOrganizationRouter(logger, orgSrv, authenticate, router)
+	configureServiceAccountRouter(logger, serviceAccountSrv, authenticate, router)
+	configureAuditRouter(logger, auditSrv, authenticate, router)
+	configureUploadRouter(logger, uploadSrv, findSrv, config.MaxBinDataSize, authenticate, router)
+	configureJWKSRouter(logger, auth.Keys(), router)
+	go purgeExpiredUploads(logger, store)
+	go purgeStagedChunkData(logger, store)
+	go purgeTrash(logger, store, config.TrashRetention)
+	go purgeLoginAttempts(logger, store)
+	go purgeSessions(logger, store)
+	go purgeServiceAccountAssertions(logger, store)
+	go runSecretEvents(logger, eventsSrv)
 
 	cert, err := tls.LoadX509KeyPair(config.ServerCRTPath, config.ServerKeyPath)
 	if err != nil {
@@ -79,13 +148,34 @@ func configureUserRouter(
 	logger *zap.Logger,
 	registerSrv services.RegisterService,
 	authSrv services.AuthenticateService,
+	totpSrv services.TOTPService,
+	sessionSrv services.SessionService,
+	settingsSrv services.UserSettingsService,
+	apiTokenSrv services.APITokenService,
+	authenticate func(http.Handler) http.Handler,
 	mainRouter chi.Router) {
 
 	handler := handlers.NewUserHandlers(logger)
+	apiTokenHandler := handlers.NewAPITokenHandler(logger)
 	mainRouter.Group(func(router chi.Router) {
 		router.Use(middleware.AllowContentType("application/json"))
 		router.Post("/api/user/register", handler.Register(registerSrv))
 		router.Post("/api/user/login", handler.Authenticate(authSrv))
+		router.Post("/api/user/login/totp", handler.AuthenticateTOTP(totpSrv))
+		router.Post("/api/user/refresh", handler.Refresh(sessionSrv))
+	})
+	mainRouter.Group(func(router chi.Router) {
+		router.Use(authenticate, middlewares.RequireSession, middleware.AllowContentType("application/json"))
+		router.Put("/api/user/settings", handler.UpdateSettings(settingsSrv))
+		router.Post("/api/user/logout", handler.Logout(sessionSrv))
+		router.Get("/api/user/sessions", handler.Sessions(sessionSrv))
+		router.Delete("/api/user/sessions/{id}", handler.RevokeSession(sessionSrv))
+		router.Post("/api/user/sessions/revoke-others", handler.RevokeOtherSessions(sessionSrv))
+		router.Post("/api/user/totp", handler.EnrollTOTP(totpSrv))
+		router.Post("/api/user/totp/confirm", handler.ConfirmTOTP(totpSrv))
+		router.Post("/api/user/tokens", apiTokenHandler.Create(apiTokenSrv))
+		router.Get("/api/user/tokens", apiTokenHandler.Index(apiTokenSrv))
+		router.Delete("/api/user/tokens/{id}", apiTokenHandler.Revoke(apiTokenSrv))
 	})
 }
 
@@ -93,21 +183,294 @@ func configureSecretRouter(
 	logger *zap.Logger,
 	createSrv services.CreateSecretService,
 	findSrv services.FindSecretService,
+	showSrv services.ShowSecretService,
+	listSrv services.ListSecretsService,
 	updateSrv services.UpdateSecretService,
+	metadataSrv services.SecretMetadataService,
+	folderSrv services.FolderService,
+	tagSrv services.TagService,
+	revisionSrv services.RevisionService,
+	binDataSrv services.BinDataService,
 	fetchSrv services.FetchUserSecretsService,
 	deleteSrv services.DeleteSecretService,
+	trashSrv services.TrashService,
+	syncSrv services.SyncService,
+	eventsSrv services.SecretEventsService,
+	shareSrv services.ShareService,
+	authenticate func(http.Handler) http.Handler,
 	mainRouter chi.Router) {
 
 	handler := handlers.NewSecretHandler(logger)
 	mainRouter.Group(func(router chi.Router) {
-		router.Use(middlewares.Authenticate)
-		router.Post("/api/secrets", handler.Create(createSrv))
-		router.Patch("/api/secrets/{id}", handler.Update(findSrv, updateSrv))
+		router.Use(authenticate)
+		router.Post("/api/secrets", handler.Create(createSrv, binDataSrv))
+		router.Patch("/api/secrets/{id}", handler.Update(findSrv, updateSrv, binDataSrv))
+		router.Put("/api/secrets/{id}/metadata", handler.UpdateMetadata(findSrv, metadataSrv))
+		router.Put("/api/secrets/{id}/folder", handler.Move(findSrv, folderSrv))
+		router.Put("/api/secrets/{id}/tags", handler.SetTags(findSrv, tagSrv))
 		router.Get("/api/secrets", handler.GetUserSecrets(fetchSrv))
+		router.Get("/api/secrets/index", handler.Index(listSrv))
+		router.Get("/api/secrets/{id}", handler.Get(findSrv, showSrv, binDataSrv))
+		router.Get("/api/secrets/{id}/revisions", handler.Revisions(findSrv, revisionSrv))
+		router.Get("/api/secrets/{id}/revisions/{version}", handler.GetRevision(findSrv, revisionSrv, binDataSrv))
+		router.Post("/api/secrets/{id}/revisions/{version}/restore", handler.RestoreRevision(findSrv, revisionSrv))
 		router.Delete("/api/secrets/{id}", handler.Delete(findSrv, deleteSrv))
+		router.Get("/api/secrets/{id}/shares", handler.Shares(findSrv, shareSrv))
+		router.Post("/api/secrets/{id}/shares", handler.Share(findSrv, shareSrv))
+		router.Delete("/api/secrets/{id}/shares/{userID}", handler.RevokeShare(findSrv, shareSrv))
+		router.Get("/api/trash", handler.TrashIndex(trashSrv))
+		router.Post("/api/trash/{id}/restore", handler.RestoreTrashed(trashSrv))
+		router.Delete("/api/trash/{id}", handler.PurgeTrashed(trashSrv, deleteSrv))
+		router.Get("/api/sync", handler.Sync(syncSrv))
+		router.Get("/api/events", handler.Events(eventsSrv))
+	})
+}
+
+func configureFolderRouter(
+	logger *zap.Logger,
+	folderSrv services.FolderService,
+	authenticate func(http.Handler) http.Handler,
+	mainRouter chi.Router) {
+
+	handler := handlers.NewFolderHandler(logger)
+	mainRouter.Group(func(router chi.Router) {
+		router.Use(authenticate, middlewares.RequireSession, middleware.AllowContentType("application/json"))
+		router.Post("/api/folders", handler.Create(folderSrv))
+		router.Get("/api/folders", handler.Index(folderSrv))
+		router.Patch("/api/folders/{id}", handler.Update(folderSrv))
+	})
+}
+
+func configureTagRouter(
+	logger *zap.Logger,
+	tagSrv services.TagService,
+	authenticate func(http.Handler) http.Handler,
+	mainRouter chi.Router) {
+
+	handler := handlers.NewTagHandler(logger)
+	mainRouter.Group(func(router chi.Router) {
+		router.Use(authenticate, middlewares.RequireSession, middleware.AllowContentType("application/json"))
+		router.Post("/api/tags", handler.Create(tagSrv))
+		router.Get("/api/tags", handler.Index(tagSrv))
+		router.Patch("/api/tags/{id}", handler.Rename(tagSrv))
 	})
 }
 
+func configureOrganizationRouter(
+	logger *zap.Logger,
+	orgSrv services.OrganizationService,
+	authenticate func(http.Handler) http.Handler,
+	mainRouter chi.Router) {
+
+	handler := handlers.NewOrganizationHandler(logger)
+	mainRouter.Group(func(router chi.Router) {
+		router.Use(authenticate, middlewares.RequireSession, middleware.AllowContentType("application/json"))
+		router.Post("/api/organizations", handler.Create(orgSrv))
+		router.Get("/api/organizations", handler.Index(orgSrv))
+		router.Post("/api/organizations/{id}/accept", handler.Accept(orgSrv))
+		router.Get("/api/organizations/{id}/members", handler.Members(orgSrv))
+		router.Post("/api/organizations/{id}/members", handler.Invite(orgSrv))
+		router.Put("/api/organizations/{id}/members/{userID}", handler.SetRole(orgSrv))
+		router.Delete("/api/organizations/{id}/members/{userID}", handler.RemoveMember(orgSrv))
+		router.Post("/api/organizations/{id}/vaults", handler.CreateVault(orgSrv))
+		router.Get("/api/vaults", handler.Vaults(orgSrv))
+	})
+}
+
+func configureServiceAccountRouter(
+	logger *zap.Logger,
+	serviceAccountSrv services.ServiceAccountService,
+	authenticate func(http.Handler) http.Handler,
+	mainRouter chi.Router) {
+
+	handler := handlers.NewServiceAccountHandler(logger)
+	mainRouter.Group(func(router chi.Router) {
+		router.Use(middleware.AllowContentType("application/json"))
+		router.Post("/api/service-accounts/token", handler.Token(serviceAccountSrv))
+	})
+	mainRouter.Group(func(router chi.Router) {
+		router.Use(authenticate, middlewares.RequireSession, middleware.AllowContentType("application/json"))
+		router.Post("/api/organizations/{id}/service-accounts", handler.Create(serviceAccountSrv))
+		router.Get("/api/organizations/{id}/service-accounts", handler.Index(serviceAccountSrv))
+		router.Post("/api/organizations/{id}/service-accounts/{accountID}/disable", handler.Disable(serviceAccountSrv))
+		router.Get("/api/organizations/{id}/service-accounts/{accountID}/credentials", handler.Credentials(serviceAccountSrv))
+		router.Post("/api/organizations/{id}/service-accounts/{accountID}/credentials", handler.CreateCredential(serviceAccountSrv))
+		router.Delete(
+			"/api/organizations/{id}/service-accounts/{accountID}/credentials/{credentialID}",
+			handler.DeleteCredential(serviceAccountSrv),
+		)
+		router.Get("/api/organizations/{id}/service-accounts/{accountID}/audit", handler.Audit(serviceAccountSrv))
+	})
+}
+
+func configureAuditRouter(
+	logger *zap.Logger,
+	auditSrv services.AuditService,
+	authenticate func(http.Handler) http.Handler,
+	mainRouter chi.Router) {
+
+	handler := handlers.NewAuditHandler(logger)
+	mainRouter.Group(func(router chi.Router) {
+		router.Use(authenticate, middlewares.RequireSession)
+		router.Get("/api/audit", handler.Index(auditSrv))
+	})
+}
+
+// verifyAudit checks the audit log hash chain and returns the exit code
+// of the verify-audit command, it is not exposed over HTTP since the check
+// reads the whole log.
+func verifyAudit(auditSrv services.AuditService) int {
+	result, err := auditSrv.Verify(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to verify audit log: %v\n", err)
+		return 1
+	}
+	if !result.Valid {
+		fmt.Printf("audit log is broken at event %d, %d events checked\n", result.BrokenEventID, result.Events)
+		return 1
+	}
+
+	fmt.Printf("audit log is valid, %d events checked\n", result.Events)
+	return 0
+}
+
+func configureJWKSRouter(logger *zap.Logger, keySet *auth.KeySet, mainRouter chi.Router) {
+	handler := handlers.NewJWKSHandler(logger)
+	mainRouter.Get("/.well-known/jwks.json", handler.Show(keySet))
+}
+
+func configureUploadRouter(
+	logger *zap.Logger,
+	uploadSrv services.UploadService,
+	findSrv services.FindSecretService,
+	maxBinDataSize int64,
+	authenticate func(http.Handler) http.Handler,
+	mainRouter chi.Router) {
+
+	handler := handlers.NewUploadHandler(logger, maxBinDataSize)
+	mainRouter.Group(func(router chi.Router) {
+		router.Use(authenticate, handler.Tus)
+		router.Options("/api/uploads", handler.Options())
+		router.Post("/api/uploads", handler.Create(uploadSrv, findSrv))
+		router.Head("/api/uploads/{id}", handler.Head(uploadSrv))
+		router.Patch("/api/uploads/{id}", handler.Patch(uploadSrv))
+		router.Delete("/api/uploads/{id}", handler.Delete(uploadSrv))
+	})
+}
+
+func purgeExpiredUploads(logger *zap.Logger, store *storage.DBStorage) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		deleted, err := store.DeleteExpiredUploads(context.Background())
+		if err != nil {
+			logger.Info("failed to delete expired uploads", zap.Error(err))
+			continue
+		}
+		if deleted > 0 {
+			logger.Info("deleted expired uploads", zap.Int64("count", deleted))
+		}
+	}
+}
+
+// purgeStagedChunkData deletes chunk data left unattached by failed writes.
+func purgeStagedChunkData(logger *zap.Logger, store *storage.DBStorage) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		deleted, err := store.DeleteStagedChunkData(context.Background())
+		if err != nil {
+			logger.Info("failed to delete staged chunk data", zap.Error(err))
+			continue
+		}
+		if deleted > 0 {
+			logger.Info("deleted staged chunk data", zap.Int64("count", deleted))
+		}
+	}
+}
+
+// purgeTrash permanently deletes secrets kept in the trash longer than retention.
+func purgeTrash(logger *zap.Logger, store *storage.DBStorage, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		deleted, err := store.DeleteTrashedSecrets(context.Background(), time.Now().Add(-retention))
+		if err != nil {
+			logger.Info("failed to purge trash", zap.Error(err))
+			continue
+		}
+		if deleted > 0 {
+			logger.Info("purged trashed secrets", zap.Int64("count", deleted))
+		}
+	}
+}
+
+// purgeLoginAttempts deletes failed login attempts, which are no longer
+// counted.
+func purgeLoginAttempts(logger *zap.Logger, store *storage.DBStorage) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		window := services.UserLoginPolicy.Window
+		if services.IPLoginPolicy.Window > window {
+			window = services.IPLoginPolicy.Window
+		}
+		deleted, err := store.DeleteStaleLoginAttempts(context.Background(), time.Now().Add(-window))
+		if err != nil {
+			logger.Info("failed to delete stale login attempts", zap.Error(err))
+			continue
+		}
+		if deleted > 0 {
+			logger.Info("deleted stale login attempts", zap.Int64("count", deleted))
+		}
+	}
+}
+
+// purgeSessions deletes expired refresh tokens and sessions which can not
+// be used anymore.
+func purgeSessions(logger *zap.Logger, store *storage.DBStorage) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		// revoked sessions are kept while their access tokens are valid
+		deleted, err := store.DeleteExpiredSessions(context.Background(), time.Now().Add(-configs.AuthTokenExp))
+		if err != nil {
+			logger.Info("failed to delete expired sessions", zap.Error(err))
+			continue
+		}
+		if deleted > 0 {
+			logger.Info("deleted expired sessions", zap.Int64("count", deleted))
+		}
+	}
+}
+
+// purgeServiceAccountAssertions deletes ids of expired assertions, which
+// can not be replayed anyway.
+func purgeServiceAccountAssertions(logger *zap.Logger, store *storage.DBStorage) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		deleted, err := store.DeleteExpiredServiceAccountAssertions(context.Background(), time.Now())
+		if err != nil {
+			logger.Info("failed to delete expired assertions", zap.Error(err))
+			continue
+		}
+		if deleted > 0 {
+			logger.Info("deleted expired assertions", zap.Int64("count", deleted))
+		}
+	}
+}
+
+// runSecretEvents delivers secret events to subscribers and listens again
+// after a failure.
+func runSecretEvents(logger *zap.Logger, eventsSrv services.SecretEventsService) {
+	for {
+		err := eventsSrv.Run(context.Background())
+		logger.Info("failed to listen secret events", zap.Error(err))
+		time.Sleep(time.Second)
+	}
+}
+
 func configureLogger(level string) *zap.Logger {
 	logLvl, err := zap.ParseAtomicLevel(level)
 	if err != nil {
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -25,18 +26,59 @@ func ValidatePasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-func BuildJWTString(userID int) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(configs.AuthTokenExp)),
-		},
-		UserID: userID,
+// Issuer and audiences of tokens. Services verifying access tokens with
+// the published keys must check the access token audience, MFA tokens
+// have another audience, so they can not be used as access tokens.
+const (
+	TokenIssuer         = "gophkeeper"
+	AccessTokenAudience = "gophkeeper"
+	MFATokenAudience    = "gophkeeper-mfa"
+)
+
+// BuildJWTString returns the access token of the user session.
+func BuildJWTString(userID int, sessionID int) (string, error) {
+	return Keys().Sign(Claims{
+		RegisteredClaims: registeredClaims(AccessTokenAudience, configs.AuthTokenExp),
+		UserID:           userID,
+		SessionID:        sessionID,
 	})
-	tokenString, err := token.SignedString([]byte(configs.SecretKey))
-	if err != nil {
-		return "", fmt.Errorf("failed to sign token: %w", err)
+}
+
+// BuildMFAToken returns a token of the user who has to pass the second
+// factor check.
+func BuildMFAToken(userID int) (string, error) {
+	return Keys().Sign(MFAClaims{
+		RegisteredClaims: registeredClaims(MFATokenAudience, configs.MFATokenExp),
+		UserID:           userID,
+	})
+}
+
+// ParseMFAToken returns the user of the valid MFA token.
+func ParseMFAToken(tokenString string) (int, error) {
+	claims := MFAClaims{}
+	if err := Keys().Parse(tokenString, &claims); err != nil || !claims.VerifyAudience(MFATokenAudience, true) {
+		return 0, errors.New("invalid MFA token")
+	}
+	return claims.UserID, nil
+}
+
+// ParseJWT returns claims of the valid access token.
+func ParseJWT(tokenString string) (Claims, error) {
+	claims := Claims{}
+	if err := Keys().Parse(tokenString, &claims); err != nil || !claims.VerifyAudience(AccessTokenAudience, true) {
+		return claims, errors.New("invalid access token")
+	}
+	return claims, nil
+}
+
+func registeredClaims(audience string, exp time.Duration) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Issuer:    TokenIssuer,
+		Audience:  jwt.ClaimStrings{audience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(exp)),
 	}
-	return tokenString, nil
 }
 
 func SetJWTCookie(w http.ResponseWriter, token string) {
@@ -50,3 +92,24 @@ func SetJWTCookie(w http.ResponseWriter, token string) {
 		},
 	)
 }
+
+// SetRefreshTokenCookie sets the refresh token cookie, it is sent to user
+// endpoints only.
+func SetRefreshTokenCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(
+		w,
+		&http.Cookie{
+			Name:     "refresh_token",
+			Value:    token,
+			Path:     "/api/user",
+			MaxAge:   int(configs.RefreshTokenExp / time.Second),
+			HttpOnly: true,
+		},
+	)
+}
+
+// ClearSessionCookies removes the access and the refresh token cookies.
+func ClearSessionCookies(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: "jwt", MaxAge: -1, HttpOnly: true})
+	http.SetCookie(w, &http.Cookie{Name: "refresh_token", Path: "/api/user", MaxAge: -1, HttpOnly: true})
+}
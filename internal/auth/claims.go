@@ -2,7 +2,20 @@ package auth
 
 import "github.com/golang-jwt/jwt/v4"
 
+// Claims of access tokens, SessionID is the session the token is issued
+// for, tokens of revoked sessions are rejected. Tokens of service accounts
+// have ServiceAccountID instead of SessionID and are rejected once the
+// service account is disabled.
 type Claims struct {
+	jwt.RegisteredClaims
+	UserID           int
+	SessionID        int
+	ServiceAccountID int `json:",omitempty"`
+}
+
+// MFAClaims identify the user who has passed the password check and has to
+// pass the second factor check.
+type MFAClaims struct {
 	jwt.RegisteredClaims
 	UserID int
 }
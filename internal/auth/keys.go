@@ -0,0 +1,319 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Algorithms of JWT keys.
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+	AlgES256 = "ES256"
+)
+
+const minHMACSecretSize = 32
+
+// Key is a key tokens are signed and verified with. A key without the
+// private part only verifies tokens.
+type Key struct {
+	ID        string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+	public    bool
+}
+
+// NewHMACKey returns an HS256 key, the secret must be at least 32 bytes.
+// HMAC keys are not published in JWKS.
+func NewHMACKey(id string, secret []byte) (Key, error) {
+	if len(secret) < minHMACSecretSize {
+		return Key{}, fmt.Errorf("secret of key %q must be at least %d bytes", id, minHMACSecretSize)
+	}
+
+	return Key{ID: id, method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}, nil
+}
+
+// NewEd25519Key returns an EdDSA key, privateKey may be nil for a key
+// which only verifies tokens.
+func NewEd25519Key(id string, privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey) Key {
+	key := Key{ID: id, method: jwt.SigningMethodEdDSA, verifyKey: publicKey, public: true}
+	if privateKey != nil {
+		key.signKey = privateKey
+		key.verifyKey = privateKey.Public()
+	}
+
+	return key
+}
+
+// NewECDSAKey returns an ES256 key, privateKey may be nil for a key which
+// only verifies tokens.
+func NewECDSAKey(id string, privateKey *ecdsa.PrivateKey, publicKey *ecdsa.PublicKey) (Key, error) {
+	if privateKey != nil {
+		publicKey = &privateKey.PublicKey
+	}
+	if publicKey == nil || publicKey.Curve != elliptic.P256() {
+		return Key{}, fmt.Errorf("key %q must be a P-256 key", id)
+	}
+	key := Key{ID: id, method: jwt.SigningMethodES256, verifyKey: publicKey, public: true}
+	if privateKey != nil {
+		key.signKey = privateKey
+	}
+
+	return key, nil
+}
+
+// KeySet signs tokens with the signing key and verifies tokens signed with
+// any of its keys. Keys are rotated by adding a new key, making it the
+// signing key once all instances know it, and removing the old key once
+// tokens signed with it have expired.
+type KeySet struct {
+	signing Key
+	keys    map[string]Key
+}
+
+func NewKeySet(signingKeyID string, keys ...Key) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]Key, len(keys))}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("key id must be non empty")
+		}
+		if _, ok := ks.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+	signing, ok := ks.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found", signingKeyID)
+	}
+	if signing.signKey == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKeyID)
+	}
+	ks.signing = signing
+
+	return ks, nil
+}
+
+// Sign returns the token with the claims signed with the signing key, the
+// key id is set in the "kid" header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	token.Header["kid"] = ks.signing.ID
+	tokenString, err := token.SignedString(ks.signing.signKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return tokenString, nil
+}
+
+// Parse verifies the token with the key of its "kid" header and decodes
+// its claims.
+func (ks *KeySet) Parse(tokenString string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected algorithm %q of key %q", token.Method.Alg(), kid)
+		}
+		return key.verifyKey, nil
+	})
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return errors.New("invalid token")
+	}
+
+	return nil
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	ID        string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns public keys of the set ordered by id, HMAC keys are secret
+// and are not returned.
+func (ks *KeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(ks.keys))
+	for id := range ks.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, id := range ids {
+		key := ks.keys[id]
+		if !key.public {
+			continue
+		}
+		jwk := JWK{ID: key.ID, Algorithm: key.method.Alg(), Use: "sig"}
+		switch publicKey := key.verifyKey.(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		case *ecdsa.PublicKey:
+			jwk.KeyType = "EC"
+			jwk.Curve = "P-256"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, 32)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, 32)))
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+// keysFile is the JSON file of the key set. Secrets are base64 encoded,
+// key files are PEM files, relative paths are resolved against the
+// directory of the keys file.
+type keysFile struct {
+	SigningKeyID string `json:"signing_key_id"`
+	Keys         []struct {
+		ID             string `json:"id"`
+		Algorithm      string `json:"algorithm"`
+		Secret         string `json:"secret"`
+		PrivateKeyFile string `json:"private_key_file"`
+		PublicKeyFile  string `json:"public_key_file"`
+	} `json:"keys"`
+}
+
+// LoadKeySet reads the key set from the JSON keys file.
+func LoadKeySet(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keys file: %w", err)
+	}
+	var file keysFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse keys file: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	readPEM := func(name string) ([]byte, error) {
+		if name == "" {
+			return nil, nil
+		}
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		return os.ReadFile(name)
+	}
+	keys := make([]Key, 0, len(file.Keys))
+	for _, entry := range file.Keys {
+		privatePEM, err := readPEM(entry.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key %q: %w", entry.ID, err)
+		}
+		publicPEM, err := readPEM(entry.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key %q: %w", entry.ID, err)
+		}
+		key, err := parseKey(entry.ID, entry.Algorithm, entry.Secret, privatePEM, publicPEM)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeySet(file.SigningKeyID, keys...)
+}
+
+func parseKey(id, algorithm, secret string, privatePEM, publicPEM []byte) (Key, error) {
+	switch algorithm {
+	case AlgHS256:
+		bs, err := base64.StdEncoding.DecodeString(secret)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid secret of key %q: %w", id, err)
+		}
+		return NewHMACKey(id, bs)
+	case AlgEdDSA:
+		if privatePEM != nil {
+			privateKey, err := jwt.ParseEdPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return Key{}, fmt.Errorf("invalid private key %q: %w", id, err)
+			}
+			return NewEd25519Key(id, privateKey.(ed25519.PrivateKey), nil), nil
+		}
+		publicKey, err := jwt.ParseEdPublicKeyFromPEM(publicPEM)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid public key %q: %w", id, err)
+		}
+		return NewEd25519Key(id, nil, publicKey.(ed25519.PublicKey)), nil
+	case AlgES256:
+		if privatePEM != nil {
+			privateKey, err := jwt.ParseECPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return Key{}, fmt.Errorf("invalid private key %q: %w", id, err)
+			}
+			return NewECDSAKey(id, privateKey, nil)
+		}
+		publicKey, err := jwt.ParseECPublicKeyFromPEM(publicPEM)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid public key %q: %w", id, err)
+		}
+		return NewECDSAKey(id, nil, publicKey)
+	default:
+		return Key{}, fmt.Errorf("unsupported algorithm %q of key %q", algorithm, id)
+	}
+}
+
+var keySet atomic.Pointer[KeySet]
+
+// Tokens are signed with a random key until SetKeySet is called, such
+// tokens are not valid after a restart or on other instances.
+func init() {
+	secret := make([]byte, minHMACSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	key, err := NewHMACKey("ephemeral", secret)
+	if err != nil {
+		panic(err)
+	}
+	ks, err := NewKeySet(key.ID, key)
+	if err != nil {
+		panic(err)
+	}
+	keySet.Store(ks)
+}
+
+// SetKeySet sets the key set tokens are signed and verified with.
+func SetKeySet(ks *KeySet) {
+	keySet.Store(ks)
+}
+
+// Keys returns the key set tokens are signed and verified with.
+func Keys() *KeySet {
+	return keySet.Load()
+}
@@ -0,0 +1,141 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testClaims(userID int) auth.Claims {
+	return auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+		UserID: userID,
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey, err := auth.NewHMACKey("old", []byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	_, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	newKey := auth.NewEd25519Key("new", edPrivateKey, nil)
+
+	before, err := auth.NewKeySet("old", oldKey)
+	require.NoError(t, err)
+	oldToken, err := before.Sign(testClaims(1))
+	require.NoError(t, err)
+
+	after, err := auth.NewKeySet("new", oldKey, newKey)
+	require.NoError(t, err)
+	newToken, err := after.Sign(testClaims(2))
+	require.NoError(t, err)
+
+	claims := auth.Claims{}
+	require.NoError(t, after.Parse(oldToken, &claims))
+	assert.Equal(t, 1, claims.UserID)
+	claims = auth.Claims{}
+	require.NoError(t, after.Parse(newToken, &claims))
+	assert.Equal(t, 2, claims.UserID)
+
+	// tokens of unknown keys are rejected
+	assert.Error(t, before.Parse(newToken, &auth.Claims{}))
+}
+
+func TestKeySetParseRejectsForgedTokens(t *testing.T) {
+	_, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key := auth.NewEd25519Key("ed", edPrivateKey, nil)
+	ks, err := auth.NewKeySet("ed", key)
+	require.NoError(t, err)
+
+	// HMAC signed with the public key of an asymmetric key
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims(1))
+	forged.Header["kid"] = "ed"
+	tokenString, err := forged.SignedString([]byte(edPrivateKey.Public().(ed25519.PublicKey)))
+	require.NoError(t, err)
+	assert.Error(t, ks.Parse(tokenString, &auth.Claims{}))
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, testClaims(1))
+	unsigned.Header["kid"] = "ed"
+	tokenString, err = unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	assert.Error(t, ks.Parse(tokenString, &auth.Claims{}))
+}
+
+func TestNewKeySet(t *testing.T) {
+	_, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	verifyOnly := auth.NewEd25519Key("ed", nil, edPrivateKey.Public().(ed25519.PublicKey))
+
+	_, err = auth.NewHMACKey("short", []byte("secret"))
+	assert.Error(t, err)
+	_, err = auth.NewKeySet("missing", verifyOnly)
+	assert.Error(t, err)
+	_, err = auth.NewKeySet("ed", verifyOnly)
+	assert.Error(t, err)
+	_, err = auth.NewKeySet("ed", auth.NewEd25519Key("ed", edPrivateKey, nil), verifyOnly)
+	assert.Error(t, err)
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+	ecPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(ecPrivateKey)
+	require.NoError(t, err)
+	writeFile(t, filepath.Join(dir, "es256.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	writeFile(t, filepath.Join(dir, "keys.json"), []byte(`{
+		"signing_key_id": "es",
+		"keys": [
+			{"id": "hs", "algorithm": "HS256", "secret": "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="},
+			{"id": "es", "algorithm": "ES256", "private_key_file": "es256.pem"}
+		]
+	}`))
+
+	ks, err := auth.LoadKeySet(filepath.Join(dir, "keys.json"))
+	require.NoError(t, err)
+	tokenString, err := ks.Sign(testClaims(1))
+	require.NoError(t, err)
+	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, &auth.Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, "es", token.Header["kid"])
+	assert.Equal(t, "ES256", token.Header["alg"])
+
+	jwks := ks.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "es", jwks.Keys[0].ID)
+	assert.Equal(t, "EC", jwks.Keys[0].KeyType)
+	assert.Equal(t, "P-256", jwks.Keys[0].Curve)
+	assert.Len(t, jwks.Keys[0].X, 43)
+	assert.Len(t, jwks.Keys[0].Y, 43)
+}
+
+func TestParseJWTRejectsMFAToken(t *testing.T) {
+	mfaToken, err := auth.BuildMFAToken(1)
+	require.NoError(t, err)
+	_, err = auth.ParseJWT(mfaToken)
+	assert.Error(t, err)
+
+	accessToken, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	_, err = auth.ParseMFAToken(accessToken)
+	assert.Error(t, err)
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	require.NoError(t, os.WriteFile(name, data, 0600))
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/configs"
+)
+
+// AssertionAudience is the audience of JWT assertions service accounts
+// exchange for access tokens.
+const AssertionAudience = "gophkeeper-token"
+
+// BuildServiceAccountToken returns the access token of the service account
+// backed by the user, the token has no session and can not be refreshed.
+func BuildServiceAccountToken(userID int, serviceAccountID int) (string, error) {
+	return Keys().Sign(Claims{
+		RegisteredClaims: registeredClaims(AccessTokenAudience, configs.ServiceAccountTokenExp),
+		UserID:           userID,
+		ServiceAccountID: serviceAccountID,
+	})
+}
+
+// ParsePublicKey returns the key which verifies tokens with the PEM
+// encoded Ed25519 or P-256 public key.
+func ParsePublicKey(id string, publicPEM []byte) (Key, error) {
+	if publicKey, err := jwt.ParseEdPublicKeyFromPEM(publicPEM); err == nil {
+		if edKey, ok := publicKey.(ed25519.PublicKey); ok {
+			return NewEd25519Key(id, nil, edKey), nil
+		}
+	}
+	if publicKey, err := jwt.ParseECPublicKeyFromPEM(publicPEM); err == nil {
+		return NewECDSAKey(id, nil, publicKey)
+	}
+
+	return Key{}, errors.New("public key must be a PEM encoded Ed25519 or P-256 key")
+}
+
+// ParseAssertion verifies the JWT assertion with the key lookup returns for
+// its issuer and "kid" header and returns its claims. The assertion must be
+// issued by the client for itself, have an id and expire within
+// configs.MaxAssertionLifetime.
+func ParseAssertion(
+	tokenString string,
+	lookup func(issuer string, kid string) (Key, error)) (jwt.RegisteredClaims, error) {
+
+	claims := jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := lookup(claims.Issuer, kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected algorithm %q of key %q", token.Method.Alg(), kid)
+		}
+		return key.verifyKey, nil
+	})
+	if err != nil {
+		return claims, err
+	}
+	if !token.Valid {
+		return claims, errors.New("invalid assertion")
+	}
+	if claims.Subject != claims.Issuer || !claims.VerifyAudience(AssertionAudience, true) {
+		return claims, errors.New("assertion is not issued for the token endpoint")
+	}
+	if claims.ID == "" {
+		return claims, errors.New("assertion has no id")
+	}
+	if claims.ExpiresAt == nil || time.Until(claims.ExpiresAt.Time) > configs.MaxAssertionLifetime {
+		return claims, errors.New("assertion lifetime is too long")
+	}
+
+	return claims, nil
+}
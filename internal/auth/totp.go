@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// TOTP codes follow RFC 6238 with the parameters authenticator apps use by
+// default: HMAC-SHA1, 6 digits and 30 second steps.
+const (
+	TOTPPeriod     = 30 * time.Second
+	TOTPDigits     = 6
+	TOTPSecretSize = 20
+)
+
+// TOTPStep returns the time step the time belongs to.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod/time.Second)
+}
+
+// TOTPCode returns the code of the secret at the time step.
+func TOTPCode(secret []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+// EncodeTOTPSecret encodes the secret as authenticator apps expect it.
+func EncodeTOTPSecret(secret []byte) string {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret)
+}
+
+// TOTPURI returns the otpauth URI of the secret, which authenticator apps
+// import from a QR code or a link.
+func TOTPURI(issuer string, account string, secret []byte) string {
+	query := url.Values{}
+	query.Set("secret", EncodeTOTPSecret(secret))
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(TOTPDigits))
+	query.Set("period", fmt.Sprint(int(TOTPPeriod/time.Second)))
+	label := url.PathEscape(issuer + ":" + account)
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
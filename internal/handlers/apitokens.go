@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"go.uber.org/zap"
+)
+
+type APITokenService interface {
+	Create(
+		ctx context.Context,
+		userID int,
+		name string,
+		scope models.TokenScope,
+		expiresAt time.Time,
+	) (models.APIToken, string, error)
+	List(ctx context.Context, userID int) ([]models.APIToken, error)
+	Revoke(ctx context.Context, userID int, id int) error
+}
+
+// apiTokenPayload secret_types and secret_ids are optional, empty lists do
+// not limit the token. Zero expires_at creates a token which never expires.
+type apiTokenPayload struct {
+	Name        string    `json:"name"`
+	ReadOnly    bool      `json:"read_only"`
+	SecretTypes []string  `json:"secret_types"`
+	SecretIDs   []int     `json:"secret_ids"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type apiTokenResponse struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Token       string     `json:"token,omitempty"`
+	ReadOnly    bool       `json:"read_only"`
+	SecretTypes []string   `json:"secret_types"`
+	SecretIDs   []int      `json:"secret_ids"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type apiTokensIndexResponse struct {
+	Tokens []apiTokenResponse `json:"tokens"`
+}
+
+type APITokenHandler struct {
+	logger *zap.Logger
+}
+
+func NewAPITokenHandler(logger *zap.Logger) APITokenHandler {
+	return APITokenHandler{
+		logger: logger,
+	}
+}
+
+// Create creates an API token of the user, the token value is returned in
+// this response only.
+func (h APITokenHandler) Create(srv APITokenService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		var payload apiTokenPayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid api token request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		scope, err := parseTokenScope(payload)
+		if err != nil {
+			h.logger.Info("invalid api token request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, value, err := srv.Create(r.Context(), userID, payload.Name, scope, payload.ExpiresAt)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidAPITokenName) || errors.Is(err, services.ErrInvalidAPITokenExpiry) {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+			h.logger.Info("failed to create api token", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := newAPITokenResponse(token)
+		response.Token = value
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// Index responds with API tokens of the user without their values.
+func (h APITokenHandler) Index(srv APITokenService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		tokens, err := srv.List(r.Context(), userID)
+		if err != nil {
+			h.logger.Info("failed to list api tokens", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := apiTokensIndexResponse{Tokens: make([]apiTokenResponse, len(tokens))}
+		for i, token := range tokens {
+			response.Tokens[i] = newAPITokenResponse(token)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+func (h APITokenHandler) Revoke(srv APITokenService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		tokenID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			h.logger.Info("invalid api token id", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := srv.Revoke(r.Context(), userID, tokenID); err != nil {
+			var notFoundErr storage.ErrAPITokenNotFound
+			if errors.As(err, &notFoundErr) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			h.logger.Info("failed to revoke api token", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func parseTokenScope(payload apiTokenPayload) (models.TokenScope, error) {
+	scope := models.TokenScope{ReadOnly: payload.ReadOnly, SecretIDs: payload.SecretIDs}
+	for _, name := range payload.SecretTypes {
+		secretType, ok := models.ParseSecretType(name)
+		if !ok {
+			return scope, fmt.Errorf("invalid secret type %q", name)
+		}
+		scope.SecretTypes = append(scope.SecretTypes, secretType)
+	}
+	for _, secretID := range scope.SecretIDs {
+		if secretID <= 0 {
+			return scope, fmt.Errorf("invalid secret id %d", secretID)
+		}
+	}
+
+	return scope, nil
+}
+
+func newAPITokenResponse(token models.APIToken) apiTokenResponse {
+	response := apiTokenResponse{
+		ID:          token.ID,
+		Name:        token.Name,
+		ReadOnly:    token.Scope.ReadOnly,
+		SecretTypes: make([]string, len(token.Scope.SecretTypes)),
+		SecretIDs:   token.Scope.SecretIDs,
+		CreatedAt:   token.CreatedAt,
+	}
+	for i, secretType := range token.Scope.SecretTypes {
+		response.SecretTypes[i] = secretType.String()
+	}
+	if response.SecretIDs == nil {
+		response.SecretIDs = []int{}
+	}
+	if !token.ExpiresAt.IsZero() {
+		response.ExpiresAt = &token.ExpiresAt
+	}
+	if !token.LastUsedAt.IsZero() {
+		response.LastUsedAt = &token.LastUsedAt
+	}
+
+	return response
+}
+
+// requestScope returns the scope of the API token of the request, false is
+// returned for requests authenticated with a session, their scope does not
+// limit access.
+func requestScope(r *http.Request) (models.TokenScope, bool) {
+	token, ok := middlewares.APITokenFromContext(r.Context())
+	return token.Scope, ok
+}
+
+// allowedByScope writes the forbidden response and returns false if the
+// API token of the request does not allow access to the secret. Zero
+// secretID checks creation of a secret of the type, tokens limited to
+// secret ids can not create secrets.
+func allowedByScope(
+	w http.ResponseWriter,
+	r *http.Request,
+	secretID int,
+	secretType models.SecretType,
+	write bool) bool {
+
+	scope, ok := requestScope(r)
+	if !ok {
+		return true
+	}
+	if (write && scope.ReadOnly) || !scope.AllowsSecret(secretID, secretType) {
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// allowedUnlimitedScope writes the forbidden response and returns false if
+// the API token of the request is limited to some secrets. It protects
+// endpoints which respond with all secrets of the user.
+func allowedUnlimitedScope(w http.ResponseWriter, r *http.Request) bool {
+	if scope, ok := requestScope(r); ok && scope.LimitsSecrets() {
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
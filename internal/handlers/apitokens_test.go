@@ -0,0 +1,256 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type apiTokenServiceMock struct{ mock.Mock }
+
+func (m *apiTokenServiceMock) Create(
+	ctx context.Context,
+	userID int,
+	name string,
+	scope models.TokenScope,
+	expiresAt time.Time) (models.APIToken, string, error) {
+
+	args := m.Called(ctx, userID, name, scope, expiresAt)
+	return args.Get(0).(models.APIToken), args.String(1), args.Error(2)
+}
+
+func (m *apiTokenServiceMock) List(ctx context.Context, userID int) ([]models.APIToken, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.APIToken), args.Error(1)
+}
+
+func (m *apiTokenServiceMock) Revoke(ctx context.Context, userID int, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func TestCreateAPIToken(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+	}
+	createdAt := time.Date(2024, 5, 29, 9, 32, 15, 0, time.UTC)
+	expiresAt := time.Date(2024, 8, 29, 0, 0, 0, 0, time.UTC)
+	scope := models.TokenScope{
+		ReadOnly:    true,
+		SecretTypes: []models.SecretType{models.CredentialsSecret},
+		SecretIDs:   []int{1},
+	}
+	testCases := []struct {
+		name      string
+		body      string
+		createErr error
+		want      want
+	}{
+		{
+			name: "responds with created token",
+			body: `{"name":"ci","read_only":true,"secret_types":["credentials"],"secret_ids":[1],` +
+				`"expires_at":"2024-08-29T00:00:00Z"}`,
+			want: want{
+				code: http.StatusCreated,
+				response: `{"id":2,"name":"ci","token":"gkp_token","read_only":true,"secret_types":["credentials"],` +
+					`"secret_ids":[1],"expires_at":"2024-08-29T00:00:00Z","created_at":"2024-05-29T09:32:15Z"}` + "\n",
+			},
+		},
+		{
+			name: "responds with bad request if secret type is invalid",
+			body: `{"name":"ci","secret_types":["passport"]}`,
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:      "responds with unprocessable entity if name is invalid",
+			body:      `{"name":"ci","read_only":true,"secret_types":["credentials"],"secret_ids":[1],"expires_at":"2024-08-29T00:00:00Z"}`,
+			createErr: services.ErrInvalidAPITokenName,
+			want: want{
+				code: http.StatusUnprocessableEntity,
+			},
+		},
+	}
+
+	srv := new(apiTokenServiceMock)
+	handler := http.HandlerFunc(handlers.NewAPITokenHandler(zaptest.NewLogger(t)).Create(srv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token := models.APIToken{ID: 2, UserID: 1, Name: "ci", Scope: scope, ExpiresAt: expiresAt, CreatedAt: createdAt}
+			createCall := srv.On("Create", mock.Anything, mock.Anything, "ci", scope, expiresAt).
+				Return(token, "gkp_token", tc.createErr)
+			defer createCall.Unset()
+
+			request := httptest.NewRequest(http.MethodPost, "/api/user/tokens", strings.NewReader(tc.body))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
+
+func TestAPITokenIndex(t *testing.T) {
+	createdAt := time.Date(2024, 5, 29, 9, 32, 15, 0, time.UTC)
+	srv := new(apiTokenServiceMock)
+	srv.On("List", mock.Anything, mock.Anything).Return(
+		[]models.APIToken{{ID: 2, UserID: 1, Name: "ci", CreatedAt: createdAt}},
+		nil,
+	)
+	handler := http.HandlerFunc(handlers.NewAPITokenHandler(zaptest.NewLogger(t)).Index(srv))
+
+	request := httptest.NewRequest(http.MethodGet, "/api/user/tokens", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	assert.Equal(
+		t,
+		`{"tokens":[{"id":2,"name":"ci","read_only":false,"secret_types":[],"secret_ids":[],`+
+			`"created_at":"2024-05-29T09:32:15Z"}]}`+"\n",
+		recorder.Body.String(),
+	)
+}
+
+func TestRevokeAPIToken(t *testing.T) {
+	testCases := []struct {
+		name      string
+		revokeErr error
+		wantCode  int
+	}{
+		{
+			name:     "revokes token",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:      "responds with not found",
+			revokeErr: storage.ErrAPITokenNotFound{ID: 2},
+			wantCode:  http.StatusNotFound,
+		},
+		{
+			name:      "responds with internal server error",
+			revokeErr: errors.New("error"),
+			wantCode:  http.StatusInternalServerError,
+		},
+	}
+
+	srv := new(apiTokenServiceMock)
+	handler := http.HandlerFunc(handlers.NewAPITokenHandler(zaptest.NewLogger(t)).Revoke(srv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			revokeCall := srv.On("Revoke", mock.Anything, mock.Anything, 2).Return(tc.revokeErr)
+			defer revokeCall.Unset()
+
+			request := httptest.NewRequest(http.MethodDelete, "/api/user/tokens/2", nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "2")
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.wantCode, recorder.Result().StatusCode)
+		})
+	}
+}
+
+func TestAPITokenScope(t *testing.T) {
+	credentials := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret}
+	text := models.Secret{ID: 2, UserID: 1, SecretType: models.TextSecret}
+	readCredentials := models.APIToken{
+		ID:     3,
+		UserID: 1,
+		Scope: models.TokenScope{
+			ReadOnly:    true,
+			SecretTypes: []models.SecretType{models.CredentialsSecret},
+		},
+	}
+	newRequest := func(method string, url string, secretID string) *http.Request {
+		request := httptest.NewRequest(method, url, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", secretID)
+		ctx := context.WithValue(request.Context(), chi.RouteCtxKey, rctx)
+		return request.WithContext(middlewares.WithAPIToken(ctx, readCredentials))
+	}
+	handler := handlers.NewSecretHandler(zaptest.NewLogger(t))
+
+	t.Run("allows reading secret of allowed type", func(t *testing.T) {
+		findSrv := new(findSecretServiceMock)
+		findSrv.On("Find", mock.Anything, 1).Return(credentials, nil)
+		showSrv := new(showServiceMock)
+		showSrv.On("Show", mock.Anything, 1, credentials).
+			Return(&models.Credentials{ID: 1, Login: "login", Password: "password"}, nil)
+
+		recorder := httptest.NewRecorder()
+		handler.Get(findSrv, showSrv, new(binDataServiceMock))(recorder, newRequest(http.MethodGet, "/api/secrets/1", "1"))
+
+		require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	})
+
+	t.Run("forbids reading secret of other type", func(t *testing.T) {
+		findSrv := new(findSecretServiceMock)
+		findSrv.On("Find", mock.Anything, 2).Return(text, nil)
+		showSrv := new(showServiceMock)
+
+		recorder := httptest.NewRecorder()
+		handler.Get(findSrv, showSrv, new(binDataServiceMock))(recorder, newRequest(http.MethodGet, "/api/secrets/2", "2"))
+
+		assert.Equal(t, http.StatusForbidden, recorder.Result().StatusCode)
+		showSrv.AssertNotCalled(t, "Show", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("forbids deleting with read-only token", func(t *testing.T) {
+		findSrv := new(findSecretServiceMock)
+		findSrv.On("Find", mock.Anything, 1).Return(credentials, nil)
+		deleteSrv := new(deleteServiceMock)
+
+		recorder := httptest.NewRecorder()
+		handler.Delete(findSrv, deleteSrv)(recorder, newRequest(http.MethodDelete, "/api/secrets/1", "1"))
+
+		assert.Equal(t, http.StatusForbidden, recorder.Result().StatusCode)
+		deleteSrv.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("lists secrets of allowed type only", func(t *testing.T) {
+		listSrv := new(listServiceMock)
+		listSrv.On("List", mock.Anything, 1, models.SecretsFilter{Scope: readCredentials.Scope}).Return(
+			[]models.SecretInfo{{ID: 1, SecretType: models.CredentialsSecret}},
+			1,
+			nil,
+		)
+
+		recorder := httptest.NewRecorder()
+		handler.Index(listSrv)(recorder, newRequest(http.MethodGet, "/api/secrets/index", ""))
+
+		require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+		assert.Contains(t, recorder.Body.String(), `"id":1`)
+		listSrv.AssertExpectations(t)
+	})
+
+	t.Run("forbids exporting all secrets with limited token", func(t *testing.T) {
+		fetchSrv := new(secretFetcherMock)
+
+		recorder := httptest.NewRecorder()
+		handler.GetUserSecrets(fetchSrv)(recorder, newRequest(http.MethodGet, "/api/secrets", ""))
+
+		assert.Equal(t, http.StatusForbidden, recorder.Result().StatusCode)
+		fetchSrv.AssertNotCalled(t, "FetchUserSecrets", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+type AuditService interface {
+	List(ctx context.Context, userID int, filter models.AuditFilter) ([]models.AuditEvent, error)
+}
+
+type auditEventResponse struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Action    string    `json:"action"`
+	SecretID  int       `json:"secret_id"`
+	OwnerID   int       `json:"owner_id"`
+	VaultID   int       `json:"vault_id,omitempty"`
+	ClientIP  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	Hash      string    `json:"hash"`
+}
+
+type auditIndexResponse struct {
+	Events []auditEventResponse `json:"events"`
+}
+
+type AuditHandler struct {
+	logger *zap.Logger
+}
+
+func NewAuditHandler(logger *zap.Logger) AuditHandler {
+	return AuditHandler{
+		logger: logger,
+	}
+}
+
+// Index responds with audit events visible to the user, the latest first.
+// Events are filtered by the "from" and "to" RFC 3339 times, "secret_id"
+// and "limit" query parameters.
+func (h AuditHandler) Index(srv AuditService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		filter, err := parseAuditFilter(r)
+		if err != nil {
+			h.logger.Info("invalid audit request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		events, err := srv.List(r.Context(), userID, filter)
+		if err != nil {
+			h.logger.Info("failed to list audit events", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := auditIndexResponse{
+			Events: make([]auditEventResponse, len(events)),
+		}
+		for i, event := range events {
+			response.Events[i] = newAuditEventResponse(event)
+		}
+		h.writeJSON(w, response)
+	}
+}
+
+func (h AuditHandler) writeJSON(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Info("failed to encode response", zap.Error(err))
+	}
+}
+
+func parseAuditFilter(r *http.Request) (models.AuditFilter, error) {
+	query := r.URL.Query()
+	var filter models.AuditFilter
+	if from := query.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from time: %w", err)
+		}
+		filter.From = t
+	}
+	if to := query.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to time: %w", err)
+		}
+		filter.To = t
+	}
+	if secretIDStr := query.Get("secret_id"); secretIDStr != "" {
+		secretID, err := strconv.Atoi(secretIDStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid secret id: %w", err)
+		}
+		filter.SecretID = secretID
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return filter, fmt.Errorf("invalid limit %q", limitStr)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+func newAuditEventResponse(event models.AuditEvent) auditEventResponse {
+	return auditEventResponse{
+		ID:        event.ID,
+		UserID:    event.UserID,
+		Action:    event.Action.String(),
+		SecretID:  event.SecretID,
+		OwnerID:   event.OwnerID,
+		VaultID:   event.VaultID,
+		ClientIP:  event.ClientIP,
+		UserAgent: event.UserAgent,
+		CreatedAt: event.CreatedAt,
+		Hash:      fmt.Sprintf("%x", event.Hash),
+	}
+}
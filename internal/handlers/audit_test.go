@@ -0,0 +1,111 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+type auditServiceMock struct{ mock.Mock }
+
+func (m *auditServiceMock) List(ctx context.Context, userID int, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	args := m.Called(ctx, userID, filter)
+	return args.Get(0).([]models.AuditEvent), args.Error(1)
+}
+
+func TestAuditIndex(t *testing.T) {
+	type want struct {
+		code     int
+		filter   models.AuditFilter
+		response string
+	}
+	timestamp := time.Date(2024, 5, 19, 10, 18, 45, 0, time.UTC)
+	events := []models.AuditEvent{
+		{
+			ID:        7,
+			UserID:    2,
+			Action:    models.AuditRead,
+			SecretID:  1,
+			OwnerID:   1,
+			ClientIP:  "10.0.0.1",
+			UserAgent: "gophkeeper",
+			CreatedAt: timestamp,
+			Hash:      []byte{0xab, 0xcd},
+		},
+	}
+	testCases := []struct {
+		name    string
+		query   string
+		listErr error
+		want    want
+	}{
+		{
+			name:  "responds with audit events",
+			query: "?from=2024-05-19T00:00:00Z&to=2024-05-20T00:00:00Z&secret_id=1&limit=10",
+			want: want{
+				code: http.StatusOK,
+				filter: models.AuditFilter{
+					From:     time.Date(2024, 5, 19, 0, 0, 0, 0, time.UTC),
+					To:       time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC),
+					SecretID: 1,
+					Limit:    10,
+				},
+				response: `{"events":[{"id":7,"user_id":2,"action":"read","secret_id":1,"owner_id":1,` +
+					`"client_ip":"10.0.0.1","user_agent":"gophkeeper","created_at":"2024-05-19T10:18:45Z",` +
+					`"hash":"abcd"}]}` + "\n",
+			},
+		},
+		{
+			name:  "responds with bad request if time is invalid",
+			query: "?from=yesterday",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:  "responds with bad request if secret id is invalid",
+			query: "?secret_id=abc",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:    "responds with internal server error",
+			listErr: errors.New("error"),
+			want: want{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	handler := handlers.NewAuditHandler(zaptest.NewLogger(t))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := new(auditServiceMock)
+			srv.On("List", mock.Anything, mock.Anything, mock.Anything).Return(events, tc.listErr)
+			request := httptest.NewRequest(http.MethodGet, "/api/audit"+tc.query, nil)
+			w := httptest.NewRecorder()
+			handler.Index(srv)(w, request)
+
+			result := w.Result()
+			defer result.Body.Close()
+			assert.Equal(t, tc.want.code, result.StatusCode)
+			if tc.want.code == http.StatusOK {
+				srv.AssertCalled(t, "List", mock.Anything, mock.Anything, tc.want.filter)
+				assert.Equal(t, tc.want.response, w.Body.String())
+			}
+			if tc.want.code == http.StatusBadRequest {
+				srv.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
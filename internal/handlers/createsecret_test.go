@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
 	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
@@ -24,14 +26,87 @@ type createServiceMock struct{ mock.Mock }
 func (m *createServiceMock) Create(
 	ctx context.Context,
 	userID int,
+	vaultID int,
 	description string,
 	secretType models.SecretType,
-	marshallableSecret services.Marshaller) (models.Secret, error) {
+	marshallableSecret services.Marshaller,
+	metadata models.Metadata) (models.Secret, error) {
 
-	args := m.Called(ctx, userID, description, secretType, marshallableSecret)
+	args := m.Called(ctx, userID, vaultID, description, secretType, marshallableSecret, metadata)
 	return args.Get(0).(models.Secret), args.Error(1)
 }
 
+type binDataServiceMock struct{ mock.Mock }
+
+func (m *binDataServiceMock) Create(
+	ctx context.Context,
+	userID int,
+	vaultID int,
+	description string,
+	filename string,
+	content io.Reader,
+	metadata models.Metadata) (models.Secret, error) {
+
+	contentBytes, err := io.ReadAll(content)
+	if err != nil {
+		return models.Secret{}, err
+	}
+	args := m.Called(ctx, userID, vaultID, description, filename, contentBytes, metadata)
+	return args.Get(0).(models.Secret), args.Error(1)
+}
+
+func (m *binDataServiceMock) Update(
+	ctx context.Context,
+	userID int,
+	secret models.Secret,
+	description string,
+	filename string,
+	content io.Reader,
+	metadata models.Metadata) (int, error) {
+
+	contentBytes, err := io.ReadAll(content)
+	if err != nil {
+		return 0, err
+	}
+	args := m.Called(ctx, userID, secret, description, filename, contentBytes, metadata)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *binDataServiceMock) WriteContent(
+	ctx context.Context,
+	secret models.Secret,
+	binData *models.BinData,
+	w io.Writer) error {
+
+	args := m.Called(ctx, secret, binData, w)
+	if content, ok := args.Get(0).([]byte); ok {
+		if _, err := w.Write(content); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *binDataServiceMock) OpenContent(
+	ctx context.Context,
+	secret models.Secret,
+	binData *models.BinData) (io.ReadSeeker, error) {
+
+	args := m.Called(ctx, secret, binData)
+	content, _ := args.Get(0).([]byte)
+	return bytes.NewReader(content), args.Error(1)
+}
+
+func (m *binDataServiceMock) OpenRevisionContent(
+	ctx context.Context,
+	revision models.SecretRevision,
+	binData *models.BinData) (io.ReadSeeker, error) {
+
+	args := m.Called(ctx, revision, binData)
+	content, _ := args.Get(0).([]byte)
+	return bytes.NewReader(content), args.Error(1)
+}
+
 func TestCreateCredentials(t *testing.T) {
 	type createResult struct {
 		secret models.Secret
@@ -42,7 +117,7 @@ func TestCreateCredentials(t *testing.T) {
 		response string
 	}
 	userID := 1
-	jwtStr, err := auth.BuildJWTString(userID)
+	jwtStr, err := auth.BuildJWTString(userID, 1)
 	require.NoError(t, err)
 	authCookie := &http.Cookie{
 		Name:  "jwt",
@@ -64,10 +139,11 @@ func TestCreateCredentials(t *testing.T) {
 			login:       "login",
 			password:    "password",
 			createRes: createResult{
-				secret: models.Secret{ID: 1},
+				secret: models.Secret{ID: 1, SecretType: models.CredentialsSecret, Description: "description"},
 			},
 			want: want{
-				code: http.StatusOK,
+				code:     http.StatusCreated,
+				response: "{\"id\":1,\"secret_type\":\"credentials\",\"description\":\"description\"}\n",
 			},
 		},
 		{
@@ -86,10 +162,11 @@ func TestCreateCredentials(t *testing.T) {
 	}
 	logger := zaptest.NewLogger(t)
 	createSrv := new(createServiceMock)
+	binDataSrv := new(binDataServiceMock)
 	handler := http.HandlerFunc(
 		handlers.
 			NewSecretHandler(logger).
-			Create(createSrv),
+			Create(createSrv, binDataSrv),
 	)
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -99,6 +176,8 @@ func TestCreateCredentials(t *testing.T) {
 					mock.Anything,
 					mock.Anything,
 					mock.Anything,
+					mock.Anything,
+					mock.Anything,
 					mock.Anything).
 				Return(tc.createRes.secret, tc.createRes.err).
 				Once()
@@ -138,7 +217,7 @@ func TestCreateCreditCard(t *testing.T) {
 		response string
 	}
 	userID := 1
-	jwtStr, err := auth.BuildJWTString(userID)
+	jwtStr, err := auth.BuildJWTString(userID, 1)
 	require.NoError(t, err)
 	authCookie := &http.Cookie{
 		Name:  "jwt",
@@ -164,10 +243,11 @@ func TestCreateCreditCard(t *testing.T) {
 			expiryDate:  "2025-10-02T15:00:00Z",
 			cvv2:        "123",
 			createRes: createResult{
-				secret: models.Secret{ID: 1},
+				secret: models.Secret{ID: 1, SecretType: models.CreditCardSecret, Description: "description"},
 			},
 			want: want{
-				code: http.StatusOK,
+				code:     http.StatusCreated,
+				response: "{\"id\":1,\"secret_type\":\"credit_card_info\",\"description\":\"description\"}\n",
 			},
 		},
 		{
@@ -188,10 +268,11 @@ func TestCreateCreditCard(t *testing.T) {
 	}
 	logger := zaptest.NewLogger(t)
 	createSrv := new(createServiceMock)
+	binDataSrv := new(binDataServiceMock)
 	handler := http.HandlerFunc(
 		handlers.
 			NewSecretHandler(logger).
-			Create(createSrv),
+			Create(createSrv, binDataSrv),
 	)
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -201,6 +282,8 @@ func TestCreateCreditCard(t *testing.T) {
 					mock.Anything,
 					mock.Anything,
 					mock.Anything,
+					mock.Anything,
+					mock.Anything,
 					mock.Anything).
 				Return(tc.createRes.secret, tc.createRes.err).
 				Once()
@@ -242,7 +325,7 @@ func TestCreateBinData(t *testing.T) {
 		response string
 	}
 	userID := 1
-	jwtStr, err := auth.BuildJWTString(userID)
+	jwtStr, err := auth.BuildJWTString(userID, 1)
 	require.NoError(t, err)
 	authCookie := &http.Cookie{
 		Name:  "jwt",
@@ -259,16 +342,29 @@ func TestCreateBinData(t *testing.T) {
 			name:   "responds with ok status",
 			userID: userID,
 			createRes: createResult{
-				secret: models.Secret{ID: 1},
+				secret: models.Secret{ID: 1, SecretType: models.BinDataSecret, Description: "description"},
 			},
 			fileContent: []byte{0x1, 0x2, 0x3},
 			want: want{
-				code: http.StatusOK,
+				code:     http.StatusCreated,
+				response: "{\"id\":1,\"secret_type\":\"bin_data\",\"description\":\"description\"}\n",
 			},
 		},
 		{
-			name:   "responds with internal server error",
-			userID: userID,
+			name:        "responds with request entity too large",
+			userID:      userID,
+			fileContent: []byte{0x1, 0x2, 0x3, 0x4},
+			createRes: createResult{
+				err: services.ErrBinDataTooLarge,
+			},
+			want: want{
+				code: http.StatusRequestEntityTooLarge,
+			},
+		},
+		{
+			name:        "responds with internal server error",
+			userID:      userID,
+			fileContent: []byte{0x5},
 			createRes: createResult{
 				err: errors.New("error"),
 			},
@@ -279,19 +375,22 @@ func TestCreateBinData(t *testing.T) {
 	}
 	logger := zaptest.NewLogger(t)
 	createSrv := new(createServiceMock)
+	binDataSrv := new(binDataServiceMock)
 	handler := http.HandlerFunc(
 		handlers.
 			NewSecretHandler(logger).
-			Create(createSrv),
+			Create(createSrv, binDataSrv),
 	)
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			createSrv.
+			binDataSrv.
 				On("Create",
 					mock.Anything,
 					mock.Anything,
-					mock.Anything,
-					mock.Anything,
+					0,
+					"description",
+					"file",
+					tc.fileContent,
 					mock.Anything).
 				Return(tc.createRes.secret, tc.createRes.err).
 				Once()
@@ -299,6 +398,7 @@ func TestCreateBinData(t *testing.T) {
 			reqBody := bytes.Buffer{}
 			writer := multipart.NewWriter(&reqBody)
 			createFormField(t, writer, "secret_type", []byte("bin_data"))
+			createFormField(t, writer, "description", []byte("description"))
 			fw, err := writer.CreateFormFile("file", "file")
 			require.NoError(t, err)
 			_, err = fw.Write(tc.fileContent)
@@ -323,9 +423,298 @@ func TestCreateBinData(t *testing.T) {
 	}
 }
 
+func TestCreateSecretJSON(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+		location string
+	}
+	userID := 1
+	jwtStr, err := auth.BuildJWTString(userID, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	testCases := []struct {
+		name        string
+		requestBody []byte
+		secretType  models.SecretType
+		secret      services.Marshaller
+		metadata    models.Metadata
+		binData     *models.BinData
+		createErr   error
+		want        want
+	}{
+		{
+			name: "creates credentials",
+			requestBody: toJSON(t, map[string]interface{}{
+				"secret_type": "credentials",
+				"description": "description",
+				"data":        map[string]string{"login": "login", "password": "password"},
+			}),
+			secretType: models.CredentialsSecret,
+			secret:     &models.Credentials{Login: "login", Password: "password"},
+			want: want{
+				code:     http.StatusCreated,
+				response: "{\"id\":1,\"secret_type\":\"credentials\",\"description\":\"description\"}\n",
+				location: "/api/secrets/1",
+			},
+		},
+		{
+			name: "creates credit card",
+			requestBody: toJSON(t, map[string]interface{}{
+				"secret_type": "credit_card_info",
+				"description": "description",
+				"data": map[string]string{
+					"number":      "1234 5678 9101 1121",
+					"name":        "Name Name",
+					"expiry_date": "2025-10-02T15:00:00Z",
+					"cvv2":        "123",
+				},
+			}),
+			secretType: models.CreditCardSecret,
+			secret: &models.CreditCard{
+				Number:     "1234 5678 9101 1121",
+				Name:       "Name Name",
+				ExpiryDate: time.Date(2025, 10, 2, 15, 0, 0, 0, time.UTC),
+				CVV2:       "123",
+			},
+			want: want{
+				code:     http.StatusCreated,
+				response: "{\"id\":1,\"secret_type\":\"credit_card_info\",\"description\":\"description\"}\n",
+				location: "/api/secrets/1",
+			},
+		},
+		{
+			name: "creates credentials with metadata",
+			requestBody: toJSON(t, map[string]interface{}{
+				"secret_type": "credentials",
+				"description": "description",
+				"data":        map[string]string{"login": "login", "password": "password"},
+				"metadata":    map[string]string{"url": "https://example.com", "env": "prod"},
+			}),
+			secretType: models.CredentialsSecret,
+			secret:     &models.Credentials{Login: "login", Password: "password"},
+			metadata:   models.Metadata{"url": "https://example.com", "env": "prod"},
+			want: want{
+				code:     http.StatusCreated,
+				response: "{\"id\":1,\"secret_type\":\"credentials\",\"description\":\"description\"}\n",
+				location: "/api/secrets/1",
+			},
+		},
+		{
+			name: "responds with bad request if metadata key is empty",
+			requestBody: toJSON(t, map[string]interface{}{
+				"secret_type": "credentials",
+				"data":        map[string]string{"login": "login", "password": "password"},
+				"metadata":    map[string]string{"": "value"},
+			}),
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "creates text",
+			requestBody: toJSON(t, map[string]interface{}{
+				"secret_type": "text",
+				"description": "description",
+				"data":        map[string]string{"title": "title", "body": "line 1\nline 2"},
+			}),
+			secretType: models.TextSecret,
+			secret:     &models.Text{Title: "title", Body: "line 1\nline 2"},
+			want: want{
+				code:     http.StatusCreated,
+				response: "{\"id\":1,\"secret_type\":\"text\",\"description\":\"description\"}\n",
+				location: "/api/secrets/1",
+			},
+		},
+		{
+			name: "creates bin data",
+			requestBody: toJSON(t, map[string]interface{}{
+				"secret_type": "bin_data",
+				"description": "description",
+				"data":        map[string]interface{}{"filename": "file", "content": []byte{0x1, 0x2, 0x3}},
+			}),
+			secretType: models.BinDataSecret,
+			binData:    &models.BinData{Filename: "file", Bytes: []byte{0x1, 0x2, 0x3}},
+			want: want{
+				code:     http.StatusCreated,
+				response: "{\"id\":1,\"secret_type\":\"bin_data\",\"description\":\"description\"}\n",
+				location: "/api/secrets/1",
+			},
+		},
+		{
+			name: "responds with bad request if secret type is invalid",
+			requestBody: toJSON(t, map[string]interface{}{
+				"secret_type": "unknown",
+				"data":        map[string]string{},
+			}),
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "responds with internal server error",
+			requestBody: toJSON(t, map[string]interface{}{
+				"secret_type": "credentials",
+				"description": "description",
+				"data":        map[string]string{"login": "login", "password": "password"},
+			}),
+			secretType: models.CredentialsSecret,
+			secret:     &models.Credentials{Login: "login", Password: "password"},
+			createErr:  errors.New("error"),
+			want: want{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+	logger := zaptest.NewLogger(t)
+	createSrv := new(createServiceMock)
+	binDataSrv := new(binDataServiceMock)
+	handler := http.HandlerFunc(
+		handlers.
+			NewSecretHandler(logger).
+			Create(createSrv, binDataSrv),
+	)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			createCall := createSrv.
+				On("Create",
+					mock.Anything,
+					mock.Anything,
+					0,
+					"description",
+					tc.secretType,
+					tc.secret,
+					tc.metadata).
+				Return(
+					models.Secret{ID: 1, SecretType: tc.secretType, Description: "description"},
+					tc.createErr,
+				)
+			defer createCall.Unset()
+			if tc.binData != nil {
+				binDataCall := binDataSrv.
+					On("Create",
+						mock.Anything,
+						mock.Anything,
+						0,
+						"description",
+						tc.binData.Filename,
+						tc.binData.Bytes,
+						tc.metadata).
+					Return(
+						models.Secret{ID: 1, SecretType: tc.secretType, Description: "description"},
+						tc.createErr,
+					)
+				defer binDataCall.Unset()
+			}
+
+			request, err := http.NewRequest(
+				http.MethodPost,
+				"/api/secrets",
+				bytes.NewReader(tc.requestBody),
+			)
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			request.Header.Add("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+			assert.Equal(t, tc.want.location, recorder.Header().Get("Location"))
+		})
+	}
+}
+
 func createFormField(t *testing.T, writer *multipart.Writer, name string, value []byte) {
 	fw, err := writer.CreateFormField(name)
 	require.NoError(t, err)
 	_, err = fw.Write(value)
 	require.NoError(t, err)
 }
+
+func TestCreateVaultSecret(t *testing.T) {
+	type want struct {
+		code     int
+		location string
+	}
+	testCases := []struct {
+		name      string
+		query     string
+		vaultID   int
+		createErr error
+		want      want
+	}{
+		{
+			name:    "creates secret in vault",
+			query:   "?vault_id=2",
+			vaultID: 2,
+			want: want{
+				code:     http.StatusCreated,
+				location: "/api/secrets/1",
+			},
+		},
+		{
+			name:      "responds with forbidden status if user is not organization member",
+			query:     "?vault_id=2",
+			vaultID:   2,
+			createErr: services.ErrNoPermission{UserID: 1, VaultID: 2},
+			want: want{
+				code: http.StatusForbidden,
+			},
+		},
+		{
+			name:  "responds with bad request if vault id is invalid",
+			query: "?vault_id=vault",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+	}
+	logger := zaptest.NewLogger(t)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			createSrv := new(createServiceMock)
+			createSrv.
+				On("Create",
+					mock.Anything,
+					mock.Anything,
+					tc.vaultID,
+					"description",
+					models.TextSecret,
+					mock.Anything,
+					mock.Anything).
+				Return(
+					models.Secret{ID: 1, VaultID: tc.vaultID, SecretType: models.TextSecret, Description: "description"},
+					tc.createErr,
+				)
+			handler := http.HandlerFunc(
+				handlers.
+					NewSecretHandler(logger).
+					Create(createSrv, new(binDataServiceMock)),
+			)
+
+			request, err := http.NewRequest(
+				http.MethodPost,
+				"/api/secrets"+tc.query,
+				bytes.NewReader(toJSON(t, map[string]interface{}{
+					"secret_type": "text",
+					"description": "description",
+					"data":        map[string]string{"title": "title", "body": "body"},
+				})),
+			)
+			require.NoError(t, err)
+			request.Header.Add("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.location, recorder.Header().Get("Location"))
+			if tc.want.code == http.StatusBadRequest {
+				createSrv.AssertNotCalled(t, "Create")
+			}
+		})
+	}
+}
@@ -34,6 +34,11 @@ func (m *deleteServiceMock) Delete(ctx context.Context, userID int, secret model
 	return args.Error(0)
 }
 
+func (m *deleteServiceMock) Purge(ctx context.Context, userID int, secret models.Secret) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
 func TestDelete(t *testing.T) {
 	type want struct {
 		code     int
@@ -46,9 +51,13 @@ func TestDelete(t *testing.T) {
 	testCases := []struct {
 		name      string
 		secretID  int
+		query     string
+		ifMatch   string
 		findRes   findResult
 		deleteErr error
-		want      want
+		// method is the delete service method expected to be called
+		method string
+		want   want
 	}{
 		{
 			name:     "responds with ok status",
@@ -60,10 +69,103 @@ func TestDelete(t *testing.T) {
 					SecretType: models.CredentialsSecret,
 				},
 			},
+			method: "Delete",
+			want: want{
+				code: http.StatusOK,
+			},
+		},
+		{
+			name:     "deletes secret permanently",
+			secretID: 1,
+			query:    "?permanent=true",
+			findRes: findResult{
+				secret: models.Secret{
+					ID:         1,
+					UserID:     1,
+					SecretType: models.CredentialsSecret,
+				},
+			},
+			method: "Purge",
+			want: want{
+				code: http.StatusOK,
+			},
+		},
+		{
+			name:     "deletes secret whatever its version is without If-Match",
+			secretID: 1,
+			findRes: findResult{
+				secret: models.Secret{
+					ID:         1,
+					UserID:     1,
+					SecretType: models.CredentialsSecret,
+					Version:    7,
+				},
+			},
+			method: "Delete",
+			want: want{
+				code: http.StatusOK,
+			},
+		},
+		{
+			name:     "deletes secret if it matches If-Match",
+			secretID: 1,
+			ifMatch:  `"7"`,
+			findRes: findResult{
+				secret: models.Secret{
+					ID:         1,
+					UserID:     1,
+					SecretType: models.CredentialsSecret,
+					Version:    7,
+				},
+			},
+			method: "Delete",
 			want: want{
 				code: http.StatusOK,
 			},
 		},
+		{
+			name:     "responds with precondition failed if secret does not match If-Match",
+			secretID: 1,
+			query:    "?permanent=true",
+			ifMatch:  `"6"`,
+			findRes: findResult{
+				secret: models.Secret{
+					ID:         1,
+					UserID:     1,
+					SecretType: models.CredentialsSecret,
+					Version:    7,
+				},
+			},
+			want: want{
+				code: http.StatusPreconditionFailed,
+			},
+		},
+		{
+			name:     "responds with precondition failed if secret has been changed concurrently",
+			secretID: 1,
+			ifMatch:  `"7"`,
+			findRes: findResult{
+				secret: models.Secret{
+					ID:         1,
+					UserID:     1,
+					SecretType: models.CredentialsSecret,
+					Version:    7,
+				},
+			},
+			deleteErr: storage.ErrSecretVersionMismatch{Secret: models.Secret{ID: 1, Version: 7}},
+			method:    "Delete",
+			want: want{
+				code: http.StatusPreconditionFailed,
+			},
+		},
+		{
+			name:     "responds with bad request if permanent parameter is invalid",
+			secretID: 1,
+			query:    "?permanent=yes",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
 		{
 			name:     "responds with status not found",
 			secretID: 1,
@@ -85,6 +187,7 @@ func TestDelete(t *testing.T) {
 				},
 			},
 			deleteErr: services.ErrNoPermission{SecretID: 1, UserID: 2},
+			method:    "Delete",
 			want: want{
 				code: http.StatusForbidden,
 			},
@@ -102,36 +205,37 @@ func TestDelete(t *testing.T) {
 	}
 
 	userID := 1
-	jwtStr, err := auth.BuildJWTString(userID)
+	jwtStr, err := auth.BuildJWTString(userID, 1)
 	require.NoError(t, err)
 	authCookie := &http.Cookie{
 		Name:  "jwt",
 		Value: jwtStr,
 	}
-	delSrv := new(deleteServiceMock)
 	findSrv := new(findSecretServiceMock)
-	handler := http.HandlerFunc(
-		handlers.NewSecretHandler(zaptest.NewLogger(t)).
-			Delete(findSrv, delSrv),
-	)
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			findCall := findSrv.On("Find", mock.Anything, mock.Anything).
 				Return(tc.findRes.secret, tc.findRes.err).
 				Once()
 			defer findCall.Unset()
-			delCall := delSrv.On("Delete", mock.Anything, mock.Anything, mock.Anything).
-				Return(tc.deleteErr).
-				Once()
-			defer delCall.Unset()
+			delSrv := new(deleteServiceMock)
+			delSrv.On("Delete", mock.Anything, mock.Anything, mock.Anything).Return(tc.deleteErr)
+			delSrv.On("Purge", mock.Anything, mock.Anything, mock.Anything).Return(tc.deleteErr)
+			handler := http.HandlerFunc(
+				handlers.NewSecretHandler(zaptest.NewLogger(t)).
+					Delete(findSrv, delSrv),
+			)
 
 			request, err := http.NewRequest(
 				http.MethodDelete,
-				"/api/secrets/"+strconv.Itoa(tc.secretID),
+				"/api/secrets/"+strconv.Itoa(tc.secretID)+tc.query,
 				nil,
 			)
 			require.NoError(t, err)
 			request.AddCookie(authCookie)
+			if tc.ifMatch != "" {
+				request.Header.Set("If-Match", tc.ifMatch)
+			}
 			rctx := chi.NewRouteContext()
 			rctx.URLParams.Add("id", strconv.Itoa(tc.secretID))
 			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
@@ -140,6 +244,18 @@ func TestDelete(t *testing.T) {
 
 			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
 			assert.Equal(t, tc.want.response, recorder.Body.String())
+			// without If-Match the secret is deleted whatever its version is
+			secret := tc.findRes.secret
+			if tc.ifMatch == "" {
+				secret.Version = 0
+			}
+			for _, method := range []string{"Delete", "Purge"} {
+				if method == tc.method {
+					delSrv.AssertCalled(t, method, mock.Anything, mock.Anything, secret)
+				} else {
+					delSrv.AssertNotCalled(t, method, mock.Anything, mock.Anything, mock.Anything)
+				}
+			}
 		})
 	}
 }
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// eventsKeepAliveInterval is the interval of comments sent to keep idle
+// event streams open through proxies.
+const eventsKeepAliveInterval = 30 * time.Second
+
+type SecretEventsService interface {
+	Subscribe(userID int) (<-chan models.SecretEvent, func())
+}
+
+// Events streams changes of secrets the user can read as server-sent
+// events. The event type is the change kind and the event ID is the change
+// revision. Revisions count changes of the secret owner, so changes of
+// secrets of other users and vaults are sent without them. The stream
+// ends if events may have been lost, the client should sync secrets from
+// the last received revision then and reconnect.
+func (h SecretHandler) Events(eventsSrv SecretEventsService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowedUnlimitedScope(w, r) {
+			return
+		}
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		events, unsubscribe := eventsSrv.Subscribe(userID)
+		defer unsubscribe()
+
+		controller := http.NewResponseController(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		if err := controller.Flush(); err != nil {
+			h.logger.Info("failed to flush events stream", zap.Error(err))
+			return
+		}
+
+		keepAlive := time.NewTicker(eventsKeepAliveInterval)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				response := secretEventResponse{ID: event.SecretID}
+				if event.UserID == userID && event.VaultID == 0 {
+					response.Revision = event.Revision
+				}
+				data, err := json.Marshal(response)
+				if err != nil {
+					h.logger.Info("failed to encode secret event", zap.Error(err))
+					return
+				}
+				if response.Revision != 0 {
+					_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", response.Revision, event.Kind, data)
+				} else {
+					_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Kind, data)
+				}
+				if err != nil {
+					return
+				}
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type secretEventsServiceMock struct{ mock.Mock }
+
+func (m *secretEventsServiceMock) Subscribe(userID int) (<-chan models.SecretEvent, func()) {
+	args := m.Called(userID)
+	return args.Get(0).(<-chan models.SecretEvent), args.Get(1).(func())
+}
+
+func TestEvents(t *testing.T) {
+	testCases := []struct {
+		name     string
+		events   []models.SecretEvent
+		response string
+	}{
+		{
+			name: "streams secret events",
+			events: []models.SecretEvent{
+				{Kind: models.SecretCreated, UserID: 1, SecretID: 3, Revision: 7},
+				{Kind: models.SecretUpdated, UserID: 1, SecretID: 3, Revision: 8},
+				{Kind: models.SecretDeleted, UserID: 1, SecretID: 2, Revision: 9},
+			},
+			response: "id: 7\nevent: created\ndata: {\"id\":3,\"revision\":7}\n\n" +
+				"id: 8\nevent: updated\ndata: {\"id\":3,\"revision\":8}\n\n" +
+				"id: 9\nevent: deleted\ndata: {\"id\":2,\"revision\":9}\n\n",
+		},
+		{
+			name: "streams events of other owners without revisions",
+			events: []models.SecretEvent{
+				{Kind: models.SecretUpdated, UserID: 2, SecretID: 4, Revision: 3},
+				{Kind: models.SecretCreated, UserID: 1, VaultID: 1, SecretID: 5, Revision: 10},
+			},
+			response: "event: updated\ndata: {\"id\":4}\n\n" +
+				"event: created\ndata: {\"id\":5}\n\n",
+		},
+		{
+			name: "ends stream if subscription is closed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			events := make(chan models.SecretEvent, len(tc.events))
+			for _, event := range tc.events {
+				events <- event
+			}
+			close(events)
+			unsubscribed := false
+			eventsSrv := new(secretEventsServiceMock)
+			eventsSrv.On("Subscribe", mock.Anything).
+				Return((<-chan models.SecretEvent)(events), func() { unsubscribed = true })
+			handler := http.HandlerFunc(handlers.NewSecretHandler(zaptest.NewLogger(t)).Events(eventsSrv))
+
+			request, err := http.NewRequest(http.MethodGet, "/api/events", nil)
+			require.NoError(t, err)
+			request = request.WithContext(middlewares.WithAPIToken(request.Context(), models.APIToken{UserID: 1}))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+			assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+			assert.Equal(t, tc.response, recorder.Body.String())
+			assert.True(t, recorder.Flushed)
+			assert.True(t, unsubscribed)
+		})
+	}
+}
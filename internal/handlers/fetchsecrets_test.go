@@ -3,12 +3,14 @@ package handlers_test
 import (
 	"context"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
 	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -17,9 +19,14 @@ import (
 
 type secretFetcherMock struct{ mock.Mock }
 
-func (m *secretFetcherMock) FetchUserSecrets(ctx context.Context, userID int) ([]byte, error) {
-	args := m.Called(ctx, userID)
-	return args.Get(0).([]byte), args.Error(1)
+func (m *secretFetcherMock) FetchUserSecrets(
+	ctx context.Context,
+	userID int,
+	filter models.SecretsFilter,
+	w io.Writer) error {
+
+	args := m.Called(ctx, userID, filter, w)
+	return args.Error(0)
 }
 
 func TestGetUserHandler(t *testing.T) {
@@ -33,6 +40,8 @@ func TestGetUserHandler(t *testing.T) {
 	}
 	testCases := []struct {
 		name     string
+		query    string
+		filter   models.SecretsFilter
 		fetchRes fetchResult
 		want     want
 	}{
@@ -46,6 +55,25 @@ func TestGetUserHandler(t *testing.T) {
 				response: []byte{0x1, 0x2, 0x3},
 			},
 		},
+		{
+			name:   "passes folder and tag filter",
+			query:  "?folder_id=2&tag_id=3",
+			filter: models.SecretsFilter{FolderID: 2, TagID: 3},
+			fetchRes: fetchResult{
+				archiveContent: []byte{0x1},
+			},
+			want: want{
+				code:     http.StatusOK,
+				response: []byte{0x1},
+			},
+		},
+		{
+			name:  "responds with bad request if folder id is invalid",
+			query: "?folder_id=abc",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
 		{
 			name: "responds with internal server error",
 			fetchRes: fetchResult{
@@ -55,10 +83,21 @@ func TestGetUserHandler(t *testing.T) {
 				code: http.StatusInternalServerError,
 			},
 		},
+		{
+			name: "keeps ok status if archive was partially sent",
+			fetchRes: fetchResult{
+				archiveContent: []byte{0x1, 0x2},
+				err:            errors.New("error"),
+			},
+			want: want{
+				code:     http.StatusOK,
+				response: []byte{0x1, 0x2},
+			},
+		},
 	}
 
 	userID := 1
-	jwtStr, err := auth.BuildJWTString(userID)
+	jwtStr, err := auth.BuildJWTString(userID, 1)
 	require.NoError(t, err)
 	authCookie := &http.Cookie{
 		Name:  "jwt",
@@ -71,13 +110,19 @@ func TestGetUserHandler(t *testing.T) {
 	)
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			fetchCall := fetchSrv.On("FetchUserSecrets", mock.Anything, mock.Anything).
-				Return(tc.fetchRes.archiveContent, tc.fetchRes.err)
+			fetchCall := fetchSrv.On("FetchUserSecrets", mock.Anything, mock.Anything, tc.filter, mock.Anything).
+				Run(func(args mock.Arguments) {
+					if len(tc.fetchRes.archiveContent) > 0 {
+						_, err := args.Get(3).(io.Writer).Write(tc.fetchRes.archiveContent)
+						require.NoError(t, err)
+					}
+				}).
+				Return(tc.fetchRes.err)
 			defer fetchCall.Unset()
 
 			request, err := http.NewRequest(
 				http.MethodGet,
-				"/api/secrets",
+				"/api/secrets"+tc.query,
 				nil,
 			)
 			require.NoError(t, err)
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"go.uber.org/zap"
+)
+
+type FolderService interface {
+	Create(ctx context.Context, userID int, name string, parentID int) (models.Folder, error)
+	Find(ctx context.Context, userID int, id int) (models.Folder, error)
+	List(ctx context.Context, userID int) ([]models.Folder, error)
+	Update(ctx context.Context, folder models.Folder) error
+}
+
+type folderPayload struct {
+	Name     string `json:"name"`
+	ParentID int    `json:"parent_id"`
+}
+
+// folderUpdatePayload fields are optional, a nil field is not changed.
+type folderUpdatePayload struct {
+	Name     *string `json:"name"`
+	ParentID *int    `json:"parent_id"`
+}
+
+type folderResponse struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	ParentID int    `json:"parent_id,omitempty"`
+}
+
+type foldersIndexResponse struct {
+	Folders []folderResponse `json:"folders"`
+}
+
+type FolderHandler struct {
+	logger *zap.Logger
+}
+
+func NewFolderHandler(logger *zap.Logger) FolderHandler {
+	return FolderHandler{
+		logger: logger,
+	}
+}
+
+func (h FolderHandler) Create(srv FolderService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		var payload folderPayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid folder request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		folder, err := srv.Create(r.Context(), userID, payload.Name, payload.ParentID)
+		if err != nil {
+			h.writeError(w, "failed to create folder", err)
+			return
+		}
+
+		h.writeFolder(w, http.StatusCreated, folder)
+	}
+}
+
+func (h FolderHandler) Index(srv FolderService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		folders, err := srv.List(r.Context(), userID)
+		if err != nil {
+			h.logger.Info("failed to list folders", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := foldersIndexResponse{Folders: make([]folderResponse, len(folders))}
+		for i, folder := range folders {
+			response.Folders[i] = newFolderResponse(folder)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// Update renames the folder and moves it into another folder, zero
+// parent_id moves the folder to the top level.
+func (h FolderHandler) Update(srv FolderService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		folderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			h.logger.Info("invalid folder id", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var payload folderUpdatePayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid folder request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		folder, err := srv.Find(r.Context(), userID, folderID)
+		if err != nil {
+			var notFoundErr storage.ErrFolderNotFound
+			if errors.As(err, &notFoundErr) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			h.logger.Info("failed to update folder", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if payload.Name != nil {
+			folder.Name = *payload.Name
+		}
+		if payload.ParentID != nil {
+			folder.ParentID = *payload.ParentID
+		}
+		if err := srv.Update(r.Context(), folder); err != nil {
+			h.writeError(w, "failed to update folder", err)
+			return
+		}
+
+		h.writeFolder(w, http.StatusOK, folder)
+	}
+}
+
+// writeError responds to failed create and update requests, a not found
+// folder is the parent folder from the request body.
+func (h FolderHandler) writeError(w http.ResponseWriter, msg string, err error) {
+	var notFoundErr storage.ErrFolderNotFound
+	var notUniqErr storage.ErrFolderNotUniq
+	switch {
+	case errors.Is(err, services.ErrInvalidFolderName), errors.As(err, &notFoundErr):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, services.ErrFolderCycle), errors.As(err, &notUniqErr):
+		w.WriteHeader(http.StatusConflict)
+	default:
+		h.logger.Info(msg, zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (h FolderHandler) writeFolder(w http.ResponseWriter, status int, folder models.Folder) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(newFolderResponse(folder)); err != nil {
+		h.logger.Info("failed to encode response", zap.Error(err))
+	}
+}
+
+func newFolderResponse(folder models.Folder) folderResponse {
+	return folderResponse{
+		ID:       folder.ID,
+		Name:     folder.Name,
+		ParentID: folder.ParentID,
+	}
+}
@@ -0,0 +1,343 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type folderServiceMock struct{ mock.Mock }
+
+func (m *folderServiceMock) Create(ctx context.Context, userID int, name string, parentID int) (models.Folder, error) {
+	args := m.Called(ctx, userID, name, parentID)
+	return args.Get(0).(models.Folder), args.Error(1)
+}
+
+func (m *folderServiceMock) Find(ctx context.Context, userID int, id int) (models.Folder, error) {
+	args := m.Called(ctx, userID, id)
+	return args.Get(0).(models.Folder), args.Error(1)
+}
+
+func (m *folderServiceMock) List(ctx context.Context, userID int) ([]models.Folder, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Folder), args.Error(1)
+}
+
+func (m *folderServiceMock) Update(ctx context.Context, folder models.Folder) error {
+	args := m.Called(ctx, folder)
+	return args.Error(0)
+}
+
+func (m *folderServiceMock) MoveSecret(ctx context.Context, userID int, secret models.Secret, folderID int) error {
+	args := m.Called(ctx, userID, secret, folderID)
+	return args.Error(0)
+}
+
+func TestCreateFolder(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+	}
+	type createResult struct {
+		folder models.Folder
+		err    error
+	}
+	testCases := []struct {
+		name        string
+		requestBody string
+		createRes   createResult
+		want        want
+	}{
+		{
+			name:        "responds with created status",
+			requestBody: `{"name":"Cards","parent_id":1}`,
+			createRes: createResult{
+				folder: models.Folder{ID: 2, UserID: 1, ParentID: 1, Name: "Cards"},
+			},
+			want: want{
+				code:     http.StatusCreated,
+				response: "{\"id\":2,\"name\":\"Cards\",\"parent_id\":1}\n",
+			},
+		},
+		{
+			name:        "responds with bad request if name is invalid",
+			requestBody: `{"name":"Work/Cards"}`,
+			createRes: createResult{
+				err: services.ErrInvalidFolderName,
+			},
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:        "responds with bad request if parent folder is not found",
+			requestBody: `{"name":"Cards","parent_id":1}`,
+			createRes: createResult{
+				err: storage.ErrFolderNotFound{Folder: models.Folder{ID: 1}},
+			},
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:        "responds with conflict if folder already exists",
+			requestBody: `{"name":"Cards","parent_id":1}`,
+			createRes: createResult{
+				err: storage.ErrFolderNotUniq{Folder: models.Folder{Name: "Cards"}},
+			},
+			want: want{
+				code: http.StatusConflict,
+			},
+		},
+		{
+			name:        "responds with internal server error",
+			requestBody: `{"name":"Cards","parent_id":1}`,
+			createRes: createResult{
+				err: errors.New("error"),
+			},
+			want: want{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	folderSrv := new(folderServiceMock)
+	handler := http.HandlerFunc(handlers.NewFolderHandler(zaptest.NewLogger(t)).Create(folderSrv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			createCall := folderSrv.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(tc.createRes.folder, tc.createRes.err)
+			defer createCall.Unset()
+
+			request, err := http.NewRequest(http.MethodPost, "/api/folders", strings.NewReader(tc.requestBody))
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			request.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
+
+func TestUpdateFolder(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+	}
+	type findResult struct {
+		folder models.Folder
+		err    error
+	}
+	folder := models.Folder{ID: 2, UserID: 1, ParentID: 1, Name: "Cards"}
+	testCases := []struct {
+		name          string
+		requestBody   string
+		findRes       findResult
+		updatedFolder models.Folder
+		updateErr     error
+		want          want
+	}{
+		{
+			name:          "renames folder",
+			requestBody:   `{"name":"Credit cards"}`,
+			findRes:       findResult{folder: folder},
+			updatedFolder: models.Folder{ID: 2, UserID: 1, ParentID: 1, Name: "Credit cards"},
+			want: want{
+				code:     http.StatusOK,
+				response: "{\"id\":2,\"name\":\"Credit cards\",\"parent_id\":1}\n",
+			},
+		},
+		{
+			name:          "moves folder to top level",
+			requestBody:   `{"parent_id":0}`,
+			findRes:       findResult{folder: folder},
+			updatedFolder: models.Folder{ID: 2, UserID: 1, Name: "Cards"},
+			want: want{
+				code:     http.StatusOK,
+				response: "{\"id\":2,\"name\":\"Cards\"}\n",
+			},
+		},
+		{
+			name:          "responds with conflict if folder is moved into its subfolder",
+			requestBody:   `{"parent_id":3}`,
+			findRes:       findResult{folder: folder},
+			updatedFolder: models.Folder{ID: 2, UserID: 1, ParentID: 3, Name: "Cards"},
+			updateErr:     services.ErrFolderCycle,
+			want: want{
+				code: http.StatusConflict,
+			},
+		},
+		{
+			name:        "responds with not found status",
+			requestBody: `{"name":"Credit cards"}`,
+			findRes: findResult{
+				err: storage.ErrFolderNotFound{Folder: models.Folder{ID: 2}},
+			},
+			want: want{
+				code: http.StatusNotFound,
+			},
+		},
+		{
+			name:        "responds with bad request if body is invalid",
+			requestBody: `{"name":1}`,
+			findRes:     findResult{folder: folder},
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+	}
+
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	folderSrv := new(folderServiceMock)
+	handler := http.HandlerFunc(handlers.NewFolderHandler(zaptest.NewLogger(t)).Update(folderSrv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			findCall := folderSrv.On("Find", mock.Anything, mock.Anything, 2).
+				Return(tc.findRes.folder, tc.findRes.err)
+			defer findCall.Unset()
+			updateCall := folderSrv.On("Update", mock.Anything, tc.updatedFolder).
+				Return(tc.updateErr)
+			defer updateCall.Unset()
+
+			request, err := http.NewRequest(http.MethodPatch, "/api/folders/2", strings.NewReader(tc.requestBody))
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			request.Header.Set("Content-Type", "application/json")
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "2")
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
+
+func TestMoveSecret(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+	}
+	type findResult struct {
+		secret models.Secret
+		err    error
+	}
+	secret := models.Secret{
+		ID:          1,
+		UserID:      1,
+		SecretType:  models.CredentialsSecret,
+		Description: "description",
+	}
+	testCases := []struct {
+		name        string
+		requestBody string
+		findRes     findResult
+		folderID    int
+		moveErr     error
+		want        want
+	}{
+		{
+			name:        "responds with ok status",
+			requestBody: `{"folder_id":2}`,
+			findRes:     findResult{secret: secret},
+			folderID:    2,
+			want: want{
+				code:     http.StatusOK,
+				response: "{\"id\":1,\"secret_type\":\"credentials\",\"description\":\"description\"}\n",
+			},
+		},
+		{
+			name:        "responds with bad request if folder is not found",
+			requestBody: `{"folder_id":3}`,
+			findRes:     findResult{secret: secret},
+			folderID:    3,
+			moveErr:     storage.ErrFolderNotFound{Folder: models.Folder{ID: 3}},
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:        "responds with forbidden status",
+			requestBody: `{"folder_id":2}`,
+			findRes:     findResult{secret: secret},
+			folderID:    2,
+			moveErr:     services.ErrNoPermission{UserID: 1, SecretID: 1},
+			want: want{
+				code: http.StatusForbidden,
+			},
+		},
+		{
+			name:        "responds with not found status",
+			requestBody: `{"folder_id":2}`,
+			findRes: findResult{
+				err: storage.ErrSecretNotFound{Secret: models.Secret{ID: 1}},
+			},
+			want: want{
+				code: http.StatusNotFound,
+			},
+		},
+	}
+
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	findSrv := new(findSecretServiceMock)
+	folderSrv := new(folderServiceMock)
+	handler := http.HandlerFunc(handlers.NewSecretHandler(zaptest.NewLogger(t)).Move(findSrv, folderSrv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			findCall := findSrv.On("Find", mock.Anything, 1).
+				Return(tc.findRes.secret, tc.findRes.err)
+			defer findCall.Unset()
+			moveCall := folderSrv.On("MoveSecret", mock.Anything, mock.Anything, tc.findRes.secret, tc.folderID).
+				Return(tc.moveErr)
+			defer moveCall.Unset()
+
+			request, err := http.NewRequest(http.MethodPut, "/api/secrets/1/folder", strings.NewReader(tc.requestBody))
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			request.Header.Set("Content-Type", "application/json")
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
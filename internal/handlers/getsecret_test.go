@@ -0,0 +1,284 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type showServiceMock struct{ mock.Mock }
+
+func (m *showServiceMock) Show(ctx context.Context, userID int, secret models.Secret) (services.Unmarshaller, error) {
+	args := m.Called(ctx, userID, secret)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(services.Unmarshaller), args.Error(1)
+}
+
+func TestGetSecret(t *testing.T) {
+	type want struct {
+		code               int
+		contentType        string
+		contentDisposition string
+		etag               string
+		digest             string
+		metadata           string
+		response           string
+	}
+	type findResult struct {
+		secret models.Secret
+		err    error
+	}
+	type showResult struct {
+		secret services.Unmarshaller
+		err    error
+	}
+	testCases := []struct {
+		name    string
+		findRes findResult
+		headers map[string]string
+		showRes showResult
+		content []byte
+		want    want
+	}{
+		{
+			name: "responds with credentials",
+			findRes: findResult{
+				secret: models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret},
+			},
+			showRes: showResult{
+				secret: &models.Credentials{ID: 1, Login: "login", Password: "password"},
+			},
+			want: want{
+				code:        http.StatusOK,
+				contentType: "application/json",
+				response:    "{\"ID\":1,\"Description\":\"\",\"Login\":\"login\",\"Password\":\"password\"}\n",
+			},
+		},
+		{
+			name: "responds with credentials metadata",
+			findRes: findResult{
+				secret: models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret},
+			},
+			showRes: showResult{
+				secret: &models.Credentials{
+					ID:       1,
+					Login:    "login",
+					Password: "password",
+					Metadata: models.Metadata{"env": "prod"},
+				},
+			},
+			want: want{
+				code:        http.StatusOK,
+				contentType: "application/json",
+				response: "{\"ID\":1,\"Description\":\"\",\"Login\":\"login\",\"Password\":\"password\"," +
+					"\"Metadata\":{\"env\":\"prod\"}}\n",
+			},
+		},
+		{
+			name: "responds with bin data attachment",
+			findRes: findResult{
+				secret: models.Secret{ID: 1, UserID: 1, SecretType: models.BinDataSecret},
+			},
+			showRes: showResult{
+				secret: &models.BinData{ID: 1, Filename: "file.txt", Size: 7},
+			},
+			content: []byte("content"),
+			want: want{
+				code:               http.StatusOK,
+				contentType:        "application/octet-stream",
+				contentDisposition: "attachment; filename=file.txt",
+				response:           "content",
+			},
+		},
+		{
+			name: "responds with bin data range",
+			findRes: findResult{
+				secret: models.Secret{ID: 1, UserID: 1, SecretType: models.BinDataSecret, EncryptedData: []byte("data"), Version: 7},
+			},
+			headers: map[string]string{"Range": "bytes=2-4"},
+			showRes: showResult{
+				secret: &models.BinData{ID: 1, Filename: "file.txt", Size: 7},
+			},
+			content: []byte("content"),
+			want: want{
+				code:               http.StatusPartialContent,
+				contentType:        "application/octet-stream",
+				contentDisposition: "attachment; filename=file.txt",
+				etag:               `"7"`,
+				response:           "nte",
+			},
+		},
+		{
+			name: "responds with bin data digest",
+			findRes: findResult{
+				secret: models.Secret{ID: 1, UserID: 1, SecretType: models.BinDataSecret, EncryptedData: []byte("data"), Version: 7},
+			},
+			showRes: showResult{
+				secret: &models.BinData{ID: 1, Filename: "file.txt", Bytes: []byte("content")},
+			},
+			content: []byte("content"),
+			want: want{
+				code:               http.StatusOK,
+				contentType:        "application/octet-stream",
+				contentDisposition: "attachment; filename=file.txt",
+				etag:               `"7"`,
+				digest:             "sha-256=:7XACtDnprIRfIjV9giusFERzD722AW0+yUMil7nsn3M=:",
+				response:           "content",
+			},
+		},
+		{
+			name: "responds with bin data metadata",
+			findRes: findResult{
+				secret: models.Secret{ID: 1, UserID: 1, SecretType: models.BinDataSecret},
+			},
+			showRes: showResult{
+				secret: &models.BinData{
+					ID:       1,
+					Filename: "file.txt",
+					Size:     7,
+					Metadata: models.Metadata{"url": "https://example.com", "env": "prod"},
+				},
+			},
+			content: []byte("content"),
+			want: want{
+				code:               http.StatusOK,
+				contentType:        "application/octet-stream",
+				contentDisposition: "attachment; filename=file.txt",
+				metadata:           "env=prod&url=https%3A%2F%2Fexample.com",
+				response:           "content",
+			},
+		},
+		{
+			name: "responds with not modified status if bin data has not changed",
+			findRes: findResult{
+				secret: models.Secret{ID: 1, UserID: 1, SecretType: models.BinDataSecret, EncryptedData: []byte("data"), Version: 7},
+			},
+			headers: map[string]string{"If-None-Match": `"7"`},
+			showRes: showResult{
+				secret: &models.BinData{ID: 1, Filename: "file.txt", Size: 7},
+			},
+			content: []byte("content"),
+			want: want{
+				code:               http.StatusNotModified,
+				contentDisposition: "attachment; filename=file.txt",
+				etag:               `"7"`,
+			},
+		},
+		{
+			name: "responds with not modified status if credentials have not changed",
+			findRes: findResult{
+				secret: models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret, EncryptedData: []byte("data"), Version: 7},
+			},
+			headers: map[string]string{"If-None-Match": `"other", "7"`},
+			showRes: showResult{
+				secret: &models.Credentials{ID: 1, Login: "login", Password: "password"},
+			},
+			want: want{
+				code: http.StatusNotModified,
+				etag: `"7"`,
+			},
+		},
+		{
+			name: "responds with not found status",
+			findRes: findResult{
+				err: storage.ErrSecretNotFound{Secret: models.Secret{ID: 1}},
+			},
+			want: want{
+				code: http.StatusNotFound,
+			},
+		},
+		{
+			name: "responds with forbidden status",
+			findRes: findResult{
+				secret: models.Secret{ID: 1, UserID: 2, SecretType: models.CredentialsSecret},
+			},
+			showRes: showResult{
+				err: services.ErrNoPermission{UserID: 1, SecretID: 1},
+			},
+			want: want{
+				code: http.StatusForbidden,
+			},
+		},
+		{
+			name: "responds with internal server error",
+			findRes: findResult{
+				secret: models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret},
+			},
+			showRes: showResult{
+				err: errors.New("error"),
+			},
+			want: want{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	userID := 1
+	jwtStr, err := auth.BuildJWTString(userID, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	findSrv := new(findSecretServiceMock)
+	showSrv := new(showServiceMock)
+	binDataSrv := new(binDataServiceMock)
+	handler := http.HandlerFunc(
+		handlers.NewSecretHandler(zaptest.NewLogger(t)).
+			Get(findSrv, showSrv, binDataSrv),
+	)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			findCall := findSrv.On("Find", mock.Anything, mock.Anything).
+				Return(tc.findRes.secret, tc.findRes.err)
+			defer findCall.Unset()
+			showCall := showSrv.On("Show", mock.Anything, mock.Anything, mock.Anything).
+				Return(tc.showRes.secret, tc.showRes.err)
+			defer showCall.Unset()
+			openCall := binDataSrv.On("OpenContent", mock.Anything, mock.Anything, mock.Anything).
+				Return(tc.content, nil)
+			defer openCall.Unset()
+
+			request, err := http.NewRequest(http.MethodGet, "/api/secrets/1", nil)
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			for name, value := range tc.headers {
+				request.Header.Set(name, value)
+			}
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", strconv.Itoa(1))
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+			if tc.want.contentType != "" {
+				assert.Equal(t, tc.want.contentType, recorder.Header().Get("Content-Type"))
+			}
+			assert.Equal(t, tc.want.contentDisposition, recorder.Header().Get("Content-Disposition"))
+			if tc.want.etag != "" {
+				assert.Equal(t, tc.want.etag, recorder.Header().Get("ETag"))
+			}
+			assert.Equal(t, tc.want.digest, recorder.Header().Get("Repr-Digest"))
+			assert.Equal(t, tc.want.metadata, recorder.Header().Get("Secret-Metadata"))
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"go.uber.org/zap"
+)
+
+type KeySource interface {
+	JWKS() auth.JWKS
+}
+
+type JWKSHandler struct {
+	logger *zap.Logger
+}
+
+func NewJWKSHandler(logger *zap.Logger) JWKSHandler {
+	return JWKSHandler{
+		logger: logger,
+	}
+}
+
+// Show responds with public keys tokens are verified with, so other
+// services can verify tokens. Keys are cached for a few minutes, a new
+// key has to be published before it becomes the signing key.
+func (h JWKSHandler) Show(keys KeySource) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(keys.JWKS()); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
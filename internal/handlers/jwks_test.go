@@ -0,0 +1,42 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type keySourceStub auth.JWKS
+
+func (s keySourceStub) JWKS() auth.JWKS {
+	return auth.JWKS(s)
+}
+
+func TestShowJWKS(t *testing.T) {
+	keys := keySourceStub{
+		Keys: []auth.JWK{
+			{KeyType: "OKP", ID: "2024-05", Algorithm: "EdDSA", Use: "sig", Curve: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+		},
+	}
+	handler := handlers.NewJWKSHandler(zaptest.NewLogger(t))
+	request := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+	handler.Show(keys)(w, request)
+
+	result := w.Result()
+	defer result.Body.Close()
+	require.Equal(t, http.StatusOK, result.StatusCode)
+	assert.Equal(t, "application/json", result.Header.Get("Content-Type"))
+	assert.Equal(
+		t,
+		`{"keys":[{"kty":"OKP","kid":"2024-05","alg":"EdDSA","use":"sig","crv":"Ed25519",`+
+			`"x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}`+"\n",
+		w.Body.String(),
+	)
+}
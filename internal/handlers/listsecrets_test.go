@@ -0,0 +1,168 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type listServiceMock struct{ mock.Mock }
+
+func (m *listServiceMock) List(
+	ctx context.Context,
+	userID int,
+	filter models.SecretsFilter) ([]models.SecretInfo, int, error) {
+
+	args := m.Called(ctx, userID, filter)
+	return args.Get(0).([]models.SecretInfo), args.Int(1), args.Error(2)
+}
+
+func TestIndex(t *testing.T) {
+	type listResult struct {
+		secrets    []models.SecretInfo
+		nextCursor int
+		err        error
+	}
+	type want struct {
+		code     int
+		response string
+	}
+	timestamp := time.Date(2024, 4, 21, 9, 30, 0, 0, time.UTC)
+	testCases := []struct {
+		name    string
+		query   string
+		filter  models.SecretsFilter
+		listRes listResult
+		want    want
+	}{
+		{
+			name:   "responds with secrets page",
+			query:  "?cursor=1&limit=1&type=credentials&description=mail",
+			filter: models.SecretsFilter{AfterID: 1, Limit: 1, SecretType: models.CredentialsSecret, Description: "mail"},
+			listRes: listResult{
+				secrets: []models.SecretInfo{
+					{
+						ID:          2,
+						SecretType:  models.CredentialsSecret,
+						Description: "mail",
+						Size:        10,
+						CreatedAt:   timestamp,
+						UpdatedAt:   timestamp,
+					},
+				},
+				nextCursor: 2,
+			},
+			want: want{
+				code: http.StatusOK,
+				response: "{\"secrets\":[{\"id\":2,\"secret_type\":\"credentials\",\"description\":\"mail\",\"size\":10," +
+					"\"created_at\":\"2024-04-21T09:30:00Z\",\"updated_at\":\"2024-04-21T09:30:00Z\"}],\"next_cursor\":2}\n",
+			},
+		},
+		{
+			name: "responds with shared secrets",
+			listRes: listResult{
+				secrets: []models.SecretInfo{
+					{
+						ID:          3,
+						SecretType:  models.TextSecret,
+						Description: "deploy notes",
+						Size:        20,
+						Version:     4,
+						Shared:      models.SecretReadAccess,
+						CreatedAt:   timestamp,
+						UpdatedAt:   timestamp,
+					},
+				},
+			},
+			want: want{
+				code: http.StatusOK,
+				response: "{\"secrets\":[{\"id\":3,\"secret_type\":\"text\",\"description\":\"deploy notes\",\"size\":20," +
+					"\"version\":4,\"shared\":\"read\"," +
+					"\"created_at\":\"2024-04-21T09:30:00Z\",\"updated_at\":\"2024-04-21T09:30:00Z\"}]}\n",
+			},
+		},
+		{
+			name:    "responds with empty list",
+			listRes: listResult{secrets: []models.SecretInfo{}},
+			want: want{
+				code:     http.StatusOK,
+				response: "{\"secrets\":[]}\n",
+			},
+		},
+		{
+			name:    "responds with vault secrets",
+			query:   "?vault_id=2",
+			filter:  models.SecretsFilter{VaultID: 2},
+			listRes: listResult{secrets: []models.SecretInfo{}},
+			want: want{
+				code:     http.StatusOK,
+				response: "{\"secrets\":[]}\n",
+			},
+		},
+		{
+			name:    "responds with forbidden status if user is not organization member",
+			query:   "?vault_id=2",
+			filter:  models.SecretsFilter{VaultID: 2},
+			listRes: listResult{err: services.ErrNoPermission{UserID: 1, VaultID: 2}},
+			want: want{
+				code: http.StatusForbidden,
+			},
+		},
+		{
+			name:  "responds with bad request if type is invalid",
+			query: "?type=unknown",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:    "responds with internal server error",
+			listRes: listResult{err: errors.New("error")},
+			want: want{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	userID := 1
+	jwtStr, err := auth.BuildJWTString(userID, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	listSrv := new(listServiceMock)
+	handler := http.HandlerFunc(
+		handlers.NewSecretHandler(zaptest.NewLogger(t)).
+			Index(listSrv),
+	)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			listCall := listSrv.On("List", mock.Anything, mock.Anything, tc.filter).
+				Return(tc.listRes.secrets, tc.listRes.nextCursor, tc.listRes.err)
+			defer listCall.Unset()
+
+			request, err := http.NewRequest(http.MethodGet, "/api/secrets/index"+tc.query, nil)
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
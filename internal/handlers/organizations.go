@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"go.uber.org/zap"
+)
+
+type OrganizationService interface {
+	Create(ctx context.Context, userID int, name string) (models.Organization, error)
+	List(ctx context.Context, userID int) ([]models.Organization, error)
+	Invite(
+		ctx context.Context,
+		userID int,
+		orgID int,
+		login string,
+		role models.OrganizationRole,
+	) (models.OrganizationMember, error)
+	Accept(ctx context.Context, userID int, orgID int) error
+	Members(ctx context.Context, userID int, orgID int) ([]models.OrganizationMember, error)
+	SetRole(ctx context.Context, userID int, orgID int, memberID int, role models.OrganizationRole) error
+	Remove(ctx context.Context, userID int, orgID int, memberID int) error
+	CreateVault(ctx context.Context, userID int, orgID int, name string) (models.Vault, error)
+	Vaults(ctx context.Context, userID int) ([]models.Vault, error)
+}
+
+type organizationPayload struct {
+	Name string `json:"name"`
+}
+
+// organizationMemberPayload invites the user with the editor role if
+// the role is not set.
+type organizationMemberPayload struct {
+	Login string `json:"login"`
+	Role  string `json:"role"`
+}
+
+type organizationRolePayload struct {
+	Role string `json:"role"`
+}
+
+type vaultPayload struct {
+	Name string `json:"name"`
+}
+
+type organizationResponse struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   int       `json:"owner_id"`
+	Invited   bool      `json:"invited,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type organizationsIndexResponse struct {
+	Organizations []organizationResponse `json:"organizations"`
+}
+
+type organizationMemberResponse struct {
+	UserID    int       `json:"user_id"`
+	Login     string    `json:"login"`
+	Role      string    `json:"role"`
+	Accepted  bool      `json:"accepted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type organizationMembersResponse struct {
+	Members []organizationMemberResponse `json:"members"`
+}
+
+type vaultResponse struct {
+	ID             int       `json:"id"`
+	OrganizationID int       `json:"organization_id"`
+	Name           string    `json:"name"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type vaultsIndexResponse struct {
+	Vaults []vaultResponse `json:"vaults"`
+}
+
+type OrganizationHandler struct {
+	logger *zap.Logger
+}
+
+func NewOrganizationHandler(logger *zap.Logger) OrganizationHandler {
+	return OrganizationHandler{
+		logger: logger,
+	}
+}
+
+func (h OrganizationHandler) Create(srv OrganizationService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		var payload organizationPayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid organization request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		org, err := srv.Create(r.Context(), userID, payload.Name)
+		if err != nil {
+			h.writeError(w, "failed to create organization", err)
+			return
+		}
+
+		h.writeJSON(w, http.StatusCreated, newOrganizationResponse(org))
+	}
+}
+
+// Index responds with organizations the user is a member of or is
+// invited to.
+func (h OrganizationHandler) Index(srv OrganizationService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		orgs, err := srv.List(r.Context(), userID)
+		if err != nil {
+			h.writeError(w, "failed to list organizations", err)
+			return
+		}
+
+		response := organizationsIndexResponse{Organizations: make([]organizationResponse, len(orgs))}
+		for i, org := range orgs {
+			response.Organizations[i] = newOrganizationResponse(org)
+		}
+		h.writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// Invite invites the user with the login from the request body to
+// the organization.
+func (h OrganizationHandler) Invite(srv OrganizationService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		orgID, ok := h.organizationID(w, r)
+		if !ok {
+			return
+		}
+		var payload organizationMemberPayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid organization member request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		role := models.OrganizationEditorRole
+		if payload.Role != "" {
+			var ok bool
+			if role, ok = models.ParseOrganizationRole(payload.Role); !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		member, err := srv.Invite(r.Context(), userID, orgID, payload.Login, role)
+		if err != nil {
+			h.writeError(w, "failed to invite organization member", err)
+			return
+		}
+
+		h.writeJSON(w, http.StatusCreated, newOrganizationMemberResponse(member))
+	}
+}
+
+// Accept accepts the invitation of the user to the organization.
+func (h OrganizationHandler) Accept(srv OrganizationService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		orgID, ok := h.organizationID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := srv.Accept(r.Context(), userID, orgID); err != nil {
+			h.writeError(w, "failed to accept organization invitation", err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// Members responds with members and invited users of the organization.
+func (h OrganizationHandler) Members(srv OrganizationService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		orgID, ok := h.organizationID(w, r)
+		if !ok {
+			return
+		}
+
+		members, err := srv.Members(r.Context(), userID, orgID)
+		if err != nil {
+			h.writeError(w, "failed to list organization members", err)
+			return
+		}
+
+		response := organizationMembersResponse{Members: make([]organizationMemberResponse, len(members))}
+		for i, member := range members {
+			response.Members[i] = newOrganizationMemberResponse(member)
+		}
+		h.writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// SetRole changes the role of the user from the userID URL parameter in
+// the organization.
+func (h OrganizationHandler) SetRole(srv OrganizationService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		orgID, ok := h.organizationID(w, r)
+		if !ok {
+			return
+		}
+		memberID, ok := h.memberID(w, r)
+		if !ok {
+			return
+		}
+		var payload organizationRolePayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid organization role request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		role, ok := models.ParseOrganizationRole(payload.Role)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := srv.SetRole(r.Context(), userID, orgID, memberID, role); err != nil {
+			h.writeError(w, "failed to change organization member role", err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// RemoveMember removes the user from the userID URL parameter from
+// the organization.
+func (h OrganizationHandler) RemoveMember(srv OrganizationService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		orgID, ok := h.organizationID(w, r)
+		if !ok {
+			return
+		}
+		memberID, ok := h.memberID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := srv.Remove(r.Context(), userID, orgID, memberID); err != nil {
+			h.writeError(w, "failed to remove organization member", err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (h OrganizationHandler) CreateVault(srv OrganizationService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		orgID, ok := h.organizationID(w, r)
+		if !ok {
+			return
+		}
+		var payload vaultPayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid vault request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		vault, err := srv.CreateVault(r.Context(), userID, orgID, payload.Name)
+		if err != nil {
+			h.writeError(w, "failed to create vault", err)
+			return
+		}
+
+		h.writeJSON(w, http.StatusCreated, newVaultResponse(vault))
+	}
+}
+
+// Vaults responds with vaults of all organizations the user is a member of.
+func (h OrganizationHandler) Vaults(srv OrganizationService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		vaults, err := srv.Vaults(r.Context(), userID)
+		if err != nil {
+			h.writeError(w, "failed to list vaults", err)
+			return
+		}
+
+		response := vaultsIndexResponse{Vaults: make([]vaultResponse, len(vaults))}
+		for i, vault := range vaults {
+			response.Vaults[i] = newVaultResponse(vault)
+		}
+		h.writeJSON(w, http.StatusOK, response)
+	}
+}
+
+func (h OrganizationHandler) organizationID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	orgID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Info("invalid organization id", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return 0, false
+	}
+
+	return orgID, true
+}
+
+func (h OrganizationHandler) memberID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	memberID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		h.logger.Info("invalid user id", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return 0, false
+	}
+
+	return memberID, true
+}
+
+func (h OrganizationHandler) writeError(w http.ResponseWriter, msg string, err error) {
+	var permErr services.ErrNoPermission
+	var orgNotFoundErr storage.ErrOrganizationNotFound
+	var memberNotFoundErr storage.ErrOrganizationMemberNotFound
+	var userNotFoundErr storage.ErrUserNotFound
+	var memberNotUniqErr storage.ErrOrganizationMemberNotUniq
+	var vaultNotUniqErr storage.ErrVaultNotUniq
+	switch {
+	case errors.As(err, &permErr):
+		w.WriteHeader(http.StatusForbidden)
+	case errors.As(err, &orgNotFoundErr), errors.As(err, &memberNotFoundErr):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidOrganizationName),
+		errors.Is(err, services.ErrInvalidVaultName),
+		errors.Is(err, services.ErrInvalidOrganizationRole):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.As(err, &memberNotUniqErr), errors.As(err, &vaultNotUniqErr):
+		w.WriteHeader(http.StatusConflict)
+	case errors.As(err, &userNotFoundErr),
+		errors.Is(err, services.ErrRemoveOrganizationOwner),
+		errors.Is(err, services.ErrChangeOrganizationOwnerRole):
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	default:
+		h.logger.Info(msg, zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (h OrganizationHandler) writeJSON(w http.ResponseWriter, status int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Info("failed to encode response", zap.Error(err))
+	}
+}
+
+func newOrganizationResponse(org models.Organization) organizationResponse {
+	return organizationResponse{
+		ID:        org.ID,
+		Name:      org.Name,
+		OwnerID:   org.OwnerID,
+		Invited:   org.Invited,
+		CreatedAt: org.CreatedAt,
+	}
+}
+
+func newOrganizationMemberResponse(member models.OrganizationMember) organizationMemberResponse {
+	return organizationMemberResponse{
+		UserID:    member.UserID,
+		Login:     member.Login,
+		Role:      member.Role.String(),
+		Accepted:  member.Accepted,
+		CreatedAt: member.CreatedAt,
+	}
+}
+
+func newVaultResponse(vault models.Vault) vaultResponse {
+	return vaultResponse{
+		ID:             vault.ID,
+		OrganizationID: vault.OrganizationID,
+		Name:           vault.Name,
+		CreatedAt:      vault.CreatedAt,
+	}
+}
@@ -0,0 +1,364 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type organizationServiceMock struct{ mock.Mock }
+
+func (m *organizationServiceMock) Create(ctx context.Context, userID int, name string) (models.Organization, error) {
+	args := m.Called(ctx, userID, name)
+	return args.Get(0).(models.Organization), args.Error(1)
+}
+
+func (m *organizationServiceMock) List(ctx context.Context, userID int) ([]models.Organization, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Organization), args.Error(1)
+}
+
+func (m *organizationServiceMock) Invite(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	login string,
+	role models.OrganizationRole) (models.OrganizationMember, error) {
+
+	args := m.Called(ctx, userID, orgID, login, role)
+	return args.Get(0).(models.OrganizationMember), args.Error(1)
+}
+
+func (m *organizationServiceMock) Accept(ctx context.Context, userID int, orgID int) error {
+	args := m.Called(ctx, userID, orgID)
+	return args.Error(0)
+}
+
+func (m *organizationServiceMock) Members(
+	ctx context.Context,
+	userID int,
+	orgID int) ([]models.OrganizationMember, error) {
+
+	args := m.Called(ctx, userID, orgID)
+	return args.Get(0).([]models.OrganizationMember), args.Error(1)
+}
+
+func (m *organizationServiceMock) SetRole(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	memberID int,
+	role models.OrganizationRole) error {
+
+	args := m.Called(ctx, userID, orgID, memberID, role)
+	return args.Error(0)
+}
+
+func (m *organizationServiceMock) Remove(ctx context.Context, userID int, orgID int, memberID int) error {
+	args := m.Called(ctx, userID, orgID, memberID)
+	return args.Error(0)
+}
+
+func (m *organizationServiceMock) CreateVault(ctx context.Context, userID int, orgID int, name string) (models.Vault, error) {
+	args := m.Called(ctx, userID, orgID, name)
+	return args.Get(0).(models.Vault), args.Error(1)
+}
+
+func (m *organizationServiceMock) Vaults(ctx context.Context, userID int) ([]models.Vault, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Vault), args.Error(1)
+}
+
+func TestCreateOrganization(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+	}
+	createdAt := time.Date(2024, 5, 16, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name      string
+		org       models.Organization
+		createErr error
+		want      want
+	}{
+		{
+			name: "responds with created status",
+			org:  models.Organization{ID: 1, Name: "team", OwnerID: 1, CreatedAt: createdAt},
+			want: want{
+				code:     http.StatusCreated,
+				response: "{\"id\":1,\"name\":\"team\",\"owner_id\":1,\"created_at\":\"2024-05-16T10:00:00Z\"}\n",
+			},
+		},
+		{
+			name:      "responds with bad request if name is invalid",
+			createErr: services.ErrInvalidOrganizationName,
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:      "responds with internal server error",
+			createErr: errors.New("error"),
+			want: want{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			orgSrv := new(organizationServiceMock)
+			orgSrv.On("Create", mock.Anything, mock.Anything, "team").Return(tc.org, tc.createErr)
+			handler := http.HandlerFunc(handlers.NewOrganizationHandler(zaptest.NewLogger(t)).Create(orgSrv))
+
+			request, err := http.NewRequest(http.MethodPost, "/api/organizations", strings.NewReader(`{"name":"team"}`))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
+
+func TestInviteOrganizationMember(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+	}
+	createdAt := time.Date(2024, 5, 16, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name      string
+		member    models.OrganizationMember
+		inviteErr error
+		want      want
+	}{
+		{
+			name: "responds with created status",
+			member: models.OrganizationMember{
+				OrganizationID: 1,
+				UserID:         2,
+				Login:          "bob",
+				Role:           models.OrganizationViewerRole,
+				CreatedAt:      createdAt,
+			},
+			want: want{
+				code: http.StatusCreated,
+				response: "{\"user_id\":2,\"login\":\"bob\",\"role\":\"viewer\",\"accepted\":false," +
+					"\"created_at\":\"2024-05-16T10:00:00Z\"}\n",
+			},
+		},
+		{
+			name:      "responds with forbidden status if user is not admin",
+			inviteErr: services.ErrNoPermission{UserID: 2, OrganizationID: 1},
+			want: want{
+				code: http.StatusForbidden,
+			},
+		},
+		{
+			name:      "responds with not found if organization does not exist",
+			inviteErr: storage.ErrOrganizationNotFound{Organization: models.Organization{ID: 1}},
+			want: want{
+				code: http.StatusNotFound,
+			},
+		},
+		{
+			name:      "responds with unprocessable entity if user does not exist",
+			inviteErr: storage.ErrUserNotFound{User: models.User{Login: "bob"}},
+			want: want{
+				code: http.StatusUnprocessableEntity,
+			},
+		},
+		{
+			name: "responds with conflict if user is already member",
+			inviteErr: storage.ErrOrganizationMemberNotUniq{
+				Member: models.OrganizationMember{OrganizationID: 1, Login: "bob"},
+			},
+			want: want{
+				code: http.StatusConflict,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			orgSrv := new(organizationServiceMock)
+			orgSrv.On("Invite", mock.Anything, mock.Anything, 1, "bob", models.OrganizationViewerRole).
+				Return(tc.member, tc.inviteErr)
+			handler := http.HandlerFunc(handlers.NewOrganizationHandler(zaptest.NewLogger(t)).Invite(orgSrv))
+
+			request, err := http.NewRequest(
+				http.MethodPost,
+				"/api/organizations/1/members",
+				strings.NewReader(`{"login":"bob","role":"viewer"}`),
+			)
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/json")
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
+
+func TestSetOrganizationMemberRole(t *testing.T) {
+	testCases := []struct {
+		name       string
+		reqBody    string
+		setRoleErr error
+		wantCode   int
+	}{
+		{
+			name:     "responds with ok status",
+			reqBody:  `{"role":"admin"}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "responds with bad request if role is invalid",
+			reqBody:  `{"role":"owner"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:       "responds with forbidden status if user is not admin",
+			reqBody:    `{"role":"admin"}`,
+			setRoleErr: services.ErrNoPermission{UserID: 3, OrganizationID: 1},
+			wantCode:   http.StatusForbidden,
+		},
+		{
+			name:       "responds with unprocessable entity if role of owner is changed",
+			reqBody:    `{"role":"admin"}`,
+			setRoleErr: services.ErrChangeOrganizationOwnerRole,
+			wantCode:   http.StatusUnprocessableEntity,
+		},
+		{
+			name:    "responds with not found if user is not member",
+			reqBody: `{"role":"admin"}`,
+			setRoleErr: storage.ErrOrganizationMemberNotFound{
+				Member: models.OrganizationMember{OrganizationID: 1, UserID: 2},
+			},
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			orgSrv := new(organizationServiceMock)
+			orgSrv.On("SetRole", mock.Anything, mock.Anything, 1, 2, models.OrganizationAdminRole).Return(tc.setRoleErr)
+			handler := http.HandlerFunc(handlers.NewOrganizationHandler(zaptest.NewLogger(t)).SetRole(orgSrv))
+
+			request, err := http.NewRequest(http.MethodPut, "/api/organizations/1/members/2", strings.NewReader(tc.reqBody))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/json")
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			rctx.URLParams.Add("userID", "2")
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.wantCode, recorder.Result().StatusCode)
+		})
+	}
+}
+
+func TestRemoveOrganizationMember(t *testing.T) {
+	testCases := []struct {
+		name      string
+		memberID  string
+		removeErr error
+		wantCode  int
+	}{
+		{
+			name:     "responds with ok status",
+			memberID: "2",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "responds with bad request if user id is invalid",
+			memberID: "bob",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:      "responds with forbidden status if user can not remove member",
+			memberID:  "2",
+			removeErr: services.ErrNoPermission{UserID: 3, OrganizationID: 1},
+			wantCode:  http.StatusForbidden,
+		},
+		{
+			name:      "responds with unprocessable entity if owner is removed",
+			memberID:  "2",
+			removeErr: services.ErrRemoveOrganizationOwner,
+			wantCode:  http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "responds with not found if user is not member",
+			memberID: "2",
+			removeErr: storage.ErrOrganizationMemberNotFound{
+				Member: models.OrganizationMember{OrganizationID: 1, UserID: 2},
+			},
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			orgSrv := new(organizationServiceMock)
+			orgSrv.On("Remove", mock.Anything, mock.Anything, 1, 2).Return(tc.removeErr)
+			handler := http.HandlerFunc(handlers.NewOrganizationHandler(zaptest.NewLogger(t)).RemoveMember(orgSrv))
+
+			request, err := http.NewRequest(http.MethodDelete, "/api/organizations/1/members/"+tc.memberID, nil)
+			require.NoError(t, err)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			rctx.URLParams.Add("userID", tc.memberID)
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.wantCode, recorder.Result().StatusCode)
+		})
+	}
+}
+
+func TestVaultsIndex(t *testing.T) {
+	createdAt := time.Date(2024, 5, 16, 10, 0, 0, 0, time.UTC)
+	orgSrv := new(organizationServiceMock)
+	orgSrv.On("Vaults", mock.Anything, mock.Anything).Return(
+		[]models.Vault{{ID: 3, OrganizationID: 1, Name: "servers", CreatedAt: createdAt}},
+		nil,
+	)
+	handler := http.HandlerFunc(handlers.NewOrganizationHandler(zaptest.NewLogger(t)).Vaults(orgSrv))
+
+	request, err := http.NewRequest(http.MethodGet, "/api/vaults", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	assert.Equal(
+		t,
+		"{\"vaults\":[{\"id\":3,\"organization_id\":1,\"name\":\"servers\",\"created_at\":\"2024-05-16T10:00:00Z\"}]}\n",
+		recorder.Body.String(),
+	)
+}
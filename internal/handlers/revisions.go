@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"go.uber.org/zap"
+)
+
+type RevisionService interface {
+	List(ctx context.Context, userID int, secret models.Secret) ([]models.SecretRevision, error)
+	Show(
+		ctx context.Context,
+		userID int,
+		secret models.Secret,
+		version int,
+	) (services.Unmarshaller, models.SecretRevision, error)
+	Restore(ctx context.Context, userID int, secret models.Secret, version int) error
+}
+
+type RevisionContentService interface {
+	OpenRevisionContent(
+		ctx context.Context,
+		revision models.SecretRevision,
+		binData *models.BinData,
+	) (io.ReadSeeker, error)
+}
+
+func (h SecretHandler) Revisions(
+	findSrv FindSecretService,
+	revisionSrv RevisionService) func(http.ResponseWriter, *http.Request) {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		secret, ok := h.findSecret(w, r, findSrv)
+		if !ok {
+			return
+		}
+		if !allowedByScope(w, r, secret.ID, secret.SecretType, false) {
+			return
+		}
+		revisions, err := revisionSrv.List(r.Context(), userID, secret)
+		if err != nil {
+			h.writeRevisionError(w, err, "failed to list secret revisions")
+			return
+		}
+
+		response := secretRevisionsResponse{
+			Revisions: make([]secretRevisionResponse, len(revisions)),
+		}
+		for i, revision := range revisions {
+			response.Revisions[i] = secretRevisionResponse{
+				Version:     revision.Version,
+				Description: revision.Description,
+				Size:        revision.Size,
+				CreatedAt:   revision.CreatedAt,
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// GetRevision responds with the secret decrypted as it was at the revision
+// in the same format as Get.
+func (h SecretHandler) GetRevision(
+	findSrv FindSecretService,
+	revisionSrv RevisionService,
+	contentSrv RevisionContentService) func(http.ResponseWriter, *http.Request) {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		version, err := strconv.Atoi(chi.URLParam(r, "version"))
+		if err != nil {
+			h.logger.Info("invalid revision version", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		secret, ok := h.findSecret(w, r, findSrv)
+		if !ok {
+			return
+		}
+		if !allowedByScope(w, r, secret.ID, secret.SecretType, false) {
+			return
+		}
+		decryptedSecret, revision, err := revisionSrv.Show(r.Context(), userID, secret, version)
+		if err != nil {
+			h.writeRevisionError(w, err, "failed to get secret revision")
+			return
+		}
+
+		if binData, ok := decryptedSecret.(*models.BinData); ok {
+			content, err := contentSrv.OpenRevisionContent(r.Context(), revision, binData)
+			if err != nil {
+				h.logger.Info("failed to open bin data", zap.Error(err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			h.serveBinData(w, r, secret.ID, binData, content)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(decryptedSecret); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// RestoreRevision makes the revision the current secret version.
+func (h SecretHandler) RestoreRevision(
+	findSrv FindSecretService,
+	revisionSrv RevisionService) func(http.ResponseWriter, *http.Request) {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		version, err := strconv.Atoi(chi.URLParam(r, "version"))
+		if err != nil {
+			h.logger.Info("invalid revision version", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		secret, ok := h.findSecret(w, r, findSrv)
+		if !ok {
+			return
+		}
+		if !allowedByScope(w, r, secret.ID, secret.SecretType, true) {
+			return
+		}
+		if err := revisionSrv.Restore(r.Context(), userID, secret, version); err != nil {
+			h.writeRevisionError(w, err, "failed to restore secret revision")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// findSecret finds the secret from the URL, it writes the error response
+// and returns false if the secret can not be found.
+func (h SecretHandler) findSecret(
+	w http.ResponseWriter,
+	r *http.Request,
+	findSrv FindSecretService) (models.Secret, bool) {
+
+	secretID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Info("invalid secret id", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return models.Secret{}, false
+	}
+	secret, err := findSrv.Find(r.Context(), secretID)
+	if err != nil {
+		var notFoundErr storage.ErrSecretNotFound
+		if errors.As(err, &notFoundErr) {
+			w.WriteHeader(http.StatusNotFound)
+			return secret, false
+		}
+		h.logger.Info("failed to find secret", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return secret, false
+	}
+
+	return secret, true
+}
+
+func (h SecretHandler) writeRevisionError(w http.ResponseWriter, err error, msg string) {
+	var permErr services.ErrNoPermission
+	if errors.As(err, &permErr) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	var notFoundErr storage.ErrRevisionNotFound
+	if errors.As(err, &notFoundErr) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	h.logger.Info(msg, zap.Error(err))
+	w.WriteHeader(http.StatusInternalServerError)
+}
@@ -0,0 +1,297 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type revisionServiceMock struct{ mock.Mock }
+
+func (m *revisionServiceMock) List(ctx context.Context, userID int, secret models.Secret) ([]models.SecretRevision, error) {
+	args := m.Called(ctx, userID, secret)
+	return args.Get(0).([]models.SecretRevision), args.Error(1)
+}
+
+func (m *revisionServiceMock) Show(
+	ctx context.Context,
+	userID int,
+	secret models.Secret,
+	version int) (services.Unmarshaller, models.SecretRevision, error) {
+
+	args := m.Called(ctx, userID, secret, version)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(models.SecretRevision), args.Error(2)
+	}
+	return args.Get(0).(services.Unmarshaller), args.Get(1).(models.SecretRevision), args.Error(2)
+}
+
+func (m *revisionServiceMock) Restore(ctx context.Context, userID int, secret models.Secret, version int) error {
+	args := m.Called(ctx, userID, secret, version)
+	return args.Error(0)
+}
+
+func TestSecretRevisions(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+	}
+	type listResult struct {
+		revisions []models.SecretRevision
+		err       error
+	}
+	secret := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret}
+	createdAt := time.Date(2024, 5, 5, 11, 42, 10, 0, time.UTC)
+	testCases := []struct {
+		name    string
+		listRes listResult
+		want    want
+	}{
+		{
+			name: "responds with secret revisions",
+			listRes: listResult{
+				revisions: []models.SecretRevision{
+					{ID: 3, SecretID: 1, Version: 2, Description: "new", Size: 48, CreatedAt: createdAt},
+					{ID: 1, SecretID: 1, Version: 1, Description: "old", Size: 40, CreatedAt: createdAt},
+				},
+			},
+			want: want{
+				code: http.StatusOK,
+				response: `{"revisions":[` +
+					`{"version":2,"description":"new","size":48,"created_at":"2024-05-05T11:42:10Z"},` +
+					`{"version":1,"description":"old","size":40,"created_at":"2024-05-05T11:42:10Z"}]}` + "\n",
+			},
+		},
+		{
+			name: "responds with forbidden status",
+			listRes: listResult{
+				err: services.ErrNoPermission{UserID: 2, SecretID: 1},
+			},
+			want: want{
+				code: http.StatusForbidden,
+			},
+		},
+	}
+
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	findSrv := new(findSecretServiceMock)
+	findSrv.On("Find", mock.Anything, 1).Return(secret, nil)
+	revisionSrv := new(revisionServiceMock)
+	handler := http.HandlerFunc(handlers.NewSecretHandler(zaptest.NewLogger(t)).Revisions(findSrv, revisionSrv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			listCall := revisionSrv.On("List", mock.Anything, mock.Anything, secret).
+				Return(tc.listRes.revisions, tc.listRes.err)
+			defer listCall.Unset()
+
+			request, err := http.NewRequest(http.MethodGet, "/api/secrets/1/revisions", nil)
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
+
+func TestGetSecretRevision(t *testing.T) {
+	type want struct {
+		code               int
+		contentType        string
+		contentDisposition string
+		response           string
+	}
+	type showResult struct {
+		secret   services.Unmarshaller
+		revision models.SecretRevision
+		err      error
+	}
+	secret := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret}
+	testCases := []struct {
+		name    string
+		version string
+		showRes showResult
+		content []byte
+		want    want
+	}{
+		{
+			name:    "responds with secret revision",
+			version: "2",
+			showRes: showResult{
+				secret: &models.Credentials{
+					ID:          1,
+					Description: "description",
+					Login:       "login",
+					Password:    "old password",
+				},
+				revision: models.SecretRevision{ID: 5, SecretID: 1, Version: 2},
+			},
+			want: want{
+				code:        http.StatusOK,
+				contentType: "application/json",
+				response:    "{\"ID\":1,\"Description\":\"description\",\"Login\":\"login\",\"Password\":\"old password\"}\n",
+			},
+		},
+		{
+			name:    "responds with bin data revision content",
+			version: "2",
+			showRes: showResult{
+				secret:   &models.BinData{ID: 1, Filename: "file.txt", Size: 4},
+				revision: models.SecretRevision{ID: 5, SecretID: 1, Version: 2},
+			},
+			content: []byte("text"),
+			want: want{
+				code:               http.StatusOK,
+				contentType:        "application/octet-stream",
+				contentDisposition: "attachment; filename=file.txt",
+				response:           "text",
+			},
+		},
+		{
+			name:    "responds with not found status",
+			version: "3",
+			showRes: showResult{
+				err: storage.ErrRevisionNotFound{Revision: models.SecretRevision{SecretID: 1, Version: 3}},
+			},
+			want: want{
+				code: http.StatusNotFound,
+			},
+		},
+		{
+			name:    "responds with bad request if version is invalid",
+			version: "latest",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:    "responds with internal server error",
+			version: "2",
+			showRes: showResult{
+				err: errors.New("error"),
+			},
+			want: want{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	findSrv := new(findSecretServiceMock)
+	findSrv.On("Find", mock.Anything, 1).Return(secret, nil)
+	revisionSrv := new(revisionServiceMock)
+	binDataSrv := new(binDataServiceMock)
+	handler := http.HandlerFunc(
+		handlers.NewSecretHandler(zaptest.NewLogger(t)).GetRevision(findSrv, revisionSrv, binDataSrv),
+	)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			showCall := revisionSrv.On("Show", mock.Anything, mock.Anything, secret, mock.Anything).
+				Return(tc.showRes.secret, tc.showRes.revision, tc.showRes.err)
+			defer showCall.Unset()
+			openCall := binDataSrv.On("OpenRevisionContent", mock.Anything, tc.showRes.revision, mock.Anything).
+				Return(tc.content, nil)
+			defer openCall.Unset()
+
+			request, err := http.NewRequest(http.MethodGet, "/api/secrets/1/revisions/"+tc.version, nil)
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			rctx.URLParams.Add("version", tc.version)
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			result := recorder.Result()
+			defer result.Body.Close()
+			assert.Equal(t, tc.want.code, result.StatusCode)
+			assert.Equal(t, tc.want.contentType, result.Header.Get("Content-Type"))
+			assert.Equal(t, tc.want.contentDisposition, result.Header.Get("Content-Disposition"))
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
+
+func TestRestoreSecretRevision(t *testing.T) {
+	secret := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret}
+	testCases := []struct {
+		name       string
+		restoreErr error
+		wantCode   int
+	}{
+		{
+			name:     "responds with ok status",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:       "responds with not found status",
+			restoreErr: storage.ErrRevisionNotFound{Revision: models.SecretRevision{SecretID: 1, Version: 2}},
+			wantCode:   http.StatusNotFound,
+		},
+		{
+			name:       "responds with forbidden status",
+			restoreErr: services.ErrNoPermission{UserID: 2, SecretID: 1},
+			wantCode:   http.StatusForbidden,
+		},
+	}
+
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	findSrv := new(findSecretServiceMock)
+	findSrv.On("Find", mock.Anything, 1).Return(secret, nil)
+	revisionSrv := new(revisionServiceMock)
+	handler := http.HandlerFunc(handlers.NewSecretHandler(zaptest.NewLogger(t)).RestoreRevision(findSrv, revisionSrv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			restoreCall := revisionSrv.On("Restore", mock.Anything, mock.Anything, secret, 2).
+				Return(tc.restoreErr)
+			defer restoreCall.Unset()
+
+			request, err := http.NewRequest(http.MethodPost, "/api/secrets/1/revisions/2/restore", nil)
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			rctx.URLParams.Add("version", "2")
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.wantCode, recorder.Result().StatusCode)
+		})
+	}
+}
@@ -0,0 +1,436 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+)
+
+const (
+	// maxJSONBodySize limits JSON requests, large bin data should be
+	// uploaded as multipart form.
+	maxJSONBodySize  = 64 << 20
+	maxFormValueSize = 1 << 20
+)
+
+var errInvalidSecretType = errors.New("invalid secret type")
+
+type secretInput struct {
+	secretType  models.SecretType
+	description string
+	secret      services.Marshaller
+	// metadata is nil if it is not set in the request
+	metadata models.Metadata
+	// filename and content are set for bin data only
+	filename string
+	content  io.Reader
+}
+
+type secretPayload struct {
+	SecretType  string          `json:"secret_type"`
+	Description string          `json:"description"`
+	Data        json.RawMessage `json:"data"`
+	Metadata    models.Metadata `json:"metadata"`
+}
+
+type credentialsPayload struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+type creditCardPayload struct {
+	Number     string    `json:"number"`
+	Name       string    `json:"name"`
+	ExpiryDate time.Time `json:"expiry_date"`
+	CVV2       string    `json:"cvv2"`
+}
+
+type textPayload struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type binDataPayload struct {
+	Filename string `json:"filename"`
+	Content  []byte `json:"content"`
+}
+
+type secretMetadataPayload struct {
+	Metadata models.Metadata `json:"metadata"`
+}
+
+type secretFolderPayload struct {
+	FolderID int `json:"folder_id"`
+}
+
+type secretTagsPayload struct {
+	TagIDs []int `json:"tag_ids"`
+}
+
+type secretResponse struct {
+	ID          int    `json:"id"`
+	SecretType  string `json:"secret_type"`
+	Description string `json:"description"`
+}
+
+type secretInfoResponse struct {
+	ID          int       `json:"id"`
+	SecretType  string    `json:"secret_type"`
+	Description string    `json:"description"`
+	Size        int       `json:"size"`
+	FolderID    int       `json:"folder_id,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Version     int       `json:"version,omitempty"`
+	Shared      string    `json:"shared,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type secretsIndexResponse struct {
+	Secrets    []secretInfoResponse `json:"secrets"`
+	NextCursor int                  `json:"next_cursor,omitempty"`
+}
+
+func parseSecretsFilter(r *http.Request) (models.SecretsFilter, error) {
+	query := r.URL.Query()
+	filter := models.SecretsFilter{
+		Description: query.Get("description"),
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		afterID, err := strconv.Atoi(cursor)
+		if err != nil {
+			return filter, fmt.Errorf("invalid cursor: %w", err)
+		}
+		filter.AfterID = afterID
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return filter, fmt.Errorf("invalid limit %q", limitStr)
+		}
+		filter.Limit = limit
+	}
+	if typeName := query.Get("type"); typeName != "" {
+		secretType, ok := models.ParseSecretType(typeName)
+		if !ok {
+			return filter, errInvalidSecretType
+		}
+		filter.SecretType = secretType
+	}
+	if folderIDStr := query.Get("folder_id"); folderIDStr != "" {
+		folderID, err := strconv.Atoi(folderIDStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid folder id: %w", err)
+		}
+		filter.FolderID = folderID
+	}
+	if tagIDStr := query.Get("tag_id"); tagIDStr != "" {
+		tagID, err := strconv.Atoi(tagIDStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid tag id: %w", err)
+		}
+		filter.TagID = tagID
+	}
+	vaultID, err := parseVaultID(r)
+	if err != nil {
+		return filter, err
+	}
+	filter.VaultID = vaultID
+
+	return filter, nil
+}
+
+// parseVaultID returns the vault the request targets, zero if the request
+// targets personal secrets of the user.
+func parseVaultID(r *http.Request) (int, error) {
+	vaultIDStr := r.URL.Query().Get("vault_id")
+	if vaultIDStr == "" {
+		return 0, nil
+	}
+	vaultID, err := strconv.Atoi(vaultIDStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid vault id: %w", err)
+	}
+
+	return vaultID, nil
+}
+
+func parseSecretRequest(r *http.Request) (secretInput, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		return parseSecretJSON(r)
+	case "multipart/form-data":
+		return parseSecretMultipart(r)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return secretInput{}, fmt.Errorf("failed to parse form: %w", err)
+	}
+
+	return secretInputFromForm(r.PostForm, "", nil)
+}
+
+func parseSecretJSON(r *http.Request) (secretInput, error) {
+	var payload secretPayload
+	body := io.LimitReader(r.Body, maxJSONBodySize)
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
+		return secretInput{}, fmt.Errorf("failed to decode request body: %w", err)
+	}
+	secretType, ok := models.ParseSecretType(payload.SecretType)
+	if !ok {
+		return secretInput{}, errInvalidSecretType
+	}
+
+	if err := validateMetadata(payload.Metadata); err != nil {
+		return secretInput{}, err
+	}
+
+	input := secretInput{
+		secretType:  secretType,
+		description: payload.Description,
+		metadata:    payload.Metadata,
+	}
+	switch secretType {
+	case models.CredentialsSecret:
+		var data credentialsPayload
+		if err := json.Unmarshal(payload.Data, &data); err != nil {
+			return input, fmt.Errorf("failed to decode credentials: %w", err)
+		}
+		input.secret = &models.Credentials{
+			Login:    data.Login,
+			Password: data.Password,
+		}
+	case models.CreditCardSecret:
+		var data creditCardPayload
+		if err := json.Unmarshal(payload.Data, &data); err != nil {
+			return input, fmt.Errorf("failed to decode credit card: %w", err)
+		}
+		input.secret = &models.CreditCard{
+			Number:     data.Number,
+			Name:       data.Name,
+			ExpiryDate: data.ExpiryDate,
+			CVV2:       data.CVV2,
+		}
+	case models.TextSecret:
+		var data textPayload
+		if err := json.Unmarshal(payload.Data, &data); err != nil {
+			return input, fmt.Errorf("failed to decode text: %w", err)
+		}
+		input.secret = &models.Text{
+			Title: data.Title,
+			Body:  data.Body,
+		}
+	case models.BinDataSecret:
+		var data binDataPayload
+		if err := json.Unmarshal(payload.Data, &data); err != nil {
+			return input, fmt.Errorf("failed to decode bin data: %w", err)
+		}
+		input.filename = services.BaseFilename(data.Filename)
+		input.content = bytes.NewReader(data.Content)
+	}
+
+	return input, nil
+}
+
+// parseSecretMultipart reads form fields up to the "file" part. The file
+// part is not buffered, its content is read by the service directly from
+// the request body, so the fields have to be sent before the file.
+func parseSecretMultipart(r *http.Request) (secretInput, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return secretInput{}, fmt.Errorf("failed to parse form: %w", err)
+	}
+
+	values := url.Values{}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return secretInputFromForm(values, "", nil)
+		}
+		if err != nil {
+			return secretInput{}, fmt.Errorf("failed to parse form: %w", err)
+		}
+		if part.FormName() == "file" {
+			return secretInputFromForm(values, part.FileName(), part)
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFormValueSize+1))
+		if err != nil {
+			return secretInput{}, fmt.Errorf("failed to read form value: %w", err)
+		}
+		if len(value) > maxFormValueSize {
+			return secretInput{}, fmt.Errorf("too large form value %q", part.FormName())
+		}
+		values.Add(part.FormName(), string(value))
+	}
+}
+
+func secretInputFromForm(values url.Values, filename string, file io.Reader) (secretInput, error) {
+	secretType, ok := models.ParseSecretType(values.Get("secret_type"))
+	if !ok {
+		return secretInput{}, errInvalidSecretType
+	}
+
+	input := secretInput{
+		secretType:  secretType,
+		description: values.Get("description"),
+	}
+	if pairs, ok := values["meta"]; ok {
+		metadata, err := parseMetadataPairs(pairs)
+		if err != nil {
+			return input, err
+		}
+		input.metadata = metadata
+	}
+	switch secretType {
+	case models.CredentialsSecret:
+		input.secret = &models.Credentials{
+			Login:    values.Get("login"),
+			Password: values.Get("password"),
+		}
+	case models.CreditCardSecret:
+		expiryDateStr := values.Get("credit_card_expiry_date")
+		expDate, err := time.Parse(time.RFC3339, expiryDateStr)
+		if err != nil {
+			return input, fmt.Errorf("failed to parse date %q: %w", expiryDateStr, err)
+		}
+		input.secret = &models.CreditCard{
+			Number:     values.Get("credit_card_number"),
+			Name:       values.Get("credit_card_name"),
+			ExpiryDate: expDate,
+			CVV2:       values.Get("credit_card_cvv2"),
+		}
+	case models.TextSecret:
+		input.secret = &models.Text{
+			Title: values.Get("title"),
+			Body:  values.Get("body"),
+		}
+	case models.BinDataSecret:
+		if file == nil {
+			return input, errors.New("failed to get file: missing file")
+		}
+		input.filename = services.BaseFilename(filename)
+		input.content = file
+	}
+
+	return input, nil
+}
+
+// parseMetadataPairs parses metadata from key=value pairs.
+func parseMetadataPairs(pairs []string) (models.Metadata, error) {
+	metadata := make(models.Metadata, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid metadata %q, key=value expected", pair)
+		}
+		metadata[key] = value
+	}
+
+	return metadata, validateMetadata(metadata)
+}
+
+func validateMetadata(metadata models.Metadata) error {
+	for key := range metadata {
+		if key == "" {
+			return errors.New("empty metadata key")
+		}
+	}
+
+	return nil
+}
+
+type secretRevisionResponse struct {
+	Version     int       `json:"version"`
+	Description string    `json:"description"`
+	Size        int       `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type secretRevisionsResponse struct {
+	Revisions []secretRevisionResponse `json:"revisions"`
+}
+
+type trashedSecretResponse struct {
+	secretInfoResponse
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type trashIndexResponse struct {
+	Secrets []trashedSecretResponse `json:"secrets"`
+}
+
+type syncedSecretResponse struct {
+	ID          int                   `json:"id"`
+	SecretType  string                `json:"secret_type"`
+	Description string                `json:"description"`
+	FolderID    int                   `json:"folder_id,omitempty"`
+	Revision    int                   `json:"revision"`
+	Data        services.Unmarshaller `json:"data"`
+}
+
+type syncResponse struct {
+	Revision int                    `json:"revision"`
+	HasMore  bool                   `json:"has_more"`
+	Created  []syncedSecretResponse `json:"created"`
+	Updated  []syncedSecretResponse `json:"updated"`
+	Deleted  []int                  `json:"deleted"`
+}
+
+type secretEventResponse struct {
+	ID       int `json:"id"`
+	Revision int `json:"revision,omitempty"`
+}
+
+func newSyncedSecretsResponse(secrets []services.SyncedSecret) []syncedSecretResponse {
+	response := make([]syncedSecretResponse, len(secrets))
+	for i, secret := range secrets {
+		response[i] = syncedSecretResponse{
+			ID:          secret.Secret.ID,
+			SecretType:  secret.Secret.SecretType.String(),
+			Description: secret.Secret.Description,
+			FolderID:    secret.Secret.FolderID,
+			Revision:    secret.Revision,
+			Data:        secret.Data,
+		}
+	}
+
+	return response
+}
+
+type secretSharePayload struct {
+	Login  string `json:"login"`
+	Access string `json:"access"`
+}
+
+type secretShareResponse struct {
+	UserID    int       `json:"user_id"`
+	Login     string    `json:"login"`
+	Access    string    `json:"access"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type secretSharesResponse struct {
+	Shares []secretShareResponse `json:"shares"`
+}
+
+func newSecretShareResponse(share models.SecretShare) secretShareResponse {
+	return secretShareResponse{
+		UserID:    share.UserID,
+		Login:     share.Login,
+		Access:    share.Access.String(),
+		CreatedAt: share.CreatedAt,
+	}
+}
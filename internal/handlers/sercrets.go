@@ -1,12 +1,17 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -21,9 +26,11 @@ type CreateSecretService interface {
 	Create(
 		ctx context.Context,
 		userID int,
+		vaultID int,
 		description string,
 		secretType models.SecretType,
 		marshallableSecret services.Marshaller,
+		metadata models.Metadata,
 	) (models.Secret, error)
 }
 
@@ -39,15 +46,60 @@ type UpdateSecretService interface {
 		newSecretType models.SecretType,
 		description string,
 		marshallableSecret services.Marshaller,
-		key []byte) error
+		metadata models.Metadata,
+		key []byte) (int, error)
+}
+
+type SecretMetadataService interface {
+	Update(ctx context.Context, userID int, secret models.Secret, metadata models.Metadata) (int, error)
+}
+
+type SecretFolderService interface {
+	MoveSecret(ctx context.Context, userID int, secret models.Secret, folderID int) error
+}
+
+type SecretTagsService interface {
+	SetSecretTags(ctx context.Context, userID int, secret models.Secret, tagIDs []int) error
 }
 
 type FetchUserSecretsService interface {
-	FetchUserSecrets(ctx context.Context, userID int) ([]byte, error)
+	FetchUserSecrets(ctx context.Context, userID int, filter models.SecretsFilter, w io.Writer) error
+}
+
+type ShowSecretService interface {
+	Show(ctx context.Context, userID int, secret models.Secret) (services.Unmarshaller, error)
+}
+
+type ListSecretsService interface {
+	List(ctx context.Context, userID int, filter models.SecretsFilter) ([]models.SecretInfo, int, error)
+}
+
+type BinDataService interface {
+	Create(
+		ctx context.Context,
+		userID int,
+		vaultID int,
+		description string,
+		filename string,
+		content io.Reader,
+		metadata models.Metadata,
+	) (models.Secret, error)
+	Update(
+		ctx context.Context,
+		userID int,
+		secret models.Secret,
+		description string,
+		filename string,
+		content io.Reader,
+		metadata models.Metadata,
+	) (int, error)
+	WriteContent(ctx context.Context, secret models.Secret, binData *models.BinData, w io.Writer) error
+	OpenContent(ctx context.Context, secret models.Secret, binData *models.BinData) (io.ReadSeeker, error)
 }
 
 type DeleteSecretService interface {
 	Delete(ctx context.Context, userID int, secret models.Secret) error
+	Purge(ctx context.Context, userID int, secret models.Secret) error
 }
 
 type SecretHandler struct {
@@ -60,157 +112,76 @@ func NewSecretHandler(logger *zap.Logger) SecretHandler {
 	}
 }
 
-func (h SecretHandler) Create(srv CreateSecretService) func(http.ResponseWriter, *http.Request) {
+func (h SecretHandler) Create(srv CreateSecretService, binDataSrv BinDataService) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		r.ParseMultipartForm(1 << 30)
 		userID, _ := middlewares.UserIDFromContext(r.Context())
-		description := r.FormValue("description")
-		var status int
-		switch r.FormValue("secret_type") {
-		case "credentials":
-			status = h.handleCreateCredsSecret(
+		vaultID, err := parseVaultID(r)
+		if err != nil {
+			h.logger.Info("invalid secret request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		input, err := parseSecretRequest(r)
+		if err != nil {
+			h.logger.Info("invalid secret request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !allowedByScope(w, r, 0, input.secretType, true) {
+			return
+		}
+
+		var secret models.Secret
+		if input.secretType == models.BinDataSecret {
+			secret, err = binDataSrv.Create(
 				r.Context(),
 				userID,
-				description,
-				r.FormValue("login"),
-				r.FormValue("password"),
-				srv,
+				vaultID,
+				input.description,
+				input.filename,
+				input.content,
+				input.metadata,
 			)
-		case "credit_card_info":
-			status = h.handlerCreateCreditCardSecret(
+		} else {
+			secret, err = srv.Create(
 				r.Context(),
 				userID,
-				description,
-				r.FormValue("credit_card_number"),
-				r.FormValue("credit_card_name"),
-				r.FormValue("credit_card_expiry_date"),
-				r.FormValue("credit_card_cvv2"),
-				srv,
-			)
-		case "bin_data":
-			status = h.handleCreateBinDataSecret(
-				userID,
-				description,
-				r,
-				srv,
+				vaultID,
+				input.description,
+				input.secretType,
+				input.secret,
+				input.metadata,
 			)
-		default:
-			status = http.StatusBadRequest
 		}
-		w.WriteHeader(status)
-	}
-}
-
-func (h SecretHandler) handleCreateCredsSecret(
-	ctx context.Context,
-	userID int,
-	description,
-	login,
-	password string,
-	createSrv CreateSecretService) int {
-
-	_, err := createSrv.Create(
-		ctx,
-		userID,
-		description,
-		models.CredentialsSecret,
-		&models.Credentials{
-			Login:    login,
-			Password: password,
-		},
-	)
-	if err != nil {
-		h.logger.Info("failed to create secret", zap.Error(err))
-		return http.StatusInternalServerError
-	}
-
-	return http.StatusOK
-}
-
-func (h SecretHandler) handlerCreateCreditCardSecret(
-	ctx context.Context,
-	userID int,
-	description,
-	number,
-	name,
-	expiryDateStr,
-	cvv2 string,
-	createSrv CreateSecretService) int {
-
-	expDate, err := time.Parse(time.RFC3339, expiryDateStr)
-	if err != nil {
-		h.logger.Info("failed to parse date", zap.String("credit_card_expiry_date", expiryDateStr), zap.Error(err))
-		return http.StatusBadRequest
-	}
+		if err != nil {
+			var permErr services.ErrNoPermission
+			if errors.As(err, &permErr) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if errors.Is(err, services.ErrBinDataTooLarge) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			h.logger.Info("failed to create secret", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-	_, err = createSrv.Create(
-		ctx,
-		userID,
-		description,
-		models.CreditCardSecret,
-		&models.CreditCard{
-			Number:     number,
-			Name:       name,
-			ExpiryDate: expDate,
-			CVV2:       cvv2,
-		},
-	)
-	if err != nil {
-		h.logger.Info("failed save credit card secret", zap.Error(err))
-		return http.StatusInternalServerError
+		w.Header().Set("ETag", secretETag(secret))
+		h.writeSecret(w, http.StatusCreated, secret)
 	}
-
-	return http.StatusOK
 }
 
-func (h SecretHandler) handleCreateBinDataSecret(
-	userID int,
-	description string,
-	r *http.Request,
-	srv CreateSecretService) int {
+// Update replaces the secret with the one from the request. If the If-Match
+// header is set, the secret is updated only if it still matches it.
+func (h SecretHandler) Update(
+	findSrv FindSecretService,
+	updateSrv UpdateSecretService,
+	binDataSrv BinDataService) func(http.ResponseWriter, *http.Request) {
 
-	file, header, err := r.FormFile("file")
-	if err != nil {
-		h.logger.Info("failed to get file", zap.Error(err))
-		return http.StatusBadRequest
-	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			h.logger.Info("failed to close file", zap.Error(err))
-		}
-	}()
-	if header.Size > 1<<30 {
-		h.logger.Info("too large file")
-		return http.StatusBadRequest
-	}
-
-	fileContent := bytes.NewBuffer(nil)
-	if _, err := io.Copy(fileContent, file); err != nil {
-		h.logger.Info("failed to copy file content", zap.Error(err))
-		return http.StatusInternalServerError
-	}
-	_, err = srv.Create(
-		r.Context(),
-		userID,
-		description,
-		models.BinDataSecret,
-		&models.BinData{
-			Filename: header.Filename,
-			Bytes:    fileContent.Bytes(),
-		},
-	)
-	if err != nil {
-		h.logger.Info("failed to create binary data secret", zap.Error(err))
-		return http.StatusInternalServerError
-	}
-
-	return http.StatusOK
-}
-
-func (h SecretHandler) Update(findSrv FindSecretService, updateSrv UpdateSecretService) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, _ := middlewares.UserIDFromContext(r.Context())
-		r.ParseMultipartForm(1 << 30)
 		secretID, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
 			h.logger.Info("invalid secret id", zap.Error(err))
@@ -228,207 +199,363 @@ func (h SecretHandler) Update(findSrv FindSecretService, updateSrv UpdateSecretS
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		description := r.FormValue("description")
-		var status int
-		switch r.FormValue("secret_type") {
-		case "credentials":
-			status = h.handleUpdateCreds(
+		if !allowedByScope(w, r, secret.ID, secret.SecretType, true) {
+			return
+		}
+		version, ok := expectedVersion(r, secret)
+		if !ok {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		secret.Version = version
+		input, err := parseSecretRequest(r)
+		if err != nil {
+			h.logger.Info("invalid secret request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var newVersion int
+		if input.secretType == models.BinDataSecret {
+			newVersion, err = binDataSrv.Update(
 				r.Context(),
 				userID,
-				description,
 				secret,
-				models.CredentialsSecret,
-				r.FormValue("login"),
-				r.FormValue("password"),
-				updateSrv,
+				input.description,
+				input.filename,
+				input.content,
+				input.metadata,
 			)
-		case "credit_card_info":
-			status = h.handleUpdateCreditCardSecret(
+		} else {
+			newVersion, err = updateSrv.Update(
 				r.Context(),
 				userID,
-				description,
 				secret,
-				models.CreditCardSecret,
-				r.FormValue("credit_card_number"),
-				r.FormValue("credit_card_name"),
-				r.FormValue("credit_card_expiry_date"),
-				r.FormValue("credit_card_cvv2"),
-				updateSrv,
+				input.secretType,
+				input.description,
+				input.secret,
+				input.metadata,
+				secret.EncryptedKey,
 			)
-		case "bin_data":
-			status = h.handleUpdateBinDataSecret(
-				userID,
-				description,
-				secret,
-				models.BinDataSecret,
-				r,
-				updateSrv,
-			)
-		default:
-			status = http.StatusBadRequest
 		}
-		w.WriteHeader(status)
-	}
-}
+		if err != nil {
+			if errors.Is(err, services.ErrWrongSecretType) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, services.ErrBinDataTooLarge) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			var permErr services.ErrNoPermission
+			if errors.As(err, &permErr) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			var mismatchErr storage.ErrSecretVersionMismatch
+			if errors.As(err, &mismatchErr) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
 
-func (h SecretHandler) handleUpdateCreds(
-	ctx context.Context,
-	userID int,
-	description string,
-	secret models.Secret,
-	newSecretType models.SecretType,
-	login string,
-	password string,
-	updateSrv UpdateSecretService) int {
-
-	err := updateSrv.Update(
-		ctx,
-		userID,
-		secret,
-		newSecretType,
-		description,
-		&models.Credentials{
-			Login:    login,
-			Password: password,
-		},
-		secret.EncryptedKey,
-	)
-	if err != nil {
-		if errors.Is(err, services.ErrWrongSecretType) {
-			return http.StatusBadRequest
-		}
-		var permErr services.ErrNoPermission
-		if errors.As(err, &permErr) {
-			return http.StatusForbidden
+			h.logger.Info("failed to update secret", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
 		}
 
-		h.logger.Info("failed to update secret", zap.Error(err))
-		return http.StatusInternalServerError
+		secret.Description = input.description
+		secret.Version = newVersion
+		w.Header().Set("ETag", secretETag(secret))
+		h.writeSecret(w, http.StatusOK, secret)
 	}
-
-	return http.StatusOK
 }
 
-func (h SecretHandler) handleUpdateCreditCardSecret(
-	ctx context.Context,
-	userID int,
-	description string,
-	secret models.Secret,
-	newSecreteType models.SecretType,
-	number,
-	name,
-	expiryDateStr,
-	cvv2 string,
-	updateSrv UpdateSecretService) int {
+// UpdateMetadata replaces secret metadata with the metadata object from the
+// request body, the secret data is not changed.
+func (h SecretHandler) UpdateMetadata(
+	findSrv FindSecretService,
+	metadataSrv SecretMetadataService) func(http.ResponseWriter, *http.Request) {
 
-	expDate, err := time.Parse(time.RFC3339, expiryDateStr)
-	if err != nil {
-		h.logger.Info("failed to parse date", zap.String("credit_card_expiry_date", expiryDateStr), zap.Error(err))
-		return http.StatusBadRequest
-	}
-	err = updateSrv.Update(
-		ctx,
-		userID,
-		secret,
-		newSecreteType,
-		description,
-		&models.CreditCard{
-			Number:     number,
-			Name:       name,
-			ExpiryDate: expDate,
-			CVV2:       cvv2,
-		},
-		secret.EncryptedKey,
-	)
-	if err != nil {
-		if errors.Is(err, services.ErrWrongSecretType) {
-			return http.StatusBadRequest
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		secretID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			h.logger.Info("invalid secret id", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var payload secretMetadataPayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid metadata request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := validateMetadata(payload.Metadata); err != nil {
+			h.logger.Info("invalid metadata request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		secret, err := findSrv.Find(r.Context(), secretID)
+		if err != nil {
+			var notFoundErr storage.ErrSecretNotFound
+			if errors.As(err, &notFoundErr) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			h.logger.Info("failed to update secret metadata", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
 		}
-		var permErr services.ErrNoPermission
-		if errors.As(err, &permErr) {
-			return http.StatusForbidden
+		if !allowedByScope(w, r, secret.ID, secret.SecretType, true) {
+			return
 		}
+		version, ok := expectedVersion(r, secret)
+		if !ok {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		secret.Version = version
 
-		h.logger.Info("failed to update secret", zap.Error(err))
-		return http.StatusInternalServerError
-	}
+		newVersion, err := metadataSrv.Update(r.Context(), userID, secret, payload.Metadata)
+		if err != nil {
+			var permErr services.ErrNoPermission
+			if errors.As(err, &permErr) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			var mismatchErr storage.ErrSecretVersionMismatch
+			if errors.As(err, &mismatchErr) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			h.logger.Info("failed to update secret metadata", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-	return http.StatusOK
+		secret.Version = newVersion
+		w.Header().Set("ETag", secretETag(secret))
+		h.writeSecret(w, http.StatusOK, secret)
+	}
 }
 
-func (h SecretHandler) handleUpdateBinDataSecret(
-	userID int,
-	description string,
-	secret models.Secret,
-	newSecretType models.SecretType,
-	r *http.Request,
-	updateSrv UpdateSecretService) int {
+// Move moves the secret into the folder from the request body, zero
+// folder_id moves it out of any folder.
+func (h SecretHandler) Move(
+	findSrv FindSecretService,
+	folderSrv SecretFolderService) func(http.ResponseWriter, *http.Request) {
 
-	file, header, err := r.FormFile("file")
-	if err != nil {
-		h.logger.Info("failed to get file", zap.Error(err))
-		return http.StatusBadRequest
-	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			h.logger.Info("failed to close file", zap.Error(err))
-		}
-	}()
-	if header.Size > 1<<30 {
-		h.logger.Info("too large file")
-		return http.StatusBadRequest
-	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		secretID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			h.logger.Info("invalid secret id", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var payload secretFolderPayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid secret folder request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		secret, err := findSrv.Find(r.Context(), secretID)
+		if err != nil {
+			var notFoundErr storage.ErrSecretNotFound
+			if errors.As(err, &notFoundErr) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			h.logger.Info("failed to move secret", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !allowedByScope(w, r, secret.ID, secret.SecretType, true) {
+			return
+		}
+
+		err = folderSrv.MoveSecret(r.Context(), userID, secret, payload.FolderID)
+		if err != nil {
+			var permErr services.ErrNoPermission
+			if errors.As(err, &permErr) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			var folderNotFoundErr storage.ErrFolderNotFound
+			if errors.As(err, &folderNotFoundErr) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			h.logger.Info("failed to move secret", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-	fileContent := bytes.NewBuffer(nil)
-	if _, err := io.Copy(fileContent, file); err != nil {
-		h.logger.Info("failed to copy file content", zap.Error(err))
-		return http.StatusInternalServerError
+		h.writeSecret(w, http.StatusOK, secret)
 	}
+}
 
-	err = updateSrv.Update(
-		r.Context(),
-		userID,
-		secret,
-		newSecretType,
-		description,
-		&models.BinData{
-			Filename: header.Filename,
-			Bytes:    fileContent.Bytes(),
-		},
-		secret.EncryptedKey,
-	)
-	if err != nil {
-		if errors.Is(err, services.ErrWrongSecretType) {
-			return http.StatusBadRequest
+// SetTags replaces secret tags with the tags from the request body.
+func (h SecretHandler) SetTags(
+	findSrv FindSecretService,
+	tagsSrv SecretTagsService) func(http.ResponseWriter, *http.Request) {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		secretID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			h.logger.Info("invalid secret id", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
 		}
-		var permErr services.ErrNoPermission
-		if errors.As(err, &permErr) {
-			return http.StatusForbidden
+		var payload secretTagsPayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid secret tags request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		secret, err := findSrv.Find(r.Context(), secretID)
+		if err != nil {
+			var notFoundErr storage.ErrSecretNotFound
+			if errors.As(err, &notFoundErr) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			h.logger.Info("failed to set secret tags", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !allowedByScope(w, r, secret.ID, secret.SecretType, true) {
+			return
 		}
 
-		h.logger.Info("failed to update secret", zap.Error(err))
-		return http.StatusInternalServerError
+		err = tagsSrv.SetSecretTags(r.Context(), userID, secret, payload.TagIDs)
+		if err != nil {
+			var permErr services.ErrNoPermission
+			if errors.As(err, &permErr) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			var tagNotFoundErr storage.ErrTagNotFound
+			if errors.As(err, &tagNotFoundErr) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			h.logger.Info("failed to set secret tags", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		h.writeSecret(w, http.StatusOK, secret)
 	}
+}
 
-	return http.StatusOK
+func (h SecretHandler) writeSecret(w http.ResponseWriter, status int, secret models.Secret) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/secrets/"+strconv.Itoa(secret.ID))
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	err := encoder.Encode(secretResponse{
+		ID:          secret.ID,
+		SecretType:  secret.SecretType.String(),
+		Description: secret.Description,
+	})
+	if err != nil {
+		h.logger.Info("failed to encode response", zap.Error(err))
+	}
 }
 
 func (h SecretHandler) GetUserSecrets(secretsFetcher FetchUserSecretsService) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowedUnlimitedScope(w, r) {
+			return
+		}
 		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="secrets.zip"`)
 		userID, _ := middlewares.UserIDFromContext(r.Context())
-		archiveContent, err := secretsFetcher.FetchUserSecrets(r.Context(), userID)
+		filter, err := parseSecretsFilter(r)
+		if err != nil {
+			h.logger.Info("invalid secrets filter", zap.Error(err))
+			w.Header().Del("Content-Disposition")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		cw := &countingWriter{w: w}
+		err = secretsFetcher.FetchUserSecrets(r.Context(), userID, filter, cw)
 		if err != nil {
+			var permErr services.ErrNoPermission
+			if errors.As(err, &permErr) && cw.n == 0 {
+				w.Header().Del("Content-Disposition")
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
 			h.logger.Info("failed to create secrets archive", zap.Error(err))
+			// Once the archive has been partially sent the status can not be changed
+			// and the client gets a truncated archive.
+			if cw.n == 0 {
+				w.Header().Del("Content-Disposition")
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+	}
+}
+
+func (h SecretHandler) Index(listSrv ListSecretsService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		filter, err := parseSecretsFilter(r)
+		if err != nil {
+			h.logger.Info("invalid secrets filter", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// the scope is applied by the query, so pages of limited tokens
+		// are full and the cursor continues after the last allowed secret
+		filter.Scope, _ = requestScope(r)
+		secrets, nextCursor, err := listSrv.List(r.Context(), userID, filter)
+		if err != nil {
+			var permErr services.ErrNoPermission
+			if errors.As(err, &permErr) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			h.logger.Info("failed to list secrets", zap.Error(err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+
+		response := secretsIndexResponse{
+			Secrets:    make([]secretInfoResponse, 0, len(secrets)),
+			NextCursor: nextCursor,
+		}
+		for _, secret := range secrets {
+			response.Secrets = append(response.Secrets, secretInfoResponse{
+				ID:          secret.ID,
+				SecretType:  secret.SecretType.String(),
+				Description: secret.Description,
+				Size:        secret.Size,
+				FolderID:    secret.FolderID,
+				Tags:        secret.Tags,
+				Version:     secret.Version,
+				Shared:      secret.Shared.String(),
+				CreatedAt:   secret.CreatedAt,
+				UpdatedAt:   secret.UpdatedAt,
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		w.Write(archiveContent)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
 	}
 }
 
-func (h SecretHandler) Delete(findSrv FindSecretService, deleteSrv DeleteSecretService) func(http.ResponseWriter, *http.Request) {
+func (h SecretHandler) Get(
+	findSrv FindSecretService,
+	showSrv ShowSecretService,
+	binDataSrv BinDataService) func(http.ResponseWriter, *http.Request) {
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, _ := middlewares.UserIDFromContext(r.Context())
 		secretID, err := strconv.Atoi(chi.URLParam(r, "id"))
@@ -444,21 +571,209 @@ func (h SecretHandler) Delete(findSrv FindSecretService, deleteSrv DeleteSecretS
 				w.WriteHeader(http.StatusNotFound)
 				return
 			}
-			h.logger.Info("failed to delete secret", zap.Error(err))
+			h.logger.Info("failed to get secret", zap.Error(err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		err = deleteSrv.Delete(r.Context(), userID, secret)
+		if !allowedByScope(w, r, secret.ID, secret.SecretType, false) {
+			return
+		}
+		decryptedSecret, err := showSrv.Show(r.Context(), userID, secret)
 		if err != nil {
 			var permErr services.ErrNoPermission
 			if errors.As(err, &permErr) {
 				w.WriteHeader(http.StatusForbidden)
 				return
 			}
-			h.logger.Info("failed to delete secret", zap.Error(err))
+			h.logger.Info("failed to get secret", zap.Error(err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+
+		etag := secretETag(secret)
+		w.Header().Set("ETag", etag)
+		if binData, ok := decryptedSecret.(*models.BinData); ok {
+			content, err := binDataSrv.OpenContent(r.Context(), secret, binData)
+			if err != nil {
+				h.logger.Info("failed to open bin data", zap.Error(err))
+				w.Header().Del("ETag")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			h.serveBinData(w, r, secret.ID, binData, content)
+			return
+		}
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(decryptedSecret); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// serveBinData sends decrypted bin data content with its digest and metadata.
+func (h SecretHandler) serveBinData(
+	w http.ResponseWriter,
+	r *http.Request,
+	secretID int,
+	binData *models.BinData,
+	content io.ReadSeeker) {
+
+	fname := services.BaseFilename(binData.Filename)
+	if fname == "" {
+		fname = "bin_data_" + strconv.Itoa(secretID)
+	}
+	contentHash := binData.SHA256
+	if len(contentHash) == 0 && len(binData.Bytes) > 0 {
+		sum := sha256.Sum256(binData.Bytes)
+		contentHash = sum[:]
+	}
+	if len(contentHash) > 0 {
+		w.Header().Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(contentHash)+":")
+	}
+	if len(binData.Metadata) > 0 {
+		w.Header().Set("Secret-Metadata", encodeMetadataHeader(binData.Metadata))
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set(
+		"Content-Disposition",
+		mime.FormatMediaType("attachment", map[string]string{"filename": fname}),
+	)
+	// ServeContent handles Range, If-Range and If-None-Match requests
+	http.ServeContent(w, r, fname, time.Time{}, content)
+}
+
+// Delete moves the secret to the trash, the secret is deleted permanently
+// if the permanent query parameter is true. If the If-Match header is set,
+// the secret is deleted only if it still matches it.
+func (h SecretHandler) Delete(findSrv FindSecretService, deleteSrv DeleteSecretService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		permanent := false
+		if permanentStr := r.URL.Query().Get("permanent"); permanentStr != "" {
+			var err error
+			permanent, err = strconv.ParseBool(permanentStr)
+			if err != nil {
+				h.logger.Info("invalid permanent parameter", zap.Error(err))
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		secret, ok := h.findSecret(w, r, findSrv)
+		if !ok {
+			return
+		}
+		if !allowedByScope(w, r, secret.ID, secret.SecretType, true) {
+			return
+		}
+		version, ok := expectedVersion(r, secret)
+		if !ok {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		secret.Version = version
+		deleteFn := deleteSrv.Delete
+		if permanent {
+			deleteFn = deleteSrv.Purge
+		}
+		if err := deleteFn(r.Context(), userID, secret); err != nil {
+			h.writeDeleteError(w, err)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+func (h SecretHandler) writeDeleteError(w http.ResponseWriter, err error) {
+	var permErr services.ErrNoPermission
+	if errors.As(err, &permErr) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	var notFoundErr storage.ErrSecretNotFound
+	if errors.As(err, &notFoundErr) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var mismatchErr storage.ErrSecretVersionMismatch
+	if errors.As(err, &mismatchErr) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	h.logger.Info("failed to delete secret", zap.Error(err))
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+
+	return n, err
+}
+
+// secretETag is derived from the secret version, which changes on every
+// change of the secret.
+func secretETag(secret models.Secret) string {
+	return `"` + strconv.Itoa(secret.Version) + `"`
+}
+
+// expectedVersion returns the version the secret is expected to have when
+// it is changed and reports whether the secret matches the If-Match header.
+// Storage checks the version once more, since the secret could change after
+// it was found. Without the header the secret is changed whatever its
+// version is, so zero version is returned.
+func expectedVersion(r *http.Request, secret models.Secret) (int, bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, true
+	}
+
+	return secret.Version, etagMatchesStrong(header, secretETag(secret))
+}
+
+// etagMatches compares tags of If-None-Match weakly, so a weak tag matches
+// the same version.
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// etagMatchesStrong compares tags of If-Match strongly as RFC 9110
+// requires, weak tags never match.
+func etagMatchesStrong(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// encodeMetadataHeader encodes metadata as URL query, since bin data
+// content is sent in the response body.
+func encodeMetadataHeader(metadata models.Metadata) string {
+	values := make(url.Values, len(metadata))
+	for key, value := range metadata {
+		values.Set(key, value)
+	}
+
+	return values.Encode()
+}
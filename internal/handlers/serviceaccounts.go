@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Grant types of the service account token request.
+const (
+	ClientCredentialsGrant = "client_credentials"
+	JWTBearerGrant         = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+)
+
+type ServiceAccountService interface {
+	Create(
+		ctx context.Context,
+		userID int,
+		orgID int,
+		name string,
+		role models.OrganizationRole,
+	) (models.ServiceAccount, error)
+	List(ctx context.Context, userID int, orgID int) ([]models.ServiceAccount, error)
+	Disable(ctx context.Context, userID int, orgID int, id int) error
+	CreateSecret(ctx context.Context, userID int, orgID int, id int) (models.ServiceAccountCredential, string, error)
+	AddPublicKey(
+		ctx context.Context,
+		userID int,
+		orgID int,
+		id int,
+		publicKey string,
+	) (models.ServiceAccountCredential, error)
+	Credentials(ctx context.Context, userID int, orgID int, id int) ([]models.ServiceAccountCredential, error)
+	DeleteCredential(ctx context.Context, userID int, orgID int, id int, credentialID int) error
+	Audit(ctx context.Context, userID int, orgID int, id int, filter models.AuditFilter) ([]models.AuditEvent, error)
+}
+
+type ServiceAccountTokenService interface {
+	IssueToken(ctx context.Context, clientID string, clientSecret string) (services.ServiceAccountToken, error)
+	IssueTokenForAssertion(ctx context.Context, assertion string) (services.ServiceAccountToken, error)
+}
+
+// serviceAccountPayload creates the service account with the viewer role
+// if the role is not set.
+type serviceAccountPayload struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// credentialPayload type is "secret" or "public_key", public_key is the PEM
+// encoded key of public key credentials.
+type credentialPayload struct {
+	Type      string `json:"type"`
+	PublicKey string `json:"public_key"`
+}
+
+// tokenRequestPayload client_id and client_secret are set for the client
+// credentials grant, assertion is set for the JWT bearer grant.
+type tokenRequestPayload struct {
+	GrantType    string `json:"grant_type"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Assertion    string `json:"assertion"`
+}
+
+type serviceAccountResponse struct {
+	ID             int       `json:"id"`
+	UserID         int       `json:"user_id"`
+	OrganizationID int       `json:"organization_id"`
+	Name           string    `json:"name"`
+	ClientID       string    `json:"client_id"`
+	CreatedBy      int       `json:"created_by,omitempty"`
+	Disabled       bool      `json:"disabled"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type serviceAccountsIndexResponse struct {
+	ServiceAccounts []serviceAccountResponse `json:"service_accounts"`
+}
+
+type credentialResponse struct {
+	ID           int        `json:"id"`
+	Type         string     `json:"type"`
+	ClientSecret string     `json:"client_secret,omitempty"`
+	PublicKey    string     `json:"public_key,omitempty"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type credentialsIndexResponse struct {
+	Credentials []credentialResponse `json:"credentials"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type ServiceAccountHandler struct {
+	logger *zap.Logger
+}
+
+func NewServiceAccountHandler(logger *zap.Logger) ServiceAccountHandler {
+	return ServiceAccountHandler{
+		logger: logger,
+	}
+}
+
+func (h ServiceAccountHandler) Create(srv ServiceAccountService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		orgID, ok := h.urlID(w, r, "id")
+		if !ok {
+			return
+		}
+		var payload serviceAccountPayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid service account request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		role := models.OrganizationViewerRole
+		if payload.Role != "" {
+			var ok bool
+			if role, ok = models.ParseOrganizationRole(payload.Role); !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		account, err := srv.Create(r.Context(), userID, orgID, payload.Name, role)
+		if err != nil {
+			h.writeError(w, "failed to create service account", err)
+			return
+		}
+
+		h.writeJSON(w, http.StatusCreated, newServiceAccountResponse(account))
+	}
+}
+
+// Index responds with service accounts of the organization.
+func (h ServiceAccountHandler) Index(srv ServiceAccountService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		orgID, ok := h.urlID(w, r, "id")
+		if !ok {
+			return
+		}
+
+		accounts, err := srv.List(r.Context(), userID, orgID)
+		if err != nil {
+			h.writeError(w, "failed to list service accounts", err)
+			return
+		}
+
+		response := serviceAccountsIndexResponse{ServiceAccounts: make([]serviceAccountResponse, len(accounts))}
+		for i, account := range accounts {
+			response.ServiceAccounts[i] = newServiceAccountResponse(account)
+		}
+		h.writeJSON(w, http.StatusOK, response)
+	}
+}
+
+func (h ServiceAccountHandler) Disable(srv ServiceAccountService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		orgID, accountID, ok := h.accountIDs(w, r)
+		if !ok {
+			return
+		}
+
+		if err := srv.Disable(r.Context(), userID, orgID, accountID); err != nil {
+			h.writeError(w, "failed to disable service account", err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// CreateCredential creates a client secret or adds a public key of
+// the service account, the client secret is returned in this response
+// only.
+func (h ServiceAccountHandler) CreateCredential(srv ServiceAccountService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		orgID, accountID, ok := h.accountIDs(w, r)
+		if !ok {
+			return
+		}
+		var payload credentialPayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid service account credential request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		credType, ok := models.ParseCredentialType(payload.Type)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var credential models.ServiceAccountCredential
+		var secret string
+		var err error
+		if credType == models.CredentialSecret {
+			credential, secret, err = srv.CreateSecret(r.Context(), userID, orgID, accountID)
+		} else {
+			credential, err = srv.AddPublicKey(r.Context(), userID, orgID, accountID, payload.PublicKey)
+		}
+		if err != nil {
+			h.writeError(w, "failed to create service account credential", err)
+			return
+		}
+
+		response := newCredentialResponse(credential)
+		response.ClientSecret = secret
+		h.writeJSON(w, http.StatusCreated, response)
+	}
+}
+
+// Credentials responds with client secrets without their values and
+// public keys of the service account.
+func (h ServiceAccountHandler) Credentials(srv ServiceAccountService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		orgID, accountID, ok := h.accountIDs(w, r)
+		if !ok {
+			return
+		}
+
+		credentials, err := srv.Credentials(r.Context(), userID, orgID, accountID)
+		if err != nil {
+			h.writeError(w, "failed to list service account credentials", err)
+			return
+		}
+
+		response := credentialsIndexResponse{Credentials: make([]credentialResponse, len(credentials))}
+		for i, credential := range credentials {
+			response.Credentials[i] = newCredentialResponse(credential)
+		}
+		h.writeJSON(w, http.StatusOK, response)
+	}
+}
+
+func (h ServiceAccountHandler) DeleteCredential(srv ServiceAccountService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		orgID, accountID, ok := h.accountIDs(w, r)
+		if !ok {
+			return
+		}
+		credentialID, ok := h.urlID(w, r, "credentialID")
+		if !ok {
+			return
+		}
+
+		if err := srv.DeleteCredential(r.Context(), userID, orgID, accountID, credentialID); err != nil {
+			h.writeError(w, "failed to delete service account credential", err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// Audit responds with audit events of the service account, the latest
+// first. Events are filtered by the same query parameters as the user
+// audit log.
+func (h ServiceAccountHandler) Audit(srv ServiceAccountService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		orgID, accountID, ok := h.accountIDs(w, r)
+		if !ok {
+			return
+		}
+		filter, err := parseAuditFilter(r)
+		if err != nil {
+			h.logger.Info("invalid audit request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		events, err := srv.Audit(r.Context(), userID, orgID, accountID, filter)
+		if err != nil {
+			h.writeError(w, "failed to list service account audit events", err)
+			return
+		}
+
+		response := auditIndexResponse{Events: make([]auditEventResponse, len(events))}
+		for i, event := range events {
+			response.Events[i] = newAuditEventResponse(event)
+		}
+		h.writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// Token exchanges the client secret or the JWT assertion of the service
+// account for an access token, which is sent in the Authorization header
+// as a bearer token. Every issued token is logged with the client address.
+func (h ServiceAccountHandler) Token(srv ServiceAccountTokenService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload tokenRequestPayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid service account token request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var token services.ServiceAccountToken
+		var err error
+		switch payload.GrantType {
+		case ClientCredentialsGrant:
+			token, err = srv.IssueToken(r.Context(), payload.ClientID, payload.ClientSecret)
+		case JWTBearerGrant:
+			token, err = srv.IssueTokenForAssertion(r.Context(), payload.Assertion)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		client := services.ClientInfoFromContext(r.Context())
+		if err != nil {
+			var accountNotFoundErr storage.ErrServiceAccountNotFound
+			var credentialNotFoundErr storage.ErrServiceAccountCredentialNotFound
+			var assertionNotUniqErr storage.ErrServiceAccountAssertionNotUniq
+			if errors.Is(err, services.ErrInvalidClientCredentials) ||
+				errors.As(err, &accountNotFoundErr) ||
+				errors.As(err, &credentialNotFoundErr) ||
+				errors.As(err, &assertionNotUniqErr) {
+
+				h.logger.Info(
+					"rejected service account token request",
+					zap.String("grant_type", payload.GrantType),
+					zap.String("client_id", payload.ClientID),
+					zap.String("client_ip", client.IP),
+					zap.Error(err),
+				)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			h.logger.Info("failed to issue service account token", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		h.logger.Info(
+			"issued service account token",
+			zap.String("grant_type", payload.GrantType),
+			zap.String("client_id", token.ClientID),
+			zap.String("client_ip", client.IP),
+		)
+		w.Header().Set("Cache-Control", "no-store")
+		h.writeJSON(w, http.StatusOK, tokenResponse{
+			AccessToken: token.AccessToken,
+			TokenType:   "Bearer",
+			ExpiresIn:   int(token.ExpiresIn / time.Second),
+		})
+	}
+}
+
+func (h ServiceAccountHandler) urlID(w http.ResponseWriter, r *http.Request, param string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, param))
+	if err != nil {
+		h.logger.Info("invalid id", zap.String("param", param), zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return 0, false
+	}
+
+	return id, true
+}
+
+// accountIDs returns the organization and the service account from URL
+// parameters.
+func (h ServiceAccountHandler) accountIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	orgID, ok := h.urlID(w, r, "id")
+	if !ok {
+		return 0, 0, false
+	}
+	accountID, ok := h.urlID(w, r, "accountID")
+	if !ok {
+		return 0, 0, false
+	}
+
+	return orgID, accountID, true
+}
+
+func (h ServiceAccountHandler) writeError(w http.ResponseWriter, msg string, err error) {
+	var permErr services.ErrNoPermission
+	var accountNotFoundErr storage.ErrServiceAccountNotFound
+	var credentialNotFoundErr storage.ErrServiceAccountCredentialNotFound
+	var accountNotUniqErr storage.ErrServiceAccountNotUniq
+	switch {
+	case errors.As(err, &permErr):
+		w.WriteHeader(http.StatusForbidden)
+	case errors.As(err, &accountNotFoundErr), errors.As(err, &credentialNotFoundErr):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidServiceAccountName),
+		errors.Is(err, services.ErrInvalidOrganizationRole),
+		errors.Is(err, services.ErrInvalidPublicKey):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.As(err, &accountNotUniqErr):
+		w.WriteHeader(http.StatusConflict)
+	case errors.Is(err, services.ErrServiceAccountDisabled):
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	default:
+		h.logger.Info(msg, zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (h ServiceAccountHandler) writeJSON(w http.ResponseWriter, status int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Info("failed to encode response", zap.Error(err))
+	}
+}
+
+func newServiceAccountResponse(account models.ServiceAccount) serviceAccountResponse {
+	return serviceAccountResponse{
+		ID:             account.ID,
+		UserID:         account.UserID,
+		OrganizationID: account.OrganizationID,
+		Name:           account.Name,
+		ClientID:       account.ClientID,
+		CreatedBy:      account.CreatedBy,
+		Disabled:       account.Disabled,
+		CreatedAt:      account.CreatedAt,
+	}
+}
+
+func newCredentialResponse(credential models.ServiceAccountCredential) credentialResponse {
+	response := credentialResponse{
+		ID:        credential.ID,
+		Type:      credential.Type.String(),
+		PublicKey: credential.PublicKey,
+		CreatedAt: credential.CreatedAt,
+	}
+	if !credential.LastUsedAt.IsZero() {
+		response.LastUsedAt = &credential.LastUsedAt
+	}
+
+	return response
+}
@@ -0,0 +1,381 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+type serviceAccountServiceMock struct{ mock.Mock }
+
+func (m *serviceAccountServiceMock) Create(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	name string,
+	role models.OrganizationRole) (models.ServiceAccount, error) {
+
+	args := m.Called(ctx, userID, orgID, name, role)
+	return args.Get(0).(models.ServiceAccount), args.Error(1)
+}
+
+func (m *serviceAccountServiceMock) List(ctx context.Context, userID int, orgID int) ([]models.ServiceAccount, error) {
+	args := m.Called(ctx, userID, orgID)
+	return args.Get(0).([]models.ServiceAccount), args.Error(1)
+}
+
+func (m *serviceAccountServiceMock) Disable(ctx context.Context, userID int, orgID int, id int) error {
+	args := m.Called(ctx, userID, orgID, id)
+	return args.Error(0)
+}
+
+func (m *serviceAccountServiceMock) CreateSecret(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	id int) (models.ServiceAccountCredential, string, error) {
+
+	args := m.Called(ctx, userID, orgID, id)
+	return args.Get(0).(models.ServiceAccountCredential), args.String(1), args.Error(2)
+}
+
+func (m *serviceAccountServiceMock) AddPublicKey(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	id int,
+	publicKey string) (models.ServiceAccountCredential, error) {
+
+	args := m.Called(ctx, userID, orgID, id, publicKey)
+	return args.Get(0).(models.ServiceAccountCredential), args.Error(1)
+}
+
+func (m *serviceAccountServiceMock) Credentials(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	id int) ([]models.ServiceAccountCredential, error) {
+
+	args := m.Called(ctx, userID, orgID, id)
+	return args.Get(0).([]models.ServiceAccountCredential), args.Error(1)
+}
+
+func (m *serviceAccountServiceMock) DeleteCredential(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	id int,
+	credentialID int) error {
+
+	args := m.Called(ctx, userID, orgID, id, credentialID)
+	return args.Error(0)
+}
+
+func (m *serviceAccountServiceMock) Audit(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	id int,
+	filter models.AuditFilter) ([]models.AuditEvent, error) {
+
+	args := m.Called(ctx, userID, orgID, id, filter)
+	return args.Get(0).([]models.AuditEvent), args.Error(1)
+}
+
+type serviceAccountTokenServiceMock struct{ mock.Mock }
+
+func (m *serviceAccountTokenServiceMock) IssueToken(
+	ctx context.Context,
+	clientID string,
+	clientSecret string) (services.ServiceAccountToken, error) {
+
+	args := m.Called(ctx, clientID, clientSecret)
+	return args.Get(0).(services.ServiceAccountToken), args.Error(1)
+}
+
+func (m *serviceAccountTokenServiceMock) IssueTokenForAssertion(
+	ctx context.Context,
+	assertion string) (services.ServiceAccountToken, error) {
+
+	args := m.Called(ctx, assertion)
+	return args.Get(0).(services.ServiceAccountToken), args.Error(1)
+}
+
+// withURLParams returns the request with chi URL parameters given as
+// name, value pairs.
+func withURLParams(request *http.Request, params ...string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for i := 0; i+1 < len(params); i += 2 {
+		rctx.URLParams.Add(params[i], params[i+1])
+	}
+
+	return request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestCreateServiceAccount(t *testing.T) {
+	type want struct {
+		code     int
+		role     models.OrganizationRole
+		response string
+	}
+	createdAt := time.Date(2024, 6, 1, 10, 25, 44, 0, time.UTC)
+	testCases := []struct {
+		name      string
+		body      string
+		createErr error
+		want      want
+	}{
+		{
+			name: "responds with created service account with viewer role by default",
+			body: `{"name":"deploy"}`,
+			want: want{
+				code: http.StatusCreated,
+				role: models.OrganizationViewerRole,
+				response: `{"id":2,"user_id":10,"organization_id":1,"name":"deploy",` +
+					`"client_id":"deploy@1.service-account","created_by":1,"disabled":false,` +
+					`"created_at":"2024-06-01T10:25:44Z"}` + "\n",
+			},
+		},
+		{
+			name: "responds with bad request if role is invalid",
+			body: `{"name":"deploy","role":"owner"}`,
+			want: want{code: http.StatusBadRequest},
+		},
+		{
+			name:      "responds with bad request if name is invalid",
+			body:      `{"name":"deploy","role":"editor"}`,
+			createErr: services.ErrInvalidServiceAccountName,
+			want:      want{code: http.StatusBadRequest, role: models.OrganizationEditorRole},
+		},
+		{
+			name:      "responds with forbidden if user is not admin",
+			body:      `{"name":"deploy"}`,
+			createErr: services.ErrNoPermission{UserID: 1, OrganizationID: 1},
+			want:      want{code: http.StatusForbidden, role: models.OrganizationViewerRole},
+		},
+		{
+			name:      "responds with conflict if name is taken",
+			body:      `{"name":"deploy"}`,
+			createErr: storage.ErrServiceAccountNotUniq{Account: models.ServiceAccount{Name: "deploy"}},
+			want:      want{code: http.StatusConflict, role: models.OrganizationViewerRole},
+		},
+	}
+
+	srv := new(serviceAccountServiceMock)
+	handler := http.HandlerFunc(handlers.NewServiceAccountHandler(zaptest.NewLogger(t)).Create(srv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			account := models.ServiceAccount{
+				ID:             2,
+				UserID:         10,
+				OrganizationID: 1,
+				Name:           "deploy",
+				ClientID:       "deploy@1.service-account",
+				CreatedBy:      1,
+				CreatedAt:      createdAt,
+			}
+			createCall := srv.On("Create", mock.Anything, mock.Anything, 1, "deploy", tc.want.role).
+				Return(account, tc.createErr)
+			defer createCall.Unset()
+
+			request := httptest.NewRequest(
+				http.MethodPost,
+				"/api/organizations/1/service-accounts",
+				strings.NewReader(tc.body),
+			)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, withURLParams(request, "id", "1"))
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
+
+func TestCreateServiceAccountCredential(t *testing.T) {
+	createdAt := time.Date(2024, 6, 1, 10, 25, 44, 0, time.UTC)
+	srv := new(serviceAccountServiceMock)
+	srv.On("CreateSecret", mock.Anything, mock.Anything, 1, 2).Return(
+		models.ServiceAccountCredential{ID: 3, ServiceAccountID: 2, Type: models.CredentialSecret, CreatedAt: createdAt},
+		"gks_secret",
+		nil,
+	)
+	srv.On("AddPublicKey", mock.Anything, mock.Anything, 1, 2, "invalid").
+		Return(models.ServiceAccountCredential{}, services.ErrInvalidPublicKey)
+	srv.On("AddPublicKey", mock.Anything, mock.Anything, 1, 3, mock.Anything).
+		Return(models.ServiceAccountCredential{}, services.ErrServiceAccountDisabled)
+	handler := http.HandlerFunc(handlers.NewServiceAccountHandler(zaptest.NewLogger(t)).CreateCredential(srv))
+
+	testCases := []struct {
+		name         string
+		accountID    string
+		body         string
+		wantCode     int
+		wantResponse string
+	}{
+		{
+			name:      "responds with created client secret",
+			accountID: "2",
+			body:      `{"type":"secret"}`,
+			wantCode:  http.StatusCreated,
+			wantResponse: `{"id":3,"type":"secret","client_secret":"gks_secret",` +
+				`"created_at":"2024-06-01T10:25:44Z"}` + "\n",
+		},
+		{
+			name:      "responds with bad request if type is invalid",
+			accountID: "2",
+			body:      `{"type":"password"}`,
+			wantCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "responds with bad request if public key is invalid",
+			accountID: "2",
+			body:      `{"type":"public_key","public_key":"invalid"}`,
+			wantCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "responds with unprocessable entity if service account is disabled",
+			accountID: "3",
+			body:      `{"type":"public_key","public_key":"key"}`,
+			wantCode:  http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(
+				http.MethodPost,
+				fmt.Sprintf("/api/organizations/1/service-accounts/%s/credentials", tc.accountID),
+				strings.NewReader(tc.body),
+			)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, withURLParams(request, "id", "1", "accountID", tc.accountID))
+
+			assert.Equal(t, tc.wantCode, recorder.Result().StatusCode)
+			assert.Equal(t, tc.wantResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestDisableServiceAccount(t *testing.T) {
+	testCases := []struct {
+		name       string
+		disableErr error
+		wantCode   int
+	}{
+		{
+			name:     "disables service account",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:       "responds with not found",
+			disableErr: storage.ErrServiceAccountNotFound{ID: 2},
+			wantCode:   http.StatusNotFound,
+		},
+		{
+			name:       "responds with internal server error",
+			disableErr: errors.New("error"),
+			wantCode:   http.StatusInternalServerError,
+		},
+	}
+
+	srv := new(serviceAccountServiceMock)
+	handler := http.HandlerFunc(handlers.NewServiceAccountHandler(zaptest.NewLogger(t)).Disable(srv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			disableCall := srv.On("Disable", mock.Anything, mock.Anything, 1, 2).Return(tc.disableErr)
+			defer disableCall.Unset()
+
+			request := httptest.NewRequest(http.MethodPost, "/api/organizations/1/service-accounts/2/disable", nil)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, withURLParams(request, "id", "1", "accountID", "2"))
+
+			assert.Equal(t, tc.wantCode, recorder.Result().StatusCode)
+		})
+	}
+}
+
+func TestServiceAccountToken(t *testing.T) {
+	token := services.ServiceAccountToken{AccessToken: "jwt", ExpiresIn: 15 * time.Minute}
+	testCases := []struct {
+		name         string
+		body         string
+		issueErr     error
+		wantCode     int
+		wantResponse string
+	}{
+		{
+			name:         "issues token for client credentials",
+			body:         `{"grant_type":"client_credentials","client_id":"deploy@1.service-account","client_secret":"gks_secret"}`,
+			wantCode:     http.StatusOK,
+			wantResponse: `{"access_token":"jwt","token_type":"Bearer","expires_in":900}` + "\n",
+		},
+		{
+			name:         "issues token for assertion",
+			body:         `{"grant_type":"urn:ietf:params:oauth:grant-type:jwt-bearer","assertion":"assertion"}`,
+			wantCode:     http.StatusOK,
+			wantResponse: `{"access_token":"jwt","token_type":"Bearer","expires_in":900}` + "\n",
+		},
+		{
+			name:     "responds with bad request if grant type is unsupported",
+			body:     `{"grant_type":"password"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "responds with unauthorized if credentials are invalid",
+			body:     `{"grant_type":"client_credentials","client_id":"deploy@1.service-account","client_secret":"gks_secret"}`,
+			issueErr: services.ErrInvalidClientCredentials,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "responds with unauthorized if client is unknown",
+			body:     `{"grant_type":"client_credentials","client_id":"deploy@1.service-account","client_secret":"gks_secret"}`,
+			issueErr: fmt.Errorf("failed to issue service account token: %w", storage.ErrServiceAccountNotFound{}),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "responds with unauthorized if assertion is replayed",
+			body:     `{"grant_type":"urn:ietf:params:oauth:grant-type:jwt-bearer","assertion":"assertion"}`,
+			issueErr: storage.ErrServiceAccountAssertionNotUniq{JTI: "1"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "responds with internal server error",
+			body:     `{"grant_type":"urn:ietf:params:oauth:grant-type:jwt-bearer","assertion":"assertion"}`,
+			issueErr: errors.New("error"),
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+
+	srv := new(serviceAccountTokenServiceMock)
+	handler := http.HandlerFunc(handlers.NewServiceAccountHandler(zaptest.NewLogger(t)).Token(srv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			issueCall := srv.On("IssueToken", mock.Anything, "deploy@1.service-account", "gks_secret").
+				Return(token, tc.issueErr)
+			defer issueCall.Unset()
+			assertionCall := srv.On("IssueTokenForAssertion", mock.Anything, "assertion").Return(token, tc.issueErr)
+			defer assertionCall.Unset()
+
+			request := httptest.NewRequest(http.MethodPost, "/api/service-accounts/token", strings.NewReader(tc.body))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.wantCode, recorder.Result().StatusCode)
+			assert.Equal(t, tc.wantResponse, recorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"go.uber.org/zap"
+)
+
+type ShareService interface {
+	Share(
+		ctx context.Context,
+		userID int,
+		secret models.Secret,
+		login string,
+		access models.SecretAccess,
+	) (models.SecretShare, error)
+	List(ctx context.Context, userID int, secret models.Secret) ([]models.SecretShare, error)
+	Revoke(ctx context.Context, userID int, secret models.Secret, recipientID int) error
+}
+
+// Share shares the secret with the user from the request body, the access
+// of the existing share is replaced.
+func (h SecretHandler) Share(findSrv FindSecretService, shareSrv ShareService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		var payload secretSharePayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid secret share request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		access, ok := models.ParseSecretAccess(payload.Access)
+		if !ok {
+			h.logger.Info("invalid secret share access", zap.String("access", payload.Access))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		secret, ok := h.findSecret(w, r, findSrv)
+		if !ok {
+			return
+		}
+		if !allowedByScope(w, r, secret.ID, secret.SecretType, true) {
+			return
+		}
+
+		share, err := shareSrv.Share(r.Context(), userID, secret, payload.Login, access)
+		if err != nil {
+			var permErr services.ErrNoPermission
+			if errors.As(err, &permErr) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			var userNotFoundErr storage.ErrUserNotFound
+			if errors.As(err, &userNotFoundErr) ||
+				errors.Is(err, services.ErrShareWithOwner) ||
+				errors.Is(err, services.ErrInvalidShareAccess) ||
+				errors.Is(err, services.ErrShareVaultSecret) {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+			h.logger.Info("failed to share secret", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(newSecretShareResponse(share)); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// Shares responds with users the secret is shared with.
+func (h SecretHandler) Shares(findSrv FindSecretService, shareSrv ShareService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		secret, ok := h.findSecret(w, r, findSrv)
+		if !ok {
+			return
+		}
+		if !allowedByScope(w, r, secret.ID, secret.SecretType, false) {
+			return
+		}
+
+		shares, err := shareSrv.List(r.Context(), userID, secret)
+		if err != nil {
+			var permErr services.ErrNoPermission
+			if errors.As(err, &permErr) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			h.logger.Info("failed to list secret shares", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := secretSharesResponse{Shares: make([]secretShareResponse, len(shares))}
+		for i, share := range shares {
+			response.Shares[i] = newSecretShareResponse(share)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// RevokeShare revokes access of the user from the userID URL parameter to
+// the secret.
+func (h SecretHandler) RevokeShare(findSrv FindSecretService, shareSrv ShareService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		recipientID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+		if err != nil {
+			h.logger.Info("invalid user id", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		secret, ok := h.findSecret(w, r, findSrv)
+		if !ok {
+			return
+		}
+		if !allowedByScope(w, r, secret.ID, secret.SecretType, true) {
+			return
+		}
+
+		if err := shareSrv.Revoke(r.Context(), userID, secret, recipientID); err != nil {
+			var permErr services.ErrNoPermission
+			if errors.As(err, &permErr) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			var notFoundErr storage.ErrSecretShareNotFound
+			if errors.As(err, &notFoundErr) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			h.logger.Info("failed to revoke secret share", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
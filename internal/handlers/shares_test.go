@@ -0,0 +1,252 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type shareServiceMock struct{ mock.Mock }
+
+func (m *shareServiceMock) Share(
+	ctx context.Context,
+	userID int,
+	secret models.Secret,
+	login string,
+	access models.SecretAccess) (models.SecretShare, error) {
+
+	args := m.Called(ctx, userID, secret, login, access)
+	return args.Get(0).(models.SecretShare), args.Error(1)
+}
+
+func (m *shareServiceMock) List(ctx context.Context, userID int, secret models.Secret) ([]models.SecretShare, error) {
+	args := m.Called(ctx, userID, secret)
+	return args.Get(0).([]models.SecretShare), args.Error(1)
+}
+
+func (m *shareServiceMock) Revoke(ctx context.Context, userID int, secret models.Secret, recipientID int) error {
+	args := m.Called(ctx, userID, secret, recipientID)
+	return args.Error(0)
+}
+
+func TestShare(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+	}
+	type shareResult struct {
+		share models.SecretShare
+		err   error
+	}
+	timestamp := time.Date(2024, 5, 14, 9, 23, 10, 0, time.UTC)
+	secret := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret}
+	testCases := []struct {
+		name     string
+		body     string
+		access   models.SecretAccess
+		shareRes shareResult
+		want     want
+	}{
+		{
+			name:   "responds with share",
+			body:   `{"login":"bob","access":"write"}`,
+			access: models.SecretWriteAccess,
+			shareRes: shareResult{
+				share: models.SecretShare{
+					SecretID:  1,
+					UserID:    2,
+					Login:     "bob",
+					Access:    models.SecretWriteAccess,
+					CreatedAt: timestamp,
+				},
+			},
+			want: want{
+				code:     http.StatusOK,
+				response: `{"user_id":2,"login":"bob","access":"write","created_at":"2024-05-14T09:23:10Z"}` + "\n",
+			},
+		},
+		{
+			name: "responds with bad request if access is invalid",
+			body: `{"login":"bob","access":"admin"}`,
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:     "responds with forbidden status if user is not owner",
+			body:     `{"login":"bob","access":"read"}`,
+			access:   models.SecretReadAccess,
+			shareRes: shareResult{err: services.ErrNoPermission{UserID: 3, SecretID: 1}},
+			want: want{
+				code: http.StatusForbidden,
+			},
+		},
+		{
+			name:   "responds with unprocessable entity if recipient does not exist",
+			body:   `{"login":"nobody","access":"read"}`,
+			access: models.SecretReadAccess,
+			shareRes: shareResult{
+				err: storage.ErrUserNotFound{User: models.User{Login: "nobody"}},
+			},
+			want: want{
+				code: http.StatusUnprocessableEntity,
+			},
+		},
+		{
+			name:     "responds with unprocessable entity if access is owner",
+			body:     `{"login":"bob","access":"owner"}`,
+			access:   models.SecretOwnerAccess,
+			shareRes: shareResult{err: services.ErrInvalidShareAccess},
+			want: want{
+				code: http.StatusUnprocessableEntity,
+			},
+		},
+		{
+			name:     "responds with internal server error",
+			body:     `{"login":"bob","access":"read"}`,
+			access:   models.SecretReadAccess,
+			shareRes: shareResult{err: errors.New("error")},
+			want: want{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	findSrv := new(findSecretServiceMock)
+	findSrv.On("Find", mock.Anything, 1).Return(secret, nil)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			shareSrv := new(shareServiceMock)
+			shareSrv.On("Share", mock.Anything, mock.Anything, secret, mock.Anything, tc.access).
+				Return(tc.shareRes.share, tc.shareRes.err)
+			handler := http.HandlerFunc(handlers.NewSecretHandler(zaptest.NewLogger(t)).Share(findSrv, shareSrv))
+
+			request, err := http.NewRequest(http.MethodPost, "/api/secrets/1/shares", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
+
+func TestShares(t *testing.T) {
+	timestamp := time.Date(2024, 5, 14, 9, 23, 10, 0, time.UTC)
+	secret := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret}
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	findSrv := new(findSecretServiceMock)
+	findSrv.On("Find", mock.Anything, 1).Return(secret, nil)
+	shareSrv := new(shareServiceMock)
+	shareSrv.On("List", mock.Anything, mock.Anything, secret).Return(
+		[]models.SecretShare{
+			{SecretID: 1, UserID: 2, Login: "bob", Access: models.SecretReadAccess, CreatedAt: timestamp},
+		},
+		nil,
+	)
+	handler := http.HandlerFunc(handlers.NewSecretHandler(zaptest.NewLogger(t)).Shares(findSrv, shareSrv))
+
+	request, err := http.NewRequest(http.MethodGet, "/api/secrets/1/shares", nil)
+	require.NoError(t, err)
+	request.AddCookie(&http.Cookie{Name: "jwt", Value: jwtStr})
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	assert.Equal(
+		t,
+		`{"shares":[{"user_id":2,"login":"bob","access":"read","created_at":"2024-05-14T09:23:10Z"}]}`+"\n",
+		recorder.Body.String(),
+	)
+}
+
+func TestRevokeShare(t *testing.T) {
+	secret := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret}
+	testCases := []struct {
+		name        string
+		recipientID string
+		revokeErr   error
+		wantCode    int
+	}{
+		{
+			name:        "responds with ok status",
+			recipientID: "2",
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "responds with bad request if user id is invalid",
+			recipientID: "bob",
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name:        "responds with forbidden status",
+			recipientID: "2",
+			revokeErr:   services.ErrNoPermission{UserID: 3, SecretID: 1},
+			wantCode:    http.StatusForbidden,
+		},
+		{
+			name:        "responds with not found status if secret is not shared",
+			recipientID: "2",
+			revokeErr:   storage.ErrSecretShareNotFound{Share: models.SecretShare{SecretID: 1, UserID: 2}},
+			wantCode:    http.StatusNotFound,
+		},
+	}
+
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	findSrv := new(findSecretServiceMock)
+	findSrv.On("Find", mock.Anything, 1).Return(secret, nil)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			shareSrv := new(shareServiceMock)
+			shareSrv.On("Revoke", mock.Anything, mock.Anything, secret, 2).Return(tc.revokeErr)
+			handler := http.HandlerFunc(handlers.NewSecretHandler(zaptest.NewLogger(t)).RevokeShare(findSrv, shareSrv))
+
+			request, err := http.NewRequest(http.MethodDelete, "/api/secrets/1/shares/"+tc.recipientID, nil)
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			rctx.URLParams.Add("userID", tc.recipientID)
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.wantCode, recorder.Result().StatusCode)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"go.uber.org/zap"
+)
+
+type SyncService interface {
+	Changes(ctx context.Context, userID int, since int, limit int) (services.SyncChanges, error)
+}
+
+// Sync responds with secrets created, updated and deleted after the
+// revision from the since query parameter. Bin data content is not
+// included, it should be downloaded with Get. Only personal secrets of the
+// user are synced, secrets shared with the user and vault secrets are not.
+func (h SecretHandler) Sync(syncSrv SyncService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowedUnlimitedScope(w, r) {
+			return
+		}
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		query := r.URL.Query()
+		since := 0
+		if sinceStr := query.Get("since"); sinceStr != "" {
+			var err error
+			since, err = strconv.Atoi(sinceStr)
+			if err != nil || since < 0 {
+				h.logger.Info("invalid sync revision", zap.String("since", sinceStr))
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		limit := 0
+		if limitStr := query.Get("limit"); limitStr != "" {
+			var err error
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit < 0 {
+				h.logger.Info("invalid sync limit", zap.String("limit", limitStr))
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		changes, err := syncSrv.Changes(r.Context(), userID, since, limit)
+		if err != nil {
+			h.logger.Info("failed to sync secrets", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := syncResponse{
+			Revision: changes.Revision,
+			HasMore:  changes.HasMore,
+			Created:  newSyncedSecretsResponse(changes.Created),
+			Updated:  newSyncedSecretsResponse(changes.Updated),
+			Deleted:  changes.Deleted,
+		}
+		if response.Deleted == nil {
+			response.Deleted = []int{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
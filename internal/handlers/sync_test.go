@@ -0,0 +1,138 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type syncServiceMock struct{ mock.Mock }
+
+func (m *syncServiceMock) Changes(ctx context.Context, userID int, since int, limit int) (services.SyncChanges, error) {
+	args := m.Called(ctx, userID, since, limit)
+	return args.Get(0).(services.SyncChanges), args.Error(1)
+}
+
+func TestSync(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+	}
+	type changesResult struct {
+		changes services.SyncChanges
+		err     error
+	}
+	testCases := []struct {
+		name          string
+		query         string
+		expectedSince int
+		expectedLimit int
+		changesRes    changesResult
+		want          want
+	}{
+		{
+			name:          "responds with secret changes",
+			query:         "?since=3&limit=10",
+			expectedSince: 3,
+			expectedLimit: 10,
+			changesRes: changesResult{
+				changes: services.SyncChanges{
+					Revision: 6,
+					Created: []services.SyncedSecret{
+						{
+							Secret: models.Secret{
+								ID:          1,
+								UserID:      1,
+								SecretType:  models.CredentialsSecret,
+								Description: "mail",
+								FolderID:    2,
+							},
+							Revision: 4,
+							Data: &models.Credentials{
+								ID:          1,
+								Description: "mail",
+								Login:       "login",
+								Password:    "password",
+							},
+						},
+					},
+					Deleted: []int{3},
+				},
+			},
+			want: want{
+				code: http.StatusOK,
+				response: `{"revision":6,"has_more":false,"created":[{"id":1,"secret_type":"credentials",` +
+					`"description":"mail","folder_id":2,"revision":4,"data":{"ID":1,"Description":"mail",` +
+					`"Login":"login","Password":"password"}}],"updated":[],"deleted":[3]}` + "\n",
+			},
+		},
+		{
+			name: "syncs from the beginning without since",
+			changesRes: changesResult{
+				changes: services.SyncChanges{Revision: 0},
+			},
+			want: want{
+				code:     http.StatusOK,
+				response: `{"revision":0,"has_more":false,"created":[],"updated":[],"deleted":[]}` + "\n",
+			},
+		},
+		{
+			name:  "responds with bad request if since is invalid",
+			query: "?since=-1",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:  "responds with bad request if limit is invalid",
+			query: "?limit=many",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "responds with internal server error",
+			changesRes: changesResult{
+				err: errors.New("error"),
+			},
+			want: want{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			syncSrv := new(syncServiceMock)
+			syncSrv.On("Changes", mock.Anything, mock.Anything, tc.expectedSince, tc.expectedLimit).
+				Return(tc.changesRes.changes, tc.changesRes.err)
+			handler := http.HandlerFunc(handlers.NewSecretHandler(zaptest.NewLogger(t)).Sync(syncSrv))
+
+			request, err := http.NewRequest(http.MethodGet, "/api/sync"+tc.query, nil)
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"go.uber.org/zap"
+)
+
+type TagService interface {
+	Create(ctx context.Context, userID int, name string) (models.Tag, error)
+	List(ctx context.Context, userID int) ([]models.Tag, error)
+	Rename(ctx context.Context, userID int, tagID int, name string) (models.Tag, error)
+}
+
+type tagPayload struct {
+	Name string `json:"name"`
+}
+
+type tagResponse struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type tagsIndexResponse struct {
+	Tags []tagResponse `json:"tags"`
+}
+
+type TagHandler struct {
+	logger *zap.Logger
+}
+
+func NewTagHandler(logger *zap.Logger) TagHandler {
+	return TagHandler{
+		logger: logger,
+	}
+}
+
+func (h TagHandler) Create(srv TagService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		var payload tagPayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid tag request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		tag, err := srv.Create(r.Context(), userID, payload.Name)
+		if err != nil {
+			h.writeError(w, "failed to create tag", err)
+			return
+		}
+
+		h.writeTag(w, http.StatusCreated, tag)
+	}
+}
+
+func (h TagHandler) Index(srv TagService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		tags, err := srv.List(r.Context(), userID)
+		if err != nil {
+			h.logger.Info("failed to list tags", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := tagsIndexResponse{Tags: make([]tagResponse, len(tags))}
+		for i, tag := range tags {
+			response.Tags[i] = tagResponse{ID: tag.ID, Name: tag.Name}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+func (h TagHandler) Rename(srv TagService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		tagID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			h.logger.Info("invalid tag id", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var payload tagPayload
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxFormValueSize)).Decode(&payload); err != nil {
+			h.logger.Info("invalid tag request", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		tag, err := srv.Rename(r.Context(), userID, tagID, payload.Name)
+		if err != nil {
+			var notFoundErr storage.ErrTagNotFound
+			if errors.As(err, &notFoundErr) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			h.writeError(w, "failed to rename tag", err)
+			return
+		}
+
+		h.writeTag(w, http.StatusOK, tag)
+	}
+}
+
+func (h TagHandler) writeError(w http.ResponseWriter, msg string, err error) {
+	var notUniqErr storage.ErrTagNotUniq
+	switch {
+	case errors.Is(err, services.ErrInvalidTagName):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.As(err, &notUniqErr):
+		w.WriteHeader(http.StatusConflict)
+	default:
+		h.logger.Info(msg, zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (h TagHandler) writeTag(w http.ResponseWriter, status int, tag models.Tag) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(tagResponse{ID: tag.ID, Name: tag.Name}); err != nil {
+		h.logger.Info("failed to encode response", zap.Error(err))
+	}
+}
@@ -0,0 +1,206 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type tagServiceMock struct{ mock.Mock }
+
+func (m *tagServiceMock) Create(ctx context.Context, userID int, name string) (models.Tag, error) {
+	args := m.Called(ctx, userID, name)
+	return args.Get(0).(models.Tag), args.Error(1)
+}
+
+func (m *tagServiceMock) List(ctx context.Context, userID int) ([]models.Tag, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Tag), args.Error(1)
+}
+
+func (m *tagServiceMock) Rename(ctx context.Context, userID int, tagID int, name string) (models.Tag, error) {
+	args := m.Called(ctx, userID, tagID, name)
+	return args.Get(0).(models.Tag), args.Error(1)
+}
+
+func (m *tagServiceMock) SetSecretTags(ctx context.Context, userID int, secret models.Secret, tagIDs []int) error {
+	args := m.Called(ctx, userID, secret, tagIDs)
+	return args.Error(0)
+}
+
+func TestRenameTag(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+	}
+	type renameResult struct {
+		tag models.Tag
+		err error
+	}
+	testCases := []struct {
+		name        string
+		requestBody string
+		renameRes   renameResult
+		want        want
+	}{
+		{
+			name:        "responds with ok status",
+			requestBody: `{"name":"prod"}`,
+			renameRes: renameResult{
+				tag: models.Tag{ID: 1, UserID: 1, Name: "prod"},
+			},
+			want: want{
+				code:     http.StatusOK,
+				response: "{\"id\":1,\"name\":\"prod\"}\n",
+			},
+		},
+		{
+			name:        "responds with not found status",
+			requestBody: `{"name":"prod"}`,
+			renameRes: renameResult{
+				err: storage.ErrTagNotFound{Tag: models.Tag{ID: 1}},
+			},
+			want: want{
+				code: http.StatusNotFound,
+			},
+		},
+		{
+			name:        "responds with conflict if tag already exists",
+			requestBody: `{"name":"prod"}`,
+			renameRes: renameResult{
+				err: storage.ErrTagNotUniq{Tag: models.Tag{Name: "prod"}},
+			},
+			want: want{
+				code: http.StatusConflict,
+			},
+		},
+		{
+			name:        "responds with bad request if name is empty",
+			requestBody: `{"name":""}`,
+			renameRes: renameResult{
+				err: services.ErrInvalidTagName,
+			},
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:        "responds with internal server error",
+			requestBody: `{"name":"prod"}`,
+			renameRes: renameResult{
+				err: errors.New("error"),
+			},
+			want: want{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	tagSrv := new(tagServiceMock)
+	handler := http.HandlerFunc(handlers.NewTagHandler(zaptest.NewLogger(t)).Rename(tagSrv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			renameCall := tagSrv.On("Rename", mock.Anything, mock.Anything, 1, mock.Anything).
+				Return(tc.renameRes.tag, tc.renameRes.err)
+			defer renameCall.Unset()
+
+			request, err := http.NewRequest(http.MethodPatch, "/api/tags/1", strings.NewReader(tc.requestBody))
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			request.Header.Set("Content-Type", "application/json")
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
+
+func TestSetSecretTags(t *testing.T) {
+	type want struct {
+		code int
+	}
+	secret := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret}
+	testCases := []struct {
+		name        string
+		requestBody string
+		tagIDs      []int
+		setErr      error
+		want        want
+	}{
+		{
+			name:        "responds with ok status",
+			requestBody: `{"tag_ids":[1,2]}`,
+			tagIDs:      []int{1, 2},
+			want:        want{code: http.StatusOK},
+		},
+		{
+			name:        "responds with bad request if tag is not found",
+			requestBody: `{"tag_ids":[3]}`,
+			tagIDs:      []int{3},
+			setErr:      storage.ErrTagNotFound{Tag: models.Tag{ID: 3}},
+			want:        want{code: http.StatusBadRequest},
+		},
+		{
+			name:        "responds with forbidden status",
+			requestBody: `{"tag_ids":[1]}`,
+			tagIDs:      []int{1},
+			setErr:      services.ErrNoPermission{UserID: 1, SecretID: 1},
+			want:        want{code: http.StatusForbidden},
+		},
+	}
+
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	findSrv := new(findSecretServiceMock)
+	findSrv.On("Find", mock.Anything, 1).Return(secret, nil)
+	tagSrv := new(tagServiceMock)
+	handler := http.HandlerFunc(handlers.NewSecretHandler(zaptest.NewLogger(t)).SetTags(findSrv, tagSrv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setCall := tagSrv.On("SetSecretTags", mock.Anything, mock.Anything, secret, tc.tagIDs).
+				Return(tc.setErr)
+			defer setCall.Unset()
+
+			request, err := http.NewRequest(http.MethodPut, "/api/secrets/1/tags", strings.NewReader(tc.requestBody))
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			request.Header.Set("Content-Type", "application/json")
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+type TrashService interface {
+	List(ctx context.Context, userID int) ([]models.SecretInfo, error)
+	Find(ctx context.Context, id int) (models.Secret, error)
+	Restore(ctx context.Context, userID int, secret models.Secret) error
+}
+
+func (h SecretHandler) TrashIndex(trashSrv TrashService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		secrets, err := trashSrv.List(r.Context(), userID)
+		if err != nil {
+			h.logger.Info("failed to list trash", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := trashIndexResponse{
+			Secrets: make([]trashedSecretResponse, 0, len(secrets)),
+		}
+		scope, _ := requestScope(r)
+		for _, secret := range secrets {
+			if !scope.AllowsSecret(secret.ID, secret.SecretType) {
+				continue
+			}
+			response.Secrets = append(response.Secrets, trashedSecretResponse{
+				secretInfoResponse: secretInfoResponse{
+					ID:          secret.ID,
+					SecretType:  secret.SecretType.String(),
+					Description: secret.Description,
+					Size:        secret.Size,
+					FolderID:    secret.FolderID,
+					CreatedAt:   secret.CreatedAt,
+					UpdatedAt:   secret.UpdatedAt,
+				},
+				DeletedAt: secret.DeletedAt,
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// RestoreTrashed moves the secret out of the trash.
+func (h SecretHandler) RestoreTrashed(trashSrv TrashService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		secret, ok := h.findSecret(w, r, trashSrv)
+		if !ok {
+			return
+		}
+		if !allowedByScope(w, r, secret.ID, secret.SecretType, true) {
+			return
+		}
+		if err := trashSrv.Restore(r.Context(), userID, secret); err != nil {
+			h.writeDeleteError(w, err)
+			return
+		}
+
+		h.writeSecret(w, http.StatusOK, secret)
+	}
+}
+
+// PurgeTrashed deletes the trashed secret permanently.
+func (h SecretHandler) PurgeTrashed(
+	trashSrv TrashService,
+	deleteSrv DeleteSecretService) func(http.ResponseWriter, *http.Request) {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		secret, ok := h.findSecret(w, r, trashSrv)
+		if !ok {
+			return
+		}
+		if !allowedByScope(w, r, secret.ID, secret.SecretType, true) {
+			return
+		}
+		if err := deleteSrv.Purge(r.Context(), userID, secret); err != nil {
+			h.writeDeleteError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
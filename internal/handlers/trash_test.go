@@ -0,0 +1,225 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type trashServiceMock struct{ mock.Mock }
+
+func (m *trashServiceMock) List(ctx context.Context, userID int) ([]models.SecretInfo, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.SecretInfo), args.Error(1)
+}
+
+func (m *trashServiceMock) Find(ctx context.Context, id int) (models.Secret, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Secret), args.Error(1)
+}
+
+func (m *trashServiceMock) Restore(ctx context.Context, userID int, secret models.Secret) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func TestTrashIndex(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+	}
+	type listResult struct {
+		secrets []models.SecretInfo
+		err     error
+	}
+	timestamp := time.Date(2024, 5, 7, 9, 35, 21, 0, time.UTC)
+	testCases := []struct {
+		name    string
+		listRes listResult
+		want    want
+	}{
+		{
+			name: "responds with trashed secrets",
+			listRes: listResult{
+				secrets: []models.SecretInfo{
+					{
+						ID:          1,
+						SecretType:  models.CredentialsSecret,
+						Description: "mail",
+						Size:        40,
+						CreatedAt:   timestamp,
+						UpdatedAt:   timestamp,
+						DeletedAt:   timestamp,
+					},
+				},
+			},
+			want: want{
+				code: http.StatusOK,
+				response: `{"secrets":[{"id":1,"secret_type":"credentials","description":"mail","size":40,` +
+					`"created_at":"2024-05-07T09:35:21Z","updated_at":"2024-05-07T09:35:21Z",` +
+					`"deleted_at":"2024-05-07T09:35:21Z"}]}` + "\n",
+			},
+		},
+		{
+			name: "responds with internal server error",
+			listRes: listResult{
+				err: errors.New("error"),
+			},
+			want: want{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	trashSrv := new(trashServiceMock)
+	handler := http.HandlerFunc(handlers.NewSecretHandler(zaptest.NewLogger(t)).TrashIndex(trashSrv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			listCall := trashSrv.On("List", mock.Anything, mock.Anything).
+				Return(tc.listRes.secrets, tc.listRes.err)
+			defer listCall.Unset()
+
+			request, err := http.NewRequest(http.MethodGet, "/api/trash", nil)
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
+
+func TestRestoreTrashed(t *testing.T) {
+	type findResult struct {
+		secret models.Secret
+		err    error
+	}
+	secret := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret, Description: "mail"}
+	testCases := []struct {
+		name       string
+		findRes    findResult
+		restoreErr error
+		wantCode   int
+	}{
+		{
+			name:     "responds with ok status",
+			findRes:  findResult{secret: secret},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "responds with not found status if secret is not in trash",
+			findRes: findResult{
+				err: storage.ErrSecretNotFound{Secret: models.Secret{ID: 1}},
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:       "responds with forbidden status",
+			findRes:    findResult{secret: secret},
+			restoreErr: services.ErrNoPermission{UserID: 2, SecretID: 1},
+			wantCode:   http.StatusForbidden,
+		},
+	}
+
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	trashSrv := new(trashServiceMock)
+	handler := http.HandlerFunc(handlers.NewSecretHandler(zaptest.NewLogger(t)).RestoreTrashed(trashSrv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			findCall := trashSrv.On("Find", mock.Anything, 1).Return(tc.findRes.secret, tc.findRes.err)
+			defer findCall.Unset()
+			restoreCall := trashSrv.On("Restore", mock.Anything, mock.Anything, secret).Return(tc.restoreErr)
+			defer restoreCall.Unset()
+
+			request, err := http.NewRequest(http.MethodPost, "/api/trash/1/restore", nil)
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.wantCode, recorder.Result().StatusCode)
+		})
+	}
+}
+
+func TestPurgeTrashed(t *testing.T) {
+	secret := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret}
+	testCases := []struct {
+		name     string
+		purgeErr error
+		wantCode int
+	}{
+		{
+			name:     "responds with ok status",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "responds with forbidden status",
+			purgeErr: services.ErrNoPermission{UserID: 2, SecretID: 1},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "responds with internal server error",
+			purgeErr: errors.New("error"),
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+
+	jwtStr, err := auth.BuildJWTString(1, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	trashSrv := new(trashServiceMock)
+	trashSrv.On("Find", mock.Anything, 1).Return(secret, nil)
+	delSrv := new(deleteServiceMock)
+	handler := http.HandlerFunc(handlers.NewSecretHandler(zaptest.NewLogger(t)).PurgeTrashed(trashSrv, delSrv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			purgeCall := delSrv.On("Purge", mock.Anything, mock.Anything, secret).Return(tc.purgeErr)
+			defer purgeCall.Unset()
+
+			request, err := http.NewRequest(http.MethodDelete, "/api/trash/1", nil)
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.wantCode, recorder.Result().StatusCode)
+		})
+	}
+}
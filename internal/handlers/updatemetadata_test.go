@@ -0,0 +1,190 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type metadataServiceMock struct{ mock.Mock }
+
+func (m *metadataServiceMock) Update(
+	ctx context.Context,
+	userID int,
+	secret models.Secret,
+	metadata models.Metadata) (int, error) {
+
+	args := m.Called(ctx, userID, secret, metadata)
+	return args.Int(0), args.Error(1)
+}
+
+func TestUpdateSecretMetadata(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+		etag     string
+	}
+	type findResult struct {
+		secret models.Secret
+		err    error
+	}
+	secret := models.Secret{
+		ID:          1,
+		UserID:      1,
+		SecretType:  models.CredentialsSecret,
+		Description: "description",
+		Version:     3,
+	}
+	testCases := []struct {
+		name        string
+		requestBody string
+		ifMatch     string
+		findRes     findResult
+		metadata    models.Metadata
+		updateErr   error
+		want        want
+	}{
+		{
+			name:        "responds with ok status",
+			requestBody: `{"metadata":{"env":"prod","owner":"payments-team"}}`,
+			findRes:     findResult{secret: secret},
+			metadata:    models.Metadata{"env": "prod", "owner": "payments-team"},
+			want: want{
+				code:     http.StatusOK,
+				response: "{\"id\":1,\"secret_type\":\"credentials\",\"description\":\"description\"}\n",
+				etag:     `"4"`,
+			},
+		},
+		{
+			name:        "responds with precondition failed if secret does not match If-Match",
+			requestBody: `{"metadata":{"env":"prod"}}`,
+			ifMatch:     `"2"`,
+			findRes:     findResult{secret: secret},
+			want: want{
+				code: http.StatusPreconditionFailed,
+			},
+		},
+		{
+			name:        "responds with precondition failed if secret has been changed concurrently",
+			requestBody: `{"metadata":{"env":"prod"}}`,
+			ifMatch:     `"3"`,
+			findRes:     findResult{secret: secret},
+			metadata:    models.Metadata{"env": "prod"},
+			updateErr:   storage.ErrSecretVersionMismatch{Secret: models.Secret{ID: 1, Version: 3}},
+			want: want{
+				code: http.StatusPreconditionFailed,
+			},
+		},
+		{
+			name:        "responds with bad request if metadata key is empty",
+			requestBody: `{"metadata":{"":"value"}}`,
+			findRes:     findResult{secret: secret},
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:        "responds with bad request if body is invalid",
+			requestBody: `{"metadata":["env"]}`,
+			findRes:     findResult{secret: secret},
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:        "responds with not found status",
+			requestBody: `{"metadata":{}}`,
+			findRes: findResult{
+				err: storage.ErrSecretNotFound{Secret: models.Secret{ID: 1}},
+			},
+			want: want{
+				code: http.StatusNotFound,
+			},
+		},
+		{
+			name:        "responds with forbidden status",
+			requestBody: `{"metadata":{}}`,
+			findRes:     findResult{secret: secret},
+			metadata:    models.Metadata{},
+			updateErr:   services.ErrNoPermission{UserID: 2, SecretID: 1},
+			want: want{
+				code: http.StatusForbidden,
+			},
+		},
+		{
+			name:        "responds with internal server error",
+			requestBody: `{"metadata":{"env":"prod"}}`,
+			findRes:     findResult{secret: secret},
+			metadata:    models.Metadata{"env": "prod"},
+			updateErr:   errors.New("error"),
+			want: want{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	userID := 1
+	jwtStr, err := auth.BuildJWTString(userID, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	findSrv := new(findSecretServiceMock)
+	metadataSrv := new(metadataServiceMock)
+	handler := http.HandlerFunc(
+		handlers.NewSecretHandler(zaptest.NewLogger(t)).
+			UpdateMetadata(findSrv, metadataSrv),
+	)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			findCall := findSrv.On("Find", mock.Anything, mock.Anything).
+				Return(tc.findRes.secret, tc.findRes.err)
+			defer findCall.Unset()
+			// without If-Match the secret is updated whatever its version is
+			secret := tc.findRes.secret
+			if tc.ifMatch == "" {
+				secret.Version = 0
+			}
+			updateCall := metadataSrv.On("Update", mock.Anything, mock.Anything, secret, tc.metadata).
+				Return(4, tc.updateErr)
+			defer updateCall.Unset()
+
+			request, err := http.NewRequest(
+				http.MethodPut,
+				"/api/secrets/1/metadata",
+				strings.NewReader(tc.requestBody),
+			)
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			request.Header.Set("Content-Type", "application/json")
+			if tc.ifMatch != "" {
+				request.Header.Set("If-Match", tc.ifMatch)
+			}
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", strconv.Itoa(1))
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+			assert.Equal(t, tc.want.etag, recorder.Header().Get("ETag"))
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
 	"github.com/ilya-burinskiy/gophkeeper/internal/models"
 	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -30,16 +31,18 @@ func (m *updateServiceMock) Update(
 	newSecretType models.SecretType,
 	description string,
 	marshallableSecret services.Marshaller,
-	encryptedKey []byte) error {
+	metadata models.Metadata,
+	encryptedKey []byte) (int, error) {
 
-	args := m.Called(ctx, userID, secret, newSecretType, description, marshallableSecret, encryptedKey)
-	return args.Error(0)
+	args := m.Called(ctx, userID, secret, newSecretType, description, marshallableSecret, metadata, encryptedKey)
+	return args.Int(0), args.Error(1)
 }
 
 func TestUpdateCredentials(t *testing.T) {
 	type want struct {
 		code     int
 		response string
+		etag     string
 	}
 	type findResult struct {
 		secret models.Secret
@@ -50,6 +53,7 @@ func TestUpdateCredentials(t *testing.T) {
 		secretID  int
 		login     string
 		password  string
+		ifMatch   string
 		findRes   findResult
 		updateErr error
 		want      want
@@ -67,7 +71,84 @@ func TestUpdateCredentials(t *testing.T) {
 				},
 			},
 			want: want{
-				code: http.StatusOK,
+				code:     http.StatusOK,
+				response: "{\"id\":1,\"secret_type\":\"credentials\",\"description\":\"\"}\n",
+				etag:     `"8"`,
+			},
+		},
+		{
+			name:     "updates secret if it matches If-Match",
+			secretID: 1,
+			login:    "login",
+			password: "password",
+			ifMatch:  `"7"`,
+			findRes: findResult{
+				secret: models.Secret{
+					ID:         1,
+					UserID:     1,
+					SecretType: models.CredentialsSecret,
+					Version:    7,
+				},
+			},
+			want: want{
+				code:     http.StatusOK,
+				response: "{\"id\":1,\"secret_type\":\"credentials\",\"description\":\"\"}\n",
+				etag:     `"8"`,
+			},
+		},
+		{
+			name:     "responds with precondition failed if If-Match is a weak tag",
+			secretID: 1,
+			login:    "login",
+			password: "password",
+			ifMatch:  `W/"7"`,
+			findRes: findResult{
+				secret: models.Secret{
+					ID:         1,
+					UserID:     1,
+					SecretType: models.CredentialsSecret,
+					Version:    7,
+				},
+			},
+			want: want{
+				code: http.StatusPreconditionFailed,
+			},
+		},
+		{
+			name:     "responds with precondition failed if secret does not match If-Match",
+			secretID: 1,
+			login:    "login",
+			password: "password",
+			ifMatch:  `"6"`,
+			findRes: findResult{
+				secret: models.Secret{
+					ID:         1,
+					UserID:     1,
+					SecretType: models.CredentialsSecret,
+					Version:    7,
+				},
+			},
+			want: want{
+				code: http.StatusPreconditionFailed,
+			},
+		},
+		{
+			name:     "responds with precondition failed if secret has been changed concurrently",
+			secretID: 1,
+			login:    "login",
+			password: "password",
+			ifMatch:  `"7"`,
+			findRes: findResult{
+				secret: models.Secret{
+					ID:         1,
+					UserID:     1,
+					SecretType: models.CredentialsSecret,
+					Version:    7,
+				},
+			},
+			updateErr: storage.ErrSecretVersionMismatch{Secret: models.Secret{ID: 1, Version: 7}},
+			want: want{
+				code: http.StatusPreconditionFailed,
 			},
 		},
 		{
@@ -110,7 +191,7 @@ func TestUpdateCredentials(t *testing.T) {
 	}
 
 	userID := 1
-	jwtStr, err := auth.BuildJWTString(userID)
+	jwtStr, err := auth.BuildJWTString(userID, 1)
 	require.NoError(t, err)
 	authCookie := &http.Cookie{
 		Name:  "jwt",
@@ -118,9 +199,10 @@ func TestUpdateCredentials(t *testing.T) {
 	}
 	findSrv := new(findSecretServiceMock)
 	updateSrv := new(updateServiceMock)
+	binDataSrv := new(binDataServiceMock)
 	handler := http.HandlerFunc(
 		handlers.NewSecretHandler(zaptest.NewLogger(t)).
-			Update(findSrv, updateSrv),
+			Update(findSrv, updateSrv, binDataSrv),
 	)
 
 	for _, tc := range testCases {
@@ -129,8 +211,8 @@ func TestUpdateCredentials(t *testing.T) {
 				Return(tc.findRes.secret, tc.findRes.err)
 			defer findCall.Unset()
 			updateCall := updateSrv.
-				On("Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
-				Return(tc.updateErr)
+				On("Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(8, tc.updateErr)
 			defer updateCall.Unset()
 
 			reqBody := bytes.Buffer{}
@@ -152,12 +234,16 @@ func TestUpdateCredentials(t *testing.T) {
 			rctx.URLParams.Add("id", strconv.Itoa(tc.secretID))
 			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
 			request.Header.Add("Content-Type", writer.FormDataContentType())
+			if tc.ifMatch != "" {
+				request.Header.Set("If-Match", tc.ifMatch)
+			}
 
 			recorder := httptest.NewRecorder()
 			handler.ServeHTTP(recorder, request)
 
 			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
 			assert.Equal(t, tc.want.response, recorder.Body.String())
+			assert.Equal(t, tc.want.etag, recorder.Header().Get("ETag"))
 		})
 	}
 }
@@ -196,7 +282,8 @@ func TestUpdateCreditCard(t *testing.T) {
 				},
 			},
 			want: want{
-				code: http.StatusOK,
+				code:     http.StatusOK,
+				response: "{\"id\":1,\"secret_type\":\"credit_card_info\",\"description\":\"\"}\n",
 			},
 		},
 		{
@@ -241,7 +328,7 @@ func TestUpdateCreditCard(t *testing.T) {
 	}
 
 	userID := 1
-	jwtStr, err := auth.BuildJWTString(userID)
+	jwtStr, err := auth.BuildJWTString(userID, 1)
 	require.NoError(t, err)
 	authCookie := &http.Cookie{
 		Name:  "jwt",
@@ -249,9 +336,10 @@ func TestUpdateCreditCard(t *testing.T) {
 	}
 	findSrv := new(findSecretServiceMock)
 	updateSrv := new(updateServiceMock)
+	binDataSrv := new(binDataServiceMock)
 	handler := http.HandlerFunc(
 		handlers.NewSecretHandler(zaptest.NewLogger(t)).
-			Update(findSrv, updateSrv),
+			Update(findSrv, updateSrv, binDataSrv),
 	)
 
 	for _, tc := range testCases {
@@ -260,8 +348,8 @@ func TestUpdateCreditCard(t *testing.T) {
 				Return(tc.findRes.secret, tc.findRes.err)
 			defer findCall.Unset()
 			updateCall := updateSrv.
-				On("Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
-				Return(tc.updateErr)
+				On("Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(8, tc.updateErr)
 			defer updateCall.Unset()
 
 			reqBody := bytes.Buffer{}
@@ -324,7 +412,8 @@ func TestUpdateBinData(t *testing.T) {
 				},
 			},
 			want: want{
-				code: http.StatusOK,
+				code:     http.StatusOK,
+				response: "{\"id\":1,\"secret_type\":\"bin_data\",\"description\":\"\"}\n",
 			},
 		},
 		{
@@ -345,6 +434,21 @@ func TestUpdateBinData(t *testing.T) {
 				code: http.StatusForbidden,
 			},
 		},
+		{
+			name:        "responds with request entity too large",
+			fileContent: []byte{0x1, 0x2, 0x3},
+			findRes: findResult{
+				secret: models.Secret{
+					ID:         1,
+					SecretType: models.BinDataSecret,
+					UserID:     1,
+				},
+			},
+			updateErr: services.ErrBinDataTooLarge,
+			want: want{
+				code: http.StatusRequestEntityTooLarge,
+			},
+		},
 		{
 			name:        "responds with internal server error",
 			fileContent: []byte{0x1, 0x2, 0x3},
@@ -363,7 +467,7 @@ func TestUpdateBinData(t *testing.T) {
 	}
 
 	userID := 1
-	jwtStr, err := auth.BuildJWTString(userID)
+	jwtStr, err := auth.BuildJWTString(userID, 1)
 	require.NoError(t, err)
 	authCookie := &http.Cookie{
 		Name:  "jwt",
@@ -371,9 +475,10 @@ func TestUpdateBinData(t *testing.T) {
 	}
 	findSrv := new(findSecretServiceMock)
 	updateSrv := new(updateServiceMock)
+	binDataSrv := new(binDataServiceMock)
 	handler := http.HandlerFunc(
 		handlers.NewSecretHandler(zaptest.NewLogger(t)).
-			Update(findSrv, updateSrv),
+			Update(findSrv, updateSrv, binDataSrv),
 	)
 
 	for _, tc := range testCases {
@@ -381,9 +486,9 @@ func TestUpdateBinData(t *testing.T) {
 			findCall := findSrv.On("Find", mock.Anything, mock.Anything).
 				Return(tc.findRes.secret, tc.findRes.err)
 			defer findCall.Unset()
-			updateCall := updateSrv.
-				On("Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
-				Return(tc.updateErr)
+			updateCall := binDataSrv.
+				On("Update", mock.Anything, mock.Anything, tc.findRes.secret, "", "file", tc.fileContent, mock.Anything).
+				Return(8, tc.updateErr)
 			defer updateCall.Unset()
 
 			reqBody := bytes.Buffer{}
@@ -395,8 +500,6 @@ func TestUpdateBinData(t *testing.T) {
 			require.NoError(t, err)
 			err = writer.Close()
 			require.NoError(t, err)
-			err = writer.Close()
-			require.NoError(t, err)
 
 			request, err := http.NewRequest(
 				http.MethodPut,
@@ -419,3 +522,152 @@ func TestUpdateBinData(t *testing.T) {
 	}
 
 }
+
+func TestUpdateSecretJSON(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+	}
+	testCases := []struct {
+		name        string
+		secretID    int
+		requestBody []byte
+		secret      models.Secret
+		updateErr   error
+		want        want
+	}{
+		{
+			name:     "responds with updated secret",
+			secretID: 1,
+			requestBody: toJSON(t, map[string]interface{}{
+				"secret_type": "credentials",
+				"description": "new description",
+				"data":        map[string]string{"login": "login", "password": "password"},
+			}),
+			secret: models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret},
+			want: want{
+				code:     http.StatusOK,
+				response: "{\"id\":1,\"secret_type\":\"credentials\",\"description\":\"new description\"}\n",
+			},
+		},
+		{
+			name:     "responds with bad request if secret type changes",
+			secretID: 1,
+			requestBody: toJSON(t, map[string]interface{}{
+				"secret_type": "credit_card_info",
+				"data":        map[string]string{"number": "1234"},
+			}),
+			secret:    models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret},
+			updateErr: services.ErrWrongSecretType,
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name:        "responds with bad request if body is invalid",
+			secretID:    1,
+			requestBody: []byte("{"),
+			secret:      models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret},
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+	}
+
+	userID := 1
+	jwtStr, err := auth.BuildJWTString(userID, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	findSrv := new(findSecretServiceMock)
+	updateSrv := new(updateServiceMock)
+	binDataSrv := new(binDataServiceMock)
+	handler := http.HandlerFunc(
+		handlers.NewSecretHandler(zaptest.NewLogger(t)).
+			Update(findSrv, updateSrv, binDataSrv),
+	)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			findCall := findSrv.On("Find", mock.Anything, mock.Anything).
+				Return(tc.secret, nil)
+			defer findCall.Unset()
+			updateCall := updateSrv.
+				On("Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(8, tc.updateErr)
+			defer updateCall.Unset()
+
+			request, err := http.NewRequest(
+				http.MethodPatch,
+				"/api/secrets/"+strconv.Itoa(tc.secretID),
+				bytes.NewReader(tc.requestBody),
+			)
+			require.NoError(t, err)
+			request.AddCookie(authCookie)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", strconv.Itoa(tc.secretID))
+			request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+			request.Header.Add("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+		})
+	}
+}
+
+func TestUpdateSecretConcurrentlyWithoutIfMatch(t *testing.T) {
+	userID := 1
+	jwtStr, err := auth.BuildJWTString(userID, 1)
+	require.NoError(t, err)
+	authCookie := &http.Cookie{
+		Name:  "jwt",
+		Value: jwtStr,
+	}
+	// both clients find the secret of version 7, the second one updates it
+	// after the first one has changed its version to 8
+	findSrv := new(findSecretServiceMock)
+	findSrv.On("Find", mock.Anything, 1).
+		Return(models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret, Version: 7}, nil)
+	// clients not sending If-Match do not ask for the version check
+	unchecked := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret}
+	updateSrv := new(updateServiceMock)
+	updateSrv.
+		On("Update", mock.Anything, mock.Anything, unchecked, models.CredentialsSecret, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(8, nil).Once()
+	updateSrv.
+		On("Update", mock.Anything, mock.Anything, unchecked, models.CredentialsSecret, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(9, nil).Once()
+	handler := http.HandlerFunc(
+		handlers.NewSecretHandler(zaptest.NewLogger(t)).
+			Update(findSrv, updateSrv, new(binDataServiceMock)),
+	)
+
+	for _, wantETag := range []string{`"8"`, `"9"`} {
+		request, err := http.NewRequest(
+			http.MethodPatch,
+			"/api/secrets/1",
+			bytes.NewReader(toJSON(t, map[string]interface{}{
+				"secret_type": "credentials",
+				"data":        map[string]string{"login": "login", "password": "password"},
+			})),
+		)
+		require.NoError(t, err)
+		request.AddCookie(authCookie)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "1")
+		request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, rctx))
+		request.Header.Add("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+		assert.Equal(t, wantETag, recorder.Header().Get("ETag"))
+	}
+	updateSrv.AssertExpectations(t)
+}
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"go.uber.org/zap"
+)
+
+// tusVersion is the supported version of the tus resumable upload protocol.
+const tusVersion = "1.0.0"
+
+// appendSaveTimeout is the time given to save the content received before
+// the client disconnected or the server stopped serving the request.
+const appendSaveTimeout = 10 * time.Second
+
+type UploadService interface {
+	Create(
+		ctx context.Context,
+		userID int,
+		vaultID int,
+		length int64,
+		description string,
+		filename string,
+		metadata models.Metadata,
+		secret *models.Secret,
+	) (models.Upload, error)
+	Find(ctx context.Context, userID int, id string) (models.Upload, error)
+	Append(ctx context.Context, upload models.Upload, offset int64, content io.Reader) (models.Upload, error)
+	Delete(ctx context.Context, upload models.Upload) error
+}
+
+// UploadHandler implements the core tus protocol with the creation and
+// termination extensions. A completed upload becomes a bin data secret,
+// its location is returned in the Location header of the last PATCH and
+// of any following HEAD request.
+type UploadHandler struct {
+	logger  *zap.Logger
+	maxSize int64
+}
+
+func NewUploadHandler(logger *zap.Logger, maxSize int64) UploadHandler {
+	return UploadHandler{
+		logger:  logger,
+		maxSize: maxSize,
+	}
+}
+
+// Tus checks the protocol version of requests except OPTIONS ones and
+// sets the Tus-Resumable header.
+func (h UploadHandler) Tus(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", tusVersion)
+		if r.Method != http.MethodOptions && r.Header.Get("Tus-Resumable") != tusVersion {
+			w.Header().Set("Tus-Version", tusVersion)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h UploadHandler) Options() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Version", tusVersion)
+		w.Header().Set("Tus-Extension", "creation,termination")
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.maxSize, 10))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Create starts an upload. Upload-Metadata may contain filename and
+// description of the secret, secret_id of the secret to be updated or
+// vault_id of the vault of the new secret and metadata of the secret as
+// a JSON object. If the If-Match header is set, the secret must match it.
+func (h UploadHandler) Create(uploadSrv UploadService, findSrv FindSecretService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+		if err != nil || length < 0 {
+			h.logger.Info("invalid upload length", zap.String("length", r.Header.Get("Upload-Length")))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		metadata, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+		if err != nil {
+			h.logger.Info("invalid upload metadata", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var secretMetadata models.Metadata
+		if metadataJSON, ok := metadata["metadata"]; ok {
+			err := json.Unmarshal([]byte(metadataJSON), &secretMetadata)
+			if err == nil {
+				err = validateMetadata(secretMetadata)
+			}
+			if err != nil {
+				h.logger.Info("invalid secret metadata", zap.Error(err))
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		var secret *models.Secret
+		if secretIDStr, ok := metadata["secret_id"]; ok {
+			secretID, err := strconv.Atoi(secretIDStr)
+			if err != nil {
+				h.logger.Info("invalid secret id", zap.Error(err))
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			foundSecret, err := findSrv.Find(r.Context(), secretID)
+			if err != nil {
+				var notFoundErr storage.ErrSecretNotFound
+				if errors.As(err, &notFoundErr) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				h.logger.Info("failed to create upload", zap.Error(err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			version, ok := expectedVersion(r, foundSecret)
+			if !ok {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			foundSecret.Version = version
+			secret = &foundSecret
+		}
+		scopeSecret := models.Secret{SecretType: models.BinDataSecret}
+		if secret != nil {
+			scopeSecret = *secret
+		}
+		if !allowedByScope(w, r, scopeSecret.ID, scopeSecret.SecretType, true) {
+			return
+		}
+		var vaultID int
+		if vaultIDStr, ok := metadata["vault_id"]; ok {
+			vaultID, err = strconv.Atoi(vaultIDStr)
+			if err != nil {
+				h.logger.Info("invalid vault id", zap.Error(err))
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		upload, err := uploadSrv.Create(
+			r.Context(),
+			userID,
+			vaultID,
+			length,
+			metadata["description"],
+			services.BaseFilename(metadata["filename"]),
+			secretMetadata,
+			secret,
+		)
+		if err != nil {
+			if errors.Is(err, services.ErrBinDataTooLarge) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			if errors.Is(err, services.ErrWrongSecretType) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			var permErr services.ErrNoPermission
+			if errors.As(err, &permErr) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			h.logger.Info("failed to create upload", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Location", "/api/uploads/"+upload.ID)
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func (h UploadHandler) Head(uploadSrv UploadService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		upload, ok := h.findUpload(w, r, uploadSrv)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+		h.setSecretLocation(w, upload)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (h UploadHandler) Patch(uploadSrv UploadService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/offset+octet-stream" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+		if err != nil || offset < 0 {
+			h.logger.Info("invalid upload offset", zap.String("offset", r.Header.Get("Upload-Offset")))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		upload, ok := h.findUpload(w, r, uploadSrv)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := appendContext(r.Context())
+		defer cancel()
+		upload, err = uploadSrv.Append(ctx, upload, offset, r.Body)
+		if err != nil {
+			if errors.Is(err, services.ErrUploadOffsetMismatch) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			var mismatchErr storage.ErrSecretVersionMismatch
+			if errors.As(err, &mismatchErr) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			h.logger.Info("failed to append upload", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+		h.setSecretLocation(w, upload)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (h UploadHandler) Delete(uploadSrv UploadService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		upload, ok := h.findUpload(w, r, uploadSrv)
+		if !ok {
+			return
+		}
+		if err := uploadSrv.Delete(r.Context(), upload); err != nil {
+			h.logger.Info("failed to delete upload", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// findUpload finds the upload of the user and checks the API token scope
+// the same way Create does, since appending to an upload may create or
+// replace a secret.
+func (h UploadHandler) findUpload(w http.ResponseWriter, r *http.Request, uploadSrv UploadService) (models.Upload, bool) {
+	userID, _ := middlewares.UserIDFromContext(r.Context())
+	upload, err := uploadSrv.Find(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		var notFoundErr storage.ErrUploadNotFound
+		if errors.As(err, &notFoundErr) {
+			w.WriteHeader(http.StatusNotFound)
+			return upload, false
+		}
+		var permErr services.ErrNoPermission
+		if errors.As(err, &permErr) {
+			w.WriteHeader(http.StatusForbidden)
+			return upload, false
+		}
+		h.logger.Info("failed to find upload", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return upload, false
+	}
+	if !allowedByScope(w, r, upload.SecretID, models.BinDataSecret, true) {
+		return upload, false
+	}
+
+	return upload, true
+}
+
+// appendContext returns a context which is canceled appendSaveTimeout after
+// the request context is done. Content received before the client
+// disconnects has to be saved, so the request context is not used directly.
+func appendContext(requestCtx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-requestCtx.Done():
+		}
+		timer := time.NewTimer(appendSaveTimeout)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+			cancel()
+		}
+	}()
+
+	return ctx, cancel
+}
+
+func (h UploadHandler) setSecretLocation(w http.ResponseWriter, upload models.Upload) {
+	if upload.Completed {
+		w.Header().Set("Location", "/api/secrets/"+strconv.Itoa(upload.SecretID))
+	}
+}
+
+// parseUploadMetadata parses the Upload-Metadata header, which is a comma
+// separated list of keys and base64 encoded values.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encodedValue, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, fmt.Errorf("invalid metadata pair %q", pair)
+		}
+		value, err := base64.StdEncoding.DecodeString(encodedValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata value of %q: %w", key, err)
+		}
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,430 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type uploadServiceMock struct{ mock.Mock }
+
+func (m *uploadServiceMock) Create(
+	ctx context.Context,
+	userID int,
+	vaultID int,
+	length int64,
+	description string,
+	filename string,
+	metadata models.Metadata,
+	secret *models.Secret) (models.Upload, error) {
+
+	args := m.Called(ctx, userID, vaultID, length, description, filename, metadata, secret)
+	return args.Get(0).(models.Upload), args.Error(1)
+}
+
+func (m *uploadServiceMock) Find(ctx context.Context, userID int, id string) (models.Upload, error) {
+	args := m.Called(ctx, userID, id)
+	return args.Get(0).(models.Upload), args.Error(1)
+}
+
+func (m *uploadServiceMock) Append(
+	ctx context.Context,
+	upload models.Upload,
+	offset int64,
+	content io.Reader) (models.Upload, error) {
+
+	contentBytes, err := io.ReadAll(content)
+	if err != nil {
+		return upload, err
+	}
+	args := m.Called(ctx, upload, offset, contentBytes)
+	return args.Get(0).(models.Upload), args.Error(1)
+}
+
+func (m *uploadServiceMock) Delete(ctx context.Context, upload models.Upload) error {
+	args := m.Called(ctx, upload)
+	return args.Error(0)
+}
+
+func newUploadRouter(t *testing.T, uploadSrv *uploadServiceMock, findSrv *findSecretServiceMock) http.Handler {
+	handler := handlers.NewUploadHandler(zaptest.NewLogger(t), 100)
+	router := chi.NewRouter()
+	router.Use(handler.Tus)
+	router.Options("/api/uploads", handler.Options())
+	router.Post("/api/uploads", handler.Create(uploadSrv, findSrv))
+	router.Head("/api/uploads/{id}", handler.Head(uploadSrv))
+	router.Patch("/api/uploads/{id}", handler.Patch(uploadSrv))
+	router.Delete("/api/uploads/{id}", handler.Delete(uploadSrv))
+
+	return router
+}
+
+func TestCreateUpload(t *testing.T) {
+	type want struct {
+		code     int
+		location string
+	}
+	type createResult struct {
+		upload models.Upload
+		err    error
+	}
+	testCases := []struct {
+		name         string
+		headers      map[string]string
+		findErr      error
+		createSecret *models.Secret
+		createRes    createResult
+		want         want
+	}{
+		{
+			name: "creates upload of new secret",
+			headers: map[string]string{
+				"Upload-Length":   "10",
+				"Upload-Metadata": "filename ZmlsZS50eHQ=,description ZGVzY3JpcHRpb24=",
+			},
+			createRes: createResult{
+				upload: models.Upload{ID: "abc", Length: 10, ExpiresAt: time.Now()},
+			},
+			want: want{
+				code:     http.StatusCreated,
+				location: "/api/uploads/abc",
+			},
+		},
+		{
+			name: "strips directories from filename",
+			headers: map[string]string{
+				"Upload-Length":   "10",
+				"Upload-Metadata": "filename Li4vLi4vZmlsZS50eHQ=,description ZGVzY3JpcHRpb24=",
+			},
+			createRes: createResult{
+				upload: models.Upload{ID: "abc", Length: 10, ExpiresAt: time.Now()},
+			},
+			want: want{
+				code:     http.StatusCreated,
+				location: "/api/uploads/abc",
+			},
+		},
+		{
+			name: "creates upload of secret content",
+			headers: map[string]string{
+				"Upload-Length":   "10",
+				"Upload-Metadata": "filename ZmlsZS50eHQ=,description ZGVzY3JpcHRpb24=,secret_id MQ==",
+			},
+			createSecret: &models.Secret{ID: 1, UserID: 1, SecretType: models.BinDataSecret},
+			createRes: createResult{
+				upload: models.Upload{ID: "abc", Length: 10, SecretID: 1, ExpiresAt: time.Now()},
+			},
+			want: want{
+				code:     http.StatusCreated,
+				location: "/api/uploads/abc",
+			},
+		},
+		{
+			name: "responds with not found if secret does not exist",
+			headers: map[string]string{
+				"Upload-Length":   "10",
+				"Upload-Metadata": "filename ZmlsZS50eHQ=,description ZGVzY3JpcHRpb24=,secret_id MQ==",
+			},
+			findErr: storage.ErrSecretNotFound{Secret: models.Secret{ID: 1}},
+			want: want{
+				code: http.StatusNotFound,
+			},
+		},
+		{
+			name: "responds with precondition failed if secret does not match If-Match",
+			headers: map[string]string{
+				"Upload-Length":   "10",
+				"Upload-Metadata": "filename ZmlsZS50eHQ=,description ZGVzY3JpcHRpb24=,secret_id MQ==",
+				"If-Match":        `"6"`,
+			},
+			want: want{
+				code: http.StatusPreconditionFailed,
+			},
+		},
+		{
+			name:    "responds with bad request if length is missing",
+			headers: map[string]string{},
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "responds with bad request if metadata is invalid",
+			headers: map[string]string{
+				"Upload-Length":   "10",
+				"Upload-Metadata": "filename !!!",
+			},
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "responds with request entity too large",
+			headers: map[string]string{
+				"Upload-Length":   "1000",
+				"Upload-Metadata": "filename ZmlsZS50eHQ=,description ZGVzY3JpcHRpb24=",
+			},
+			createRes: createResult{
+				err: services.ErrBinDataTooLarge,
+			},
+			want: want{
+				code: http.StatusRequestEntityTooLarge,
+			},
+		},
+	}
+
+	uploadSrv := new(uploadServiceMock)
+	findSrv := new(findSecretServiceMock)
+	router := newUploadRouter(t, uploadSrv, findSrv)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			findCall := findSrv.On("Find", mock.Anything, 1).
+				Return(models.Secret{ID: 1, UserID: 1, SecretType: models.BinDataSecret}, tc.findErr)
+			defer findCall.Unset()
+			createCall := uploadSrv.
+				On("Create", mock.Anything, mock.Anything, 0, mock.Anything, "description", "file.txt", mock.Anything, tc.createSecret).
+				Return(tc.createRes.upload, tc.createRes.err)
+			defer createCall.Unset()
+
+			request, err := http.NewRequest(http.MethodPost, "/api/uploads", nil)
+			require.NoError(t, err)
+			request.Header.Set("Tus-Resumable", "1.0.0")
+			for name, value := range tc.headers {
+				request.Header.Set(name, value)
+			}
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.location, recorder.Header().Get("Location"))
+			assert.Equal(t, "1.0.0", recorder.Header().Get("Tus-Resumable"))
+		})
+	}
+}
+
+func TestPatchUpload(t *testing.T) {
+	type want struct {
+		code     int
+		offset   string
+		location string
+	}
+	type appendResult struct {
+		upload models.Upload
+		err    error
+	}
+	upload := models.Upload{ID: "abc", UserID: 1, Length: 10, Offset: 4}
+	testCases := []struct {
+		name        string
+		contentType string
+		offset      string
+		findErr     error
+		appendRes   appendResult
+		want        want
+	}{
+		{
+			name:        "appends content",
+			contentType: "application/offset+octet-stream",
+			offset:      "4",
+			appendRes: appendResult{
+				upload: models.Upload{ID: "abc", UserID: 1, Length: 10, Offset: 7},
+			},
+			want: want{
+				code:   http.StatusNoContent,
+				offset: "7",
+			},
+		},
+		{
+			name:        "responds with secret location if upload is completed",
+			contentType: "application/offset+octet-stream",
+			offset:      "4",
+			appendRes: appendResult{
+				upload: models.Upload{ID: "abc", UserID: 1, Length: 10, Offset: 10, Completed: true, SecretID: 5},
+			},
+			want: want{
+				code:     http.StatusNoContent,
+				offset:   "10",
+				location: "/api/secrets/5",
+			},
+		},
+		{
+			name:        "responds with conflict if offset does not match",
+			contentType: "application/offset+octet-stream",
+			offset:      "0",
+			appendRes: appendResult{
+				upload: upload,
+				err:    services.ErrUploadOffsetMismatch,
+			},
+			want: want{
+				code: http.StatusConflict,
+			},
+		},
+		{
+			name:        "responds with precondition failed if secret has changed since upload creation",
+			contentType: "application/offset+octet-stream",
+			offset:      "4",
+			appendRes: appendResult{
+				upload: upload,
+				err:    storage.ErrSecretVersionMismatch{Secret: models.Secret{ID: 5, Version: 3}},
+			},
+			want: want{
+				code: http.StatusPreconditionFailed,
+			},
+		},
+		{
+			name:        "responds with unsupported media type",
+			contentType: "application/json",
+			offset:      "4",
+			want: want{
+				code: http.StatusUnsupportedMediaType,
+			},
+		},
+		{
+			name:        "responds with not found",
+			contentType: "application/offset+octet-stream",
+			offset:      "4",
+			findErr:     storage.ErrUploadNotFound{Upload: models.Upload{ID: "abc"}},
+			want: want{
+				code: http.StatusNotFound,
+			},
+		},
+		{
+			name:        "responds with forbidden status",
+			contentType: "application/offset+octet-stream",
+			offset:      "4",
+			findErr:     services.ErrNoPermission{UserID: 2},
+			want: want{
+				code: http.StatusForbidden,
+			},
+		},
+	}
+
+	uploadSrv := new(uploadServiceMock)
+	router := newUploadRouter(t, uploadSrv, new(findSecretServiceMock))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			findCall := uploadSrv.On("Find", mock.Anything, mock.Anything, "abc").
+				Return(upload, tc.findErr)
+			defer findCall.Unset()
+			appendCall := uploadSrv.On("Append", mock.Anything, upload, mock.Anything, []byte("abc")).
+				Return(tc.appendRes.upload, tc.appendRes.err)
+			defer appendCall.Unset()
+
+			request, err := http.NewRequest(http.MethodPatch, "/api/uploads/abc", bytes.NewReader([]byte("abc")))
+			require.NoError(t, err)
+			request.Header.Set("Tus-Resumable", "1.0.0")
+			request.Header.Set("Content-Type", tc.contentType)
+			request.Header.Set("Upload-Offset", tc.offset)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.offset, recorder.Header().Get("Upload-Offset"))
+			assert.Equal(t, tc.want.location, recorder.Header().Get("Location"))
+		})
+	}
+}
+
+func TestHeadUpload(t *testing.T) {
+	uploadSrv := new(uploadServiceMock)
+	router := newUploadRouter(t, uploadSrv, new(findSecretServiceMock))
+	uploadSrv.On("Find", mock.Anything, mock.Anything, "abc").
+		Return(models.Upload{ID: "abc", UserID: 1, Length: 10, Offset: 4}, nil)
+
+	request, err := http.NewRequest(http.MethodHead, "/api/uploads/abc", nil)
+	require.NoError(t, err)
+	request.Header.Set("Tus-Resumable", "1.0.0")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	assert.Equal(t, "4", recorder.Header().Get("Upload-Offset"))
+	assert.Equal(t, "10", recorder.Header().Get("Upload-Length"))
+	assert.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+}
+
+func TestUploadRequiresTusVersion(t *testing.T) {
+	router := newUploadRouter(t, new(uploadServiceMock), new(findSecretServiceMock))
+
+	request, err := http.NewRequest(http.MethodHead, "/api/uploads/abc", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusPreconditionFailed, recorder.Result().StatusCode)
+	assert.Equal(t, "1.0.0", recorder.Header().Get("Tus-Version"))
+}
+
+func TestUploadAPITokenScope(t *testing.T) {
+	upload := models.Upload{ID: "abc", UserID: 1, SecretID: 6, Length: 10, Offset: 4}
+	newRequest := func(method string, scope models.TokenScope) *http.Request {
+		request := httptest.NewRequest(method, "/api/uploads/abc", bytes.NewReader([]byte("abc")))
+		request.Header.Set("Tus-Resumable", "1.0.0")
+		request.Header.Set("Content-Type", "application/offset+octet-stream")
+		request.Header.Set("Upload-Offset", "4")
+		return request.WithContext(middlewares.WithAPIToken(request.Context(), models.APIToken{UserID: 1, Scope: scope}))
+	}
+
+	t.Run("forbids appending with read-only token", func(t *testing.T) {
+		uploadSrv := new(uploadServiceMock)
+		uploadSrv.On("Find", mock.Anything, 1, "abc").Return(upload, nil)
+		recorder := httptest.NewRecorder()
+		newUploadRouter(t, uploadSrv, new(findSecretServiceMock)).
+			ServeHTTP(recorder, newRequest(http.MethodPatch, models.TokenScope{ReadOnly: true}))
+
+		assert.Equal(t, http.StatusForbidden, recorder.Result().StatusCode)
+		uploadSrv.AssertNotCalled(t, "Append", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("forbids deleting upload of other secret", func(t *testing.T) {
+		uploadSrv := new(uploadServiceMock)
+		uploadSrv.On("Find", mock.Anything, 1, "abc").Return(upload, nil)
+		recorder := httptest.NewRecorder()
+		newUploadRouter(t, uploadSrv, new(findSecretServiceMock)).
+			ServeHTTP(recorder, newRequest(http.MethodDelete, models.TokenScope{SecretIDs: []int{5}}))
+
+		assert.Equal(t, http.StatusForbidden, recorder.Result().StatusCode)
+		uploadSrv.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("forbids checking upload with token of other secret types", func(t *testing.T) {
+		uploadSrv := new(uploadServiceMock)
+		uploadSrv.On("Find", mock.Anything, 1, "abc").Return(upload, nil)
+		recorder := httptest.NewRecorder()
+		newUploadRouter(t, uploadSrv, new(findSecretServiceMock)).
+			ServeHTTP(recorder, newRequest(http.MethodHead, models.TokenScope{
+				SecretTypes: []models.SecretType{models.CredentialsSecret},
+			}))
+
+		assert.Equal(t, http.StatusForbidden, recorder.Result().StatusCode)
+	})
+
+	t.Run("allows appending to upload of allowed secret", func(t *testing.T) {
+		uploadSrv := new(uploadServiceMock)
+		uploadSrv.On("Find", mock.Anything, 1, "abc").Return(upload, nil)
+		uploadSrv.On("Append", mock.Anything, upload, int64(4), []byte("abc")).
+			Return(models.Upload{ID: "abc", UserID: 1, SecretID: 6, Length: 10, Offset: 7}, nil)
+		recorder := httptest.NewRecorder()
+		newUploadRouter(t, uploadSrv, new(findSecretServiceMock)).
+			ServeHTTP(recorder, newRequest(http.MethodPatch, models.TokenScope{SecretIDs: []int{6}}))
+
+		assert.Equal(t, http.StatusNoContent, recorder.Result().StatusCode)
+		assert.Equal(t, "7", recorder.Header().Get("Upload-Offset"))
+	})
+}
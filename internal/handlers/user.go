@@ -4,19 +4,74 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
 	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
 	"go.uber.org/zap"
 )
 
 type RegisterService interface {
-	Register(ctx context.Context, login, password string) (string, error)
+	Register(ctx context.Context, login, password string) (services.SessionTokens, error)
 }
 
 type AuthenticateService interface {
-	Authenticate(ctx context.Context, login, password string) (string, error)
+	Authenticate(ctx context.Context, login, password string) (services.SessionTokens, error)
+}
+
+type SessionService interface {
+	Refresh(ctx context.Context, refreshToken string) (services.SessionTokens, error)
+	Revoke(ctx context.Context, sessionID int) error
+	List(ctx context.Context, userID int) ([]models.Session, error)
+	RevokeUserSession(ctx context.Context, userID int, sessionID int) error
+	RevokeOthers(ctx context.Context, userID int, currentSessionID int) (int64, error)
+}
+
+type TOTPService interface {
+	Enroll(ctx context.Context, userID int) (services.TOTPEnrollment, error)
+	Confirm(ctx context.Context, userID int, code string) ([]string, error)
+	Authenticate(ctx context.Context, mfaToken string, code string) (services.SessionTokens, error)
+}
+
+type UserSettingsService interface {
+	Update(ctx context.Context, userID int, settings models.UserSettings) error
+}
+
+type totpRequiredResponse struct {
+	TOTPRequired bool   `json:"totp_required"`
+	MFAToken     string `json:"mfa_token"`
+}
+
+type totpEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// sessionResponse Current is set for the session of the request.
+type sessionResponse struct {
+	ID        int       `json:"id"`
+	Current   bool      `json:"current"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type sessionsIndexResponse struct {
+	Sessions []sessionResponse `json:"sessions"`
+}
+
+type revokedSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
 }
 
 type UserHandler struct {
@@ -24,7 +79,9 @@ type UserHandler struct {
 }
 
 func NewUserHandlers(logger *zap.Logger) UserHandler {
-	return UserHandler{}
+	return UserHandler{
+		logger: logger,
+	}
 }
 
 func (h UserHandler) Register(regSrv RegisterService) func(http.ResponseWriter, *http.Request) {
@@ -46,7 +103,7 @@ func (h UserHandler) Register(regSrv RegisterService) func(http.ResponseWriter,
 			}
 			return
 		}
-		jwtStr, err := regSrv.Register(r.Context(), requestBody.Login, requestBody.Password)
+		tokens, err := regSrv.Register(r.Context(), requestBody.Login, requestBody.Password)
 		if err != nil {
 			var notUniqErr storage.ErrUserNotUniq
 			if errors.As(err, &notUniqErr) {
@@ -56,13 +113,20 @@ func (h UserHandler) Register(regSrv RegisterService) func(http.ResponseWriter,
 				}
 				return
 			}
+			if errors.Is(err, services.ErrReservedLogin) {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				if err := encoder.Encode(err.Error()); err != nil {
+					h.logger.Info("failed to encode response", zap.Error(err))
+				}
+				return
+			}
 			w.WriteHeader(http.StatusInternalServerError)
 			if err := encoder.Encode(err.Error()); err != nil {
 				h.logger.Info("failed to encode response", zap.Error(err))
 			}
 			return
 		}
-		auth.SetJWTCookie(w, jwtStr)
+		setSessionCookies(w, tokens)
 		w.WriteHeader(http.StatusOK)
 	}
 }
@@ -86,16 +150,334 @@ func (h UserHandler) Authenticate(authService AuthenticateService) func(http.Res
 			}
 			return
 		}
-		jwtStr, err := authService.Authenticate(r.Context(), requestBody.Login, requestBody.Password)
+		tokens, err := authService.Authenticate(r.Context(), requestBody.Login, requestBody.Password)
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
+			// unknown logins and wrong passwords get the same response, so
+			// logins can not be enumerated
+			var totpErr services.ErrTOTPRequired
+			var lockedErr services.ErrLoginLocked
+			var notFoundErr storage.ErrUserNotFound
+			switch {
+			case errors.As(err, &totpErr):
+				// the session is issued after the TOTP check
+				w.WriteHeader(http.StatusOK)
+				response := totpRequiredResponse{TOTPRequired: true, MFAToken: totpErr.MFAToken}
+				if err := encoder.Encode(response); err != nil {
+					h.logger.Info("failed to encode response", zap.Error(err))
+				}
+				return
+			case errors.As(err, &lockedErr):
+				writeRetryAfter(w, lockedErr)
+			case errors.Is(err, services.ErrInvalidCredentials), errors.As(err, &notFoundErr):
+				err = services.ErrInvalidCredentials
+				w.WriteHeader(http.StatusUnauthorized)
+			default:
+				h.logger.Info("failed to authenticate user", zap.Error(err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := encoder.Encode(err.Error()); err != nil {
+				h.logger.Info("failed to encode response", zap.Error(err))
+			}
+			return
+		}
+
+		setSessionCookies(w, tokens)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// AuthenticateTOTP is the second login step of users with TOTP enabled,
+// it sets the JWT cookie if the code passes the check.
+func (h UserHandler) AuthenticateTOTP(totpSrv TOTPService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		type payload struct {
+			MFAToken string `json:"mfa_token"`
+			Code     string `json:"code"`
+		}
+		var requestBody payload
+		encoder := json.NewEncoder(w)
+		err := json.NewDecoder(r.Body).Decode(&requestBody)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			if err := encoder.Encode("invalid request body"); err != nil {
+				h.logger.Info("failed to encode response", zap.Error(err))
+			}
+			return
+		}
+		tokens, err := totpSrv.Authenticate(r.Context(), requestBody.MFAToken, requestBody.Code)
+		if err != nil {
+			var lockedErr services.ErrLoginLocked
+			switch {
+			case errors.As(err, &lockedErr):
+				writeRetryAfter(w, lockedErr)
+			case errors.Is(err, services.ErrInvalidMFAToken), errors.Is(err, services.ErrInvalidTOTPCode):
+				w.WriteHeader(http.StatusUnauthorized)
+			default:
+				h.logger.Info("failed to authenticate user", zap.Error(err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := encoder.Encode(err.Error()); err != nil {
+				h.logger.Info("failed to encode response", zap.Error(err))
+			}
+			return
+		}
+
+		setSessionCookies(w, tokens)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// EnrollTOTP responds with a new TOTP secret of the user, which has to be
+// confirmed with ConfirmTOTP.
+func (h UserHandler) EnrollTOTP(totpSrv TOTPService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		encoder := json.NewEncoder(w)
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		enrollment, err := totpSrv.Enroll(r.Context(), userID)
+		if err != nil {
+			if errors.Is(err, services.ErrTOTPAlreadyEnabled) {
+				w.WriteHeader(http.StatusConflict)
+				if err := encoder.Encode(err.Error()); err != nil {
+					h.logger.Info("failed to encode response", zap.Error(err))
+				}
+				return
+			}
+			h.logger.Info("failed to enroll TOTP", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		response := totpEnrollmentResponse{Secret: enrollment.Secret, URI: enrollment.URI}
+		if err := encoder.Encode(response); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// ConfirmTOTP enables TOTP of the user and responds with recovery codes.
+func (h UserHandler) ConfirmTOTP(totpSrv TOTPService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		type payload struct {
+			Code string `json:"code"`
+		}
+		var requestBody payload
+		encoder := json.NewEncoder(w)
+		err := json.NewDecoder(r.Body).Decode(&requestBody)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			if err := encoder.Encode("invalid request body"); err != nil {
+				h.logger.Info("failed to encode response", zap.Error(err))
+			}
+			return
+		}
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		codes, err := totpSrv.Confirm(r.Context(), userID, requestBody.Code)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrTOTPAlreadyEnabled):
+				w.WriteHeader(http.StatusConflict)
+			case errors.Is(err, services.ErrTOTPNotEnrolled), errors.Is(err, services.ErrInvalidTOTPCode):
+				w.WriteHeader(http.StatusUnprocessableEntity)
+			default:
+				h.logger.Info("failed to confirm TOTP", zap.Error(err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
 			if err := encoder.Encode(err.Error()); err != nil {
 				h.logger.Info("failed to encode response", zap.Error(err))
 			}
 			return
 		}
 
-		auth.SetJWTCookie(w, jwtStr)
+		w.WriteHeader(http.StatusOK)
+		if err := encoder.Encode(recoveryCodesResponse{RecoveryCodes: codes}); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// Refresh exchanges the refresh token cookie for new session tokens.
+func (h UserHandler) Refresh(sessionSrv SessionService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		encoder := json.NewEncoder(w)
+		cookie, err := r.Cookie("refresh_token")
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			if err := encoder.Encode(services.ErrInvalidRefreshToken.Error()); err != nil {
+				h.logger.Info("failed to encode response", zap.Error(err))
+			}
+			return
+		}
+		tokens, err := sessionSrv.Refresh(r.Context(), cookie.Value)
+		if err != nil {
+			var notFoundErr storage.ErrRefreshTokenNotFound
+			if errors.Is(err, services.ErrInvalidRefreshToken) || errors.As(err, &notFoundErr) {
+				auth.ClearSessionCookies(w)
+				w.WriteHeader(http.StatusUnauthorized)
+				if err := encoder.Encode(services.ErrInvalidRefreshToken.Error()); err != nil {
+					h.logger.Info("failed to encode response", zap.Error(err))
+				}
+				return
+			}
+			h.logger.Info("failed to refresh session", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		setSessionCookies(w, tokens)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// Logout revokes the session of the access token.
+func (h UserHandler) Logout(sessionSrv SessionService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID, _ := middlewares.SessionIDFromContext(r.Context())
+		if err := sessionSrv.Revoke(r.Context(), sessionID); err != nil {
+			h.logger.Info("failed to revoke session", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		auth.ClearSessionCookies(w)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// Sessions responds with active sessions of the user.
+func (h UserHandler) Sessions(sessionSrv SessionService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		currentSessionID, _ := middlewares.SessionIDFromContext(r.Context())
+		sessions, err := sessionSrv.List(r.Context(), userID)
+		if err != nil {
+			h.logger.Info("failed to list sessions", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := sessionsIndexResponse{Sessions: make([]sessionResponse, len(sessions))}
+		for i, session := range sessions {
+			response.Sessions[i] = sessionResponse{
+				ID:        session.ID,
+				Current:   session.ID == currentSessionID,
+				CreatedAt: session.CreatedAt,
+				ExpiresAt: session.ExpiresAt,
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// RevokeSession revokes the session of the user, session cookies are
+// cleared if it is the session of the request.
+func (h UserHandler) RevokeSession(sessionSrv SessionService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		currentSessionID, _ := middlewares.SessionIDFromContext(r.Context())
+		sessionID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			h.logger.Info("invalid session id", zap.Error(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := sessionSrv.RevokeUserSession(r.Context(), userID, sessionID); err != nil {
+			var notFoundErr storage.ErrSessionNotFound
+			if errors.As(err, &notFoundErr) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			h.logger.Info("failed to revoke session", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if sessionID == currentSessionID {
+			auth.ClearSessionCookies(w)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// RevokeOtherSessions revokes all sessions of the user except the session
+// of the request and responds with their number.
+func (h UserHandler) RevokeOtherSessions(sessionSrv SessionService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		currentSessionID, _ := middlewares.SessionIDFromContext(r.Context())
+		revoked, err := sessionSrv.RevokeOthers(r.Context(), userID, currentSessionID)
+		if err != nil {
+			h.logger.Info("failed to revoke sessions", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(revokedSessionsResponse{Revoked: revoked}); err != nil {
+			h.logger.Info("failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+func (h UserHandler) UpdateSettings(settingsSrv UserSettingsService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		type payload struct {
+			RevisionsRetention *int `json:"revisions_retention"`
+		}
+		var requestBody payload
+		encoder := json.NewEncoder(w)
+		err := json.NewDecoder(r.Body).Decode(&requestBody)
+		if err != nil || requestBody.RevisionsRetention == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			if err := encoder.Encode("invalid request body"); err != nil {
+				h.logger.Info("failed to encode response", zap.Error(err))
+			}
+			return
+		}
+		userID, _ := middlewares.UserIDFromContext(r.Context())
+		settings := models.UserSettings{RevisionsRetention: *requestBody.RevisionsRetention}
+		err = settingsSrv.Update(r.Context(), userID, settings)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidRetention) {
+				w.WriteHeader(http.StatusBadRequest)
+				if err := encoder.Encode(err.Error()); err != nil {
+					h.logger.Info("failed to encode response", zap.Error(err))
+				}
+				return
+			}
+			h.logger.Info("failed to update user settings", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+func setSessionCookies(w http.ResponseWriter, tokens services.SessionTokens) {
+	auth.SetJWTCookie(w, tokens.AccessToken)
+	auth.SetRefreshTokenCookie(w, tokens.RefreshToken)
+}
+
+func writeRetryAfter(w http.ResponseWriter, lockedErr services.ErrLoginLocked) {
+	retryAfter := int(math.Ceil(lockedErr.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.WriteHeader(http.StatusTooManyRequests)
+}
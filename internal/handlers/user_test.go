@@ -5,12 +5,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/ilya-burinskiy/gophkeeper/internal/handlers"
+	"github.com/ilya-burinskiy/gophkeeper/internal/middlewares"
 	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
 	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -20,16 +25,71 @@ import (
 
 type registerService struct{ mock.Mock }
 
-func (srv *registerService) Register(ctx context.Context, login, password string) (string, error) {
+func (srv *registerService) Register(ctx context.Context, login, password string) (services.SessionTokens, error) {
 	args := srv.Called(ctx, login, password)
-	return args.String(0), args.Error(1)
+	return args.Get(0).(services.SessionTokens), args.Error(1)
 }
 
 type authenticateService struct{ mock.Mock }
 
-func (srv *authenticateService) Authenticate(ctx context.Context, login, password string) (string, error) {
+func (srv *authenticateService) Authenticate(ctx context.Context, login, password string) (services.SessionTokens, error) {
 	args := srv.Called(ctx, login, password)
-	return args.String(0), args.Error(1)
+	return args.Get(0).(services.SessionTokens), args.Error(1)
+}
+
+type totpServiceMock struct{ mock.Mock }
+
+func (srv *totpServiceMock) Enroll(ctx context.Context, userID int) (services.TOTPEnrollment, error) {
+	args := srv.Called(ctx, userID)
+	return args.Get(0).(services.TOTPEnrollment), args.Error(1)
+}
+
+func (srv *totpServiceMock) Confirm(ctx context.Context, userID int, code string) ([]string, error) {
+	args := srv.Called(ctx, userID, code)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (srv *totpServiceMock) Authenticate(
+	ctx context.Context,
+	mfaToken string,
+	code string) (services.SessionTokens, error) {
+
+	args := srv.Called(ctx, mfaToken, code)
+	return args.Get(0).(services.SessionTokens), args.Error(1)
+}
+
+type sessionServiceMock struct{ mock.Mock }
+
+func (srv *sessionServiceMock) Refresh(ctx context.Context, refreshToken string) (services.SessionTokens, error) {
+	args := srv.Called(ctx, refreshToken)
+	return args.Get(0).(services.SessionTokens), args.Error(1)
+}
+
+func (srv *sessionServiceMock) Revoke(ctx context.Context, sessionID int) error {
+	args := srv.Called(ctx, sessionID)
+	return args.Error(0)
+}
+
+func (srv *sessionServiceMock) List(ctx context.Context, userID int) ([]models.Session, error) {
+	args := srv.Called(ctx, userID)
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (srv *sessionServiceMock) RevokeUserSession(ctx context.Context, userID int, sessionID int) error {
+	args := srv.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (srv *sessionServiceMock) RevokeOthers(ctx context.Context, userID int, currentSessionID int) (int64, error) {
+	args := srv.Called(ctx, userID, currentSessionID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type userSettingsServiceMock struct{ mock.Mock }
+
+func (srv *userSettingsServiceMock) Update(ctx context.Context, userID int, settings models.UserSettings) error {
+	args := srv.Called(ctx, userID, settings)
+	return args.Error(0)
 }
 
 func TestRegister(t *testing.T) {
@@ -38,7 +98,7 @@ func TestRegister(t *testing.T) {
 		response string
 	}
 	type registerResult struct {
-		jwtStr string
+		tokens services.SessionTokens
 		err    error
 	}
 	testCases := []struct {
@@ -50,7 +110,7 @@ func TestRegister(t *testing.T) {
 		{
 			name:        "responses with ok status",
 			requestBody: toJSON(t, map[string]string{"login": "login", "password": "password"}),
-			registerRes: registerResult{jwtStr: "123"},
+			registerRes: registerResult{tokens: services.SessionTokens{AccessToken: "123", RefreshToken: "456"}},
 			want:        want{code: http.StatusOK},
 		},
 		{
@@ -72,6 +132,17 @@ func TestRegister(t *testing.T) {
 				response: string(toJSON(t, "user with login \"login\" already exists")) + "\n",
 			},
 		},
+		{
+			name:        "responses with unprocessable entity status if login is reserved",
+			requestBody: toJSON(t, map[string]string{"login": "ci@1.service-account", "password": "password"}),
+			registerRes: registerResult{
+				err: services.ErrReservedLogin,
+			},
+			want: want{
+				code:     http.StatusUnprocessableEntity,
+				response: string(toJSON(t, "login is reserved for service accounts")) + "\n",
+			},
+		},
 		{
 			name:        "responses with internal server error status",
 			requestBody: toJSON(t, map[string]string{"login": "login", "password": "password"}),
@@ -93,7 +164,7 @@ func TestRegister(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			regCall := regService.On("Register", mock.Anything, mock.Anything, mock.Anything).
-				Return(tc.registerRes.jwtStr, tc.registerRes.err)
+				Return(tc.registerRes.tokens, tc.registerRes.err)
 			defer regCall.Unset()
 
 			recorder := httptest.NewRecorder()
@@ -112,11 +183,12 @@ func TestRegister(t *testing.T) {
 
 func TestAuthenticate(t *testing.T) {
 	type want struct {
-		code     int
-		response string
+		code       int
+		retryAfter string
+		response   string
 	}
 	type authenticateResult struct {
-		jwtStr string
+		tokens services.SessionTokens
 		err    error
 	}
 	testCases := []struct {
@@ -128,7 +200,7 @@ func TestAuthenticate(t *testing.T) {
 		{
 			name:        "responses with ok status",
 			requestBody: toJSON(t, map[string]string{"login": "login", "password": "password"}),
-			authRes:     authenticateResult{jwtStr: "123"},
+			authRes:     authenticateResult{tokens: services.SessionTokens{AccessToken: "123", RefreshToken: "456"}},
 			want:        want{code: http.StatusOK},
 		},
 		{
@@ -143,11 +215,55 @@ func TestAuthenticate(t *testing.T) {
 			name:        "responses with status unauthorized",
 			requestBody: toJSON(t, map[string]string{"login": "login", "password": "password"}),
 			authRes: authenticateResult{
-				err: errors.New("error"),
+				err: services.ErrInvalidCredentials,
 			},
 			want: want{
 				code:     http.StatusUnauthorized,
-				response: string(toJSON(t, "error")) + "\n",
+				response: string(toJSON(t, "invalid login or password")) + "\n",
+			},
+		},
+		{
+			name:        "responses with status unauthorized if user is not found",
+			requestBody: toJSON(t, map[string]string{"login": "login", "password": "password"}),
+			authRes: authenticateResult{
+				err: fmt.Errorf("failed to authenticate user: %w", storage.ErrUserNotFound{}),
+			},
+			want: want{
+				code:     http.StatusUnauthorized,
+				response: string(toJSON(t, "invalid login or password")) + "\n",
+			},
+		},
+		{
+			name:        "responses with MFA token if TOTP is required",
+			requestBody: toJSON(t, map[string]string{"login": "login", "password": "password"}),
+			authRes: authenticateResult{
+				err: services.ErrTOTPRequired{MFAToken: "mfa"},
+			},
+			want: want{
+				code:     http.StatusOK,
+				response: `{"totp_required":true,"mfa_token":"mfa"}` + "\n",
+			},
+		},
+		{
+			name:        "responses with too many requests status if login is locked",
+			requestBody: toJSON(t, map[string]string{"login": "login", "password": "password"}),
+			authRes: authenticateResult{
+				err: services.ErrLoginLocked{RetryAfter: 1500 * time.Millisecond},
+			},
+			want: want{
+				code:       http.StatusTooManyRequests,
+				retryAfter: "2",
+				response:   string(toJSON(t, "too many failed login attempts, retry after 2s")) + "\n",
+			},
+		},
+		{
+			name:        "responses with internal server error",
+			requestBody: toJSON(t, map[string]string{"login": "login", "password": "password"}),
+			authRes: authenticateResult{
+				err: errors.New("error"),
+			},
+			want: want{
+				code: http.StatusInternalServerError,
 			},
 		},
 	}
@@ -160,7 +276,7 @@ func TestAuthenticate(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			authCall := authService.On("Authenticate", mock.Anything, mock.Anything, mock.Anything).
-				Return(tc.authRes.jwtStr, tc.authRes.err)
+				Return(tc.authRes.tokens, tc.authRes.err)
 			defer authCall.Unset()
 
 			recorder := httptest.NewRecorder()
@@ -172,6 +288,492 @@ func TestAuthenticate(t *testing.T) {
 			require.NoError(t, err)
 			handler.ServeHTTP(recorder, request)
 			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.retryAfter, recorder.Header().Get("Retry-After"))
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+			assert.Equal(t, tc.authRes.tokens.AccessToken != "", len(recorder.Result().Cookies()) > 0)
+		})
+	}
+}
+
+func TestAuthenticateTOTP(t *testing.T) {
+	type want struct {
+		code       int
+		retryAfter string
+		response   string
+	}
+	type authenticateResult struct {
+		tokens services.SessionTokens
+		err    error
+	}
+	testCases := []struct {
+		name        string
+		requestBody []byte
+		authRes     authenticateResult
+		want        want
+	}{
+		{
+			name:        "responses with ok status",
+			requestBody: toJSON(t, map[string]string{"mfa_token": "mfa", "code": "123456"}),
+			authRes:     authenticateResult{tokens: services.SessionTokens{AccessToken: "123", RefreshToken: "456"}},
+			want:        want{code: http.StatusOK},
+		},
+		{
+			name:        "responses with bad request status if request body is invalid",
+			requestBody: toJSON(t, "mfa_token: mfa"),
+			want: want{
+				code:     http.StatusBadRequest,
+				response: string(toJSON(t, "invalid request body")) + "\n",
+			},
+		},
+		{
+			name:        "responses with status unauthorized if code is invalid",
+			requestBody: toJSON(t, map[string]string{"mfa_token": "mfa", "code": "123456"}),
+			authRes:     authenticateResult{err: services.ErrInvalidTOTPCode},
+			want: want{
+				code:     http.StatusUnauthorized,
+				response: string(toJSON(t, services.ErrInvalidTOTPCode.Error())) + "\n",
+			},
+		},
+		{
+			name:        "responses with status unauthorized if MFA token is invalid",
+			requestBody: toJSON(t, map[string]string{"mfa_token": "mfa", "code": "123456"}),
+			authRes:     authenticateResult{err: services.ErrInvalidMFAToken},
+			want: want{
+				code:     http.StatusUnauthorized,
+				response: string(toJSON(t, services.ErrInvalidMFAToken.Error())) + "\n",
+			},
+		},
+		{
+			name:        "responses with too many requests status if user is locked",
+			requestBody: toJSON(t, map[string]string{"mfa_token": "mfa", "code": "123456"}),
+			authRes:     authenticateResult{err: services.ErrLoginLocked{RetryAfter: time.Minute}},
+			want: want{
+				code:       http.StatusTooManyRequests,
+				retryAfter: "60",
+				response:   string(toJSON(t, "too many failed login attempts, retry after 1m0s")) + "\n",
+			},
+		},
+		{
+			name:        "responses with internal server error",
+			requestBody: toJSON(t, map[string]string{"mfa_token": "mfa", "code": "123456"}),
+			authRes:     authenticateResult{err: errors.New("error")},
+			want:        want{code: http.StatusInternalServerError},
+		},
+	}
+
+	totpSrv := new(totpServiceMock)
+	handler := http.HandlerFunc(
+		handlers.NewUserHandlers(zaptest.NewLogger(t)).
+			AuthenticateTOTP(totpSrv),
+	)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authCall := totpSrv.On("Authenticate", mock.Anything, "mfa", "123456").
+				Return(tc.authRes.tokens, tc.authRes.err)
+			defer authCall.Unset()
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(
+				http.MethodPost,
+				"/api/user/login/totp",
+				bytes.NewReader(tc.requestBody),
+			)
+			require.NoError(t, err)
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.want.retryAfter, recorder.Header().Get("Retry-After"))
+			assert.Equal(t, tc.want.response, recorder.Body.String())
+			assert.Equal(t, tc.authRes.tokens.AccessToken != "", len(recorder.Result().Cookies()) > 0)
+		})
+	}
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	testCases := []struct {
+		name       string
+		enrollment services.TOTPEnrollment
+		enrollErr  error
+		code       int
+		response   string
+	}{
+		{
+			name:       "responses with secret",
+			enrollment: services.TOTPEnrollment{Secret: "SECRET", URI: "otpauth://totp/Gophkeeper:login?secret=SECRET"},
+			code:       http.StatusOK,
+			response:   `{"secret":"SECRET","uri":"otpauth://totp/Gophkeeper:login?secret=SECRET"}` + "\n",
+		},
+		{
+			name:      "responses with conflict status if TOTP is enabled",
+			enrollErr: services.ErrTOTPAlreadyEnabled,
+			code:      http.StatusConflict,
+			response:  string(toJSON(t, services.ErrTOTPAlreadyEnabled.Error())) + "\n",
+		},
+		{
+			name:      "responses with internal server error",
+			enrollErr: errors.New("error"),
+			code:      http.StatusInternalServerError,
+		},
+	}
+
+	totpSrv := new(totpServiceMock)
+	handler := http.HandlerFunc(
+		handlers.NewUserHandlers(zaptest.NewLogger(t)).
+			EnrollTOTP(totpSrv),
+	)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			enrollCall := totpSrv.On("Enroll", mock.Anything, mock.Anything).Return(tc.enrollment, tc.enrollErr)
+			defer enrollCall.Unset()
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/api/user/totp", nil)
+			require.NoError(t, err)
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, tc.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.response, recorder.Body.String())
+		})
+	}
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	testCases := []struct {
+		name        string
+		requestBody []byte
+		codes       []string
+		confirmErr  error
+		code        int
+		response    string
+	}{
+		{
+			name:        "responses with recovery codes",
+			requestBody: toJSON(t, map[string]string{"code": "123456"}),
+			codes:       []string{"aaaa-bbbb", "cccc-dddd"},
+			code:        http.StatusOK,
+			response:    `{"recovery_codes":["aaaa-bbbb","cccc-dddd"]}` + "\n",
+		},
+		{
+			name:        "responses with bad request status if request body is invalid",
+			requestBody: toJSON(t, "code: 123456"),
+			code:        http.StatusBadRequest,
+			response:    string(toJSON(t, "invalid request body")) + "\n",
+		},
+		{
+			name:        "responses with unprocessable entity status if code is invalid",
+			requestBody: toJSON(t, map[string]string{"code": "123456"}),
+			confirmErr:  services.ErrInvalidTOTPCode,
+			code:        http.StatusUnprocessableEntity,
+			response:    string(toJSON(t, services.ErrInvalidTOTPCode.Error())) + "\n",
+		},
+		{
+			name:        "responses with unprocessable entity status if TOTP is not enrolled",
+			requestBody: toJSON(t, map[string]string{"code": "123456"}),
+			confirmErr:  services.ErrTOTPNotEnrolled,
+			code:        http.StatusUnprocessableEntity,
+			response:    string(toJSON(t, services.ErrTOTPNotEnrolled.Error())) + "\n",
+		},
+		{
+			name:        "responses with conflict status if TOTP is enabled",
+			requestBody: toJSON(t, map[string]string{"code": "123456"}),
+			confirmErr:  services.ErrTOTPAlreadyEnabled,
+			code:        http.StatusConflict,
+			response:    string(toJSON(t, services.ErrTOTPAlreadyEnabled.Error())) + "\n",
+		},
+		{
+			name:        "responses with internal server error",
+			requestBody: toJSON(t, map[string]string{"code": "123456"}),
+			confirmErr:  errors.New("error"),
+			code:        http.StatusInternalServerError,
+		},
+	}
+
+	totpSrv := new(totpServiceMock)
+	handler := http.HandlerFunc(
+		handlers.NewUserHandlers(zaptest.NewLogger(t)).
+			ConfirmTOTP(totpSrv),
+	)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			confirmCall := totpSrv.On("Confirm", mock.Anything, mock.Anything, "123456").Return(tc.codes, tc.confirmErr)
+			defer confirmCall.Unset()
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(
+				http.MethodPost,
+				"/api/user/totp/confirm",
+				bytes.NewReader(tc.requestBody),
+			)
+			require.NoError(t, err)
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, tc.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.response, recorder.Body.String())
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	tokens := services.SessionTokens{AccessToken: "123", RefreshToken: "456"}
+	testCases := []struct {
+		name         string
+		refreshToken string
+		refreshErr   error
+		code         int
+		cookies      map[string]string
+	}{
+		{
+			name:         "responses with new tokens",
+			refreshToken: "refresh",
+			code:         http.StatusOK,
+			cookies:      map[string]string{"jwt": "123", "refresh_token": "456"},
+		},
+		{
+			name: "responses with status unauthorized without refresh token",
+			code: http.StatusUnauthorized,
+		},
+		{
+			name:         "responses with status unauthorized if refresh token is invalid",
+			refreshToken: "refresh",
+			refreshErr:   services.ErrInvalidRefreshToken,
+			code:         http.StatusUnauthorized,
+			cookies:      map[string]string{"jwt": "", "refresh_token": ""},
+		},
+		{
+			name:         "responses with status unauthorized if refresh token is not found",
+			refreshToken: "refresh",
+			refreshErr:   fmt.Errorf("failed to refresh session: %w", storage.ErrRefreshTokenNotFound{}),
+			code:         http.StatusUnauthorized,
+			cookies:      map[string]string{"jwt": "", "refresh_token": ""},
+		},
+		{
+			name:         "responses with internal server error",
+			refreshToken: "refresh",
+			refreshErr:   errors.New("error"),
+			code:         http.StatusInternalServerError,
+		},
+	}
+
+	sessionSrv := new(sessionServiceMock)
+	handler := http.HandlerFunc(
+		handlers.NewUserHandlers(zaptest.NewLogger(t)).
+			Refresh(sessionSrv),
+	)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			refreshCall := sessionSrv.On("Refresh", mock.Anything, "refresh").Return(tokens, tc.refreshErr)
+			defer refreshCall.Unset()
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/api/user/refresh", nil)
+			require.NoError(t, err)
+			if tc.refreshToken != "" {
+				request.AddCookie(&http.Cookie{Name: "refresh_token", Value: tc.refreshToken})
+			}
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, tc.code, recorder.Result().StatusCode)
+			cookies := make(map[string]string)
+			for _, cookie := range recorder.Result().Cookies() {
+				cookies[cookie.Name] = cookie.Value
+			}
+			if tc.cookies == nil {
+				assert.Empty(t, cookies)
+			} else {
+				assert.Equal(t, tc.cookies, cookies)
+			}
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	testCases := []struct {
+		name      string
+		revokeErr error
+		code      int
+	}{
+		{
+			name: "responses with ok status",
+			code: http.StatusOK,
+		},
+		{
+			name:      "responses with internal server error",
+			revokeErr: errors.New("error"),
+			code:      http.StatusInternalServerError,
+		},
+	}
+
+	sessionSrv := new(sessionServiceMock)
+	handler := http.HandlerFunc(
+		handlers.NewUserHandlers(zaptest.NewLogger(t)).
+			Logout(sessionSrv),
+	)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			revokeCall := sessionSrv.On("Revoke", mock.Anything, mock.Anything).Return(tc.revokeErr)
+			defer revokeCall.Unset()
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/api/user/logout", nil)
+			require.NoError(t, err)
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, tc.code, recorder.Result().StatusCode)
+			sessionSrv.AssertCalled(t, "Revoke", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestSessions(t *testing.T) {
+	createdAt := time.Date(2024, 5, 26, 10, 15, 30, 0, time.UTC)
+	expiresAt := createdAt.Add(30 * 24 * time.Hour)
+	sessionSrv := new(sessionServiceMock)
+	sessionSrv.On("List", mock.Anything, 1).Return(
+		[]models.Session{
+			{ID: 2, UserID: 1, CreatedAt: createdAt, ExpiresAt: expiresAt},
+			{ID: 3, UserID: 1, CreatedAt: createdAt, ExpiresAt: expiresAt},
+		},
+		nil,
+	)
+	handler := handlers.NewUserHandlers(zaptest.NewLogger(t)).Sessions(sessionSrv)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/api/user/sessions", nil)
+	request = request.WithContext(middlewares.WithSession(request.Context(), 1, 3))
+	handler(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	assert.JSONEq(
+		t,
+		`{"sessions":[
+		   {"id":2,"current":false,"created_at":"2024-05-26T10:15:30Z","expires_at":"2024-06-25T10:15:30Z"},
+		   {"id":3,"current":true,"created_at":"2024-05-26T10:15:30Z","expires_at":"2024-06-25T10:15:30Z"}
+		 ]}`,
+		recorder.Body.String(),
+	)
+}
+
+func TestRevokeSession(t *testing.T) {
+	testCases := []struct {
+		name          string
+		sessionID     string
+		revokeErr     error
+		code          int
+		clearsCookies bool
+	}{
+		{
+			name:      "revokes other session",
+			sessionID: "2",
+			code:      http.StatusOK,
+		},
+		{
+			name:          "revokes current session and clears cookies",
+			sessionID:     "3",
+			code:          http.StatusOK,
+			clearsCookies: true,
+		},
+		{
+			name:      "responds with not found if session belongs to another user",
+			sessionID: "4",
+			revokeErr: storage.ErrSessionNotFound{ID: 4},
+			code:      http.StatusNotFound,
+		},
+		{
+			name:      "responds with bad request if id is invalid",
+			sessionID: "abc",
+			code:      http.StatusBadRequest,
+		},
+	}
+
+	sessionSrv := new(sessionServiceMock)
+	router := chi.NewRouter()
+	router.Delete("/api/user/sessions/{id}", handlers.NewUserHandlers(zaptest.NewLogger(t)).RevokeSession(sessionSrv))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			revokeCall := sessionSrv.On("RevokeUserSession", mock.Anything, 1, mock.Anything).Return(tc.revokeErr)
+			defer revokeCall.Unset()
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodDelete, "/api/user/sessions/"+tc.sessionID, nil)
+			request = request.WithContext(middlewares.WithSession(request.Context(), 1, 3))
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.code, recorder.Result().StatusCode)
+			assert.Equal(t, tc.clearsCookies, len(recorder.Result().Cookies()) > 0)
+		})
+	}
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	sessionSrv := new(sessionServiceMock)
+	sessionSrv.On("RevokeOthers", mock.Anything, 1, 3).Return(int64(2), nil)
+	handler := handlers.NewUserHandlers(zaptest.NewLogger(t)).RevokeOtherSessions(sessionSrv)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/api/user/sessions/revoke-others", nil)
+	request = request.WithContext(middlewares.WithSession(request.Context(), 1, 3))
+	handler(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	assert.Equal(t, `{"revoked":2}`+"\n", recorder.Body.String())
+}
+
+func TestUpdateSettings(t *testing.T) {
+	type want struct {
+		code     int
+		response string
+	}
+	testCases := []struct {
+		name        string
+		requestBody []byte
+		updateErr   error
+		want        want
+	}{
+		{
+			name:        "responses with ok status",
+			requestBody: toJSON(t, map[string]int{"revisions_retention": 5}),
+			want:        want{code: http.StatusOK},
+		},
+		{
+			name:        "responses with bad request status if retention is missing",
+			requestBody: toJSON(t, map[string]int{}),
+			want: want{
+				code:     http.StatusBadRequest,
+				response: string(toJSON(t, "invalid request body")) + "\n",
+			},
+		},
+		{
+			name:        "responses with bad request status if retention is invalid",
+			requestBody: toJSON(t, map[string]int{"revisions_retention": 5}),
+			updateErr:   services.ErrInvalidRetention,
+			want: want{
+				code:     http.StatusBadRequest,
+				response: string(toJSON(t, services.ErrInvalidRetention.Error())) + "\n",
+			},
+		},
+		{
+			name:        "responses with internal server error status",
+			requestBody: toJSON(t, map[string]int{"revisions_retention": 5}),
+			updateErr:   errors.New("error"),
+			want:        want{code: http.StatusInternalServerError},
+		},
+	}
+
+	settingsSrv := new(userSettingsServiceMock)
+	handler := http.HandlerFunc(
+		handlers.NewUserHandlers(zaptest.NewLogger(t)).
+			UpdateSettings(settingsSrv),
+	)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			updateCall := settingsSrv.On("Update", mock.Anything, mock.Anything, models.UserSettings{RevisionsRetention: 5}).
+				Return(tc.updateErr)
+			defer updateCall.Unset()
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(
+				http.MethodPut,
+				"/api/user/settings",
+				bytes.NewReader(tc.requestBody),
+			)
+			require.NoError(t, err)
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, tc.want.code, recorder.Result().StatusCode)
 			assert.Equal(t, tc.want.response, recorder.Body.String())
 		})
 	}
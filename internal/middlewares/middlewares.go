@@ -2,12 +2,16 @@ package middlewares
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
-	"github.com/ilya-burinskiy/gophkeeper/internal/configs"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
 	"go.uber.org/zap"
 )
 
@@ -19,7 +23,28 @@ type loggingResponseWriter struct {
 
 type contextKey string
 
-const userIDKey contextKey = "user_id"
+const (
+	userIDKey           contextKey = "user_id"
+	sessionIDKey        contextKey = "session_id"
+	apiTokenKey         contextKey = "api_token"
+	serviceAccountIDKey contextKey = "service_account_id"
+)
+
+// SessionChecker reports whether the session has been revoked.
+type SessionChecker interface {
+	IsSessionRevoked(ctx context.Context, sessionID int) (bool, error)
+}
+
+// ServiceAccountChecker reports whether the service account has been
+// disabled.
+type ServiceAccountChecker interface {
+	IsServiceAccountDisabled(ctx context.Context, id int) (bool, error)
+}
+
+// APITokenAuthenticator finds valid API tokens by their value.
+type APITokenAuthenticator interface {
+	Authenticate(ctx context.Context, value string) (models.APIToken, error)
+}
 
 func (lw *loggingResponseWriter) Write(bytes []byte) (int, error) {
 	size, err := lw.ResponseWriter.Write(bytes)
@@ -33,6 +58,11 @@ func (lw *loggingResponseWriter) WriteHeader(status int) {
 	lw.Status = status
 }
 
+// Unwrap allows handlers to flush the response with http.ResponseController.
+func (lw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
+
 func LogResponse(logger *zap.Logger) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -62,25 +92,128 @@ func LogRequest(logger *zap.Logger) func(http.Handler) http.Handler {
 	}
 }
 
-func Authenticate(h http.Handler) http.Handler {
+// ClientInfo puts the client address and user agent into the request
+// context, so services record them in audit events. The address is taken
+// from the connection, forwarding headers are not trusted.
+func ClientInfo(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("jwt")
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
+			ip = r.RemoteAddr
 		}
+		ctx := services.WithClientInfo(r.Context(), services.ClientInfo{
+			IP:        ip,
+			UserAgent: r.UserAgent(),
+		})
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		claims := &auth.Claims{}
-		token, err := jwt.ParseWithClaims(cookie.Value, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(configs.SecretKey), nil
+// Authenticate rejects requests without a valid access token or with a
+// token of a revoked session. An API token may be sent instead in the
+// Authorization header as a bearer token, handlers get it with
+// APITokenFromContext to enforce its scope. Service accounts send their
+// access tokens as bearer tokens too, such tokens are rejected once the
+// service account is disabled.
+func Authenticate(
+	sessions SessionChecker,
+	tokens APITokenAuthenticator,
+	accounts ServiceAccountChecker) func(http.Handler) http.Handler {
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if authorization := r.Header.Get("Authorization"); authorization != "" {
+				value, ok := strings.CutPrefix(authorization, "Bearer ")
+				if !ok {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if !strings.HasPrefix(value, services.APITokenPrefix) {
+					authenticateServiceAccount(h, accounts, value, w, r)
+					return
+				}
+				token, err := tokens.Authenticate(r.Context(), value)
+				if err != nil {
+					var notFoundErr storage.ErrAPITokenNotFound
+					if errors.Is(err, services.ErrInvalidAPIToken) || errors.As(err, &notFoundErr) {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				h.ServeHTTP(w, r.WithContext(WithAPIToken(r.Context(), token)))
+				return
+			}
+
+			cookie, err := r.Cookie("jwt")
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := auth.ParseJWT(cookie.Value)
+			if err != nil || claims.SessionID == 0 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			revoked, err := sessions.IsSessionRevoked(r.Context(), claims.SessionID)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if revoked {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, sessionIDKey, claims.SessionID)
+			h.ServeHTTP(w, r.WithContext(ctx))
 		})
-		if err != nil || !token.Valid {
-			w.WriteHeader(http.StatusUnauthorized)
+	}
+}
+
+// authenticateServiceAccount serves the request as the user backing the
+// service account of the access token.
+func authenticateServiceAccount(
+	h http.Handler,
+	accounts ServiceAccountChecker,
+	tokenString string,
+	w http.ResponseWriter,
+	r *http.Request) {
+
+	claims, err := auth.ParseJWT(tokenString)
+	if err != nil || claims.ServiceAccountID == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	disabled, err := accounts.IsServiceAccountDisabled(r.Context(), claims.ServiceAccountID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if disabled {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+	ctx = context.WithValue(ctx, serviceAccountIDKey, claims.ServiceAccountID)
+	h.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireSession rejects requests authenticated with an API token or as
+// a service account, it protects endpoints which manage the user account
+// and are not meant for automation.
+func RequireSession(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := SessionIDFromContext(r.Context()); !ok {
+			w.WriteHeader(http.StatusForbidden)
 			return
 		}
-
-		ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
-		h.ServeHTTP(w, r.WithContext(ctx))
+		h.ServeHTTP(w, r)
 	})
 }
 
@@ -88,3 +221,37 @@ func UserIDFromContext(ctx context.Context) (int, bool) {
 	userID, ok := ctx.Value(userIDKey).(int)
 	return userID, ok
 }
+
+// SessionIDFromContext returns the session of the access token.
+func SessionIDFromContext(ctx context.Context) (int, bool) {
+	sessionID, ok := ctx.Value(sessionIDKey).(int)
+	return sessionID, ok
+}
+
+// WithSession returns the context of a request authenticated with an access
+// token of the user session.
+func WithSession(ctx context.Context, userID int, sessionID int) context.Context {
+	ctx = context.WithValue(ctx, userIDKey, userID)
+	return context.WithValue(ctx, sessionIDKey, sessionID)
+}
+
+// APITokenFromContext returns the API token the request is authenticated
+// with, false is returned for requests authenticated with a session.
+func APITokenFromContext(ctx context.Context) (models.APIToken, bool) {
+	token, ok := ctx.Value(apiTokenKey).(models.APIToken)
+	return token, ok
+}
+
+// WithAPIToken returns the context of a request authenticated with the
+// API token.
+func WithAPIToken(ctx context.Context, token models.APIToken) context.Context {
+	ctx = context.WithValue(ctx, userIDKey, token.UserID)
+	return context.WithValue(ctx, apiTokenKey, token)
+}
+
+// ServiceAccountIDFromContext returns the service account the request is
+// authenticated as.
+func ServiceAccountIDFromContext(ctx context.Context) (int, bool) {
+	serviceAccountID, ok := ctx.Value(serviceAccountIDKey).(int)
+	return serviceAccountID, ok
+}
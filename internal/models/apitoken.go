@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// TokenScope limits access of an API token to secrets. Empty SecretTypes
+// and SecretIDs do not limit the access.
+type TokenScope struct {
+	ReadOnly    bool
+	SecretTypes []SecretType
+	SecretIDs   []int
+}
+
+// LimitsSecrets reports whether the scope allows access to some of the
+// user secrets only.
+func (s TokenScope) LimitsSecrets() bool {
+	return len(s.SecretTypes) > 0 || len(s.SecretIDs) > 0
+}
+
+// AllowsSecretType reports whether the scope allows access to secrets of
+// the type.
+func (s TokenScope) AllowsSecretType(secretType SecretType) bool {
+	if len(s.SecretTypes) == 0 {
+		return true
+	}
+	for _, allowed := range s.SecretTypes {
+		if allowed == secretType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsSecret reports whether the scope allows access to the secret.
+func (s TokenScope) AllowsSecret(secretID int, secretType SecretType) bool {
+	if !s.AllowsSecretType(secretType) {
+		return false
+	}
+	if len(s.SecretIDs) == 0 {
+		return true
+	}
+	for _, allowed := range s.SecretIDs {
+		if allowed == secretID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// APIToken is a long-lived personal token for automation, only the hash
+// of the token is stored. ExpiresAt and LastUsedAt are zero if the token
+// never expires or has not been used.
+type APIToken struct {
+	ID         int
+	UserID     int
+	Name       string
+	Scope      TokenScope
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	CreatedAt  time.Time
+}
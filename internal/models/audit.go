@@ -0,0 +1,101 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"time"
+)
+
+type AuditAction int
+
+const (
+	_ AuditAction = iota
+	AuditRead
+	AuditCreate
+	AuditUpdate
+	AuditDelete
+	AuditRestore
+	AuditPurge
+)
+
+var auditActionNames = map[AuditAction]string{
+	AuditRead:    "read",
+	AuditCreate:  "create",
+	AuditUpdate:  "update",
+	AuditDelete:  "delete",
+	AuditRestore: "restore",
+	AuditPurge:   "purge",
+}
+
+func (action AuditAction) String() string {
+	return auditActionNames[action]
+}
+
+func ParseAuditAction(name string) (AuditAction, bool) {
+	for action, actionName := range auditActionNames {
+		if actionName == name {
+			return action, true
+		}
+	}
+
+	return 0, false
+}
+
+// AuditEvent records an action of the user with the secret. Events form
+// a chain, Hash covers the event fields and PrevHash, which is Hash of the
+// previous event, so changing or deleting an event breaks the chain.
+type AuditEvent struct {
+	ID       int
+	UserID   int
+	Action   AuditAction
+	SecretID int
+	// OwnerID is the secret owner
+	OwnerID int
+	// VaultID is zero for personal secrets
+	VaultID   int
+	ClientIP  string
+	UserAgent string
+	CreatedAt time.Time
+	PrevHash  []byte
+	Hash      []byte
+}
+
+// ComputeHash returns the hash of the event fields chained to PrevHash.
+// CreatedAt is hashed in UTC with microsecond precision as it is stored.
+func (event AuditEvent) ComputeHash() []byte {
+	fields, _ := json.Marshal(struct {
+		ID        int    `json:"id"`
+		UserID    int    `json:"user_id"`
+		Action    string `json:"action"`
+		SecretID  int    `json:"secret_id"`
+		OwnerID   int    `json:"owner_id"`
+		VaultID   int    `json:"vault_id"`
+		ClientIP  string `json:"client_ip"`
+		UserAgent string `json:"user_agent"`
+		CreatedAt string `json:"created_at"`
+	}{
+		ID:        event.ID,
+		UserID:    event.UserID,
+		Action:    event.Action.String(),
+		SecretID:  event.SecretID,
+		OwnerID:   event.OwnerID,
+		VaultID:   event.VaultID,
+		ClientIP:  event.ClientIP,
+		UserAgent: event.UserAgent,
+		CreatedAt: event.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	})
+	hash := sha256.New()
+	hash.Write(event.PrevHash)
+	hash.Write(fields)
+
+	return hash.Sum(nil)
+}
+
+// AuditFilter selects audit events created in [From, To), zero bounds
+// are not applied. SecretID selects events of a single secret if not zero.
+type AuditFilter struct {
+	From     time.Time
+	To       time.Time
+	SecretID int
+	Limit    int
+}
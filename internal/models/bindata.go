@@ -5,10 +5,17 @@ import (
 	"encoding/gob"
 )
 
+// BinData describes binary data. Content of secrets created before chunked
+// storage was introduced is kept in Bytes, content of newer ones is stored
+// in chunks and Size holds its length. SHA256 is the content hash, it is
+// not known for secrets created before it was introduced.
 type BinData struct {
 	ID       int
 	Filename string
+	Size     int64
+	SHA256   []byte
 	Bytes    []byte
+	Metadata Metadata `json:",omitempty"`
 }
 
 func (bin *BinData) Marshall() ([]byte, error) {
@@ -13,6 +13,7 @@ type CreditCard struct {
 	Name        string
 	ExpiryDate  time.Time
 	CVV2        string
+	Metadata    Metadata `json:",omitempty"`
 }
 
 func (cc *CreditCard) Marshall() ([]byte, error) {
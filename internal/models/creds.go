@@ -10,6 +10,7 @@ type Credentials struct {
 	Description string
 	Login       string
 	Password    string
+	Metadata    Metadata `json:",omitempty"`
 }
 
 func (creds *Credentials) Marshall() ([]byte, error) {
@@ -0,0 +1,17 @@
+package models
+
+// Folder groups user secrets. Folders of a user form a tree, ParentID
+// is zero for top level folders.
+type Folder struct {
+	ID       int
+	UserID   int
+	ParentID int
+	Name     string
+}
+
+// Tag labels user secrets, a secret may have any number of tags.
+type Tag struct {
+	ID     int
+	UserID int
+	Name   string
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// Metadata is user-defined key/value pairs attached to a secret, such as
+// url=https://example.com or env=prod.
+type Metadata map[string]string
+
+func (metadata Metadata) Marshall() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	err := encoder.Encode(metadata)
+
+	return buf.Bytes(), err
+}
+
+func (metadata *Metadata) Unmarshall(bs []byte) error {
+	buf := bytes.NewBuffer(bs)
+	decoder := gob.NewDecoder(buf)
+
+	return decoder.Decode(metadata)
+}
@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// Organization owns vaults shared by its members. Members with the admin
+// role, including the organization owner, manage members and vaults.
+type Organization struct {
+	ID        int
+	Name      string
+	OwnerID   int
+	CreatedAt time.Time
+	// Invited is set when the organization is listed for a user who has
+	// been invited to it but has not joined it yet
+	Invited bool
+}
+
+// OrganizationRole is the role of the organization member in all vaults of
+// the organization, every role includes the lower ones.
+type OrganizationRole int
+
+const (
+	_ OrganizationRole = iota
+	// OrganizationViewerRole allows to read vault secrets
+	OrganizationViewerRole
+	// OrganizationEditorRole allows to create and to change vault secrets
+	OrganizationEditorRole
+	// OrganizationAdminRole allows to delete vault secrets and to manage
+	// members and vaults, the organization owner always has it
+	OrganizationAdminRole
+)
+
+var organizationRoleNames = map[OrganizationRole]string{
+	OrganizationViewerRole: "viewer",
+	OrganizationEditorRole: "editor",
+	OrganizationAdminRole:  "admin",
+}
+
+func (role OrganizationRole) String() string {
+	return organizationRoleNames[role]
+}
+
+func ParseOrganizationRole(name string) (OrganizationRole, bool) {
+	for role, roleName := range organizationRoleNames {
+		if roleName == name {
+			return role, true
+		}
+	}
+
+	return 0, false
+}
+
+// SecretAccess returns the access to vault secrets the role gives.
+func (role OrganizationRole) SecretAccess() SecretAccess {
+	switch role {
+	case OrganizationViewerRole:
+		return SecretReadAccess
+	case OrganizationEditorRole:
+		return SecretWriteAccess
+	case OrganizationAdminRole:
+		return SecretOwnerAccess
+	default:
+		return 0
+	}
+}
+
+// OrganizationMember is a member of the organization or a user invited
+// to it, who gets access to the organization vaults once accepted.
+type OrganizationMember struct {
+	OrganizationID int
+	UserID         int
+	Login          string
+	Role           OrganizationRole
+	Accepted       bool
+	CreatedAt      time.Time
+}
+
+// Vault holds secrets of an organization, organization members have access
+// to them according to their roles.
+type Vault struct {
+	ID             int
+	OrganizationID int
+	Name           string
+	CreatedAt      time.Time
+}
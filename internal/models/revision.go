@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// SecretRevision is a previous version of a secret. Versions of every
+// secret are numbered from 1, CreatedAt is the time the version was saved.
+type SecretRevision struct {
+	ID                int
+	SecretID          int
+	Version           int
+	Description       string
+	EncryptedData     []byte
+	EncryptedKey      []byte
+	EncryptedMetadata []byte
+	Size              int
+	CreatedAt         time.Time
+}
+
+// Secret returns the secret as it was at the revision.
+func (revision SecretRevision) Secret(secret Secret) Secret {
+	secret.Description = revision.Description
+	secret.EncryptedData = revision.EncryptedData
+	secret.EncryptedKey = revision.EncryptedKey
+	secret.EncryptedMetadata = revision.EncryptedMetadata
+
+	return secret
+}
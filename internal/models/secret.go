@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type SecretType int
 
 const (
@@ -7,8 +9,30 @@ const (
 	CredentialsSecret
 	CreditCardSecret
 	BinDataSecret
+	TextSecret
 )
 
+var secretTypeNames = map[SecretType]string{
+	CredentialsSecret: "credentials",
+	CreditCardSecret:  "credit_card_info",
+	BinDataSecret:     "bin_data",
+	TextSecret:        "text",
+}
+
+func (t SecretType) String() string {
+	return secretTypeNames[t]
+}
+
+func ParseSecretType(name string) (SecretType, bool) {
+	for secretType, secretTypeName := range secretTypeNames {
+		if secretTypeName == name {
+			return secretType, true
+		}
+	}
+
+	return 0, false
+}
+
 type Secret struct {
 	ID            int
 	UserID        int
@@ -16,4 +40,49 @@ type Secret struct {
 	Description   string
 	EncryptedData []byte
 	EncryptedKey  []byte
+	// EncryptedMetadata is encrypted with the secret key, it is nil
+	// if the secret has no metadata
+	EncryptedMetadata []byte
+	// FolderID is zero if the secret is not in a folder
+	FolderID int
+	// VaultID is zero for personal secrets of the user
+	VaultID int
+	// Version changes on every change of the secret, it is used as
+	// the secret ETag
+	Version int
+}
+
+// SecretInfo describes a secret without its encrypted payload.
+type SecretInfo struct {
+	ID          int
+	SecretType  SecretType
+	Description string
+	Size        int
+	FolderID    int
+	Tags        []string
+	Version     int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	// DeletedAt is set for secrets in the trash only
+	DeletedAt time.Time
+	// Shared is the access to a secret of another user shared with the
+	// user, it is zero for own secrets
+	Shared SecretAccess
+}
+
+// SecretsFilter selects a page of secrets ordered by ID. Zero values
+// disable the corresponding condition.
+type SecretsFilter struct {
+	AfterID     int
+	Limit       int
+	SecretType  SecretType
+	Description string
+	// FolderID selects secrets of the folder and all of its subfolders
+	FolderID int
+	TagID    int
+	// VaultID selects secrets of the vault instead of personal secrets
+	// of the user and secrets shared with the user
+	VaultID int
+	// Scope selects secrets the API token of the request allows access to
+	Scope TokenScope
 }
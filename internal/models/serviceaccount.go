@@ -0,0 +1,83 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ServiceAccountLoginSuffix ends logins of users backing service accounts,
+// people can not register such logins.
+const ServiceAccountLoginSuffix = ".service-account"
+
+// ServiceAccount is a non-human principal of an organization for CI
+// pipelines and other automation. It is backed by a user without a
+// password, so it owns secrets, gets secrets shared and is recorded in the
+// audit log like any user, and it outlives the people who created it.
+// ClientID is the login of the backing user.
+type ServiceAccount struct {
+	ID             int
+	UserID         int
+	OrganizationID int
+	Name           string
+	ClientID       string
+	CreatedBy      int
+	Disabled       bool
+	CreatedAt      time.Time
+}
+
+// ServiceAccountClientID returns the client id of the service account with
+// the name in the organization.
+func ServiceAccountClientID(orgID int, name string) string {
+	return fmt.Sprintf("%s@%d%s", name, orgID, ServiceAccountLoginSuffix)
+}
+
+// IsServiceAccountLogin reports whether the login is reserved for users
+// backing service accounts.
+func IsServiceAccountLogin(login string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSpace(login)), ServiceAccountLoginSuffix)
+}
+
+// CredentialType is the way a service account proves its identity.
+type CredentialType int
+
+const (
+	_ CredentialType = iota
+	// CredentialSecret is a client secret, only its hash is stored
+	CredentialSecret
+	// CredentialPublicKey verifies JWT assertions signed by the service
+	// account
+	CredentialPublicKey
+)
+
+var credentialTypeNames = map[CredentialType]string{
+	CredentialSecret:    "secret",
+	CredentialPublicKey: "public_key",
+}
+
+func (credType CredentialType) String() string {
+	return credentialTypeNames[credType]
+}
+
+func ParseCredentialType(name string) (CredentialType, bool) {
+	for credType, credTypeName := range credentialTypeNames {
+		if credTypeName == name {
+			return credType, true
+		}
+	}
+
+	return 0, false
+}
+
+// ServiceAccountCredential is a client secret or a public key of
+// the service account, PublicKey is the PEM encoded key of public key
+// credentials. The credential id is the "kid" header of assertions signed
+// with the key.
+type ServiceAccountCredential struct {
+	ID               int
+	ServiceAccountID int
+	Type             CredentialType
+	PublicKey        string
+	LastUsedAt       time.Time
+	CreatedAt        time.Time
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// RefreshToken is a refresh token of the user session, only the hash of
+// the token is stored. Used is set once the token has been exchanged for
+// a new one.
+type RefreshToken struct {
+	SessionID      int
+	UserID         int
+	ExpiresAt      time.Time
+	Used           bool
+	SessionRevoked bool
+}
+
+// Session is an active session of the user, ExpiresAt is the time its
+// refresh token expires unless the session is refreshed.
+type Session struct {
+	ID        int
+	UserID    int
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
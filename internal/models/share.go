@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// SecretAccess is the level of access to a secret, every level includes
+// the lower ones.
+type SecretAccess int
+
+const (
+	_ SecretAccess = iota
+	SecretReadAccess
+	SecretWriteAccess
+	// SecretOwnerAccess allows to delete and share the secret, only the
+	// secret owner or an admin of the vault organization has it
+	SecretOwnerAccess
+)
+
+var secretAccessNames = map[SecretAccess]string{
+	SecretReadAccess:  "read",
+	SecretWriteAccess: "write",
+	SecretOwnerAccess: "owner",
+}
+
+func (access SecretAccess) String() string {
+	return secretAccessNames[access]
+}
+
+func ParseSecretAccess(name string) (SecretAccess, bool) {
+	for access, accessName := range secretAccessNames {
+		if accessName == name {
+			return access, true
+		}
+	}
+
+	return 0, false
+}
+
+// SecretShare grants the user access to the secret of another user.
+type SecretShare struct {
+	SecretID  int
+	UserID    int
+	Login     string
+	Access    SecretAccess
+	CreatedAt time.Time
+}
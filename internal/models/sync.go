@@ -0,0 +1,47 @@
+package models
+
+type SecretChangeKind int
+
+const (
+	_ SecretChangeKind = iota
+	SecretCreated
+	SecretUpdated
+	SecretDeleted
+)
+
+var secretChangeKindNames = map[SecretChangeKind]string{
+	SecretCreated: "created",
+	SecretUpdated: "updated",
+	SecretDeleted: "deleted",
+}
+
+func (kind SecretChangeKind) String() string {
+	return secretChangeKindNames[kind]
+}
+
+func ParseSecretChangeKind(name string) (SecretChangeKind, bool) {
+	for kind, kindName := range secretChangeKindNames {
+		if kindName == name {
+			return kind, true
+		}
+	}
+
+	return 0, false
+}
+
+// SecretChange is the secret state after the change at the revision.
+// Only Secret.ID and Secret.UserID are set for deleted secrets.
+type SecretChange struct {
+	Kind     SecretChangeKind
+	Revision int
+	Secret   Secret
+}
+
+// SecretEvent notifies that the secret has been changed at the revision.
+type SecretEvent struct {
+	Kind     SecretChangeKind
+	UserID   int
+	VaultID  int
+	SecretID int
+	Revision int
+}
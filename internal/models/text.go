@@ -0,0 +1,30 @@
+package models
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// Text is a free-text note with a title and a multiline body.
+type Text struct {
+	ID          int
+	Description string
+	Title       string
+	Body        string
+	Metadata    Metadata `json:",omitempty"`
+}
+
+func (text *Text) Marshall() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	err := encoder.Encode(text)
+
+	return buf.Bytes(), err
+}
+
+func (text *Text) Unmarshall(b []byte) error {
+	buf := bytes.NewBuffer(b)
+	decoder := gob.NewDecoder(buf)
+
+	return decoder.Decode(text)
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Upload is a resumable bin data upload. SecretID is the secret to be
+// replaced by the upload content, for a new secret it is set once the
+// upload is completed.
+type Upload struct {
+	ID            string
+	UserID        int
+	SecretID      int
+	Description   string
+	Length        int64
+	Offset        int64
+	EncryptedData []byte
+	EncryptedKey  []byte
+	// EncryptedHashState is the encrypted state of the content hash at Offset
+	EncryptedHashState []byte
+	EncryptedMetadata  []byte
+	// SecretVersion is the version of the replaced secret the upload is
+	// based on, the upload is not completed if the secret has changed
+	SecretVersion int
+	// VaultID is the vault of the new secret, it is zero for a personal
+	// secret of the user
+	VaultID   int
+	Completed bool
+	ExpiresAt time.Time
+}
@@ -4,4 +4,23 @@ type User struct {
 	ID                int
 	Login             string
 	EncryptedPassword []byte
+	// TOTPEnabled is set if logins require a TOTP code
+	TOTPEnabled bool
+}
+
+// UserTOTP is the TOTP secret of the user encrypted with EncryptedKey.
+// Enabled is not set until the user confirms the secret with a code.
+// LastStep is the time step of the last accepted code.
+type UserTOTP struct {
+	EncryptedSecret []byte
+	EncryptedKey    []byte
+	Enabled         bool
+	LastStep        int64
+}
+
+// UserSettings are preferences of a user.
+type UserSettings struct {
+	// RevisionsRetention is the number of previous versions kept for
+	// every secret, zero disables secret history.
+	RevisionsRetention int
 }
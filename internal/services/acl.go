@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+type SecretAccessFinder interface {
+	// FindSecretShareAccess returns zero access if the secret is not
+	// shared with the user.
+	FindSecretShareAccess(ctx context.Context, secretID int, userID int) (models.SecretAccess, error)
+	// FindSecretShareAccesses returns the access the secret is shared with
+	// by user id, users from userIDs the secret is not shared with are
+	// omitted.
+	FindSecretShareAccesses(ctx context.Context, secretID int, userIDs []int) (map[int]models.SecretAccess, error)
+	// FindVaultAccess returns zero access if the user is not a member of
+	// the vault organization.
+	FindVaultAccess(ctx context.Context, vaultID int, userID int) (models.SecretAccess, error)
+	// FindVaultAccesses returns the vault access by user id, users from
+	// userIDs who are not members of the vault organization are omitted.
+	FindVaultAccesses(ctx context.Context, vaultID int, userIDs []int) (map[int]models.SecretAccess, error)
+	// FindOrganizationRole returns zero role if the user is not a member
+	// of the organization.
+	FindOrganizationRole(ctx context.Context, orgID int, userID int) (models.OrganizationRole, error)
+}
+
+type SecretAuthorizer interface {
+	Authorize(ctx context.Context, userID int, secret models.Secret, access models.SecretAccess) error
+	AuthorizeVault(ctx context.Context, userID int, vaultID int, access models.SecretAccess) error
+}
+
+type OrganizationAuthorizer interface {
+	AuthorizeOrganization(ctx context.Context, userID int, orgID int, role models.OrganizationRole) error
+}
+
+// SecretACL is the single place where permissions are checked. It gives
+// the secret owner full access to the secret and other users the access
+// the secret is shared with. Access to vault secrets is given by the role
+// of the user in the vault organization only, so it is lost along with
+// the membership.
+type SecretACL struct {
+	finder SecretAccessFinder
+}
+
+func NewSecretACL(finder SecretAccessFinder) SecretACL {
+	return SecretACL{
+		finder: finder,
+	}
+}
+
+// Authorize returns ErrNoPermission if the user does not have the access
+// to the secret.
+func (acl SecretACL) Authorize(ctx context.Context, userID int, secret models.Secret, access models.SecretAccess) error {
+	if secret.VaultID != 0 {
+		granted, err := acl.finder.FindVaultAccess(ctx, secret.VaultID, userID)
+		if err != nil {
+			return err
+		}
+		if granted >= access {
+			return nil
+		}
+		return ErrNoPermission{UserID: userID, SecretID: secret.ID}
+	}
+	if userID == secret.UserID {
+		return nil
+	}
+	if access < models.SecretOwnerAccess {
+		granted, err := acl.finder.FindSecretShareAccess(ctx, secret.ID, userID)
+		if err != nil {
+			return err
+		}
+		if granted >= access {
+			return nil
+		}
+	}
+
+	return ErrNoPermission{UserID: userID, SecretID: secret.ID}
+}
+
+// AuthorizedUsers returns users from userIDs who have the access to the
+// secret. It follows the rules of Authorize, but checks all the users with
+// a single storage query.
+func (acl SecretACL) AuthorizedUsers(
+	ctx context.Context,
+	userIDs []int,
+	secret models.Secret,
+	access models.SecretAccess) ([]int, error) {
+
+	var (
+		granted map[int]models.SecretAccess
+		err     error
+	)
+	switch {
+	case secret.VaultID != 0:
+		granted, err = acl.finder.FindVaultAccesses(ctx, secret.VaultID, userIDs)
+	case access < models.SecretOwnerAccess:
+		granted, err = acl.finder.FindSecretShareAccesses(ctx, secret.ID, userIDs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	authorized := make([]int, 0, len(userIDs))
+	for _, userID := range userIDs {
+		isOwner := secret.VaultID == 0 && userID == secret.UserID
+		if isOwner || granted[userID] >= access {
+			authorized = append(authorized, userID)
+		}
+	}
+
+	return authorized, nil
+}
+
+// AuthorizeVault returns ErrNoPermission if the user does not have the
+// access to secrets of the vault.
+func (acl SecretACL) AuthorizeVault(ctx context.Context, userID int, vaultID int, access models.SecretAccess) error {
+	granted, err := acl.finder.FindVaultAccess(ctx, vaultID, userID)
+	if err != nil {
+		return err
+	}
+	if granted < access {
+		return ErrNoPermission{UserID: userID, VaultID: vaultID}
+	}
+
+	return nil
+}
+
+// AuthorizeOrganization returns ErrNoPermission if the user is not
+// a member of the organization with at least the role.
+func (acl SecretACL) AuthorizeOrganization(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	role models.OrganizationRole) error {
+
+	granted, err := acl.finder.FindOrganizationRole(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if granted < role {
+		return ErrNoPermission{UserID: userID, OrganizationID: orgID}
+	}
+
+	return nil
+}
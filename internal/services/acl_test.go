@@ -0,0 +1,276 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type secretAccessFinderMock struct{ mock.Mock }
+
+func (m *secretAccessFinderMock) FindSecretShareAccess(ctx context.Context, secretID int, userID int) (models.SecretAccess, error) {
+	args := m.Called(ctx, secretID, userID)
+	return args.Get(0).(models.SecretAccess), args.Error(1)
+}
+
+func (m *secretAccessFinderMock) FindVaultAccess(ctx context.Context, vaultID int, userID int) (models.SecretAccess, error) {
+	args := m.Called(ctx, vaultID, userID)
+	return args.Get(0).(models.SecretAccess), args.Error(1)
+}
+
+func (m *secretAccessFinderMock) FindSecretShareAccesses(
+	ctx context.Context,
+	secretID int,
+	userIDs []int) (map[int]models.SecretAccess, error) {
+
+	args := m.Called(ctx, secretID, userIDs)
+	return args.Get(0).(map[int]models.SecretAccess), args.Error(1)
+}
+
+func (m *secretAccessFinderMock) FindVaultAccesses(
+	ctx context.Context,
+	vaultID int,
+	userIDs []int) (map[int]models.SecretAccess, error) {
+
+	args := m.Called(ctx, vaultID, userIDs)
+	return args.Get(0).(map[int]models.SecretAccess), args.Error(1)
+}
+
+func (m *secretAccessFinderMock) FindOrganizationRole(
+	ctx context.Context,
+	orgID int,
+	userID int) (models.OrganizationRole, error) {
+
+	args := m.Called(ctx, orgID, userID)
+	return args.Get(0).(models.OrganizationRole), args.Error(1)
+}
+
+func TestSecretACLAuthorize(t *testing.T) {
+	type findResult struct {
+		access models.SecretAccess
+		err    error
+	}
+	secret := models.Secret{ID: 1, UserID: 1}
+	testCases := []struct {
+		name    string
+		userID  int
+		access  models.SecretAccess
+		findRes findResult
+		wantErr error
+	}{
+		{
+			name:   "gives owner access to owner",
+			userID: 1,
+			access: models.SecretOwnerAccess,
+		},
+		{
+			name:    "gives read access to user with read share",
+			userID:  2,
+			access:  models.SecretReadAccess,
+			findRes: findResult{access: models.SecretReadAccess},
+		},
+		{
+			name:    "gives read access to user with write share",
+			userID:  2,
+			access:  models.SecretReadAccess,
+			findRes: findResult{access: models.SecretWriteAccess},
+		},
+		{
+			name:    "gives write access to user with write share",
+			userID:  2,
+			access:  models.SecretWriteAccess,
+			findRes: findResult{access: models.SecretWriteAccess},
+		},
+		{
+			name:    "denies write access to user with read share",
+			userID:  2,
+			access:  models.SecretWriteAccess,
+			findRes: findResult{access: models.SecretReadAccess},
+			wantErr: services.ErrNoPermission{UserID: 2, SecretID: 1},
+		},
+		{
+			name:    "denies owner access to user with write share",
+			userID:  2,
+			access:  models.SecretOwnerAccess,
+			findRes: findResult{access: models.SecretWriteAccess},
+			wantErr: services.ErrNoPermission{UserID: 2, SecretID: 1},
+		},
+		{
+			name:    "denies read access if secret is not shared",
+			userID:  2,
+			access:  models.SecretReadAccess,
+			wantErr: services.ErrNoPermission{UserID: 2, SecretID: 1},
+		},
+		{
+			name:    "returns error if share can not be found",
+			userID:  2,
+			access:  models.SecretReadAccess,
+			findRes: findResult{err: errors.New("error")},
+			wantErr: errors.New("error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			finder := new(secretAccessFinderMock)
+			finder.On("FindSecretShareAccess", mock.Anything, secret.ID, tc.userID).
+				Return(tc.findRes.access, tc.findRes.err)
+			acl := services.NewSecretACL(finder)
+
+			err := acl.Authorize(context.TODO(), tc.userID, secret, tc.access)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestSecretACLAuthorizeVaultSecret(t *testing.T) {
+	type findResult struct {
+		access models.SecretAccess
+		err    error
+	}
+	secret := models.Secret{ID: 1, UserID: 1, VaultID: 2}
+	testCases := []struct {
+		name    string
+		userID  int
+		access  models.SecretAccess
+		findRes findResult
+		wantErr error
+	}{
+		{
+			name:    "gives write access to editor",
+			userID:  2,
+			access:  models.SecretWriteAccess,
+			findRes: findResult{access: models.OrganizationEditorRole.SecretAccess()},
+		},
+		{
+			name:    "denies write access to viewer",
+			userID:  2,
+			access:  models.SecretWriteAccess,
+			findRes: findResult{access: models.OrganizationViewerRole.SecretAccess()},
+			wantErr: services.ErrNoPermission{UserID: 2, SecretID: 1},
+		},
+		{
+			name:    "denies owner access to editor",
+			userID:  2,
+			access:  models.SecretOwnerAccess,
+			findRes: findResult{access: models.SecretWriteAccess},
+			wantErr: services.ErrNoPermission{UserID: 2, SecretID: 1},
+		},
+		{
+			name:    "gives owner access to admin",
+			userID:  3,
+			access:  models.SecretOwnerAccess,
+			findRes: findResult{access: models.OrganizationAdminRole.SecretAccess()},
+		},
+		{
+			name:    "denies read access to secret creator removed from organization",
+			userID:  1,
+			access:  models.SecretReadAccess,
+			wantErr: services.ErrNoPermission{UserID: 1, SecretID: 1},
+		},
+		{
+			name:    "returns error if vault access can not be found",
+			userID:  2,
+			access:  models.SecretReadAccess,
+			findRes: findResult{err: errors.New("error")},
+			wantErr: errors.New("error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			finder := new(secretAccessFinderMock)
+			finder.On("FindVaultAccess", mock.Anything, secret.VaultID, tc.userID).
+				Return(tc.findRes.access, tc.findRes.err)
+			acl := services.NewSecretACL(finder)
+
+			err := acl.Authorize(context.TODO(), tc.userID, secret, tc.access)
+			assert.Equal(t, tc.wantErr, err)
+			finder.AssertNotCalled(t, "FindSecretShareAccess", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestSecretACLAuthorizeVault(t *testing.T) {
+	finder := new(secretAccessFinderMock)
+	finder.On("FindVaultAccess", mock.Anything, 1, 1).Return(models.SecretWriteAccess, nil)
+	finder.On("FindVaultAccess", mock.Anything, 1, 2).Return(models.SecretAccess(0), nil)
+	acl := services.NewSecretACL(finder)
+
+	assert.NoError(t, acl.AuthorizeVault(context.TODO(), 1, 1, models.SecretWriteAccess))
+	assert.Equal(
+		t,
+		services.ErrNoPermission{UserID: 1, VaultID: 1},
+		acl.AuthorizeVault(context.TODO(), 1, 1, models.SecretOwnerAccess),
+	)
+	assert.Equal(
+		t,
+		services.ErrNoPermission{UserID: 2, VaultID: 1},
+		acl.AuthorizeVault(context.TODO(), 2, 1, models.SecretReadAccess),
+	)
+}
+
+func TestSecretACLAuthorizedUsers(t *testing.T) {
+	userIDs := []int{1, 2, 3, 4}
+	finder := new(secretAccessFinderMock)
+	finder.On("FindSecretShareAccesses", mock.Anything, 1, userIDs).
+		Return(map[int]models.SecretAccess{2: models.SecretReadAccess, 3: models.SecretWriteAccess}, nil)
+	// user 1 owns the vault secret, but has been removed from the vault organization
+	finder.On("FindVaultAccesses", mock.Anything, 7, userIDs).
+		Return(map[int]models.SecretAccess{2: models.SecretReadAccess, 4: models.SecretOwnerAccess}, nil)
+	finder.On("FindSecretShareAccesses", mock.Anything, 3, userIDs).
+		Return(map[int]models.SecretAccess(nil), errors.New("error"))
+	acl := services.NewSecretACL(finder)
+
+	authorized, err := acl.AuthorizedUsers(context.TODO(), userIDs, models.Secret{ID: 1, UserID: 1}, models.SecretReadAccess)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, authorized)
+
+	authorized, err = acl.AuthorizedUsers(context.TODO(), userIDs, models.Secret{ID: 1, UserID: 1}, models.SecretWriteAccess)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, authorized)
+
+	// shares never give the owner access, so they are not queried
+	authorized, err = acl.AuthorizedUsers(context.TODO(), userIDs, models.Secret{ID: 2, UserID: 1}, models.SecretOwnerAccess)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, authorized)
+
+	authorized, err = acl.AuthorizedUsers(
+		context.TODO(),
+		userIDs,
+		models.Secret{ID: 5, UserID: 1, VaultID: 7},
+		models.SecretReadAccess,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 4}, authorized)
+
+	_, err = acl.AuthorizedUsers(context.TODO(), userIDs, models.Secret{ID: 3, UserID: 1}, models.SecretReadAccess)
+	assert.Equal(t, errors.New("error"), err)
+	finder.AssertNotCalled(t, "FindSecretShareAccesses", mock.Anything, 2, mock.Anything)
+}
+
+func TestSecretACLAuthorizeOrganization(t *testing.T) {
+	finder := new(secretAccessFinderMock)
+	finder.On("FindOrganizationRole", mock.Anything, 1, 1).Return(models.OrganizationAdminRole, nil)
+	finder.On("FindOrganizationRole", mock.Anything, 1, 2).Return(models.OrganizationViewerRole, nil)
+	finder.On("FindOrganizationRole", mock.Anything, 1, 3).Return(models.OrganizationRole(0), nil)
+	acl := services.NewSecretACL(finder)
+
+	assert.NoError(t, acl.AuthorizeOrganization(context.TODO(), 1, 1, models.OrganizationAdminRole))
+	assert.NoError(t, acl.AuthorizeOrganization(context.TODO(), 2, 1, models.OrganizationViewerRole))
+	assert.Equal(
+		t,
+		services.ErrNoPermission{UserID: 2, OrganizationID: 1},
+		acl.AuthorizeOrganization(context.TODO(), 2, 1, models.OrganizationEditorRole),
+	)
+	assert.Equal(
+		t,
+		services.ErrNoPermission{UserID: 3, OrganizationID: 1},
+		acl.AuthorizeOrganization(context.TODO(), 3, 1, models.OrganizationViewerRole),
+	)
+}
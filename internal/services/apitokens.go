@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+const (
+	// APITokenPrefix tells API tokens from JWT and makes leaked tokens
+	// easy to find.
+	APITokenPrefix     = "gkp_"
+	apiTokenSize       = 32
+	maxAPITokenNameLen = 100
+)
+
+type APITokenStorage interface {
+	CreateAPIToken(ctx context.Context, token models.APIToken, tokenHash []byte) (models.APIToken, error)
+	FindAPIToken(ctx context.Context, tokenHash []byte) (models.APIToken, error)
+	ListUserAPITokens(ctx context.Context, userID int) ([]models.APIToken, error)
+	DeleteUserAPIToken(ctx context.Context, userID int, id int) error
+	TouchAPIToken(ctx context.Context, id int) error
+}
+
+type APITokenService struct {
+	storage APITokenStorage
+	randGen RandGen
+}
+
+func NewAPITokenService(storage APITokenStorage, randGen RandGen) APITokenService {
+	return APITokenService{
+		storage: storage,
+		randGen: randGen,
+	}
+}
+
+// Create creates an API token of the user and returns it with its value.
+// Only the hash of the value is stored, so it can not be shown again.
+// Zero expiresAt creates a token which never expires.
+func (srv APITokenService) Create(
+	ctx context.Context,
+	userID int,
+	name string,
+	scope models.TokenScope,
+	expiresAt time.Time) (models.APIToken, string, error) {
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxAPITokenNameLen {
+		return models.APIToken{}, "", ErrInvalidAPITokenName
+	}
+	if !expiresAt.IsZero() && !expiresAt.After(time.Now()) {
+		return models.APIToken{}, "", ErrInvalidAPITokenExpiry
+	}
+	bs, err := srv.randGen.Gen(apiTokenSize)
+	if err != nil {
+		return models.APIToken{}, "", fmt.Errorf("failed to generate api token: %w", err)
+	}
+	value := APITokenPrefix + base64.RawURLEncoding.EncodeToString(bs)
+	token, err := srv.storage.CreateAPIToken(
+		ctx,
+		models.APIToken{UserID: userID, Name: name, Scope: scope, ExpiresAt: expiresAt},
+		hashAPIToken(value),
+	)
+	if err != nil {
+		return models.APIToken{}, "", err
+	}
+
+	return token, value, nil
+}
+
+func (srv APITokenService) List(ctx context.Context, userID int) ([]models.APIToken, error) {
+	return srv.storage.ListUserAPITokens(ctx, userID)
+}
+
+// Revoke deletes the API token of the user, requests with it are rejected
+// from now on.
+func (srv APITokenService) Revoke(ctx context.Context, userID int, id int) error {
+	return srv.storage.DeleteUserAPIToken(ctx, userID, id)
+}
+
+// Authenticate returns the API token with the value. ErrInvalidAPIToken is
+// returned if the value is not an API token or the token has expired.
+func (srv APITokenService) Authenticate(ctx context.Context, value string) (models.APIToken, error) {
+	if !strings.HasPrefix(value, APITokenPrefix) {
+		return models.APIToken{}, ErrInvalidAPIToken
+	}
+	token, err := srv.storage.FindAPIToken(ctx, hashAPIToken(value))
+	if err != nil {
+		return models.APIToken{}, fmt.Errorf("failed to authenticate api token: %w", err)
+	}
+	if !token.ExpiresAt.IsZero() && !token.ExpiresAt.After(time.Now()) {
+		return models.APIToken{}, ErrInvalidAPIToken
+	}
+	if err := srv.storage.TouchAPIToken(ctx, token.ID); err != nil {
+		return models.APIToken{}, fmt.Errorf("failed to authenticate api token: %w", err)
+	}
+
+	return token, nil
+}
+
+func hashAPIToken(value string) []byte {
+	hash := sha256.Sum256([]byte(value))
+	return hash[:]
+}
@@ -0,0 +1,207 @@
+package services_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type apiTokenStorageMock struct{ mock.Mock }
+
+func (m *apiTokenStorageMock) CreateAPIToken(
+	ctx context.Context,
+	token models.APIToken,
+	tokenHash []byte) (models.APIToken, error) {
+
+	args := m.Called(ctx, token, tokenHash)
+	return args.Get(0).(models.APIToken), args.Error(1)
+}
+
+func (m *apiTokenStorageMock) FindAPIToken(ctx context.Context, tokenHash []byte) (models.APIToken, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(models.APIToken), args.Error(1)
+}
+
+func (m *apiTokenStorageMock) ListUserAPITokens(ctx context.Context, userID int) ([]models.APIToken, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.APIToken), args.Error(1)
+}
+
+func (m *apiTokenStorageMock) DeleteUserAPIToken(ctx context.Context, userID int, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *apiTokenStorageMock) TouchAPIToken(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func TestAPITokenCreate(t *testing.T) {
+	randGen := new(randGenMock)
+	randGen.On("Gen", 32).Return(make([]byte, 32), nil)
+	value := services.APITokenPrefix + base64.RawURLEncoding.EncodeToString(make([]byte, 32))
+	hash := sha256.Sum256([]byte(value))
+	scope := models.TokenScope{ReadOnly: true, SecretTypes: []models.SecretType{models.CredentialsSecret}}
+	expiresAt := time.Now().Add(time.Hour)
+
+	testCases := []struct {
+		name      string
+		tokenName string
+		expiresAt time.Time
+		wantErr   error
+	}{
+		{
+			name:      "creates token",
+			tokenName: " ci ",
+			expiresAt: expiresAt,
+		},
+		{
+			name:      "creates token which never expires",
+			tokenName: "ci",
+		},
+		{
+			name:      "returns error if name is empty",
+			tokenName: " ",
+			wantErr:   services.ErrInvalidAPITokenName,
+		},
+		{
+			name:      "returns error if expiry is in the past",
+			tokenName: "ci",
+			expiresAt: time.Now().Add(-time.Minute),
+			wantErr:   services.ErrInvalidAPITokenExpiry,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(apiTokenStorageMock)
+			want := models.APIToken{UserID: 1, Name: "ci", Scope: scope, ExpiresAt: tc.expiresAt}
+			created := want
+			created.ID = 2
+			store.On("CreateAPIToken", mock.Anything, want, hash[:]).Return(created, nil)
+			srv := services.NewAPITokenService(store, randGen)
+
+			token, tokenValue, err := srv.Create(context.TODO(), 1, tc.tokenName, scope, tc.expiresAt)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				store.AssertNotCalled(t, "CreateAPIToken", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, created, token)
+			assert.Equal(t, value, tokenValue)
+		})
+	}
+}
+
+func TestAPITokenAuthenticate(t *testing.T) {
+	value := services.APITokenPrefix + "token"
+	hash := sha256.Sum256([]byte(value))
+	valid := models.APIToken{ID: 2, UserID: 1, Name: "ci"}
+
+	t.Run("returns token", func(t *testing.T) {
+		store := new(apiTokenStorageMock)
+		store.On("FindAPIToken", mock.Anything, hash[:]).Return(valid, nil)
+		store.On("TouchAPIToken", mock.Anything, 2).Return(nil)
+		srv := services.NewAPITokenService(store, new(randGenMock))
+
+		token, err := srv.Authenticate(context.TODO(), value)
+		require.NoError(t, err)
+		assert.Equal(t, valid, token)
+		store.AssertCalled(t, "TouchAPIToken", mock.Anything, 2)
+	})
+
+	t.Run("rejects value without prefix", func(t *testing.T) {
+		store := new(apiTokenStorageMock)
+		srv := services.NewAPITokenService(store, new(randGenMock))
+
+		_, err := srv.Authenticate(context.TODO(), "token")
+		assert.ErrorIs(t, err, services.ErrInvalidAPIToken)
+		store.AssertNotCalled(t, "FindAPIToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects expired token", func(t *testing.T) {
+		expired := valid
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		store := new(apiTokenStorageMock)
+		store.On("FindAPIToken", mock.Anything, hash[:]).Return(expired, nil)
+		srv := services.NewAPITokenService(store, new(randGenMock))
+
+		_, err := srv.Authenticate(context.TODO(), value)
+		assert.ErrorIs(t, err, services.ErrInvalidAPIToken)
+		store.AssertNotCalled(t, "TouchAPIToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("returns error if failed to find token", func(t *testing.T) {
+		store := new(apiTokenStorageMock)
+		store.On("FindAPIToken", mock.Anything, hash[:]).Return(models.APIToken{}, errors.New("error"))
+		srv := services.NewAPITokenService(store, new(randGenMock))
+
+		_, err := srv.Authenticate(context.TODO(), value)
+		assert.EqualError(t, err, "failed to authenticate api token: error")
+	})
+}
+
+func TestTokenScopeAllowsSecret(t *testing.T) {
+	testCases := []struct {
+		name       string
+		scope      models.TokenScope
+		secretID   int
+		secretType models.SecretType
+		want       bool
+	}{
+		{
+			name:       "unlimited scope allows any secret",
+			secretID:   1,
+			secretType: models.TextSecret,
+			want:       true,
+		},
+		{
+			name:       "allows secret of allowed type",
+			scope:      models.TokenScope{SecretTypes: []models.SecretType{models.TextSecret}},
+			secretID:   1,
+			secretType: models.TextSecret,
+			want:       true,
+		},
+		{
+			name:       "rejects secret of other type",
+			scope:      models.TokenScope{SecretTypes: []models.SecretType{models.TextSecret}},
+			secretID:   1,
+			secretType: models.CredentialsSecret,
+		},
+		{
+			name:       "allows allowed secret",
+			scope:      models.TokenScope{SecretIDs: []int{1, 2}},
+			secretID:   2,
+			secretType: models.CredentialsSecret,
+			want:       true,
+		},
+		{
+			name:       "rejects other secret",
+			scope:      models.TokenScope{SecretIDs: []int{1, 2}},
+			secretID:   3,
+			secretType: models.CredentialsSecret,
+		},
+		{
+			name:       "rejects new secret if scope is limited to secret ids",
+			scope:      models.TokenScope{SecretIDs: []int{1}},
+			secretType: models.CredentialsSecret,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.scope.AllowsSecret(tc.secretID, tc.secretType))
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+const (
+	DefaultAuditPageSize = 100
+	MaxAuditPageSize     = 1000
+)
+
+// ClientInfo describes the client the request came from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo returns a copy of ctx carrying the client info, which is
+// recorded in audit events.
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFromContext returns the client info set by WithClientInfo.
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
+
+// newAuditEvent returns the event of the user action with the client from
+// ctx, the secret fields are filled by Record or by storage.
+func newAuditEvent(ctx context.Context, userID int, action models.AuditAction) models.AuditEvent {
+	client := ClientInfoFromContext(ctx)
+	return models.AuditEvent{
+		UserID:    userID,
+		Action:    action,
+		ClientIP:  client.IP,
+		UserAgent: client.UserAgent,
+	}
+}
+
+// AuditRecorder records actions of the user with secrets.
+type AuditRecorder interface {
+	Record(ctx context.Context, userID int, action models.AuditAction, secrets ...models.Secret) error
+}
+
+type AuditStorage interface {
+	CreateAuditEvents(ctx context.Context, events []models.AuditEvent) error
+	ListAuditEvents(ctx context.Context, userID int, filter models.AuditFilter) ([]models.AuditEvent, error)
+	StreamAuditEvents(ctx context.Context, fn func(models.AuditEvent) error) error
+}
+
+// AuditVerification is the result of the audit log check, BrokenEventID is
+// the first event which does not match its hash or the previous event.
+type AuditVerification struct {
+	Events        int
+	Valid         bool
+	BrokenEventID int
+}
+
+type AuditService struct {
+	storage AuditStorage
+}
+
+func NewAuditService(storage AuditStorage) AuditService {
+	return AuditService{
+		storage: storage,
+	}
+}
+
+// Record appends an event per secret to the audit log, the client is
+// taken from ctx. It records reads, changes are recorded by storage in the
+// transaction of the change with the event of newAuditEvent.
+func (srv AuditService) Record(
+	ctx context.Context,
+	userID int,
+	action models.AuditAction,
+	secrets ...models.Secret) error {
+
+	if len(secrets) == 0 {
+		return nil
+	}
+	events := make([]models.AuditEvent, len(secrets))
+	for i, secret := range secrets {
+		events[i] = newAuditEvent(ctx, userID, action)
+		events[i].SecretID = secret.ID
+		events[i].OwnerID = secret.UserID
+		events[i].VaultID = secret.VaultID
+	}
+	if err := srv.storage.CreateAuditEvents(ctx, events); err != nil {
+		return fmt.Errorf("failed to record audit events: %w", err)
+	}
+
+	return nil
+}
+
+// List returns audit events visible to the user, the latest first.
+func (srv AuditService) List(ctx context.Context, userID int, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultAuditPageSize
+	}
+	if filter.Limit > MaxAuditPageSize {
+		filter.Limit = MaxAuditPageSize
+	}
+
+	return srv.storage.ListAuditEvents(ctx, userID, filter)
+}
+
+var errAuditChainBroken = errors.New("audit chain is broken")
+
+// Verify recomputes hashes of all audit events and checks that every event
+// is chained to the previous one.
+func (srv AuditService) Verify(ctx context.Context) (AuditVerification, error) {
+	result := AuditVerification{Valid: true}
+	prevHash := []byte{}
+	err := srv.storage.StreamAuditEvents(ctx, func(event models.AuditEvent) error {
+		result.Events++
+		if !bytes.Equal(event.PrevHash, prevHash) || !bytes.Equal(event.ComputeHash(), event.Hash) {
+			result.Valid = false
+			result.BrokenEventID = event.ID
+			return errAuditChainBroken
+		}
+		prevHash = event.Hash
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errAuditChainBroken) {
+		return AuditVerification{}, err
+	}
+
+	return result, nil
+}
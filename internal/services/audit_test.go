@@ -0,0 +1,210 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type auditStorageMock struct {
+	mock.Mock
+	events []models.AuditEvent
+}
+
+func (m *auditStorageMock) CreateAuditEvents(ctx context.Context, events []models.AuditEvent) error {
+	args := m.Called(ctx, events)
+	return args.Error(0)
+}
+
+func (m *auditStorageMock) ListAuditEvents(
+	ctx context.Context,
+	userID int,
+	filter models.AuditFilter) ([]models.AuditEvent, error) {
+
+	args := m.Called(ctx, userID, filter)
+	return args.Get(0).([]models.AuditEvent), args.Error(1)
+}
+
+func (m *auditStorageMock) StreamAuditEvents(ctx context.Context, fn func(models.AuditEvent) error) error {
+	for _, event := range m.events {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type auditRecorderMock struct{ mock.Mock }
+
+func (m *auditRecorderMock) Record(
+	ctx context.Context,
+	userID int,
+	action models.AuditAction,
+	secrets ...models.Secret) error {
+
+	args := m.Called(ctx, userID, action, secrets)
+	return args.Error(0)
+}
+
+func TestAuditRecord(t *testing.T) {
+	store := new(auditStorageMock)
+	store.On("CreateAuditEvents", mock.Anything, mock.Anything).Return(nil)
+	srv := services.NewAuditService(store)
+	ctx := services.WithClientInfo(context.TODO(), services.ClientInfo{IP: "10.0.0.1", UserAgent: "gophkeeper"})
+
+	err := srv.Record(
+		ctx,
+		2,
+		models.AuditRead,
+		models.Secret{ID: 1, UserID: 1},
+		models.Secret{ID: 3, UserID: 4, VaultID: 5},
+	)
+	require.NoError(t, err)
+	store.AssertCalled(t, "CreateAuditEvents", mock.Anything, []models.AuditEvent{
+		{UserID: 2, Action: models.AuditRead, SecretID: 1, OwnerID: 1, ClientIP: "10.0.0.1", UserAgent: "gophkeeper"},
+		{UserID: 2, Action: models.AuditRead, SecretID: 3, OwnerID: 4, VaultID: 5, ClientIP: "10.0.0.1", UserAgent: "gophkeeper"},
+	})
+
+	t.Run("records nothing without secrets", func(t *testing.T) {
+		store := new(auditStorageMock)
+		srv := services.NewAuditService(store)
+
+		assert.NoError(t, srv.Record(ctx, 2, models.AuditRead))
+		store.AssertNotCalled(t, "CreateAuditEvents", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuditList(t *testing.T) {
+	testCases := []struct {
+		name      string
+		limit     int
+		wantLimit int
+	}{
+		{
+			name:      "uses default limit",
+			wantLimit: services.DefaultAuditPageSize,
+		},
+		{
+			name:      "keeps limit",
+			limit:     10,
+			wantLimit: 10,
+		},
+		{
+			name:      "caps limit",
+			limit:     services.MaxAuditPageSize + 1,
+			wantLimit: services.MaxAuditPageSize,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(auditStorageMock)
+			store.On("ListAuditEvents", mock.Anything, mock.Anything, mock.Anything).Return([]models.AuditEvent{}, nil)
+			srv := services.NewAuditService(store)
+
+			_, err := srv.List(context.TODO(), 1, models.AuditFilter{SecretID: 2, Limit: tc.limit})
+			require.NoError(t, err)
+			store.AssertCalled(t, "ListAuditEvents", mock.Anything, 1, models.AuditFilter{SecretID: 2, Limit: tc.wantLimit})
+		})
+	}
+}
+
+func TestAuditVerify(t *testing.T) {
+	chain := func() []models.AuditEvent {
+		events := make([]models.AuditEvent, 3)
+		prevHash := []byte{}
+		for i := range events {
+			events[i] = models.AuditEvent{
+				ID:        i + 1,
+				UserID:    1,
+				Action:    models.AuditRead,
+				SecretID:  i + 10,
+				OwnerID:   1,
+				ClientIP:  "10.0.0.1",
+				CreatedAt: time.Date(2024, 5, 19, 10, 18, 45, 0, time.UTC),
+				PrevHash:  prevHash,
+			}
+			events[i].Hash = events[i].ComputeHash()
+			prevHash = events[i].Hash
+		}
+		return events
+	}
+	testCases := []struct {
+		name   string
+		tamper func([]models.AuditEvent) []models.AuditEvent
+		want   services.AuditVerification
+	}{
+		{
+			name:   "accepts intact chain",
+			tamper: func(events []models.AuditEvent) []models.AuditEvent { return events },
+			want:   services.AuditVerification{Events: 3, Valid: true},
+		},
+		{
+			name: "detects changed event",
+			tamper: func(events []models.AuditEvent) []models.AuditEvent {
+				events[1].Action = models.AuditUpdate
+				return events
+			},
+			want: services.AuditVerification{Events: 2, BrokenEventID: 2},
+		},
+		{
+			name: "detects deleted event",
+			tamper: func(events []models.AuditEvent) []models.AuditEvent {
+				return append(events[:1], events[2:]...)
+			},
+			want: services.AuditVerification{Events: 2, BrokenEventID: 3},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &auditStorageMock{events: tc.tamper(chain())}
+			srv := services.NewAuditService(store)
+
+			result, err := srv.Verify(context.TODO())
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, result)
+		})
+	}
+}
+
+func TestShowSecretRecordsRead(t *testing.T) {
+	secret := models.Secret{ID: 1, UserID: 1, SecretType: models.TextSecret}
+	text, err := (&models.Text{Title: "title", Body: "body"}).Marshall()
+	require.NoError(t, err)
+	decryptor := new(decryptorMock)
+	decryptor.On("Decrypt", mock.Anything, mock.Anything).Return(text, nil)
+
+	t.Run("records read", func(t *testing.T) {
+		auditor := new(auditRecorderMock)
+		auditor.On("Record", mock.Anything, 1, models.AuditRead, []models.Secret{secret}).Return(nil)
+		srv := services.NewShowSecretService(decryptor, ownerOnlyACL(), auditor)
+
+		_, err := srv.Show(context.TODO(), 1, secret)
+		require.NoError(t, err)
+		auditor.AssertExpectations(t)
+	})
+
+	t.Run("does not show secret if read is not recorded", func(t *testing.T) {
+		auditor := new(auditRecorderMock)
+		auditor.On("Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("error"))
+		srv := services.NewShowSecretService(decryptor, ownerOnlyACL(), auditor)
+
+		_, err := srv.Show(context.TODO(), 1, secret)
+		assert.EqualError(t, err, "error")
+	})
+
+	t.Run("does not record denied read", func(t *testing.T) {
+		auditor := new(auditRecorderMock)
+		srv := services.NewShowSecretService(decryptor, ownerOnlyACL(), auditor)
+
+		_, err := srv.Show(context.TODO(), 2, secret)
+		assert.Error(t, err)
+		auditor.AssertNotCalled(t, "Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
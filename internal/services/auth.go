@@ -2,8 +2,11 @@ package services
 
 import (
 	"context"
-	"errors"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
 	"github.com/ilya-burinskiy/gophkeeper/internal/models"
@@ -13,30 +16,182 @@ type UserFinder interface {
 	FindUserByLogin(ctx context.Context, login string) (models.User, error)
 }
 
+// LoginAttemptStore keeps failed login attempts, it must be shared by all
+// server instances.
+type LoginAttemptStore interface {
+	FindLoginLockout(ctx context.Context, keys []string) (time.Duration, error)
+	RecordLoginFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	LockLogin(ctx context.Context, key string, duration time.Duration) error
+	ResetLoginFailures(ctx context.Context, key string) error
+}
+
+// LoginPolicy locks logins after FreeAttempts failures in a row. The first
+// lockout lasts BaseDelay and every next failure doubles it up to MaxDelay.
+// Failures are forgotten if there have been none for Window.
+type LoginPolicy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	Window       time.Duration
+}
+
+var (
+	// UserLoginPolicy counts failures of a login from any address.
+	UserLoginPolicy = LoginPolicy{
+		FreeAttempts: 5,
+		BaseDelay:    time.Second,
+		MaxDelay:     15 * time.Minute,
+		Window:       time.Hour,
+	}
+	// IPLoginPolicy counts failures from an address with any login.
+	IPLoginPolicy = LoginPolicy{
+		FreeAttempts: 20,
+		BaseDelay:    time.Second,
+		MaxDelay:     15 * time.Minute,
+		Window:       time.Hour,
+	}
+)
+
+// Delay returns the lockout after the number of failures.
+func (policy LoginPolicy) Delay(failures int) time.Duration {
+	if failures <= policy.FreeAttempts {
+		return 0
+	}
+	delay := policy.BaseDelay
+	for i := policy.FreeAttempts + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= policy.MaxDelay {
+			return policy.MaxDelay
+		}
+	}
+
+	return delay
+}
+
 type AuthenticateService struct {
 	userFinder UserFinder
+	attempts   LoginAttemptStore
+	sessions   SessionStarter
 }
 
-func NewAuthenticateService(usrFinder UserFinder) AuthenticateService {
+func NewAuthenticateService(
+	usrFinder UserFinder,
+	attempts LoginAttemptStore,
+	sessions SessionStarter) AuthenticateService {
+
 	return AuthenticateService{
 		userFinder: usrFinder,
+		attempts:   attempts,
+		sessions:   sessions,
 	}
 }
 
-func (srv AuthenticateService) Authenticate(ctx context.Context, login, password string) (string, error) {
-	user, err := srv.userFinder.FindUserByLogin(ctx, login)
+// Authenticate starts a session of the user with the login and password.
+// Logins are rejected with ErrLoginLocked while the login or the client
+// address is locked after failed attempts. A password is checked even if
+// there is no user with the login, so unknown logins take as long as known
+// ones. If the user has enabled TOTP, ErrTOTPRequired with an MFA token is
+// returned instead of session tokens.
+func (srv AuthenticateService) Authenticate(ctx context.Context, login, password string) (SessionTokens, error) {
+	loginKey := loginAttemptKey(login)
+	keys := []string{loginKey}
+	if ip := ClientInfoFromContext(ctx).IP; ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	lockout, err := srv.attempts.FindLoginLockout(ctx, keys)
 	if err != nil {
-		return "", fmt.Errorf("failed to authenticate user: %w", err)
+		return SessionTokens{}, fmt.Errorf("failed to authenticate user: %w", err)
+	}
+	if lockout > 0 {
+		return SessionTokens{}, ErrLoginLocked{RetryAfter: lockout}
 	}
 
+	user, err := srv.userFinder.FindUserByLogin(ctx, login)
+	if err != nil {
+		auth.ValidatePasswordHash(password, dummyPasswordHash())
+		if err := srv.recordFailure(ctx, keys); err != nil {
+			return SessionTokens{}, err
+		}
+		return SessionTokens{}, fmt.Errorf("failed to authenticate user: %w", err)
+	}
 	if !auth.ValidatePasswordHash(password, string(user.EncryptedPassword)) {
-		return "", errors.New("invalid login or password")
+		if err := srv.recordFailure(ctx, keys); err != nil {
+			return SessionTokens{}, err
+		}
+		return SessionTokens{}, ErrInvalidCredentials
+	}
+	if err := srv.attempts.ResetLoginFailures(ctx, loginKey); err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to authenticate user: %w", err)
+	}
+	if user.TOTPEnabled {
+		mfaToken, err := auth.BuildMFAToken(user.ID)
+		if err != nil {
+			return SessionTokens{}, fmt.Errorf("failed to authenticate user: %w", err)
+		}
+		return SessionTokens{}, ErrTOTPRequired{MFAToken: mfaToken}
 	}
 
-	jwtStr, err := auth.BuildJWTString(user.ID)
+	tokens, err := srv.sessions.Start(ctx, user.ID)
 	if err != nil {
-		return "", fmt.Errorf("failed to authenticate user: %w", err)
+		return SessionTokens{}, fmt.Errorf("failed to authenticate user: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// loginAttemptKey returns the key failed attempts of the login are counted
+// by. The login is hashed, so the key has the same length for logins of
+// any length sent by clients.
+func loginAttemptKey(login string) string {
+	hash := sha256.Sum256([]byte(login))
+	return "login:" + hex.EncodeToString(hash[:])
+}
+
+// recordFailure counts the failure for every key and locks keys which
+// have exceeded their free attempts. The first key is the login.
+func (srv AuthenticateService) recordFailure(ctx context.Context, keys []string) error {
+	for i, key := range keys {
+		policy := UserLoginPolicy
+		if i > 0 {
+			policy = IPLoginPolicy
+		}
+		if err := recordLoginFailure(ctx, srv.attempts, key, policy); err != nil {
+			return fmt.Errorf("failed to authenticate user: %w", err)
+		}
 	}
 
-	return jwtStr, nil
+	return nil
+}
+
+// recordLoginFailure counts the failure of the key and locks the key if
+// it has exceeded free attempts of the policy.
+func recordLoginFailure(ctx context.Context, attempts LoginAttemptStore, key string, policy LoginPolicy) error {
+	failures, err := attempts.RecordLoginFailure(ctx, key, policy.Window)
+	if err != nil {
+		return err
+	}
+	if delay := policy.Delay(failures); delay > 0 {
+		return attempts.LockLogin(ctx, key, delay)
+	}
+
+	return nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash returns a hash to check passwords of unknown logins
+// against, it is computed with the same cost as user password hashes.
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		hash, err := auth.HashPassword("gophkeeper")
+		if err != nil {
+			panic(err)
+		}
+		dummyHash = string(hash)
+	})
+
+	return dummyHash
 }
@@ -2,8 +2,12 @@ package services_test
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ilya-burinskiy/gophkeeper/internal/models"
 	"github.com/ilya-burinskiy/gophkeeper/internal/services"
@@ -20,6 +24,37 @@ func (f *userFinder) FindUserByLogin(ctx context.Context, login string) (models.
 	return args.Get(0).(models.User), args.Error(1)
 }
 
+type loginAttemptStoreMock struct{ mock.Mock }
+
+func (m *loginAttemptStoreMock) FindLoginLockout(ctx context.Context, keys []string) (time.Duration, error) {
+	args := m.Called(ctx, keys)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+func (m *loginAttemptStoreMock) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	args := m.Called(ctx, key, window)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *loginAttemptStoreMock) LockLogin(ctx context.Context, key string, duration time.Duration) error {
+	args := m.Called(ctx, key, duration)
+	return args.Error(0)
+}
+
+func (m *loginAttemptStoreMock) ResetLoginFailures(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+// noLoginFailures is the store without failed login attempts.
+func noLoginFailures() *loginAttemptStoreMock {
+	store := new(loginAttemptStoreMock)
+	store.On("FindLoginLockout", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+	store.On("RecordLoginFailure", mock.Anything, mock.Anything, mock.Anything).Return(1, nil)
+	store.On("ResetLoginFailures", mock.Anything, mock.Anything).Return(nil)
+	return store
+}
+
 func TestAuthenticate(t *testing.T) {
 	type want struct {
 		jwtStr string
@@ -30,7 +65,7 @@ func TestAuthenticate(t *testing.T) {
 		err  error
 	}
 	usrFinder := new(userFinder)
-	authSrv := services.NewAuthenticateService(usrFinder)
+	authSrv := services.NewAuthenticateService(usrFinder, noLoginFailures(), sessionStarterStub{})
 	testCases := []struct {
 		name     string
 		login    string
@@ -62,6 +97,19 @@ func TestAuthenticate(t *testing.T) {
 			},
 			want: want{errMsg: "failed to authenticate user: error"},
 		},
+		{
+			name:     "returns error if password is invalid",
+			login:    "login",
+			password: "wrong",
+			findRes: findUserResult{
+				user: models.User{
+					ID:                1,
+					Login:             "login",
+					EncryptedPassword: hashPassword(t, "password"),
+				},
+			},
+			want: want{errMsg: services.ErrInvalidCredentials.Error()},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -71,12 +119,12 @@ func TestAuthenticate(t *testing.T) {
 				Return(tc.findRes.user, tc.findRes.err)
 			defer findCall.Unset()
 
-			jwtStr, err := authSrv.Authenticate(ctx, tc.login, tc.password)
+			tokens, err := authSrv.Authenticate(ctx, tc.login, tc.password)
 			if err == nil {
 				assert.Equal(
 					t,
 					userIDFromJWT(t, tc.want.jwtStr),
-					userIDFromJWT(t, jwtStr),
+					userIDFromJWT(t, tokens.AccessToken),
 				)
 			} else {
 				assert.EqualError(t, err, tc.want.errMsg)
@@ -85,6 +133,99 @@ func TestAuthenticate(t *testing.T) {
 	}
 }
 
+func TestAuthenticateThrottling(t *testing.T) {
+	user := models.User{ID: 1, Login: "login", EncryptedPassword: hashPassword(t, "password")}
+	usrFinder := new(userFinder)
+	usrFinder.On("FindUserByLogin", mock.Anything, "login").Return(user, nil)
+	usrFinder.On("FindUserByLogin", mock.Anything, "unknown").Return(models.User{}, errors.New("not found"))
+	ctx := services.WithClientInfo(context.TODO(), services.ClientInfo{IP: "10.0.0.1"})
+	keys := []string{loginAttemptKey("login"), "ip:10.0.0.1"}
+
+	t.Run("rejects locked login", func(t *testing.T) {
+		store := new(loginAttemptStoreMock)
+		store.On("FindLoginLockout", mock.Anything, keys).Return(3*time.Second, nil)
+		usrFinder := new(userFinder)
+		authSrv := services.NewAuthenticateService(usrFinder, store, sessionStarterStub{})
+
+		_, err := authSrv.Authenticate(ctx, "login", "password")
+		assert.Equal(t, services.ErrLoginLocked{RetryAfter: 3 * time.Second}, err)
+		usrFinder.AssertNotCalled(t, "FindUserByLogin", mock.Anything, mock.Anything)
+	})
+
+	t.Run("locks login after free attempts", func(t *testing.T) {
+		store := new(loginAttemptStoreMock)
+		store.On("FindLoginLockout", mock.Anything, keys).Return(time.Duration(0), nil)
+		store.On("RecordLoginFailure", mock.Anything, loginAttemptKey("login"), services.UserLoginPolicy.Window).
+			Return(services.UserLoginPolicy.FreeAttempts+2, nil)
+		store.On("RecordLoginFailure", mock.Anything, "ip:10.0.0.1", services.IPLoginPolicy.Window).Return(1, nil)
+		store.On("LockLogin", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		authSrv := services.NewAuthenticateService(usrFinder, store, sessionStarterStub{})
+
+		_, err := authSrv.Authenticate(ctx, "login", "wrong")
+		assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+		store.AssertCalled(t, "LockLogin", mock.Anything, loginAttemptKey("login"), 2*services.UserLoginPolicy.BaseDelay)
+		store.AssertNotCalled(t, "LockLogin", mock.Anything, "ip:10.0.0.1", mock.Anything)
+	})
+
+	t.Run("counts failures of unknown login", func(t *testing.T) {
+		store := noLoginFailures()
+		authSrv := services.NewAuthenticateService(usrFinder, store, sessionStarterStub{})
+
+		_, err := authSrv.Authenticate(ctx, "unknown", "password")
+		assert.EqualError(t, err, "failed to authenticate user: not found")
+		store.AssertCalled(t, "RecordLoginFailure", mock.Anything, loginAttemptKey("unknown"), mock.Anything)
+		store.AssertCalled(t, "RecordLoginFailure", mock.Anything, "ip:10.0.0.1", mock.Anything)
+	})
+
+	t.Run("counts failures of over-long login by its hash", func(t *testing.T) {
+		longLogin := strings.Repeat("a", 1000)
+		usrFinder := new(userFinder)
+		usrFinder.On("FindUserByLogin", mock.Anything, longLogin).Return(models.User{}, errors.New("not found"))
+		store := noLoginFailures()
+		authSrv := services.NewAuthenticateService(usrFinder, store, sessionStarterStub{})
+
+		_, err := authSrv.Authenticate(ctx, longLogin, "password")
+		assert.EqualError(t, err, "failed to authenticate user: not found")
+		store.AssertCalled(t, "RecordLoginFailure", mock.Anything, loginAttemptKey(longLogin), mock.Anything)
+		store.AssertCalled(t, "RecordLoginFailure", mock.Anything, "ip:10.0.0.1", mock.Anything)
+	})
+
+	t.Run("resets login failures on success", func(t *testing.T) {
+		store := noLoginFailures()
+		authSrv := services.NewAuthenticateService(usrFinder, store, sessionStarterStub{})
+
+		_, err := authSrv.Authenticate(ctx, "login", "password")
+		require.NoError(t, err)
+		store.AssertCalled(t, "ResetLoginFailures", mock.Anything, loginAttemptKey("login"))
+		store.AssertNotCalled(t, "ResetLoginFailures", mock.Anything, "ip:10.0.0.1")
+	})
+}
+
+// loginAttemptKey is the key failed attempts of the login are counted by.
+func loginAttemptKey(login string) string {
+	hash := sha256.Sum256([]byte(login))
+	return "login:" + hex.EncodeToString(hash[:])
+}
+
+func TestLoginPolicyDelay(t *testing.T) {
+	policy := services.LoginPolicy{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	testCases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: 0},
+		{failures: 3, want: 0},
+		{failures: 4, want: time.Second},
+		{failures: 5, want: 2 * time.Second},
+		{failures: 7, want: 8 * time.Second},
+		{failures: 8, want: 10 * time.Second},
+		{failures: 100, want: 10 * time.Second},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, policy.Delay(tc.failures), "failures=%d", tc.failures)
+	}
+}
+
 func hashPassword(t *testing.T, pwd string) []byte {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(pwd), bcrypt.DefaultCost)
 	require.NoError(t, err)
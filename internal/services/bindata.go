@@ -0,0 +1,349 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+// ChunkSize is the size of plaintext bin data chunks.
+const ChunkSize = 1 << 20
+
+type ChunkedSecretStorage interface {
+	CreateChunkedSecret(
+		ctx context.Context,
+		userID int,
+		vaultID int,
+		secretType models.SecretType,
+		description string,
+		encryptedKey []byte,
+		encryptedMetadata []byte,
+		writeContent func(writeChunk func(idx int, data []byte, size int) error) ([]byte, error),
+		event models.AuditEvent,
+	) (models.Secret, error)
+	UpdateChunkedSecret(
+		ctx context.Context,
+		secretID int,
+		version int,
+		description string,
+		encryptedMetadata []byte,
+		writeContent func(writeChunk func(idx int, data []byte, size int) error) ([]byte, error),
+		event models.AuditEvent,
+	) (int, error)
+	StreamSecretChunks(ctx context.Context, secretID int, fn func(idx int, data []byte) error) error
+	FindSecretChunk(ctx context.Context, secretID int, idx int) ([]byte, error)
+	FindRevisionChunk(ctx context.Context, revisionID int, idx int) ([]byte, error)
+}
+
+type ChunkEncryptor interface {
+	GenerateKey() ([]byte, error)
+	ReEncrypt(msg []byte, encryptedKey []byte) ([]byte, error)
+	NewChunkCipher(encryptedKey []byte) (ChunkCipher, error)
+}
+
+// BinDataService stores bin data content in encrypted chunks, so neither
+// upload nor download requires the whole content to be kept in memory.
+type BinDataService struct {
+	storage    ChunkedSecretStorage
+	encryptor  ChunkEncryptor
+	authorizer SecretAuthorizer
+	maxSize    int64
+}
+
+func NewBinDataService(
+	storage ChunkedSecretStorage,
+	encryptor ChunkEncryptor,
+	authorizer SecretAuthorizer,
+	maxSize int64) BinDataService {
+
+	return BinDataService{
+		storage:    storage,
+		encryptor:  encryptor,
+		authorizer: authorizer,
+		maxSize:    maxSize,
+	}
+}
+
+// Create creates a personal bin data secret of the user or, if vaultID is
+// not zero, a secret of the vault, which requires write access to the vault.
+func (srv BinDataService) Create(
+	ctx context.Context,
+	userID int,
+	vaultID int,
+	description string,
+	filename string,
+	content io.Reader,
+	metadata models.Metadata) (models.Secret, error) {
+
+	if vaultID != 0 {
+		if err := srv.authorizer.AuthorizeVault(ctx, userID, vaultID, models.SecretWriteAccess); err != nil {
+			return models.Secret{}, err
+		}
+	}
+	encryptedKey, err := srv.encryptor.GenerateKey()
+	if err != nil {
+		return models.Secret{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+	encryptedMetadata, err := encryptMetadata(srv.encryptor, metadata, encryptedKey)
+	if err != nil {
+		return models.Secret{}, err
+	}
+	chunkCipher, err := srv.encryptor.NewChunkCipher(encryptedKey)
+	if err != nil {
+		return models.Secret{}, fmt.Errorf("failed to create chunk cipher: %w", err)
+	}
+
+	secret, err := srv.storage.CreateChunkedSecret(
+		ctx,
+		userID,
+		vaultID,
+		models.BinDataSecret,
+		description,
+		encryptedKey,
+		encryptedMetadata,
+		srv.contentWriter(chunkCipher, encryptedKey, filename, content),
+		newAuditEvent(ctx, userID, models.AuditCreate),
+	)
+	if err != nil {
+		return models.Secret{}, err
+	}
+
+	return secret, nil
+}
+
+// Update replaces bin data content unless the secret has been changed since
+// secret.Version and returns the new version. Secret metadata is kept if
+// metadata is nil.
+func (srv BinDataService) Update(
+	ctx context.Context,
+	userID int,
+	secret models.Secret,
+	description string,
+	filename string,
+	content io.Reader,
+	metadata models.Metadata) (int, error) {
+
+	if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretWriteAccess); err != nil {
+		return 0, err
+	}
+	if secret.SecretType != models.BinDataSecret {
+		return 0, ErrWrongSecretType
+	}
+	chunkCipher, err := srv.encryptor.NewChunkCipher(secret.EncryptedKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create chunk cipher: %w", err)
+	}
+	encryptedMetadata, err := encryptMetadata(srv.encryptor, metadata, secret.EncryptedKey)
+	if err != nil {
+		return 0, err
+	}
+
+	return srv.storage.UpdateChunkedSecret(
+		ctx,
+		secret.ID,
+		secret.Version,
+		description,
+		encryptedMetadata,
+		srv.contentWriter(chunkCipher, secret.EncryptedKey, filename, content),
+		newAuditEvent(ctx, userID, models.AuditUpdate),
+	)
+}
+
+// WriteContent writes decrypted bin data content to w.
+func (srv BinDataService) WriteContent(
+	ctx context.Context,
+	secret models.Secret,
+	binData *models.BinData,
+	w io.Writer) error {
+
+	if len(binData.Bytes) > 0 {
+		if _, err := w.Write(binData.Bytes); err != nil {
+			return err
+		}
+	}
+	chunkCipher, err := srv.encryptor.NewChunkCipher(secret.EncryptedKey)
+	if err != nil {
+		return fmt.Errorf("failed to create chunk cipher: %w", err)
+	}
+
+	return srv.storage.StreamSecretChunks(ctx, secret.ID, func(idx int, data []byte) error {
+		chunk, err := chunkCipher.Open(idx, data)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt chunk %d: %w", idx, err)
+		}
+		_, err = w.Write(chunk)
+
+		return err
+	})
+}
+
+// OpenContent returns a reader of decrypted bin data content, which
+// decrypts only the chunks being read.
+func (srv BinDataService) OpenContent(
+	ctx context.Context,
+	secret models.Secret,
+	binData *models.BinData) (io.ReadSeeker, error) {
+
+	if len(binData.Bytes) > 0 || binData.Size == 0 {
+		return bytes.NewReader(binData.Bytes), nil
+	}
+	chunkCipher, err := srv.encryptor.NewChunkCipher(secret.EncryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chunk cipher: %w", err)
+	}
+
+	findChunk := func(idx int) ([]byte, error) {
+		return srv.storage.FindSecretChunk(ctx, secret.ID, idx)
+	}
+
+	return newChunkReader(findChunk, chunkCipher, binData.Size), nil
+}
+
+// OpenRevisionContent returns a reader of decrypted bin data content
+// of the secret revision.
+func (srv BinDataService) OpenRevisionContent(
+	ctx context.Context,
+	revision models.SecretRevision,
+	binData *models.BinData) (io.ReadSeeker, error) {
+
+	if len(binData.Bytes) > 0 || binData.Size == 0 {
+		return bytes.NewReader(binData.Bytes), nil
+	}
+	chunkCipher, err := srv.encryptor.NewChunkCipher(revision.EncryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chunk cipher: %w", err)
+	}
+	findChunk := func(idx int) ([]byte, error) {
+		return srv.storage.FindRevisionChunk(ctx, revision.ID, idx)
+	}
+
+	return newChunkReader(findChunk, chunkCipher, binData.Size), nil
+}
+
+func (srv BinDataService) contentWriter(
+	chunkCipher ChunkCipher,
+	encryptedKey []byte,
+	filename string,
+	content io.Reader) func(writeChunk func(idx int, data []byte, size int) error) ([]byte, error) {
+
+	return func(writeChunk func(idx int, data []byte, size int) error) ([]byte, error) {
+		buf := make([]byte, ChunkSize)
+		hash := sha256.New()
+		var size int64
+		for idx := 0; ; idx++ {
+			n, err := io.ReadFull(content, buf)
+			if n > 0 {
+				hash.Write(buf[:n])
+				size += int64(n)
+				if size > srv.maxSize {
+					return nil, ErrBinDataTooLarge
+				}
+				sealedChunk, err := chunkCipher.Seal(idx, buf[:n])
+				if err != nil {
+					return nil, fmt.Errorf("failed to encrypt chunk: %w", err)
+				}
+				if err := writeChunk(idx, sealedChunk, n); err != nil {
+					return nil, err
+				}
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read content: %w", err)
+			}
+		}
+
+		return encryptBinData(srv.encryptor, encryptedKey, filename, size, hash.Sum(nil))
+	}
+}
+
+// encryptBinData encrypts bin data secret data, which holds only
+// content metadata since the content itself is stored in chunks.
+func encryptBinData(
+	encryptor ChunkEncryptor,
+	encryptedKey []byte,
+	filename string,
+	size int64,
+	contentHash []byte) ([]byte, error) {
+
+	binData := &models.BinData{Filename: filename, Size: size, SHA256: contentHash}
+	binDataBytes, err := binData.Marshall()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshall bin data: %w", err)
+	}
+
+	return encryptor.ReEncrypt(binDataBytes, encryptedKey)
+}
+
+// chunkReader reads chunked content starting from any offset.
+type chunkReader struct {
+	findChunk   func(idx int) ([]byte, error)
+	chunkCipher ChunkCipher
+	size        int64
+	offset      int64
+
+	// chunkIdx is the index of the loaded chunk, -1 if none is loaded
+	chunkIdx int
+	chunk    []byte
+}
+
+func newChunkReader(findChunk func(idx int) ([]byte, error), chunkCipher ChunkCipher, size int64) *chunkReader {
+	return &chunkReader{
+		findChunk:   findChunk,
+		chunkCipher: chunkCipher,
+		size:        size,
+		chunkIdx:    -1,
+	}
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	idx := int(r.offset / ChunkSize)
+	if idx != r.chunkIdx {
+		data, err := r.findChunk(idx)
+		if err != nil {
+			return 0, err
+		}
+		chunk, err := r.chunkCipher.Open(idx, data)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decrypt chunk %d: %w", idx, err)
+		}
+		r.chunkIdx = idx
+		r.chunk = chunk
+	}
+
+	chunkOffset := int(r.offset % ChunkSize)
+	if chunkOffset >= len(r.chunk) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, r.chunk[chunkOffset:])
+	r.offset += int64(n)
+
+	return n, nil
+}
+
+func (r *chunkReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.offset = offset
+
+	return offset, nil
+}
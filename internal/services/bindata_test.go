@@ -0,0 +1,302 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type chunkedStorageMock struct {
+	mock.Mock
+	chunks        [][]byte
+	encryptedData []byte
+}
+
+func (m *chunkedStorageMock) CreateChunkedSecret(
+	ctx context.Context,
+	userID int,
+	vaultID int,
+	secretType models.SecretType,
+	description string,
+	encryptedKey []byte,
+	encryptedMetadata []byte,
+	writeContent func(writeChunk func(idx int, data []byte, size int) error) ([]byte, error),
+	event models.AuditEvent) (models.Secret, error) {
+
+	args := m.Called(ctx, userID, vaultID, secretType, description, event)
+	if err := m.writeContent(writeContent); err != nil {
+		return models.Secret{}, err
+	}
+	secret := args.Get(0).(models.Secret)
+	secret.EncryptedData = m.encryptedData
+	secret.EncryptedKey = encryptedKey
+	secret.EncryptedMetadata = encryptedMetadata
+
+	return secret, args.Error(1)
+}
+
+func (m *chunkedStorageMock) UpdateChunkedSecret(
+	ctx context.Context,
+	secretID int,
+	version int,
+	description string,
+	encryptedMetadata []byte,
+	writeContent func(writeChunk func(idx int, data []byte, size int) error) ([]byte, error),
+	event models.AuditEvent) (int, error) {
+
+	args := m.Called(ctx, secretID, version, description, event)
+	if err := m.writeContent(writeContent); err != nil {
+		return 0, err
+	}
+
+	return args.Int(0), args.Error(1)
+}
+
+func (m *chunkedStorageMock) StreamSecretChunks(ctx context.Context, secretID int, fn func(idx int, data []byte) error) error {
+	args := m.Called(ctx, secretID)
+	for idx, chunk := range m.chunks {
+		if err := fn(idx, chunk); err != nil {
+			return err
+		}
+	}
+
+	return args.Error(0)
+}
+
+func (m *chunkedStorageMock) FindSecretChunk(ctx context.Context, secretID int, idx int) ([]byte, error) {
+	return m.chunks[idx], nil
+}
+
+func (m *chunkedStorageMock) FindRevisionChunk(ctx context.Context, revisionID int, idx int) ([]byte, error) {
+	return m.chunks[idx], nil
+}
+
+func (m *chunkedStorageMock) writeContent(
+	writeContent func(writeChunk func(idx int, data []byte, size int) error) ([]byte, error)) error {
+
+	m.chunks = nil
+	encryptedData, err := writeContent(func(idx int, data []byte, size int) error {
+		m.chunks = append(m.chunks, data)
+		return nil
+	})
+	m.encryptedData = encryptedData
+
+	return err
+}
+
+func TestBinDataService(t *testing.T) {
+	testCases := []struct {
+		name       string
+		content    []byte
+		chunksNum  int
+		wantErrMsg string
+	}{
+		{
+			name:      "stores content in several chunks",
+			content:   bytes.Repeat([]byte("a"), 2*services.ChunkSize+10),
+			chunksNum: 3,
+		},
+		{
+			name:      "stores content in single chunk",
+			content:   []byte("content"),
+			chunksNum: 1,
+		},
+		{
+			name:      "stores empty content",
+			content:   []byte{},
+			chunksNum: 0,
+		},
+		{
+			name:       "returns error if content is too large",
+			content:    bytes.Repeat([]byte("a"), 3*services.ChunkSize),
+			wantErrMsg: services.ErrBinDataTooLarge.Error(),
+		},
+	}
+
+	encryptor, err := services.NewDataEncryptor(
+		services.CryptoRandGen{},
+		[]byte{
+			239, 140, 200, 100, 31, 6, 108, 25, 158, 54, 149, 16, 138, 90, 157, 144,
+			53, 144, 0, 193, 140, 107, 205, 41, 70, 167, 116, 218, 39, 58, 207, 240,
+		},
+	)
+	require.NoError(t, err)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(chunkedStorageMock)
+			store.
+				On(
+					"CreateChunkedSecret",
+					mock.Anything,
+					1,
+					0,
+					models.BinDataSecret,
+					"description",
+					models.AuditEvent{UserID: 1, Action: models.AuditCreate},
+				).
+				Return(models.Secret{ID: 1, UserID: 1, SecretType: models.BinDataSecret}, nil)
+			store.On("StreamSecretChunks", mock.Anything, 1).Return(nil)
+			binDataSrv := services.NewBinDataService(store, encryptor, ownerOnlyACL(), 2*services.ChunkSize+10)
+
+			secret, err := binDataSrv.Create(
+				context.TODO(),
+				1,
+				0,
+				"description",
+				"file.txt",
+				bytes.NewReader(tc.content),
+				nil,
+			)
+			if tc.wantErrMsg != "" {
+				assert.EqualError(t, err, tc.wantErrMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, store.chunks, tc.chunksNum)
+
+			binData, err := services.NewShowSecretService(encryptor, ownerOnlyACL(), noAuditLog{}).Show(context.TODO(), 1, secret)
+			require.NoError(t, err)
+			contentHash := sha256.Sum256(tc.content)
+			assert.Equal(
+				t,
+				&models.BinData{ID: 1, Filename: "file.txt", Size: int64(len(tc.content)), SHA256: contentHash[:]},
+				binData,
+			)
+
+			content := bytes.Buffer{}
+			err = binDataSrv.WriteContent(context.TODO(), secret, binData.(*models.BinData), &content)
+			require.NoError(t, err)
+			assert.Equal(t, string(tc.content), content.String())
+		})
+	}
+}
+
+func TestUpdateBinDataService(t *testing.T) {
+	encryptor, err := services.NewDataEncryptor(
+		services.CryptoRandGen{},
+		[]byte{
+			239, 140, 200, 100, 31, 6, 108, 25, 158, 54, 149, 16, 138, 90, 157, 144,
+			53, 144, 0, 193, 140, 107, 205, 41, 70, 167, 116, 218, 39, 58, 207, 240,
+		},
+	)
+	require.NoError(t, err)
+	encryptedKey, err := encryptor.GenerateKey()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		userID     int
+		secret     models.Secret
+		wantErrMsg string
+	}{
+		{
+			name:   "replaces content",
+			userID: 1,
+			secret: models.Secret{ID: 1, UserID: 1, SecretType: models.BinDataSecret, EncryptedKey: encryptedKey},
+		},
+		{
+			name:       "returns error if user is not secret owner",
+			userID:     2,
+			secret:     models.Secret{ID: 1, UserID: 1, SecretType: models.BinDataSecret, EncryptedKey: encryptedKey},
+			wantErrMsg: services.ErrNoPermission{UserID: 2, SecretID: 1}.Error(),
+		},
+		{
+			name:       "returns error if secret type changes",
+			userID:     1,
+			secret:     models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret, EncryptedKey: encryptedKey},
+			wantErrMsg: services.ErrWrongSecretType.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(chunkedStorageMock)
+			store.
+				On("UpdateChunkedSecret", mock.Anything, tc.secret.ID, tc.secret.Version, "new description", mock.Anything).
+				Return(2, nil)
+			store.On("StreamSecretChunks", mock.Anything, tc.secret.ID).Return(nil)
+			binDataSrv := services.NewBinDataService(store, encryptor, ownerOnlyACL(), services.ChunkSize)
+
+			_, err := binDataSrv.Update(
+				context.TODO(),
+				tc.userID,
+				tc.secret,
+				"new description",
+				"new.txt",
+				bytes.NewReader([]byte("new content")),
+				nil,
+			)
+			if tc.wantErrMsg != "" {
+				assert.EqualError(t, err, tc.wantErrMsg)
+				return
+			}
+			require.NoError(t, err)
+
+			tc.secret.EncryptedData = store.encryptedData
+			binData, err := services.NewShowSecretService(encryptor, ownerOnlyACL(), noAuditLog{}).Show(context.TODO(), tc.userID, tc.secret)
+			require.NoError(t, err)
+			contentHash := sha256.Sum256([]byte("new content"))
+			assert.Equal(t, &models.BinData{ID: 1, Filename: "new.txt", Size: 11, SHA256: contentHash[:]}, binData)
+
+			content := bytes.Buffer{}
+			err = binDataSrv.WriteContent(context.TODO(), tc.secret, binData.(*models.BinData), &content)
+			require.NoError(t, err)
+			assert.Equal(t, "new content", content.String())
+		})
+	}
+}
+
+func TestOpenBinDataContent(t *testing.T) {
+	encryptor, err := services.NewDataEncryptor(
+		services.CryptoRandGen{},
+		[]byte{
+			239, 140, 200, 100, 31, 6, 108, 25, 158, 54, 149, 16, 138, 90, 157, 144,
+			53, 144, 0, 193, 140, 107, 205, 41, 70, 167, 116, 218, 39, 58, 207, 240,
+		},
+	)
+	require.NoError(t, err)
+	content := bytes.Repeat([]byte("0123456789"), services.ChunkSize/4)
+	store := new(chunkedStorageMock)
+	store.On("CreateChunkedSecret", mock.Anything, 1, 0, models.BinDataSecret, "", mock.Anything).
+		Return(models.Secret{ID: 1, UserID: 1, SecretType: models.BinDataSecret}, nil)
+	binDataSrv := services.NewBinDataService(store, encryptor, ownerOnlyACL(), int64(len(content)))
+	secret, err := binDataSrv.Create(context.TODO(), 1, 0, "", "file", bytes.NewReader(content), nil)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		offset int64
+		length int64
+	}{
+		{name: "reads whole content", offset: 0, length: int64(len(content))},
+		{name: "reads range within chunk", offset: 10, length: 100},
+		{name: "reads range across chunks", offset: services.ChunkSize - 5, length: services.ChunkSize + 10},
+		{name: "reads content tail", offset: int64(len(content)) - 3, length: 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader, err := binDataSrv.OpenContent(
+				context.TODO(),
+				secret,
+				&models.BinData{Size: int64(len(content))},
+			)
+			require.NoError(t, err)
+			size, err := reader.Seek(0, io.SeekEnd)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(content)), size)
+			_, err = reader.Seek(tc.offset, io.SeekStart)
+			require.NoError(t, err)
+
+			readContent, err := io.ReadAll(io.LimitReader(reader, tc.length))
+			require.NoError(t, err)
+			assert.Equal(t, content[tc.offset:tc.offset+tc.length], readContent)
+		})
+	}
+}
@@ -11,15 +11,20 @@ type SecretCreator interface {
 	CreateSecret(
 		ctx context.Context,
 		userID int,
+		vaultID int,
 		secretType models.SecretType,
 		description string,
 		encryptedData []byte,
+		size int,
 		encryptedKey []byte,
+		encryptedMetadata []byte,
+		event models.AuditEvent,
 	) (models.Secret, error)
 }
 
 type SecretEncryptor interface {
 	Encrypt(msg []byte) ([]byte, []byte, error)
+	ReEncrypt(msg []byte, key []byte) ([]byte, error)
 }
 
 type Marshaller interface {
@@ -27,23 +32,39 @@ type Marshaller interface {
 }
 
 type CreateSecretService struct {
-	creator   SecretCreator
-	encryptor SecretEncryptor
+	creator    SecretCreator
+	encryptor  SecretEncryptor
+	authorizer SecretAuthorizer
 }
 
-func NewCreateSecretService(creator SecretCreator, encryptor SecretEncryptor) CreateSecretService {
+func NewCreateSecretService(
+	creator SecretCreator,
+	encryptor SecretEncryptor,
+	authorizer SecretAuthorizer) CreateSecretService {
+
 	return CreateSecretService{
-		creator:   creator,
-		encryptor: encryptor,
+		creator:    creator,
+		encryptor:  encryptor,
+		authorizer: authorizer,
 	}
 }
 
+// Create creates a personal secret of the user or, if vaultID is not zero,
+// a secret of the vault, which requires write access to the vault.
 func (srv CreateSecretService) Create(
 	ctx context.Context,
 	userID int,
+	vaultID int,
 	description string,
 	secretType models.SecretType,
-	marshallableSecret Marshaller) (models.Secret, error) {
+	marshallableSecret Marshaller,
+	metadata models.Metadata) (models.Secret, error) {
+
+	if vaultID != 0 {
+		if err := srv.authorizer.AuthorizeVault(ctx, userID, vaultID, models.SecretWriteAccess); err != nil {
+			return models.Secret{}, err
+		}
+	}
 
 	secretBytes, err := marshallableSecret.Marshall()
 	if err != nil {
@@ -53,7 +74,22 @@ func (srv CreateSecretService) Create(
 	if err != nil {
 		return models.Secret{}, fmt.Errorf("failed to encrypt message: %w", err)
 	}
-	secret, err := srv.creator.CreateSecret(ctx, userID, secretType, description, encryptedMsg, encryptedKey)
+	encryptedMetadata, err := encryptMetadata(srv.encryptor, metadata, encryptedKey)
+	if err != nil {
+		return models.Secret{}, err
+	}
+	secret, err := srv.creator.CreateSecret(
+		ctx,
+		userID,
+		vaultID,
+		secretType,
+		description,
+		encryptedMsg,
+		len(secretBytes),
+		encryptedKey,
+		encryptedMetadata,
+		newAuditEvent(ctx, userID, models.AuditCreate),
+	)
 	if err != nil {
 		return models.Secret{}, err
 	}
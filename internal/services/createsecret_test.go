@@ -9,6 +9,7 @@ import (
 	"github.com/ilya-burinskiy/gophkeeper/internal/services"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type secretCreatorMock struct{ mock.Mock }
@@ -16,12 +17,16 @@ type secretCreatorMock struct{ mock.Mock }
 func (m *secretCreatorMock) CreateSecret(
 	ctx context.Context,
 	userID int,
+	vaultID int,
 	secretType models.SecretType,
 	description string,
 	encryptedData []byte,
-	encryptedKey []byte) (models.Secret, error) {
+	size int,
+	encryptedKey []byte,
+	encryptedMetadata []byte,
+	event models.AuditEvent) (models.Secret, error) {
 
-	args := m.Called(ctx, userID, secretType, description, encryptedData, encryptedKey)
+	args := m.Called(ctx, userID, vaultID, secretType, description, encryptedData, size, encryptedKey, encryptedMetadata, event)
 	return args.Get(0).(models.Secret), args.Error(1)
 }
 
@@ -32,6 +37,11 @@ func (m *secretEncryptorMock) Encrypt(msg []byte) ([]byte, []byte, error) {
 	return args.Get(0).([]byte), args.Get(1).([]byte), args.Error(2)
 }
 
+func (m *secretEncryptorMock) ReEncrypt(msg []byte, key []byte) ([]byte, error) {
+	args := m.Called(msg, key)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
 func TestCreateSecret(t *testing.T) {
 	type createResult struct {
 		secret models.Secret
@@ -48,7 +58,7 @@ func TestCreateSecret(t *testing.T) {
 	}
 	secretCreator := new(secretCreatorMock)
 	encryptor := new(secretEncryptorMock)
-	createSrv := services.NewCreateSecretService(secretCreator, encryptor)
+	createSrv := services.NewCreateSecretService(secretCreator, encryptor, ownerOnlyACL())
 	testCases := []struct {
 		name               string
 		userID             int
@@ -141,6 +151,10 @@ func TestCreateSecret(t *testing.T) {
 				Once()
 			secretCreator.
 				On("CreateSecret",
+					mock.Anything,
+					mock.Anything,
+					mock.Anything,
+					mock.Anything,
 					mock.Anything,
 					mock.Anything,
 					mock.Anything,
@@ -152,9 +166,11 @@ func TestCreateSecret(t *testing.T) {
 			secret, err := createSrv.Create(
 				context.TODO(),
 				tc.userID,
+				0,
 				tc.description,
 				tc.secretType,
 				tc.marshallableSecret,
+				nil,
 			)
 			if err == nil {
 				assert.Equal(t, tc.want.secret, secret)
@@ -164,3 +180,67 @@ func TestCreateSecret(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateVaultSecret(t *testing.T) {
+	testCases := []struct {
+		name        string
+		userID      int
+		vaultAccess models.SecretAccess
+		wantErr     error
+	}{
+		{
+			name:        "creates secret in vault of organization member",
+			userID:      1,
+			vaultAccess: models.SecretWriteAccess,
+		},
+		{
+			name:    "returns error if user is not organization member",
+			userID:  1,
+			wantErr: services.ErrNoPermission{UserID: 1, VaultID: 2},
+		},
+	}
+
+	text := &models.Text{Title: "title", Body: "body"}
+	textBytes, err := text.Marshall()
+	require.NoError(t, err)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			secretCreator := new(secretCreatorMock)
+			encryptor := new(secretEncryptorMock)
+			finder := new(secretAccessFinderMock)
+			finder.On("FindVaultAccess", mock.Anything, 2, tc.userID).Return(tc.vaultAccess, nil)
+			encryptor.On("Encrypt", mock.Anything).Return([]byte{1, 2, 3}, []byte{4, 5, 6}, nil)
+			secretCreator.
+				On("CreateSecret",
+					mock.Anything,
+					tc.userID,
+					2,
+					models.TextSecret,
+					"description",
+					[]byte{1, 2, 3},
+					len(textBytes),
+					[]byte{4, 5, 6},
+					mock.Anything,
+					models.AuditEvent{UserID: tc.userID, Action: models.AuditCreate}).
+				Return(models.Secret{ID: 1, UserID: tc.userID, VaultID: 2}, nil)
+			createSrv := services.NewCreateSecretService(secretCreator, encryptor, services.NewSecretACL(finder))
+
+			secret, err := createSrv.Create(
+				context.TODO(),
+				tc.userID,
+				2,
+				"description",
+				models.TextSecret,
+				text,
+				nil,
+			)
+			if tc.wantErr != nil {
+				assert.Equal(t, tc.wantErr, err)
+				secretCreator.AssertNotCalled(t, "CreateSecret")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 2, secret.VaultID)
+		})
+	}
+}
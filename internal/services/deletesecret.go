@@ -7,29 +7,47 @@ import (
 )
 
 type SecretDeleter interface {
+	TrashSecret(ctx context.Context, secretID int, version int, event models.AuditEvent) error
 	DeleteSecret(
 		ctx context.Context,
 		secretID int,
+		version int,
+		event models.AuditEvent,
 	) error
 }
 
 type DeleteSecretService struct {
 	secretDeleter SecretDeleter
+	authorizer    SecretAuthorizer
 }
 
-func NewDeleteSecretService(secretDeleter SecretDeleter) DeleteSecretService {
+func NewDeleteSecretService(
+	secretDeleter SecretDeleter,
+	authorizer SecretAuthorizer) DeleteSecretService {
+
 	return DeleteSecretService{
 		secretDeleter: secretDeleter,
+		authorizer:    authorizer,
 	}
 }
 
+// Delete moves the secret to the trash, it can be restored until the
+// trash is purged. Personal secrets can be deleted by their owner and vault
+// secrets by organization admins. The secret is not deleted if it has been
+// changed since secret.Version.
 func (srv DeleteSecretService) Delete(ctx context.Context, userID int, secret models.Secret) error {
-	if userID != secret.UserID {
-		return ErrNoPermission{
-			UserID:   userID,
-			SecretID: secret.ID,
-		}
+	if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretOwnerAccess); err != nil {
+		return err
+	}
+
+	return srv.secretDeleter.TrashSecret(ctx, secret.ID, secret.Version, newAuditEvent(ctx, userID, models.AuditDelete))
+}
+
+// Purge deletes the secret permanently, the secret may be in the trash.
+func (srv DeleteSecretService) Purge(ctx context.Context, userID int, secret models.Secret) error {
+	if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretOwnerAccess); err != nil {
+		return err
 	}
 
-	return srv.secretDeleter.DeleteSecret(ctx, secret.ID)
+	return srv.secretDeleter.DeleteSecret(ctx, secret.ID, secret.Version, newAuditEvent(ctx, userID, models.AuditPurge))
 }
@@ -12,8 +12,13 @@ import (
 
 type secretDeleterMock struct{ mock.Mock }
 
-func (m *secretDeleterMock) DeleteSecret(ctx context.Context, secretID int) error {
-	args := m.Called(ctx, secretID)
+func (m *secretDeleterMock) TrashSecret(ctx context.Context, secretID int, version int, event models.AuditEvent) error {
+	args := m.Called(ctx, secretID, version, event)
+	return args.Error(0)
+}
+
+func (m *secretDeleterMock) DeleteSecret(ctx context.Context, secretID int, version int, event models.AuditEvent) error {
+	args := m.Called(ctx, secretID, version, event)
 	return args.Error(0)
 }
 
@@ -26,12 +31,13 @@ func TestDelete(t *testing.T) {
 		expectedErrMsg string
 	}{
 		{
-			name:   "deletes secret",
+			name:   "moves secret to trash",
 			userID: 1,
 			secret: models.Secret{
 				ID:         1,
 				UserID:     1,
 				SecretType: models.CredentialsSecret,
+				Version:    3,
 			},
 		},
 		{
@@ -41,21 +47,74 @@ func TestDelete(t *testing.T) {
 				ID:         1,
 				UserID:     1,
 				SecretType: models.CredentialsSecret,
+				Version:    3,
 			},
 			expectedErrMsg: "user with id=2 doesn't have permission to secret with id=1",
 		},
 	}
-	secretDeleter := new(secretDeleterMock)
-	delSrv := services.NewDeleteSecretService(secretDeleter)
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			secretDeleter.On("DeleteSecret", mock.Anything, mock.Anything).
-				Return(tc.delErr).
-				Once()
+			event := models.AuditEvent{UserID: tc.userID, Action: models.AuditDelete}
+			secretDeleter := new(secretDeleterMock)
+			secretDeleter.On("TrashSecret", mock.Anything, tc.secret.ID, tc.secret.Version, event).Return(tc.delErr)
+			delSrv := services.NewDeleteSecretService(secretDeleter, ownerOnlyACL())
 
 			err := delSrv.Delete(context.TODO(), tc.userID, tc.secret)
-			if err != nil {
+			if tc.expectedErrMsg == "" {
+				assert.NoError(t, err)
+				secretDeleter.AssertCalled(t, "TrashSecret", mock.Anything, tc.secret.ID, tc.secret.Version, event)
+			} else {
+				assert.EqualError(t, err, tc.expectedErrMsg)
+				secretDeleter.AssertNotCalled(t, "TrashSecret", mock.Anything, tc.secret.ID, tc.secret.Version, event)
+			}
+			secretDeleter.AssertNotCalled(t, "DeleteSecret", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPurge(t *testing.T) {
+	testCases := []struct {
+		name           string
+		userID         int
+		secret         models.Secret
+		expectedErrMsg string
+	}{
+		{
+			name:   "deletes secret permanently",
+			userID: 1,
+			secret: models.Secret{
+				ID:         1,
+				UserID:     1,
+				SecretType: models.CredentialsSecret,
+				Version:    3,
+			},
+		},
+		{
+			name:   "returns permission error if user is not secret owner",
+			userID: 2,
+			secret: models.Secret{
+				ID:         1,
+				UserID:     1,
+				SecretType: models.CredentialsSecret,
+				Version:    3,
+			},
+			expectedErrMsg: "user with id=2 doesn't have permission to secret with id=1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			event := models.AuditEvent{UserID: tc.userID, Action: models.AuditPurge}
+			secretDeleter := new(secretDeleterMock)
+			secretDeleter.On("DeleteSecret", mock.Anything, tc.secret.ID, tc.secret.Version, event).Return(nil)
+			delSrv := services.NewDeleteSecretService(secretDeleter, ownerOnlyACL())
+
+			err := delSrv.Purge(context.TODO(), tc.userID, tc.secret)
+			if tc.expectedErrMsg == "" {
+				assert.NoError(t, err)
+				secretDeleter.AssertCalled(t, "DeleteSecret", mock.Anything, tc.secret.ID, tc.secret.Version, event)
+			} else {
 				assert.EqualError(t, err, tc.expectedErrMsg)
+				secretDeleter.AssertNotCalled(t, "DeleteSecret", mock.Anything, tc.secret.ID, tc.secret.Version, event)
 			}
 		})
 	}
@@ -4,6 +4,7 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"encoding/binary"
 	"errors"
 )
 
@@ -76,6 +77,73 @@ func (de DataEncryptor) Decrypt(ciphertext []byte, encryptedKey []byte) ([]byte,
 	return msg, nil
 }
 
+// GenerateKey returns a new data key encrypted with the master key.
+func (de DataEncryptor) GenerateKey() ([]byte, error) {
+	key, err := de.randGen.Gen(len(de.masterKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return de.encrypt(key, de.masterKey)
+}
+
+// NewChunkCipher returns a cipher for content chunks of a secret
+// encrypted with the given data key.
+func (de DataEncryptor) NewChunkCipher(encryptedKey []byte) (ChunkCipher, error) {
+	key, err := de.decrypt(encryptedKey, de.masterKey)
+	if err != nil {
+		return ChunkCipher{}, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return ChunkCipher{}, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return ChunkCipher{}, err
+	}
+
+	return ChunkCipher{
+		randGen: de.randGen,
+		gcm:     gcm,
+	}, nil
+}
+
+// ChunkCipher encrypts chunks with AES-GCM. The chunk index is
+// authenticated, so chunks can not be reordered.
+type ChunkCipher struct {
+	randGen RandGen
+	gcm     cipher.AEAD
+}
+
+func (cc ChunkCipher) Seal(idx int, chunk []byte) ([]byte, error) {
+	nonce, err := cc.randGen.Gen(cc.gcm.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := make([]byte, 0, len(nonce)+len(chunk)+cc.gcm.Overhead())
+	ciphertext = append(ciphertext, nonce...)
+
+	return cc.gcm.Seal(ciphertext, nonce, chunk, chunkAdditionalData(idx)), nil
+}
+
+func (cc ChunkCipher) Open(idx int, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < cc.gcm.NonceSize() {
+		return nil, errors.New("invalid chunk ciphertext")
+	}
+	nonce := ciphertext[:cc.gcm.NonceSize()]
+	ciphertext = ciphertext[cc.gcm.NonceSize():]
+
+	return cc.gcm.Open(nil, nonce, ciphertext, chunkAdditionalData(idx))
+}
+
+func chunkAdditionalData(idx int) []byte {
+	ad := make([]byte, 8)
+	binary.BigEndian.PutUint64(ad, uint64(idx))
+
+	return ad
+}
+
 func (de DataEncryptor) encrypt(msg []byte, key []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
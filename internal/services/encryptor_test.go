@@ -7,6 +7,7 @@ import (
 	"github.com/ilya-burinskiy/gophkeeper/internal/services"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type randGenMock struct{ mock.Mock }
@@ -205,3 +206,39 @@ func TestReEncrypt(t *testing.T) {
 		})
 	}
 }
+
+func TestChunkCipher(t *testing.T) {
+	encryptor, _ := services.NewDataEncryptor(
+		services.CryptoRandGen{},
+		[]byte{
+			239, 140, 200, 100, 31, 6, 108, 25, 158, 54, 149, 16, 138, 90, 157, 144,
+			53, 144, 0, 193, 140, 107, 205, 41, 70, 167, 116, 218, 39, 58, 207, 240,
+		},
+	)
+	encryptedKey, err := encryptor.GenerateKey()
+	require.NoError(t, err)
+	chunkCipher, err := encryptor.NewChunkCipher(encryptedKey)
+	require.NoError(t, err)
+
+	ciphertext, err := chunkCipher.Seal(1, []byte("chunk"))
+	require.NoError(t, err)
+
+	t.Run("opens sealed chunk", func(t *testing.T) {
+		chunk, err := chunkCipher.Open(1, ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("chunk"), chunk)
+	})
+	t.Run("returns error if chunk index does not match", func(t *testing.T) {
+		_, err := chunkCipher.Open(2, ciphertext)
+		assert.Error(t, err)
+	})
+	t.Run("returns error if chunk is encrypted with another key", func(t *testing.T) {
+		otherKey, err := encryptor.GenerateKey()
+		require.NoError(t, err)
+		otherCipher, err := encryptor.NewChunkCipher(otherKey)
+		require.NoError(t, err)
+
+		_, err = otherCipher.Open(1, ciphertext)
+		assert.Error(t, err)
+	})
+}
@@ -3,14 +3,30 @@ package services
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
+// ErrNoPermission is returned if the user does not have permission to the
+// secret or, if VaultID or OrganizationID is set, to the vault or to the
+// organization.
 type ErrNoPermission struct {
-	UserID   int
-	SecretID int
+	UserID         int
+	SecretID       int
+	VaultID        int
+	OrganizationID int
 }
 
 func (err ErrNoPermission) Error() string {
+	if err.VaultID != 0 {
+		return fmt.Sprintf("user with id=%d doesn't have permission to vault with id=%d", err.UserID, err.VaultID)
+	}
+	if err.OrganizationID != 0 {
+		return fmt.Sprintf(
+			"user with id=%d doesn't have permission to organization with id=%d",
+			err.UserID,
+			err.OrganizationID,
+		)
+	}
 	return fmt.Sprintf(
 		"user with id=%d doesn't have permission to secret with id=%d",
 		err.UserID,
@@ -19,3 +35,73 @@ func (err ErrNoPermission) Error() string {
 }
 
 var ErrWrongSecretType = errors.New("can not change secret type")
+
+var ErrBinDataTooLarge = errors.New("bin data is too large")
+
+var ErrUploadOffsetMismatch = errors.New("upload offset mismatch")
+
+var ErrShareWithOwner = errors.New("can not share secret with its owner")
+
+var ErrInvalidShareAccess = errors.New("secret can be shared with read or write access only")
+
+var ErrShareVaultSecret = errors.New("vault secrets are shared with organization members only")
+
+var ErrInvalidOrganizationName = errors.New("invalid organization name")
+
+var ErrInvalidVaultName = errors.New("invalid vault name")
+
+var ErrRemoveOrganizationOwner = errors.New("organization owner can not be removed from the organization")
+
+var ErrInvalidOrganizationRole = errors.New("organization role must be viewer, editor or admin")
+
+var ErrChangeOrganizationOwnerRole = errors.New("role of organization owner can not be changed")
+
+var ErrInvalidCredentials = errors.New("invalid login or password")
+
+// ErrLoginLocked is returned if login attempts are rejected for RetryAfter
+// after too many failures.
+type ErrLoginLocked struct {
+	RetryAfter time.Duration
+}
+
+func (err ErrLoginLocked) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry after %s", err.RetryAfter.Round(time.Second))
+}
+
+// ErrTOTPRequired is returned if the password is valid, but the user has
+// to pass the TOTP check with the MFA token to get JWT.
+type ErrTOTPRequired struct {
+	MFAToken string
+}
+
+func (err ErrTOTPRequired) Error() string {
+	return "TOTP code is required"
+}
+
+var ErrTOTPAlreadyEnabled = errors.New("TOTP is already enabled")
+
+var ErrTOTPNotEnrolled = errors.New("TOTP is not enrolled")
+
+var ErrInvalidTOTPCode = errors.New("invalid TOTP code")
+
+var ErrInvalidMFAToken = errors.New("invalid or expired MFA token")
+
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+var ErrInvalidAPIToken = errors.New("invalid or expired api token")
+
+var ErrInvalidAPITokenName = errors.New("invalid api token name")
+
+var ErrInvalidAPITokenExpiry = errors.New("api token expiry must be in the future")
+
+var ErrInvalidServiceAccountName = errors.New(
+	"service account name must be 1-64 lowercase letters, digits or hyphens starting with a letter or a digit",
+)
+
+var ErrServiceAccountDisabled = errors.New("service account is disabled")
+
+var ErrInvalidPublicKey = errors.New("public key must be a PEM encoded Ed25519 or P-256 key")
+
+var ErrInvalidClientCredentials = errors.New("invalid client credentials or assertion")
+
+var ErrReservedLogin = errors.New("login is reserved for service accounts")
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+// SecretEventsBufferSize is the number of events kept for a subscriber
+// that has not received them yet.
+const SecretEventsBufferSize = 64
+
+type SecretEventListener interface {
+	ListenSecretEvents(ctx context.Context, handle func(models.SecretEvent)) error
+}
+
+type SecretUsersAuthorizer interface {
+	AuthorizedUsers(ctx context.Context, userIDs []int, secret models.Secret, access models.SecretAccess) ([]int, error)
+}
+
+// SecretEventsService delivers secret events received from the storage
+// to subscribers who can read the secret.
+type SecretEventsService struct {
+	listener    SecretEventListener
+	authorizer  SecretUsersAuthorizer
+	mu          *sync.Mutex
+	subscribers map[int]map[chan models.SecretEvent]struct{}
+}
+
+func NewSecretEventsService(listener SecretEventListener, authorizer SecretUsersAuthorizer) SecretEventsService {
+	return SecretEventsService{
+		listener:    listener,
+		authorizer:  authorizer,
+		mu:          &sync.Mutex{},
+		subscribers: make(map[int]map[chan models.SecretEvent]struct{}),
+	}
+}
+
+// Run delivers events until listening fails or ctx is done. Events may be
+// lost then, so all subscriptions are closed and subscribers should sync
+// secrets before subscribing again.
+func (srv SecretEventsService) Run(ctx context.Context) error {
+	err := srv.listener.ListenSecretEvents(ctx, func(event models.SecretEvent) {
+		srv.publish(ctx, event)
+	})
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for userID, channels := range srv.subscribers {
+		for events := range channels {
+			close(events)
+		}
+		delete(srv.subscribers, userID)
+	}
+
+	return err
+}
+
+// Subscribe returns the channel of the user secret events and the function
+// cancelling the subscription. The channel is closed if the subscriber
+// falls behind by more than SecretEventsBufferSize events or events may
+// have been lost.
+func (srv SecretEventsService) Subscribe(userID int) (<-chan models.SecretEvent, func()) {
+	events := make(chan models.SecretEvent, SecretEventsBufferSize)
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.subscribers[userID] == nil {
+		srv.subscribers[userID] = make(map[chan models.SecretEvent]struct{})
+	}
+	srv.subscribers[userID][events] = struct{}{}
+
+	return events, func() {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		srv.unsubscribe(userID, events)
+	}
+}
+
+// publish delivers the event to subscribers who can read the secret. The
+// access is checked at delivery, so share recipients and vault members get
+// the event and users who have lost the access do not. Shares of a purged
+// secret are deleted along with it, so its recipients are not notified.
+// Readers are resolved with a single query per event, not per subscriber.
+func (srv SecretEventsService) publish(ctx context.Context, event models.SecretEvent) {
+	secret := models.Secret{ID: event.SecretID, UserID: event.UserID, VaultID: event.VaultID}
+	userIDs := srv.subscriberIDs()
+	if len(userIDs) == 0 {
+		return
+	}
+	readers, err := srv.authorizer.AuthorizedUsers(ctx, userIDs, secret, models.SecretReadAccess)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if err != nil {
+		// the event may be lost for any subscriber, so all of them have to sync
+		for _, userID := range userIDs {
+			for events := range srv.subscribers[userID] {
+				srv.unsubscribe(userID, events)
+			}
+		}
+		return
+	}
+	for _, userID := range readers {
+		for events := range srv.subscribers[userID] {
+			select {
+			case events <- event:
+			default:
+				srv.unsubscribe(userID, events)
+			}
+		}
+	}
+}
+
+// subscriberIDs returns users having subscriptions, readers are resolved
+// without holding srv.mu, since it queries the storage.
+func (srv SecretEventsService) subscriberIDs() []int {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	userIDs := make([]int, 0, len(srv.subscribers))
+	for userID := range srv.subscribers {
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs
+}
+
+// unsubscribe closes the subscription if it has not been closed yet,
+// srv.mu must be held.
+func (srv SecretEventsService) unsubscribe(userID int, events chan models.SecretEvent) {
+	if _, ok := srv.subscribers[userID][events]; !ok {
+		return
+	}
+	close(events)
+	delete(srv.subscribers[userID], events)
+	if len(srv.subscribers[userID]) == 0 {
+		delete(srv.subscribers, userID)
+	}
+}
@@ -0,0 +1,186 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type secretEventListenerMock struct{ mock.Mock }
+
+func (m *secretEventListenerMock) ListenSecretEvents(ctx context.Context, handle func(models.SecretEvent)) error {
+	args := m.Called(ctx, handle)
+	return args.Error(0)
+}
+
+func TestSecretEventsRun(t *testing.T) {
+	manyEvents := make([]models.SecretEvent, services.SecretEventsBufferSize+1)
+	manyRevisions := make([]int, services.SecretEventsBufferSize)
+	for i := range manyEvents {
+		manyEvents[i] = models.SecretEvent{Kind: models.SecretUpdated, UserID: 1, SecretID: 1, Revision: i + 1}
+		if i < len(manyRevisions) {
+			manyRevisions[i] = i + 1
+		}
+	}
+	testCases := []struct {
+		name      string
+		events    []models.SecretEvent
+		listenErr error
+		// want are revisions received by subscribers of users 1 and 2
+		want map[int][]int
+	}{
+		{
+			name: "delivers events to subscribers of secret owner",
+			events: []models.SecretEvent{
+				{Kind: models.SecretCreated, UserID: 1, SecretID: 1, Revision: 1},
+				{Kind: models.SecretUpdated, UserID: 2, SecretID: 2, Revision: 5},
+				{Kind: models.SecretDeleted, UserID: 1, SecretID: 1, Revision: 2},
+				{Kind: models.SecretCreated, UserID: 3, SecretID: 3, Revision: 1},
+			},
+			listenErr: errors.New("error"),
+			want: map[int][]int{
+				1: {1, 2},
+				2: {5},
+			},
+		},
+		{
+			name:      "closes subscription falling behind",
+			events:    manyEvents,
+			listenErr: context.Canceled,
+			want: map[int][]int{
+				1: manyRevisions,
+				2: nil,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			listener := new(secretEventListenerMock)
+			listener.On("ListenSecretEvents", mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					handle := args.Get(1).(func(models.SecretEvent))
+					for _, event := range tc.events {
+						handle(event)
+					}
+				}).
+				Return(tc.listenErr)
+			srv := services.NewSecretEventsService(listener, ownerOnlyACL())
+			subscriptions := make(map[int]<-chan models.SecretEvent)
+			for userID := range tc.want {
+				subscriptions[userID], _ = srv.Subscribe(userID)
+			}
+
+			err := srv.Run(context.Background())
+			assert.ErrorIs(t, err, tc.listenErr)
+			for userID, events := range subscriptions {
+				var revisions []int
+				// the channel is closed after Run returns
+				for event := range events {
+					assert.Equal(t, userID, event.UserID)
+					revisions = append(revisions, event.Revision)
+				}
+				assert.Equal(t, tc.want[userID], revisions)
+			}
+		})
+	}
+}
+
+func TestSecretEventsUnsubscribe(t *testing.T) {
+	listener := new(secretEventListenerMock)
+	listener.On("ListenSecretEvents", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			handle := args.Get(1).(func(models.SecretEvent))
+			handle(models.SecretEvent{Kind: models.SecretCreated, UserID: 1, SecretID: 1, Revision: 1})
+		}).
+		Return(nil)
+	srv := services.NewSecretEventsService(listener, ownerOnlyACL())
+	events, unsubscribe := srv.Subscribe(1)
+	unsubscribe()
+	_, ok := <-events
+	assert.False(t, ok)
+
+	assert.NoError(t, srv.Run(context.Background()))
+	// cancelling closed subscription does nothing
+	unsubscribe()
+}
+
+func TestSecretEventsFanOut(t *testing.T) {
+	listener := new(secretEventListenerMock)
+	listener.On("ListenSecretEvents", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			handle := args.Get(1).(func(models.SecretEvent))
+			handle(models.SecretEvent{Kind: models.SecretUpdated, UserID: 1, SecretID: 1, Revision: 1})
+			handle(models.SecretEvent{Kind: models.SecretUpdated, UserID: 1, VaultID: 7, SecretID: 2, Revision: 2})
+		}).
+		Return(nil)
+	// readers are resolved once per event for all the subscribers
+	subscribers := mock.MatchedBy(func(userIDs []int) bool {
+		sorted := append([]int(nil), userIDs...)
+		sort.Ints(sorted)
+		return assert.ObjectsAreEqual([]int{1, 2, 3}, sorted)
+	})
+	finder := new(secretAccessFinderMock)
+	finder.On("FindSecretShareAccesses", mock.Anything, 1, subscribers).
+		Return(map[int]models.SecretAccess{2: models.SecretReadAccess}, nil).Once()
+	// user 1 has been removed from the vault organization
+	finder.On("FindVaultAccesses", mock.Anything, 7, subscribers).
+		Return(map[int]models.SecretAccess{3: models.SecretReadAccess}, nil).Once()
+	srv := services.NewSecretEventsService(listener, services.NewSecretACL(finder))
+	// want are ids of secrets received by subscribers of the users
+	want := map[int][]int{
+		1: {1},
+		2: {1},
+		3: {2},
+	}
+	subscriptions := make(map[int]<-chan models.SecretEvent)
+	for userID := range want {
+		subscriptions[userID], _ = srv.Subscribe(userID)
+	}
+
+	assert.NoError(t, srv.Run(context.Background()))
+	for userID, events := range subscriptions {
+		var secretIDs []int
+		for event := range events {
+			secretIDs = append(secretIDs, event.SecretID)
+		}
+		assert.Equal(t, want[userID], secretIDs, "user %d", userID)
+	}
+	finder.AssertExpectations(t)
+}
+
+func TestSecretEventsReadersNotFound(t *testing.T) {
+	listener := new(secretEventListenerMock)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	published := make(chan struct{})
+	listener.On("ListenSecretEvents", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			handle := args.Get(1).(func(models.SecretEvent))
+			handle(models.SecretEvent{Kind: models.SecretUpdated, UserID: 1, SecretID: 1, Revision: 1})
+			close(published)
+			<-ctx.Done()
+		}).
+		Return(nil)
+	finder := new(secretAccessFinderMock)
+	finder.On("FindSecretShareAccesses", mock.Anything, 1, mock.Anything).
+		Return(map[int]models.SecretAccess(nil), errors.New("error"))
+	srv := services.NewSecretEventsService(listener, services.NewSecretACL(finder))
+	owner, _ := srv.Subscribe(1)
+	other, _ := srv.Subscribe(2)
+
+	go srv.Run(ctx)
+	<-published
+	// the event may be lost for any subscriber, so all subscriptions are
+	// closed without delivering it
+	_, ok := <-owner
+	assert.False(t, ok)
+	_, ok = <-other
+	assert.False(t, ok)
+}
@@ -2,16 +2,22 @@ package services
 
 import (
 	"archive/zip"
-	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"strconv"
+	"strings"
 
 	"github.com/ilya-burinskiy/gophkeeper/internal/models"
 )
 
 type UserSecretsFetcher interface {
-	ListUserSecrets(ctx context.Context, userID int) ([]models.Secret, error)
+	// StreamUserSecrets must yield secrets grouped by folder and type.
+	StreamUserSecrets(ctx context.Context, userID int, filter models.SecretsFilter, fn func(models.Secret) error) error
+}
+
+type UserFoldersLister interface {
+	ListUserFolders(ctx context.Context, userID int) ([]models.Folder, error)
 }
 
 type Unmarshaller interface {
@@ -22,180 +28,282 @@ type Decryptor interface {
 	Decrypt(ciphertext []byte, encryptedKey []byte) ([]byte, error)
 }
 
+type BinDataWriter interface {
+	WriteContent(ctx context.Context, secret models.Secret, binData *models.BinData, w io.Writer) error
+}
+
 type FetchUserSecretsService struct {
-	fetcher   UserSecretsFetcher
-	decryptor Decryptor
+	fetcher       UserSecretsFetcher
+	foldersLister UserFoldersLister
+	decryptor     Decryptor
+	binDataWriter BinDataWriter
+	authorizer    SecretAuthorizer
+	auditor       AuditRecorder
 }
 
-func NewFetchUserSecretsService(fetcher UserSecretsFetcher, decryptor Decryptor) FetchUserSecretsService {
+func NewFetchUserSecretsService(
+	fetcher UserSecretsFetcher,
+	foldersLister UserFoldersLister,
+	decryptor Decryptor,
+	binDataWriter BinDataWriter,
+	authorizer SecretAuthorizer,
+	auditor AuditRecorder) FetchUserSecretsService {
+
 	return FetchUserSecretsService{
-		fetcher:   fetcher,
-		decryptor: decryptor,
+		fetcher:       fetcher,
+		foldersLister: foldersLister,
+		decryptor:     decryptor,
+		binDataWriter: binDataWriter,
+		authorizer:    authorizer,
+		auditor:       auditor,
 	}
 }
 
-func (srv FetchUserSecretsService) FetchUserSecrets(ctx context.Context, userID int) ([]byte, error) {
-	secrets, err := srv.fetcher.ListUserSecrets(ctx, userID)
-	if err != nil {
-		return nil, err
-	}
-	var (
-		credsSecrets      []models.Secret
-		creditCardSecrets []models.Secret
-		binDataSecrets    []models.Secret
-	)
-	for i := 0; i < len(secrets); i++ {
-		switch secrets[i].SecretType {
-		case models.CredentialsSecret:
-			credsSecrets = append(credsSecrets, secrets[i])
-		case models.CreditCardSecret:
-			creditCardSecrets = append(creditCardSecrets, secrets[i])
-		case models.BinDataSecret:
-			binDataSecrets = append(binDataSecrets, secrets[i])
+// FetchUserSecrets writes a zip archive with decrypted user secrets matching
+// the filter to w. Secrets are decrypted and written one at a time as they
+// are read from storage. If the filter has a vault, secrets of the vault
+// are written without folders.
+func (srv FetchUserSecretsService) FetchUserSecrets(
+	ctx context.Context,
+	userID int,
+	filter models.SecretsFilter,
+	w io.Writer) error {
+
+	var folders []models.Folder
+	if filter.VaultID != 0 {
+		err := srv.authorizer.AuthorizeVault(ctx, userID, filter.VaultID, models.SecretReadAccess)
+		if err != nil {
+			return err
+		}
+	} else {
+		var err error
+		folders, err = srv.foldersLister.ListUserFolders(ctx, userID)
+		if err != nil {
+			return err
 		}
 	}
-
-	archiveContent := bytes.Buffer{}
-	zipWriter := zip.NewWriter(&archiveContent)
-	err = srv.writeCredsSecrets(zipWriter, credsSecrets)
-	if err != nil {
-		return nil, err
+	archive := secretsArchive{
+		ctx:           ctx,
+		userID:        userID,
+		auditor:       srv.auditor,
+		zipWriter:     zip.NewWriter(w),
+		decryptor:     srv.decryptor,
+		binDataWriter: srv.binDataWriter,
+		folderPaths:   folderPaths(folders),
 	}
-	err = srv.writeCreditCardsSecrets(zipWriter, creditCardSecrets)
-	if err != nil {
-		return nil, err
+	if err := archive.writeFolders(folders, filter.FolderID); err != nil {
+		return err
 	}
-	err = srv.writeBinDataSecrets(zipWriter, binDataSecrets)
+	err := srv.fetcher.StreamUserSecrets(ctx, userID, filter, archive.writeSecret)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	err = zipWriter.Close()
-	if err != nil {
-		return nil, err
-	}
+	return archive.close()
+}
+
+// secretsArchive mirrors the folder tree of the user. In every folder
+// credentials, credit cards and notes are written as JSON arrays into
+// a single file per type and every bin data into its own file.
+// Bin data metadata is written next to the bin data file into a file
+// with ".metadata.json" suffix. Secrets without a folder are written
+// into the archive root.
+type secretsArchive struct {
+	ctx           context.Context
+	userID        int
+	auditor       AuditRecorder
+	zipWriter     *zip.Writer
+	decryptor     Decryptor
+	binDataWriter BinDataWriter
+	// folderPaths maps folder ID to its path with trailing slash
+	folderPaths map[int]string
 
-	return archiveContent.Bytes(), nil
+	jsonFile       io.Writer
+	jsonFileFolder int
+	jsonFileType   models.SecretType
+	jsonFileEmpty  bool
 }
 
-func (srv FetchUserSecretsService) writeCredsSecrets(zipWriter *zip.Writer, credsSecrets []models.Secret) error {
-	if len(credsSecrets) == 0 {
-		return nil
+// folderPaths builds folder paths from folder names, folder names can not
+// contain slashes, so the paths are unique.
+func folderPaths(folders []models.Folder) map[int]string {
+	byID := make(map[int]models.Folder, len(folders))
+	for _, folder := range folders {
+		byID[folder.ID] = folder
 	}
-
-	creds := make([]*models.Credentials, len(credsSecrets))
-	for i := 0; i < len(credsSecrets); i++ {
-		decryptedCreds, err := srv.decryptor.Decrypt(
-			credsSecrets[i].EncryptedData,
-			credsSecrets[i].EncryptedKey,
-		)
-		if err != nil {
-			return err
+	paths := make(map[int]string, len(folders))
+	var pathOf func(id int, depth int) string
+	pathOf = func(id int, depth int) string {
+		if path, ok := paths[id]; ok {
+			return path
+		}
+		folder, ok := byID[id]
+		// depth guards against a broken tree
+		if !ok || depth > len(folders) {
+			return ""
 		}
+		path := pathOf(folder.ParentID, depth+1) + folder.Name + "/"
+		paths[id] = path
+		return path
+	}
+	for _, folder := range folders {
+		pathOf(folder.ID, 0)
+	}
 
-		creds[i] = &models.Credentials{}
-		err = creds[i].Unmarshall(decryptedCreds)
-		if err != nil {
+	return paths
+}
+
+// writeFolders adds directory entries, so empty folders are kept in the
+// archive. If rootID is not zero only the folder and its subfolders are written.
+func (archive *secretsArchive) writeFolders(folders []models.Folder, rootID int) error {
+	rootPath := archive.folderPaths[rootID]
+	for _, folder := range folders {
+		path := archive.folderPaths[folder.ID]
+		if rootID != 0 && !strings.HasPrefix(path, rootPath) {
+			continue
+		}
+		if _, err := archive.zipWriter.Create(path); err != nil {
 			return err
 		}
-		creds[i].ID = credsSecrets[i].ID
-		creds[i].Description = credsSecrets[i].Description
 	}
 
-	credsJSON, err := json.Marshal(creds)
+	return nil
+}
+
+func (archive *secretsArchive) writeSecret(secret models.Secret) error {
+	if err := archive.auditor.Record(archive.ctx, archive.userID, models.AuditRead, secret); err != nil {
+		return err
+	}
+	decryptedData, err := archive.decryptor.Decrypt(secret.EncryptedData, secret.EncryptedKey)
 	if err != nil {
 		return err
 	}
-
-	f, err := zipWriter.Create("credentials.json")
+	metadata, err := decryptMetadata(archive.decryptor, secret)
 	if err != nil {
 		return err
 	}
 
-	_, err = f.Write(credsJSON)
-	return err
-}
+	switch secret.SecretType {
+	case models.CredentialsSecret:
+		creds := &models.Credentials{}
+		if err := creds.Unmarshall(decryptedData); err != nil {
+			return err
+		}
+		creds.ID = secret.ID
+		creds.Description = secret.Description
+		creds.Metadata = metadata
+		return archive.writeJSONItem(secret, "credentials.json", creds)
+	case models.CreditCardSecret:
+		creditCard := &models.CreditCard{}
+		if err := creditCard.Unmarshall(decryptedData); err != nil {
+			return err
+		}
+		creditCard.ID = secret.ID
+		creditCard.Description = secret.Description
+		creditCard.Metadata = metadata
+		return archive.writeJSONItem(secret, "credit_cards.json", creditCard)
+	case models.TextSecret:
+		text := &models.Text{}
+		if err := text.Unmarshall(decryptedData); err != nil {
+			return err
+		}
+		text.ID = secret.ID
+		text.Description = secret.Description
+		text.Metadata = metadata
+		return archive.writeJSONItem(secret, "notes.json", text)
+	case models.BinDataSecret:
+		binData := &models.BinData{}
+		if err := binData.Unmarshall(decryptedData); err != nil {
+			return err
+		}
+		binData.Metadata = metadata
+		return archive.writeBinData(secret, binData)
+	}
 
-func (srv FetchUserSecretsService) writeCreditCardsSecrets(
-	zipWriter *zip.Writer,
-	creditCardsSecrets []models.Secret) error {
+	return nil
+}
 
-	if len(creditCardsSecrets) == 0 {
-		return nil
-	}
+func (archive *secretsArchive) writeJSONItem(secret models.Secret, fname string, item interface{}) error {
+	if archive.jsonFile == nil ||
+		archive.jsonFileFolder != secret.FolderID ||
+		archive.jsonFileType != secret.SecretType {
 
-	creditCards := make([]*models.CreditCard, len(creditCardsSecrets))
-	for i := 0; i < len(creditCardsSecrets); i++ {
-		decryptedCreditCard, err := srv.decryptor.Decrypt(
-			creditCardsSecrets[i].EncryptedData,
-			creditCardsSecrets[i].EncryptedKey,
-		)
-		if err != nil {
+		if err := archive.closeJSONFile(); err != nil {
 			return err
 		}
-		creditCards[i] = &models.CreditCard{}
-		err = creditCards[i].Unmarshall(decryptedCreditCard)
+		f, err := archive.zipWriter.Create(archive.folderPaths[secret.FolderID] + fname)
 		if err != nil {
 			return err
 		}
-		creditCards[i].ID = creditCardsSecrets[i].ID
-		creditCards[i].Description = creditCardsSecrets[i].Description
+		if _, err := f.Write([]byte("[")); err != nil {
+			return err
+		}
+		archive.jsonFile = f
+		archive.jsonFileFolder = secret.FolderID
+		archive.jsonFileType = secret.SecretType
+		archive.jsonFileEmpty = true
 	}
 
-	creditCardsJSON, err := json.Marshal(creditCards)
+	itemJSON, err := json.Marshal(item)
 	if err != nil {
 		return err
 	}
+	if !archive.jsonFileEmpty {
+		if _, err := archive.jsonFile.Write([]byte(",")); err != nil {
+			return err
+		}
+	}
+	archive.jsonFileEmpty = false
+	_, err = archive.jsonFile.Write(itemJSON)
 
-	f, err := zipWriter.Create("credit_cards.json")
-	if err != nil {
-		return err
+	return err
+}
+
+func (archive *secretsArchive) closeJSONFile() error {
+	if archive.jsonFile == nil {
+		return nil
 	}
+	_, err := archive.jsonFile.Write([]byte("]"))
+	archive.jsonFile = nil
 
-	_, err = f.Write(creditCardsJSON)
 	return err
 }
 
-func (srv FetchUserSecretsService) writeBinDataSecrets(
-	zipWriter *zip.Writer,
-	binDataSecrets []models.Secret) error {
+func (archive *secretsArchive) writeBinData(secret models.Secret, binData *models.BinData) error {
+	if err := archive.closeJSONFile(); err != nil {
+		return err
+	}
 
-	if len(binDataSecrets) == 0 {
+	// names stored before they were sanitized on upload may contain paths
+	fname := BaseFilename(binData.Filename)
+	if fname == "" {
+		fname = "bin_data"
+	}
+	fname = archive.folderPaths[secret.FolderID] + fname + "_" + strconv.Itoa(secret.ID)
+
+	f, err := archive.zipWriter.Create(fname)
+	if err != nil {
+		return err
+	}
+	if err := archive.binDataWriter.WriteContent(archive.ctx, secret, binData, f); err != nil {
+		return err
+	}
+	if len(binData.Metadata) == 0 {
 		return nil
 	}
 
-	binData := make([]*models.BinData, len(binDataSecrets))
-	for i := 0; i < len(binDataSecrets); i++ {
-		decryptedBinData, err := srv.decryptor.Decrypt(
-			binDataSecrets[i].EncryptedData,
-			binDataSecrets[i].EncryptedKey,
-		)
-		if err != nil {
-			return err
-		}
-		binData[i] = &models.BinData{}
-		err = binData[i].Unmarshall(decryptedBinData)
-		if err != nil {
-			return err
-		}
+	f, err = archive.zipWriter.Create(fname + ".metadata.json")
+	if err != nil {
+		return err
+	}
 
-		var fname string
-		if binData[i].Filename != "" {
-			fname = binData[i].Filename
-		} else {
-			fname = "bin_data"
-		}
-		fname = fname + "_" + strconv.Itoa(binDataSecrets[i].ID)
+	return json.NewEncoder(f).Encode(binData.Metadata)
+}
 
-		f, err := zipWriter.Create(fname)
-		if err != nil {
-			return err
-		}
-		_, err = f.Write(binData[i].Bytes)
-		if err != nil {
-			return err
-		}
+func (archive *secretsArchive) close() error {
+	if err := archive.closeJSONFile(); err != nil {
+		return err
 	}
 
-	return nil
+	return archive.zipWriter.Close()
 }
@@ -1,9 +1,12 @@
 package services_test
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
 	"encoding/hex"
-	"fmt"
+	"errors"
+	"io"
 	"testing"
 
 	"github.com/ilya-burinskiy/gophkeeper/internal/models"
@@ -15,9 +18,27 @@ import (
 
 type secretFetcherMock struct{ mock.Mock }
 
-func (m *secretFetcherMock) ListUserSecrets(ctx context.Context, userID int) ([]models.Secret, error) {
+func (m *secretFetcherMock) StreamUserSecrets(
+	ctx context.Context,
+	userID int,
+	filter models.SecretsFilter,
+	fn func(models.Secret) error) error {
+
+	args := m.Called(ctx, userID, filter, fn)
+	secrets := args.Get(0).([]models.Secret)
+	for _, secret := range secrets {
+		if err := fn(secret); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+type foldersListerMock struct{ mock.Mock }
+
+func (m *foldersListerMock) ListUserFolders(ctx context.Context, userID int) ([]models.Folder, error) {
 	args := m.Called(ctx, userID)
-	return args.Get(0).([]models.Secret), args.Error(1)
+	return args.Get(0).([]models.Folder), args.Error(1)
 }
 
 func TestFetchSecrets(t *testing.T) {
@@ -26,8 +47,8 @@ func TestFetchSecrets(t *testing.T) {
 		err     error
 	}
 	type want struct {
-		archiveContent []byte
-		errMsg         string
+		archiveFiles map[string]string
+		errMsg       string
 	}
 
 	encryptedCredentials, err := hex.DecodeString(
@@ -51,6 +72,13 @@ func TestFetchSecrets(t *testing.T) {
 			"7d1d03bbf656fbc945568c2809484188de6b8ffdc058bc40ca7f80",
 	)
 	require.NoError(t, err)
+	encryptedText, err := hex.DecodeString(
+		"aa62f0b5c67e3ebc55577dfa59f9a1e5afa34f8f8c450dfcaaecb8d46860b" +
+			"3193e5626c364464961d9fb1cccc73989763cfd2e701dfdf9417a4e84f341" +
+			"e38498ecc48eade9355ba7f7501ab0e403b522527f8bb1ababc4127c2bfd3" +
+			"303b92be5470246b881226c0299a547fe010e2a2a3295",
+	)
+	require.NoError(t, err)
 
 	encryptedKey1, err := hex.DecodeString(
 		"a45753d45593d5f4a9308df51213afb8f98732540d812cb579171acb70d8" +
@@ -67,31 +95,22 @@ func TestFetchSecrets(t *testing.T) {
 			"0bf7a39fa7442811addc51a357059cd4f24db9159f3b9b49bee642652ab7",
 	)
 	require.NoError(t, err)
-	expctedArciveContent, err := hex.DecodeString(
-		"504b030414000800080000000000000000000000000000000000100000006" +
-			"3726564656e7469616c732e6a736f6e8aae56f27451b232d45172492d4e2ec" +
-			"a2c28c9cccf53b25252d251f2c94fcf043173c0b48e5240627171797e518a9" +
-			"2955241798a91526d2c200000ffff504b07082bb1120a3d0000003d0000005" +
-			"04b03041400080008000000000000000000000000000000000011000000637" +
-			"2656469745f63617264732e6a736f6e8aae56f27451b232d25172492d4e2ec" +
-			"a2c28c9cccf53b25252d251f22bcd4d4a2d52b252323432363631353533318" +
-			"64023632313907c626e2a44a56b45416651a54b6209886f646064aa6b68a06" +
-			"b601462686a656060656010a5a4a3e41c166604525d1b0b080000ffff504b0" +
-			"7081b646deb6a00000073000000504b0304140008000800000000000000000" +
-			"00000000000000000050000007478745f33ca2d4ee702040000ffff504b070" +
-			"8b532d6740a00000004000000504b01021400140008000800000000002bb11" +
-			"20a3d0000003d0000001000000000000000000000000000000000006372656" +
-			"4656e7469616c732e6a736f6e504b01021400140008000800000000001b646" +
-			"deb6a0000007300000011000000000000000000000000007b0000006372656" +
-			"469745f63617264732e6a736f6e504b0102140014000800080000000000b53" +
-			"2d6740a0000000400000005000000000000000000000000002401000074787" +
-			"45f33504b05060000000003000300b0000000610100000000",
+	encryptedKey4, err := hex.DecodeString(
+		"fd33bbcf718f7d9477e06f0046fd0c575b59f66e91ff739c1fe379c38f8c" +
+			"d2758d9ee1fb72953487a877cc529c6980f3689dd7a1b1c8dd647c64d423",
 	)
 	require.NoError(t, err)
 
+	folders := []models.Folder{
+		{ID: 1, UserID: 1, Name: "Work"},
+		{ID: 2, UserID: 1, ParentID: 1, Name: "Cards"},
+		{ID: 3, UserID: 1, Name: "Personal"},
+	}
 	testCases := []struct {
 		name     string
 		userID   int
+		filter   models.SecretsFilter
+		folders  []models.Folder
 		fetchRes fetchResult
 		want     want
 	}{
@@ -124,7 +143,158 @@ func TestFetchSecrets(t *testing.T) {
 				},
 			},
 			want: want{
-				archiveContent: expctedArciveContent,
+				archiveFiles: map[string]string{
+					"credentials.json":  `[{"ID":1,"Description":"","Login":"login","Password":"pwd2"}]`,
+					"credit_cards.json": `[{"ID":2,"Description":"","Number":"12334556434343432324","Name":"","ExpiryDate":"2025-10-02T15:00:00Z","CVV2":""}]`,
+					"txt_3":             "msg\n",
+				},
+			},
+		},
+		{
+			name:   "writes every credentials secret into single file",
+			userID: 1,
+			fetchRes: fetchResult{
+				secrets: []models.Secret{
+					{
+						ID:            1,
+						UserID:        1,
+						SecretType:    models.CredentialsSecret,
+						EncryptedData: encryptedCredentials,
+						EncryptedKey:  encryptedKey1,
+					},
+					{
+						ID:            4,
+						UserID:        1,
+						SecretType:    models.CredentialsSecret,
+						Description:   "description",
+						EncryptedData: encryptedCredentials,
+						EncryptedKey:  encryptedKey1,
+					},
+				},
+			},
+			want: want{
+				archiveFiles: map[string]string{
+					"credentials.json": `[{"ID":1,"Description":"","Login":"login","Password":"pwd2"},` +
+						`{"ID":4,"Description":"description","Login":"login","Password":"pwd2"}]`,
+				},
+			},
+		},
+		{
+			name:   "writes notes into notes file",
+			userID: 1,
+			fetchRes: fetchResult{
+				secrets: []models.Secret{
+					{
+						ID:            5,
+						UserID:        1,
+						SecretType:    models.TextSecret,
+						Description:   "description",
+						EncryptedData: encryptedText,
+						EncryptedKey:  encryptedKey4,
+					},
+				},
+			},
+			want: want{
+				archiveFiles: map[string]string{
+					"notes.json": `[{"ID":5,"Description":"description","Title":"title","Body":"line 1\nline 2"}]`,
+				},
+			},
+		},
+		{
+			name:    "mirrors folder tree",
+			userID:  1,
+			folders: folders,
+			fetchRes: fetchResult{
+				secrets: []models.Secret{
+					{
+						ID:            1,
+						UserID:        1,
+						SecretType:    models.CredentialsSecret,
+						EncryptedData: encryptedCredentials,
+						EncryptedKey:  encryptedKey1,
+					},
+					{
+						ID:            4,
+						UserID:        1,
+						SecretType:    models.CredentialsSecret,
+						EncryptedData: encryptedCredentials,
+						EncryptedKey:  encryptedKey1,
+						FolderID:      1,
+					},
+					{
+						ID:            2,
+						UserID:        1,
+						SecretType:    models.CreditCardSecret,
+						EncryptedData: encryptedCreditCard,
+						EncryptedKey:  encryptedKey2,
+						FolderID:      2,
+					},
+					{
+						ID:            3,
+						UserID:        1,
+						SecretType:    models.BinDataSecret,
+						EncryptedData: encryptedBinData,
+						EncryptedKey:  encryptedKey3,
+						FolderID:      2,
+					},
+				},
+			},
+			want: want{
+				archiveFiles: map[string]string{
+					"credentials.json":             `[{"ID":1,"Description":"","Login":"login","Password":"pwd2"}]`,
+					"Work/":                        "",
+					"Work/credentials.json":        `[{"ID":4,"Description":"","Login":"login","Password":"pwd2"}]`,
+					"Work/Cards/":                  "",
+					"Work/Cards/credit_cards.json": `[{"ID":2,"Description":"","Number":"12334556434343432324","Name":"","ExpiryDate":"2025-10-02T15:00:00Z","CVV2":""}]`,
+					"Work/Cards/txt_3":             "msg\n",
+					"Personal/":                    "",
+				},
+			},
+		},
+		{
+			name:    "writes only filtered folder tree",
+			userID:  1,
+			filter:  models.SecretsFilter{FolderID: 1},
+			folders: folders,
+			fetchRes: fetchResult{
+				secrets: []models.Secret{
+					{
+						ID:            4,
+						UserID:        1,
+						SecretType:    models.CredentialsSecret,
+						EncryptedData: encryptedCredentials,
+						EncryptedKey:  encryptedKey1,
+						FolderID:      2,
+					},
+				},
+			},
+			want: want{
+				archiveFiles: map[string]string{
+					"Work/":                       "",
+					"Work/Cards/":                 "",
+					"Work/Cards/credentials.json": `[{"ID":4,"Description":"","Login":"login","Password":"pwd2"}]`,
+				},
+			},
+		},
+		{
+			name:   "returns empty archive",
+			userID: 1,
+			fetchRes: fetchResult{
+				secrets: []models.Secret{},
+			},
+			want: want{
+				archiveFiles: map[string]string{},
+			},
+		},
+		{
+			name:   "returns error if could not fetch secrets",
+			userID: 1,
+			fetchRes: fetchResult{
+				secrets: []models.Secret{},
+				err:     errors.New("error"),
+			},
+			want: want{
+				errMsg: "error",
 			},
 		},
 	}
@@ -137,20 +307,44 @@ func TestFetchSecrets(t *testing.T) {
 			53, 144, 0, 193, 140, 107, 205, 41, 70, 167, 116, 218, 39, 58, 207, 240,
 		},
 	)
-	fetchSrv := services.NewFetchUserSecretsService(fetcher, decryptor)
+	chunkedStorage := new(chunkedStorageMock)
+	chunkedStorage.On("StreamSecretChunks", mock.Anything, mock.Anything).Return(nil)
+	binDataSrv := services.NewBinDataService(chunkedStorage, decryptor, ownerOnlyACL(), services.ChunkSize)
+	foldersLister := new(foldersListerMock)
+	fetchSrv := services.NewFetchUserSecretsService(fetcher, foldersLister, decryptor, binDataSrv, ownerOnlyACL(), noAuditLog{})
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			fetcherCall := fetcher.On("ListUserSecrets", mock.Anything, mock.Anything).
+			fetcherCall := fetcher.On("StreamUserSecrets", mock.Anything, mock.Anything, tc.filter, mock.Anything).
 				Return(tc.fetchRes.secrets, tc.fetchRes.err)
 			defer fetcherCall.Unset()
+			listerCall := foldersLister.On("ListUserFolders", mock.Anything, tc.userID).
+				Return(append([]models.Folder{}, tc.folders...), nil)
+			defer listerCall.Unset()
 
-			archiveContent, err := fetchSrv.FetchUserSecrets(context.TODO(), tc.userID)
+			archiveContent := bytes.Buffer{}
+			err := fetchSrv.FetchUserSecrets(context.TODO(), tc.userID, tc.filter, &archiveContent)
 			if err == nil {
-				fmt.Println(hex.EncodeToString(archiveContent))
-				assert.Equal(t, tc.want.archiveContent, archiveContent)
+				assert.Equal(t, tc.want.archiveFiles, readArchive(t, archiveContent.Bytes()))
 			} else {
 				assert.EqualError(t, err, tc.want.errMsg)
 			}
 		})
 	}
 }
+
+func readArchive(t *testing.T, archiveContent []byte) map[string]string {
+	zipReader, err := zip.NewReader(bytes.NewReader(archiveContent), int64(len(archiveContent)))
+	require.NoError(t, err)
+
+	files := make(map[string]string, len(zipReader.File))
+	for _, f := range zipReader.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		files[f.Name] = string(content)
+	}
+
+	return files
+}
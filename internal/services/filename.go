@@ -0,0 +1,18 @@
+package services
+
+import (
+	"path"
+	"strings"
+)
+
+// BaseFilename returns the last element of the bin data file name sent by
+// the client, so the name cannot point outside of the directory it is
+// extracted to. Both slash and backslash are treated as separators.
+func BaseFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == ".." || name == "/" {
+		return ""
+	}
+
+	return name
+}
@@ -0,0 +1,30 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFilename(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filename string
+		want     string
+	}{
+		{name: "keeps plain name", filename: "file.txt", want: "file.txt"},
+		{name: "strips relative path", filename: "../../etc/passwd", want: "passwd"},
+		{name: "strips absolute path", filename: "/tmp/file.txt", want: "file.txt"},
+		{name: "strips windows path", filename: `..\..\file.txt`, want: "file.txt"},
+		{name: "rejects parent directory", filename: "..", want: ""},
+		{name: "rejects root", filename: "/", want: ""},
+		{name: "keeps empty name", filename: "", want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, services.BaseFilename(tc.filename))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+const maxFolderNameLen = 255
+
+var ErrInvalidFolderName = errors.New("invalid folder name")
+
+var ErrFolderCycle = errors.New("folder can not be moved into itself or its subfolder")
+
+type FolderStorage interface {
+	CreateFolder(ctx context.Context, folder models.Folder) (models.Folder, error)
+	FindUserFolder(ctx context.Context, userID int, id int) (models.Folder, error)
+	ListUserFolders(ctx context.Context, userID int) ([]models.Folder, error)
+	UpdateFolder(ctx context.Context, folder models.Folder) error
+	UpdateSecretFolder(ctx context.Context, secretID int, folderID int) error
+}
+
+type FolderService struct {
+	storage    FolderStorage
+	authorizer SecretAuthorizer
+}
+
+func NewFolderService(storage FolderStorage, authorizer SecretAuthorizer) FolderService {
+	return FolderService{
+		storage:    storage,
+		authorizer: authorizer,
+	}
+}
+
+// Create creates a folder inside the parent folder, zero parentID creates
+// a top level folder.
+func (srv FolderService) Create(ctx context.Context, userID int, name string, parentID int) (models.Folder, error) {
+	folder := models.Folder{UserID: userID, ParentID: parentID, Name: name}
+	if err := validateFolderName(name); err != nil {
+		return folder, err
+	}
+	if parentID != 0 {
+		if _, err := srv.storage.FindUserFolder(ctx, userID, parentID); err != nil {
+			return folder, err
+		}
+	}
+
+	return srv.storage.CreateFolder(ctx, folder)
+}
+
+func (srv FolderService) Find(ctx context.Context, userID int, id int) (models.Folder, error) {
+	return srv.storage.FindUserFolder(ctx, userID, id)
+}
+
+func (srv FolderService) List(ctx context.Context, userID int) ([]models.Folder, error) {
+	return srv.storage.ListUserFolders(ctx, userID)
+}
+
+// Update renames the folder and moves it into the folder with folder.ParentID.
+// A folder can not be moved into itself or any of its subfolders.
+func (srv FolderService) Update(ctx context.Context, folder models.Folder) error {
+	if err := validateFolderName(folder.Name); err != nil {
+		return err
+	}
+	if folder.ParentID != 0 {
+		if _, err := srv.storage.FindUserFolder(ctx, folder.UserID, folder.ParentID); err != nil {
+			return err
+		}
+		folders, err := srv.storage.ListUserFolders(ctx, folder.UserID)
+		if err != nil {
+			return err
+		}
+		parents := make(map[int]int, len(folders))
+		for _, f := range folders {
+			parents[f.ID] = f.ParentID
+		}
+		for id := folder.ParentID; id != 0; id = parents[id] {
+			if id == folder.ID {
+				return ErrFolderCycle
+			}
+		}
+	}
+
+	return srv.storage.UpdateFolder(ctx, folder)
+}
+
+// MoveSecret moves the secret into the folder, zero folderID moves it out
+// of any folder.
+func (srv FolderService) MoveSecret(ctx context.Context, userID int, secret models.Secret, folderID int) error {
+	if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretOwnerAccess); err != nil {
+		return err
+	}
+	if folderID != 0 {
+		if _, err := srv.storage.FindUserFolder(ctx, userID, folderID); err != nil {
+			return err
+		}
+	}
+
+	return srv.storage.UpdateSecretFolder(ctx, secret.ID, folderID)
+}
+
+// validateFolderName rejects names which can not be used as a path
+// segment in the secrets archive.
+func validateFolderName(name string) error {
+	if strings.TrimSpace(name) == "" || len(name) > maxFolderNameLen ||
+		name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return ErrInvalidFolderName
+	}
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type folderStorageMock struct{ mock.Mock }
+
+func (m *folderStorageMock) CreateFolder(ctx context.Context, folder models.Folder) (models.Folder, error) {
+	args := m.Called(ctx, folder)
+	return args.Get(0).(models.Folder), args.Error(1)
+}
+
+func (m *folderStorageMock) FindUserFolder(ctx context.Context, userID int, id int) (models.Folder, error) {
+	args := m.Called(ctx, userID, id)
+	return args.Get(0).(models.Folder), args.Error(1)
+}
+
+func (m *folderStorageMock) ListUserFolders(ctx context.Context, userID int) ([]models.Folder, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Folder), args.Error(1)
+}
+
+func (m *folderStorageMock) UpdateFolder(ctx context.Context, folder models.Folder) error {
+	args := m.Called(ctx, folder)
+	return args.Error(0)
+}
+
+func (m *folderStorageMock) UpdateSecretFolder(ctx context.Context, secretID int, folderID int) error {
+	args := m.Called(ctx, secretID, folderID)
+	return args.Error(0)
+}
+
+func TestCreateFolder(t *testing.T) {
+	testCases := []struct {
+		name     string
+		userID   int
+		folder   string
+		parentID int
+		findErr  error
+		errMsg   string
+	}{
+		{
+			name:   "creates top level folder",
+			userID: 1,
+			folder: "Work",
+		},
+		{
+			name:     "creates subfolder",
+			userID:   1,
+			folder:   "Cards",
+			parentID: 2,
+		},
+		{
+			name:     "returns error if parent folder is not found",
+			userID:   1,
+			folder:   "Cards",
+			parentID: 3,
+			findErr:  storage.ErrFolderNotFound{Folder: models.Folder{ID: 3}},
+			errMsg:   "folder with id=3 not found",
+		},
+		{
+			name:   "returns error if folder name contains slash",
+			userID: 1,
+			folder: "Work/Cards",
+			errMsg: services.ErrInvalidFolderName.Error(),
+		},
+		{
+			name:   "returns error if folder name is blank",
+			userID: 1,
+			folder: " ",
+			errMsg: services.ErrInvalidFolderName.Error(),
+		},
+	}
+
+	folderStorage := new(folderStorageMock)
+	srv := services.NewFolderService(folderStorage, ownerOnlyACL())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expectedFolder := models.Folder{UserID: tc.userID, ParentID: tc.parentID, Name: tc.folder}
+			findCall := folderStorage.On("FindUserFolder", mock.Anything, tc.userID, tc.parentID).
+				Return(models.Folder{ID: tc.parentID, UserID: tc.userID}, tc.findErr)
+			defer findCall.Unset()
+			createCall := folderStorage.On("CreateFolder", mock.Anything, expectedFolder).
+				Return(models.Folder{ID: 5, UserID: tc.userID, ParentID: tc.parentID, Name: tc.folder}, nil)
+			defer createCall.Unset()
+
+			folder, err := srv.Create(context.TODO(), tc.userID, tc.folder, tc.parentID)
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, 5, folder.ID)
+			} else {
+				assert.EqualError(t, err, tc.errMsg)
+				folderStorage.AssertNotCalled(t, "CreateFolder", mock.Anything, expectedFolder)
+			}
+		})
+	}
+}
+
+func TestUpdateFolder(t *testing.T) {
+	// Work(1) -> Cards(2) -> Old(3), Personal(4)
+	folders := []models.Folder{
+		{ID: 1, UserID: 1, Name: "Work"},
+		{ID: 2, UserID: 1, ParentID: 1, Name: "Cards"},
+		{ID: 3, UserID: 1, ParentID: 2, Name: "Old"},
+		{ID: 4, UserID: 1, Name: "Personal"},
+	}
+	testCases := []struct {
+		name   string
+		folder models.Folder
+		errMsg string
+	}{
+		{
+			name:   "renames folder",
+			folder: models.Folder{ID: 2, UserID: 1, ParentID: 1, Name: "Credit cards"},
+		},
+		{
+			name:   "moves folder into another folder",
+			folder: models.Folder{ID: 2, UserID: 1, ParentID: 4, Name: "Cards"},
+		},
+		{
+			name:   "moves folder to top level",
+			folder: models.Folder{ID: 3, UserID: 1, Name: "Old"},
+		},
+		{
+			name:   "returns error if folder is moved into itself",
+			folder: models.Folder{ID: 2, UserID: 1, ParentID: 2, Name: "Cards"},
+			errMsg: services.ErrFolderCycle.Error(),
+		},
+		{
+			name:   "returns error if folder is moved into its subfolder",
+			folder: models.Folder{ID: 1, UserID: 1, ParentID: 3, Name: "Work"},
+			errMsg: services.ErrFolderCycle.Error(),
+		},
+		{
+			name:   "returns error if parent folder is not found",
+			folder: models.Folder{ID: 1, UserID: 1, ParentID: 10, Name: "Work"},
+			errMsg: "folder with id=10 not found",
+		},
+		{
+			name:   "returns error if folder name is invalid",
+			folder: models.Folder{ID: 1, UserID: 1, Name: ".."},
+			errMsg: services.ErrInvalidFolderName.Error(),
+		},
+	}
+
+	folderStorage := new(folderStorageMock)
+	for _, folder := range folders {
+		folderStorage.On("FindUserFolder", mock.Anything, 1, folder.ID).Return(folder, nil)
+	}
+	folderStorage.On("FindUserFolder", mock.Anything, 1, 10).
+		Return(models.Folder{}, storage.ErrFolderNotFound{Folder: models.Folder{ID: 10}})
+	folderStorage.On("ListUserFolders", mock.Anything, 1).Return(folders, nil)
+	srv := services.NewFolderService(folderStorage, ownerOnlyACL())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			updateCall := folderStorage.On("UpdateFolder", mock.Anything, tc.folder).Return(nil)
+			defer updateCall.Unset()
+
+			err := srv.Update(context.TODO(), tc.folder)
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+				folderStorage.AssertCalled(t, "UpdateFolder", mock.Anything, tc.folder)
+			} else {
+				assert.EqualError(t, err, tc.errMsg)
+				folderStorage.AssertNotCalled(t, "UpdateFolder", mock.Anything, tc.folder)
+			}
+		})
+	}
+}
+
+func TestMoveSecret(t *testing.T) {
+	secret := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret}
+	testCases := []struct {
+		name     string
+		userID   int
+		folderID int
+		findErr  error
+		errMsg   string
+	}{
+		{
+			name:     "moves secret into folder",
+			userID:   1,
+			folderID: 2,
+		},
+		{
+			name:   "moves secret out of folder",
+			userID: 1,
+		},
+		{
+			name:     "returns error if folder is not found",
+			userID:   1,
+			folderID: 3,
+			findErr:  storage.ErrFolderNotFound{Folder: models.Folder{ID: 3}},
+			errMsg:   "folder with id=3 not found",
+		},
+		{
+			name:     "returns permission error if user is not secret owner",
+			userID:   2,
+			folderID: 2,
+			errMsg:   "user with id=2 doesn't have permission to secret with id=1",
+		},
+	}
+
+	folderStorage := new(folderStorageMock)
+	srv := services.NewFolderService(folderStorage, ownerOnlyACL())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			findCall := folderStorage.On("FindUserFolder", mock.Anything, tc.userID, tc.folderID).
+				Return(models.Folder{ID: tc.folderID, UserID: tc.userID}, tc.findErr)
+			defer findCall.Unset()
+			updateCall := folderStorage.On("UpdateSecretFolder", mock.Anything, secret.ID, tc.folderID).
+				Return(nil)
+			defer updateCall.Unset()
+
+			err := srv.MoveSecret(context.TODO(), tc.userID, secret, tc.folderID)
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+				folderStorage.AssertCalled(t, "UpdateSecretFolder", mock.Anything, secret.ID, tc.folderID)
+			} else {
+				assert.EqualError(t, err, tc.errMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+const (
+	DefaultSecretsPageSize = 50
+	MaxSecretsPageSize     = 500
+)
+
+type UserSecretsInfoLister interface {
+	ListUserSecretsInfo(ctx context.Context, userID int, filter models.SecretsFilter) ([]models.SecretInfo, error)
+}
+
+type ListSecretsService struct {
+	lister     UserSecretsInfoLister
+	authorizer SecretAuthorizer
+}
+
+func NewListSecretsService(lister UserSecretsInfoLister, authorizer SecretAuthorizer) ListSecretsService {
+	return ListSecretsService{
+		lister:     lister,
+		authorizer: authorizer,
+	}
+}
+
+// List returns a page of the user's secrets, or of the vault secrets if
+// the filter has a vault, and the cursor of the next page, which is zero
+// when there are no more secrets.
+func (srv ListSecretsService) List(
+	ctx context.Context,
+	userID int,
+	filter models.SecretsFilter) ([]models.SecretInfo, int, error) {
+
+	if filter.VaultID != 0 {
+		err := srv.authorizer.AuthorizeVault(ctx, userID, filter.VaultID, models.SecretReadAccess)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultSecretsPageSize
+	}
+	if filter.Limit > MaxSecretsPageSize {
+		filter.Limit = MaxSecretsPageSize
+	}
+	limit := filter.Limit
+	filter.Limit++
+
+	secrets, err := srv.lister.ListUserSecretsInfo(ctx, userID, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	if len(secrets) <= limit {
+		return secrets, 0, nil
+	}
+
+	secrets = secrets[:limit]
+	return secrets, secrets[limit-1].ID, nil
+}
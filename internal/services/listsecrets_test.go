@@ -0,0 +1,145 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type secretsInfoListerMock struct{ mock.Mock }
+
+func (m *secretsInfoListerMock) ListUserSecretsInfo(
+	ctx context.Context,
+	userID int,
+	filter models.SecretsFilter) ([]models.SecretInfo, error) {
+
+	args := m.Called(ctx, userID, filter)
+	return args.Get(0).([]models.SecretInfo), args.Error(1)
+}
+
+func TestListSecrets(t *testing.T) {
+	type listResult struct {
+		secrets []models.SecretInfo
+		err     error
+	}
+	type want struct {
+		secrets    []models.SecretInfo
+		nextCursor int
+		errMsg     string
+	}
+	testCases := []struct {
+		name          string
+		filter        models.SecretsFilter
+		storageFilter models.SecretsFilter
+		listRes       listResult
+		want          want
+	}{
+		{
+			name:          "returns last page",
+			filter:        models.SecretsFilter{Limit: 2, SecretType: models.CredentialsSecret},
+			storageFilter: models.SecretsFilter{Limit: 3, SecretType: models.CredentialsSecret},
+			listRes: listResult{
+				secrets: []models.SecretInfo{{ID: 1}, {ID: 2}},
+			},
+			want: want{
+				secrets: []models.SecretInfo{{ID: 1}, {ID: 2}},
+			},
+		},
+		{
+			name:          "returns next cursor if there are more secrets",
+			filter:        models.SecretsFilter{AfterID: 1, Limit: 2},
+			storageFilter: models.SecretsFilter{AfterID: 1, Limit: 3},
+			listRes: listResult{
+				secrets: []models.SecretInfo{{ID: 2}, {ID: 3}, {ID: 4}},
+			},
+			want: want{
+				secrets:    []models.SecretInfo{{ID: 2}, {ID: 3}},
+				nextCursor: 3,
+			},
+		},
+		{
+			name:          "uses default page size",
+			storageFilter: models.SecretsFilter{Limit: services.DefaultSecretsPageSize + 1},
+			listRes: listResult{
+				secrets: []models.SecretInfo{},
+			},
+			want: want{
+				secrets: []models.SecretInfo{},
+			},
+		},
+		{
+			name:          "returns error if could not list secrets",
+			filter:        models.SecretsFilter{Limit: 1000},
+			storageFilter: models.SecretsFilter{Limit: services.MaxSecretsPageSize + 1},
+			listRes: listResult{
+				err: errors.New("error"),
+			},
+			want: want{
+				errMsg: "failed to list secrets: error",
+			},
+		},
+	}
+
+	lister := new(secretsInfoListerMock)
+	listSrv := services.NewListSecretsService(lister, ownerOnlyACL())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			listCall := lister.On("ListUserSecretsInfo", mock.Anything, 1, tc.storageFilter).
+				Return(tc.listRes.secrets, tc.listRes.err)
+			defer listCall.Unset()
+
+			secrets, nextCursor, err := listSrv.List(context.TODO(), 1, tc.filter)
+			if err == nil {
+				assert.Equal(t, tc.want.secrets, secrets)
+				assert.Equal(t, tc.want.nextCursor, nextCursor)
+			} else {
+				assert.EqualError(t, err, tc.want.errMsg)
+			}
+		})
+	}
+}
+
+func TestListVaultSecrets(t *testing.T) {
+	secrets := []models.SecretInfo{{ID: 1, SecretType: models.TextSecret}}
+	testCases := []struct {
+		name        string
+		userID      int
+		vaultAccess models.SecretAccess
+		wantErr     error
+	}{
+		{
+			name:        "lists vault secrets to organization member",
+			userID:      1,
+			vaultAccess: models.SecretWriteAccess,
+		},
+		{
+			name:    "returns error if user is not organization member",
+			userID:  2,
+			wantErr: services.ErrNoPermission{UserID: 2, VaultID: 3},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lister := new(secretsInfoListerMock)
+			storageFilter := models.SecretsFilter{Limit: services.DefaultSecretsPageSize + 1, VaultID: 3}
+			lister.On("ListUserSecretsInfo", mock.Anything, tc.userID, storageFilter).Return(secrets, nil)
+			finder := new(secretAccessFinderMock)
+			finder.On("FindVaultAccess", mock.Anything, 3, tc.userID).Return(tc.vaultAccess, nil)
+			listSrv := services.NewListSecretsService(lister, services.NewSecretACL(finder))
+
+			result, _, err := listSrv.List(context.TODO(), tc.userID, models.SecretsFilter{VaultID: 3})
+			assert.Equal(t, tc.wantErr, err)
+			if tc.wantErr == nil {
+				assert.Equal(t, secrets, result)
+			} else {
+				lister.AssertNotCalled(t, "ListUserSecretsInfo", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+type SecretMetadataUpdater interface {
+	UpdateSecretMetadata(ctx context.Context, secretID int, version int, encryptedMetadata []byte, event models.AuditEvent) (int, error)
+}
+
+// SecretMetadataService edits secret metadata without touching its data.
+type SecretMetadataService struct {
+	updater     SecretMetadataUpdater
+	reEncryptor ReEncryptor
+	authorizer  SecretAuthorizer
+}
+
+func NewSecretMetadataService(
+	updater SecretMetadataUpdater,
+	reEncryptor ReEncryptor,
+	authorizer SecretAuthorizer) SecretMetadataService {
+
+	return SecretMetadataService{
+		updater:     updater,
+		reEncryptor: reEncryptor,
+		authorizer:  authorizer,
+	}
+}
+
+// Update replaces secret metadata unless the secret has been changed since
+// secret.Version and returns the new version, empty metadata removes it.
+func (srv SecretMetadataService) Update(
+	ctx context.Context,
+	userID int,
+	secret models.Secret,
+	metadata models.Metadata) (int, error) {
+
+	if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretWriteAccess); err != nil {
+		return 0, err
+	}
+	var encryptedMetadata []byte
+	if len(metadata) > 0 {
+		var err error
+		encryptedMetadata, err = encryptMetadata(srv.reEncryptor, metadata, secret.EncryptedKey)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return srv.updater.UpdateSecretMetadata(
+		ctx,
+		secret.ID,
+		secret.Version,
+		encryptedMetadata,
+		newAuditEvent(ctx, userID, models.AuditUpdate),
+	)
+}
+
+// encryptMetadata encrypts metadata with the secret key. Nil metadata
+// is returned as nil, so storage keeps the current secret metadata.
+func encryptMetadata(reEncryptor ReEncryptor, metadata models.Metadata, encryptedKey []byte) ([]byte, error) {
+	if metadata == nil {
+		return nil, nil
+	}
+	metadataBytes, err := metadata.Marshall()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshall metadata: %w", err)
+	}
+	encryptedMetadata, err := reEncryptor.ReEncrypt(metadataBytes, encryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt metadata: %w", err)
+	}
+
+	return encryptedMetadata, nil
+}
+
+func decryptMetadata(decryptor Decryptor, secret models.Secret) (models.Metadata, error) {
+	if len(secret.EncryptedMetadata) == 0 {
+		return nil, nil
+	}
+	metadataBytes, err := decryptor.Decrypt(secret.EncryptedMetadata, secret.EncryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt metadata: %w", err)
+	}
+	var metadata models.Metadata
+	if err := metadata.Unmarshall(metadataBytes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshall metadata: %w", err)
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,99 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type metadataUpdaterMock struct {
+	mock.Mock
+	encryptedMetadata []byte
+}
+
+func (m *metadataUpdaterMock) UpdateSecretMetadata(
+	ctx context.Context,
+	secretID int,
+	version int,
+	encryptedMetadata []byte,
+	event models.AuditEvent) (int, error) {
+
+	args := m.Called(ctx, secretID, version, event)
+	m.encryptedMetadata = encryptedMetadata
+	return args.Int(0), args.Error(1)
+}
+
+func TestUpdateSecretMetadata(t *testing.T) {
+	encryptor, err := services.NewDataEncryptor(services.CryptoRandGen{}, make([]byte, 32))
+	require.NoError(t, err)
+	creds := &models.Credentials{Login: "login", Password: "password"}
+	credsBytes, err := creds.Marshall()
+	require.NoError(t, err)
+	encryptedData, encryptedKey, err := encryptor.Encrypt(credsBytes)
+	require.NoError(t, err)
+	secret := models.Secret{
+		ID:            1,
+		UserID:        1,
+		SecretType:    models.CredentialsSecret,
+		EncryptedData: encryptedData,
+		EncryptedKey:  encryptedKey,
+		Version:       3,
+	}
+
+	testCases := []struct {
+		name         string
+		userID       int
+		metadata     models.Metadata
+		wantMetadata models.Metadata
+		wantErrMsg   string
+	}{
+		{
+			name:         "replaces secret metadata",
+			userID:       1,
+			metadata:     models.Metadata{"url": "https://example.com", "env": "prod"},
+			wantMetadata: models.Metadata{"url": "https://example.com", "env": "prod"},
+		},
+		{
+			name:     "removes secret metadata",
+			userID:   1,
+			metadata: models.Metadata{},
+		},
+		{
+			name:       "returns permission error if user is not secret owner",
+			userID:     2,
+			metadata:   models.Metadata{"env": "prod"},
+			wantErrMsg: "user with id=2 doesn't have permission to secret with id=1",
+		},
+	}
+
+	showSrv := services.NewShowSecretService(encryptor, ownerOnlyACL(), noAuditLog{})
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			updater := new(metadataUpdaterMock)
+			updater.
+				On("UpdateSecretMetadata", mock.Anything, secret.ID, secret.Version, models.AuditEvent{UserID: tc.userID, Action: models.AuditUpdate}).
+				Return(4, nil)
+			metadataSrv := services.NewSecretMetadataService(updater, encryptor, ownerOnlyACL())
+
+			version, err := metadataSrv.Update(context.TODO(), tc.userID, secret, tc.metadata)
+			if tc.wantErrMsg != "" {
+				assert.EqualError(t, err, tc.wantErrMsg)
+				updater.AssertNotCalled(t, "UpdateSecretMetadata", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 4, version)
+
+			updatedSecret := secret
+			updatedSecret.EncryptedMetadata = updater.encryptedMetadata
+			shownSecret, err := showSrv.Show(context.TODO(), 1, updatedSecret)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantMetadata, shownSecret.(*models.Credentials).Metadata)
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+const (
+	maxOrganizationNameLen = 255
+	maxVaultNameLen        = 255
+)
+
+type OrganizationStorage interface {
+	FindUserByLogin(ctx context.Context, login string) (models.User, error)
+	CreateOrganization(ctx context.Context, org models.Organization) (models.Organization, error)
+	FindOrganization(ctx context.Context, id int) (models.Organization, error)
+	ListUserOrganizations(ctx context.Context, userID int) ([]models.Organization, error)
+	CreateOrganizationMember(ctx context.Context, member models.OrganizationMember) (models.OrganizationMember, error)
+	AcceptOrganizationInvitation(ctx context.Context, orgID int, userID int) error
+	ListOrganizationMembers(ctx context.Context, orgID int) ([]models.OrganizationMember, error)
+	UpdateOrganizationMemberRole(ctx context.Context, orgID int, userID int, role models.OrganizationRole) error
+	DeleteOrganizationMember(ctx context.Context, orgID int, userID int) error
+	CreateVault(ctx context.Context, vault models.Vault) (models.Vault, error)
+	ListUserVaults(ctx context.Context, userID int) ([]models.Vault, error)
+}
+
+// OrganizationService manages organizations, their members and vaults.
+// Admins invite and remove members, change their roles and create vaults,
+// invited users become members once they accept the invitation.
+type OrganizationService struct {
+	storage    OrganizationStorage
+	authorizer OrganizationAuthorizer
+}
+
+func NewOrganizationService(storage OrganizationStorage, authorizer OrganizationAuthorizer) OrganizationService {
+	return OrganizationService{
+		storage:    storage,
+		authorizer: authorizer,
+	}
+}
+
+// Create creates an organization owned by the user.
+func (srv OrganizationService) Create(ctx context.Context, userID int, name string) (models.Organization, error) {
+	org := models.Organization{Name: name, OwnerID: userID}
+	if strings.TrimSpace(name) == "" || len(name) > maxOrganizationNameLen {
+		return org, ErrInvalidOrganizationName
+	}
+
+	return srv.storage.CreateOrganization(ctx, org)
+}
+
+// List returns organizations the user is a member of or is invited to.
+func (srv OrganizationService) List(ctx context.Context, userID int) ([]models.Organization, error) {
+	return srv.storage.ListUserOrganizations(ctx, userID)
+}
+
+// Invite invites the user with the login to the organization with the role.
+func (srv OrganizationService) Invite(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	login string,
+	role models.OrganizationRole) (models.OrganizationMember, error) {
+
+	if role.String() == "" {
+		return models.OrganizationMember{}, ErrInvalidOrganizationRole
+	}
+	if err := srv.authorizer.AuthorizeOrganization(ctx, userID, orgID, models.OrganizationAdminRole); err != nil {
+		return models.OrganizationMember{}, err
+	}
+	user, err := srv.storage.FindUserByLogin(ctx, login)
+	if err != nil {
+		return models.OrganizationMember{}, err
+	}
+
+	return srv.storage.CreateOrganizationMember(ctx, models.OrganizationMember{
+		OrganizationID: orgID,
+		UserID:         user.ID,
+		Login:          user.Login,
+		Role:           role,
+	})
+}
+
+// Accept makes the invited user a member of the organization.
+func (srv OrganizationService) Accept(ctx context.Context, userID int, orgID int) error {
+	return srv.storage.AcceptOrganizationInvitation(ctx, orgID, userID)
+}
+
+// Members returns members and invited users of the organization, only
+// members can see them.
+func (srv OrganizationService) Members(ctx context.Context, userID int, orgID int) ([]models.OrganizationMember, error) {
+	if err := srv.authorizer.AuthorizeOrganization(ctx, userID, orgID, models.OrganizationViewerRole); err != nil {
+		return nil, err
+	}
+
+	return srv.storage.ListOrganizationMembers(ctx, orgID)
+}
+
+// SetRole changes the role of the member or of the invited user, the role
+// of the organization owner can not be changed. The new role applies to
+// all organization vaults at once.
+func (srv OrganizationService) SetRole(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	memberID int,
+	role models.OrganizationRole) error {
+
+	if role.String() == "" {
+		return ErrInvalidOrganizationRole
+	}
+	if err := srv.authorizer.AuthorizeOrganization(ctx, userID, orgID, models.OrganizationAdminRole); err != nil {
+		return err
+	}
+	org, err := srv.storage.FindOrganization(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if memberID == org.OwnerID {
+		return ErrChangeOrganizationOwnerRole
+	}
+
+	return srv.storage.UpdateOrganizationMemberRole(ctx, orgID, memberID, role)
+}
+
+// Remove removes the member or cancels the invitation. Admins can remove
+// anybody but the owner, other users can leave the organization or decline
+// the invitation. The removed member loses access to all organization
+// vaults at once.
+func (srv OrganizationService) Remove(ctx context.Context, userID int, orgID int, memberID int) error {
+	org, err := srv.storage.FindOrganization(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if memberID == org.OwnerID {
+		return ErrRemoveOrganizationOwner
+	}
+	if userID != memberID {
+		err := srv.authorizer.AuthorizeOrganization(ctx, userID, orgID, models.OrganizationAdminRole)
+		if err != nil {
+			return err
+		}
+	}
+
+	return srv.storage.DeleteOrganizationMember(ctx, orgID, memberID)
+}
+
+// CreateVault creates a vault of the organization.
+func (srv OrganizationService) CreateVault(ctx context.Context, userID int, orgID int, name string) (models.Vault, error) {
+	vault := models.Vault{OrganizationID: orgID, Name: name}
+	if strings.TrimSpace(name) == "" || len(name) > maxVaultNameLen {
+		return vault, ErrInvalidVaultName
+	}
+	if err := srv.authorizer.AuthorizeOrganization(ctx, userID, orgID, models.OrganizationAdminRole); err != nil {
+		return vault, err
+	}
+
+	return srv.storage.CreateVault(ctx, vault)
+}
+
+// Vaults returns vaults of all organizations the user is a member of.
+func (srv OrganizationService) Vaults(ctx context.Context, userID int) ([]models.Vault, error) {
+	return srv.storage.ListUserVaults(ctx, userID)
+}
@@ -0,0 +1,404 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type organizationStorageMock struct{ mock.Mock }
+
+func (m *organizationStorageMock) FindUserByLogin(ctx context.Context, login string) (models.User, error) {
+	args := m.Called(ctx, login)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+func (m *organizationStorageMock) CreateOrganization(
+	ctx context.Context,
+	org models.Organization) (models.Organization, error) {
+
+	args := m.Called(ctx, org)
+	return args.Get(0).(models.Organization), args.Error(1)
+}
+
+func (m *organizationStorageMock) FindOrganization(ctx context.Context, id int) (models.Organization, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Organization), args.Error(1)
+}
+
+func (m *organizationStorageMock) ListUserOrganizations(ctx context.Context, userID int) ([]models.Organization, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Organization), args.Error(1)
+}
+
+func (m *organizationStorageMock) CreateOrganizationMember(
+	ctx context.Context,
+	member models.OrganizationMember) (models.OrganizationMember, error) {
+
+	args := m.Called(ctx, member)
+	return args.Get(0).(models.OrganizationMember), args.Error(1)
+}
+
+func (m *organizationStorageMock) AcceptOrganizationInvitation(ctx context.Context, orgID int, userID int) error {
+	args := m.Called(ctx, orgID, userID)
+	return args.Error(0)
+}
+
+func (m *organizationStorageMock) ListOrganizationMembers(
+	ctx context.Context,
+	orgID int) ([]models.OrganizationMember, error) {
+
+	args := m.Called(ctx, orgID)
+	return args.Get(0).([]models.OrganizationMember), args.Error(1)
+}
+
+func (m *organizationStorageMock) UpdateOrganizationMemberRole(
+	ctx context.Context,
+	orgID int,
+	userID int,
+	role models.OrganizationRole) error {
+
+	args := m.Called(ctx, orgID, userID, role)
+	return args.Error(0)
+}
+
+func (m *organizationStorageMock) DeleteOrganizationMember(ctx context.Context, orgID int, userID int) error {
+	args := m.Called(ctx, orgID, userID)
+	return args.Error(0)
+}
+
+func (m *organizationStorageMock) CreateVault(ctx context.Context, vault models.Vault) (models.Vault, error) {
+	args := m.Called(ctx, vault)
+	return args.Get(0).(models.Vault), args.Error(1)
+}
+
+func (m *organizationStorageMock) ListUserVaults(ctx context.Context, userID int) ([]models.Vault, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Vault), args.Error(1)
+}
+
+// rolesACL gives users the roles from the map in every organization.
+func rolesACL(roles map[int]models.OrganizationRole) services.SecretACL {
+	finder := new(secretAccessFinderMock)
+	for userID, role := range roles {
+		finder.On("FindOrganizationRole", mock.Anything, mock.Anything, userID).Return(role, nil)
+	}
+	finder.On("FindOrganizationRole", mock.Anything, mock.Anything, mock.Anything).
+		Return(models.OrganizationRole(0), nil)
+
+	return services.NewSecretACL(finder)
+}
+
+func TestCreateOrganization(t *testing.T) {
+	testCases := []struct {
+		name    string
+		orgName string
+		wantErr error
+	}{
+		{
+			name:    "creates organization owned by user",
+			orgName: "team",
+		},
+		{
+			name:    "returns error if name is blank",
+			orgName: " ",
+			wantErr: services.ErrInvalidOrganizationName,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(organizationStorageMock)
+			wantOrg := models.Organization{Name: tc.orgName, OwnerID: 1}
+			store.On("CreateOrganization", mock.Anything, wantOrg).Return(wantOrg, nil)
+			srv := services.NewOrganizationService(store, rolesACL(nil))
+
+			_, err := srv.Create(context.TODO(), 1, tc.orgName)
+			assert.Equal(t, tc.wantErr, err)
+			if tc.wantErr == nil {
+				store.AssertCalled(t, "CreateOrganization", mock.Anything, wantOrg)
+			} else {
+				store.AssertNotCalled(t, "CreateOrganization", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+// roles of organization members in tests, user 1 is the organization owner
+var testOrganizationRoles = map[int]models.OrganizationRole{
+	1: models.OrganizationAdminRole,
+	2: models.OrganizationEditorRole,
+	3: models.OrganizationViewerRole,
+	4: models.OrganizationAdminRole,
+}
+
+func TestInviteOrganizationMember(t *testing.T) {
+	testCases := []struct {
+		name        string
+		userID      int
+		role        models.OrganizationRole
+		wantInvited bool
+		wantErr     error
+	}{
+		{
+			name:        "owner invites user",
+			userID:      1,
+			role:        models.OrganizationViewerRole,
+			wantInvited: true,
+		},
+		{
+			name:        "admin invites user",
+			userID:      4,
+			role:        models.OrganizationAdminRole,
+			wantInvited: true,
+		},
+		{
+			name:    "returns error if user is not admin",
+			userID:  2,
+			role:    models.OrganizationViewerRole,
+			wantErr: services.ErrNoPermission{UserID: 2, OrganizationID: 1},
+		},
+		{
+			name:    "returns error if user is not member",
+			userID:  5,
+			role:    models.OrganizationViewerRole,
+			wantErr: services.ErrNoPermission{UserID: 5, OrganizationID: 1},
+		},
+		{
+			name:    "returns error if role is invalid",
+			userID:  1,
+			role:    models.OrganizationRole(4),
+			wantErr: services.ErrInvalidOrganizationRole,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(organizationStorageMock)
+			store.On("FindUserByLogin", mock.Anything, "bob").Return(models.User{ID: 6, Login: "bob"}, nil)
+			wantMember := models.OrganizationMember{OrganizationID: 1, UserID: 6, Login: "bob", Role: tc.role}
+			store.On("CreateOrganizationMember", mock.Anything, wantMember).Return(wantMember, nil)
+			srv := services.NewOrganizationService(store, rolesACL(testOrganizationRoles))
+
+			member, err := srv.Invite(context.TODO(), tc.userID, 1, "bob", tc.role)
+			assert.Equal(t, tc.wantErr, err)
+			if tc.wantInvited {
+				assert.Equal(t, wantMember, member)
+			} else {
+				store.AssertNotCalled(t, "CreateOrganizationMember", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestListOrganizationMembers(t *testing.T) {
+	members := []models.OrganizationMember{
+		{OrganizationID: 1, UserID: 1, Login: "alice", Role: models.OrganizationAdminRole, Accepted: true},
+		{OrganizationID: 1, UserID: 3, Login: "carol", Role: models.OrganizationViewerRole, Accepted: true},
+	}
+	testCases := []struct {
+		name    string
+		userID  int
+		wantErr error
+	}{
+		{
+			name:   "lists members to viewer",
+			userID: 3,
+		},
+		{
+			name:    "returns error if user is not member",
+			userID:  5,
+			wantErr: services.ErrNoPermission{UserID: 5, OrganizationID: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(organizationStorageMock)
+			store.On("ListOrganizationMembers", mock.Anything, 1).Return(members, nil)
+			srv := services.NewOrganizationService(store, rolesACL(testOrganizationRoles))
+
+			result, err := srv.Members(context.TODO(), tc.userID, 1)
+			assert.Equal(t, tc.wantErr, err)
+			if tc.wantErr == nil {
+				assert.Equal(t, members, result)
+			}
+		})
+	}
+}
+
+func TestSetOrganizationMemberRole(t *testing.T) {
+	org := models.Organization{ID: 1, Name: "team", OwnerID: 1}
+	testCases := []struct {
+		name        string
+		userID      int
+		memberID    int
+		role        models.OrganizationRole
+		wantUpdated bool
+		wantErr     error
+	}{
+		{
+			name:        "admin changes role of member",
+			userID:      4,
+			memberID:    2,
+			role:        models.OrganizationViewerRole,
+			wantUpdated: true,
+		},
+		{
+			name:     "returns error if user is not admin",
+			userID:   2,
+			memberID: 3,
+			role:     models.OrganizationEditorRole,
+			wantErr:  services.ErrNoPermission{UserID: 2, OrganizationID: 1},
+		},
+		{
+			name:     "returns error if role of owner is changed",
+			userID:   4,
+			memberID: 1,
+			role:     models.OrganizationViewerRole,
+			wantErr:  services.ErrChangeOrganizationOwnerRole,
+		},
+		{
+			name:     "returns error if role is invalid",
+			userID:   1,
+			memberID: 2,
+			wantErr:  services.ErrInvalidOrganizationRole,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(organizationStorageMock)
+			store.On("FindOrganization", mock.Anything, org.ID).Return(org, nil)
+			store.On("UpdateOrganizationMemberRole", mock.Anything, org.ID, tc.memberID, tc.role).Return(nil)
+			srv := services.NewOrganizationService(store, rolesACL(testOrganizationRoles))
+
+			err := srv.SetRole(context.TODO(), tc.userID, org.ID, tc.memberID, tc.role)
+			assert.Equal(t, tc.wantErr, err)
+			if tc.wantUpdated {
+				store.AssertCalled(t, "UpdateOrganizationMemberRole", mock.Anything, org.ID, tc.memberID, tc.role)
+			} else {
+				store.AssertNotCalled(
+					t,
+					"UpdateOrganizationMemberRole",
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+				)
+			}
+		})
+	}
+}
+
+func TestRemoveOrganizationMember(t *testing.T) {
+	org := models.Organization{ID: 1, Name: "team", OwnerID: 1}
+	testCases := []struct {
+		name        string
+		userID      int
+		memberID    int
+		deleteErr   error
+		wantDeleted bool
+		wantErr     error
+	}{
+		{
+			name:        "admin removes member",
+			userID:      4,
+			memberID:    2,
+			wantDeleted: true,
+		},
+		{
+			name:        "member leaves organization",
+			userID:      3,
+			memberID:    3,
+			wantDeleted: true,
+		},
+		{
+			name:     "returns error if editor removes another member",
+			userID:   2,
+			memberID: 3,
+			wantErr:  services.ErrNoPermission{UserID: 2, OrganizationID: 1},
+		},
+		{
+			name:     "returns error if owner is removed",
+			userID:   4,
+			memberID: 1,
+			wantErr:  services.ErrRemoveOrganizationOwner,
+		},
+		{
+			name:     "returns error if user is not member",
+			userID:   1,
+			memberID: 5,
+			deleteErr: storage.ErrOrganizationMemberNotFound{
+				Member: models.OrganizationMember{OrganizationID: 1, UserID: 5},
+			},
+			wantDeleted: true,
+			wantErr: storage.ErrOrganizationMemberNotFound{
+				Member: models.OrganizationMember{OrganizationID: 1, UserID: 5},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(organizationStorageMock)
+			store.On("FindOrganization", mock.Anything, org.ID).Return(org, nil)
+			store.On("DeleteOrganizationMember", mock.Anything, org.ID, tc.memberID).Return(tc.deleteErr)
+			srv := services.NewOrganizationService(store, rolesACL(testOrganizationRoles))
+
+			err := srv.Remove(context.TODO(), tc.userID, org.ID, tc.memberID)
+			assert.Equal(t, tc.wantErr, err)
+			if tc.wantDeleted {
+				store.AssertCalled(t, "DeleteOrganizationMember", mock.Anything, org.ID, tc.memberID)
+			} else {
+				store.AssertNotCalled(t, "DeleteOrganizationMember", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestCreateVault(t *testing.T) {
+	testCases := []struct {
+		name      string
+		userID    int
+		vaultName string
+		wantErr   error
+	}{
+		{
+			name:      "admin creates vault",
+			userID:    4,
+			vaultName: "servers",
+		},
+		{
+			name:      "returns error if user is not admin",
+			userID:    2,
+			vaultName: "servers",
+			wantErr:   services.ErrNoPermission{UserID: 2, OrganizationID: 1},
+		},
+		{
+			name:      "returns error if name is blank",
+			userID:    1,
+			vaultName: "",
+			wantErr:   services.ErrInvalidVaultName,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(organizationStorageMock)
+			wantVault := models.Vault{OrganizationID: 1, Name: tc.vaultName}
+			store.On("CreateVault", mock.Anything, wantVault).Return(wantVault, nil)
+			srv := services.NewOrganizationService(store, rolesACL(testOrganizationRoles))
+
+			_, err := srv.CreateVault(context.TODO(), tc.userID, 1, tc.vaultName)
+			assert.Equal(t, tc.wantErr, err)
+			if tc.wantErr == nil {
+				store.AssertCalled(t, "CreateVault", mock.Anything, wantVault)
+			} else {
+				store.AssertNotCalled(t, "CreateVault", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
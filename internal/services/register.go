@@ -14,29 +14,37 @@ type UserCreator interface {
 
 type RegisterService struct {
 	usrCreator UserCreator
+	sessions   SessionStarter
 }
 
-func NewRegisterService(usrCreator UserCreator) RegisterService {
+func NewRegisterService(usrCreator UserCreator, sessions SessionStarter) RegisterService {
 	return RegisterService{
 		usrCreator: usrCreator,
+		sessions:   sessions,
 	}
 }
 
-func (srv RegisterService) Register(ctx context.Context, login string, password string) (string, error) {
+// Register creates the user and starts a session of the user. Logins of
+// service accounts are reserved, so nobody can receive shares meant for
+// a service account.
+func (srv RegisterService) Register(ctx context.Context, login string, password string) (SessionTokens, error) {
+	if models.IsServiceAccountLogin(login) {
+		return SessionTokens{}, ErrReservedLogin
+	}
 	encryptedPassword, err := auth.HashPassword(password)
 	if err != nil {
-		return "", fmt.Errorf("failed to register user: %w", err)
+		return SessionTokens{}, fmt.Errorf("failed to register user: %w", err)
 	}
 
 	user, err := srv.usrCreator.CreateUser(ctx, login, encryptedPassword)
 	if err != nil {
-		return "", fmt.Errorf("failed to register user: %w", err)
+		return SessionTokens{}, fmt.Errorf("failed to register user: %w", err)
 	}
 
-	jwtStr, err := auth.BuildJWTString(user.ID)
+	tokens, err := srv.sessions.Start(ctx, user.ID)
 	if err != nil {
-		return "", fmt.Errorf("failed to register user: %w", err)
+		return SessionTokens{}, fmt.Errorf("failed to register user: %w", err)
 	}
 
-	return jwtStr, nil
+	return tokens, nil
 }
@@ -32,7 +32,7 @@ func TestRegister(t *testing.T) {
 	}
 
 	usrCreator := new(userCreator)
-	registerSrv := services.NewRegisterService(usrCreator)
+	registerSrv := services.NewRegisterService(usrCreator, sessionStarterStub{})
 	testCases := []struct {
 		name      string
 		login     string
@@ -62,6 +62,14 @@ func TestRegister(t *testing.T) {
 				err: fmt.Errorf("failed to register user: %w", errors.New("error")),
 			},
 		},
+		{
+			name:     "returns error if login is reserved for service accounts",
+			login:    "deploy@1.service-account",
+			password: "password",
+			want: want{
+				err: services.ErrReservedLogin,
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -71,22 +79,22 @@ func TestRegister(t *testing.T) {
 				Return(tc.createRes.user, tc.createRes.err)
 			defer createCall.Unset()
 
-			jwtStr, err := registerSrv.Register(ctx, tc.login, tc.password)
+			tokens, err := registerSrv.Register(ctx, tc.login, tc.password)
 			if err == nil {
 				assert.Equal(
 					t,
 					userIDFromJWT(t, tc.want.jwtStr),
-					userIDFromJWT(t, jwtStr),
+					userIDFromJWT(t, tokens.AccessToken),
 				)
 			} else {
-				assert.EqualError(t, err, "failed to register user: error")
+				assert.EqualError(t, err, tc.want.err.Error())
 			}
 		})
 	}
 }
 
 func buildJWTString(t *testing.T, userID int) string {
-	jwtStr, error := auth.BuildJWTString(userID)
+	jwtStr, error := auth.BuildJWTString(userID, 1)
 	require.NoError(t, error)
 
 	return jwtStr
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+type RevisionStorage interface {
+	ListSecretRevisions(ctx context.Context, secretID int) ([]models.SecretRevision, error)
+	FindSecretRevision(ctx context.Context, secretID int, version int) (models.SecretRevision, error)
+	RestoreSecretRevision(ctx context.Context, secretID int, version int, event models.AuditEvent) error
+}
+
+// RevisionService gives access to previous versions of secrets, which
+// storage saves on every secret update.
+type RevisionService struct {
+	storage    RevisionStorage
+	decryptor  Decryptor
+	authorizer SecretAuthorizer
+	auditor    AuditRecorder
+}
+
+func NewRevisionService(
+	storage RevisionStorage,
+	decryptor Decryptor,
+	authorizer SecretAuthorizer,
+	auditor AuditRecorder) RevisionService {
+
+	return RevisionService{
+		storage:    storage,
+		decryptor:  decryptor,
+		authorizer: authorizer,
+		auditor:    auditor,
+	}
+}
+
+// List returns secret revisions without encrypted data, the latest first.
+func (srv RevisionService) List(ctx context.Context, userID int, secret models.Secret) ([]models.SecretRevision, error) {
+	if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretReadAccess); err != nil {
+		return nil, err
+	}
+
+	return srv.storage.ListSecretRevisions(ctx, secret.ID)
+}
+
+// Show decrypts the secret as it was at the revision. The revision is
+// returned as well, since bin data content is read from its chunks.
+func (srv RevisionService) Show(
+	ctx context.Context,
+	userID int,
+	secret models.Secret,
+	version int) (Unmarshaller, models.SecretRevision, error) {
+
+	if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretReadAccess); err != nil {
+		return nil, models.SecretRevision{}, err
+	}
+	revision, err := srv.storage.FindSecretRevision(ctx, secret.ID, version)
+	if err != nil {
+		return nil, revision, err
+	}
+	if err := srv.auditor.Record(ctx, userID, models.AuditRead, secret); err != nil {
+		return nil, revision, err
+	}
+	decryptedSecret, err := decryptSecret(srv.decryptor, revision.Secret(secret))
+	if err != nil {
+		return nil, revision, err
+	}
+
+	return decryptedSecret, revision, nil
+}
+
+// Restore makes the revision the current secret version, the replaced
+// version becomes the latest revision.
+func (srv RevisionService) Restore(ctx context.Context, userID int, secret models.Secret, version int) error {
+	if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretWriteAccess); err != nil {
+		return err
+	}
+
+	return srv.storage.RestoreSecretRevision(ctx, secret.ID, version, newAuditEvent(ctx, userID, models.AuditUpdate))
+}
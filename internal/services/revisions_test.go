@@ -0,0 +1,148 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type revisionStorageMock struct{ mock.Mock }
+
+func (m *revisionStorageMock) ListSecretRevisions(ctx context.Context, secretID int) ([]models.SecretRevision, error) {
+	args := m.Called(ctx, secretID)
+	return args.Get(0).([]models.SecretRevision), args.Error(1)
+}
+
+func (m *revisionStorageMock) FindSecretRevision(ctx context.Context, secretID int, version int) (models.SecretRevision, error) {
+	args := m.Called(ctx, secretID, version)
+	return args.Get(0).(models.SecretRevision), args.Error(1)
+}
+
+func (m *revisionStorageMock) RestoreSecretRevision(
+	ctx context.Context,
+	secretID int,
+	version int,
+	event models.AuditEvent) error {
+
+	args := m.Called(ctx, secretID, version, event)
+	return args.Error(0)
+}
+
+func TestShowRevision(t *testing.T) {
+	creds := &models.Credentials{Login: "login", Password: "old password"}
+	credsBytes, err := creds.Marshall()
+	require.NoError(t, err)
+	secret := models.Secret{
+		ID:            1,
+		UserID:        1,
+		SecretType:    models.CredentialsSecret,
+		Description:   "description",
+		EncryptedData: []byte("current data"),
+		EncryptedKey:  []byte("current key"),
+	}
+	revision := models.SecretRevision{
+		ID:            5,
+		SecretID:      1,
+		Version:       2,
+		Description:   "old description",
+		EncryptedData: []byte("old data"),
+		EncryptedKey:  []byte("old key"),
+	}
+	testCases := []struct {
+		name    string
+		userID  int
+		version int
+		findErr error
+		want    services.Unmarshaller
+		errMsg  string
+	}{
+		{
+			name:    "decrypts secret revision",
+			userID:  1,
+			version: 2,
+			want: &models.Credentials{
+				ID:          1,
+				Description: "old description",
+				Login:       "login",
+				Password:    "old password",
+			},
+		},
+		{
+			name:    "returns error if revision is not found",
+			userID:  1,
+			version: 3,
+			findErr: storage.ErrRevisionNotFound{Revision: models.SecretRevision{SecretID: 1, Version: 3}},
+			errMsg:  "revision 3 of secret with id=1 not found",
+		},
+		{
+			name:    "returns permission error if user is not secret owner",
+			userID:  2,
+			version: 2,
+			errMsg:  "user with id=2 doesn't have permission to secret with id=1",
+		},
+	}
+
+	revisionStorage := new(revisionStorageMock)
+	decryptor := new(decryptorMock)
+	decryptor.On("Decrypt", revision.EncryptedData, revision.EncryptedKey).Return(credsBytes, nil)
+	srv := services.NewRevisionService(revisionStorage, decryptor, ownerOnlyACL(), noAuditLog{})
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			findCall := revisionStorage.On("FindSecretRevision", mock.Anything, secret.ID, tc.version).
+				Return(revision, tc.findErr)
+			defer findCall.Unset()
+
+			decryptedSecret, foundRevision, err := srv.Show(context.TODO(), tc.userID, secret, tc.version)
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, decryptedSecret)
+				assert.Equal(t, revision, foundRevision)
+			} else {
+				assert.EqualError(t, err, tc.errMsg)
+			}
+		})
+	}
+}
+
+func TestRestoreRevision(t *testing.T) {
+	secret := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret}
+	testCases := []struct {
+		name   string
+		userID int
+		errMsg string
+	}{
+		{
+			name:   "restores secret revision",
+			userID: 1,
+		},
+		{
+			name:   "returns permission error if user is not secret owner",
+			userID: 2,
+			errMsg: "user with id=2 doesn't have permission to secret with id=1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			event := models.AuditEvent{UserID: tc.userID, Action: models.AuditUpdate}
+			revisionStorage := new(revisionStorageMock)
+			revisionStorage.On("RestoreSecretRevision", mock.Anything, secret.ID, 2, event).Return(nil)
+			srv := services.NewRevisionService(revisionStorage, new(decryptorMock), ownerOnlyACL(), noAuditLog{})
+
+			err := srv.Restore(context.TODO(), tc.userID, secret, 2)
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+				revisionStorage.AssertCalled(t, "RestoreSecretRevision", mock.Anything, secret.ID, 2, event)
+			} else {
+				assert.EqualError(t, err, tc.errMsg)
+				revisionStorage.AssertNotCalled(t, "RestoreSecretRevision", mock.Anything, secret.ID, 2, event)
+			}
+		})
+	}
+}
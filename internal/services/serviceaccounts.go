@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/configs"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+const (
+	// ClientSecretPrefix tells client secrets of service accounts from
+	// other tokens and makes leaked secrets easy to find.
+	ClientSecretPrefix = "gks_"
+	clientSecretSize   = 32
+	maxAssertionIDLen  = 255
+)
+
+var serviceAccountNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+type ServiceAccountStorage interface {
+	CreateServiceAccount(
+		ctx context.Context,
+		account models.ServiceAccount,
+		role models.OrganizationRole,
+	) (models.ServiceAccount, error)
+	FindServiceAccount(ctx context.Context, id int) (models.ServiceAccount, error)
+	FindServiceAccountByClientID(ctx context.Context, clientID string) (models.ServiceAccount, error)
+	ListOrganizationServiceAccounts(ctx context.Context, orgID int) ([]models.ServiceAccount, error)
+	DisableServiceAccount(ctx context.Context, id int) error
+	CreateServiceAccountCredential(
+		ctx context.Context,
+		credential models.ServiceAccountCredential,
+		secretHash []byte,
+	) (models.ServiceAccountCredential, error)
+	FindServiceAccountCredential(ctx context.Context, accountID int, id int) (models.ServiceAccountCredential, error)
+	FindServiceAccountSecret(
+		ctx context.Context,
+		accountID int,
+		secretHash []byte,
+	) (models.ServiceAccountCredential, error)
+	ListServiceAccountCredentials(ctx context.Context, accountID int) ([]models.ServiceAccountCredential, error)
+	DeleteServiceAccountCredential(ctx context.Context, accountID int, id int) error
+	TouchServiceAccountCredential(ctx context.Context, id int) error
+	CreateServiceAccountAssertion(ctx context.Context, accountID int, jti string, expiresAt time.Time) error
+}
+
+// AuditLister lists audit events visible to the user.
+type AuditLister interface {
+	List(ctx context.Context, userID int, filter models.AuditFilter) ([]models.AuditEvent, error)
+}
+
+// ServiceAccountToken is the access token issued to the service account
+// with the client id.
+type ServiceAccountToken struct {
+	ClientID    string
+	AccessToken string
+	ExpiresIn   time.Duration
+}
+
+// ServiceAccountService manages service accounts of organizations and
+// issues their access tokens. Organization admins create service
+// accounts, give them roles in the organization like to members and
+// manage their credentials. Service accounts exchange a client secret or
+// a JWT assertion signed with their private key for a short-lived access
+// token.
+type ServiceAccountService struct {
+	storage    ServiceAccountStorage
+	authorizer OrganizationAuthorizer
+	audit      AuditLister
+	randGen    RandGen
+}
+
+func NewServiceAccountService(
+	storage ServiceAccountStorage,
+	authorizer OrganizationAuthorizer,
+	audit AuditLister,
+	randGen RandGen) ServiceAccountService {
+
+	return ServiceAccountService{
+		storage:    storage,
+		authorizer: authorizer,
+		audit:      audit,
+		randGen:    randGen,
+	}
+}
+
+// Create creates the service account of the organization with the role in
+// it, the role gives access to the organization vaults.
+func (srv ServiceAccountService) Create(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	name string,
+	role models.OrganizationRole) (models.ServiceAccount, error) {
+
+	if !serviceAccountNameRe.MatchString(name) {
+		return models.ServiceAccount{}, ErrInvalidServiceAccountName
+	}
+	if role.String() == "" {
+		return models.ServiceAccount{}, ErrInvalidOrganizationRole
+	}
+	if err := srv.authorizer.AuthorizeOrganization(ctx, userID, orgID, models.OrganizationAdminRole); err != nil {
+		return models.ServiceAccount{}, err
+	}
+
+	return srv.storage.CreateServiceAccount(
+		ctx,
+		models.ServiceAccount{
+			OrganizationID: orgID,
+			Name:           name,
+			ClientID:       models.ServiceAccountClientID(orgID, name),
+			CreatedBy:      userID,
+		},
+		role,
+	)
+}
+
+// List returns service accounts of the organization, only admins can see
+// them.
+func (srv ServiceAccountService) List(ctx context.Context, userID int, orgID int) ([]models.ServiceAccount, error) {
+	if err := srv.authorizer.AuthorizeOrganization(ctx, userID, orgID, models.OrganizationAdminRole); err != nil {
+		return nil, err
+	}
+
+	return srv.storage.ListOrganizationServiceAccounts(ctx, orgID)
+}
+
+// Disable disables the service account, its access tokens are rejected at
+// once and it can not get new ones. Its secrets and audit events are kept.
+func (srv ServiceAccountService) Disable(ctx context.Context, userID int, orgID int, id int) error {
+	account, err := srv.find(ctx, userID, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	return srv.storage.DisableServiceAccount(ctx, account.ID)
+}
+
+// CreateSecret creates a client secret of the service account and returns
+// it with its value. Only the hash of the value is stored, so it can not
+// be shown again.
+func (srv ServiceAccountService) CreateSecret(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	id int) (models.ServiceAccountCredential, string, error) {
+
+	account, err := srv.findEnabled(ctx, userID, orgID, id)
+	if err != nil {
+		return models.ServiceAccountCredential{}, "", err
+	}
+	bs, err := srv.randGen.Gen(clientSecretSize)
+	if err != nil {
+		return models.ServiceAccountCredential{}, "", fmt.Errorf("failed to generate client secret: %w", err)
+	}
+	value := ClientSecretPrefix + base64.RawURLEncoding.EncodeToString(bs)
+	credential, err := srv.storage.CreateServiceAccountCredential(
+		ctx,
+		models.ServiceAccountCredential{ServiceAccountID: account.ID, Type: models.CredentialSecret},
+		hashClientSecret(value),
+	)
+	if err != nil {
+		return models.ServiceAccountCredential{}, "", err
+	}
+
+	return credential, value, nil
+}
+
+// AddPublicKey adds the PEM encoded Ed25519 or P-256 public key, which
+// verifies assertions of the service account signed with its private key.
+func (srv ServiceAccountService) AddPublicKey(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	id int,
+	publicKey string) (models.ServiceAccountCredential, error) {
+
+	if _, err := auth.ParsePublicKey("", []byte(publicKey)); err != nil {
+		return models.ServiceAccountCredential{}, ErrInvalidPublicKey
+	}
+	account, err := srv.findEnabled(ctx, userID, orgID, id)
+	if err != nil {
+		return models.ServiceAccountCredential{}, err
+	}
+
+	return srv.storage.CreateServiceAccountCredential(
+		ctx,
+		models.ServiceAccountCredential{
+			ServiceAccountID: account.ID,
+			Type:             models.CredentialPublicKey,
+			PublicKey:        publicKey,
+		},
+		nil,
+	)
+}
+
+// Credentials returns client secrets without their values and public keys
+// of the service account.
+func (srv ServiceAccountService) Credentials(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	id int) ([]models.ServiceAccountCredential, error) {
+
+	account, err := srv.find(ctx, userID, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return srv.storage.ListServiceAccountCredentials(ctx, account.ID)
+}
+
+// DeleteCredential deletes the client secret or the public key, access
+// tokens issued for it are valid until they expire.
+func (srv ServiceAccountService) DeleteCredential(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	id int,
+	credentialID int) error {
+
+	account, err := srv.find(ctx, userID, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	return srv.storage.DeleteServiceAccountCredential(ctx, account.ID, credentialID)
+}
+
+// Audit returns audit events of the service account, the latest first:
+// its actions with secrets and actions of others with its own secrets.
+func (srv ServiceAccountService) Audit(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	id int,
+	filter models.AuditFilter) ([]models.AuditEvent, error) {
+
+	account, err := srv.find(ctx, userID, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return srv.audit.List(ctx, account.UserID, filter)
+}
+
+// IssueToken exchanges the client secret of the service account for an
+// access token. ErrInvalidClientCredentials is returned for disabled
+// service accounts, unknown clients and secrets are not found.
+func (srv ServiceAccountService) IssueToken(
+	ctx context.Context,
+	clientID string,
+	clientSecret string) (ServiceAccountToken, error) {
+
+	account, err := srv.findClient(ctx, clientID)
+	if err != nil {
+		return ServiceAccountToken{}, err
+	}
+	credential, err := srv.storage.FindServiceAccountSecret(ctx, account.ID, hashClientSecret(clientSecret))
+	if err != nil {
+		return ServiceAccountToken{}, fmt.Errorf("failed to issue service account token: %w", err)
+	}
+
+	return srv.issue(ctx, account, credential)
+}
+
+// IssueTokenForAssertion exchanges the JWT assertion signed with a private
+// key of the service account for an access token. The assertion is issued
+// by the service account for itself with the client id as the issuer and
+// the subject, its "kid" header is the id of the public key credential.
+// Every assertion is accepted once, a replayed assertion is not unique.
+func (srv ServiceAccountService) IssueTokenForAssertion(
+	ctx context.Context,
+	assertion string) (ServiceAccountToken, error) {
+
+	var account models.ServiceAccount
+	var credential models.ServiceAccountCredential
+	var lookupErr error
+	claims, err := auth.ParseAssertion(assertion, func(issuer string, kid string) (auth.Key, error) {
+		if account, lookupErr = srv.findClient(ctx, issuer); lookupErr != nil {
+			return auth.Key{}, lookupErr
+		}
+		credentialID, err := strconv.Atoi(kid)
+		if err != nil {
+			lookupErr = ErrInvalidClientCredentials
+			return auth.Key{}, lookupErr
+		}
+		credential, lookupErr = srv.storage.FindServiceAccountCredential(ctx, account.ID, credentialID)
+		if lookupErr != nil {
+			lookupErr = fmt.Errorf("failed to issue service account token: %w", lookupErr)
+			return auth.Key{}, lookupErr
+		}
+		if credential.Type != models.CredentialPublicKey {
+			lookupErr = ErrInvalidClientCredentials
+			return auth.Key{}, lookupErr
+		}
+		return auth.ParsePublicKey(kid, []byte(credential.PublicKey))
+	})
+	if err != nil {
+		if lookupErr != nil {
+			return ServiceAccountToken{}, lookupErr
+		}
+		return ServiceAccountToken{}, ErrInvalidClientCredentials
+	}
+	if len(claims.ID) > maxAssertionIDLen {
+		return ServiceAccountToken{}, ErrInvalidClientCredentials
+	}
+	err = srv.storage.CreateServiceAccountAssertion(ctx, account.ID, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return ServiceAccountToken{}, fmt.Errorf("failed to issue service account token: %w", err)
+	}
+
+	return srv.issue(ctx, account, credential)
+}
+
+func (srv ServiceAccountService) issue(
+	ctx context.Context,
+	account models.ServiceAccount,
+	credential models.ServiceAccountCredential) (ServiceAccountToken, error) {
+
+	if err := srv.storage.TouchServiceAccountCredential(ctx, credential.ID); err != nil {
+		return ServiceAccountToken{}, fmt.Errorf("failed to issue service account token: %w", err)
+	}
+	accessToken, err := auth.BuildServiceAccountToken(account.UserID, account.ID)
+	if err != nil {
+		return ServiceAccountToken{}, fmt.Errorf("failed to issue service account token: %w", err)
+	}
+
+	return ServiceAccountToken{
+		ClientID:    account.ClientID,
+		AccessToken: accessToken,
+		ExpiresIn:   configs.ServiceAccountTokenExp,
+	}, nil
+}
+
+// findClient returns the enabled service account with the client id.
+func (srv ServiceAccountService) findClient(ctx context.Context, clientID string) (models.ServiceAccount, error) {
+	account, err := srv.storage.FindServiceAccountByClientID(ctx, clientID)
+	if err != nil {
+		return account, fmt.Errorf("failed to issue service account token: %w", err)
+	}
+	if account.Disabled {
+		return account, ErrInvalidClientCredentials
+	}
+
+	return account, nil
+}
+
+// find returns the service account of the organization if the user is its
+// admin.
+func (srv ServiceAccountService) find(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	id int) (models.ServiceAccount, error) {
+
+	if err := srv.authorizer.AuthorizeOrganization(ctx, userID, orgID, models.OrganizationAdminRole); err != nil {
+		return models.ServiceAccount{}, err
+	}
+	account, err := srv.storage.FindServiceAccount(ctx, id)
+	if err != nil {
+		return account, err
+	}
+	if account.OrganizationID != orgID {
+		return models.ServiceAccount{}, ErrNoPermission{UserID: userID, OrganizationID: account.OrganizationID}
+	}
+
+	return account, nil
+}
+
+func (srv ServiceAccountService) findEnabled(
+	ctx context.Context,
+	userID int,
+	orgID int,
+	id int) (models.ServiceAccount, error) {
+
+	account, err := srv.find(ctx, userID, orgID, id)
+	if err != nil {
+		return account, err
+	}
+	if account.Disabled {
+		return account, ErrServiceAccountDisabled
+	}
+
+	return account, nil
+}
+
+func hashClientSecret(value string) []byte {
+	hash := sha256.Sum256([]byte(value))
+	return hash[:]
+}
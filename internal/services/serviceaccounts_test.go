@@ -0,0 +1,494 @@
+package services_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type serviceAccountStorageMock struct{ mock.Mock }
+
+func (m *serviceAccountStorageMock) CreateServiceAccount(
+	ctx context.Context,
+	account models.ServiceAccount,
+	role models.OrganizationRole) (models.ServiceAccount, error) {
+
+	args := m.Called(ctx, account, role)
+	return args.Get(0).(models.ServiceAccount), args.Error(1)
+}
+
+func (m *serviceAccountStorageMock) FindServiceAccount(ctx context.Context, id int) (models.ServiceAccount, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.ServiceAccount), args.Error(1)
+}
+
+func (m *serviceAccountStorageMock) FindServiceAccountByClientID(
+	ctx context.Context,
+	clientID string) (models.ServiceAccount, error) {
+
+	args := m.Called(ctx, clientID)
+	return args.Get(0).(models.ServiceAccount), args.Error(1)
+}
+
+func (m *serviceAccountStorageMock) ListOrganizationServiceAccounts(
+	ctx context.Context,
+	orgID int) ([]models.ServiceAccount, error) {
+
+	args := m.Called(ctx, orgID)
+	return args.Get(0).([]models.ServiceAccount), args.Error(1)
+}
+
+func (m *serviceAccountStorageMock) DisableServiceAccount(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *serviceAccountStorageMock) CreateServiceAccountCredential(
+	ctx context.Context,
+	credential models.ServiceAccountCredential,
+	secretHash []byte) (models.ServiceAccountCredential, error) {
+
+	args := m.Called(ctx, credential, secretHash)
+	return args.Get(0).(models.ServiceAccountCredential), args.Error(1)
+}
+
+func (m *serviceAccountStorageMock) FindServiceAccountCredential(
+	ctx context.Context,
+	accountID int,
+	id int) (models.ServiceAccountCredential, error) {
+
+	args := m.Called(ctx, accountID, id)
+	return args.Get(0).(models.ServiceAccountCredential), args.Error(1)
+}
+
+func (m *serviceAccountStorageMock) FindServiceAccountSecret(
+	ctx context.Context,
+	accountID int,
+	secretHash []byte) (models.ServiceAccountCredential, error) {
+
+	args := m.Called(ctx, accountID, secretHash)
+	return args.Get(0).(models.ServiceAccountCredential), args.Error(1)
+}
+
+func (m *serviceAccountStorageMock) ListServiceAccountCredentials(
+	ctx context.Context,
+	accountID int) ([]models.ServiceAccountCredential, error) {
+
+	args := m.Called(ctx, accountID)
+	return args.Get(0).([]models.ServiceAccountCredential), args.Error(1)
+}
+
+func (m *serviceAccountStorageMock) DeleteServiceAccountCredential(ctx context.Context, accountID int, id int) error {
+	args := m.Called(ctx, accountID, id)
+	return args.Error(0)
+}
+
+func (m *serviceAccountStorageMock) TouchServiceAccountCredential(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *serviceAccountStorageMock) CreateServiceAccountAssertion(
+	ctx context.Context,
+	accountID int,
+	jti string,
+	expiresAt time.Time) error {
+
+	args := m.Called(ctx, accountID, jti, expiresAt)
+	return args.Error(0)
+}
+
+type auditListerMock struct{ mock.Mock }
+
+func (m *auditListerMock) List(
+	ctx context.Context,
+	userID int,
+	filter models.AuditFilter) ([]models.AuditEvent, error) {
+
+	args := m.Called(ctx, userID, filter)
+	return args.Get(0).([]models.AuditEvent), args.Error(1)
+}
+
+const testClientID = "deploy@1.service-account"
+
+func TestCreateServiceAccount(t *testing.T) {
+	testCases := []struct {
+		name        string
+		userID      int
+		accountName string
+		role        models.OrganizationRole
+		wantErr     error
+	}{
+		{
+			name:        "admin creates service account",
+			userID:      4,
+			accountName: "deploy",
+			role:        models.OrganizationViewerRole,
+		},
+		{
+			name:        "returns error if user is not admin",
+			userID:      2,
+			accountName: "deploy",
+			role:        models.OrganizationViewerRole,
+			wantErr:     services.ErrNoPermission{UserID: 2, OrganizationID: 1},
+		},
+		{
+			name:        "returns error if name is invalid",
+			userID:      1,
+			accountName: "Deploy Bot",
+			role:        models.OrganizationViewerRole,
+			wantErr:     services.ErrInvalidServiceAccountName,
+		},
+		{
+			name:        "returns error if role is invalid",
+			userID:      1,
+			accountName: "deploy",
+			role:        models.OrganizationRole(4),
+			wantErr:     services.ErrInvalidOrganizationRole,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(serviceAccountStorageMock)
+			wantAccount := models.ServiceAccount{
+				OrganizationID: 1,
+				Name:           tc.accountName,
+				ClientID:       testClientID,
+				CreatedBy:      tc.userID,
+			}
+			store.On("CreateServiceAccount", mock.Anything, wantAccount, tc.role).Return(wantAccount, nil)
+			srv := services.NewServiceAccountService(
+				store,
+				rolesACL(testOrganizationRoles),
+				new(auditListerMock),
+				new(randGenMock),
+			)
+
+			account, err := srv.Create(context.TODO(), tc.userID, 1, tc.accountName, tc.role)
+			assert.Equal(t, tc.wantErr, err)
+			if tc.wantErr == nil {
+				assert.Equal(t, wantAccount, account)
+			} else {
+				store.AssertNotCalled(t, "CreateServiceAccount", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestServiceAccountOfAnotherOrganization(t *testing.T) {
+	store := new(serviceAccountStorageMock)
+	store.On("FindServiceAccount", mock.Anything, 1).
+		Return(models.ServiceAccount{ID: 1, UserID: 10, OrganizationID: 2}, nil)
+	srv := services.NewServiceAccountService(
+		store,
+		rolesACL(testOrganizationRoles),
+		new(auditListerMock),
+		new(randGenMock),
+	)
+
+	err := srv.Disable(context.TODO(), 1, 1, 1)
+	assert.Equal(t, services.ErrNoPermission{UserID: 1, OrganizationID: 2}, err)
+	store.AssertNotCalled(t, "DisableServiceAccount", mock.Anything, mock.Anything)
+}
+
+func TestServiceAccountCreateSecret(t *testing.T) {
+	randGen := new(randGenMock)
+	randGen.On("Gen", 32).Return(make([]byte, 32), nil)
+	value := services.ClientSecretPrefix + base64.RawURLEncoding.EncodeToString(make([]byte, 32))
+	hash := sha256.Sum256([]byte(value))
+
+	testCases := []struct {
+		name     string
+		disabled bool
+		wantErr  error
+	}{
+		{
+			name: "creates client secret",
+		},
+		{
+			name:     "returns error if service account is disabled",
+			disabled: true,
+			wantErr:  services.ErrServiceAccountDisabled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(serviceAccountStorageMock)
+			store.On("FindServiceAccount", mock.Anything, 1).
+				Return(models.ServiceAccount{ID: 1, UserID: 10, OrganizationID: 1, Disabled: tc.disabled}, nil)
+			wantCredential := models.ServiceAccountCredential{ServiceAccountID: 1, Type: models.CredentialSecret}
+			store.On("CreateServiceAccountCredential", mock.Anything, wantCredential, hash[:]).
+				Return(wantCredential, nil)
+			srv := services.NewServiceAccountService(store, rolesACL(testOrganizationRoles), new(auditListerMock), randGen)
+
+			credential, secret, err := srv.CreateSecret(context.TODO(), 1, 1, 1)
+			assert.Equal(t, tc.wantErr, err)
+			if tc.wantErr == nil {
+				assert.Equal(t, wantCredential, credential)
+				assert.Equal(t, value, secret)
+			} else {
+				store.AssertNotCalled(t, "CreateServiceAccountCredential", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestServiceAccountAddPublicKey(t *testing.T) {
+	publicKey, _ := generateServiceAccountKey(t)
+	store := new(serviceAccountStorageMock)
+	store.On("FindServiceAccount", mock.Anything, 1).
+		Return(models.ServiceAccount{ID: 1, UserID: 10, OrganizationID: 1}, nil)
+	store.On("CreateServiceAccountCredential", mock.Anything, mock.Anything, []byte(nil)).
+		Return(models.ServiceAccountCredential{ID: 2}, nil)
+	srv := services.NewServiceAccountService(
+		store,
+		rolesACL(testOrganizationRoles),
+		new(auditListerMock),
+		new(randGenMock),
+	)
+
+	_, err := srv.AddPublicKey(context.TODO(), 1, 1, 1, publicKey)
+	require.NoError(t, err)
+	store.AssertCalled(
+		t,
+		"CreateServiceAccountCredential",
+		mock.Anything,
+		models.ServiceAccountCredential{ServiceAccountID: 1, Type: models.CredentialPublicKey, PublicKey: publicKey},
+		[]byte(nil),
+	)
+
+	_, err = srv.AddPublicKey(context.TODO(), 1, 1, 1, "not a key")
+	assert.Equal(t, services.ErrInvalidPublicKey, err)
+}
+
+func TestServiceAccountAudit(t *testing.T) {
+	store := new(serviceAccountStorageMock)
+	store.On("FindServiceAccount", mock.Anything, 1).
+		Return(models.ServiceAccount{ID: 1, UserID: 10, OrganizationID: 1}, nil)
+	audit := new(auditListerMock)
+	events := []models.AuditEvent{{ID: 1, UserID: 10, Action: models.AuditRead, SecretID: 3}}
+	filter := models.AuditFilter{Limit: 10}
+	audit.On("List", mock.Anything, 10, filter).Return(events, nil)
+	srv := services.NewServiceAccountService(store, rolesACL(testOrganizationRoles), audit, new(randGenMock))
+
+	result, err := srv.Audit(context.TODO(), 1, 1, 1, filter)
+	require.NoError(t, err)
+	assert.Equal(t, events, result)
+
+	_, err = srv.Audit(context.TODO(), 3, 1, 1, filter)
+	assert.Equal(t, services.ErrNoPermission{UserID: 3, OrganizationID: 1}, err)
+}
+
+func TestServiceAccountIssueToken(t *testing.T) {
+	secret := services.ClientSecretPrefix + "secret"
+	hash := sha256.Sum256([]byte(secret))
+	testCases := []struct {
+		name     string
+		account  models.ServiceAccount
+		findErr  error
+		wantErr  error
+		wantFind bool
+	}{
+		{
+			name:     "issues token for client secret",
+			account:  models.ServiceAccount{ID: 1, UserID: 10, ClientID: testClientID},
+			wantFind: true,
+		},
+		{
+			name:    "returns error if service account is disabled",
+			account: models.ServiceAccount{ID: 1, UserID: 10, ClientID: testClientID, Disabled: true},
+			wantErr: services.ErrInvalidClientCredentials,
+		},
+		{
+			name:    "returns error if service account is not found",
+			findErr: storage.ErrServiceAccountNotFound{},
+			wantErr: storage.ErrServiceAccountNotFound{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(serviceAccountStorageMock)
+			store.On("FindServiceAccountByClientID", mock.Anything, testClientID).Return(tc.account, tc.findErr)
+			store.On("FindServiceAccountSecret", mock.Anything, 1, hash[:]).
+				Return(models.ServiceAccountCredential{ID: 5, ServiceAccountID: 1, Type: models.CredentialSecret}, nil)
+			store.On("TouchServiceAccountCredential", mock.Anything, 5).Return(nil)
+			srv := services.NewServiceAccountService(store, rolesACL(nil), new(auditListerMock), new(randGenMock))
+
+			token, err := srv.IssueToken(context.TODO(), testClientID, secret)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				store.AssertNotCalled(t, "TouchServiceAccountCredential", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			claims, err := auth.ParseJWT(token.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, 10, claims.UserID)
+			assert.Equal(t, 1, claims.ServiceAccountID)
+			assert.Zero(t, claims.SessionID)
+			store.AssertCalled(t, "TouchServiceAccountCredential", mock.Anything, 5)
+		})
+	}
+}
+
+func TestServiceAccountIssueTokenForAssertion(t *testing.T) {
+	publicKey, privateKey := generateServiceAccountKey(t)
+	_, otherPrivateKey := generateServiceAccountKey(t)
+	account := models.ServiceAccount{ID: 1, UserID: 10, ClientID: testClientID}
+	credential := models.ServiceAccountCredential{
+		ID:               7,
+		ServiceAccountID: 1,
+		Type:             models.CredentialPublicKey,
+		PublicKey:        publicKey,
+	}
+	secretCredential := models.ServiceAccountCredential{ID: 8, ServiceAccountID: 1, Type: models.CredentialSecret}
+
+	testCases := []struct {
+		name         string
+		assertion    string
+		assertionErr error
+		wantErr      error
+	}{
+		{
+			name:      "issues token for assertion",
+			assertion: signAssertion(t, privateKey, "7", testClientID, "1", 5*time.Minute),
+		},
+		{
+			name:      "returns error if assertion is signed with another key",
+			assertion: signAssertion(t, otherPrivateKey, "7", testClientID, "1", 5*time.Minute),
+			wantErr:   services.ErrInvalidClientCredentials,
+		},
+		{
+			name:      "returns error if key is not a public key credential",
+			assertion: signAssertion(t, privateKey, "8", testClientID, "1", 5*time.Minute),
+			wantErr:   services.ErrInvalidClientCredentials,
+		},
+		{
+			name:      "returns error if assertion lives too long",
+			assertion: signAssertion(t, privateKey, "7", testClientID, "1", time.Hour),
+			wantErr:   services.ErrInvalidClientCredentials,
+		},
+		{
+			name:      "returns error if assertion has expired",
+			assertion: signAssertion(t, privateKey, "7", testClientID, "1", -time.Minute),
+			wantErr:   services.ErrInvalidClientCredentials,
+		},
+		{
+			name:      "returns error if assertion has no id",
+			assertion: signAssertion(t, privateKey, "7", testClientID, "", 5*time.Minute),
+			wantErr:   services.ErrInvalidClientCredentials,
+		},
+		{
+			name:      "returns error if client is unknown",
+			assertion: signAssertion(t, privateKey, "7", "unknown", "1", 5*time.Minute),
+			wantErr:   storage.ErrServiceAccountNotFound{},
+		},
+		{
+			name:         "returns error if assertion is replayed",
+			assertion:    signAssertion(t, privateKey, "7", testClientID, "1", 5*time.Minute),
+			assertionErr: storage.ErrServiceAccountAssertionNotUniq{JTI: "1"},
+			wantErr:      storage.ErrServiceAccountAssertionNotUniq{JTI: "1"},
+		},
+		{
+			name:      "returns error if assertion is signed with HMAC",
+			assertion: signHMACAssertion(t, []byte(publicKey), "7", testClientID),
+			wantErr:   services.ErrInvalidClientCredentials,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(serviceAccountStorageMock)
+			store.On("FindServiceAccountByClientID", mock.Anything, testClientID).Return(account, nil)
+			store.On("FindServiceAccountByClientID", mock.Anything, "unknown").
+				Return(models.ServiceAccount{}, storage.ErrServiceAccountNotFound{})
+			store.On("FindServiceAccountCredential", mock.Anything, 1, 7).Return(credential, nil)
+			store.On("FindServiceAccountCredential", mock.Anything, 1, 8).Return(secretCredential, nil)
+			store.On("CreateServiceAccountAssertion", mock.Anything, 1, "1", mock.Anything).Return(tc.assertionErr)
+			store.On("TouchServiceAccountCredential", mock.Anything, 7).Return(nil)
+			srv := services.NewServiceAccountService(store, rolesACL(nil), new(auditListerMock), new(randGenMock))
+
+			token, err := srv.IssueTokenForAssertion(context.TODO(), tc.assertion)
+			if tc.wantErr != nil {
+				assert.True(t, errors.Is(err, tc.wantErr), "unexpected error %v", err)
+				store.AssertNotCalled(t, "TouchServiceAccountCredential", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testClientID, token.ClientID)
+			claims, err := auth.ParseJWT(token.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, 10, claims.UserID)
+			assert.Equal(t, 1, claims.ServiceAccountID)
+		})
+	}
+}
+
+// generateServiceAccountKey returns the PEM encoded public key and the
+// private key of an Ed25519 key pair.
+func generateServiceAccountKey(t *testing.T) (string, ed25519.PrivateKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), privateKey
+}
+
+func signAssertion(
+	t *testing.T,
+	privateKey ed25519.PrivateKey,
+	kid string,
+	clientID string,
+	jti string,
+	exp time.Duration) string {
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, assertionClaims(clientID, jti, exp))
+	token.Header["kid"] = kid
+	tokenString, err := token.SignedString(privateKey)
+	require.NoError(t, err)
+
+	return tokenString
+}
+
+// signHMACAssertion signs the assertion with the public key as an HMAC
+// secret, such assertions must not be accepted.
+func signHMACAssertion(t *testing.T, secret []byte, kid string, clientID string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, assertionClaims(clientID, "1", 5*time.Minute))
+	token.Header["kid"] = kid
+	tokenString, err := token.SignedString(secret)
+	require.NoError(t, err)
+
+	return tokenString
+}
+
+func assertionClaims(clientID string, jti string, exp time.Duration) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Issuer:    clientID,
+		Subject:   clientID,
+		Audience:  jwt.ClaimStrings{auth.AssertionAudience},
+		ID:        jti,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(exp)),
+	}
+}
@@ -1,21 +1,71 @@
 package services_test
 
 import (
+	"context"
 	"testing"
 
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
-	"github.com/ilya-burinskiy/gophkeeper/internal/configs"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
 	"github.com/stretchr/testify/require"
 )
 
 func userIDFromJWT(t *testing.T, jwtStr string) int {
-	claims := &auth.Claims{}
-	token, err := jwt.ParseWithClaims(jwtStr, claims, func(tok *jwt.Token) (interface{}, error) {
-		return []byte(configs.SecretKey), nil
-	})
+	claims, err := auth.ParseJWT(jwtStr)
 	require.NoError(t, err)
-	require.True(t, token.Valid)
 
 	return claims.UserID
 }
+
+// sessionStarterStub starts sessions without storing them, the session id
+// is always 1.
+type sessionStarterStub struct{}
+
+func (sessionStarterStub) Start(ctx context.Context, userID int) (services.SessionTokens, error) {
+	accessToken, err := auth.BuildJWTString(userID, 1)
+	return services.SessionTokens{AccessToken: accessToken, RefreshToken: "refresh"}, err
+}
+
+// noSharesStorage is the storage of secrets shared with nobody and of no
+// vaults.
+type noSharesStorage struct{}
+
+func (noSharesStorage) FindSecretShareAccess(ctx context.Context, secretID int, userID int) (models.SecretAccess, error) {
+	return 0, nil
+}
+
+func (noSharesStorage) FindSecretShareAccesses(
+	ctx context.Context,
+	secretID int,
+	userIDs []int) (map[int]models.SecretAccess, error) {
+
+	return nil, nil
+}
+
+func (noSharesStorage) FindVaultAccess(ctx context.Context, vaultID int, userID int) (models.SecretAccess, error) {
+	return 0, nil
+}
+
+func (noSharesStorage) FindVaultAccesses(
+	ctx context.Context,
+	vaultID int,
+	userIDs []int) (map[int]models.SecretAccess, error) {
+
+	return nil, nil
+}
+
+func (noSharesStorage) FindOrganizationRole(ctx context.Context, orgID int, userID int) (models.OrganizationRole, error) {
+	return 0, nil
+}
+
+// ownerOnlyACL gives access to secrets to their owners only.
+func ownerOnlyACL() services.SecretACL {
+	return services.NewSecretACL(noSharesStorage{})
+}
+
+// noAuditLog records nothing.
+type noAuditLog struct{}
+
+func (noAuditLog) Record(ctx context.Context, userID int, action models.AuditAction, secrets ...models.Secret) error {
+	return nil
+}
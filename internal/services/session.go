@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/configs"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+const refreshTokenSize = 32
+
+// SessionStarter starts sessions of authenticated users.
+type SessionStarter interface {
+	Start(ctx context.Context, userID int) (SessionTokens, error)
+}
+
+type SessionStorage interface {
+	CreateSession(ctx context.Context, userID int, refreshTokenHash []byte, expiresAt time.Time) (int, error)
+	FindRefreshToken(ctx context.Context, refreshTokenHash []byte) (models.RefreshToken, error)
+	RotateRefreshToken(
+		ctx context.Context,
+		refreshTokenHash []byte,
+		newRefreshTokenHash []byte,
+		expiresAt time.Time) (bool, error)
+	RevokeSession(ctx context.Context, sessionID int) error
+	ListUserSessions(ctx context.Context, userID int) ([]models.Session, error)
+	RevokeUserSession(ctx context.Context, userID int, sessionID int) error
+	RevokeUserSessions(ctx context.Context, userID int, exceptSessionID int) (int64, error)
+}
+
+// SessionTokens are the short-lived access token (JWT) and the refresh
+// token, which is exchanged for new tokens once.
+type SessionTokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+type SessionService struct {
+	storage SessionStorage
+	randGen RandGen
+}
+
+func NewSessionService(storage SessionStorage, randGen RandGen) SessionService {
+	return SessionService{
+		storage: storage,
+		randGen: randGen,
+	}
+}
+
+// Start creates a session of the user and returns its tokens.
+func (srv SessionService) Start(ctx context.Context, userID int) (SessionTokens, error) {
+	refreshToken, err := srv.generateRefreshToken()
+	if err != nil {
+		return SessionTokens{}, err
+	}
+	sessionID, err := srv.storage.CreateSession(
+		ctx,
+		userID,
+		hashRefreshToken(refreshToken),
+		time.Now().Add(configs.RefreshTokenExp),
+	)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to start session: %w", err)
+	}
+	accessToken, err := auth.BuildJWTString(userID, sessionID)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to start session: %w", err)
+	}
+
+	return SessionTokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// Refresh exchanges the refresh token for new tokens of its session. A
+// refresh token which has already been used may have been stolen, so its
+// reuse revokes the session.
+func (srv SessionService) Refresh(ctx context.Context, refreshToken string) (SessionTokens, error) {
+	hash := hashRefreshToken(refreshToken)
+	token, err := srv.storage.FindRefreshToken(ctx, hash)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to refresh session: %w", err)
+	}
+	if token.Used {
+		if err := srv.storage.RevokeSession(ctx, token.SessionID); err != nil {
+			return SessionTokens{}, fmt.Errorf("failed to refresh session: %w", err)
+		}
+		return SessionTokens{}, ErrInvalidRefreshToken
+	}
+	if token.SessionRevoked || !token.ExpiresAt.After(time.Now()) {
+		return SessionTokens{}, ErrInvalidRefreshToken
+	}
+
+	newRefreshToken, err := srv.generateRefreshToken()
+	if err != nil {
+		return SessionTokens{}, err
+	}
+	rotated, err := srv.storage.RotateRefreshToken(
+		ctx,
+		hash,
+		hashRefreshToken(newRefreshToken),
+		time.Now().Add(configs.RefreshTokenExp),
+	)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to refresh session: %w", err)
+	}
+	if !rotated {
+		// the token has been used concurrently
+		if err := srv.storage.RevokeSession(ctx, token.SessionID); err != nil {
+			return SessionTokens{}, fmt.Errorf("failed to refresh session: %w", err)
+		}
+		return SessionTokens{}, ErrInvalidRefreshToken
+	}
+	accessToken, err := auth.BuildJWTString(token.UserID, token.SessionID)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to refresh session: %w", err)
+	}
+
+	return SessionTokens{AccessToken: accessToken, RefreshToken: newRefreshToken}, nil
+}
+
+// Revoke ends the session, its access and refresh tokens are rejected
+// from now on.
+func (srv SessionService) Revoke(ctx context.Context, sessionID int) error {
+	return srv.storage.RevokeSession(ctx, sessionID)
+}
+
+// List returns active sessions of the user.
+func (srv SessionService) List(ctx context.Context, userID int) ([]models.Session, error) {
+	return srv.storage.ListUserSessions(ctx, userID)
+}
+
+// RevokeUserSession ends the session of the user, sessions of other users
+// are not found.
+func (srv SessionService) RevokeUserSession(ctx context.Context, userID int, sessionID int) error {
+	return srv.storage.RevokeUserSession(ctx, userID, sessionID)
+}
+
+// RevokeOthers ends all sessions of the user except the current one, for
+// example after a device has been lost, and returns their number.
+func (srv SessionService) RevokeOthers(ctx context.Context, userID int, currentSessionID int) (int64, error) {
+	return srv.storage.RevokeUserSessions(ctx, userID, currentSessionID)
+}
+
+func (srv SessionService) generateRefreshToken() (string, error) {
+	bs, err := srv.randGen.Gen(refreshTokenSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bs), nil
+}
+
+func hashRefreshToken(refreshToken string) []byte {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hash[:]
+}
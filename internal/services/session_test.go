@@ -0,0 +1,189 @@
+package services_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type sessionStorageMock struct{ mock.Mock }
+
+func (m *sessionStorageMock) CreateSession(
+	ctx context.Context,
+	userID int,
+	refreshTokenHash []byte,
+	expiresAt time.Time) (int, error) {
+
+	args := m.Called(ctx, userID, refreshTokenHash, expiresAt)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *sessionStorageMock) FindRefreshToken(ctx context.Context, refreshTokenHash []byte) (models.RefreshToken, error) {
+	args := m.Called(ctx, refreshTokenHash)
+	return args.Get(0).(models.RefreshToken), args.Error(1)
+}
+
+func (m *sessionStorageMock) RotateRefreshToken(
+	ctx context.Context,
+	refreshTokenHash []byte,
+	newRefreshTokenHash []byte,
+	expiresAt time.Time) (bool, error) {
+
+	args := m.Called(ctx, refreshTokenHash, newRefreshTokenHash, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *sessionStorageMock) RevokeSession(ctx context.Context, sessionID int) error {
+	args := m.Called(ctx, sessionID)
+	return args.Error(0)
+}
+
+func (m *sessionStorageMock) ListUserSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *sessionStorageMock) RevokeUserSession(ctx context.Context, userID int, sessionID int) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *sessionStorageMock) RevokeUserSessions(ctx context.Context, userID int, exceptSessionID int) (int64, error) {
+	args := m.Called(ctx, userID, exceptSessionID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func sessionIDFromJWT(t *testing.T, jwtStr string) int {
+	claims, err := auth.ParseJWT(jwtStr)
+	require.NoError(t, err)
+
+	return claims.SessionID
+}
+
+func TestSessionStart(t *testing.T) {
+	randGen := new(randGenMock)
+	randGen.On("Gen", 32).Return(make([]byte, 32), nil)
+	refreshToken := base64.RawURLEncoding.EncodeToString(make([]byte, 32))
+	hash := sha256.Sum256([]byte(refreshToken))
+
+	t.Run("creates session", func(t *testing.T) {
+		store := new(sessionStorageMock)
+		store.On("CreateSession", mock.Anything, 1, hash[:], mock.Anything).Return(2, nil)
+		srv := services.NewSessionService(store, randGen)
+
+		tokens, err := srv.Start(context.TODO(), 1)
+		require.NoError(t, err)
+		assert.Equal(t, refreshToken, tokens.RefreshToken)
+		assert.Equal(t, 1, userIDFromJWT(t, tokens.AccessToken))
+		assert.Equal(t, 2, sessionIDFromJWT(t, tokens.AccessToken))
+	})
+
+	t.Run("returns error if failed to create session", func(t *testing.T) {
+		store := new(sessionStorageMock)
+		store.On("CreateSession", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(0, errors.New("error"))
+		srv := services.NewSessionService(store, randGen)
+
+		_, err := srv.Start(context.TODO(), 1)
+		assert.EqualError(t, err, "failed to start session: error")
+	})
+}
+
+func TestSessionRefresh(t *testing.T) {
+	randGen := new(randGenMock)
+	randGen.On("Gen", 32).Return(make([]byte, 32), nil)
+	hash := sha256.Sum256([]byte("refresh"))
+	valid := models.RefreshToken{SessionID: 2, UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}
+
+	t.Run("rotates refresh token", func(t *testing.T) {
+		store := new(sessionStorageMock)
+		store.On("FindRefreshToken", mock.Anything, hash[:]).Return(valid, nil)
+		store.On("RotateRefreshToken", mock.Anything, hash[:], mock.Anything, mock.Anything).Return(true, nil)
+		srv := services.NewSessionService(store, randGen)
+
+		tokens, err := srv.Refresh(context.TODO(), "refresh")
+		require.NoError(t, err)
+		assert.NotEqual(t, "refresh", tokens.RefreshToken)
+		assert.Equal(t, 1, userIDFromJWT(t, tokens.AccessToken))
+		assert.Equal(t, 2, sessionIDFromJWT(t, tokens.AccessToken))
+		store.AssertNotCalled(t, "RevokeSession", mock.Anything, mock.Anything)
+	})
+
+	t.Run("revokes session if used token is reused", func(t *testing.T) {
+		used := valid
+		used.Used = true
+		store := new(sessionStorageMock)
+		store.On("FindRefreshToken", mock.Anything, hash[:]).Return(used, nil)
+		store.On("RevokeSession", mock.Anything, 2).Return(nil)
+		srv := services.NewSessionService(store, randGen)
+
+		_, err := srv.Refresh(context.TODO(), "refresh")
+		assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+		store.AssertCalled(t, "RevokeSession", mock.Anything, 2)
+		store.AssertNotCalled(t, "RotateRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("revokes session if token is used concurrently", func(t *testing.T) {
+		store := new(sessionStorageMock)
+		store.On("FindRefreshToken", mock.Anything, hash[:]).Return(valid, nil)
+		store.On("RotateRefreshToken", mock.Anything, hash[:], mock.Anything, mock.Anything).Return(false, nil)
+		store.On("RevokeSession", mock.Anything, 2).Return(nil)
+		srv := services.NewSessionService(store, randGen)
+
+		_, err := srv.Refresh(context.TODO(), "refresh")
+		assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+		store.AssertCalled(t, "RevokeSession", mock.Anything, 2)
+	})
+
+	t.Run("rejects token of revoked session", func(t *testing.T) {
+		revoked := valid
+		revoked.SessionRevoked = true
+		store := new(sessionStorageMock)
+		store.On("FindRefreshToken", mock.Anything, hash[:]).Return(revoked, nil)
+		srv := services.NewSessionService(store, randGen)
+
+		_, err := srv.Refresh(context.TODO(), "refresh")
+		assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+		store.AssertNotCalled(t, "RotateRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects expired token", func(t *testing.T) {
+		expired := valid
+		expired.ExpiresAt = time.Now().Add(-time.Second)
+		store := new(sessionStorageMock)
+		store.On("FindRefreshToken", mock.Anything, hash[:]).Return(expired, nil)
+		srv := services.NewSessionService(store, randGen)
+
+		_, err := srv.Refresh(context.TODO(), "refresh")
+		assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+	})
+
+	t.Run("returns error if failed to find token", func(t *testing.T) {
+		store := new(sessionStorageMock)
+		store.On("FindRefreshToken", mock.Anything, hash[:]).Return(models.RefreshToken{}, errors.New("error"))
+		srv := services.NewSessionService(store, randGen)
+
+		_, err := srv.Refresh(context.TODO(), "refresh")
+		assert.EqualError(t, err, "failed to refresh session: error")
+	})
+}
+
+func TestSessionRevokeOthers(t *testing.T) {
+	store := new(sessionStorageMock)
+	store.On("RevokeUserSessions", mock.Anything, 1, 2).Return(int64(3), nil)
+	srv := services.NewSessionService(store, new(randGenMock))
+
+	revoked, err := srv.RevokeOthers(context.TODO(), 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), revoked)
+	store.AssertNotCalled(t, "RevokeSession", mock.Anything, mock.Anything)
+}
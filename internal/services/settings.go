@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+const maxRevisionsRetention = 100
+
+var ErrInvalidRetention = errors.New("revisions retention must be between 0 and 100")
+
+type UserSettingsUpdater interface {
+	UpdateUserSettings(ctx context.Context, userID int, settings models.UserSettings) error
+}
+
+type UserSettingsService struct {
+	updater UserSettingsUpdater
+}
+
+func NewUserSettingsService(updater UserSettingsUpdater) UserSettingsService {
+	return UserSettingsService{
+		updater: updater,
+	}
+}
+
+// Update saves user settings. Lowering the revisions retention drops
+// the oldest revisions of user secrets.
+func (srv UserSettingsService) Update(ctx context.Context, userID int, settings models.UserSettings) error {
+	if settings.RevisionsRetention < 0 || settings.RevisionsRetention > maxRevisionsRetention {
+		return ErrInvalidRetention
+	}
+
+	return srv.updater.UpdateUserSettings(ctx, userID, settings)
+}
@@ -0,0 +1,63 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type userSettingsUpdaterMock struct{ mock.Mock }
+
+func (m *userSettingsUpdaterMock) UpdateUserSettings(ctx context.Context, userID int, settings models.UserSettings) error {
+	args := m.Called(ctx, userID, settings)
+	return args.Error(0)
+}
+
+func TestUpdateUserSettings(t *testing.T) {
+	testCases := []struct {
+		name      string
+		retention int
+		wantErr   error
+	}{
+		{
+			name:      "updates revisions retention",
+			retention: 5,
+		},
+		{
+			name:      "disables secret history",
+			retention: 0,
+		},
+		{
+			name:      "returns error if retention is negative",
+			retention: -1,
+			wantErr:   services.ErrInvalidRetention,
+		},
+		{
+			name:      "returns error if retention is too large",
+			retention: 101,
+			wantErr:   services.ErrInvalidRetention,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			settings := models.UserSettings{RevisionsRetention: tc.retention}
+			updater := new(userSettingsUpdaterMock)
+			updater.On("UpdateUserSettings", mock.Anything, 1, settings).Return(nil)
+			srv := services.NewUserSettingsService(updater)
+
+			err := srv.Update(context.TODO(), 1, settings)
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+				updater.AssertCalled(t, "UpdateUserSettings", mock.Anything, 1, settings)
+			} else {
+				assert.ErrorIs(t, err, tc.wantErr)
+				updater.AssertNotCalled(t, "UpdateUserSettings", mock.Anything, 1, settings)
+			}
+		})
+	}
+}
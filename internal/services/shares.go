@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+type SecretShareStorage interface {
+	FindUserByLogin(ctx context.Context, login string) (models.User, error)
+	SaveSecretShare(ctx context.Context, share models.SecretShare) (models.SecretShare, error)
+	ListSecretShares(ctx context.Context, secretID int) ([]models.SecretShare, error)
+	DeleteSecretShare(ctx context.Context, secretID int, userID int) error
+}
+
+// ShareService lets secret owners share secrets with other users.
+type ShareService struct {
+	storage    SecretShareStorage
+	authorizer SecretAuthorizer
+}
+
+func NewShareService(storage SecretShareStorage, authorizer SecretAuthorizer) ShareService {
+	return ShareService{
+		storage:    storage,
+		authorizer: authorizer,
+	}
+}
+
+// Share gives the user with the login read or write access to the secret,
+// the access of the existing share is replaced. Vault secrets can not be
+// shared, they are available to the organization members.
+func (srv ShareService) Share(
+	ctx context.Context,
+	userID int,
+	secret models.Secret,
+	login string,
+	access models.SecretAccess) (models.SecretShare, error) {
+
+	if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretOwnerAccess); err != nil {
+		return models.SecretShare{}, err
+	}
+	if secret.VaultID != 0 {
+		return models.SecretShare{}, ErrShareVaultSecret
+	}
+	if access != models.SecretReadAccess && access != models.SecretWriteAccess {
+		return models.SecretShare{}, ErrInvalidShareAccess
+	}
+	user, err := srv.storage.FindUserByLogin(ctx, login)
+	if err != nil {
+		return models.SecretShare{}, err
+	}
+	if user.ID == secret.UserID {
+		return models.SecretShare{}, ErrShareWithOwner
+	}
+
+	return srv.storage.SaveSecretShare(ctx, models.SecretShare{
+		SecretID: secret.ID,
+		UserID:   user.ID,
+		Login:    user.Login,
+		Access:   access,
+	})
+}
+
+// List returns users the secret is shared with.
+func (srv ShareService) List(ctx context.Context, userID int, secret models.Secret) ([]models.SecretShare, error) {
+	if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretOwnerAccess); err != nil {
+		return nil, err
+	}
+
+	return srv.storage.ListSecretShares(ctx, secret.ID)
+}
+
+// Revoke takes the access to the secret away from the recipient. The owner
+// can revoke any share, and the recipient can give up its own one.
+func (srv ShareService) Revoke(ctx context.Context, userID int, secret models.Secret, recipientID int) error {
+	if userID != recipientID {
+		if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretOwnerAccess); err != nil {
+			return err
+		}
+	}
+
+	return srv.storage.DeleteSecretShare(ctx, secret.ID, recipientID)
+}
@@ -0,0 +1,155 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type shareStorageMock struct{ mock.Mock }
+
+func (m *shareStorageMock) FindUserByLogin(ctx context.Context, login string) (models.User, error) {
+	args := m.Called(ctx, login)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+func (m *shareStorageMock) SaveSecretShare(ctx context.Context, share models.SecretShare) (models.SecretShare, error) {
+	args := m.Called(ctx, share)
+	return args.Get(0).(models.SecretShare), args.Error(1)
+}
+
+func (m *shareStorageMock) ListSecretShares(ctx context.Context, secretID int) ([]models.SecretShare, error) {
+	args := m.Called(ctx, secretID)
+	return args.Get(0).([]models.SecretShare), args.Error(1)
+}
+
+func (m *shareStorageMock) DeleteSecretShare(ctx context.Context, secretID int, userID int) error {
+	args := m.Called(ctx, secretID, userID)
+	return args.Error(0)
+}
+
+func TestShare(t *testing.T) {
+	type findUserResult struct {
+		user models.User
+		err  error
+	}
+	secret := models.Secret{ID: 1, UserID: 1}
+	testCases := []struct {
+		name        string
+		userID      int
+		login       string
+		access      models.SecretAccess
+		findUserRes findUserResult
+		wantSaved   bool
+		wantErr     error
+	}{
+		{
+			name:        "shares secret",
+			userID:      1,
+			login:       "bob",
+			access:      models.SecretWriteAccess,
+			findUserRes: findUserResult{user: models.User{ID: 2, Login: "bob"}},
+			wantSaved:   true,
+		},
+		{
+			name:    "returns error if user is not owner",
+			userID:  2,
+			login:   "carol",
+			access:  models.SecretReadAccess,
+			wantErr: services.ErrNoPermission{UserID: 2, SecretID: 1},
+		},
+		{
+			name:    "returns error if access is owner",
+			userID:  1,
+			login:   "bob",
+			access:  models.SecretOwnerAccess,
+			wantErr: services.ErrInvalidShareAccess,
+		},
+		{
+			name:        "returns error if recipient is owner",
+			userID:      1,
+			login:       "alice",
+			access:      models.SecretReadAccess,
+			findUserRes: findUserResult{user: models.User{ID: 1, Login: "alice"}},
+			wantErr:     services.ErrShareWithOwner,
+		},
+		{
+			name:   "returns error if recipient does not exist",
+			userID: 1,
+			login:  "nobody",
+			access: models.SecretReadAccess,
+			findUserRes: findUserResult{
+				err: storage.ErrUserNotFound{User: models.User{Login: "nobody"}},
+			},
+			wantErr: storage.ErrUserNotFound{User: models.User{Login: "nobody"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(shareStorageMock)
+			store.On("FindUserByLogin", mock.Anything, tc.login).
+				Return(tc.findUserRes.user, tc.findUserRes.err)
+			wantShare := models.SecretShare{SecretID: 1, UserID: 2, Login: "bob", Access: tc.access}
+			store.On("SaveSecretShare", mock.Anything, wantShare).Return(wantShare, nil)
+			srv := services.NewShareService(store, ownerOnlyACL())
+
+			share, err := srv.Share(context.TODO(), tc.userID, secret, tc.login, tc.access)
+			assert.Equal(t, tc.wantErr, err)
+			if tc.wantSaved {
+				assert.Equal(t, wantShare, share)
+				store.AssertCalled(t, "SaveSecretShare", mock.Anything, wantShare)
+			} else {
+				store.AssertNotCalled(t, "SaveSecretShare", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestRevokeShare(t *testing.T) {
+	secret := models.Secret{ID: 1, UserID: 1}
+	testCases := []struct {
+		name        string
+		userID      int
+		recipientID int
+		wantErr     error
+	}{
+		{
+			name:        "owner revokes share",
+			userID:      1,
+			recipientID: 2,
+		},
+		{
+			name:        "recipient gives up share",
+			userID:      2,
+			recipientID: 2,
+		},
+		{
+			name:        "returns error if user revokes share of another recipient",
+			userID:      2,
+			recipientID: 3,
+			wantErr:     services.ErrNoPermission{UserID: 2, SecretID: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(shareStorageMock)
+			store.On("DeleteSecretShare", mock.Anything, secret.ID, tc.recipientID).Return(nil)
+			srv := services.NewShareService(store, ownerOnlyACL())
+
+			err := srv.Revoke(context.TODO(), tc.userID, secret, tc.recipientID)
+			assert.Equal(t, tc.wantErr, err)
+			if tc.wantErr == nil {
+				store.AssertCalled(t, "DeleteSecretShare", mock.Anything, secret.ID, tc.recipientID)
+			} else {
+				store.AssertNotCalled(t, "DeleteSecretShare", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
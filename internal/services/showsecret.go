@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+type ShowSecretService struct {
+	decryptor  Decryptor
+	authorizer SecretAuthorizer
+	auditor    AuditRecorder
+}
+
+func NewShowSecretService(decryptor Decryptor, authorizer SecretAuthorizer, auditor AuditRecorder) ShowSecretService {
+	return ShowSecretService{
+		decryptor:  decryptor,
+		authorizer: authorizer,
+		auditor:    auditor,
+	}
+}
+
+func (srv ShowSecretService) Show(ctx context.Context, userID int, secret models.Secret) (Unmarshaller, error) {
+	if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretReadAccess); err != nil {
+		return nil, err
+	}
+	if err := srv.auditor.Record(ctx, userID, models.AuditRead, secret); err != nil {
+		return nil, err
+	}
+
+	return decryptSecret(srv.decryptor, secret)
+}
+
+func decryptSecret(decryptor Decryptor, secret models.Secret) (Unmarshaller, error) {
+	decryptedData, err := decryptor.Decrypt(secret.EncryptedData, secret.EncryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	metadata, err := decryptMetadata(decryptor, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch secret.SecretType {
+	case models.CredentialsSecret:
+		creds := &models.Credentials{}
+		if err := creds.Unmarshall(decryptedData); err != nil {
+			return nil, fmt.Errorf("failed to unmarshall secret: %w", err)
+		}
+		creds.ID = secret.ID
+		creds.Description = secret.Description
+		creds.Metadata = metadata
+		return creds, nil
+	case models.CreditCardSecret:
+		creditCard := &models.CreditCard{}
+		if err := creditCard.Unmarshall(decryptedData); err != nil {
+			return nil, fmt.Errorf("failed to unmarshall secret: %w", err)
+		}
+		creditCard.ID = secret.ID
+		creditCard.Description = secret.Description
+		creditCard.Metadata = metadata
+		return creditCard, nil
+	case models.TextSecret:
+		text := &models.Text{}
+		if err := text.Unmarshall(decryptedData); err != nil {
+			return nil, fmt.Errorf("failed to unmarshall secret: %w", err)
+		}
+		text.ID = secret.ID
+		text.Description = secret.Description
+		text.Metadata = metadata
+		return text, nil
+	case models.BinDataSecret:
+		binData := &models.BinData{}
+		if err := binData.Unmarshall(decryptedData); err != nil {
+			return nil, fmt.Errorf("failed to unmarshall secret: %w", err)
+		}
+		binData.ID = secret.ID
+		binData.Metadata = metadata
+		return binData, nil
+	default:
+		return nil, fmt.Errorf("unknown secret type %d", secret.SecretType)
+	}
+}
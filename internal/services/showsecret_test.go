@@ -0,0 +1,146 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type decryptorMock struct{ mock.Mock }
+
+func (m *decryptorMock) Decrypt(ciphertext []byte, encryptedKey []byte) ([]byte, error) {
+	args := m.Called(ciphertext, encryptedKey)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func TestShowSecret(t *testing.T) {
+	type decryptResult struct {
+		msg []byte
+		err error
+	}
+	type want struct {
+		secret services.Unmarshaller
+		errMsg string
+	}
+	creds := &models.Credentials{Login: "login", Password: "password"}
+	credsBytes, err := creds.Marshall()
+	require.NoError(t, err)
+	text := &models.Text{Title: "title", Body: "line 1\nline 2"}
+	textBytes, err := text.Marshall()
+	require.NoError(t, err)
+	binData := &models.BinData{Filename: "file", Bytes: []byte{0x1, 0x2, 0x3}}
+	binDataBytes, err := binData.Marshall()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		userID     int
+		secret     models.Secret
+		decryptRes decryptResult
+		want       want
+	}{
+		{
+			name:   "returns decrypted credentials",
+			userID: 1,
+			secret: models.Secret{
+				ID:          1,
+				UserID:      1,
+				SecretType:  models.CredentialsSecret,
+				Description: "description",
+			},
+			decryptRes: decryptResult{msg: credsBytes},
+			want: want{
+				secret: &models.Credentials{
+					ID:          1,
+					Description: "description",
+					Login:       "login",
+					Password:    "password",
+				},
+			},
+		},
+		{
+			name:   "returns decrypted text",
+			userID: 1,
+			secret: models.Secret{
+				ID:          3,
+				UserID:      1,
+				SecretType:  models.TextSecret,
+				Description: "description",
+			},
+			decryptRes: decryptResult{msg: textBytes},
+			want: want{
+				secret: &models.Text{
+					ID:          3,
+					Description: "description",
+					Title:       "title",
+					Body:        "line 1\nline 2",
+				},
+			},
+		},
+		{
+			name:   "returns decrypted bin data",
+			userID: 1,
+			secret: models.Secret{
+				ID:         2,
+				UserID:     1,
+				SecretType: models.BinDataSecret,
+			},
+			decryptRes: decryptResult{msg: binDataBytes},
+			want: want{
+				secret: &models.BinData{
+					ID:       2,
+					Filename: "file",
+					Bytes:    []byte{0x1, 0x2, 0x3},
+				},
+			},
+		},
+		{
+			name:   "returns permission error if user is not secret owner",
+			userID: 2,
+			secret: models.Secret{
+				ID:         1,
+				UserID:     1,
+				SecretType: models.CredentialsSecret,
+			},
+			want: want{
+				errMsg: "user with id=2 doesn't have permission to secret with id=1",
+			},
+		},
+		{
+			name:   "returns error if could not decrypt secret",
+			userID: 1,
+			secret: models.Secret{
+				ID:         1,
+				UserID:     1,
+				SecretType: models.CredentialsSecret,
+			},
+			decryptRes: decryptResult{err: errors.New("error")},
+			want: want{
+				errMsg: "failed to decrypt secret: error",
+			},
+		},
+	}
+
+	decryptor := new(decryptorMock)
+	showSrv := services.NewShowSecretService(decryptor, ownerOnlyACL(), noAuditLog{})
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decryptCall := decryptor.On("Decrypt", mock.Anything, mock.Anything).
+				Return(tc.decryptRes.msg, tc.decryptRes.err)
+			defer decryptCall.Unset()
+
+			secret, err := showSrv.Show(context.TODO(), tc.userID, tc.secret)
+			if err == nil {
+				assert.Equal(t, tc.want.secret, secret)
+			} else {
+				assert.EqualError(t, err, tc.want.errMsg)
+			}
+		})
+	}
+}
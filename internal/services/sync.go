@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+const (
+	DefaultSyncPageSize = 100
+	MaxSyncPageSize     = 500
+)
+
+type SyncStorage interface {
+	UserSecretsRevision(ctx context.Context, userID int) (int, error)
+	ListSecretChanges(ctx context.Context, userID int, since int, until int, limit int) ([]models.SecretChange, error)
+}
+
+// SyncedSecret is the decrypted secret state at the revision.
+type SyncedSecret struct {
+	Secret   models.Secret
+	Revision int
+	Data     Unmarshaller
+}
+
+// SyncChanges describes user secret changes up to Revision, which the
+// client passes as since on the next sync. HasMore is set if there are
+// changes after Revision already.
+type SyncChanges struct {
+	Revision int
+	HasMore  bool
+	Created  []SyncedSecret
+	Updated  []SyncedSecret
+	Deleted  []int
+}
+
+type SyncService struct {
+	storage   SyncStorage
+	decryptor Decryptor
+	auditor   AuditRecorder
+}
+
+func NewSyncService(storage SyncStorage, decryptor Decryptor, auditor AuditRecorder) SyncService {
+	return SyncService{
+		storage:   storage,
+		decryptor: decryptor,
+		auditor:   auditor,
+	}
+}
+
+// Changes returns up to limit user secret changes made after the since revision.
+func (srv SyncService) Changes(ctx context.Context, userID int, since int, limit int) (SyncChanges, error) {
+	if limit <= 0 {
+		limit = DefaultSyncPageSize
+	}
+	if limit > MaxSyncPageSize {
+		limit = MaxSyncPageSize
+	}
+
+	// The revision is read first, changes committed after that are left
+	// for the next sync, so none of them is skipped.
+	revision, err := srv.storage.UserSecretsRevision(ctx, userID)
+	if err != nil {
+		return SyncChanges{}, err
+	}
+	result := SyncChanges{Revision: revision}
+	if since >= revision {
+		return result, nil
+	}
+	changes, err := srv.storage.ListSecretChanges(ctx, userID, since, revision, limit+1)
+	if err != nil {
+		return SyncChanges{}, err
+	}
+	if len(changes) > limit {
+		changes = changes[:limit]
+		result.Revision = changes[limit-1].Revision
+		result.HasMore = true
+	}
+
+	var read []models.Secret
+	for _, change := range changes {
+		if change.Kind == models.SecretDeleted {
+			result.Deleted = append(result.Deleted, change.Secret.ID)
+			continue
+		}
+		data, err := decryptSecret(srv.decryptor, change.Secret)
+		if err != nil {
+			return SyncChanges{}, fmt.Errorf("failed to sync secret with id=%d: %w", change.Secret.ID, err)
+		}
+		read = append(read, change.Secret)
+		synced := SyncedSecret{
+			Secret:   change.Secret,
+			Revision: change.Revision,
+			Data:     data,
+		}
+		if change.Kind == models.SecretCreated {
+			result.Created = append(result.Created, synced)
+		} else {
+			result.Updated = append(result.Updated, synced)
+		}
+	}
+	if err := srv.auditor.Record(ctx, userID, models.AuditRead, read...); err != nil {
+		return SyncChanges{}, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,163 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type syncStorageMock struct{ mock.Mock }
+
+func (m *syncStorageMock) UserSecretsRevision(ctx context.Context, userID int) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *syncStorageMock) ListSecretChanges(
+	ctx context.Context,
+	userID int,
+	since int,
+	until int,
+	limit int) ([]models.SecretChange, error) {
+
+	args := m.Called(ctx, userID, since, until, limit)
+	return args.Get(0).([]models.SecretChange), args.Error(1)
+}
+
+func TestSyncChanges(t *testing.T) {
+	creds := &models.Credentials{Login: "login", Password: "password"}
+	credsBytes, err := creds.Marshall()
+	require.NoError(t, err)
+	createdSecret := models.Secret{
+		ID:            1,
+		UserID:        1,
+		SecretType:    models.CredentialsSecret,
+		Description:   "created",
+		EncryptedData: []byte("created data"),
+		EncryptedKey:  []byte("created key"),
+	}
+	updatedSecret := models.Secret{
+		ID:            2,
+		UserID:        1,
+		SecretType:    models.CredentialsSecret,
+		Description:   "updated",
+		EncryptedData: []byte("updated data"),
+		EncryptedKey:  []byte("updated key"),
+	}
+	changes := []models.SecretChange{
+		{Kind: models.SecretCreated, Revision: 4, Secret: createdSecret},
+		{Kind: models.SecretDeleted, Revision: 5, Secret: models.Secret{ID: 3, UserID: 1}},
+		{Kind: models.SecretUpdated, Revision: 6, Secret: updatedSecret},
+	}
+	testCases := []struct {
+		name          string
+		since         int
+		limit         int
+		revision      int
+		revisionErr   error
+		changes       []models.SecretChange
+		expectedLimit int
+		want          services.SyncChanges
+		errMsg        string
+	}{
+		{
+			name:          "returns changes since revision",
+			since:         3,
+			revision:      6,
+			changes:       changes,
+			expectedLimit: services.DefaultSyncPageSize + 1,
+			want: services.SyncChanges{
+				Revision: 6,
+				Created: []services.SyncedSecret{
+					{
+						Secret:   createdSecret,
+						Revision: 4,
+						Data: &models.Credentials{
+							ID:          1,
+							Description: "created",
+							Login:       "login",
+							Password:    "password",
+						},
+					},
+				},
+				Updated: []services.SyncedSecret{
+					{
+						Secret:   updatedSecret,
+						Revision: 6,
+						Data: &models.Credentials{
+							ID:          2,
+							Description: "updated",
+							Login:       "login",
+							Password:    "password",
+						},
+					},
+				},
+				Deleted: []int{3},
+			},
+		},
+		{
+			name:          "stops at the last returned change if there are more changes",
+			since:         3,
+			limit:         2,
+			revision:      6,
+			changes:       changes,
+			expectedLimit: 3,
+			want: services.SyncChanges{
+				Revision: 5,
+				HasMore:  true,
+				Created: []services.SyncedSecret{
+					{
+						Secret:   createdSecret,
+						Revision: 4,
+						Data: &models.Credentials{
+							ID:          1,
+							Description: "created",
+							Login:       "login",
+							Password:    "password",
+						},
+					},
+				},
+				Deleted: []int{3},
+			},
+		},
+		{
+			name:     "returns no changes if client is up to date",
+			since:    6,
+			revision: 6,
+			want:     services.SyncChanges{Revision: 6},
+		},
+		{
+			name:        "returns error if revision can not be read",
+			revisionErr: errors.New("connection refused"),
+			errMsg:      "connection refused",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			syncStorage := new(syncStorageMock)
+			syncStorage.On("UserSecretsRevision", mock.Anything, 1).Return(tc.revision, tc.revisionErr)
+			syncStorage.On("ListSecretChanges", mock.Anything, 1, tc.since, tc.revision, tc.expectedLimit).
+				Return(tc.changes, nil)
+			decryptor := new(decryptorMock)
+			decryptor.On("Decrypt", createdSecret.EncryptedData, createdSecret.EncryptedKey).Return(credsBytes, nil)
+			decryptor.On("Decrypt", updatedSecret.EncryptedData, updatedSecret.EncryptedKey).Return(credsBytes, nil)
+			srv := services.NewSyncService(syncStorage, decryptor, noAuditLog{})
+
+			result, err := srv.Changes(context.TODO(), 1, tc.since, tc.limit)
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, result)
+			} else {
+				assert.EqualError(t, err, tc.errMsg)
+				syncStorage.AssertNotCalled(t, "ListSecretChanges", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
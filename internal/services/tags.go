@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+const maxTagNameLen = 255
+
+var ErrInvalidTagName = errors.New("invalid tag name")
+
+type TagStorage interface {
+	CreateTag(ctx context.Context, userID int, name string) (models.Tag, error)
+	FindUserTag(ctx context.Context, userID int, id int) (models.Tag, error)
+	ListUserTags(ctx context.Context, userID int) ([]models.Tag, error)
+	RenameTag(ctx context.Context, tag models.Tag) error
+	SetSecretTags(ctx context.Context, secretID int, tagIDs []int) error
+}
+
+type TagService struct {
+	storage    TagStorage
+	authorizer SecretAuthorizer
+}
+
+func NewTagService(storage TagStorage, authorizer SecretAuthorizer) TagService {
+	return TagService{
+		storage:    storage,
+		authorizer: authorizer,
+	}
+}
+
+func (srv TagService) Create(ctx context.Context, userID int, name string) (models.Tag, error) {
+	if err := validateTagName(name); err != nil {
+		return models.Tag{UserID: userID, Name: name}, err
+	}
+
+	return srv.storage.CreateTag(ctx, userID, name)
+}
+
+func (srv TagService) List(ctx context.Context, userID int) ([]models.Tag, error) {
+	return srv.storage.ListUserTags(ctx, userID)
+}
+
+func (srv TagService) Rename(ctx context.Context, userID int, tagID int, name string) (models.Tag, error) {
+	if err := validateTagName(name); err != nil {
+		return models.Tag{ID: tagID, UserID: userID, Name: name}, err
+	}
+	tag, err := srv.storage.FindUserTag(ctx, userID, tagID)
+	if err != nil {
+		return tag, err
+	}
+	tag.Name = name
+
+	return tag, srv.storage.RenameTag(ctx, tag)
+}
+
+// SetSecretTags replaces the secret tags, every tag must belong to the user.
+func (srv TagService) SetSecretTags(ctx context.Context, userID int, secret models.Secret, tagIDs []int) error {
+	if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretOwnerAccess); err != nil {
+		return err
+	}
+	for _, tagID := range tagIDs {
+		if _, err := srv.storage.FindUserTag(ctx, userID, tagID); err != nil {
+			return err
+		}
+	}
+
+	return srv.storage.SetSecretTags(ctx, secret.ID, tagIDs)
+}
+
+func validateTagName(name string) error {
+	if strings.TrimSpace(name) == "" || len(name) > maxTagNameLen {
+		return ErrInvalidTagName
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/ilya-burinskiy/gophkeeper/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type tagStorageMock struct{ mock.Mock }
+
+func (m *tagStorageMock) CreateTag(ctx context.Context, userID int, name string) (models.Tag, error) {
+	args := m.Called(ctx, userID, name)
+	return args.Get(0).(models.Tag), args.Error(1)
+}
+
+func (m *tagStorageMock) FindUserTag(ctx context.Context, userID int, id int) (models.Tag, error) {
+	args := m.Called(ctx, userID, id)
+	return args.Get(0).(models.Tag), args.Error(1)
+}
+
+func (m *tagStorageMock) ListUserTags(ctx context.Context, userID int) ([]models.Tag, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Tag), args.Error(1)
+}
+
+func (m *tagStorageMock) RenameTag(ctx context.Context, tag models.Tag) error {
+	args := m.Called(ctx, tag)
+	return args.Error(0)
+}
+
+func (m *tagStorageMock) SetSecretTags(ctx context.Context, secretID int, tagIDs []int) error {
+	args := m.Called(ctx, secretID, tagIDs)
+	return args.Error(0)
+}
+
+func TestRenameTag(t *testing.T) {
+	testCases := []struct {
+		name    string
+		tagID   int
+		tagName string
+		findErr error
+		want    models.Tag
+		errMsg  string
+	}{
+		{
+			name:    "renames tag",
+			tagID:   1,
+			tagName: "prod",
+			want:    models.Tag{ID: 1, UserID: 1, Name: "prod"},
+		},
+		{
+			name:    "returns error if tag is not found",
+			tagID:   2,
+			tagName: "prod",
+			findErr: storage.ErrTagNotFound{Tag: models.Tag{ID: 2}},
+			errMsg:  "tag with id=2 not found",
+		},
+		{
+			name:    "returns error if tag name is empty",
+			tagID:   1,
+			tagName: "",
+			errMsg:  services.ErrInvalidTagName.Error(),
+		},
+	}
+
+	tagStorage := new(tagStorageMock)
+	srv := services.NewTagService(tagStorage, ownerOnlyACL())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			findCall := tagStorage.On("FindUserTag", mock.Anything, 1, tc.tagID).
+				Return(models.Tag{ID: tc.tagID, UserID: 1, Name: "staging"}, tc.findErr)
+			defer findCall.Unset()
+			renameCall := tagStorage.On("RenameTag", mock.Anything, tc.want).Return(nil)
+			defer renameCall.Unset()
+
+			tag, err := srv.Rename(context.TODO(), 1, tc.tagID, tc.tagName)
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, tag)
+			} else {
+				assert.EqualError(t, err, tc.errMsg)
+			}
+		})
+	}
+}
+
+func TestSetSecretTags(t *testing.T) {
+	secret := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret}
+	testCases := []struct {
+		name   string
+		userID int
+		tagIDs []int
+		errMsg string
+	}{
+		{
+			name:   "sets secret tags",
+			userID: 1,
+			tagIDs: []int{1, 2},
+		},
+		{
+			name:   "removes secret tags",
+			userID: 1,
+			tagIDs: []int{},
+		},
+		{
+			name:   "returns error if tag belongs to another user",
+			userID: 1,
+			tagIDs: []int{1, 3},
+			errMsg: "tag with id=3 not found",
+		},
+		{
+			name:   "returns permission error if user is not secret owner",
+			userID: 2,
+			tagIDs: []int{1},
+			errMsg: "user with id=2 doesn't have permission to secret with id=1",
+		},
+	}
+
+	tagStorage := new(tagStorageMock)
+	tagStorage.On("FindUserTag", mock.Anything, 1, 1).Return(models.Tag{ID: 1, UserID: 1}, nil)
+	tagStorage.On("FindUserTag", mock.Anything, 1, 2).Return(models.Tag{ID: 2, UserID: 1}, nil)
+	tagStorage.On("FindUserTag", mock.Anything, 1, 3).
+		Return(models.Tag{}, storage.ErrTagNotFound{Tag: models.Tag{ID: 3}})
+	srv := services.NewTagService(tagStorage, ownerOnlyACL())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setCall := tagStorage.On("SetSecretTags", mock.Anything, secret.ID, tc.tagIDs).Return(nil)
+			defer setCall.Unset()
+
+			err := srv.SetSecretTags(context.TODO(), tc.userID, secret, tc.tagIDs)
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+				tagStorage.AssertCalled(t, "SetSecretTags", mock.Anything, secret.ID, tc.tagIDs)
+			} else {
+				assert.EqualError(t, err, tc.errMsg)
+				tagStorage.AssertNotCalled(t, "SetSecretTags", mock.Anything, secret.ID, tc.tagIDs)
+			}
+		})
+	}
+}
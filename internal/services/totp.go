@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+const (
+	TOTPIssuer         = "Gophkeeper"
+	RecoveryCodesCount = 10
+	recoveryCodeSize   = 5
+)
+
+type TOTPStorage interface {
+	FindUserByID(ctx context.Context, id int) (models.User, error)
+	FindUserTOTP(ctx context.Context, userID int) (models.UserTOTP, error)
+	SaveUserTOTP(ctx context.Context, userID int, encryptedSecret []byte, encryptedKey []byte) (bool, error)
+	EnableUserTOTP(ctx context.Context, userID int, step int64, recoveryCodeHashes [][]byte) (bool, error)
+	AcceptTOTPStep(ctx context.Context, userID int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int, codeHash []byte) (bool, error)
+}
+
+type TOTPEncryptor interface {
+	Encrypt(msg []byte) ([]byte, []byte, error)
+	Decrypt(ciphertext []byte, encryptedKey []byte) ([]byte, error)
+}
+
+// TOTPEnrollment is the new TOTP secret of the user, Secret is base32
+// encoded for manual entry and URI is the otpauth URI of the secret.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+type TOTPService struct {
+	storage   TOTPStorage
+	encryptor TOTPEncryptor
+	randGen   RandGen
+	attempts  LoginAttemptStore
+	sessions  SessionStarter
+}
+
+func NewTOTPService(
+	storage TOTPStorage,
+	encryptor TOTPEncryptor,
+	randGen RandGen,
+	attempts LoginAttemptStore,
+	sessions SessionStarter) TOTPService {
+
+	return TOTPService{
+		storage:   storage,
+		encryptor: encryptor,
+		randGen:   randGen,
+		attempts:  attempts,
+		sessions:  sessions,
+	}
+}
+
+// Enroll generates a new TOTP secret of the user. The secret is not
+// required at login until it is confirmed with a code.
+func (srv TOTPService) Enroll(ctx context.Context, userID int) (TOTPEnrollment, error) {
+	user, err := srv.storage.FindUserByID(ctx, userID)
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("failed to enroll TOTP: %w", err)
+	}
+	if user.TOTPEnabled {
+		return TOTPEnrollment{}, ErrTOTPAlreadyEnabled
+	}
+	secret, err := srv.randGen.Gen(auth.TOTPSecretSize)
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	encryptedSecret, encryptedKey, err := srv.encryptor.Encrypt(secret)
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("failed to encrypt TOTP secret: %w", err)
+	}
+	saved, err := srv.storage.SaveUserTOTP(ctx, userID, encryptedSecret, encryptedKey)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	if !saved {
+		return TOTPEnrollment{}, ErrTOTPAlreadyEnabled
+	}
+
+	return TOTPEnrollment{
+		Secret: auth.EncodeTOTPSecret(secret),
+		URI:    auth.TOTPURI(TOTPIssuer, user.Login, secret),
+	}, nil
+}
+
+// Confirm enables TOTP of the user if the code matches the enrolled
+// secret and returns new recovery codes. Only hashes of recovery codes
+// are stored, so they can not be shown again.
+func (srv TOTPService) Confirm(ctx context.Context, userID int, code string) ([]string, error) {
+	totp, err := srv.storage.FindUserTOTP(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm TOTP: %w", err)
+	}
+	if totp.Enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if len(totp.EncryptedSecret) == 0 {
+		return nil, ErrTOTPNotEnrolled
+	}
+	secret, err := srv.encryptor.Decrypt(totp.EncryptedSecret, totp.EncryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+	}
+	step, ok := srv.matchTOTPCode(secret, code, 0)
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+
+	codes := make([]string, RecoveryCodesCount)
+	hashes := make([][]byte, RecoveryCodesCount)
+	for i := range codes {
+		codes[i], err = srv.generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	enabled, err := srv.storage.EnableUserTOTP(ctx, userID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	return codes, nil
+}
+
+// Authenticate starts a session of the user of the MFA token if the code
+// is a TOTP code or an unused recovery code of the user. A TOTP code is
+// accepted once, and failures lock the user like failed passwords.
+func (srv TOTPService) Authenticate(ctx context.Context, mfaToken string, code string) (SessionTokens, error) {
+	userID, err := auth.ParseMFAToken(mfaToken)
+	if err != nil {
+		return SessionTokens{}, ErrInvalidMFAToken
+	}
+	key := "totp:" + strconv.Itoa(userID)
+	lockout, err := srv.attempts.FindLoginLockout(ctx, []string{key})
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to authenticate user: %w", err)
+	}
+	if lockout > 0 {
+		return SessionTokens{}, ErrLoginLocked{RetryAfter: lockout}
+	}
+
+	totp, err := srv.storage.FindUserTOTP(ctx, userID)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to authenticate user: %w", err)
+	}
+	if !totp.Enabled {
+		return SessionTokens{}, ErrInvalidMFAToken
+	}
+	secret, err := srv.encryptor.Decrypt(totp.EncryptedSecret, totp.EncryptedKey)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+	}
+	accepted := false
+	if step, ok := srv.matchTOTPCode(secret, code, totp.LastStep); ok {
+		accepted, err = srv.storage.AcceptTOTPStep(ctx, userID, step)
+	} else if normalized := normalizeRecoveryCode(code); len(normalized) > auth.TOTPDigits {
+		accepted, err = srv.storage.UseRecoveryCode(ctx, userID, hashRecoveryCode(normalized))
+	}
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to authenticate user: %w", err)
+	}
+	if !accepted {
+		if err := recordLoginFailure(ctx, srv.attempts, key, UserLoginPolicy); err != nil {
+			return SessionTokens{}, fmt.Errorf("failed to authenticate user: %w", err)
+		}
+		return SessionTokens{}, ErrInvalidTOTPCode
+	}
+	if err := srv.attempts.ResetLoginFailures(ctx, key); err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to authenticate user: %w", err)
+	}
+
+	tokens, err := srv.sessions.Start(ctx, userID)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("failed to authenticate user: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// matchTOTPCode returns the step of the code after lastStep. Codes of the
+// previous and the next step are accepted too, to allow for clock drift.
+func (srv TOTPService) matchTOTPCode(secret []byte, code string, lastStep int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != auth.TOTPDigits {
+		return 0, false
+	}
+	current := auth.TOTPStep(time.Now())
+	for step := current - 1; step <= current+1; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(auth.TOTPCode(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// generateRecoveryCode returns a code like "abcd-efg2".
+func (srv TOTPService) generateRecoveryCode() (string, error) {
+	bs, err := srv.randGen.Gen(recoveryCodeSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(bs))
+
+	return code[:len(code)/2] + "-" + code[len(code)/2:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
+
+func hashRecoveryCode(code string) []byte {
+	hash := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hash[:]
+}
@@ -0,0 +1,276 @@
+package services_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/auth"
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type totpStorageMock struct{ mock.Mock }
+
+func (m *totpStorageMock) FindUserByID(ctx context.Context, id int) (models.User, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+func (m *totpStorageMock) FindUserTOTP(ctx context.Context, userID int) (models.UserTOTP, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(models.UserTOTP), args.Error(1)
+}
+
+func (m *totpStorageMock) SaveUserTOTP(
+	ctx context.Context,
+	userID int,
+	encryptedSecret []byte,
+	encryptedKey []byte) (bool, error) {
+
+	args := m.Called(ctx, userID, encryptedSecret, encryptedKey)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *totpStorageMock) EnableUserTOTP(
+	ctx context.Context,
+	userID int,
+	step int64,
+	recoveryCodeHashes [][]byte) (bool, error) {
+
+	args := m.Called(ctx, userID, step, recoveryCodeHashes)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *totpStorageMock) AcceptTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *totpStorageMock) UseRecoveryCode(ctx context.Context, userID int, codeHash []byte) (bool, error) {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+type totpEncryptorMock struct{ mock.Mock }
+
+func (m *totpEncryptorMock) Encrypt(msg []byte) ([]byte, []byte, error) {
+	args := m.Called(msg)
+	return args.Get(0).([]byte), args.Get(1).([]byte), args.Error(2)
+}
+
+func (m *totpEncryptorMock) Decrypt(ciphertext []byte, encryptedKey []byte) ([]byte, error) {
+	args := m.Called(ciphertext, encryptedKey)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+var totpSecret = []byte("12345678901234567890")
+
+func TestTOTPCode(t *testing.T) {
+	// test vectors of RFC 6238 truncated to 6 digits
+	testCases := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 20000000000, want: "353130"},
+	}
+	for _, tc := range testCases {
+		step := auth.TOTPStep(time.Unix(tc.unix, 0))
+		assert.Equal(t, tc.want, auth.TOTPCode(totpSecret, step), "time=%d", tc.unix)
+	}
+}
+
+func TestTOTPEnroll(t *testing.T) {
+	randGen := new(randGenMock)
+	randGen.On("Gen", auth.TOTPSecretSize).Return(totpSecret, nil)
+	encryptor := new(totpEncryptorMock)
+	encryptor.On("Encrypt", totpSecret).Return([]byte("secret"), []byte("key"), nil)
+
+	t.Run("saves new secret", func(t *testing.T) {
+		store := new(totpStorageMock)
+		store.On("FindUserByID", mock.Anything, 1).Return(models.User{ID: 1, Login: "login"}, nil)
+		store.On("SaveUserTOTP", mock.Anything, 1, []byte("secret"), []byte("key")).Return(true, nil)
+		srv := services.NewTOTPService(store, encryptor, randGen, noLoginFailures(), sessionStarterStub{})
+
+		enrollment, err := srv.Enroll(context.TODO(), 1)
+		require.NoError(t, err)
+		assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", enrollment.Secret)
+		assert.Equal(
+			t,
+			"otpauth://totp/Gophkeeper:login?algorithm=SHA1&digits=6&issuer=Gophkeeper&period=30"+
+				"&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			enrollment.URI,
+		)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("rejects enabled TOTP", func(t *testing.T) {
+		store := new(totpStorageMock)
+		store.On("FindUserByID", mock.Anything, 1).Return(models.User{ID: 1, TOTPEnabled: true}, nil)
+		srv := services.NewTOTPService(store, encryptor, randGen, noLoginFailures(), sessionStarterStub{})
+
+		_, err := srv.Enroll(context.TODO(), 1)
+		assert.ErrorIs(t, err, services.ErrTOTPAlreadyEnabled)
+		store.AssertNotCalled(t, "SaveUserTOTP", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestTOTPConfirm(t *testing.T) {
+	enrolled := models.UserTOTP{EncryptedSecret: []byte("secret"), EncryptedKey: []byte("key")}
+	encryptor := new(totpEncryptorMock)
+	encryptor.On("Decrypt", []byte("secret"), []byte("key")).Return(totpSecret, nil)
+	randGen := new(randGenMock)
+	randGen.On("Gen", 5).Return([]byte{0, 1, 2, 3, 4}, nil)
+	step := auth.TOTPStep(time.Now())
+
+	t.Run("enables TOTP and returns recovery codes", func(t *testing.T) {
+		store := new(totpStorageMock)
+		store.On("FindUserTOTP", mock.Anything, 1).Return(enrolled, nil)
+		store.On("EnableUserTOTP", mock.Anything, 1, mock.Anything, mock.Anything).Return(true, nil)
+		srv := services.NewTOTPService(store, encryptor, randGen, noLoginFailures(), sessionStarterStub{})
+
+		codes, err := srv.Confirm(context.TODO(), 1, auth.TOTPCode(totpSecret, step))
+		require.NoError(t, err)
+		require.Len(t, codes, services.RecoveryCodesCount)
+		assert.Equal(t, "aaaq-eaye", codes[0])
+
+		call := store.Calls[1]
+		assert.InDelta(t, step, call.Arguments.Get(2).(int64), 1)
+		hash := sha256.Sum256([]byte("aaaqeaye"))
+		hashes := call.Arguments.Get(3).([][]byte)
+		require.Len(t, hashes, services.RecoveryCodesCount)
+		assert.Equal(t, hash[:], hashes[0])
+	})
+
+	t.Run("rejects invalid code", func(t *testing.T) {
+		store := new(totpStorageMock)
+		store.On("FindUserTOTP", mock.Anything, 1).Return(enrolled, nil)
+		srv := services.NewTOTPService(store, encryptor, randGen, noLoginFailures(), sessionStarterStub{})
+
+		_, err := srv.Confirm(context.TODO(), 1, auth.TOTPCode(totpSecret, step-10))
+		assert.ErrorIs(t, err, services.ErrInvalidTOTPCode)
+		store.AssertNotCalled(t, "EnableUserTOTP", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects user without secret", func(t *testing.T) {
+		store := new(totpStorageMock)
+		store.On("FindUserTOTP", mock.Anything, 1).Return(models.UserTOTP{}, nil)
+		srv := services.NewTOTPService(store, encryptor, randGen, noLoginFailures(), sessionStarterStub{})
+
+		_, err := srv.Confirm(context.TODO(), 1, "123456")
+		assert.ErrorIs(t, err, services.ErrTOTPNotEnrolled)
+	})
+}
+
+func TestTOTPAuthenticate(t *testing.T) {
+	enabled := models.UserTOTP{EncryptedSecret: []byte("secret"), EncryptedKey: []byte("key"), Enabled: true}
+	encryptor := new(totpEncryptorMock)
+	encryptor.On("Decrypt", []byte("secret"), []byte("key")).Return(totpSecret, nil)
+	mfaToken, err := auth.BuildMFAToken(1)
+	require.NoError(t, err)
+	step := auth.TOTPStep(time.Now())
+	code := auth.TOTPCode(totpSecret, step)
+
+	t.Run("returns JWT for valid code", func(t *testing.T) {
+		store := new(totpStorageMock)
+		store.On("FindUserTOTP", mock.Anything, 1).Return(enabled, nil)
+		store.On("AcceptTOTPStep", mock.Anything, 1, mock.Anything).Return(true, nil)
+		attempts := noLoginFailures()
+		srv := services.NewTOTPService(store, encryptor, new(randGenMock), attempts, sessionStarterStub{})
+
+		tokens, err := srv.Authenticate(context.TODO(), mfaToken, code)
+		require.NoError(t, err)
+		assert.Equal(t, 1, userIDFromJWT(t, tokens.AccessToken))
+		attempts.AssertCalled(t, "ResetLoginFailures", mock.Anything, "totp:1")
+	})
+
+	t.Run("rejects used code", func(t *testing.T) {
+		used := enabled
+		used.LastStep = step + 1
+		store := new(totpStorageMock)
+		store.On("FindUserTOTP", mock.Anything, 1).Return(used, nil)
+		attempts := noLoginFailures()
+		srv := services.NewTOTPService(store, encryptor, new(randGenMock), attempts, sessionStarterStub{})
+
+		_, err := srv.Authenticate(context.TODO(), mfaToken, code)
+		assert.ErrorIs(t, err, services.ErrInvalidTOTPCode)
+		store.AssertNotCalled(t, "AcceptTOTPStep", mock.Anything, mock.Anything, mock.Anything)
+		attempts.AssertCalled(t, "RecordLoginFailure", mock.Anything, "totp:1", services.UserLoginPolicy.Window)
+	})
+
+	t.Run("accepts recovery code", func(t *testing.T) {
+		hash := sha256.Sum256([]byte("aaaqeaye"))
+		store := new(totpStorageMock)
+		store.On("FindUserTOTP", mock.Anything, 1).Return(enabled, nil)
+		store.On("UseRecoveryCode", mock.Anything, 1, hash[:]).Return(true, nil)
+		srv := services.NewTOTPService(store, encryptor, new(randGenMock), noLoginFailures(), sessionStarterStub{})
+
+		tokens, err := srv.Authenticate(context.TODO(), mfaToken, "AAAQ-EAYE")
+		require.NoError(t, err)
+		assert.Equal(t, 1, userIDFromJWT(t, tokens.AccessToken))
+	})
+
+	t.Run("rejects used recovery code", func(t *testing.T) {
+		store := new(totpStorageMock)
+		store.On("FindUserTOTP", mock.Anything, 1).Return(enabled, nil)
+		store.On("UseRecoveryCode", mock.Anything, 1, mock.Anything).Return(false, nil)
+		srv := services.NewTOTPService(store, encryptor, new(randGenMock), noLoginFailures(), sessionStarterStub{})
+
+		_, err := srv.Authenticate(context.TODO(), mfaToken, "aaaq-eaye")
+		assert.ErrorIs(t, err, services.ErrInvalidTOTPCode)
+	})
+
+	t.Run("rejects invalid MFA token", func(t *testing.T) {
+		jwtStr := buildJWTString(t, 1)
+		srv := services.NewTOTPService(new(totpStorageMock), encryptor, new(randGenMock), noLoginFailures(), sessionStarterStub{})
+
+		_, err := srv.Authenticate(context.TODO(), jwtStr, code)
+		assert.ErrorIs(t, err, services.ErrInvalidMFAToken)
+	})
+
+	t.Run("rejects locked user", func(t *testing.T) {
+		attempts := new(loginAttemptStoreMock)
+		attempts.On("FindLoginLockout", mock.Anything, []string{"totp:1"}).Return(time.Minute, nil)
+		store := new(totpStorageMock)
+		srv := services.NewTOTPService(store, encryptor, new(randGenMock), attempts, sessionStarterStub{})
+
+		_, err := srv.Authenticate(context.TODO(), mfaToken, code)
+		assert.Equal(t, services.ErrLoginLocked{RetryAfter: time.Minute}, err)
+		store.AssertNotCalled(t, "FindUserTOTP", mock.Anything, mock.Anything)
+	})
+
+	t.Run("returns error if failed to accept code", func(t *testing.T) {
+		store := new(totpStorageMock)
+		store.On("FindUserTOTP", mock.Anything, 1).Return(enabled, nil)
+		store.On("AcceptTOTPStep", mock.Anything, 1, mock.Anything).Return(false, errors.New("error"))
+		srv := services.NewTOTPService(store, encryptor, new(randGenMock), noLoginFailures(), sessionStarterStub{})
+
+		_, err := srv.Authenticate(context.TODO(), mfaToken, code)
+		assert.EqualError(t, err, "failed to authenticate user: error")
+	})
+}
+
+func TestAuthenticateRequiresTOTP(t *testing.T) {
+	usrFinder := new(userFinder)
+	usrFinder.On("FindUserByLogin", mock.Anything, "login").Return(
+		models.User{ID: 1, Login: "login", EncryptedPassword: hashPassword(t, "password"), TOTPEnabled: true},
+		nil,
+	)
+	authSrv := services.NewAuthenticateService(usrFinder, noLoginFailures(), sessionStarterStub{})
+
+	tokens, err := authSrv.Authenticate(context.TODO(), "login", "password")
+	assert.Empty(t, tokens)
+	var totpErr services.ErrTOTPRequired
+	require.ErrorAs(t, err, &totpErr)
+	userID, err := auth.ParseMFAToken(totpErr.MFAToken)
+	require.NoError(t, err)
+	assert.Equal(t, 1, userID)
+}
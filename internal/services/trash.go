@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+type TrashStorage interface {
+	ListUserTrash(ctx context.Context, userID int) ([]models.SecretInfo, error)
+	FindTrashedSecret(ctx context.Context, id int) (models.Secret, error)
+	RestoreTrashedSecret(ctx context.Context, secretID int, event models.AuditEvent) error
+}
+
+// TrashService gives access to deleted secrets until they are purged.
+type TrashService struct {
+	storage    TrashStorage
+	authorizer SecretAuthorizer
+}
+
+func NewTrashService(storage TrashStorage, authorizer SecretAuthorizer) TrashService {
+	return TrashService{
+		storage:    storage,
+		authorizer: authorizer,
+	}
+}
+
+func (srv TrashService) List(ctx context.Context, userID int) ([]models.SecretInfo, error) {
+	return srv.storage.ListUserTrash(ctx, userID)
+}
+
+// Find returns the trashed secret without its encrypted data.
+func (srv TrashService) Find(ctx context.Context, id int) (models.Secret, error) {
+	return srv.storage.FindTrashedSecret(ctx, id)
+}
+
+// Restore restores the secret, which requires the access to delete it.
+func (srv TrashService) Restore(ctx context.Context, userID int, secret models.Secret) error {
+	if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretOwnerAccess); err != nil {
+		return err
+	}
+
+	return srv.storage.RestoreTrashedSecret(ctx, secret.ID, newAuditEvent(ctx, userID, models.AuditRestore))
+}
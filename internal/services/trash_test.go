@@ -0,0 +1,65 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type trashStorageMock struct{ mock.Mock }
+
+func (m *trashStorageMock) ListUserTrash(ctx context.Context, userID int) ([]models.SecretInfo, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.SecretInfo), args.Error(1)
+}
+
+func (m *trashStorageMock) FindTrashedSecret(ctx context.Context, id int) (models.Secret, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Secret), args.Error(1)
+}
+
+func (m *trashStorageMock) RestoreTrashedSecret(ctx context.Context, secretID int, event models.AuditEvent) error {
+	args := m.Called(ctx, secretID, event)
+	return args.Error(0)
+}
+
+func TestRestoreTrashed(t *testing.T) {
+	secret := models.Secret{ID: 1, UserID: 1, SecretType: models.CredentialsSecret}
+	testCases := []struct {
+		name   string
+		userID int
+		errMsg string
+	}{
+		{
+			name:   "restores trashed secret",
+			userID: 1,
+		},
+		{
+			name:   "returns permission error if user is not secret owner",
+			userID: 2,
+			errMsg: "user with id=2 doesn't have permission to secret with id=1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			event := models.AuditEvent{UserID: tc.userID, Action: models.AuditRestore}
+			trashStorage := new(trashStorageMock)
+			trashStorage.On("RestoreTrashedSecret", mock.Anything, secret.ID, event).Return(nil)
+			srv := services.NewTrashService(trashStorage, ownerOnlyACL())
+
+			err := srv.Restore(context.TODO(), tc.userID, secret)
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+				trashStorage.AssertCalled(t, "RestoreTrashedSecret", mock.Anything, secret.ID, event)
+			} else {
+				assert.EqualError(t, err, tc.errMsg)
+				trashStorage.AssertNotCalled(t, "RestoreTrashedSecret", mock.Anything, secret.ID, event)
+			}
+		})
+	}
+}
@@ -11,8 +11,12 @@ type SecretUpdater interface {
 	UpdateSecret(
 		ctx context.Context,
 		id int,
+		version int,
 		description string,
-		newData []byte) error
+		newData []byte,
+		size int,
+		encryptedMetadata []byte,
+		event models.AuditEvent) (int, error)
 }
 
 type ReEncryptor interface {
@@ -22,15 +26,24 @@ type ReEncryptor interface {
 type UpdateSecretService struct {
 	updater     SecretUpdater
 	reEncryptor ReEncryptor
+	authorizer  SecretAuthorizer
 }
 
-func NewUpdateSecretService(updater SecretUpdater, reEncryptor ReEncryptor) UpdateSecretService {
+func NewUpdateSecretService(
+	updater SecretUpdater,
+	reEncryptor ReEncryptor,
+	authorizer SecretAuthorizer) UpdateSecretService {
+
 	return UpdateSecretService{
 		updater:     updater,
 		reEncryptor: reEncryptor,
+		authorizer:  authorizer,
 	}
 }
 
+// Update replaces secret data unless the secret has been changed since
+// secret.Version and returns the new version. Secret metadata is kept if
+// metadata is nil.
 func (srv UpdateSecretService) Update(
 	ctx context.Context,
 	userID int,
@@ -38,23 +51,38 @@ func (srv UpdateSecretService) Update(
 	newSecretType models.SecretType,
 	newDescription string,
 	marshallableSecret Marshaller,
-	key []byte) error {
+	metadata models.Metadata,
+	key []byte) (int, error) {
 
-	if userID != secret.UserID {
-		return ErrNoPermission{UserID: userID, SecretID: secret.ID}
+	if err := srv.authorizer.Authorize(ctx, userID, secret, models.SecretWriteAccess); err != nil {
+		return 0, err
 	}
 	if secret.SecretType != newSecretType {
-		return ErrWrongSecretType
+		return 0, ErrWrongSecretType
 	}
 
 	secretBytes, err := marshallableSecret.Marshall()
 	if err != nil {
-		return fmt.Errorf("failed to mashall secreet: %w", err)
+		return 0, fmt.Errorf("failed to mashall secreet: %w", err)
 	}
 	encryptedMsg, err := srv.reEncryptor.ReEncrypt(secretBytes, key)
 	if err != nil {
-		return fmt.Errorf("failed to reencrypt secret: %w", err)
+		return 0, fmt.Errorf("failed to reencrypt secret: %w", err)
+	}
+
+	encryptedMetadata, err := encryptMetadata(srv.reEncryptor, metadata, key)
+	if err != nil {
+		return 0, err
 	}
 
-	return srv.updater.UpdateSecret(ctx, secret.ID, newDescription, encryptedMsg)
+	return srv.updater.UpdateSecret(
+		ctx,
+		secret.ID,
+		secret.Version,
+		newDescription,
+		encryptedMsg,
+		len(secretBytes),
+		encryptedMetadata,
+		newAuditEvent(ctx, userID, models.AuditUpdate),
+	)
 }
@@ -16,11 +16,15 @@ type secretUpdaterMock struct{ mock.Mock }
 func (m *secretUpdaterMock) UpdateSecret(
 	ctx context.Context,
 	id int,
+	version int,
 	description string,
-	newData []byte) error {
+	newData []byte,
+	size int,
+	encryptedMetadata []byte,
+	event models.AuditEvent) (int, error) {
 
-	args := m.Called(ctx, id, description, newData)
-	return args.Error(0)
+	args := m.Called(ctx, id, version, description, newData, size, encryptedMetadata, event)
+	return args.Int(0), args.Error(1)
 }
 
 type reEncryptorMock struct{ mock.Mock }
@@ -110,23 +114,24 @@ func TestUpdate(t *testing.T) {
 
 	encryptor := new(reEncryptorMock)
 	updater := new(secretUpdaterMock)
-	updateSrv := services.NewUpdateSecretService(updater, encryptor)
+	updateSrv := services.NewUpdateSecretService(updater, encryptor, ownerOnlyACL())
 	for _, tc := range testCases {
 		encryptor.On("ReEncrypt", mock.Anything, mock.Anything).
 			Return(tc.reEncryptRes.encryptedMsg, tc.reEncryptRes.err).
 			Once()
-		updater.On("UpdateSecret", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
-			Return(tc.updateErr).
+		updater.On("UpdateSecret", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(1, tc.updateErr).
 			Once()
 
 		t.Run(tc.name, func(t *testing.T) {
-			err := updateSrv.Update(
+			_, err := updateSrv.Update(
 				context.TODO(),
 				tc.userID,
 				tc.secret,
 				tc.newSecretType,
 				tc.newDescription,
 				tc.marshallableSecret,
+				nil,
 				tc.encryptedKey,
 			)
 			if err != nil {
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+// UploadTTL is the time given to complete an upload.
+const UploadTTL = 24 * time.Hour
+
+type UploadStorage interface {
+	CreateUpload(ctx context.Context, upload models.Upload) error
+	FindUpload(ctx context.Context, id string) (models.Upload, error)
+	FindUploadChunk(ctx context.Context, uploadID string, idx int) ([]byte, error)
+	SaveUploadChunk(
+		ctx context.Context,
+		uploadID string,
+		idx int,
+		data []byte,
+		size int,
+		offset int64,
+		newOffset int64,
+		encryptedHashState []byte,
+	) (bool, error)
+	CompleteUpload(ctx context.Context, upload models.Upload, event models.AuditEvent) (int, error)
+	DeleteUpload(ctx context.Context, id string) error
+}
+
+type UploadEncryptor interface {
+	ChunkEncryptor
+	Decrypt(ciphertext []byte, encryptedKey []byte) ([]byte, error)
+}
+
+// UploadService implements resumable bin data uploads. Uploaded content
+// is stored in encrypted chunks of ChunkSize, so on completion the chunks
+// are moved to the secret without re-encryption.
+type UploadService struct {
+	storage    UploadStorage
+	encryptor  UploadEncryptor
+	randGen    RandGen
+	authorizer SecretAuthorizer
+	maxSize    int64
+}
+
+func NewUploadService(
+	storage UploadStorage,
+	encryptor UploadEncryptor,
+	randGen RandGen,
+	authorizer SecretAuthorizer,
+	maxSize int64) UploadService {
+
+	return UploadService{
+		storage:    storage,
+		encryptor:  encryptor,
+		randGen:    randGen,
+		authorizer: authorizer,
+		maxSize:    maxSize,
+	}
+}
+
+// Create starts an upload of a new bin data secret or, if secret is not
+// nil, of new content of the secret. The upload can not be completed if
+// the secret changes in the meantime. The secret metadata is kept if
+// metadata is nil. A new secret is created in the vault if vaultID is
+// not zero.
+func (srv UploadService) Create(
+	ctx context.Context,
+	userID int,
+	vaultID int,
+	length int64,
+	description string,
+	filename string,
+	metadata models.Metadata,
+	secret *models.Secret) (models.Upload, error) {
+
+	if length > srv.maxSize {
+		return models.Upload{}, ErrBinDataTooLarge
+	}
+	upload := models.Upload{
+		UserID:      userID,
+		Description: description,
+		Length:      length,
+		ExpiresAt:   time.Now().Add(UploadTTL),
+	}
+	if secret == nil && vaultID != 0 {
+		if err := srv.authorizer.AuthorizeVault(ctx, userID, vaultID, models.SecretWriteAccess); err != nil {
+			return upload, err
+		}
+		upload.VaultID = vaultID
+	}
+	if secret != nil {
+		if err := srv.authorizer.Authorize(ctx, userID, *secret, models.SecretWriteAccess); err != nil {
+			return upload, err
+		}
+		if secret.SecretType != models.BinDataSecret {
+			return upload, ErrWrongSecretType
+		}
+		upload.SecretID = secret.ID
+		upload.SecretVersion = secret.Version
+	}
+
+	id, err := srv.randGen.Gen(16)
+	if err != nil {
+		return upload, fmt.Errorf("failed to generate upload id: %w", err)
+	}
+	upload.ID = hex.EncodeToString(id)
+	upload.EncryptedKey, err = srv.encryptor.GenerateKey()
+	if err != nil {
+		return upload, fmt.Errorf("failed to generate key: %w", err)
+	}
+	upload.EncryptedData, err = encryptBinData(srv.encryptor, upload.EncryptedKey, filename, length, nil)
+	if err != nil {
+		return upload, err
+	}
+	if metadata == nil && secret != nil {
+		// the upload content is encrypted with a new key, so the current
+		// metadata is encrypted with it as well
+		metadata, err = decryptMetadata(srv.encryptor, *secret)
+		if err != nil {
+			return upload, err
+		}
+	}
+	upload.EncryptedMetadata, err = encryptMetadata(srv.encryptor, metadata, upload.EncryptedKey)
+	if err != nil {
+		return upload, err
+	}
+	if err := srv.storage.CreateUpload(ctx, upload); err != nil {
+		return upload, err
+	}
+	if length == 0 {
+		return srv.complete(ctx, upload, sha256.New().Sum(nil))
+	}
+
+	return upload, nil
+}
+
+func (srv UploadService) Find(ctx context.Context, userID int, id string) (models.Upload, error) {
+	upload, err := srv.storage.FindUpload(ctx, id)
+	if err != nil {
+		return upload, err
+	}
+	if upload.UserID != userID {
+		return models.Upload{}, ErrNoPermission{UserID: userID, SecretID: upload.SecretID}
+	}
+
+	return upload, nil
+}
+
+// Append saves content starting at offset, which must be equal to the
+// upload offset. Content read before an error is kept, so the upload can be
+// resumed from the returned upload offset. The upload is completed once
+// all of its content has been received.
+func (srv UploadService) Append(
+	ctx context.Context,
+	upload models.Upload,
+	offset int64,
+	content io.Reader) (models.Upload, error) {
+
+	if offset != upload.Offset {
+		return upload, ErrUploadOffsetMismatch
+	}
+	if upload.Completed {
+		return upload, nil
+	}
+	chunkCipher, err := srv.encryptor.NewChunkCipher(upload.EncryptedKey)
+	if err != nil {
+		return upload, fmt.Errorf("failed to create chunk cipher: %w", err)
+	}
+	contentHash, err := srv.restoreHash(upload)
+	if err != nil {
+		return upload, err
+	}
+
+	content = io.LimitReader(content, upload.Length-upload.Offset)
+	buf := make([]byte, ChunkSize)
+	for upload.Offset < upload.Length {
+		idx := int(upload.Offset / ChunkSize)
+		filled := int(upload.Offset % ChunkSize)
+		if filled > 0 {
+			// the last chunk is incomplete, it is replaced by a chunk with new content appended
+			data, err := srv.storage.FindUploadChunk(ctx, upload.ID, idx)
+			if err != nil {
+				return upload, err
+			}
+			chunk, err := chunkCipher.Open(idx, data)
+			if err != nil {
+				return upload, fmt.Errorf("failed to decrypt chunk %d: %w", idx, err)
+			}
+			copy(buf, chunk)
+		}
+
+		n, readErr := io.ReadFull(content, buf[filled:])
+		if n > 0 {
+			sealedChunk, err := chunkCipher.Seal(idx, buf[:filled+n])
+			if err != nil {
+				return upload, fmt.Errorf("failed to encrypt chunk: %w", err)
+			}
+			contentHash.Write(buf[filled : filled+n])
+			encryptedHashState, err := srv.encryptHashState(upload, contentHash)
+			if err != nil {
+				return upload, err
+			}
+			newOffset := upload.Offset + int64(n)
+			ok, err := srv.storage.SaveUploadChunk(
+				ctx,
+				upload.ID,
+				idx,
+				sealedChunk,
+				filled+n,
+				upload.Offset,
+				newOffset,
+				encryptedHashState,
+			)
+			if err != nil {
+				return upload, err
+			}
+			if !ok {
+				return upload, ErrUploadOffsetMismatch
+			}
+			upload.Offset = newOffset
+			upload.EncryptedHashState = encryptedHashState
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if readErr != nil {
+			return upload, fmt.Errorf("failed to read content: %w", readErr)
+		}
+	}
+
+	if upload.Offset == upload.Length {
+		return srv.complete(ctx, upload, contentHash.Sum(nil))
+	}
+
+	return upload, nil
+}
+
+func (srv UploadService) Delete(ctx context.Context, upload models.Upload) error {
+	return srv.storage.DeleteUpload(ctx, upload.ID)
+}
+
+// complete stores the content hash in the secret data and moves the upload
+// content to the secret.
+func (srv UploadService) complete(ctx context.Context, upload models.Upload, contentHash []byte) (models.Upload, error) {
+	binDataBytes, err := srv.encryptor.Decrypt(upload.EncryptedData, upload.EncryptedKey)
+	if err != nil {
+		return upload, fmt.Errorf("failed to decrypt upload data: %w", err)
+	}
+	binData := &models.BinData{}
+	if err := binData.Unmarshall(binDataBytes); err != nil {
+		return upload, fmt.Errorf("failed to unmarshall upload data: %w", err)
+	}
+	upload.EncryptedData, err = encryptBinData(
+		srv.encryptor,
+		upload.EncryptedKey,
+		binData.Filename,
+		binData.Size,
+		contentHash,
+	)
+	if err != nil {
+		return upload, err
+	}
+
+	action := models.AuditCreate
+	if upload.SecretID != 0 {
+		action = models.AuditUpdate
+	}
+	secretID, err := srv.storage.CompleteUpload(ctx, upload, newAuditEvent(ctx, upload.UserID, action))
+	if err != nil {
+		return upload, fmt.Errorf("failed to complete upload: %w", err)
+	}
+	upload.SecretID = secretID
+	upload.Completed = true
+
+	return upload, nil
+}
+
+// restoreHash returns the content hash with the state saved along with the
+// last chunk.
+func (srv UploadService) restoreHash(upload models.Upload) (hash.Hash, error) {
+	contentHash := sha256.New()
+	if len(upload.EncryptedHashState) == 0 {
+		return contentHash, nil
+	}
+	state, err := srv.encryptor.Decrypt(upload.EncryptedHashState, upload.EncryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt hash state: %w", err)
+	}
+	if err := contentHash.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		return nil, fmt.Errorf("failed to restore hash state: %w", err)
+	}
+
+	return contentHash, nil
+}
+
+// encryptHashState encrypts the content hash state, since it contains
+// the unprocessed tail of the content.
+func (srv UploadService) encryptHashState(upload models.Upload, contentHash hash.Hash) ([]byte, error) {
+	state, err := contentHash.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save hash state: %w", err)
+	}
+
+	return srv.encryptor.ReEncrypt(state, upload.EncryptedKey)
+}
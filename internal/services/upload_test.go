@@ -0,0 +1,246 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/ilya-burinskiy/gophkeeper/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type uploadStorageMock struct {
+	mock.Mock
+	chunks    map[int][]byte
+	completed models.Upload
+}
+
+func (m *uploadStorageMock) CreateUpload(ctx context.Context, upload models.Upload) error {
+	args := m.Called(ctx, upload)
+	return args.Error(0)
+}
+
+func (m *uploadStorageMock) FindUpload(ctx context.Context, id string) (models.Upload, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Upload), args.Error(1)
+}
+
+func (m *uploadStorageMock) FindUploadChunk(ctx context.Context, uploadID string, idx int) ([]byte, error) {
+	return m.chunks[idx], nil
+}
+
+func (m *uploadStorageMock) SaveUploadChunk(
+	ctx context.Context,
+	uploadID string,
+	idx int,
+	data []byte,
+	size int,
+	offset int64,
+	newOffset int64,
+	encryptedHashState []byte) (bool, error) {
+
+	args := m.Called(ctx, uploadID, idx, size, offset, newOffset)
+	if args.Bool(0) {
+		m.chunks[idx] = data
+	}
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *uploadStorageMock) CompleteUpload(
+	ctx context.Context,
+	upload models.Upload,
+	event models.AuditEvent) (int, error) {
+
+	m.completed = upload
+	args := m.Called(ctx, upload.ID, event)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *uploadStorageMock) DeleteUpload(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func TestCreateUpload(t *testing.T) {
+	encryptor, err := services.NewDataEncryptor(
+		services.CryptoRandGen{},
+		[]byte{
+			239, 140, 200, 100, 31, 6, 108, 25, 158, 54, 149, 16, 138, 90, 157, 144,
+			53, 144, 0, 193, 140, 107, 205, 41, 70, 167, 116, 218, 39, 58, 207, 240,
+		},
+	)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name              string
+		length            int64
+		secret            *models.Secret
+		wantCompleted     bool
+		wantSecretID      int
+		wantSecretVersion int
+		wantErrMsg        string
+	}{
+		{
+			name:   "creates upload of new secret",
+			length: 10,
+		},
+		{
+			name:              "creates upload of secret content",
+			length:            10,
+			secret:            &models.Secret{ID: 2, UserID: 1, SecretType: models.BinDataSecret, Version: 4},
+			wantSecretID:      2,
+			wantSecretVersion: 4,
+		},
+		{
+			name:          "completes empty upload",
+			length:        0,
+			wantCompleted: true,
+			wantSecretID:  3,
+		},
+		{
+			name:       "returns error if length exceeds limit",
+			length:     101,
+			wantErrMsg: services.ErrBinDataTooLarge.Error(),
+		},
+		{
+			name:       "returns error if user is not secret owner",
+			length:     10,
+			secret:     &models.Secret{ID: 2, UserID: 2, SecretType: models.BinDataSecret},
+			wantErrMsg: services.ErrNoPermission{UserID: 1, SecretID: 2}.Error(),
+		},
+		{
+			name:       "returns error if secret is not bin data",
+			length:     10,
+			secret:     &models.Secret{ID: 2, UserID: 1, SecretType: models.CredentialsSecret},
+			wantErrMsg: services.ErrWrongSecretType.Error(),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &uploadStorageMock{chunks: make(map[int][]byte)}
+			store.On("CreateUpload", mock.Anything, mock.Anything).Return(nil)
+			store.On("CompleteUpload", mock.Anything, mock.Anything, mock.Anything).Return(3, nil)
+			uploadSrv := services.NewUploadService(store, encryptor, services.CryptoRandGen{}, ownerOnlyACL(), 100)
+
+			upload, err := uploadSrv.Create(context.TODO(), 1, 0, tc.length, "description", "file.txt", nil, tc.secret)
+			if tc.wantErrMsg != "" {
+				assert.EqualError(t, err, tc.wantErrMsg)
+				store.AssertNotCalled(t, "CreateUpload", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, upload.ID, 32)
+			assert.Equal(t, tc.length, upload.Length)
+			assert.Equal(t, tc.wantCompleted, upload.Completed)
+			assert.Equal(t, tc.wantSecretID, upload.SecretID)
+			assert.Equal(t, tc.wantSecretVersion, upload.SecretVersion)
+
+			binData, err := services.NewShowSecretService(encryptor, ownerOnlyACL(), noAuditLog{}).Show(
+				context.TODO(),
+				1,
+				models.Secret{
+					UserID:        1,
+					SecretType:    models.BinDataSecret,
+					EncryptedData: upload.EncryptedData,
+					EncryptedKey:  upload.EncryptedKey,
+				},
+			)
+			require.NoError(t, err)
+			wantBinData := &models.BinData{Filename: "file.txt", Size: tc.length}
+			if tc.wantCompleted {
+				contentHash := sha256.Sum256(nil)
+				wantBinData.SHA256 = contentHash[:]
+			}
+			assert.Equal(t, wantBinData, binData)
+		})
+	}
+}
+
+func TestAppendUpload(t *testing.T) {
+	encryptor, err := services.NewDataEncryptor(
+		services.CryptoRandGen{},
+		[]byte{
+			239, 140, 200, 100, 31, 6, 108, 25, 158, 54, 149, 16, 138, 90, 157, 144,
+			53, 144, 0, 193, 140, 107, 205, 41, 70, 167, 116, 218, 39, 58, 207, 240,
+		},
+	)
+	require.NoError(t, err)
+	content := bytes.Repeat([]byte("0123456789"), services.ChunkSize/5)
+	length := int64(len(content))
+
+	t.Run("resumes upload from incomplete chunk", func(t *testing.T) {
+		store := &uploadStorageMock{chunks: make(map[int][]byte)}
+		store.On("CreateUpload", mock.Anything, mock.Anything).Return(nil)
+		store.On("SaveUploadChunk", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(true, nil)
+		store.On("CompleteUpload", mock.Anything, mock.Anything, mock.Anything).Return(1, nil)
+		uploadSrv := services.NewUploadService(store, encryptor, services.CryptoRandGen{}, ownerOnlyACL(), length)
+		upload, err := uploadSrv.Create(context.TODO(), 1, 0, length, "", "file", nil, nil)
+		require.NoError(t, err)
+
+		partLen := int64(services.ChunkSize + 10)
+		upload, err = uploadSrv.Append(context.TODO(), upload, 0, bytes.NewReader(content[:partLen]))
+		require.NoError(t, err)
+		assert.Equal(t, partLen, upload.Offset)
+		assert.False(t, upload.Completed)
+
+		upload, err = uploadSrv.Append(context.TODO(), upload, partLen, bytes.NewReader(content[partLen:]))
+		require.NoError(t, err)
+		assert.Equal(t, length, upload.Offset)
+		assert.True(t, upload.Completed)
+		assert.Equal(t, 1, upload.SecretID)
+		store.AssertCalled(t, "SaveUploadChunk", mock.Anything, upload.ID, 1, 10, partLen-10, partLen)
+		store.AssertCalled(t, "SaveUploadChunk", mock.Anything, upload.ID, 1, int(length-services.ChunkSize), partLen, length)
+
+		chunkCipher, err := encryptor.NewChunkCipher(upload.EncryptedKey)
+		require.NoError(t, err)
+		uploadedContent := bytes.Buffer{}
+		for idx := 0; idx < len(store.chunks); idx++ {
+			chunk, err := chunkCipher.Open(idx, store.chunks[idx])
+			require.NoError(t, err)
+			uploadedContent.Write(chunk)
+		}
+		assert.Equal(t, content, uploadedContent.Bytes())
+
+		binData, err := services.NewShowSecretService(encryptor, ownerOnlyACL(), noAuditLog{}).Show(
+			context.TODO(),
+			1,
+			models.Secret{
+				UserID:        1,
+				SecretType:    models.BinDataSecret,
+				EncryptedData: store.completed.EncryptedData,
+				EncryptedKey:  store.completed.EncryptedKey,
+			},
+		)
+		require.NoError(t, err)
+		contentHash := sha256.Sum256(content)
+		assert.Equal(t, &models.BinData{Filename: "file", Size: length, SHA256: contentHash[:]}, binData)
+	})
+
+	t.Run("returns error if offset does not match", func(t *testing.T) {
+		store := &uploadStorageMock{chunks: make(map[int][]byte)}
+		uploadSrv := services.NewUploadService(store, encryptor, services.CryptoRandGen{}, ownerOnlyACL(), length)
+		upload := models.Upload{ID: "id", Length: length, Offset: 10}
+
+		_, err := uploadSrv.Append(context.TODO(), upload, 0, bytes.NewReader(content))
+		assert.ErrorIs(t, err, services.ErrUploadOffsetMismatch)
+	})
+
+	t.Run("returns error if offset has been changed concurrently", func(t *testing.T) {
+		store := &uploadStorageMock{chunks: make(map[int][]byte)}
+		store.On("CreateUpload", mock.Anything, mock.Anything).Return(nil)
+		store.On("SaveUploadChunk", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(false, nil)
+		uploadSrv := services.NewUploadService(store, encryptor, services.CryptoRandGen{}, ownerOnlyACL(), length)
+		upload, err := uploadSrv.Create(context.TODO(), 1, 0, length, "", "file", nil, nil)
+		require.NoError(t, err)
+
+		upload, err = uploadSrv.Append(context.TODO(), upload, 0, bytes.NewReader(content))
+		assert.ErrorIs(t, err, services.ErrUploadOffsetMismatch)
+		assert.Equal(t, int64(0), upload.Offset)
+	})
+}
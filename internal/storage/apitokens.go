@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const apiTokenColumns = `"id", "user_id", "name", "read_only", "secret_types", "secret_ids",
+		        "expires_at", "last_used_at", "created_at"`
+
+func (db *DBStorage) CreateAPIToken(
+	ctx context.Context,
+	token models.APIToken,
+	tokenHash []byte) (models.APIToken, error) {
+
+	secretTypes := make([]int, len(token.Scope.SecretTypes))
+	for i, secretType := range token.Scope.SecretTypes {
+		secretTypes[i] = int(secretType)
+	}
+	secretIDs := token.Scope.SecretIDs
+	if secretIDs == nil {
+		secretIDs = []int{}
+	}
+	row := db.pool.QueryRow(
+		ctx,
+		`INSERT INTO "api_tokens" ("user_id", "name", "token_hash", "read_only", "secret_types", "secret_ids", "expires_at")
+		 VALUES (@userID, @name, @tokenHash, @readOnly, @secretTypes, @secretIDs, @expiresAt)
+		 RETURNING "id", "created_at"`,
+		pgx.NamedArgs{
+			"userID":      token.UserID,
+			"name":        token.Name,
+			"tokenHash":   tokenHash,
+			"readOnly":    token.Scope.ReadOnly,
+			"secretTypes": secretTypes,
+			"secretIDs":   secretIDs,
+			"expiresAt":   nullableTime(token.ExpiresAt),
+		},
+	)
+	if err := row.Scan(&token.ID, &token.CreatedAt); err != nil {
+		return token, fmt.Errorf("failed to create api token: %w", err)
+	}
+
+	return token, nil
+}
+
+// FindAPIToken returns ErrAPITokenNotFound if there is no token with the
+// hash, expired tokens are returned too.
+func (db *DBStorage) FindAPIToken(ctx context.Context, tokenHash []byte) (models.APIToken, error) {
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT `+apiTokenColumns+`
+		 FROM "api_tokens"
+		 WHERE "token_hash" = $1`,
+		tokenHash,
+	)
+	if err != nil {
+		return models.APIToken{}, fmt.Errorf("failed to find api token: %w", err)
+	}
+	token, err := pgx.CollectOneRow(rows, scanAPIToken)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return token, ErrAPITokenNotFound{}
+		}
+		return token, fmt.Errorf("failed to find api token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (db *DBStorage) ListUserAPITokens(ctx context.Context, userID int) ([]models.APIToken, error) {
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT `+apiTokenColumns+`
+		 FROM "api_tokens"
+		 WHERE "user_id" = $1
+		 ORDER BY "id"`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch api tokens: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, scanAPIToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch api tokens: %w", err)
+	}
+
+	return result, nil
+}
+
+// DeleteUserAPIToken returns ErrAPITokenNotFound if the token does not
+// exist or belongs to another user.
+func (db *DBStorage) DeleteUserAPIToken(ctx context.Context, userID int, id int) error {
+	tag, err := db.pool.Exec(ctx, `DELETE FROM "api_tokens" WHERE "id" = $1 AND "user_id" = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete api token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPITokenNotFound{ID: id}
+	}
+
+	return nil
+}
+
+// TouchAPIToken updates the last use time of the token at most once a
+// minute.
+func (db *DBStorage) TouchAPIToken(ctx context.Context, id int) error {
+	_, err := db.pool.Exec(
+		ctx,
+		`UPDATE "api_tokens" SET "last_used_at" = now()
+		 WHERE "id" = $1 AND ("last_used_at" IS NULL OR "last_used_at" < now() - interval '1 minute')`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update api token: %w", err)
+	}
+
+	return nil
+}
+
+func scanAPIToken(row pgx.CollectableRow) (models.APIToken, error) {
+	var token models.APIToken
+	var secretTypes []int
+	var expiresAt, lastUsedAt *time.Time
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.Scope.ReadOnly,
+		&secretTypes,
+		&token.Scope.SecretIDs,
+		&expiresAt,
+		&lastUsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return token, err
+	}
+	for _, secretType := range secretTypes {
+		token.Scope.SecretTypes = append(token.Scope.SecretTypes, models.SecretType(secretType))
+	}
+	if len(token.Scope.SecretIDs) == 0 {
+		token.Scope.SecretIDs = nil
+	}
+	if expiresAt != nil {
+		token.ExpiresAt = *expiresAt
+	}
+	if lastUsedAt != nil {
+		token.LastUsedAt = *lastUsedAt
+	}
+
+	return token, nil
+}
+
+func nullableTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateAuditEvents appends events to the audit log in a transaction of
+// their own, it is used for reads, which change nothing.
+func (db *DBStorage) CreateAuditEvents(ctx context.Context, events []models.AuditEvent) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := appendAuditEvents(ctx, tx, events...); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to create audit events: %w", err)
+	}
+
+	return nil
+}
+
+// ListAuditEvents returns up to filter.Limit audit events visible to the
+// user, the latest first. The user sees own actions, actions with personal
+// secrets of the user and, for organization admins, actions with secrets
+// of the organization vaults.
+func (db *DBStorage) ListAuditEvents(
+	ctx context.Context,
+	userID int,
+	filter models.AuditFilter) ([]models.AuditEvent, error) {
+
+	args := pgx.NamedArgs{
+		"userID":    userID,
+		"adminRole": models.OrganizationAdminRole,
+		"limit":     filter.Limit,
+	}
+	query := `SELECT ` + auditEventColumns + `
+		 FROM "audit_events"
+		 WHERE ("user_id" = @userID
+		        OR ("vault_id" IS NULL AND "owner_id" = @userID)
+		        OR "vault_id" IN (
+		          SELECT "vaults"."id" FROM "vaults"
+		          JOIN "organization_members"
+		            ON "organization_members"."organization_id" = "vaults"."organization_id"
+		          WHERE "organization_members"."user_id" = @userID
+		            AND "organization_members"."role" = @adminRole
+		            AND "organization_members"."accepted_at" IS NOT NULL
+		        ))`
+	if !filter.From.IsZero() {
+		query += ` AND "created_at" >= @from`
+		args["from"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		query += ` AND "created_at" < @to`
+		args["to"] = filter.To
+	}
+	if filter.SecretID != 0 {
+		query += ` AND "secret_id" = @secretID`
+		args["secretID"] = filter.SecretID
+	}
+	query += ` ORDER BY "id" DESC LIMIT @limit`
+
+	rows, err := db.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch audit events: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.AuditEvent, error) {
+		return scanAuditEvent(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch audit events: %w", err)
+	}
+
+	return result, nil
+}
+
+// StreamAuditEvents calls fn for every audit event in the order they were
+// created, rows are read one by one.
+func (db *DBStorage) StreamAuditEvents(ctx context.Context, fn func(models.AuditEvent) error) error {
+	rows, err := db.pool.Query(ctx, `SELECT `+auditEventColumns+` FROM "audit_events" ORDER BY "id"`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch audit events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to fetch audit events: %w", err)
+	}
+
+	return nil
+}
+
+const auditEventColumns = `"id", "user_id", "action", "secret_id", "owner_id", COALESCE("vault_id", 0),
+		        "client_ip", "user_agent", "created_at", "prev_hash", "hash"`
+
+func scanAuditEvent(row pgx.Row) (models.AuditEvent, error) {
+	var event models.AuditEvent
+	err := row.Scan(
+		&event.ID,
+		&event.UserID,
+		&event.Action,
+		&event.SecretID,
+		&event.OwnerID,
+		&event.VaultID,
+		&event.ClientIP,
+		&event.UserAgent,
+		&event.CreatedAt,
+		&event.PrevHash,
+		&event.Hash,
+	)
+
+	return event, err
+}
+
+// appendSecretAuditEvent appends the event of the change of the secret in
+// the transaction of the change, so the change is never committed without
+// its event. The event is completed with the secret owner and vault.
+// It should be the last statement of tx, since the chain head stays locked
+// until tx ends.
+func appendSecretAuditEvent(ctx context.Context, tx pgx.Tx, event models.AuditEvent, secretID int) error {
+	event.SecretID = secretID
+	err := tx.QueryRow(
+		ctx,
+		`SELECT "user_id", COALESCE("vault_id", 0) FROM "secrets" WHERE "id" = $1`,
+		secretID,
+	).Scan(&event.OwnerID, &event.VaultID)
+	if err != nil {
+		return fmt.Errorf("failed to find audited secret: %w", err)
+	}
+
+	return appendAuditEvents(ctx, tx, event)
+}
+
+// appendAuditEvents chains events to the last event of the audit log. The
+// chain head row is locked until tx ends, so concurrent events are chained
+// one after another and their IDs follow the chain.
+func appendAuditEvents(ctx context.Context, tx pgx.Tx, events ...models.AuditEvent) error {
+	var prevHash []byte
+	err := tx.QueryRow(ctx, `SELECT "hash" FROM "audit_chain_head" FOR UPDATE`).Scan(&prevHash)
+	if err != nil {
+		return fmt.Errorf("failed to lock audit chain head: %w", err)
+	}
+
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	for _, event := range events {
+		row := tx.QueryRow(ctx, `SELECT nextval('audit_events_id_seq')`)
+		if err := row.Scan(&event.ID); err != nil {
+			return fmt.Errorf("failed to generate audit event id: %w", err)
+		}
+		event.CreatedAt = createdAt
+		event.PrevHash = prevHash
+		event.Hash = event.ComputeHash()
+		_, err := tx.Exec(
+			ctx,
+			`INSERT INTO "audit_events" (
+			   "id", "user_id", "action", "secret_id", "owner_id", "vault_id",
+			   "client_ip", "user_agent", "created_at", "prev_hash", "hash"
+			 ) VALUES (
+			   @id, @userID, @action, @secretID, @ownerID, @vaultID,
+			   @clientIP, @userAgent, @createdAt, @prevHash, @hash
+			 )`,
+			pgx.NamedArgs{
+				"id":        event.ID,
+				"userID":    event.UserID,
+				"action":    event.Action,
+				"secretID":  event.SecretID,
+				"ownerID":   event.OwnerID,
+				"vaultID":   nullableID(event.VaultID),
+				"clientIP":  event.ClientIP,
+				"userAgent": event.UserAgent,
+				"createdAt": event.CreatedAt,
+				"prevHash":  event.PrevHash,
+				"hash":      event.Hash,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create audit event: %w", err)
+		}
+		prevHash = event.Hash
+	}
+	_, err = tx.Exec(ctx, `UPDATE "audit_chain_head" SET "hash" = $1`, prevHash)
+	if err != nil {
+		return fmt.Errorf("failed to update audit chain head: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,358 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateChunkedSecret creates a secret with content stored in chunks.
+// writeContent stores chunks with writeChunk and returns encrypted secret
+// data, which may depend on the written content. Size is the size of the
+// chunk plaintext, the secret size is the size of the whole content.
+// Chunks are staged before the transaction, so it is not held open while
+// the content is streamed, and are attached to the secret when it is inserted.
+func (db *DBStorage) CreateChunkedSecret(
+	ctx context.Context,
+	userID int,
+	vaultID int,
+	secretType models.SecretType,
+	description string,
+	encryptedKey []byte,
+	encryptedMetadata []byte,
+	writeContent func(writeChunk func(idx int, data []byte, size int) error) ([]byte, error),
+	event models.AuditEvent) (models.Secret, error) {
+
+	secret := models.Secret{
+		UserID:            userID,
+		VaultID:           vaultID,
+		SecretType:        secretType,
+		Description:       description,
+		EncryptedKey:      encryptedKey,
+		EncryptedMetadata: encryptedMetadata,
+	}
+	chunks, encryptedData, err := db.stageChunks(ctx, writeContent)
+	if err != nil {
+		return secret, err
+	}
+	if err := db.createChunkedSecret(ctx, &secret, encryptedData, chunks, event); err != nil {
+		db.discardChunks(chunks)
+		return secret, err
+	}
+	secret.EncryptedData = encryptedData
+
+	return secret, nil
+}
+
+func (db *DBStorage) createChunkedSecret(
+	ctx context.Context,
+	secret *models.Secret,
+	encryptedData []byte,
+	chunks []stagedChunk,
+	event models.AuditEvent) error {
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	row := tx.QueryRow(
+		ctx,
+		`INSERT INTO "secrets" (
+		   "user_id", "vault_id", "type", "description", "encrypted_data", "size", "encrypted_key", "encrypted_metadata"
+		 ) VALUES (
+		   @userID, @vaultID, @secretType, @description, @encryptedData, @size, @encryptedKey, @encryptedMetadata
+		 ) RETURNING "id", "revision"`,
+		pgx.NamedArgs{
+			"userID":            secret.UserID,
+			"vaultID":           nullableID(secret.VaultID),
+			"secretType":        secret.SecretType,
+			"description":       secret.Description,
+			"encryptedData":     encryptedData,
+			"size":              contentSize(chunks),
+			"encryptedKey":      secret.EncryptedKey,
+			"encryptedMetadata": secret.EncryptedMetadata,
+		},
+	)
+	if err := row.Scan(&secret.ID, &secret.Version); err != nil {
+		return fmt.Errorf("failed to create secret: %w", err)
+	}
+	if err := attachChunks(ctx, tx, secret.ID, chunks); err != nil {
+		return err
+	}
+	if err := appendSecretAuditEvent(ctx, tx, event, secret.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to create secret: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateChunkedSecret replaces secret content chunks if the secret version
+// is still version and returns the new version. Metadata is kept if
+// encryptedMetadata is nil. Chunks are staged as in CreateChunkedSecret, so
+// the secret is locked and its version is checked only while the staged
+// chunks replace the current ones.
+func (db *DBStorage) UpdateChunkedSecret(
+	ctx context.Context,
+	secretID int,
+	version int,
+	description string,
+	encryptedMetadata []byte,
+	writeContent func(writeChunk func(idx int, data []byte, size int) error) ([]byte, error),
+	event models.AuditEvent) (int, error) {
+
+	chunks, encryptedData, err := db.stageChunks(ctx, writeContent)
+	if err != nil {
+		return 0, err
+	}
+	newVersion, err := db.updateChunkedSecret(
+		ctx,
+		secretID,
+		version,
+		description,
+		encryptedData,
+		encryptedMetadata,
+		chunks,
+		event,
+	)
+	if err != nil {
+		db.discardChunks(chunks)
+		return 0, err
+	}
+
+	return newVersion, nil
+}
+
+func (db *DBStorage) updateChunkedSecret(
+	ctx context.Context,
+	secretID int,
+	version int,
+	description string,
+	encryptedData []byte,
+	encryptedMetadata []byte,
+	chunks []stagedChunk,
+	event models.AuditEvent) (int, error) {
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := archiveSecret(ctx, tx, secretID, version); err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM "secret_chunks" WHERE "secret_id" = $1`, secretID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete secret chunks: %w", err)
+	}
+	if err := attachChunks(ctx, tx, secretID, chunks); err != nil {
+		return 0, err
+	}
+	var newVersion int
+	err = tx.QueryRow(
+		ctx,
+		`UPDATE "secrets"
+		 SET "encrypted_data" = $1, "size" = $2, "description" = $3,
+		     "encrypted_metadata" = COALESCE($4, "encrypted_metadata"), "updated_at" = now()
+		 WHERE "id" = $5
+		 RETURNING "revision"`,
+		encryptedData, contentSize(chunks), description, encryptedMetadata, secretID,
+	).Scan(&newVersion)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update secret: %w", err)
+	}
+	if err := appendSecretAuditEvent(ctx, tx, event, secretID); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to update secret: %w", err)
+	}
+
+	return newVersion, nil
+}
+
+// StreamSecretChunks calls fn for every secret chunk in order.
+func (db *DBStorage) StreamSecretChunks(
+	ctx context.Context,
+	secretID int,
+	fn func(idx int, data []byte) error) error {
+
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT "secret_chunks"."idx", "secret_chunk_data"."data"
+		 FROM "secret_chunks"
+		 JOIN "secret_chunk_data" ON "secret_chunk_data"."id" = "secret_chunks"."data_id"
+		 WHERE "secret_chunks"."secret_id" = $1
+		 ORDER BY "secret_chunks"."idx"`,
+		secretID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to fetch secret chunks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var idx int
+		var data []byte
+		if err := rows.Scan(&idx, &data); err != nil {
+			return fmt.Errorf("failed to scan secret chunk: %w", err)
+		}
+		if err := fn(idx, data); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to fetch secret chunks: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DBStorage) FindSecretChunk(ctx context.Context, secretID int, idx int) ([]byte, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT "secret_chunk_data"."data"
+		 FROM "secret_chunks"
+		 JOIN "secret_chunk_data" ON "secret_chunk_data"."id" = "secret_chunks"."data_id"
+		 WHERE "secret_chunks"."secret_id" = $1 AND "secret_chunks"."idx" = $2`,
+		secretID, idx,
+	)
+	var data []byte
+	if err := row.Scan(&data); err != nil {
+		return nil, fmt.Errorf("failed to find secret chunk: %w", err)
+	}
+
+	return data, nil
+}
+
+// reserveSecretID returns the ID of the secret inserted later in tx and
+// defers the check of its chunks until the end of tx, so chunks can be
+// written before the secret row.
+func reserveSecretID(ctx context.Context, tx pgx.Tx) (int, error) {
+	_, err := tx.Exec(ctx, `SET CONSTRAINTS "secret_chunks_secret_id_fkey" DEFERRED`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to defer secret chunks constraint: %w", err)
+	}
+	var id int
+	err = tx.QueryRow(ctx, `SELECT nextval(pg_get_serial_sequence('secrets', 'id'))`).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve secret id: %w", err)
+	}
+
+	return id, nil
+}
+
+// stagedChunkDataTTL is the time after which chunk data not attached to
+// a secret is deleted, it must exceed the time of the longest upload.
+const stagedChunkDataTTL = 24 * time.Hour
+
+// stagedChunk is a chunk whose data is stored but not attached to a secret.
+type stagedChunk struct {
+	idx    int
+	size   int
+	dataID int64
+}
+
+// stageChunks stores chunk data written by writeContent, every chunk in its
+// own statement, so neither a transaction nor a connection is held while
+// the content is streamed. Data of a failed write is deleted.
+func (db *DBStorage) stageChunks(
+	ctx context.Context,
+	writeContent func(writeChunk func(idx int, data []byte, size int) error) ([]byte, error)) ([]stagedChunk, []byte, error) {
+
+	var chunks []stagedChunk
+	encryptedData, err := writeContent(func(idx int, data []byte, size int) error {
+		chunk := stagedChunk{idx: idx, size: size}
+		err := db.pool.QueryRow(
+			ctx,
+			`INSERT INTO "secret_chunk_data" ("data") VALUES ($1) RETURNING "id"`,
+			data,
+		).Scan(&chunk.dataID)
+		if err != nil {
+			return fmt.Errorf("failed to save secret chunk: %w", err)
+		}
+		chunks = append(chunks, chunk)
+
+		return nil
+	})
+	if err != nil {
+		db.discardChunks(chunks)
+		return nil, nil, err
+	}
+
+	return chunks, encryptedData, nil
+}
+
+// attachChunks makes the staged chunks the content of the secret.
+func attachChunks(ctx context.Context, tx pgx.Tx, secretID int, chunks []stagedChunk) error {
+	idxs := make([]int, len(chunks))
+	sizes := make([]int, len(chunks))
+	dataIDs := make([]int64, len(chunks))
+	for i, chunk := range chunks {
+		idxs[i], sizes[i], dataIDs[i] = chunk.idx, chunk.size, chunk.dataID
+	}
+	_, err := tx.Exec(
+		ctx,
+		`INSERT INTO "secret_chunks" ("secret_id", "idx", "size", "data_id")
+		 SELECT $1, "idx", "size", "data_id"
+		 FROM unnest($2::integer[], $3::integer[], $4::bigint[]) AS "chunks" ("idx", "size", "data_id")`,
+		secretID, idxs, sizes, dataIDs,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to attach secret chunks: %w", err)
+	}
+
+	return nil
+}
+
+// contentSize returns the plaintext size of the content stored in chunks.
+func contentSize(chunks []stagedChunk) int {
+	var size int
+	for _, chunk := range chunks {
+		size += chunk.size
+	}
+
+	return size
+}
+
+// discardChunks deletes data of chunks which were not attached. It does not
+// use the request context, which is likely the one canceled, and leaves the
+// data to DeleteStagedChunkData if it fails.
+func (db *DBStorage) discardChunks(chunks []stagedChunk) {
+	if len(chunks) == 0 {
+		return
+	}
+	dataIDs := make([]int64, len(chunks))
+	for i, chunk := range chunks {
+		dataIDs[i] = chunk.dataID
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	db.pool.Exec(ctx, `DELETE FROM "secret_chunk_data" WHERE "id" = ANY($1)`, dataIDs)
+}
+
+// DeleteStagedChunkData deletes chunk data which has not been attached to
+// a secret or a revision within stagedChunkDataTTL.
+func (db *DBStorage) DeleteStagedChunkData(ctx context.Context) (int64, error) {
+	tag, err := db.pool.Exec(
+		ctx,
+		`DELETE FROM "secret_chunk_data"
+		 WHERE "created_at" < $1
+		   AND NOT EXISTS (SELECT 1 FROM "secret_chunks" WHERE "data_id" = "secret_chunk_data"."id")
+		   AND NOT EXISTS (SELECT 1 FROM "secret_revision_chunks" WHERE "data_id" = "secret_chunk_data"."id")`,
+		time.Now().Add(-stagedChunkDataTTL),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete staged chunk data: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -5,6 +5,7 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
@@ -61,7 +62,7 @@ func (db *DBStorage) CreateUser(ctx context.Context, login string, encryptedPass
 func (db *DBStorage) FindUserByLogin(ctx context.Context, login string) (models.User, error) {
 	row := db.pool.QueryRow(
 		ctx,
-		`SELECT "id", "encrypted_password"
+		`SELECT "id", "encrypted_password", "totp_enabled_at" IS NOT NULL
 		 FROM "users"
 		 WHERE "login" = @login`,
 		pgx.NamedArgs{"login": login},
@@ -69,7 +70,7 @@ func (db *DBStorage) FindUserByLogin(ctx context.Context, login string) (models.
 	user := models.User{Login: login}
 	var id int
 	var encryptedPassword []byte
-	err := row.Scan(&id, &encryptedPassword)
+	err := row.Scan(&id, &encryptedPassword, &user.TOTPEnabled)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return user, ErrUserNotFound{User: user}
@@ -83,38 +84,63 @@ func (db *DBStorage) FindUserByLogin(ctx context.Context, login string) (models.
 	return user, nil
 }
 
+// CreateSecret creates a personal secret of the user or, if vaultID is not
+// zero, a secret of the vault created by the user. Size is the size of
+// the data plaintext. The audit event is appended along with the secret.
 func (db *DBStorage) CreateSecret(
 	ctx context.Context,
 	userID int,
+	vaultID int,
 	secretType models.SecretType,
 	description string,
 	encryptedData []byte,
-	encryptedKey []byte) (models.Secret, error) {
+	size int,
+	encryptedKey []byte,
+	encryptedMetadata []byte,
+	event models.AuditEvent) (models.Secret, error) {
 
-	row := db.pool.QueryRow(
+	secret := models.Secret{
+		UserID:            userID,
+		VaultID:           vaultID,
+		SecretType:        secretType,
+		Description:       description,
+		EncryptedData:     encryptedData,
+		EncryptedKey:      encryptedKey,
+		EncryptedMetadata: encryptedMetadata,
+	}
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return secret, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	row := tx.QueryRow(
 		ctx,
-		`INSERT INTO "secrets" ("user_id", "type", "description", "encrypted_data", "encrypted_key")
-		 VALUES (@userID, @secretType, @description, @encryptedData, @encryptedKey) RETURNING "id"`,
+		`INSERT INTO "secrets" (
+		   "user_id", "vault_id", "type", "description", "encrypted_data", "size", "encrypted_key", "encrypted_metadata"
+		 ) VALUES (
+		   @userID, @vaultID, @secretType, @description, @encryptedData, @size, @encryptedKey, @encryptedMetadata
+		 ) RETURNING "id", "revision"`,
 		pgx.NamedArgs{
-			"userID":        userID,
-			"secretType":    secretType,
-			"description":   description,
-			"encryptedData": encryptedData,
-			"encryptedKey":  encryptedKey,
+			"userID":            userID,
+			"vaultID":           nullableID(vaultID),
+			"secretType":        secretType,
+			"description":       description,
+			"encryptedData":     encryptedData,
+			"size":              size,
+			"encryptedKey":      encryptedKey,
+			"encryptedMetadata": encryptedMetadata,
 		},
 	)
-	var secretID int
-	secret := models.Secret{
-		UserID:        userID,
-		SecretType:    secretType,
-		Description:   description,
-		EncryptedData: encryptedData,
-		EncryptedKey:  encryptedKey,
+	if err := row.Scan(&secret.ID, &secret.Version); err != nil {
+		return secret, fmt.Errorf("failed to create secret: %w", err)
+	}
+	if err := appendSecretAuditEvent(ctx, tx, event, secret.ID); err != nil {
+		return secret, err
 	}
-	if err := row.Scan(&secretID); err != nil {
+	if err := tx.Commit(ctx); err != nil {
 		return secret, fmt.Errorf("failed to create secret: %w", err)
 	}
-	secret.ID = secretID
 
 	return secret, nil
 }
@@ -122,9 +148,10 @@ func (db *DBStorage) CreateSecret(
 func (db *DBStorage) FindSecretByID(ctx context.Context, id int) (models.Secret, error) {
 	row := db.pool.QueryRow(
 		ctx,
-		`SELECT "user_id", "type", "description", "encrypted_data", "encrypted_key"
+		`SELECT "user_id", "type", "description", "encrypted_data", "encrypted_key", "encrypted_metadata",
+		        COALESCE("folder_id", 0), COALESCE("vault_id", 0), "revision"
 		 FROM "secrets"
-		 WHERE "id" = $1`,
+		 WHERE "id" = $1 AND "deleted_at" IS NULL`,
 		id,
 	)
 	secret := models.Secret{ID: id}
@@ -134,6 +161,10 @@ func (db *DBStorage) FindSecretByID(ctx context.Context, id int) (models.Secret,
 		&secret.Description,
 		&secret.EncryptedData,
 		&secret.EncryptedKey,
+		&secret.EncryptedMetadata,
+		&secret.FolderID,
+		&secret.VaultID,
+		&secret.Version,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -145,66 +176,354 @@ func (db *DBStorage) FindSecretByID(ctx context.Context, id int) (models.Secret,
 	return secret, nil
 }
 
+// UpdateSecret replaces secret data if the secret version is still version
+// and returns the new version. Size is the size of the data plaintext.
+// Metadata is kept if encryptedMetadata is nil.
 func (db *DBStorage) UpdateSecret(
 	ctx context.Context,
 	secretID int,
+	version int,
 	description string,
-	newData []byte) error {
+	newData []byte,
+	size int,
+	encryptedMetadata []byte,
+	event models.AuditEvent) (int, error) {
 
-	_, err := db.pool.Exec(
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := archiveSecret(ctx, tx, secretID, version); err != nil {
+		return 0, err
+	}
+	var newVersion int
+	err = tx.QueryRow(
 		ctx,
-		`UPDATE "secrets" SET "encrypted_data" = $1, "description" = $2 WHERE "id" = $3`,
-		newData, description, secretID,
-	)
+		`UPDATE "secrets"
+		 SET "encrypted_data" = $1, "size" = $2, "description" = $3,
+		     "encrypted_metadata" = COALESCE($4, "encrypted_metadata"), "updated_at" = now()
+		 WHERE "id" = $5
+		 RETURNING "revision"`,
+		newData, size, description, encryptedMetadata, secretID,
+	).Scan(&newVersion)
 	if err != nil {
-		return fmt.Errorf("failed to update encypted data: %w", err)
+		return 0, fmt.Errorf("failed to update encypted data: %w", err)
+	}
+	if err := appendSecretAuditEvent(ctx, tx, event, secretID); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to update encypted data: %w", err)
 	}
 
-	return nil
+	return newVersion, nil
 }
 
-func (db *DBStorage) ListUserSecrets(ctx context.Context, userID int) ([]models.Secret, error) {
-	rows, err := db.pool.Query(
+// UpdateSecretMetadata replaces secret metadata if the secret version is
+// still version and returns the new version.
+func (db *DBStorage) UpdateSecretMetadata(
+	ctx context.Context,
+	secretID int,
+	version int,
+	encryptedMetadata []byte,
+	event models.AuditEvent) (int, error) {
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := archiveSecret(ctx, tx, secretID, version); err != nil {
+		return 0, err
+	}
+	var newVersion int
+	err = tx.QueryRow(
 		ctx,
-		`SELECT "id", "type", "description", "encrypted_data", "encrypted_key"
-		 FROM "secrets" WHERE "user_id" = $1`,
-		userID,
-	)
+		`UPDATE "secrets"
+		 SET "encrypted_metadata" = $1, "updated_at" = now()
+		 WHERE "id" = $2
+		 RETURNING "revision"`,
+		encryptedMetadata, secretID,
+	).Scan(&newVersion)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch user secrets: %w", err)
+		return 0, fmt.Errorf("failed to update secret metadata: %w", err)
+	}
+	if err := appendSecretAuditEvent(ctx, tx, event, secretID); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to update secret metadata: %w", err)
+	}
+
+	return newVersion, nil
+}
+
+// streamBatchSize is the number of secrets StreamUserSecrets reads at once.
+const streamBatchSize = 100
+
+// StreamUserSecrets calls fn for every user secret matching the filter
+// ordered by folder, type and ID, pagination fields of the filter are ignored.
+// Secrets shared with the user and vault secrets are yielded without a folder.
+// Secrets are listed in batches without their data, which is read one secret
+// at a time right before fn is called, so at most one secret data is kept in
+// memory and the connection is released while fn reads chunks or writes
+// audit events. Secrets deleted after they were listed are skipped.
+func (db *DBStorage) StreamUserSecrets(
+	ctx context.Context,
+	userID int,
+	filter models.SecretsFilter,
+	fn func(models.Secret) error) error {
+
+	var last models.Secret
+	for first := true; ; first = false {
+		args := pgx.NamedArgs{"userID": userID}
+		query := `SELECT * FROM (
+		   SELECT "id", "user_id", COALESCE("vault_id", 0) AS "vault_id", "type", "description",
+		          "encrypted_key", "encrypted_metadata",
+		          CASE WHEN "user_id" = @userID AND "vault_id" IS NULL THEN COALESCE("folder_id", 0) ELSE 0 END
+		            AS "user_folder_id"
+		   FROM "secrets"
+		   WHERE ` + secretsScopeCondition(filter, args) + ` AND "deleted_at" IS NULL` + secretsFilterConditions(filter, args) + `
+		 ) AS "user_secrets"`
+		if !first {
+			query += ` WHERE ("user_folder_id", "type", "id") > (@lastFolderID, @lastType, @lastID)`
+			args["lastFolderID"] = last.FolderID
+			args["lastType"] = last.SecretType
+			args["lastID"] = last.ID
+		}
+		query += ` ORDER BY "user_folder_id", "type", "id" LIMIT @limit`
+		args["limit"] = streamBatchSize
+		rows, err := db.pool.Query(ctx, query, args)
+		if err != nil {
+			return fmt.Errorf("failed to fetch user secrets: %w", err)
+		}
+		secrets, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Secret, error) {
+			var secret models.Secret
+			err := row.Scan(
+				&secret.ID,
+				&secret.UserID,
+				&secret.VaultID,
+				&secret.SecretType,
+				&secret.Description,
+				&secret.EncryptedKey,
+				&secret.EncryptedMetadata,
+				&secret.FolderID,
+			)
+			return secret, err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to fetch user secrets: %w", err)
+		}
+
+		for _, secret := range secrets {
+			err := db.pool.QueryRow(
+				ctx,
+				`SELECT "encrypted_data" FROM "secrets" WHERE "id" = $1 AND "deleted_at" IS NULL`,
+				secret.ID,
+			).Scan(&secret.EncryptedData)
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					continue
+				}
+				return fmt.Errorf("failed to fetch secret data: %w", err)
+			}
+			if err := fn(secret); err != nil {
+				return err
+			}
+		}
+		if len(secrets) < streamBatchSize {
+			return nil
+		}
+		last = secrets[len(secrets)-1]
+	}
+}
+
+func (db *DBStorage) ListUserSecretsInfo(
+	ctx context.Context,
+	userID int,
+	filter models.SecretsFilter) ([]models.SecretInfo, error) {
+
+	query := `SELECT "id", "type", "description", "size",
+			CASE WHEN "user_id" = @userID AND "vault_id" IS NULL THEN COALESCE("folder_id", 0) ELSE 0 END,
+			"revision",
+			ARRAY(
+				SELECT "tags"."name" FROM "secret_tags"
+				JOIN "tags" ON "tags"."id" = "secret_tags"."tag_id"
+				WHERE "secret_tags"."secret_id" = "secrets"."id" AND "tags"."user_id" = @userID
+				ORDER BY "tags"."name"
+			),
+			"created_at", "updated_at",
+			COALESCE(
+				(SELECT "access" FROM "secret_shares"
+				 WHERE "secret_id" = "secrets"."id" AND "secret_shares"."user_id" = @userID),
+				0
+			)
+		FROM "secrets"
+		WHERE "id" > @afterID AND "deleted_at" IS NULL`
+	args := pgx.NamedArgs{"userID": userID, "afterID": filter.AfterID}
+	query += ` AND ` + secretsScopeCondition(filter, args)
+	query += secretsFilterConditions(filter, args)
+	query += ` ORDER BY "id"`
+	if filter.Limit > 0 {
+		query += ` LIMIT @limit`
+		args["limit"] = filter.Limit
 	}
 
-	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Secret, error) {
-		var secret models.Secret
+	rows, err := db.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user secrets info: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.SecretInfo, error) {
+		var info models.SecretInfo
 		err := row.Scan(
-			&secret.ID,
-			&secret.SecretType,
-			&secret.Description,
-			&secret.EncryptedData,
-			&secret.EncryptedKey,
+			&info.ID,
+			&info.SecretType,
+			&info.Description,
+			&info.Size,
+			&info.FolderID,
+			&info.Version,
+			&info.Tags,
+			&info.CreatedAt,
+			&info.UpdatedAt,
+			&info.Shared,
 		)
-		return secret, err
+		return info, err
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch user secrets: %w", err)
+		return nil, fmt.Errorf("failed to fetch user secrets info: %w", err)
 	}
 
 	return result, nil
 }
 
-func (db *DBStorage) DeleteSecret(ctx context.Context, secretID int) error {
-	_, err := db.pool.Exec(
+// DeleteSecret deletes the secret if its version is still version, zero
+// version deletes the secret unconditionally. The audit event is appended
+// if the secret is deleted.
+func (db *DBStorage) DeleteSecret(ctx context.Context, secretID int, version int, event models.AuditEvent) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	event.SecretID = secretID
+	err = tx.QueryRow(
 		ctx,
-		`DELETE FROM "secrets" WHERE "id" = $1`,
-		secretID,
-	)
+		`DELETE FROM "secrets" WHERE "id" = $1 AND ($2 = 0 OR "revision" = $2)
+		 RETURNING "user_id", COALESCE("vault_id", 0)`,
+		secretID, version,
+	).Scan(&event.OwnerID, &event.VaultID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if version == 0 {
+			return nil
+		}
+		var exists bool
+		err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM "secrets" WHERE "id" = $1)`, secretID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to delete secret with id=%d: %w", secretID, err)
+		}
+		if exists {
+			return ErrSecretVersionMismatch{Secret: models.Secret{ID: secretID, Version: version}}
+		}
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to delete secret with id=%d", secretID)
+		return fmt.Errorf("failed to delete secret with id=%d: %w", secretID, err)
+	}
+	if err := appendAuditEvents(ctx, tx, event); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to delete secret with id=%d: %w", secretID, err)
 	}
 
 	return nil
 }
 
+// accessibleSecretsCondition selects personal secrets of the @userID user
+// and secrets shared with the user.
+const accessibleSecretsCondition = `"vault_id" IS NULL AND ("user_id" = @userID OR "id" IN (
+	SELECT "secret_id" FROM "secret_shares" WHERE "secret_shares"."user_id" = @userID
+))`
+
+// vaultSecretsCondition selects secrets of vaults where the @userID user
+// has the @vaultRole role or a higher one.
+const vaultSecretsCondition = `"vault_id" IN (
+	SELECT "vaults"."id" FROM "vaults"
+	JOIN "organization_members" ON "organization_members"."organization_id" = "vaults"."organization_id"
+	WHERE "organization_members"."user_id" = @userID
+	  AND "organization_members"."role" >= @vaultRole
+	  AND "organization_members"."accepted_at" IS NOT NULL
+)`
+
+// secretsScopeCondition returns the SQL condition selecting secrets of the
+// filter vault or, if the filter has no vault, secrets accessible to the
+// @userID user outside of vaults.
+func secretsScopeCondition(filter models.SecretsFilter, args pgx.NamedArgs) string {
+	if filter.VaultID != 0 {
+		args["vaultID"] = filter.VaultID
+		return `"vault_id" = @vaultID`
+	}
+
+	return accessibleSecretsCondition
+}
+
+// secretsFilterConditions returns SQL conditions for the type, description,
+// folder, tag and API token scope of the filter and adds their arguments to
+// args. Folders and
+// tags of other users match no secrets, args must hold userID.
+func secretsFilterConditions(filter models.SecretsFilter, args pgx.NamedArgs) string {
+	var conditions string
+	if filter.SecretType != 0 {
+		conditions += ` AND "type" = @secretType`
+		args["secretType"] = filter.SecretType
+	}
+	if filter.Description != "" {
+		conditions += ` AND "description" ILIKE @description`
+		args["description"] = "%" + likeEscaper.Replace(filter.Description) + "%"
+	}
+	if filter.FolderID != 0 {
+		conditions += ` AND "folder_id" IN (
+			WITH RECURSIVE "subfolders" AS (
+				SELECT "id" FROM "folders" WHERE "id" = @folderID AND "user_id" = @userID
+				UNION ALL
+				SELECT "folders"."id" FROM "folders"
+				JOIN "subfolders" ON "folders"."parent_id" = "subfolders"."id"
+			)
+			SELECT "id" FROM "subfolders"
+		)`
+		args["folderID"] = filter.FolderID
+	}
+	if filter.TagID != 0 {
+		conditions += ` AND EXISTS (
+			SELECT 1 FROM "secret_tags"
+			JOIN "tags" ON "tags"."id" = "secret_tags"."tag_id"
+			WHERE "secret_tags"."secret_id" = "secrets"."id" AND "secret_tags"."tag_id" = @tagID
+			  AND "tags"."user_id" = @userID
+		)`
+		args["tagID"] = filter.TagID
+	}
+	if len(filter.Scope.SecretTypes) > 0 {
+		secretTypes := make([]int, len(filter.Scope.SecretTypes))
+		for i, secretType := range filter.Scope.SecretTypes {
+			secretTypes[i] = int(secretType)
+		}
+		conditions += ` AND "type" = ANY(@scopeSecretTypes)`
+		args["scopeSecretTypes"] = secretTypes
+	}
+	if len(filter.Scope.SecretIDs) > 0 {
+		conditions += ` AND "id" = ANY(@scopeSecretIDs)`
+		args["scopeSecretIDs"] = filter.Scope.SecretIDs
+	}
+
+	return conditions
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 //go:embed db/migrations/*.sql
 var migrationsDir embed.FS
 
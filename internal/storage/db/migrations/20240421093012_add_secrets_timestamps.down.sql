@@ -0,0 +1,2 @@
+DROP INDEX "secrets_user_id_id_idx";
+ALTER TABLE "secrets" DROP COLUMN "created_at", DROP COLUMN "updated_at";
@@ -0,0 +1,4 @@
+ALTER TABLE "secrets"
+    ADD COLUMN "created_at" timestamptz NOT NULL DEFAULT now(),
+    ADD COLUMN "updated_at" timestamptz NOT NULL DEFAULT now();
+CREATE INDEX "secrets_user_id_id_idx" ON "secrets" ("user_id", "id");
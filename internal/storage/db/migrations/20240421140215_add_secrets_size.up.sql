@@ -0,0 +1,4 @@
+ALTER TABLE "secrets" ADD COLUMN "size" bigint NOT NULL DEFAULT 0;
+-- Data is encrypted with AES-GCM, which adds a 12 byte nonce and a 16 byte
+-- tag to the plaintext.
+UPDATE "secrets" SET "size" = GREATEST(octet_length("encrypted_data") - 28, 0);
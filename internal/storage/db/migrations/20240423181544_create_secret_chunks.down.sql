@@ -0,0 +1,3 @@
+DROP TABLE "secret_chunks";
+DROP FUNCTION "delete_unreferenced_chunk_data"();
+DROP TABLE "secret_chunk_data";
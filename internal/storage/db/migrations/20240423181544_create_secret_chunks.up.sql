@@ -0,0 +1,34 @@
+-- Chunk data is written before it is attached to a secret, so the
+-- transaction attaching it is not held open while the content is streamed.
+-- Data left unattached by a failed write is found by its age and deleted.
+CREATE TABLE "secret_chunk_data" (
+    "id" bigserial PRIMARY KEY,
+    "data" bytea NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX ON "secret_chunk_data" ("created_at");
+
+CREATE TABLE "secret_chunks" (
+    "secret_id" bigint references "secrets"("id") ON DELETE CASCADE NOT NULL,
+    "idx" integer NOT NULL,
+    "size" integer NOT NULL,
+    "data_id" bigint references "secret_chunk_data"("id") NOT NULL,
+    PRIMARY KEY ("secret_id", "idx")
+);
+CREATE INDEX ON "secret_chunks" ("data_id");
+
+CREATE FUNCTION "delete_unreferenced_chunk_data"() RETURNS trigger AS $$
+BEGIN
+    -- the lock orders concurrent deletes of the last references, so the
+    -- later one sees the earlier one committed
+    PERFORM 1 FROM "secret_chunk_data" WHERE "id" = OLD."data_id" FOR UPDATE;
+    IF NOT EXISTS (SELECT 1 FROM "secret_chunks" WHERE "data_id" = OLD."data_id") THEN
+        DELETE FROM "secret_chunk_data" WHERE "id" = OLD."data_id";
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "secret_chunks_delete_data"
+    AFTER DELETE ON "secret_chunks"
+    FOR EACH ROW EXECUTE FUNCTION "delete_unreferenced_chunk_data"();
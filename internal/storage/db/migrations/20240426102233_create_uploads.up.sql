@@ -0,0 +1,21 @@
+CREATE TABLE "uploads" (
+    "id" varchar(64) PRIMARY KEY,
+    "user_id" bigint references "users"("id") ON DELETE CASCADE NOT NULL,
+    "secret_id" bigint references "secrets"("id") ON DELETE CASCADE,
+    "description" varchar(500) NOT NULL DEFAULT '',
+    "length" bigint NOT NULL,
+    "offset" bigint NOT NULL DEFAULT 0,
+    "encrypted_data" bytea NOT NULL,
+    "encrypted_key" bytea NOT NULL,
+    "completed" boolean NOT NULL DEFAULT false,
+    "expires_at" timestamptz NOT NULL
+);
+CREATE INDEX "uploads_expires_at_idx" ON "uploads" ("expires_at");
+
+CREATE TABLE "upload_chunks" (
+    "upload_id" varchar(64) references "uploads"("id") ON DELETE CASCADE NOT NULL,
+    "idx" integer NOT NULL,
+    "size" integer NOT NULL,
+    "data" bytea NOT NULL,
+    PRIMARY KEY ("upload_id", "idx")
+);
@@ -0,0 +1 @@
+ALTER TABLE "uploads" ADD COLUMN "encrypted_hash_state" bytea;
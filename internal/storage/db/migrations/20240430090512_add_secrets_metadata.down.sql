@@ -0,0 +1,2 @@
+ALTER TABLE "uploads" DROP COLUMN "encrypted_metadata";
+ALTER TABLE "secrets" DROP COLUMN "encrypted_metadata";
@@ -0,0 +1,2 @@
+ALTER TABLE "secrets" ADD COLUMN "encrypted_metadata" bytea;
+ALTER TABLE "uploads" ADD COLUMN "encrypted_metadata" bytea;
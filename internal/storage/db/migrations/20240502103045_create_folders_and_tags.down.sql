@@ -0,0 +1,4 @@
+DROP TABLE "secret_tags";
+DROP TABLE "tags";
+ALTER TABLE "secrets" DROP COLUMN "folder_id";
+DROP TABLE "folders";
@@ -0,0 +1,25 @@
+CREATE TABLE "folders" (
+    "id" bigserial PRIMARY KEY,
+    "user_id" bigint references "users"("id") NOT NULL,
+    "parent_id" bigint references "folders"("id") ON DELETE CASCADE,
+    "name" varchar(255) NOT NULL
+);
+CREATE UNIQUE INDEX "folders_user_id_parent_id_name_idx" ON "folders" ("user_id", COALESCE("parent_id", 0), "name");
+CREATE INDEX "folders_parent_id_idx" ON "folders" ("parent_id");
+
+ALTER TABLE "secrets" ADD COLUMN "folder_id" bigint references "folders"("id") ON DELETE SET NULL;
+CREATE INDEX "secrets_folder_id_idx" ON "secrets" ("folder_id");
+
+CREATE TABLE "tags" (
+    "id" bigserial PRIMARY KEY,
+    "user_id" bigint references "users"("id") NOT NULL,
+    "name" varchar(255) NOT NULL,
+    UNIQUE ("user_id", "name")
+);
+
+CREATE TABLE "secret_tags" (
+    "secret_id" bigint references "secrets"("id") ON DELETE CASCADE NOT NULL,
+    "tag_id" bigint references "tags"("id") ON DELETE CASCADE NOT NULL,
+    PRIMARY KEY ("secret_id", "tag_id")
+);
+CREATE INDEX "secret_tags_tag_id_idx" ON "secret_tags" ("tag_id");
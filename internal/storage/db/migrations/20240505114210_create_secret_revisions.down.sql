@@ -0,0 +1,16 @@
+DROP TABLE "secret_revision_chunks";
+DROP TABLE "secret_revisions";
+ALTER TABLE "users" DROP COLUMN "revisions_retention";
+
+-- chunk data left without references is deleted as unattached staged data
+CREATE OR REPLACE FUNCTION "delete_unreferenced_chunk_data"() RETURNS trigger AS $$
+BEGIN
+    -- the lock orders concurrent deletes of the last references, so the
+    -- later one sees the earlier one committed
+    PERFORM 1 FROM "secret_chunk_data" WHERE "id" = OLD."data_id" FOR UPDATE;
+    IF NOT EXISTS (SELECT 1 FROM "secret_chunks" WHERE "data_id" = OLD."data_id") THEN
+        DELETE FROM "secret_chunk_data" WHERE "id" = OLD."data_id";
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,42 @@
+ALTER TABLE "users" ADD COLUMN "revisions_retention" integer NOT NULL DEFAULT 10;
+
+CREATE TABLE "secret_revisions" (
+    "id" bigserial PRIMARY KEY,
+    "secret_id" bigint references "secrets"("id") ON DELETE CASCADE NOT NULL,
+    "version" integer NOT NULL,
+    "description" varchar(500) NOT NULL DEFAULT '',
+    "encrypted_data" bytea NOT NULL,
+    "size" bigint NOT NULL DEFAULT 0,
+    "encrypted_key" bytea NOT NULL,
+    "encrypted_metadata" bytea,
+    "created_at" timestamptz NOT NULL,
+    UNIQUE ("secret_id", "version")
+);
+
+-- Revision chunks refer to the chunk data of the secret instead of copying
+-- it, so saving a revision does not copy the content.
+CREATE TABLE "secret_revision_chunks" (
+    "revision_id" bigint references "secret_revisions"("id") ON DELETE CASCADE NOT NULL,
+    "idx" integer NOT NULL,
+    "size" integer NOT NULL,
+    "data_id" bigint references "secret_chunk_data"("id") NOT NULL,
+    PRIMARY KEY ("revision_id", "idx")
+);
+CREATE INDEX ON "secret_revision_chunks" ("data_id");
+
+CREATE OR REPLACE FUNCTION "delete_unreferenced_chunk_data"() RETURNS trigger AS $$
+BEGIN
+    -- the lock orders concurrent deletes of the last references, so the
+    -- later one sees the earlier one committed
+    PERFORM 1 FROM "secret_chunk_data" WHERE "id" = OLD."data_id" FOR UPDATE;
+    IF NOT EXISTS (SELECT 1 FROM "secret_chunks" WHERE "data_id" = OLD."data_id")
+       AND NOT EXISTS (SELECT 1 FROM "secret_revision_chunks" WHERE "data_id" = OLD."data_id") THEN
+        DELETE FROM "secret_chunk_data" WHERE "id" = OLD."data_id";
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "secret_revision_chunks_delete_data"
+    AFTER DELETE ON "secret_revision_chunks"
+    FOR EACH ROW EXECUTE FUNCTION "delete_unreferenced_chunk_data"();
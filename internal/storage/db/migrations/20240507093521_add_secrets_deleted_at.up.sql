@@ -0,0 +1,2 @@
+ALTER TABLE "secrets" ADD COLUMN "deleted_at" timestamptz;
+CREATE INDEX "secrets_deleted_at_idx" ON "secrets" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
@@ -0,0 +1,7 @@
+DROP TRIGGER "secrets_create_tombstone" ON "secrets";
+DROP FUNCTION "create_secret_tombstone"();
+DROP TRIGGER "secrets_bump_revision" ON "secrets";
+DROP FUNCTION "bump_secret_revision"();
+DROP TABLE "secret_tombstones";
+ALTER TABLE "secrets" DROP COLUMN "revision", DROP COLUMN "created_revision";
+ALTER TABLE "users" DROP COLUMN "secrets_revision";
@@ -0,0 +1,52 @@
+ALTER TABLE "users" ADD COLUMN "secrets_revision" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "secrets"
+    ADD COLUMN "revision" bigint NOT NULL DEFAULT 0,
+    ADD COLUMN "created_revision" bigint NOT NULL DEFAULT 0;
+
+UPDATE "secrets" SET "revision" = "ranked"."position", "created_revision" = "ranked"."position"
+FROM (
+    SELECT "id", row_number() OVER (PARTITION BY "user_id" ORDER BY "id") AS "position" FROM "secrets"
+) AS "ranked"
+WHERE "secrets"."id" = "ranked"."id";
+UPDATE "users" SET "secrets_revision" = (
+    SELECT COALESCE(max("revision"), 0) FROM "secrets" WHERE "secrets"."user_id" = "users"."id"
+);
+CREATE INDEX "secrets_user_id_revision_idx" ON "secrets" ("user_id", "revision");
+
+CREATE TABLE "secret_tombstones" (
+    "secret_id" bigint PRIMARY KEY,
+    "user_id" bigint references "users"("id") NOT NULL,
+    "revision" bigint NOT NULL
+);
+CREATE INDEX "secret_tombstones_user_id_revision_idx" ON "secret_tombstones" ("user_id", "revision");
+
+-- Every secret change takes the next revision of the owner. The owner row
+-- stays locked until the transaction ends, so revisions are committed in order.
+CREATE FUNCTION "bump_secret_revision"() RETURNS trigger AS $$
+BEGIN
+    UPDATE "users" SET "secrets_revision" = "secrets_revision" + 1
+    WHERE "id" = NEW."user_id"
+    RETURNING "secrets_revision" INTO NEW."revision";
+    IF TG_OP = 'INSERT' THEN
+        NEW."created_revision" := NEW."revision";
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "secrets_bump_revision"
+    BEFORE INSERT OR UPDATE ON "secrets"
+    FOR EACH ROW EXECUTE FUNCTION "bump_secret_revision"();
+
+CREATE FUNCTION "create_secret_tombstone"() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO "secret_tombstones" ("secret_id", "user_id", "revision")
+    SELECT OLD."id", OLD."user_id", "secrets_revision" + 1 FROM "users" WHERE "id" = OLD."user_id";
+    UPDATE "users" SET "secrets_revision" = "secrets_revision" + 1 WHERE "id" = OLD."user_id";
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "secrets_create_tombstone"
+    AFTER DELETE ON "secrets"
+    FOR EACH ROW EXECUTE FUNCTION "create_secret_tombstone"();
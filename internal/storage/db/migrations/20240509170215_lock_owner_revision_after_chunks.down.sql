@@ -0,0 +1,10 @@
+CREATE OR REPLACE FUNCTION "create_secret_tombstone"() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO "secret_tombstones" ("secret_id", "user_id", "revision")
+    SELECT OLD."id", OLD."user_id", "secrets_revision" + 1 FROM "users" WHERE "id" = OLD."user_id";
+    UPDATE "users" SET "secrets_revision" = "secrets_revision" + 1 WHERE "id" = OLD."user_id";
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE "secret_chunks" ALTER CONSTRAINT "secret_chunks_secret_id_fkey" NOT DEFERRABLE;
@@ -0,0 +1,18 @@
+-- Chunks of a new secret are written before the secret row, which locks the
+-- owner row, so the lock is not held while the content is streamed.
+ALTER TABLE "secret_chunks" ALTER CONSTRAINT "secret_chunks_secret_id_fkey" DEFERRABLE;
+
+-- The tombstone revision is taken from the locked owner row, so tombstones
+-- of concurrently deleted secrets never get the same revision.
+CREATE OR REPLACE FUNCTION "create_secret_tombstone"() RETURNS trigger AS $$
+DECLARE
+    "revision" bigint;
+BEGIN
+    UPDATE "users" SET "secrets_revision" = "secrets_revision" + 1
+    WHERE "id" = OLD."user_id"
+    RETURNING "secrets_revision" INTO "revision";
+    INSERT INTO "secret_tombstones" ("secret_id", "user_id", "revision")
+    VALUES (OLD."id", OLD."user_id", "revision");
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
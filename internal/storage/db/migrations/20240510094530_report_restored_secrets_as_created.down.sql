@@ -0,0 +1,11 @@
+CREATE OR REPLACE FUNCTION "bump_secret_revision"() RETURNS trigger AS $$
+BEGIN
+    UPDATE "users" SET "secrets_revision" = "secrets_revision" + 1
+    WHERE "id" = NEW."user_id"
+    RETURNING "secrets_revision" INTO NEW."revision";
+    IF TG_OP = 'INSERT' THEN
+        NEW."created_revision" := NEW."revision";
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
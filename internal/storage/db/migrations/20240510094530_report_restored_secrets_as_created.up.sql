@@ -0,0 +1,15 @@
+-- A secret restored from the trash takes a new created revision, since
+-- clients have removed it when it was trashed.
+CREATE OR REPLACE FUNCTION "bump_secret_revision"() RETURNS trigger AS $$
+BEGIN
+    UPDATE "users" SET "secrets_revision" = "secrets_revision" + 1
+    WHERE "id" = NEW."user_id"
+    RETURNING "secrets_revision" INTO NEW."revision";
+    IF TG_OP = 'INSERT' THEN
+        NEW."created_revision" := NEW."revision";
+    ELSIF OLD."deleted_at" IS NOT NULL AND NEW."deleted_at" IS NULL THEN
+        NEW."created_revision" := NEW."revision";
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
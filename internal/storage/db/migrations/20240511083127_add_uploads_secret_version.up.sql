@@ -0,0 +1 @@
+ALTER TABLE "uploads" ADD COLUMN "secret_version" bigint NOT NULL DEFAULT 0;
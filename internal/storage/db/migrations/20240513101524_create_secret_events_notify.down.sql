@@ -0,0 +1,2 @@
+DROP TRIGGER "secrets_notify_event" ON "secrets";
+DROP FUNCTION "notify_secret_event"();
@@ -0,0 +1,45 @@
+-- Secret changes are published to the "secret_events" channel when the
+-- transaction commits, so every server instance receives them. Moving a
+-- secret to the trash is published as deletion and restoring as creation.
+CREATE FUNCTION "notify_secret_event"() RETURNS trigger AS $$
+DECLARE
+    "kind" text;
+    "secret" "secrets";
+    "revision" bigint;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF OLD."deleted_at" IS NOT NULL THEN
+            RETURN NULL;
+        END IF;
+        "kind" := 'deleted';
+        "secret" := OLD;
+        -- "secrets_create_tombstone" fires first and takes the revision
+        SELECT "secrets_revision" INTO "revision" FROM "users" WHERE "id" = OLD."user_id";
+    ELSE
+        "secret" := NEW;
+        "revision" := NEW."revision";
+        IF TG_OP = 'INSERT' THEN
+            "kind" := 'created';
+        ELSIF OLD."deleted_at" IS NULL AND NEW."deleted_at" IS NOT NULL THEN
+            "kind" := 'deleted';
+        ELSIF OLD."deleted_at" IS NOT NULL AND NEW."deleted_at" IS NULL THEN
+            "kind" := 'created';
+        ELSIF NEW."deleted_at" IS NULL THEN
+            "kind" := 'updated';
+        ELSE
+            RETURN NULL;
+        END IF;
+    END IF;
+    PERFORM pg_notify('secret_events', json_build_object(
+        'kind', "kind",
+        'user_id', "secret"."user_id",
+        'secret_id', "secret"."id",
+        'revision', "revision"
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "secrets_notify_event"
+    AFTER INSERT OR UPDATE OR DELETE ON "secrets"
+    FOR EACH ROW EXECUTE FUNCTION "notify_secret_event"();
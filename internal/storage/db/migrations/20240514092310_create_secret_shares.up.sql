@@ -0,0 +1,8 @@
+CREATE TABLE "secret_shares" (
+    "secret_id" bigint references "secrets"("id") ON DELETE CASCADE NOT NULL,
+    "user_id" bigint references "users"("id") ON DELETE CASCADE NOT NULL,
+    "access" smallint NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("secret_id", "user_id")
+);
+CREATE INDEX "secret_shares_user_id_idx" ON "secret_shares" ("user_id");
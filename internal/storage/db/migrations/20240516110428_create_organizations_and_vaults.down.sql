@@ -0,0 +1,6 @@
+ALTER TABLE "uploads" DROP COLUMN "vault_id";
+DROP INDEX "secrets_vault_id_id_idx";
+ALTER TABLE "secrets" DROP COLUMN "vault_id";
+DROP TABLE "vaults";
+DROP TABLE "organization_members";
+DROP TABLE "organizations";
@@ -0,0 +1,24 @@
+CREATE TABLE "organizations" (
+    "id" bigserial PRIMARY KEY,
+    "name" varchar(255) NOT NULL,
+    "owner_id" bigint references "users"("id") ON DELETE CASCADE NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT now()
+);
+CREATE TABLE "organization_members" (
+    "organization_id" bigint references "organizations"("id") ON DELETE CASCADE NOT NULL,
+    "user_id" bigint references "users"("id") ON DELETE CASCADE NOT NULL,
+    "accepted_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("organization_id", "user_id")
+);
+CREATE INDEX "organization_members_user_id_idx" ON "organization_members" ("user_id");
+CREATE TABLE "vaults" (
+    "id" bigserial PRIMARY KEY,
+    "organization_id" bigint references "organizations"("id") ON DELETE CASCADE NOT NULL,
+    "name" varchar(255) NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    UNIQUE ("organization_id", "name")
+);
+ALTER TABLE "secrets" ADD COLUMN "vault_id" bigint references "vaults"("id") ON DELETE CASCADE;
+CREATE INDEX "secrets_vault_id_id_idx" ON "secrets" ("vault_id", "id");
+ALTER TABLE "uploads" ADD COLUMN "vault_id" bigint references "vaults"("id") ON DELETE CASCADE;
@@ -0,0 +1,12 @@
+CREATE OR REPLACE FUNCTION "create_secret_tombstone"() RETURNS trigger AS $$
+DECLARE
+    "revision" bigint;
+BEGIN
+    UPDATE "users" SET "secrets_revision" = "secrets_revision" + 1
+    WHERE "id" = OLD."user_id"
+    RETURNING "secrets_revision" INTO "revision";
+    INSERT INTO "secret_tombstones" ("secret_id", "user_id", "revision")
+    VALUES (OLD."id", OLD."user_id", "revision");
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
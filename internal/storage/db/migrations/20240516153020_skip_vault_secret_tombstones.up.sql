@@ -0,0 +1,16 @@
+-- Sync covers personal secrets only, so vault secrets leave no tombstones.
+CREATE OR REPLACE FUNCTION "create_secret_tombstone"() RETURNS trigger AS $$
+DECLARE
+    "revision" bigint;
+BEGIN
+    IF OLD."vault_id" IS NOT NULL THEN
+        RETURN OLD;
+    END IF;
+    UPDATE "users" SET "secrets_revision" = "secrets_revision" + 1
+    WHERE "id" = OLD."user_id"
+    RETURNING "secrets_revision" INTO "revision";
+    INSERT INTO "secret_tombstones" ("secret_id", "user_id", "revision")
+    VALUES (OLD."id", OLD."user_id", "revision");
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,5 @@
+ALTER TABLE "organization_members" ADD COLUMN "role" smallint NOT NULL DEFAULT 2;
+UPDATE "organization_members" SET "role" = 3
+FROM "organizations"
+WHERE "organizations"."id" = "organization_members"."organization_id"
+  AND "organizations"."owner_id" = "organization_members"."user_id";
@@ -0,0 +1,40 @@
+-- Audit events are never changed, "user_id" and "secret_id" have no
+-- foreign keys so events outlive deleted users and secrets.
+CREATE TABLE "audit_events" (
+    "id" bigserial PRIMARY KEY,
+    "user_id" bigint NOT NULL,
+    "action" smallint NOT NULL,
+    "secret_id" bigint NOT NULL,
+    "owner_id" bigint NOT NULL,
+    "vault_id" bigint,
+    "client_ip" varchar(64) NOT NULL,
+    "user_agent" text NOT NULL,
+    "created_at" timestamptz NOT NULL,
+    "prev_hash" bytea NOT NULL,
+    "hash" bytea NOT NULL
+);
+CREATE INDEX "audit_events_user_id_created_at_idx" ON "audit_events" ("user_id", "created_at");
+CREATE INDEX "audit_events_owner_id_created_at_idx" ON "audit_events" ("owner_id", "created_at");
+CREATE INDEX "audit_events_vault_id_created_at_idx" ON "audit_events" ("vault_id", "created_at");
+
+CREATE FUNCTION "reject_audit_event_change"() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit events are append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_events_append_only"
+    BEFORE UPDATE OR DELETE ON "audit_events"
+    FOR EACH ROW EXECUTE FUNCTION "reject_audit_event_change"();
+CREATE TRIGGER "audit_events_no_truncate"
+    BEFORE TRUNCATE ON "audit_events"
+    FOR EACH STATEMENT EXECUTE FUNCTION "reject_audit_event_change"();
+
+-- The single row holds the hash of the last event. Appending events locks
+-- it until the transaction ends, so concurrent events are chained one after
+-- another without locking the whole table.
+CREATE TABLE "audit_chain_head" (
+    "id" boolean PRIMARY KEY DEFAULT true CHECK ("id"),
+    "hash" bytea NOT NULL
+);
+INSERT INTO "audit_chain_head" ("hash") VALUES ('');
@@ -0,0 +1,10 @@
+-- Failed login attempts are counted per login and per client IP, the key
+-- is prefixed with "login:" or "ip:". The login is stored as its SHA-256
+-- hex digest, so logins of any length fit the key.
+CREATE TABLE "login_attempts" (
+    "key" varchar(320) PRIMARY KEY,
+    "failures" integer NOT NULL,
+    "last_failure_at" timestamptz NOT NULL,
+    "locked_until" timestamptz
+);
+CREATE INDEX "login_attempts_last_failure_at_idx" ON "login_attempts" ("last_failure_at");
@@ -0,0 +1,13 @@
+-- The TOTP secret is encrypted like secret data, TOTP is enabled once the
+-- first code is confirmed. "totp_last_step" is the time step of the last
+-- accepted code, so a code can not be used twice.
+ALTER TABLE "users" ADD COLUMN "encrypted_totp_secret" bytea;
+ALTER TABLE "users" ADD COLUMN "encrypted_totp_key" bytea;
+ALTER TABLE "users" ADD COLUMN "totp_enabled_at" timestamptz;
+ALTER TABLE "users" ADD COLUMN "totp_last_step" bigint NOT NULL DEFAULT 0;
+CREATE TABLE "recovery_codes" (
+    "user_id" bigint references "users"("id") ON DELETE CASCADE NOT NULL,
+    "code_hash" bytea NOT NULL,
+    "used_at" timestamptz,
+    PRIMARY KEY ("user_id", "code_hash")
+);
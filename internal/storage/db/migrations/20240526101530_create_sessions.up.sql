@@ -0,0 +1,18 @@
+-- Access tokens carry the session id and are rejected once the session is
+-- revoked. Refresh tokens are rotated on every use, a used token is kept
+-- to detect its reuse.
+CREATE TABLE "sessions" (
+    "id" bigserial PRIMARY KEY,
+    "user_id" bigint references "users"("id") ON DELETE CASCADE NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    "revoked_at" timestamptz
+);
+CREATE INDEX "sessions_user_id_idx" ON "sessions" ("user_id");
+CREATE TABLE "refresh_tokens" (
+    "token_hash" bytea PRIMARY KEY,
+    "session_id" bigint references "sessions"("id") ON DELETE CASCADE NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "used_at" timestamptz
+);
+CREATE INDEX "refresh_tokens_session_id_idx" ON "refresh_tokens" ("session_id");
+CREATE INDEX "refresh_tokens_expires_at_idx" ON "refresh_tokens" ("expires_at");
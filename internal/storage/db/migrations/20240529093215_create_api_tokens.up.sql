@@ -0,0 +1,15 @@
+-- Personal API tokens for automation, empty "secret_types" and
+-- "secret_ids" do not limit access of the token.
+CREATE TABLE "api_tokens" (
+    "id" bigserial PRIMARY KEY,
+    "user_id" bigint references "users"("id") ON DELETE CASCADE NOT NULL,
+    "name" varchar(100) NOT NULL,
+    "token_hash" bytea NOT NULL UNIQUE,
+    "read_only" boolean NOT NULL DEFAULT false,
+    "secret_types" integer[] NOT NULL DEFAULT '{}',
+    "secret_ids" bigint[] NOT NULL DEFAULT '{}',
+    "expires_at" timestamptz,
+    "last_used_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX "api_tokens_user_id_idx" ON "api_tokens" ("user_id");
@@ -0,0 +1,3 @@
+DROP TABLE "service_account_assertions";
+DROP TABLE "service_account_credentials";
+DROP TABLE "service_accounts";
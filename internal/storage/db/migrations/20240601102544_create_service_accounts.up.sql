@@ -0,0 +1,31 @@
+-- Service accounts are backed by users without a password, so they own
+-- secrets, get them shared and are recorded in the audit log like users.
+CREATE TABLE "service_accounts" (
+    "id" bigserial PRIMARY KEY,
+    "user_id" bigint references "users"("id") ON DELETE CASCADE NOT NULL UNIQUE,
+    "organization_id" bigint references "organizations"("id") ON DELETE CASCADE NOT NULL,
+    "name" varchar(64) NOT NULL,
+    "created_by" bigint references "users"("id") ON DELETE SET NULL,
+    "disabled_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    UNIQUE ("organization_id", "name")
+);
+CREATE TABLE "service_account_credentials" (
+    "id" bigserial PRIMARY KEY,
+    "service_account_id" bigint references "service_accounts"("id") ON DELETE CASCADE NOT NULL,
+    "type" smallint NOT NULL,
+    "secret_hash" bytea UNIQUE,
+    "public_key" text,
+    "last_used_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX "service_account_credentials_service_account_id_idx" ON "service_account_credentials" ("service_account_id");
+-- Ids of accepted JWT assertions are kept until the assertions expire to
+-- reject replays.
+CREATE TABLE "service_account_assertions" (
+    "service_account_id" bigint references "service_accounts"("id") ON DELETE CASCADE NOT NULL,
+    "jti" varchar(255) NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    PRIMARY KEY ("service_account_id", "jti")
+);
+CREATE INDEX "service_account_assertions_expires_at_idx" ON "service_account_assertions" ("expires_at");
@@ -29,3 +29,172 @@ type ErrSecretNotFound struct {
 func (err ErrSecretNotFound) Error() string {
 	return fmt.Sprintf("secret with id=%d not found", err.Secret.ID)
 }
+
+// ErrSecretVersionMismatch is returned if the secret has been changed
+// since the version the change is based on.
+type ErrSecretVersionMismatch struct {
+	Secret models.Secret
+}
+
+func (err ErrSecretVersionMismatch) Error() string {
+	return fmt.Sprintf("secret with id=%d has been changed since version %d", err.Secret.ID, err.Secret.Version)
+}
+
+type ErrUploadNotFound struct {
+	Upload models.Upload
+}
+
+func (err ErrUploadNotFound) Error() string {
+	return fmt.Sprintf("upload with id=%s not found", err.Upload.ID)
+}
+
+type ErrFolderNotFound struct {
+	Folder models.Folder
+}
+
+func (err ErrFolderNotFound) Error() string {
+	return fmt.Sprintf("folder with id=%d not found", err.Folder.ID)
+}
+
+type ErrFolderNotUniq struct {
+	Folder models.Folder
+}
+
+func (err ErrFolderNotUniq) Error() string {
+	return fmt.Sprintf("folder \"%s\" already exists", err.Folder.Name)
+}
+
+type ErrTagNotFound struct {
+	Tag models.Tag
+}
+
+func (err ErrTagNotFound) Error() string {
+	return fmt.Sprintf("tag with id=%d not found", err.Tag.ID)
+}
+
+type ErrTagNotUniq struct {
+	Tag models.Tag
+}
+
+func (err ErrTagNotUniq) Error() string {
+	return fmt.Sprintf("tag \"%s\" already exists", err.Tag.Name)
+}
+
+type ErrRevisionNotFound struct {
+	Revision models.SecretRevision
+}
+
+func (err ErrRevisionNotFound) Error() string {
+	return fmt.Sprintf("revision %d of secret with id=%d not found", err.Revision.Version, err.Revision.SecretID)
+}
+
+type ErrSecretShareNotFound struct {
+	Share models.SecretShare
+}
+
+func (err ErrSecretShareNotFound) Error() string {
+	return fmt.Sprintf("secret with id=%d is not shared with user with id=%d", err.Share.SecretID, err.Share.UserID)
+}
+
+type ErrOrganizationNotFound struct {
+	Organization models.Organization
+}
+
+func (err ErrOrganizationNotFound) Error() string {
+	return fmt.Sprintf("organization with id=%d not found", err.Organization.ID)
+}
+
+type ErrOrganizationMemberNotFound struct {
+	Member models.OrganizationMember
+}
+
+func (err ErrOrganizationMemberNotFound) Error() string {
+	return fmt.Sprintf(
+		"user with id=%d is not a member of organization with id=%d",
+		err.Member.UserID,
+		err.Member.OrganizationID,
+	)
+}
+
+type ErrOrganizationMemberNotUniq struct {
+	Member models.OrganizationMember
+}
+
+func (err ErrOrganizationMemberNotUniq) Error() string {
+	return fmt.Sprintf(
+		"user \"%s\" is already a member of organization with id=%d",
+		err.Member.Login,
+		err.Member.OrganizationID,
+	)
+}
+
+type ErrVaultNotUniq struct {
+	Vault models.Vault
+}
+
+func (err ErrVaultNotUniq) Error() string {
+	return fmt.Sprintf("vault \"%s\" already exists", err.Vault.Name)
+}
+
+type ErrRefreshTokenNotFound struct{}
+
+func (err ErrRefreshTokenNotFound) Error() string {
+	return "refresh token not found"
+}
+
+type ErrSessionNotFound struct {
+	ID int
+}
+
+func (err ErrSessionNotFound) Error() string {
+	return fmt.Sprintf("session with id=%d not found", err.ID)
+}
+
+type ErrAPITokenNotFound struct {
+	ID int
+}
+
+func (err ErrAPITokenNotFound) Error() string {
+	if err.ID != 0 {
+		return fmt.Sprintf("api token with id=%d not found", err.ID)
+	}
+	return "api token not found"
+}
+
+type ErrServiceAccountNotFound struct {
+	ID int
+}
+
+func (err ErrServiceAccountNotFound) Error() string {
+	if err.ID != 0 {
+		return fmt.Sprintf("service account with id=%d not found", err.ID)
+	}
+	return "service account not found"
+}
+
+type ErrServiceAccountNotUniq struct {
+	Account models.ServiceAccount
+}
+
+func (err ErrServiceAccountNotUniq) Error() string {
+	return fmt.Sprintf("service account \"%s\" already exists", err.Account.Name)
+}
+
+type ErrServiceAccountCredentialNotFound struct {
+	ID int
+}
+
+func (err ErrServiceAccountCredentialNotFound) Error() string {
+	if err.ID != 0 {
+		return fmt.Sprintf("service account credential with id=%d not found", err.ID)
+	}
+	return "service account credential not found"
+}
+
+type ErrServiceAccountAssertionNotUniq struct {
+	JTI string
+}
+
+func (err ErrServiceAccountAssertionNotUniq) Error() string {
+	return fmt.Sprintf("assertion \"%s\" has already been used", err.JTI)
+}
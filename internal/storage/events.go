@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+)
+
+type secretEventPayload struct {
+	Kind     string `json:"kind"`
+	UserID   int    `json:"user_id"`
+	VaultID  int    `json:"vault_id"`
+	SecretID int    `json:"secret_id"`
+	Revision int    `json:"revision"`
+}
+
+// ListenSecretEvents calls handle for every secret change committed by any
+// server instance. It blocks until ctx is done or the connection fails,
+// events committed meanwhile are not delivered.
+func (db *DBStorage) ListenSecretEvents(ctx context.Context, handle func(models.SecretEvent)) error {
+	poolConn, err := db.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	// the listening connection is not returned to the pool
+	conn := poolConn.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, `LISTEN "secret_events"`); err != nil {
+		return fmt.Errorf("failed to listen secret events: %w", err)
+	}
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for secret events: %w", err)
+		}
+		var payload secretEventPayload
+		if err := json.Unmarshal([]byte(notification.Payload), &payload); err != nil {
+			return fmt.Errorf("failed to decode secret event: %w", err)
+		}
+		kind, ok := models.ParseSecretChangeKind(payload.Kind)
+		if !ok {
+			return fmt.Errorf("invalid secret event kind %q", payload.Kind)
+		}
+		handle(models.SecretEvent{
+			Kind:     kind,
+			UserID:   payload.UserID,
+			VaultID:  payload.VaultID,
+			SecretID: payload.SecretID,
+			Revision: payload.Revision,
+		})
+	}
+}
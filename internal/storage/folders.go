@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (db *DBStorage) CreateFolder(ctx context.Context, folder models.Folder) (models.Folder, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`INSERT INTO "folders" ("user_id", "parent_id", "name")
+		 VALUES (@userID, @parentID, @name) RETURNING "id"`,
+		pgx.NamedArgs{
+			"userID":   folder.UserID,
+			"parentID": nullableID(folder.ParentID),
+			"name":     folder.Name,
+		},
+	)
+	if err := row.Scan(&folder.ID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return folder, ErrFolderNotUniq{Folder: folder}
+		}
+		return folder, fmt.Errorf("failed to create folder: %w", err)
+	}
+
+	return folder, nil
+}
+
+// FindUserFolder returns ErrFolderNotFound if the folder does not exist
+// or belongs to another user.
+func (db *DBStorage) FindUserFolder(ctx context.Context, userID int, id int) (models.Folder, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT COALESCE("parent_id", 0), "name"
+		 FROM "folders"
+		 WHERE "id" = $1 AND "user_id" = $2`,
+		id, userID,
+	)
+	folder := models.Folder{ID: id, UserID: userID}
+	if err := row.Scan(&folder.ParentID, &folder.Name); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return folder, ErrFolderNotFound{Folder: folder}
+		}
+		return folder, fmt.Errorf("failed to find folder: %w", err)
+	}
+
+	return folder, nil
+}
+
+func (db *DBStorage) ListUserFolders(ctx context.Context, userID int) ([]models.Folder, error) {
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT "id", COALESCE("parent_id", 0), "name"
+		 FROM "folders"
+		 WHERE "user_id" = $1
+		 ORDER BY "id"`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user folders: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Folder, error) {
+		folder := models.Folder{UserID: userID}
+		err := row.Scan(&folder.ID, &folder.ParentID, &folder.Name)
+		return folder, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user folders: %w", err)
+	}
+
+	return result, nil
+}
+
+// UpdateFolder saves the folder name and parent.
+func (db *DBStorage) UpdateFolder(ctx context.Context, folder models.Folder) error {
+	_, err := db.pool.Exec(
+		ctx,
+		`UPDATE "folders" SET "name" = @name, "parent_id" = @parentID WHERE "id" = @id`,
+		pgx.NamedArgs{
+			"id":       folder.ID,
+			"parentID": nullableID(folder.ParentID),
+			"name":     folder.Name,
+		},
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return ErrFolderNotUniq{Folder: folder}
+		}
+		return fmt.Errorf("failed to update folder: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSecretFolder moves the secret into the folder, zero folderID moves
+// it out of any folder.
+func (db *DBStorage) UpdateSecretFolder(ctx context.Context, secretID int, folderID int) error {
+	_, err := db.pool.Exec(
+		ctx,
+		`UPDATE "secrets" SET "folder_id" = $1, "updated_at" = now() WHERE "id" = $2`,
+		nullableID(folderID), secretID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update secret folder: %w", err)
+	}
+
+	return nil
+}
+
+// nullableID converts zero ID to NULL.
+func nullableID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+
+	return &id
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// FindLoginLockout returns the time left until the longest lockout of the
+// keys ends, zero if none of the keys is locked.
+func (db *DBStorage) FindLoginLockout(ctx context.Context, keys []string) (time.Duration, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT COALESCE(EXTRACT(EPOCH FROM max("locked_until") - now()), 0)::float8
+		 FROM "login_attempts"
+		 WHERE "key" = ANY($1) AND "locked_until" > now()`,
+		keys,
+	)
+	var seconds float64
+	if err := row.Scan(&seconds); err != nil {
+		return 0, fmt.Errorf("failed to find login lockout: %w", err)
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// RecordLoginFailure counts a failed login attempt of the key and returns
+// the number of failures, failures are counted anew if the last one is
+// older than window.
+func (db *DBStorage) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`INSERT INTO "login_attempts" ("key", "failures", "last_failure_at") VALUES ($1, 1, now())
+		 ON CONFLICT ("key") DO UPDATE SET
+		   "failures" = CASE
+		     WHEN "login_attempts"."last_failure_at" < now() - $2 * interval '1 second' THEN 1
+		     ELSE "login_attempts"."failures" + 1
+		   END,
+		   "last_failure_at" = now()
+		 RETURNING "failures"`,
+		key, window.Seconds(),
+	)
+	var failures int
+	if err := row.Scan(&failures); err != nil {
+		return 0, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	return failures, nil
+}
+
+// LockLogin rejects login attempts of the key for the duration.
+func (db *DBStorage) LockLogin(ctx context.Context, key string, duration time.Duration) error {
+	_, err := db.pool.Exec(
+		ctx,
+		`UPDATE "login_attempts" SET "locked_until" = now() + $2 * interval '1 second' WHERE "key" = $1`,
+		key, duration.Seconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+
+	return nil
+}
+
+// ResetLoginFailures forgets failed login attempts of the key.
+func (db *DBStorage) ResetLoginFailures(ctx context.Context, key string) error {
+	_, err := db.pool.Exec(ctx, `DELETE FROM "login_attempts" WHERE "key" = $1`, key)
+	if err != nil {
+		return fmt.Errorf("failed to reset login failures: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteStaleLoginAttempts deletes unlocked keys without failures since
+// the given time.
+func (db *DBStorage) DeleteStaleLoginAttempts(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := db.pool.Exec(
+		ctx,
+		`DELETE FROM "login_attempts"
+		 WHERE "last_failure_at" < $1 AND ("locked_until" IS NULL OR "locked_until" <= now())`,
+		before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete stale login attempts: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,332 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// CreateOrganization creates the organization and makes its owner
+// the first member with the admin role.
+func (db *DBStorage) CreateOrganization(ctx context.Context, org models.Organization) (models.Organization, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return org, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	row := tx.QueryRow(
+		ctx,
+		`INSERT INTO "organizations" ("name", "owner_id") VALUES ($1, $2) RETURNING "id", "created_at"`,
+		org.Name, org.OwnerID,
+	)
+	if err := row.Scan(&org.ID, &org.CreatedAt); err != nil {
+		return org, fmt.Errorf("failed to create organization: %w", err)
+	}
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO "organization_members" ("organization_id", "user_id", "role", "accepted_at")
+		 VALUES ($1, $2, $3, now())`,
+		org.ID, org.OwnerID, models.OrganizationAdminRole,
+	)
+	if err != nil {
+		return org, fmt.Errorf("failed to add organization owner: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return org, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	return org, nil
+}
+
+func (db *DBStorage) FindOrganization(ctx context.Context, id int) (models.Organization, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT "name", "owner_id", "created_at" FROM "organizations" WHERE "id" = $1`,
+		id,
+	)
+	org := models.Organization{ID: id}
+	if err := row.Scan(&org.Name, &org.OwnerID, &org.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return org, ErrOrganizationNotFound{Organization: org}
+		}
+		return org, fmt.Errorf("failed to find organization: %w", err)
+	}
+
+	return org, nil
+}
+
+// ListUserOrganizations returns organizations the user is a member of or
+// has been invited to ordered by name.
+func (db *DBStorage) ListUserOrganizations(ctx context.Context, userID int) ([]models.Organization, error) {
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT "organizations"."id", "organizations"."name", "organizations"."owner_id",
+		        "organizations"."created_at", "organization_members"."accepted_at" IS NULL
+		 FROM "organizations"
+		 JOIN "organization_members" ON "organization_members"."organization_id" = "organizations"."id"
+		 WHERE "organization_members"."user_id" = $1
+		 ORDER BY "organizations"."name", "organizations"."id"`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user organizations: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Organization, error) {
+		var org models.Organization
+		err := row.Scan(&org.ID, &org.Name, &org.OwnerID, &org.CreatedAt, &org.Invited)
+		return org, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user organizations: %w", err)
+	}
+
+	return result, nil
+}
+
+// CreateOrganizationMember invites the user to the organization with
+// the member role.
+func (db *DBStorage) CreateOrganizationMember(
+	ctx context.Context,
+	member models.OrganizationMember) (models.OrganizationMember, error) {
+
+	row := db.pool.QueryRow(
+		ctx,
+		`INSERT INTO "organization_members" ("organization_id", "user_id", "role")
+		 VALUES ($1, $2, $3) RETURNING "created_at"`,
+		member.OrganizationID, member.UserID, member.Role,
+	)
+	if err := row.Scan(&member.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return member, ErrOrganizationMemberNotUniq{Member: member}
+		}
+		return member, fmt.Errorf("failed to create organization member: %w", err)
+	}
+
+	return member, nil
+}
+
+// AcceptOrganizationInvitation makes the invited user a member of the
+// organization.
+func (db *DBStorage) AcceptOrganizationInvitation(ctx context.Context, orgID int, userID int) error {
+	tag, err := db.pool.Exec(
+		ctx,
+		`UPDATE "organization_members" SET "accepted_at" = now()
+		 WHERE "organization_id" = $1 AND "user_id" = $2 AND "accepted_at" IS NULL`,
+		orgID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to accept organization invitation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOrganizationMemberNotFound{
+			Member: models.OrganizationMember{OrganizationID: orgID, UserID: userID},
+		}
+	}
+
+	return nil
+}
+
+// ListOrganizationMembers returns members and invited users of the
+// organization ordered by login.
+func (db *DBStorage) ListOrganizationMembers(ctx context.Context, orgID int) ([]models.OrganizationMember, error) {
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT "organization_members"."user_id", "users"."login", "organization_members"."role",
+		        "organization_members"."accepted_at" IS NOT NULL, "organization_members"."created_at"
+		 FROM "organization_members"
+		 JOIN "users" ON "users"."id" = "organization_members"."user_id"
+		 WHERE "organization_members"."organization_id" = $1
+		 ORDER BY "users"."login"`,
+		orgID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch organization members: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.OrganizationMember, error) {
+		member := models.OrganizationMember{OrganizationID: orgID}
+		err := row.Scan(&member.UserID, &member.Login, &member.Role, &member.Accepted, &member.CreatedAt)
+		return member, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch organization members: %w", err)
+	}
+
+	return result, nil
+}
+
+// UpdateOrganizationMemberRole changes the role of the member or of the
+// invited user.
+func (db *DBStorage) UpdateOrganizationMemberRole(
+	ctx context.Context,
+	orgID int,
+	userID int,
+	role models.OrganizationRole) error {
+
+	tag, err := db.pool.Exec(
+		ctx,
+		`UPDATE "organization_members" SET "role" = $3 WHERE "organization_id" = $1 AND "user_id" = $2`,
+		orgID, userID, role,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update organization member role: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOrganizationMemberNotFound{
+			Member: models.OrganizationMember{OrganizationID: orgID, UserID: userID},
+		}
+	}
+
+	return nil
+}
+
+// DeleteOrganizationMember removes the member or cancels the invitation
+// of the user. Access to the organization vaults is checked against
+// the membership, so it is revoked along with it.
+func (db *DBStorage) DeleteOrganizationMember(ctx context.Context, orgID int, userID int) error {
+	tag, err := db.pool.Exec(
+		ctx,
+		`DELETE FROM "organization_members" WHERE "organization_id" = $1 AND "user_id" = $2`,
+		orgID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete organization member: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOrganizationMemberNotFound{
+			Member: models.OrganizationMember{OrganizationID: orgID, UserID: userID},
+		}
+	}
+
+	return nil
+}
+
+func (db *DBStorage) CreateVault(ctx context.Context, vault models.Vault) (models.Vault, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`INSERT INTO "vaults" ("organization_id", "name") VALUES ($1, $2) RETURNING "id", "created_at"`,
+		vault.OrganizationID, vault.Name,
+	)
+	if err := row.Scan(&vault.ID, &vault.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return vault, ErrVaultNotUniq{Vault: vault}
+		}
+		return vault, fmt.Errorf("failed to create vault: %w", err)
+	}
+
+	return vault, nil
+}
+
+// ListUserVaults returns vaults of organizations the user is a member of
+// ordered by ID.
+func (db *DBStorage) ListUserVaults(ctx context.Context, userID int) ([]models.Vault, error) {
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT "vaults"."id", "vaults"."organization_id", "vaults"."name", "vaults"."created_at"
+		 FROM "vaults"
+		 JOIN "organization_members" ON "organization_members"."organization_id" = "vaults"."organization_id"
+		 WHERE "organization_members"."user_id" = $1 AND "organization_members"."accepted_at" IS NOT NULL
+		 ORDER BY "vaults"."id"`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user vaults: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Vault, error) {
+		var vault models.Vault
+		err := row.Scan(&vault.ID, &vault.OrganizationID, &vault.Name, &vault.CreatedAt)
+		return vault, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user vaults: %w", err)
+	}
+
+	return result, nil
+}
+
+// FindOrganizationRole returns the role of the organization member, zero
+// role is returned if the user is not a member or has not accepted the
+// invitation yet.
+func (db *DBStorage) FindOrganizationRole(ctx context.Context, orgID int, userID int) (models.OrganizationRole, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT "role" FROM "organization_members"
+		 WHERE "organization_id" = $1 AND "user_id" = $2 AND "accepted_at" IS NOT NULL`,
+		orgID, userID,
+	)
+	var role models.OrganizationRole
+	if err := row.Scan(&role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to find organization role: %w", err)
+	}
+
+	return role, nil
+}
+
+// FindVaultAccess returns the access of the user to secrets of the vault
+// given by the role of the user in the vault organization, zero access is
+// returned if the user is not a member or the vault does not exist.
+func (db *DBStorage) FindVaultAccess(ctx context.Context, vaultID int, userID int) (models.SecretAccess, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT "organization_members"."role"
+		 FROM "vaults"
+		 JOIN "organization_members" ON "organization_members"."organization_id" = "vaults"."organization_id"
+		 WHERE "vaults"."id" = $1 AND "organization_members"."user_id" = $2
+		   AND "organization_members"."accepted_at" IS NOT NULL`,
+		vaultID, userID,
+	)
+	var role models.OrganizationRole
+	if err := row.Scan(&role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to find vault access: %w", err)
+	}
+
+	return role.SecretAccess(), nil
+}
+
+// FindVaultAccesses returns the vault access by user id for users from
+// userIDs, users who are not members of the vault organization are omitted.
+func (db *DBStorage) FindVaultAccesses(
+	ctx context.Context,
+	vaultID int,
+	userIDs []int) (map[int]models.SecretAccess, error) {
+
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT "organization_members"."user_id", "organization_members"."role"
+		 FROM "vaults"
+		 JOIN "organization_members" ON "organization_members"."organization_id" = "vaults"."organization_id"
+		 WHERE "vaults"."id" = $1 AND "organization_members"."user_id" = ANY($2)
+		   AND "organization_members"."accepted_at" IS NOT NULL`,
+		vaultID, userIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find vault accesses: %w", err)
+	}
+	members, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.OrganizationMember, error) {
+		var member models.OrganizationMember
+		err := row.Scan(&member.UserID, &member.Role)
+		return member, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find vault accesses: %w", err)
+	}
+	accesses := make(map[int]models.SecretAccess, len(members))
+	for _, member := range members {
+		accesses[member.UserID] = member.Role.SecretAccess()
+	}
+
+	return accesses, nil
+}
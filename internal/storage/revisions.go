@@ -0,0 +1,298 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+func (db *DBStorage) ListSecretRevisions(ctx context.Context, secretID int) ([]models.SecretRevision, error) {
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT "id", "version", "description", "size", "created_at"
+		 FROM "secret_revisions"
+		 WHERE "secret_id" = $1
+		 ORDER BY "version" DESC`,
+		secretID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch secret revisions: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.SecretRevision, error) {
+		revision := models.SecretRevision{SecretID: secretID}
+		err := row.Scan(
+			&revision.ID,
+			&revision.Version,
+			&revision.Description,
+			&revision.Size,
+			&revision.CreatedAt,
+		)
+		return revision, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch secret revisions: %w", err)
+	}
+
+	return result, nil
+}
+
+func (db *DBStorage) FindSecretRevision(ctx context.Context, secretID int, version int) (models.SecretRevision, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT "id", "description", "encrypted_data", "encrypted_key", "encrypted_metadata", "created_at"
+		 FROM "secret_revisions"
+		 WHERE "secret_id" = $1 AND "version" = $2`,
+		secretID, version,
+	)
+	revision := models.SecretRevision{SecretID: secretID, Version: version}
+	err := row.Scan(
+		&revision.ID,
+		&revision.Description,
+		&revision.EncryptedData,
+		&revision.EncryptedKey,
+		&revision.EncryptedMetadata,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return revision, ErrRevisionNotFound{Revision: revision}
+		}
+		return revision, fmt.Errorf("failed to find secret revision: %w", err)
+	}
+
+	return revision, nil
+}
+
+func (db *DBStorage) FindRevisionChunk(ctx context.Context, revisionID int, idx int) ([]byte, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT "secret_chunk_data"."data"
+		 FROM "secret_revision_chunks"
+		 JOIN "secret_chunk_data" ON "secret_chunk_data"."id" = "secret_revision_chunks"."data_id"
+		 WHERE "secret_revision_chunks"."revision_id" = $1 AND "secret_revision_chunks"."idx" = $2`,
+		revisionID, idx,
+	)
+	var data []byte
+	if err := row.Scan(&data); err != nil {
+		return nil, fmt.Errorf("failed to find revision chunk: %w", err)
+	}
+
+	return data, nil
+}
+
+// RestoreSecretRevision makes the revision the current secret version.
+// The replaced version is saved as a new revision.
+func (db *DBStorage) RestoreSecretRevision(
+	ctx context.Context,
+	secretID int,
+	version int,
+	event models.AuditEvent) error {
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockSecret(ctx, tx, secretID, 0); err != nil {
+		return err
+	}
+	// the revision is found before the current version is saved, since
+	// saving may drop it from the retained revisions
+	revision := models.SecretRevision{SecretID: secretID, Version: version}
+	err = tx.QueryRow(
+		ctx,
+		`SELECT "id" FROM "secret_revisions" WHERE "secret_id" = $1 AND "version" = $2`,
+		secretID, version,
+	).Scan(&revision.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrRevisionNotFound{Revision: revision}
+		}
+		return fmt.Errorf("failed to find secret revision: %w", err)
+	}
+	if err := saveRevision(ctx, tx, secretID); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM "secret_chunks" WHERE "secret_id" = $1`, secretID)
+	if err != nil {
+		return fmt.Errorf("failed to delete secret chunks: %w", err)
+	}
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO "secret_chunks" ("secret_id", "idx", "size", "data_id")
+		 SELECT $1, "idx", "size", "data_id" FROM "secret_revision_chunks" WHERE "revision_id" = $2`,
+		secretID, revision.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to restore secret chunks: %w", err)
+	}
+	// the secret row is updated last, since it locks the owner row
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE "secrets"
+		 SET "description" = "secret_revisions"."description",
+		     "encrypted_data" = "secret_revisions"."encrypted_data",
+		     "size" = "secret_revisions"."size",
+		     "encrypted_key" = "secret_revisions"."encrypted_key",
+		     "encrypted_metadata" = "secret_revisions"."encrypted_metadata",
+		     "updated_at" = now()
+		 FROM "secret_revisions"
+		 WHERE "secrets"."id" = $1 AND "secret_revisions"."id" = $2`,
+		secretID, revision.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to restore secret: %w", err)
+	}
+	if err := pruneRevisions(ctx, tx, secretID); err != nil {
+		return err
+	}
+	if err := appendSecretAuditEvent(ctx, tx, event, secretID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to restore secret: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateUserSettings saves user settings and drops secret revisions
+// exceeding the new retention.
+func (db *DBStorage) UpdateUserSettings(ctx context.Context, userID int, settings models.UserSettings) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE "users" SET "revisions_retention" = $1 WHERE "id" = $2`,
+		settings.RevisionsRetention, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user settings: %w", err)
+	}
+	_, err = tx.Exec(
+		ctx,
+		`DELETE FROM "secret_revisions" WHERE "id" IN (
+		   SELECT "id" FROM (
+		     SELECT "secret_revisions"."id",
+		            row_number() OVER (PARTITION BY "secret_id" ORDER BY "version" DESC) AS "position"
+		     FROM "secret_revisions"
+		     JOIN "secrets" ON "secrets"."id" = "secret_revisions"."secret_id"
+		     WHERE "secrets"."user_id" = $1
+		   ) AS "ranked"
+		   WHERE "position" > $2
+		 )`,
+		userID, settings.RevisionsRetention,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete secret revisions: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to update user settings: %w", err)
+	}
+
+	return nil
+}
+
+// archiveSecret saves the current secret version as a revision before
+// the secret is changed in tx. The change is rejected if the secret version
+// is not expectedVersion, zero expectedVersion is not checked.
+func archiveSecret(ctx context.Context, tx pgx.Tx, secretID int, expectedVersion int) error {
+	if err := lockSecret(ctx, tx, secretID, expectedVersion); err != nil {
+		return err
+	}
+	if err := saveRevision(ctx, tx, secretID); err != nil {
+		return err
+	}
+
+	return pruneRevisions(ctx, tx, secretID)
+}
+
+// lockSecret locks the secret row until the end of tx, so concurrent
+// changes get sequential revision versions.
+func lockSecret(ctx context.Context, tx pgx.Tx, secretID int, expectedVersion int) error {
+	var version int
+	err := tx.QueryRow(
+		ctx,
+		`SELECT "revision" FROM "secrets" WHERE "id" = $1 AND "deleted_at" IS NULL FOR UPDATE`,
+		secretID,
+	).Scan(&version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSecretNotFound{Secret: models.Secret{ID: secretID}}
+		}
+		return fmt.Errorf("failed to lock secret: %w", err)
+	}
+	if expectedVersion != 0 && version != expectedVersion {
+		return ErrSecretVersionMismatch{Secret: models.Secret{ID: secretID, Version: expectedVersion}}
+	}
+
+	return nil
+}
+
+// saveRevision copies the secret into a new revision. The revision refers
+// to the chunk data of the secret instead of copying it, the data is deleted
+// by a trigger once neither the secret nor its revisions refer to it.
+func saveRevision(ctx context.Context, tx pgx.Tx, secretID int) error {
+	var revisionID int
+	err := tx.QueryRow(
+		ctx,
+		`INSERT INTO "secret_revisions" (
+		   "secret_id", "version", "description", "encrypted_data", "size", "encrypted_key", "encrypted_metadata", "created_at"
+		 )
+		 SELECT "id",
+		        COALESCE((SELECT max("version") FROM "secret_revisions" WHERE "secret_id" = $1), 0) + 1,
+		        "description", "encrypted_data", "size", "encrypted_key", "encrypted_metadata", "updated_at"
+		 FROM "secrets"
+		 WHERE "id" = $1
+		 RETURNING "id"`,
+		secretID,
+	).Scan(&revisionID)
+	if err != nil {
+		return fmt.Errorf("failed to save secret revision: %w", err)
+	}
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO "secret_revision_chunks" ("revision_id", "idx", "size", "data_id")
+		 SELECT $1, "idx", "size", "data_id" FROM "secret_chunks" WHERE "secret_id" = $2`,
+		revisionID, secretID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save secret revision chunks: %w", err)
+	}
+
+	return nil
+}
+
+// pruneRevisions keeps only the latest revisions according to the
+// retention of the secret owner.
+func pruneRevisions(ctx context.Context, tx pgx.Tx, secretID int) error {
+	_, err := tx.Exec(
+		ctx,
+		`DELETE FROM "secret_revisions"
+		 WHERE "secret_id" = $1 AND "id" NOT IN (
+		   SELECT "id" FROM "secret_revisions"
+		   WHERE "secret_id" = $1
+		   ORDER BY "version" DESC
+		   LIMIT (
+		     SELECT "users"."revisions_retention" FROM "users"
+		     JOIN "secrets" ON "secrets"."user_id" = "users"."id"
+		     WHERE "secrets"."id" = $1
+		   )
+		 )`,
+		secretID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete secret revisions: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,384 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const serviceAccountColumns = `"service_accounts"."id", "service_accounts"."user_id", "service_accounts"."organization_id",
+		        "service_accounts"."name", "users"."login", COALESCE("service_accounts"."created_by", 0),
+		        "service_accounts"."disabled_at" IS NOT NULL, "service_accounts"."created_at"`
+
+const serviceAccountCredentialColumns = `"id", "service_account_id", "type", COALESCE("public_key", ''),
+		        "last_used_at", "created_at"`
+
+// CreateServiceAccount creates the service account with the user backing it
+// and makes the user a member of the organization with the role.
+func (db *DBStorage) CreateServiceAccount(
+	ctx context.Context,
+	account models.ServiceAccount,
+	role models.OrganizationRole) (models.ServiceAccount, error) {
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return account, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// the user has no password, so nobody can log in as the service account
+	row := tx.QueryRow(
+		ctx,
+		`INSERT INTO "users" ("login", "encrypted_password") VALUES ($1, '') RETURNING "id"`,
+		account.ClientID,
+	)
+	if err := row.Scan(&account.UserID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return account, ErrServiceAccountNotUniq{Account: account}
+		}
+		return account, fmt.Errorf("failed to create service account user: %w", err)
+	}
+	row = tx.QueryRow(
+		ctx,
+		`INSERT INTO "service_accounts" ("user_id", "organization_id", "name", "created_by")
+		 VALUES ($1, $2, $3, $4) RETURNING "id", "created_at"`,
+		account.UserID, account.OrganizationID, account.Name, account.CreatedBy,
+	)
+	if err := row.Scan(&account.ID, &account.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return account, ErrServiceAccountNotUniq{Account: account}
+		}
+		return account, fmt.Errorf("failed to create service account: %w", err)
+	}
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO "organization_members" ("organization_id", "user_id", "role", "accepted_at")
+		 VALUES ($1, $2, $3, now())`,
+		account.OrganizationID, account.UserID, role,
+	)
+	if err != nil {
+		return account, fmt.Errorf("failed to add service account to organization: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return account, fmt.Errorf("failed to create service account: %w", err)
+	}
+
+	return account, nil
+}
+
+func (db *DBStorage) FindServiceAccount(ctx context.Context, id int) (models.ServiceAccount, error) {
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT `+serviceAccountColumns+`
+		 FROM "service_accounts"
+		 JOIN "users" ON "users"."id" = "service_accounts"."user_id"
+		 WHERE "service_accounts"."id" = $1`,
+		id,
+	)
+	if err != nil {
+		return models.ServiceAccount{}, fmt.Errorf("failed to find service account: %w", err)
+	}
+	account, err := pgx.CollectOneRow(rows, scanServiceAccount)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return account, ErrServiceAccountNotFound{ID: id}
+		}
+		return account, fmt.Errorf("failed to find service account: %w", err)
+	}
+
+	return account, nil
+}
+
+// FindServiceAccountByClientID returns ErrServiceAccountNotFound if there
+// is no service account with the client id, disabled accounts are
+// returned too.
+func (db *DBStorage) FindServiceAccountByClientID(ctx context.Context, clientID string) (models.ServiceAccount, error) {
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT `+serviceAccountColumns+`
+		 FROM "service_accounts"
+		 JOIN "users" ON "users"."id" = "service_accounts"."user_id"
+		 WHERE "users"."login" = $1`,
+		clientID,
+	)
+	if err != nil {
+		return models.ServiceAccount{}, fmt.Errorf("failed to find service account: %w", err)
+	}
+	account, err := pgx.CollectOneRow(rows, scanServiceAccount)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return account, ErrServiceAccountNotFound{}
+		}
+		return account, fmt.Errorf("failed to find service account: %w", err)
+	}
+
+	return account, nil
+}
+
+func (db *DBStorage) ListOrganizationServiceAccounts(ctx context.Context, orgID int) ([]models.ServiceAccount, error) {
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT `+serviceAccountColumns+`
+		 FROM "service_accounts"
+		 JOIN "users" ON "users"."id" = "service_accounts"."user_id"
+		 WHERE "service_accounts"."organization_id" = $1
+		 ORDER BY "service_accounts"."name"`,
+		orgID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch service accounts: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, scanServiceAccount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch service accounts: %w", err)
+	}
+
+	return result, nil
+}
+
+// DisableServiceAccount disables the service account, its credentials
+// and access tokens are rejected from now on.
+func (db *DBStorage) DisableServiceAccount(ctx context.Context, id int) error {
+	tag, err := db.pool.Exec(
+		ctx,
+		`UPDATE "service_accounts" SET "disabled_at" = COALESCE("disabled_at", now()) WHERE "id" = $1`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to disable service account: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrServiceAccountNotFound{ID: id}
+	}
+
+	return nil
+}
+
+// IsServiceAccountDisabled reports whether the service account has been
+// disabled, service accounts which do not exist are disabled too.
+func (db *DBStorage) IsServiceAccountDisabled(ctx context.Context, id int) (bool, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM "service_accounts" WHERE "id" = $1 AND "disabled_at" IS NULL)`,
+		id,
+	)
+	var active bool
+	if err := row.Scan(&active); err != nil {
+		return false, fmt.Errorf("failed to check service account: %w", err)
+	}
+
+	return !active, nil
+}
+
+// CreateServiceAccountCredential stores the credential, secretHash is nil
+// for public key credentials.
+func (db *DBStorage) CreateServiceAccountCredential(
+	ctx context.Context,
+	credential models.ServiceAccountCredential,
+	secretHash []byte) (models.ServiceAccountCredential, error) {
+
+	var publicKey *string
+	if credential.PublicKey != "" {
+		publicKey = &credential.PublicKey
+	}
+	row := db.pool.QueryRow(
+		ctx,
+		`INSERT INTO "service_account_credentials" ("service_account_id", "type", "secret_hash", "public_key")
+		 VALUES ($1, $2, $3, $4) RETURNING "id", "created_at"`,
+		credential.ServiceAccountID, credential.Type, secretHash, publicKey,
+	)
+	if err := row.Scan(&credential.ID, &credential.CreatedAt); err != nil {
+		return credential, fmt.Errorf("failed to create service account credential: %w", err)
+	}
+
+	return credential, nil
+}
+
+// FindServiceAccountCredential returns ErrServiceAccountCredentialNotFound
+// if the credential does not exist or belongs to another service account.
+func (db *DBStorage) FindServiceAccountCredential(
+	ctx context.Context,
+	accountID int,
+	id int) (models.ServiceAccountCredential, error) {
+
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT `+serviceAccountCredentialColumns+`
+		 FROM "service_account_credentials"
+		 WHERE "id" = $1 AND "service_account_id" = $2`,
+		id, accountID,
+	)
+	if err != nil {
+		return models.ServiceAccountCredential{}, fmt.Errorf("failed to find service account credential: %w", err)
+	}
+	credential, err := pgx.CollectOneRow(rows, scanServiceAccountCredential)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return credential, ErrServiceAccountCredentialNotFound{ID: id}
+		}
+		return credential, fmt.Errorf("failed to find service account credential: %w", err)
+	}
+
+	return credential, nil
+}
+
+// FindServiceAccountSecret returns the client secret credential of the
+// service account with the hash.
+func (db *DBStorage) FindServiceAccountSecret(
+	ctx context.Context,
+	accountID int,
+	secretHash []byte) (models.ServiceAccountCredential, error) {
+
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT `+serviceAccountCredentialColumns+`
+		 FROM "service_account_credentials"
+		 WHERE "secret_hash" = $1 AND "service_account_id" = $2`,
+		secretHash, accountID,
+	)
+	if err != nil {
+		return models.ServiceAccountCredential{}, fmt.Errorf("failed to find service account secret: %w", err)
+	}
+	credential, err := pgx.CollectOneRow(rows, scanServiceAccountCredential)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return credential, ErrServiceAccountCredentialNotFound{}
+		}
+		return credential, fmt.Errorf("failed to find service account secret: %w", err)
+	}
+
+	return credential, nil
+}
+
+func (db *DBStorage) ListServiceAccountCredentials(
+	ctx context.Context,
+	accountID int) ([]models.ServiceAccountCredential, error) {
+
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT `+serviceAccountCredentialColumns+`
+		 FROM "service_account_credentials"
+		 WHERE "service_account_id" = $1
+		 ORDER BY "id"`,
+		accountID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch service account credentials: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, scanServiceAccountCredential)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch service account credentials: %w", err)
+	}
+
+	return result, nil
+}
+
+// DeleteServiceAccountCredential returns ErrServiceAccountCredentialNotFound
+// if the credential does not exist or belongs to another service account.
+func (db *DBStorage) DeleteServiceAccountCredential(ctx context.Context, accountID int, id int) error {
+	tag, err := db.pool.Exec(
+		ctx,
+		`DELETE FROM "service_account_credentials" WHERE "id" = $1 AND "service_account_id" = $2`,
+		id, accountID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete service account credential: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrServiceAccountCredentialNotFound{ID: id}
+	}
+
+	return nil
+}
+
+func (db *DBStorage) TouchServiceAccountCredential(ctx context.Context, id int) error {
+	_, err := db.pool.Exec(
+		ctx,
+		`UPDATE "service_account_credentials" SET "last_used_at" = now() WHERE "id" = $1`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update service account credential: %w", err)
+	}
+
+	return nil
+}
+
+// CreateServiceAccountAssertion records the id of the accepted assertion,
+// ErrServiceAccountAssertionNotUniq is returned if it has been used
+// already.
+func (db *DBStorage) CreateServiceAccountAssertion(
+	ctx context.Context,
+	accountID int,
+	jti string,
+	expiresAt time.Time) error {
+
+	_, err := db.pool.Exec(
+		ctx,
+		`INSERT INTO "service_account_assertions" ("service_account_id", "jti", "expires_at")
+		 VALUES ($1, $2, $3)`,
+		accountID, jti, expiresAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return ErrServiceAccountAssertionNotUniq{JTI: jti}
+		}
+		return fmt.Errorf("failed to record assertion: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredServiceAccountAssertions deletes ids of assertions expired
+// before the time, such assertions are rejected anyway.
+func (db *DBStorage) DeleteExpiredServiceAccountAssertions(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := db.pool.Exec(ctx, `DELETE FROM "service_account_assertions" WHERE "expires_at" < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired assertions: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func scanServiceAccount(row pgx.CollectableRow) (models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	err := row.Scan(
+		&account.ID,
+		&account.UserID,
+		&account.OrganizationID,
+		&account.Name,
+		&account.ClientID,
+		&account.CreatedBy,
+		&account.Disabled,
+		&account.CreatedAt,
+	)
+
+	return account, err
+}
+
+func scanServiceAccountCredential(row pgx.CollectableRow) (models.ServiceAccountCredential, error) {
+	var credential models.ServiceAccountCredential
+	var lastUsedAt *time.Time
+	err := row.Scan(
+		&credential.ID,
+		&credential.ServiceAccountID,
+		&credential.Type,
+		&credential.PublicKey,
+		&lastUsedAt,
+		&credential.CreatedAt,
+	)
+	if lastUsedAt != nil {
+		credential.LastUsedAt = *lastUsedAt
+	}
+
+	return credential, err
+}
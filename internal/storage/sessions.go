@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateSession creates a session of the user with the refresh token and
+// returns the session id.
+func (db *DBStorage) CreateSession(
+	ctx context.Context,
+	userID int,
+	refreshTokenHash []byte,
+	expiresAt time.Time) (int, error) {
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var sessionID int
+	row := tx.QueryRow(ctx, `INSERT INTO "sessions" ("user_id") VALUES ($1) RETURNING "id"`, userID)
+	if err := row.Scan(&sessionID); err != nil {
+		return 0, fmt.Errorf("failed to create session: %w", err)
+	}
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO "refresh_tokens" ("token_hash", "session_id", "expires_at") VALUES ($1, $2, $3)`,
+		refreshTokenHash, sessionID, expiresAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return sessionID, nil
+}
+
+func (db *DBStorage) FindRefreshToken(ctx context.Context, refreshTokenHash []byte) (models.RefreshToken, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT "refresh_tokens"."session_id", "sessions"."user_id", "refresh_tokens"."expires_at",
+		        "refresh_tokens"."used_at" IS NOT NULL, "sessions"."revoked_at" IS NOT NULL
+		 FROM "refresh_tokens"
+		 JOIN "sessions" ON "sessions"."id" = "refresh_tokens"."session_id"
+		 WHERE "refresh_tokens"."token_hash" = $1`,
+		refreshTokenHash,
+	)
+	var token models.RefreshToken
+	err := row.Scan(&token.SessionID, &token.UserID, &token.ExpiresAt, &token.Used, &token.SessionRevoked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return token, ErrRefreshTokenNotFound{}
+		}
+		return token, fmt.Errorf("failed to find refresh token: %w", err)
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken marks the refresh token as used and adds the new
+// token to its session. False is returned if the token has been used,
+// has expired or its session has been revoked.
+func (db *DBStorage) RotateRefreshToken(
+	ctx context.Context,
+	refreshTokenHash []byte,
+	newRefreshTokenHash []byte,
+	expiresAt time.Time) (bool, error) {
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var sessionID int
+	row := tx.QueryRow(
+		ctx,
+		`UPDATE "refresh_tokens" SET "used_at" = now()
+		 WHERE "token_hash" = $1 AND "used_at" IS NULL AND "expires_at" > now()
+		   AND "session_id" IN (SELECT "id" FROM "sessions" WHERE "revoked_at" IS NULL)
+		 RETURNING "session_id"`,
+		refreshTokenHash,
+	)
+	if err := row.Scan(&sessionID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to use refresh token: %w", err)
+	}
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO "refresh_tokens" ("token_hash", "session_id", "expires_at") VALUES ($1, $2, $3)`,
+		newRefreshTokenHash, sessionID, expiresAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	return true, nil
+}
+
+func (db *DBStorage) RevokeSession(ctx context.Context, sessionID int) error {
+	_, err := db.pool.Exec(
+		ctx,
+		`UPDATE "sessions" SET "revoked_at" = now() WHERE "id" = $1 AND "revoked_at" IS NULL`,
+		sessionID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+// ListUserSessions returns sessions of the user which have not been
+// revoked and have a refresh token which can still be used.
+func (db *DBStorage) ListUserSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT "sessions"."id", "sessions"."user_id", "sessions"."created_at",
+		        max("refresh_tokens"."expires_at")
+		 FROM "sessions"
+		 JOIN "refresh_tokens" ON "refresh_tokens"."session_id" = "sessions"."id"
+		 WHERE "sessions"."user_id" = $1 AND "sessions"."revoked_at" IS NULL
+		   AND "refresh_tokens"."used_at" IS NULL AND "refresh_tokens"."expires_at" > now()
+		 GROUP BY "sessions"."id"
+		 ORDER BY "sessions"."id"`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sessions: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Session, error) {
+		var session models.Session
+		err := row.Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.ExpiresAt)
+		return session, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sessions: %w", err)
+	}
+
+	return result, nil
+}
+
+// RevokeUserSession returns ErrSessionNotFound if the session does not
+// exist or belongs to another user. Revoking a revoked session does
+// nothing.
+func (db *DBStorage) RevokeUserSession(ctx context.Context, userID int, sessionID int) error {
+	tag, err := db.pool.Exec(
+		ctx,
+		`UPDATE "sessions" SET "revoked_at" = COALESCE("revoked_at", now())
+		 WHERE "id" = $1 AND "user_id" = $2`,
+		sessionID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound{ID: sessionID}
+	}
+
+	return nil
+}
+
+// RevokeUserSessions revokes all sessions of the user except the session
+// with exceptSessionID and returns the number of revoked sessions.
+func (db *DBStorage) RevokeUserSessions(ctx context.Context, userID int, exceptSessionID int) (int64, error) {
+	tag, err := db.pool.Exec(
+		ctx,
+		`UPDATE "sessions" SET "revoked_at" = now()
+		 WHERE "user_id" = $1 AND "id" <> $2 AND "revoked_at" IS NULL`,
+		userID, exceptSessionID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// IsSessionRevoked reports whether the session has been revoked, sessions
+// which do not exist are revoked too.
+func (db *DBStorage) IsSessionRevoked(ctx context.Context, sessionID int) (bool, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM "sessions" WHERE "id" = $1 AND "revoked_at" IS NULL)`,
+		sessionID,
+	)
+	var active bool
+	if err := row.Scan(&active); err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+
+	return !active, nil
+}
+
+// DeleteExpiredSessions deletes refresh tokens expired before the time,
+// sessions revoked before it and sessions left without refresh tokens.
+func (db *DBStorage) DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM "refresh_tokens" WHERE "expires_at" < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired refresh tokens: %w", err)
+	}
+	tag, err := tx.Exec(
+		ctx,
+		`DELETE FROM "sessions"
+		 WHERE "revoked_at" < $1
+		    OR NOT EXISTS (SELECT 1 FROM "refresh_tokens" WHERE "session_id" = "sessions"."id")`,
+		before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveSecretShare shares the secret with the user or changes the access
+// of the existing share.
+func (db *DBStorage) SaveSecretShare(ctx context.Context, share models.SecretShare) (models.SecretShare, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`INSERT INTO "secret_shares" ("secret_id", "user_id", "access")
+		 VALUES (@secretID, @userID, @access)
+		 ON CONFLICT ("secret_id", "user_id") DO UPDATE SET "access" = EXCLUDED."access"
+		 RETURNING "created_at"`,
+		pgx.NamedArgs{
+			"secretID": share.SecretID,
+			"userID":   share.UserID,
+			"access":   share.Access,
+		},
+	)
+	if err := row.Scan(&share.CreatedAt); err != nil {
+		return share, fmt.Errorf("failed to save secret share: %w", err)
+	}
+
+	return share, nil
+}
+
+// ListSecretShares returns users the secret is shared with ordered by login.
+func (db *DBStorage) ListSecretShares(ctx context.Context, secretID int) ([]models.SecretShare, error) {
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT "secret_shares"."user_id", "users"."login", "secret_shares"."access", "secret_shares"."created_at"
+		 FROM "secret_shares"
+		 JOIN "users" ON "users"."id" = "secret_shares"."user_id"
+		 WHERE "secret_shares"."secret_id" = $1
+		 ORDER BY "users"."login"`,
+		secretID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch secret shares: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.SecretShare, error) {
+		share := models.SecretShare{SecretID: secretID}
+		err := row.Scan(&share.UserID, &share.Login, &share.Access, &share.CreatedAt)
+		return share, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch secret shares: %w", err)
+	}
+
+	return result, nil
+}
+
+// FindSecretShareAccess returns the access the secret is shared with the
+// user, zero if it is not shared.
+func (db *DBStorage) FindSecretShareAccess(ctx context.Context, secretID int, userID int) (models.SecretAccess, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT "access" FROM "secret_shares" WHERE "secret_id" = $1 AND "user_id" = $2`,
+		secretID, userID,
+	)
+	var access models.SecretAccess
+	if err := row.Scan(&access); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to find secret share: %w", err)
+	}
+
+	return access, nil
+}
+
+// FindSecretShareAccesses returns the access the secret is shared with by
+// user id for users from userIDs, users it is not shared with are omitted.
+func (db *DBStorage) FindSecretShareAccesses(
+	ctx context.Context,
+	secretID int,
+	userIDs []int) (map[int]models.SecretAccess, error) {
+
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT "user_id", "access" FROM "secret_shares" WHERE "secret_id" = $1 AND "user_id" = ANY($2)`,
+		secretID, userIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find secret shares: %w", err)
+	}
+	shares, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.SecretShare, error) {
+		share := models.SecretShare{SecretID: secretID}
+		err := row.Scan(&share.UserID, &share.Access)
+		return share, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find secret shares: %w", err)
+	}
+	accesses := make(map[int]models.SecretAccess, len(shares))
+	for _, share := range shares {
+		accesses[share.UserID] = share.Access
+	}
+
+	return accesses, nil
+}
+
+// DeleteSecretShare revokes access of the user to the secret.
+func (db *DBStorage) DeleteSecretShare(ctx context.Context, secretID int, userID int) error {
+	tag, err := db.pool.Exec(
+		ctx,
+		`DELETE FROM "secret_shares" WHERE "secret_id" = $1 AND "user_id" = $2`,
+		secretID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete secret share: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSecretShareNotFound{Share: models.SecretShare{SecretID: secretID, UserID: userID}}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// UserSecretsRevision returns the revision of the latest user secrets change.
+func (db *DBStorage) UserSecretsRevision(ctx context.Context, userID int) (int, error) {
+	row := db.pool.QueryRow(ctx, `SELECT "secrets_revision" FROM "users" WHERE "id" = $1`, userID)
+	var revision int
+	if err := row.Scan(&revision); err != nil {
+		return 0, fmt.Errorf("failed to find user secrets revision: %w", err)
+	}
+
+	return revision, nil
+}
+
+// ListSecretChanges returns up to limit changes of personal user secrets with
+// revisions in (since, until], oldest first. Trashed secrets are reported
+// as deleted and secrets restored from the trash as created.
+func (db *DBStorage) ListSecretChanges(
+	ctx context.Context,
+	userID int,
+	since int,
+	until int,
+	limit int) ([]models.SecretChange, error) {
+
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT "id", "revision", "created_revision" > @since, "deleted_at" IS NOT NULL,
+		        "type", "description", "encrypted_data", "encrypted_key", "encrypted_metadata",
+		        COALESCE("folder_id", 0)
+		 FROM "secrets"
+		 WHERE "user_id" = @userID AND "vault_id" IS NULL AND "revision" > @since AND "revision" <= @until
+		 UNION ALL
+		 SELECT "secret_id", "revision", false, true, 0, '', NULL, NULL, NULL, 0
+		 FROM "secret_tombstones"
+		 WHERE "user_id" = @userID AND "revision" > @since AND "revision" <= @until
+		 ORDER BY 2
+		 LIMIT @limit`,
+		pgx.NamedArgs{
+			"userID": userID,
+			"since":  since,
+			"until":  until,
+			"limit":  limit,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch secret changes: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.SecretChange, error) {
+		change := models.SecretChange{Secret: models.Secret{UserID: userID}}
+		var created, deleted bool
+		err := row.Scan(
+			&change.Secret.ID,
+			&change.Revision,
+			&created,
+			&deleted,
+			&change.Secret.SecretType,
+			&change.Secret.Description,
+			&change.Secret.EncryptedData,
+			&change.Secret.EncryptedKey,
+			&change.Secret.EncryptedMetadata,
+			&change.Secret.FolderID,
+		)
+		switch {
+		case deleted:
+			change.Kind = models.SecretDeleted
+			change.Secret = models.Secret{ID: change.Secret.ID, UserID: userID}
+		case created:
+			change.Kind = models.SecretCreated
+		default:
+			change.Kind = models.SecretUpdated
+		}
+		return change, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch secret changes: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (db *DBStorage) CreateTag(ctx context.Context, userID int, name string) (models.Tag, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`INSERT INTO "tags" ("user_id", "name") VALUES ($1, $2) RETURNING "id"`,
+		userID, name,
+	)
+	tag := models.Tag{UserID: userID, Name: name}
+	if err := row.Scan(&tag.ID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return tag, ErrTagNotUniq{Tag: tag}
+		}
+		return tag, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+// FindUserTag returns ErrTagNotFound if the tag does not exist or belongs
+// to another user.
+func (db *DBStorage) FindUserTag(ctx context.Context, userID int, id int) (models.Tag, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT "name" FROM "tags" WHERE "id" = $1 AND "user_id" = $2`,
+		id, userID,
+	)
+	tag := models.Tag{ID: id, UserID: userID}
+	if err := row.Scan(&tag.Name); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tag, ErrTagNotFound{Tag: tag}
+		}
+		return tag, fmt.Errorf("failed to find tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+func (db *DBStorage) ListUserTags(ctx context.Context, userID int) ([]models.Tag, error) {
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT "id", "name" FROM "tags" WHERE "user_id" = $1 ORDER BY "name"`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user tags: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Tag, error) {
+		tag := models.Tag{UserID: userID}
+		err := row.Scan(&tag.ID, &tag.Name)
+		return tag, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user tags: %w", err)
+	}
+
+	return result, nil
+}
+
+func (db *DBStorage) RenameTag(ctx context.Context, tag models.Tag) error {
+	_, err := db.pool.Exec(
+		ctx,
+		`UPDATE "tags" SET "name" = $1 WHERE "id" = $2`,
+		tag.Name, tag.ID,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return ErrTagNotUniq{Tag: tag}
+		}
+		return fmt.Errorf("failed to rename tag: %w", err)
+	}
+
+	return nil
+}
+
+// SetSecretTags replaces the secret tags in a single transaction.
+func (db *DBStorage) SetSecretTags(ctx context.Context, secretID int, tagIDs []int) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM "secret_tags" WHERE "secret_id" = $1`, secretID)
+	if err != nil {
+		return fmt.Errorf("failed to delete secret tags: %w", err)
+	}
+	if len(tagIDs) > 0 {
+		_, err = tx.Exec(
+			ctx,
+			`INSERT INTO "secret_tags" ("secret_id", "tag_id")
+			 SELECT $1, unnest($2::bigint[])
+			 ON CONFLICT DO NOTHING`,
+			secretID, tagIDs,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create secret tags: %w", err)
+		}
+	}
+	_, err = tx.Exec(ctx, `UPDATE "secrets" SET "updated_at" = now() WHERE "id" = $1`, secretID)
+	if err != nil {
+		return fmt.Errorf("failed to update secret: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+func (db *DBStorage) FindUserByID(ctx context.Context, id int) (models.User, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT "login", "encrypted_password", "totp_enabled_at" IS NOT NULL FROM "users" WHERE "id" = $1`,
+		id,
+	)
+	user := models.User{ID: id}
+	if err := row.Scan(&user.Login, &user.EncryptedPassword, &user.TOTPEnabled); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user, ErrUserNotFound{User: user}
+		}
+		return user, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	return user, nil
+}
+
+// FindUserTOTP returns the TOTP secret of the user, the secret is empty if
+// the user has not enrolled.
+func (db *DBStorage) FindUserTOTP(ctx context.Context, userID int) (models.UserTOTP, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT "encrypted_totp_secret", "encrypted_totp_key", "totp_enabled_at" IS NOT NULL, "totp_last_step"
+		 FROM "users" WHERE "id" = $1`,
+		userID,
+	)
+	var totp models.UserTOTP
+	err := row.Scan(&totp.EncryptedSecret, &totp.EncryptedKey, &totp.Enabled, &totp.LastStep)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return totp, ErrUserNotFound{User: models.User{ID: userID}}
+		}
+		return totp, fmt.Errorf("failed to find user TOTP: %w", err)
+	}
+
+	return totp, nil
+}
+
+// SaveUserTOTP replaces the unconfirmed TOTP secret of the user, false is
+// returned if TOTP is already enabled.
+func (db *DBStorage) SaveUserTOTP(
+	ctx context.Context,
+	userID int,
+	encryptedSecret []byte,
+	encryptedKey []byte) (bool, error) {
+
+	tag, err := db.pool.Exec(
+		ctx,
+		`UPDATE "users" SET "encrypted_totp_secret" = $2, "encrypted_totp_key" = $3, "totp_last_step" = 0
+		 WHERE "id" = $1 AND "totp_enabled_at" IS NULL`,
+		userID, encryptedSecret, encryptedKey,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to save user TOTP: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// EnableUserTOTP enables the saved TOTP secret of the user, accepts the
+// code of the step and replaces recovery codes. False is returned if TOTP
+// is already enabled.
+func (db *DBStorage) EnableUserTOTP(
+	ctx context.Context,
+	userID int,
+	step int64,
+	recoveryCodeHashes [][]byte) (bool, error) {
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(
+		ctx,
+		`UPDATE "users" SET "totp_enabled_at" = now(), "totp_last_step" = $2
+		 WHERE "id" = $1 AND "totp_enabled_at" IS NULL AND "encrypted_totp_secret" IS NOT NULL`,
+		userID, step,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to enable user TOTP: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM "recovery_codes" WHERE "user_id" = $1`, userID); err != nil {
+		return false, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, hash := range recoveryCodeHashes {
+		_, err := tx.Exec(
+			ctx,
+			`INSERT INTO "recovery_codes" ("user_id", "code_hash") VALUES ($1, $2)`,
+			userID, hash,
+		)
+		if err != nil {
+			return false, fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to enable user TOTP: %w", err)
+	}
+
+	return true, nil
+}
+
+// AcceptTOTPStep records that the code of the step has been used, false is
+// returned if a code of this or a later step has been used already.
+func (db *DBStorage) AcceptTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	tag, err := db.pool.Exec(
+		ctx,
+		`UPDATE "users" SET "totp_last_step" = $2
+		 WHERE "id" = $1 AND "totp_enabled_at" IS NOT NULL AND "totp_last_step" < $2`,
+		userID, step,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to accept TOTP code: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// UseRecoveryCode marks the recovery code as used, false is returned if
+// the user has no such unused code.
+func (db *DBStorage) UseRecoveryCode(ctx context.Context, userID int, codeHash []byte) (bool, error) {
+	tag, err := db.pool.Exec(
+		ctx,
+		`UPDATE "recovery_codes" SET "used_at" = now()
+		 WHERE "user_id" = $1 AND "code_hash" = $2 AND "used_at" IS NULL`,
+		userID, codeHash,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
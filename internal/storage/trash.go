@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// TrashSecret moves the secret to the trash if its version is still version,
+// zero version is not checked. Trashed secrets are hidden from everything
+// except the trash endpoints. The audit event is appended along with the change.
+func (db *DBStorage) TrashSecret(ctx context.Context, secretID int, version int, event models.AuditEvent) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockSecret(ctx, tx, secretID, version); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `UPDATE "secrets" SET "deleted_at" = now() WHERE "id" = $1`, secretID)
+	if err != nil {
+		return fmt.Errorf("failed to trash secret: %w", err)
+	}
+	if err := appendSecretAuditEvent(ctx, tx, event, secretID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to trash secret: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DBStorage) RestoreTrashedSecret(ctx context.Context, secretID int, event models.AuditEvent) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(
+		ctx,
+		`UPDATE "secrets" SET "deleted_at" = NULL WHERE "id" = $1 AND "deleted_at" IS NOT NULL`,
+		secretID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to restore secret: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSecretNotFound{Secret: models.Secret{ID: secretID}}
+	}
+	if err := appendSecretAuditEvent(ctx, tx, event, secretID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to restore secret: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DBStorage) FindTrashedSecret(ctx context.Context, id int) (models.Secret, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT "user_id", "type", "description", COALESCE("folder_id", 0), COALESCE("vault_id", 0), "revision"
+		 FROM "secrets"
+		 WHERE "id" = $1 AND "deleted_at" IS NOT NULL`,
+		id,
+	)
+	secret := models.Secret{ID: id}
+	err := row.Scan(
+		&secret.UserID,
+		&secret.SecretType,
+		&secret.Description,
+		&secret.FolderID,
+		&secret.VaultID,
+		&secret.Version,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return secret, ErrSecretNotFound{Secret: secret}
+		}
+		return secret, fmt.Errorf("failed to find trashed secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+// ListUserTrash returns trashed secrets the user can restore, the latest
+// deleted first. These are personal secrets of the user and secrets of vaults
+// where the role of the user allows to delete them.
+func (db *DBStorage) ListUserTrash(ctx context.Context, userID int) ([]models.SecretInfo, error) {
+	rows, err := db.pool.Query(
+		ctx,
+		`SELECT "id", "type", "description", "size", COALESCE("folder_id", 0), "created_at", "updated_at", "deleted_at"
+		 FROM "secrets"
+		 WHERE "deleted_at" IS NOT NULL
+		   AND (("vault_id" IS NULL AND "user_id" = @userID) OR `+vaultSecretsCondition+`)
+		 ORDER BY "deleted_at" DESC, "id"`,
+		pgx.NamedArgs{
+			"userID":    userID,
+			"vaultRole": models.OrganizationAdminRole,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trashed secrets: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.SecretInfo, error) {
+		var info models.SecretInfo
+		err := row.Scan(
+			&info.ID,
+			&info.SecretType,
+			&info.Description,
+			&info.Size,
+			&info.FolderID,
+			&info.CreatedAt,
+			&info.UpdatedAt,
+			&info.DeletedAt,
+		)
+		return info, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trashed secrets: %w", err)
+	}
+
+	return result, nil
+}
+
+// DeleteTrashedSecrets permanently deletes secrets trashed before the
+// given time.
+func (db *DBStorage) DeleteTrashedSecrets(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := db.pool.Exec(ctx, `DELETE FROM "secrets" WHERE "deleted_at" <= $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete trashed secrets: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
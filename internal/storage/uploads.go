@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ilya-burinskiy/gophkeeper/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+func (db *DBStorage) CreateUpload(ctx context.Context, upload models.Upload) error {
+	var secretID *int
+	if upload.SecretID != 0 {
+		secretID = &upload.SecretID
+	}
+	_, err := db.pool.Exec(
+		ctx,
+		`INSERT INTO "uploads" (
+		   "id", "user_id", "secret_id", "secret_version", "vault_id", "description", "length",
+		   "encrypted_data", "encrypted_key", "encrypted_metadata", "expires_at"
+		 ) VALUES (
+		   @id, @userID, @secretID, @secretVersion, @vaultID, @description, @length,
+		   @encryptedData, @encryptedKey, @encryptedMetadata, @expiresAt
+		 )`,
+		pgx.NamedArgs{
+			"id":                upload.ID,
+			"userID":            upload.UserID,
+			"secretID":          secretID,
+			"secretVersion":     upload.SecretVersion,
+			"vaultID":           nullableID(upload.VaultID),
+			"description":       upload.Description,
+			"length":            upload.Length,
+			"encryptedData":     upload.EncryptedData,
+			"encryptedKey":      upload.EncryptedKey,
+			"encryptedMetadata": upload.EncryptedMetadata,
+			"expiresAt":         upload.ExpiresAt,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	return nil
+}
+
+// FindUpload returns not expired upload.
+func (db *DBStorage) FindUpload(ctx context.Context, id string) (models.Upload, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT "user_id", COALESCE("secret_id", 0), "secret_version", COALESCE("vault_id", 0),
+		        "description", "length", "offset",
+		        "encrypted_data", "encrypted_key", "encrypted_hash_state", "encrypted_metadata",
+		        "completed", "expires_at"
+		 FROM "uploads"
+		 WHERE "id" = $1 AND "expires_at" > now()`,
+		id,
+	)
+	upload := models.Upload{ID: id}
+	err := row.Scan(
+		&upload.UserID,
+		&upload.SecretID,
+		&upload.SecretVersion,
+		&upload.VaultID,
+		&upload.Description,
+		&upload.Length,
+		&upload.Offset,
+		&upload.EncryptedData,
+		&upload.EncryptedKey,
+		&upload.EncryptedHashState,
+		&upload.EncryptedMetadata,
+		&upload.Completed,
+		&upload.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return upload, ErrUploadNotFound{Upload: upload}
+		}
+		return upload, fmt.Errorf("failed to find upload: %w", err)
+	}
+
+	return upload, nil
+}
+
+func (db *DBStorage) FindUploadChunk(ctx context.Context, uploadID string, idx int) ([]byte, error) {
+	row := db.pool.QueryRow(
+		ctx,
+		`SELECT "data" FROM "upload_chunks" WHERE "upload_id" = $1 AND "idx" = $2`,
+		uploadID, idx,
+	)
+	var data []byte
+	if err := row.Scan(&data); err != nil {
+		return nil, fmt.Errorf("failed to find upload chunk: %w", err)
+	}
+
+	return data, nil
+}
+
+// SaveUploadChunk saves or replaces upload chunk and moves upload offset
+// from offset to newOffset along with the content hash state. It returns
+// false if the upload offset has been changed concurrently or the upload
+// has been completed.
+func (db *DBStorage) SaveUploadChunk(
+	ctx context.Context,
+	uploadID string,
+	idx int,
+	data []byte,
+	size int,
+	offset int64,
+	newOffset int64,
+	encryptedHashState []byte) (bool, error) {
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(
+		ctx,
+		`UPDATE "uploads" SET "offset" = $1, "encrypted_hash_state" = $2
+		 WHERE "id" = $3 AND "offset" = $4 AND NOT "completed"`,
+		newOffset, encryptedHashState, uploadID, offset,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update upload offset: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO "upload_chunks" ("upload_id", "idx", "size", "data") VALUES ($1, $2, $3, $4)
+		 ON CONFLICT ("upload_id", "idx") DO UPDATE SET "size" = EXCLUDED."size", "data" = EXCLUDED."data"`,
+		uploadID, idx, size, data,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to save upload chunk: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to save upload chunk: %w", err)
+	}
+
+	return true, nil
+}
+
+// CompleteUpload moves upload chunks into a new secret or into the secret
+// being updated and marks the upload as completed. It returns the secret ID.
+// The audit event is appended along with the secret.
+func (db *DBStorage) CompleteUpload(ctx context.Context, upload models.Upload, event models.AuditEvent) (int, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// chunks are moved before the secret row is written, since it locks
+	// the owner row until the end of tx
+	secretID := upload.SecretID
+	if secretID == 0 {
+		if secretID, err = reserveSecretID(ctx, tx); err != nil {
+			return 0, err
+		}
+	} else {
+		if err := archiveSecret(ctx, tx, secretID, upload.SecretVersion); err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM "secret_chunks" WHERE "secret_id" = $1`, secretID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete secret chunks: %w", err)
+		}
+	}
+	_, err = tx.Exec(
+		ctx,
+		`WITH "chunks" AS (
+		   SELECT nextval(pg_get_serial_sequence('secret_chunk_data', 'id')) AS "data_id", "idx", "size", "data"
+		   FROM "upload_chunks"
+		   WHERE "upload_id" = $2
+		 ), "chunk_data" AS (
+		   INSERT INTO "secret_chunk_data" ("id", "data") SELECT "data_id", "data" FROM "chunks"
+		 )
+		 INSERT INTO "secret_chunks" ("secret_id", "idx", "size", "data_id")
+		 SELECT $1, "idx", "size", "data_id" FROM "chunks"`,
+		secretID, upload.ID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move upload chunks: %w", err)
+	}
+
+	if upload.SecretID == 0 {
+		_, err = tx.Exec(
+			ctx,
+			`INSERT INTO "secrets" (
+			   "id", "user_id", "vault_id", "type", "description", "encrypted_data", "size", "encrypted_key", "encrypted_metadata"
+			 ) VALUES (
+			   @id, @userID, @vaultID, @secretType, @description, @encryptedData, @size, @encryptedKey, @encryptedMetadata
+			 )`,
+			pgx.NamedArgs{
+				"id":                secretID,
+				"userID":            upload.UserID,
+				"vaultID":           nullableID(upload.VaultID),
+				"secretType":        models.BinDataSecret,
+				"description":       upload.Description,
+				"encryptedData":     upload.EncryptedData,
+				"size":              upload.Length,
+				"encryptedKey":      upload.EncryptedKey,
+				"encryptedMetadata": upload.EncryptedMetadata,
+			},
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create secret: %w", err)
+		}
+	} else {
+		tag, err := tx.Exec(
+			ctx,
+			`UPDATE "secrets"
+			 SET "encrypted_data" = $1, "size" = $2, "encrypted_key" = $3, "encrypted_metadata" = $4,
+			     "description" = $5, "updated_at" = now()
+			 WHERE "id" = $6`,
+			upload.EncryptedData, upload.Length, upload.EncryptedKey, upload.EncryptedMetadata, upload.Description, secretID,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update secret: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return 0, ErrSecretNotFound{Secret: models.Secret{ID: secretID}}
+		}
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM "upload_chunks" WHERE "upload_id" = $1`, upload.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete upload chunks: %w", err)
+	}
+	tag, err := tx.Exec(
+		ctx,
+		`UPDATE "uploads" SET "completed" = true, "secret_id" = $1
+		 WHERE "id" = $2 AND "offset" = "length" AND NOT "completed"`,
+		secretID, upload.ID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to complete upload: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, fmt.Errorf("upload with id=%s is already completed", upload.ID)
+	}
+	if err := appendSecretAuditEvent(ctx, tx, event, secretID); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to complete upload: %w", err)
+	}
+
+	return secretID, nil
+}
+
+func (db *DBStorage) DeleteUpload(ctx context.Context, id string) error {
+	_, err := db.pool.Exec(ctx, `DELETE FROM "uploads" WHERE "id" = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredUploads deletes expired uploads with their chunks.
+func (db *DBStorage) DeleteExpiredUploads(ctx context.Context) (int64, error) {
+	tag, err := db.pool.Exec(ctx, `DELETE FROM "uploads" WHERE "expires_at" <= now()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired uploads: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}